@@ -3,7 +3,18 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tiny-trae/internal/audit"
+	"tiny-trae/internal/git"
+	"tiny-trae/internal/permission"
+	"tiny-trae/internal/semantic"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
@@ -15,7 +26,35 @@ type ToolDefinition struct {
 	Name        string                         `json:"name"`
 	Description string                         `json:"description"`
 	InputSchema anthropic.ToolInputSchemaParam `json:"input_schema"`
-	Function    func(input json.RawMessage) (string, error)
+	Function    func(ctx context.Context, input json.RawMessage) (string, error)
+	// RequiresApproval marks tools that run commands or mutate files; the
+	// frontend is asked before each call unless the user chose always-allow.
+	RequiresApproval bool `json:"-"`
+	// ApprovalFor, if set, decides for each call whether it needs approval,
+	// in place of RequiresApproval, for tools whose calls range from
+	// harmless to risky depending on their input.
+	ApprovalFor func(input json.RawMessage) bool `json:"-"`
+	// Preview renders the input shown to the user when asking for approval.
+	// If nil, the raw JSON input is shown instead.
+	Preview func(input json.RawMessage) string `json:"-"`
+	// MutatesFiles marks tools that write files in the workspace. A git
+	// checkpoint is taken before the first such call of each turn.
+	MutatesFiles bool `json:"-"`
+	// Images, if set, returns images to attach to a successful result, for
+	// tools that let the model look at image files. It receives the same
+	// input as Function.
+	Images func(input json.RawMessage) ([]Image, error) `json:"-"`
+	// MaxConcurrent caps how many calls of the tool run at once when the
+	// model makes several calls in one response. Zero means one at a time
+	// for tools that need approval or mutate files and no limit otherwise;
+	// a negative value means no limit.
+	MaxConcurrent int `json:"-"`
+	// MinInterval is the least time between the starts of two calls, for
+	// tools backed by rate-limited services.
+	MinInterval time.Duration `json:"-"`
+	// Timeout bounds each call; zero means the profile's ToolTimeout or
+	// DefaultToolTimeout, and a negative value means no limit.
+	Timeout time.Duration `json:"-"`
 }
 
 // Profile represents a configuration that combines model settings, tools, and system prompt.
@@ -25,15 +64,92 @@ type Profile struct {
 	MaxTokens    int64
 	Tools        []ToolDefinition
 	SystemPrompt string
+	// ToolDefaults holds default input values per tool name. They are merged
+	// into a tool call's input for any field the model did not set.
+	ToolDefaults map[string]map[string]any
+	// ToolOptions holds settings per tool name, which tools read with
+	// Options. A "timeout" option overrides the tool's timeout.
+	ToolOptions map[string]ToolOptions
+	// ToolTimeout bounds calls of tools that set no Timeout of their own;
+	// zero means DefaultToolTimeout.
+	ToolTimeout time.Duration
+	// ThinkingBudget turns on extended thinking with up to this many tokens
+	// of thinking per response, on top of MaxTokens; zero turns it off.
+	ThinkingBudget int64
+	// Temperature is the sampling temperature; nil leaves it to the API.
+	Temperature *float64
+	// Retrieval adds indexed code relevant to each user message to it.
+	Retrieval Retrieval
 }
 
 // Agent struct represents the core of the AI agent.
 type Agent struct {
-	client   anthropic.Client
-	profile  *Profile
-	frontend Frontend
+	client      anthropic.Client
+	profile     *Profile
+	frontend    Frontend
+	policy      *permission.Policy
+	auditLog    *audit.Log
+	alwaysAllow map[string]bool
+	// autoApprove reports whether a tool's calls run without asking; see
+	// SetAutoApprove.
+	autoApprove func(ToolDefinition) bool
+	// disabledTools holds the tools the user turned off with ToolsCommand.
+	disabledTools map[string]bool
+	// middlewares are added to every tool call with Use.
+	middlewares []ToolMiddleware
+	// limiter enforces the tools' concurrency and rate limits.
+	limiter toolLimiter
+	// toolStats counts the session's tool calls for StatsCommand.
+	toolStats toolStatsRecorder
+	// checkpointMu guards checkpointed and checkpoints against tools
+	// running in parallel.
+	checkpointMu sync.Mutex
+
+	// turn counts user messages; checkpointed reports whether the current
+	// turn already has a checkpoint. checkpointSession tells this session's
+	// checkpoint refs apart from those of other sessions in the repository.
+	turn              int
+	checkpointed      bool
+	checkpoints       []turnCheckpoint
+	checkpointSession string
+	// pendingNote is prepended to the next user message to tell the model
+	// about something that happened outside the conversation.
+	pendingNote string
+	// toolResults holds recent tool results for ToolResult.
+	toolResults toolResultStore
+	// usage is the session's token usage so far.
+	usage UsageData
+	// finalAnswer is the text of the model's latest response.
+	finalAnswer string
+	// limits bound non-interactive runs.
+	limits Limits
+	// retriever finds code for the profile's Retrieval, once loaded;
+	// retrievalFailed reports whether loading it failed.
+	retriever       *semantic.Retriever
+	retrievalFailed bool
+	// summary is the session's running summary.
+	summary summaryStore
+}
+
+// turnCheckpoint is the state of the workspace before a turn's first file
+// mutation.
+type turnCheckpoint struct {
+	turn       int
+	checkpoint git.Checkpoint
 }
 
+// RevertTurnCommand restores the workspace to its state before the last turn
+// that changed files.
+const RevertTurnCommand = "/revert-turn"
+
+// maxCheckpoints is how many turns back RevertTurnCommand can go. Older
+// checkpoints' refs are deleted, so that git can collect their commits.
+const maxCheckpoints = 50
+
+// sessions counts the agents created by this process, which may run several
+// sessions at once, as the server does.
+var sessions atomic.Int64
+
 // NewAgent creates a new Agent instance with a profile and frontend.
 func NewAgent(
 	client anthropic.Client,
@@ -41,9 +157,13 @@ func NewAgent(
 	frontend Frontend,
 ) *Agent {
 	return &Agent{
-		client:   client,
-		profile:  profile,
-		frontend: frontend,
+		client:        client,
+		profile:       profile,
+		frontend:      frontend,
+		alwaysAllow:   make(map[string]bool),
+		disabledTools: make(map[string]bool),
+
+		checkpointSession: fmt.Sprintf("%d-%d", os.Getpid(), sessions.Add(1)),
 	}
 }
 
@@ -65,6 +185,30 @@ func NewAgentWithDefaults(
 	return NewAgent(client, profile, frontend)
 }
 
+// SetPermissionPolicy sets the policy consulted before every tool call.
+func (a *Agent) SetPermissionPolicy(policy *permission.Policy) {
+	a.policy = policy
+}
+
+// SetAuditLog sets the log that every tool execution is recorded to.
+func (a *Agent) SetAuditLog(log *audit.Log) {
+	a.auditLog = log
+}
+
+// SetAutoApprove makes calls of the tools for which approve returns true
+// run without asking for approval, as if the user had approved them. The
+// permission policy's deny rules still apply, and the audit log records
+// the calls as auto-approved.
+func (a *Agent) SetAutoApprove(approve func(ToolDefinition) bool) {
+	a.autoApprove = approve
+}
+
+// AddInitialContext adds text, such as a map of the repository, to the
+// first user message. Call it before Run.
+func (a *Agent) AddInitialContext(text string) {
+	a.addNote(text)
+}
+
 // NewClientWithOptions creates a new Anthropic client with the given options.
 func NewClientWithOptions(options ...option.RequestOption) anthropic.Client {
 	return anthropic.NewClient(options...)
@@ -88,22 +232,41 @@ func (a *Agent) Run(ctx context.Context, initialMessage string) error {
 		errorChan <- a.runCore(ctx, initialMessage)
 	}()
 
-	// Wait for completion or error
-	select {
-	case <-ctx.Done():
+	// runCore stops soon after ctx is done; waiting for it lets its
+	// cleanup, such as deleting checkpoints, finish before Run returns
+	err := <-errorChan
+	if ctx.Err() != nil {
 		return ctx.Err()
-	case err := <-errorChan:
-		return err
 	}
+	return err
 }
 
 // runCore contains the main agent logic that runs in a separate goroutine
 func (a *Agent) runCore(ctx context.Context, initialMessage string) error {
 	conversation := []anthropic.MessageParam{}
+	defer a.deleteCheckpoints()
+	if !a.frontend.IsInteractive() {
+		// Summarize tool usage at the end of a one-shot run
+		defer func() {
+			if len(a.ToolStats()) > 0 {
+				a.frontend.SendMessage(Message{Type: MessageTypeSystemInfo, Content: a.statsReport()})
+			}
+		}()
+	}
+
+	a.sendUsage()
+
+	// Past MaxTime, ctx is done but parent is not, so a summary can still
+	// be asked for
+	parent := ctx
+	ctx, cancelLimits, timedOut := a.limitedContext(ctx)
+	defer cancelLimits()
+	timeLimit := fmt.Sprintf("time limit of %s", a.limits.MaxTime)
+	responses := 0
 
 	if initialMessage != "" {
-		userMessage := anthropic.NewUserMessage(anthropic.NewTextBlock(initialMessage))
-		conversation = append(conversation, userMessage)
+		a.startTurn()
+		conversation = append(conversation, a.userMessage(ctx, initialMessage))
 		// Send user input message to frontend
 		a.frontend.SendMessage(Message{
 			Type:    MessageTypeUserInput,
@@ -115,6 +278,9 @@ func (a *Agent) runCore(ctx context.Context, initialMessage string) error {
 	for {
 		select {
 		case <-ctx.Done():
+			if timedOut() {
+				return a.stopAtLimit(parent, conversation, timeLimit)
+			}
 			return ctx.Err()
 		default:
 		}
@@ -125,8 +291,25 @@ func (a *Agent) runCore(ctx context.Context, initialMessage string) error {
 				break
 			}
 
-			userMessage := anthropic.NewUserMessage(anthropic.NewTextBlock(userInput))
-			conversation = append(conversation, userMessage)
+			if userInput == RevertTurnCommand {
+				a.revertTurn(ctx)
+				continue
+			}
+			if isToolsCommand(userInput) {
+				a.toolsCommand(userInput)
+				continue
+			}
+			if userInput == StatsCommand {
+				a.frontend.SendMessage(Message{Type: MessageTypeSystemInfo, Content: a.statsReport()})
+				continue
+			}
+			if userInput == SummaryCommand {
+				a.frontend.SendMessage(Message{Type: MessageTypeSystemInfo, Content: a.summaryReport()})
+				continue
+			}
+
+			a.startTurn()
+			conversation = append(conversation, a.userMessage(ctx, userInput))
 
 			// Send user input message to frontend
 			a.frontend.SendMessage(Message{
@@ -135,8 +318,17 @@ func (a *Agent) runCore(ctx context.Context, initialMessage string) error {
 			})
 		}
 
-		message, err := a.runInference(ctx, conversation)
+		stepCtx, endStep := a.turnContext(ctx)
+		message, err := a.runInference(stepCtx, conversation)
 		if err != nil {
+			if endStep() {
+				a.cancelTurn()
+				readUserInput = true
+				continue
+			}
+			if timedOut() {
+				return a.stopAtLimit(parent, conversation, timeLimit)
+			}
 			a.frontend.SendMessage(Message{
 				Type:    MessageTypeError,
 				Content: fmt.Sprintf("LLM request failed: %v", err),
@@ -152,22 +344,41 @@ func (a *Agent) runCore(ctx context.Context, initialMessage string) error {
 			}
 		}
 		conversation = append(conversation, message.ToParam())
+		responses++
+		a.recordUsage(message.Usage)
+		a.sendUsage()
 
 
-		toolResults := []anthropic.ContentBlockParamUnion{}
+		var calls []toolUse
+		var texts []string
 		for _, content := range message.Content {
 			switch content.Type {
 			case "text":
+				texts = append(texts, content.Text)
 				// Send assistant message to frontend
 				// Always show assistant messages to ensure tool feedback is displayed
 				a.frontend.SendMessage(Message{
 					Type:    MessageTypeAssistant,
 					Content: content.Text,
 				})
+			case "thinking":
+				a.frontend.SendMessage(Message{
+					Type:    MessageTypeThinking,
+					Content: content.Thinking,
+				})
 			case "tool_use":
-				result := a.executeTool(content.ID, content.Name, content.Input)
-				toolResults = append(toolResults, result)
+				calls = append(calls, toolUse{id: content.ID, name: content.Name, input: content.Input})
+			}
+		}
+		a.finalAnswer = strings.Join(texts, "\n\n")
+		toolResults := a.executeTools(stepCtx, calls)
+		if endStep() {
+			if len(toolResults) > 0 {
+				conversation = append(conversation, anthropic.NewUserMessage(toolResults...))
 			}
+			a.cancelTurn()
+			readUserInput = true
+			continue
 		}
 
 		if len(toolResults) == 0 {
@@ -178,12 +389,18 @@ func (a *Agent) runCore(ctx context.Context, initialMessage string) error {
 				continue
 			} else {
 				// In non-interactive mode, exit after processing the message
+				if message.StopReason == anthropic.StopReasonMaxTokens {
+					return fmt.Errorf("%w: the response reached the limit of %d tokens", ErrBudgetExceeded, a.profile.MaxTokens)
+				}
 				return nil
 			}
 		}
 
 		// After tool execution, add tool results to conversation and continue inference
 		conversation = append(conversation, anthropic.NewUserMessage(toolResults...))
+		if a.turnsExhausted(responses) {
+			return a.stopAtLimit(parent, conversation, fmt.Sprintf("limit of %d turns", a.limits.MaxTurns))
+		}
 		
 		// Continue the inference loop to get model's response to tool results
 		// Don't read user input in the next iteration, let the model respond to tool results first
@@ -197,9 +414,10 @@ func (a *Agent) runCore(ctx context.Context, initialMessage string) error {
 // runInference sends the conversation to the Anthropic API and gets the model's response.
 // It constructs a list of tools available for the model to use and includes them in the API request.
 // The function returns the model's response message or an error if the API call fails.
-func (a *Agent) runInference(ctx context.Context, conversation []anthropic.MessageParam) (*anthropic.Message, error) {
+// Options adjust the request's parameters before it is sent.
+func (a *Agent) runInference(ctx context.Context, conversation []anthropic.MessageParam, options ...func(*anthropic.MessageNewParams)) (*anthropic.Message, error) {
 	anthropicTools := []anthropic.ToolUnionParam{}
-	for _, tool := range a.profile.Tools {
+	for _, tool := range a.enabledTools() {
 		anthropicTools = append(anthropicTools, anthropic.ToolUnionParam{
 			OfTool: &anthropic.ToolParam{
 				Name:        tool.Name,
@@ -209,22 +427,79 @@ func (a *Agent) runInference(ctx context.Context, conversation []anthropic.Messa
 		})
 	}
 
-	message, err := a.client.Messages.New(ctx, anthropic.MessageNewParams{
+	params := anthropic.MessageNewParams{
 		Model:     a.profile.Model,
 		MaxTokens: a.profile.MaxTokens,
 		Messages:  conversation,
 		Tools:     anthropicTools,
 		System:    []anthropic.TextBlockParam{{Text: a.profile.SystemPrompt}},
-	})
+	}
+	if summary := a.summaryPrompt(); summary != "" {
+		params.System = append(params.System, anthropic.TextBlockParam{Text: summary})
+	}
+	if budget := a.profile.ThinkingBudget; budget > 0 {
+		params.Thinking = anthropic.ThinkingConfigParamOfEnabled(budget)
+		params.MaxTokens += budget
+	}
+	if a.profile.Temperature != nil {
+		params.Temperature = anthropic.Float(*a.profile.Temperature)
+	}
+	for _, option := range options {
+		option(&params)
+	}
+	a.sendRequestStart(params)
+	stream := a.client.Messages.NewStreaming(ctx, params)
+	defer stream.Close()
+
+	// Pass text to the frontend as it arrives; the complete text blocks are
+	// still sent as assistant messages once the response is done
+	var message anthropic.Message
+	for stream.Next() {
+		event := stream.Current()
+		if err := message.Accumulate(event); err != nil {
+			return nil, err
+		}
+		if delta, ok := event.AsAny().(anthropic.ContentBlockDeltaEvent); ok {
+			if text, ok := delta.Delta.AsAny().(anthropic.TextDelta); ok && text.Text != "" {
+				a.frontend.SendMessage(Message{Type: MessageTypeAssistantDelta, Content: text.Text})
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
 
-	return message, err
+// executeTool executes a tool call from the model and returns the result as
+// a tool result block.
+func (a *Agent) executeTool(ctx context.Context, id, name string, input json.RawMessage) anthropic.ContentBlockParamUnion {
+	result := a.CallTool(ctx, id, name, input)
+	text := result.Text
+	if result.Meta != nil {
+		// The model sees what frontends see, such as the exit code and
+		// the files changed, to decide what to do next
+		text += "\n" + result.Meta.line()
+	}
+	return toolResultBlock(id, text, result.Images, result.IsError)
 }
 
-// executeTool executes a tool with the given name and input.
-// It finds the corresponding tool definition, calls its associated function with the provided input,
-// and returns the result as a tool result block. If the tool is not found or an error occurs
-// during execution, it returns an error message in the tool result block.
-func (a *Agent) executeTool(id, name string, input json.RawMessage) anthropic.ContentBlockParamUnion {
+// ToolCallResult is the outcome of a tool call.
+type ToolCallResult struct {
+	Text    string
+	Images  []Image
+	IsError bool
+	// Meta describes the result; it is nil for calls that did not run.
+	Meta *ToolResultMeta
+}
+
+// CallTool executes a tool with the given name and input.
+// It finds the corresponding tool definition, runs it through the middleware
+// chain, which applies the permission policy and approval, and records the
+// call in the audit log. If the tool is not found, is denied, or fails,
+// the result is an error carrying the message.
+func (a *Agent) CallTool(ctx context.Context, id, name string, input json.RawMessage) ToolCallResult {
+	start := time.Now()
 	var toolDef ToolDefinition
 	var found bool
 	for _, tool := range a.profile.Tools {
@@ -256,63 +531,353 @@ func (a *Agent) executeTool(id, name string, input json.RawMessage) anthropic.Co
 				Data:    data,
 			})
 		}
-		return anthropic.NewToolResultBlock(id, "tool not found", true)
+		a.recordAudit(id, name, input, "tool not found", start, audit.StatusError, "not_required")
+		return ToolCallResult{Text: "tool not found", IsError: true}
 	}
 
-	// Send tool call message to frontend
-	toolCallData := ToolCallData{
-		ToolName: name,
-		ToolID:   id,
-		Input:    input,
+	call := &ToolCall{
+		ID:       id,
+		Tool:     toolDef,
+		Input:    applyToolDefaults(input, a.profile.ToolDefaults[name]),
+		Approval: "not_required",
+	}
+	attachments := &imageAttachments{}
+	ctx = context.WithValue(ctx, imageAttachmentsKey{}, attachments)
+	report := &resultReport{}
+	ctx = context.WithValue(ctx, resultReportKey{}, report)
+	ctx = context.WithValue(ctx, summaryKey{}, &a.summary)
+	ctx = a.withToolOutput(ctx, id, name)
+	ctx = WithOptions(ctx, a.profile.ToolOptions[name])
+
+	response, err := a.toolChain()(ctx, call)
+	isError := err != nil
+	result := response
+	status := audit.StatusSuccess
+	var denied *ToolDeniedError
+	if errors.As(err, &denied) {
+		result = err.Error()
+		status = audit.StatusDenied
+	} else if errors.Is(err, errToolInterrupted) {
+		result = err.Error()
+		status = audit.StatusInterrupted
+	} else if err != nil {
+		result = err.Error()
+		status = audit.StatusError
+	}
+
+	var images []Image
+	if !isError {
+		images = attachments.list()
+		if toolDef.Images != nil {
+			more, err := toolDef.Images(call.Input)
+			if err != nil {
+				isError = true
+				result = err.Error()
+				status = audit.StatusError
+			}
+			images = append(images, more...)
+		}
+	}
+
+	meta := report.snapshot()
+	meta.Bytes = len(result)
+	meta.DurationMs = call.Duration.Milliseconds()
+
+	if !isError {
+		a.toolResults.add(id, result)
+	}
+	a.summary.addFiles(meta.FilesChanged)
+	a.toolStats.record(name, status, call.Duration)
+	sentMeta := &meta
+	if status == audit.StatusDenied {
+		sentMeta = nil
+	}
+	a.sendToolResult(name, id, result, isError, sentMeta)
+	a.recordAudit(id, name, call.Input, result, start, status, call.Approval)
+
+	return ToolCallResult{Text: result, Images: images, IsError: isError, Meta: sentMeta}
+}
+
+// userContent returns the blocks for a user message, attaching the images it
+// references with @path. Images that cannot be attached are reported to the
+// frontend.
+func (a *Agent) userContent(text string) []anthropic.ContentBlockParamUnion {
+	blocks, errs := userContent(text)
+	for _, err := range errs {
+		a.frontend.SendMessage(Message{
+			Type:    MessageTypeError,
+			Content: err.Error(),
+		})
+	}
+	return blocks
+}
+
+// startTurn begins a new turn, which gets its own checkpoint.
+func (a *Agent) startTurn() {
+	a.turn++
+	a.checkpointed = false
+}
+
+// checkpointTurn records a git checkpoint of the workspace if the current
+// turn does not have one yet. Outside a git repository it does nothing.
+func (a *Agent) checkpointTurn(ctx context.Context) {
+	a.checkpointMu.Lock()
+	defer a.checkpointMu.Unlock()
+	if a.checkpointed {
+		return
+	}
+	a.checkpointed = true
+
+	if _, err := git.Root(ctx, "."); err != nil {
+		return
 	}
-	data, err := json.Marshal(toolCallData)
+	cp, err := git.CreateCheckpoint(ctx, ".", fmt.Sprintf("%s-turn-%d", a.checkpointSession, a.turn))
 	if err != nil {
-		// Fallback to sending message without data if marshaling fails
 		a.frontend.SendMessage(Message{
-			Type:    MessageTypeToolCall,
-			Content: fmt.Sprintf("Executing tool: %s", name),
+			Type:    MessageTypeError,
+			Content: fmt.Sprintf("Failed to create git checkpoint: %v", err),
 		})
-	} else {
+		return
+	}
+	a.checkpoints = append(a.checkpoints, turnCheckpoint{turn: a.turn, checkpoint: cp})
+	if len(a.checkpoints) > maxCheckpoints {
+		a.deleteCheckpoint(ctx, a.checkpoints[0])
+		a.checkpoints = a.checkpoints[1:]
+	}
+}
+
+// deleteCheckpoints deletes the refs of the session's checkpoints when it
+// ends, since nothing can revert to them any more.
+func (a *Agent) deleteCheckpoints() {
+	a.checkpointMu.Lock()
+	defer a.checkpointMu.Unlock()
+	// The session's context may be done by now
+	ctx := context.Background()
+	for _, tc := range a.checkpoints {
+		a.deleteCheckpoint(ctx, tc)
+	}
+	a.checkpoints = nil
+}
+
+// deleteCheckpoint deletes the ref of a checkpoint that is no longer needed,
+// reporting a failure to the user.
+func (a *Agent) deleteCheckpoint(ctx context.Context, tc turnCheckpoint) {
+	if err := git.DeleteCheckpoint(ctx, tc.checkpoint); err != nil {
 		a.frontend.SendMessage(Message{
-			Type:    MessageTypeToolCall,
-			Content: fmt.Sprintf("Executing tool: %s", name),
-			Data:    data,
+			Type:    MessageTypeError,
+			Content: fmt.Sprintf("Failed to delete the checkpoint of turn %d (%s): %v", tc.turn, tc.checkpoint.Ref, err),
 		})
 	}
+}
 
-	response, err := toolDef.Function(input)
-	isError := err != nil
-	result := response
+// revertTurn restores the most recent checkpoint and removes it, so running
+// the command again steps back another turn.
+func (a *Agent) revertTurn(ctx context.Context) {
+	if len(a.checkpoints) == 0 {
+		a.frontend.SendMessage(Message{
+			Type:    MessageTypeSystemInfo,
+			Content: "No file changes to revert.",
+		})
+		return
+	}
+
+	last := a.checkpoints[len(a.checkpoints)-1]
+	if err := git.RestoreCheckpoint(ctx, last.checkpoint); err != nil {
+		a.frontend.SendMessage(Message{
+			Type:    MessageTypeError,
+			Content: fmt.Sprintf("Failed to revert turn: %v", err),
+		})
+		return
+	}
+	a.checkpoints = a.checkpoints[:len(a.checkpoints)-1]
+	a.deleteCheckpoint(ctx, last)
+	if last.turn == a.turn {
+		a.checkpointed = false
+	}
+
+	a.frontend.SendMessage(Message{
+		Type:    MessageTypeSystemInfo,
+		Content: fmt.Sprintf("Reverted file changes made since the start of turn %d (checkpoint %s).", last.turn, last.checkpoint.Commit[:min(8, len(last.checkpoint.Commit))]),
+	})
+	a.addNote(fmt.Sprintf("[The user ran %s: all file changes made since the start of turn %d were undone. Re-read files before editing them.]", RevertTurnCommand, last.turn))
+}
+
+// cancelTurn tells the user and the model that the user stopped the turn.
+func (a *Agent) cancelTurn() {
+	a.frontend.SendMessage(Message{Type: MessageTypeSystemInfo, Content: "Cancelled the turn."})
+	a.addNote("[The user cancelled your previous turn before it finished.]")
+}
+
+// userMessage returns the message for the user's input, with the pending
+// note and the relevant code first.
+func (a *Agent) userMessage(ctx context.Context, input string) anthropic.MessageParam {
+	blocks := []anthropic.ContentBlockParamUnion{}
+	if a.pendingNote != "" {
+		blocks = append(blocks, anthropic.NewTextBlock(a.pendingNote))
+		a.pendingNote = ""
+	}
+	if code := a.relevantCode(ctx, input); code != "" {
+		blocks = append(blocks, anthropic.NewTextBlock(code))
+	}
+	blocks = append(blocks, a.userContent(input)...)
+	return anthropic.NewUserMessage(blocks...)
+}
+
+// addNote queues a note for the model, sent with the next user message.
+func (a *Agent) addNote(note string) {
+	if a.pendingNote != "" {
+		a.pendingNote += "\n"
+	}
+	a.pendingNote += note
+}
+
+// applyToolDefaults fills in fields missing from a tool call's JSON input
+// with the profile's defaults. Inputs that are not JSON objects are returned
+// unchanged.
+func applyToolDefaults(input json.RawMessage, defaults map[string]any) json.RawMessage {
+	if len(defaults) == 0 {
+		return input
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(input, &fields); err != nil || fields == nil {
+		return input
+	}
+	for key, value := range defaults {
+		if _, ok := fields[key]; !ok {
+			fields[key] = value
+		}
+	}
+
+	merged, err := json.Marshal(fields)
 	if err != nil {
-		result = err.Error()
+		return input
 	}
+	return merged
+}
 
-	// Send tool result message to frontend
-	toolResultData := ToolResultData{
+// errToolInterrupted is returned by runTool when the user cancels a running tool.
+var errToolInterrupted = errors.New("tool execution interrupted by user")
+
+// runTool runs the tool function with a context that is cancelled when the
+// frontend signals an interrupt. The agent stops waiting as soon as the
+// interrupt arrives or the call times out, even if the tool ignores its
+// context. For calls run in parallel, executeTools watches for interrupts and
+// cancels ctx instead.
+func (a *Agent) runTool(ctx context.Context, call *ToolCall) (string, error) {
+	var interrupts <-chan struct{}
+	if parallel, _ := ctx.Value(parallelKey{}).(bool); !parallel {
+		interrupts = a.frontend.Interrupts()
+		drainInterrupts(interrupts)
+	}
+
+	toolCtx, cancel := context.WithCancel(context.WithValue(ctx, toolResultsKey{}, &a.toolResults))
+	defer cancel()
+
+	type toolOutput struct {
+		response string
+		err      error
+	}
+	outputCh := make(chan toolOutput, 1)
+	go func() {
+		response, err := call.Tool.Function(toolCtx, call.Input)
+		outputCh <- toolOutput{response: response, err: err}
+	}()
+
+	select {
+	case output := <-outputCh:
+		if output.err != nil {
+			if err := timeoutCause(ctx); err != nil {
+				return output.response, err
+			}
+		}
+		return output.response, output.err
+	case <-interrupts:
+		cancel()
+		return "", errToolInterrupted
+	case <-ctx.Done():
+		if err := timeoutCause(ctx); err != nil {
+			return "", err
+		}
+		return "", errToolInterrupted
+	}
+}
+
+// requestApproval asks the frontend whether the tool call may run. An
+// always-allow answer is remembered for the rest of the session.
+func (a *Agent) requestApproval(toolDef ToolDefinition, id string, input json.RawMessage) ApprovalDecision {
+	if a.alwaysAllow[toolDef.Name] {
+		return ApprovalAlwaysAllow
+	}
+
+	preview := string(input)
+	if toolDef.Preview != nil {
+		preview = toolDef.Preview(input)
+	}
+
+	decision := a.frontend.RequestApproval(ApprovalRequest{
+		ToolName: toolDef.Name,
+		ToolID:   id,
+		Input:    input,
+		Preview:  preview,
+	})
+	switch decision {
+	case ApprovalAlwaysAllow:
+		a.alwaysAllow[toolDef.Name] = true
+		return ApprovalAlwaysAllow
+	case ApprovalApprove:
+		return ApprovalApprove
+	default:
+		return ApprovalDeny
+	}
+}
+
+// recordAudit appends a tool execution to the audit log, if one is configured.
+// Failures to write are reported to the frontend but never fail the tool call.
+func (a *Agent) recordAudit(id, name string, input json.RawMessage, result string, start time.Time, status, approval string) {
+	if a.auditLog == nil {
+		return
+	}
+	err := a.auditLog.Record(audit.Entry{
+		Time:       start,
+		ToolName:   name,
+		ToolID:     id,
+		Input:      input,
+		ResultHash: audit.HashResult(result),
+		DurationMs: time.Since(start).Milliseconds(),
+		Status:     status,
+		Approval:   approval,
+	})
+	if err != nil {
+		a.frontend.SendMessage(Message{
+			Type:    MessageTypeError,
+			Content: fmt.Sprintf("Failed to write audit log: %v", err),
+		})
+	}
+}
+
+// sendToolResult sends a tool result message to the frontend.
+func (a *Agent) sendToolResult(name, id, result string, isError bool, meta *ToolResultMeta) {
+	data, err := json.Marshal(ToolResultData{
 		ToolName: name,
 		ToolID:   id,
 		Result:   result,
 		IsError:  isError,
-	}
-	data, err = json.Marshal(toolResultData)
+		Meta:     meta,
+	})
 	if err != nil {
 		// Fallback to sending message without data if marshaling fails
 		a.frontend.SendMessage(Message{
 			Type:    MessageTypeToolResult,
 			Content: result,
 		})
-	} else {
-		a.frontend.SendMessage(Message{
-			Type:    MessageTypeToolResult,
-			Content: result,
-			Data:    data,
-		})
+		return
 	}
-
-	if err != nil {
-		return anthropic.NewToolResultBlock(id, err.Error(), true)
-	}
-	return anthropic.NewToolResultBlock(id, response, false)
+	a.frontend.SendMessage(Message{
+		Type:    MessageTypeToolResult,
+		Content: result,
+		Data:    data,
+	})
 }
 
 // GenerateSchema generates a JSON schema for a given type.
@@ -0,0 +1,376 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"tiny-trae/internal/git"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+func TestApplyToolDefaults(t *testing.T) {
+	defaults := map[string]any{"maxResults": 50, "context": 2}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]any
+	}{
+		{
+			name:     "fills missing fields",
+			input:    `{"pattern":"TODO"}`,
+			expected: map[string]any{"pattern": "TODO", "maxResults": float64(50), "context": float64(2)},
+		},
+		{
+			name:     "keeps fields set by the model",
+			input:    `{"pattern":"TODO","maxResults":5}`,
+			expected: map[string]any{"pattern": "TODO", "maxResults": float64(5), "context": float64(2)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := applyToolDefaults(json.RawMessage(tt.input), defaults)
+			var got map[string]any
+			if err := json.Unmarshal(merged, &got); err != nil {
+				t.Fatalf("Merged input is not valid JSON: %v", err)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, got)
+			}
+			for key, value := range tt.expected {
+				if got[key] != value {
+					t.Errorf("Expected %s=%v, got %v", key, value, got[key])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyToolDefaultsLeavesNonObjects(t *testing.T) {
+	input := json.RawMessage(`"not an object"`)
+	if got := applyToolDefaults(input, map[string]any{"a": 1}); string(got) != string(input) {
+		t.Errorf("Expected input to be unchanged, got %s", got)
+	}
+}
+
+// recordingFrontend is a Frontend that records the messages it is sent.
+type recordingFrontend struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (f *recordingFrontend) SendMessage(msg Message) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = append(f.messages, msg)
+}
+func (f *recordingFrontend) GetUserInput() (string, bool) { return "", false }
+func (f *recordingFrontend) RequestApproval(ApprovalRequest) ApprovalDecision {
+	return ApprovalApprove
+}
+func (f *recordingFrontend) Interrupts() <-chan struct{}  { return nil }
+func (f *recordingFrontend) TurnCancels() <-chan struct{} { return nil }
+func (f *recordingFrontend) IsInteractive() bool          { return true }
+func (f *recordingFrontend) Close()                       {}
+
+func TestCheckpointAndRevertTurn(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	t.Chdir(dir)
+	ctx := context.Background()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+	} {
+		if _, err := git.Run(ctx, dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile("main.go", []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	frontend := &recordingFrontend{}
+	a := NewAgent(anthropic.Client{}, &Profile{}, frontend)
+
+	a.startTurn()
+	a.checkpointTurn(ctx)
+	if err := os.WriteFile("main.go", []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Only the first mutation of a turn is checkpointed
+	a.checkpointTurn(ctx)
+	if len(a.checkpoints) != 1 {
+		t.Fatalf("Expected 1 checkpoint, got %d", len(a.checkpoints))
+	}
+
+	a.revertTurn(ctx)
+	data, _ := os.ReadFile("main.go")
+	if string(data) != "package main\n" {
+		t.Errorf("Expected main.go to be reverted, got %q", data)
+	}
+	if !strings.Contains(a.pendingNote, RevertTurnCommand) {
+		t.Errorf("Expected a note for the model, got %q", a.pendingNote)
+	}
+
+	a.revertTurn(ctx)
+	last := frontend.messages[len(frontend.messages)-1]
+	if last.Content != "No file changes to revert." {
+		t.Errorf("Unexpected message %q", last.Content)
+	}
+}
+
+func TestCheckpointRefsAreDeleted(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	t.Chdir(dir)
+	ctx := context.Background()
+	if _, err := git.Run(ctx, dir, "init", "-q"); err != nil {
+		t.Fatal(err)
+	}
+	refs := func() []string {
+		t.Helper()
+		out, err := git.Run(ctx, dir, "for-each-ref", "--format=%(refname)", "refs/tiny-trae/checkpoints/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Fields(out)
+	}
+
+	frontend := &recordingFrontend{}
+	a := NewAgent(anthropic.Client{}, &Profile{}, frontend)
+	other := NewAgent(anthropic.Client{}, &Profile{}, frontend)
+	other.startTurn()
+	other.checkpointTurn(ctx)
+
+	// Only the newest checkpoints are kept
+	for range maxCheckpoints + 1 {
+		a.startTurn()
+		a.checkpointTurn(ctx)
+	}
+	if len(a.checkpoints) != maxCheckpoints || a.checkpoints[0].turn != 2 {
+		t.Fatalf("Expected the last %d checkpoints, got %d from turn %d", maxCheckpoints, len(a.checkpoints), a.checkpoints[0].turn)
+	}
+	if got := refs(); len(got) != maxCheckpoints+1 {
+		t.Fatalf("Expected %d checkpoint refs, got %d", maxCheckpoints+1, len(got))
+	}
+
+	// Reverting a turn deletes its checkpoint
+	a.revertTurn(ctx)
+	if got := refs(); len(got) != maxCheckpoints {
+		t.Errorf("Expected the reverted checkpoint's ref to be deleted, got %d refs", len(got))
+	}
+
+	// Ending the session deletes its checkpoints, and only its own
+	a.deleteCheckpoints()
+	if got := refs(); len(got) != 1 || got[0] != other.checkpoints[0].checkpoint.Ref {
+		t.Errorf("Expected only the other session's checkpoint to be left, got %v", got)
+	}
+	for _, message := range frontend.messages {
+		if message.Type == MessageTypeError {
+			t.Errorf("Unexpected error %q", message.Content)
+		}
+	}
+}
+
+func TestCheckpointTurnOutsideRepo(t *testing.T) {
+	t.Chdir(t.TempDir())
+	frontend := &recordingFrontend{}
+	a := NewAgent(anthropic.Client{}, &Profile{}, frontend)
+
+	a.startTurn()
+	a.checkpointTurn(context.Background())
+	if len(a.checkpoints) != 0 || len(frontend.messages) != 0 {
+		t.Errorf("Expected no checkpoint or message outside a repository, got %d and %v", len(a.checkpoints), frontend.messages)
+	}
+}
+
+func TestToolResultsAvailableToLaterCalls(t *testing.T) {
+	var seen string
+	profile := &Profile{Tools: []ToolDefinition{
+		{Name: "produce", Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+			return `{"items":[1,2,3]}`, nil
+		}},
+		{Name: "consume", Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+			result, ok := ToolResult(ctx, "first")
+			if !ok {
+				return "", os.ErrNotExist
+			}
+			seen = result
+			return "ok", nil
+		}},
+	}}
+	a := NewAgent(anthropic.Client{}, profile, &recordingFrontend{})
+
+	a.executeTool(context.Background(), "first", "produce", json.RawMessage(`{}`))
+	a.executeTool(context.Background(), "second", "consume", json.RawMessage(`{}`))
+	if seen != `{"items":[1,2,3]}` {
+		t.Errorf("Expected the earlier result, got %q", seen)
+	}
+
+	if _, ok := ToolResult(context.Background(), "first"); ok {
+		t.Error("Expected no results outside a tool call")
+	}
+
+	for i := range maxStoredToolResults {
+		a.toolResults.add(strings.Repeat("x", i+1), "")
+	}
+	if _, ok := a.toolResults.get("first"); ok {
+		t.Error("Expected the oldest result to be forgotten")
+	}
+}
+
+func TestRunInferenceStreamsText(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[],"usage":{"input_tokens":1,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":", world"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":3}}`,
+		`{"type":"message_stop"}`,
+	}
+	server := streamServer(events, nil)
+	defer server.Close()
+
+	frontend := &recordingFrontend{}
+	client := NewClientWithOptions(option.WithBaseURL(server.URL), option.WithAPIKey("test"))
+	a := NewAgent(client, &Profile{Model: "claude", MaxTokens: 100}, frontend)
+	message, err := a.runInference(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(message.Content) != 1 || message.Content[0].Text != "Hello, world" {
+		t.Errorf("Unexpected content %+v", message.Content)
+	}
+
+	var deltas []string
+	for _, msg := range frontend.messages {
+		if msg.Type == MessageTypeAssistantDelta {
+			deltas = append(deltas, msg.Content)
+		}
+	}
+	if strings.Join(deltas, "|") != "Hello|, world" {
+		t.Errorf("Unexpected deltas %q", deltas)
+	}
+}
+
+func TestRunInferenceRequestsThinking(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[],"usage":{"input_tokens":1,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":"","signature":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Let me see."}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":3}}`,
+		`{"type":"message_stop"}`,
+	}
+	var request struct {
+		MaxTokens int64 `json:"max_tokens"`
+		Thinking  struct {
+			Type         string `json:"type"`
+			BudgetTokens int64  `json:"budget_tokens"`
+		} `json:"thinking"`
+	}
+	server := streamServer(events, func(body []byte) { json.Unmarshal(body, &request) })
+	defer server.Close()
+
+	client := NewClientWithOptions(option.WithBaseURL(server.URL), option.WithAPIKey("test"))
+	a := NewAgent(client, &Profile{Model: "claude", MaxTokens: 100, ThinkingBudget: 2048}, &recordingFrontend{})
+	message, err := a.runInference(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if request.Thinking.Type != "enabled" || request.Thinking.BudgetTokens != 2048 || request.MaxTokens != 2148 {
+		t.Errorf("Expected thinking on top of the max tokens, got %+v", request)
+	}
+	if len(message.Content) != 1 || message.Content[0].Thinking != "Let me see." {
+		t.Errorf("Unexpected content %+v", message.Content)
+	}
+}
+
+// oneShotFrontend is a recordingFrontend for non-interactive runs.
+type oneShotFrontend struct{ recordingFrontend }
+
+func (f *oneShotFrontend) IsInteractive() bool { return false }
+
+func TestRunReportsTheFinalAnswer(t *testing.T) {
+	for _, tc := range []struct {
+		stopReason string
+		wantErr    error
+	}{
+		{"end_turn", nil},
+		{"max_tokens", ErrBudgetExceeded},
+	} {
+		t.Run(tc.stopReason, func(t *testing.T) {
+			events := []string{
+				`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[],"usage":{"input_tokens":1,"output_tokens":1}}}`,
+				`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"The answer"}}`,
+				`{"type":"content_block_stop","index":0}`,
+				`{"type":"message_delta","delta":{"stop_reason":"` + tc.stopReason + `"},"usage":{"output_tokens":3}}`,
+				`{"type":"message_stop"}`,
+			}
+			server := streamServer(events, nil)
+			defer server.Close()
+
+			client := NewClientWithOptions(option.WithBaseURL(server.URL), option.WithAPIKey("test"))
+			a := NewAgent(client, &Profile{Model: "claude", MaxTokens: 100}, &oneShotFrontend{})
+			err := a.Run(context.Background(), "question")
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("Expected error %v, got %v", tc.wantErr, err)
+			}
+			if a.FinalAnswer() != "The answer" {
+				t.Errorf("Unexpected final answer %q", a.FinalAnswer())
+			}
+		})
+	}
+}
+
+// streamServer returns a server that answers every request with the given
+// stream events, passing each request's body to onRequest if it is not nil.
+func streamServer(events []string, onRequest func(body []byte)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if onRequest != nil {
+			body, _ := io.ReadAll(r.Body)
+			onRequest(body)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			var typed struct{ Type string }
+			json.Unmarshal([]byte(event), &typed)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typed.Type, event)
+		}
+	}))
+}
+
+func TestAddInitialContext(t *testing.T) {
+	a := NewAgent(anthropic.Client{}, &Profile{}, &recordingFrontend{})
+	a.AddInitialContext("<repo_map>\n</repo_map>")
+
+	first := a.userMessage(context.Background(), "hello")
+	if len(first.Content) != 2 || first.Content[0].OfText.Text != "<repo_map>\n</repo_map>" || first.Content[1].OfText.Text != "hello" {
+		t.Errorf("Expected the context before the first message, got %+v", first.Content)
+	}
+	if second := a.userMessage(context.Background(), "again"); len(second.Content) != 1 {
+		t.Errorf("Expected the context only in the first message, got %+v", second.Content)
+	}
+}
@@ -0,0 +1,242 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// maxParallelToolCalls caps how many of the model's tool calls from one
+// response run at once, whatever the tools' own limits.
+const maxParallelToolCalls = 8
+
+// toolUse is a tool call from the model.
+type toolUse struct {
+	id    string
+	name  string
+	input json.RawMessage
+}
+
+// executeTools runs the model's tool calls and returns their result blocks
+// in the order of the calls. Calls run in parallel as far as each tool's
+// MaxConcurrent and MinInterval allow; approval prompts still come one at a
+// time, in order. An interrupt stops all of them.
+func (a *Agent) executeTools(ctx context.Context, calls []toolUse) []anthropic.ContentBlockParamUnion {
+	results := make([]anthropic.ContentBlockParamUnion, len(calls))
+	if len(calls) == 1 {
+		results[0] = a.executeTool(ctx, calls[0].id, calls[0].name, calls[0].input)
+		return results
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, parallelKey{}, true))
+	defer cancel()
+	stopWatching := a.cancelOnInterrupt(cancel)
+	defer stopWatching()
+
+	parallel := make(chan struct{}, maxParallelToolCalls)
+	turn := closedChannel()
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wait, next := turn, make(chan struct{})
+		pass := sync.OnceFunc(func() { close(next) })
+		callCtx := context.WithValue(ctx, approvalTurnKey{}, approvalTurn{wait: wait, pass: pass})
+		turn = next
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Calls that end before their approval check, such as calls to
+			// unknown tools, must still pass the turn on in order
+			defer func() {
+				<-wait
+				pass()
+			}()
+			parallel <- struct{}{}
+			defer func() { <-parallel }()
+			results[i] = a.executeTool(callCtx, call.id, call.name, call.input)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// parallelKey marks the context of calls run by executeTools, which handles
+// interrupts for all of them instead of leaving it to runTool.
+type parallelKey struct{}
+
+// cancelOnInterrupt calls cancel when the frontend signals an interrupt,
+// until the returned function is called.
+func (a *Agent) cancelOnInterrupt(cancel context.CancelFunc) (stop func()) {
+	interrupts := a.frontend.Interrupts()
+	drainInterrupts(interrupts)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-interrupts:
+			cancel()
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
+
+// turnContext returns a context for a step of a turn, the model's reply and
+// the tools it calls, that is cancelled when the user cancels the turn. The
+// returned function releases it and reports whether the user cancelled.
+func (a *Agent) turnContext(ctx context.Context) (context.Context, func() (cancelled bool)) {
+	ctx, cancel := context.WithCancel(ctx)
+	cancels := a.frontend.TurnCancels()
+	drainInterrupts(cancels)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	var cancelled bool
+	go func() {
+		defer close(exited)
+		select {
+		case <-cancels:
+			cancelled = true
+			cancel()
+		case <-done:
+		}
+	}()
+	return ctx, func() bool {
+		close(done)
+		<-exited
+		cancel()
+		return cancelled
+	}
+}
+
+// drainInterrupts drops interrupts left over from before a tool started.
+func drainInterrupts(interrupts <-chan struct{}) {
+	for {
+		select {
+		case <-interrupts:
+		default:
+			return
+		}
+	}
+}
+
+// closedChannel returns a channel that is already closed.
+func closedChannel() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}
+
+// approvalTurn orders the approval checks of parallel calls: a call waits
+// for wait to close, and calls pass once it is approved or refused.
+type approvalTurn struct {
+	wait <-chan struct{}
+	pass func()
+}
+
+// approvalTurnKey is the context key for a call's approvalTurn.
+type approvalTurnKey struct{}
+
+// awaitApprovalTurn blocks until it is the call's turn to be approved and
+// returns the function that passes the turn on. It returns at once for
+// calls that are not run in parallel.
+func awaitApprovalTurn(ctx context.Context) (done func()) {
+	turn, ok := ctx.Value(approvalTurnKey{}).(approvalTurn)
+	if !ok {
+		return func() {}
+	}
+	select {
+	case <-turn.wait:
+	case <-ctx.Done():
+	}
+	return turn.pass
+}
+
+// toolLimiter enforces each tool's MaxConcurrent and MinInterval.
+type toolLimiter struct {
+	mu        sync.Mutex
+	slots     map[string]chan struct{}
+	nextStart map[string]time.Time
+}
+
+// acquire waits until the tool may start and returns the function that
+// releases its slot. It fails if ctx ends first.
+func (l *toolLimiter) acquire(ctx context.Context, tool ToolDefinition) (release func(), err error) {
+	release = func() {}
+	if limit := tool.maxConcurrent(); limit > 0 {
+		slot := l.slot(tool.Name, limit)
+		select {
+		case slot <- struct{}{}:
+			release = func() { <-slot }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if tool.MinInterval > 0 {
+		l.mu.Lock()
+		now := time.Now()
+		start := now
+		if next := l.nextStart[tool.Name]; next.After(now) {
+			start = next
+		}
+		if l.nextStart == nil {
+			l.nextStart = make(map[string]time.Time)
+		}
+		l.nextStart[tool.Name] = start.Add(tool.MinInterval)
+		l.mu.Unlock()
+
+		if wait := start.Sub(now); wait > 0 {
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				release()
+				return nil, ctx.Err()
+			}
+		}
+	}
+	return release, nil
+}
+
+// slot returns the semaphore limiting concurrent calls of the named tool.
+func (l *toolLimiter) slot(name string, limit int) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.slots == nil {
+		l.slots = make(map[string]chan struct{})
+	}
+	slot, ok := l.slots[name]
+	if !ok {
+		slot = make(chan struct{}, limit)
+		l.slots[name] = slot
+	}
+	return slot
+}
+
+// maxConcurrent returns how many calls of the tool may run at once, or 0 for
+// no limit. Tools that need approval or change files run one at a time
+// unless they set MaxConcurrent.
+func (t ToolDefinition) maxConcurrent() int {
+	if t.MaxConcurrent != 0 {
+		return max(t.MaxConcurrent, 0)
+	}
+	if t.RequiresApproval || t.ApprovalFor != nil || t.MutatesFiles {
+		return 1
+	}
+	return 0
+}
+
+// limit makes calls wait for the tool's concurrency and rate limits.
+func (a *Agent) limit(next ToolFunc) ToolFunc {
+	return func(ctx context.Context, call *ToolCall) (string, error) {
+		release, err := a.limiter.acquire(ctx, call.Tool)
+		if err != nil {
+			return "", errToolInterrupted
+		}
+		defer release()
+		return next(ctx, call)
+	}
+}
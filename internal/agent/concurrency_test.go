@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+// concurrencyProbe is a tool function that records how many calls run at
+// once.
+type concurrencyProbe struct {
+	running atomic.Int32
+	peak    atomic.Int32
+}
+
+func (p *concurrencyProbe) run(ctx context.Context, input json.RawMessage) (string, error) {
+	n := p.running.Add(1)
+	defer p.running.Add(-1)
+	for {
+		peak := p.peak.Load()
+		if n <= peak || p.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	return string(input), nil
+}
+
+// approvalRecorder is a frontend that approves everything and records the
+// order of the approval requests.
+type approvalRecorder struct {
+	recordingFrontend
+	approvals []string
+}
+
+func (f *approvalRecorder) RequestApproval(req ApprovalRequest) ApprovalDecision {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.approvals = append(f.approvals, req.ToolID)
+	return ApprovalApprove
+}
+
+func TestExecuteToolsLimits(t *testing.T) {
+	reads, writes := &concurrencyProbe{}, &concurrencyProbe{}
+	profile := &Profile{Tools: []ToolDefinition{
+		{Name: "read", Function: reads.run},
+		{Name: "write", Function: writes.run, RequiresApproval: true},
+	}}
+	frontend := &approvalRecorder{}
+	a := NewAgent(anthropic.Client{}, profile, frontend)
+
+	var calls []toolUse
+	for _, id := range []string{"r1", "w1", "r2", "w2", "missing", "r3", "w3"} {
+		name := map[byte]string{'r': "read", 'w': "write", 'm': "nope"}[id[0]]
+		calls = append(calls, toolUse{id: id, name: name, input: json.RawMessage(`"` + id + `"`)})
+	}
+	results := a.executeTools(context.Background(), calls)
+
+	for i, result := range results {
+		if result.OfToolResult.ToolUseID != calls[i].id {
+			t.Errorf("Result %d is for %s, want %s", i, result.OfToolResult.ToolUseID, calls[i].id)
+		}
+	}
+	if got := reads.peak.Load(); got != 3 {
+		t.Errorf("Expected the reads to run in parallel, peak %d", got)
+	}
+	if got := writes.peak.Load(); got != 1 {
+		t.Errorf("Expected the writes to run one at a time, peak %d", got)
+	}
+	if got := frontend.approvals; len(got) != 3 || got[0] != "w1" || got[1] != "w2" || got[2] != "w3" {
+		t.Errorf("Expected approvals in call order, got %v", got)
+	}
+}
+
+func TestToolLimiterMinInterval(t *testing.T) {
+	var l toolLimiter
+	tool := ToolDefinition{Name: "search", MinInterval: 30 * time.Millisecond}
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := l.acquire(context.Background(), tool)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			release()
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("Expected three calls to take at least two intervals, took %s", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.acquire(ctx, tool); err == nil {
+		t.Error("Expected a cancelled wait to fail")
+	}
+}
+
+func TestMaxConcurrent(t *testing.T) {
+	for _, tc := range []struct {
+		tool ToolDefinition
+		want int
+	}{
+		{ToolDefinition{}, 0},
+		{ToolDefinition{RequiresApproval: true}, 1},
+		{ToolDefinition{MutatesFiles: true}, 1},
+		{ToolDefinition{RequiresApproval: true, MaxConcurrent: 4}, 4},
+		{ToolDefinition{RequiresApproval: true, MaxConcurrent: -1}, 0},
+	} {
+		if got := tc.tool.maxConcurrent(); got != tc.want {
+			t.Errorf("maxConcurrent(%+v) = %d, want %d", tc.tool, got, tc.want)
+		}
+	}
+}
+
+// cancellingFrontend cancels the turn when asked to, and has no more input.
+type cancellingFrontend struct {
+	recordingFrontend
+	cancels chan struct{}
+}
+
+func (f *cancellingFrontend) TurnCancels() <-chan struct{} { return f.cancels }
+
+func TestCancelTurn(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[],"usage":{"input_tokens":1,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"t1","name":"wait","input":{}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{}"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":3}}`,
+		`{"type":"message_stop"}`,
+	}
+	var requests atomic.Int32
+	server := streamServer(events, func([]byte) { requests.Add(1) })
+	defer server.Close()
+
+	frontend := &cancellingFrontend{cancels: make(chan struct{}, 1)}
+	wait := func(ctx context.Context, input json.RawMessage) (string, error) {
+		frontend.cancels <- struct{}{}
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	client := NewClientWithOptions(option.WithBaseURL(server.URL), option.WithAPIKey("test"))
+	profile := &Profile{Model: "claude", MaxTokens: 100, Tools: []ToolDefinition{{Name: "wait", Function: wait}}}
+	a := NewAgent(client, profile, frontend)
+	if err := a.runCore(context.Background(), "go"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got := requests.Load(); got != 1 {
+		t.Errorf("Expected no request after the cancel, got %d requests", got)
+	}
+	last := frontend.messages[len(frontend.messages)-1]
+	if last.Type != MessageTypeSystemInfo || last.Content != "Cancelled the turn." {
+		t.Errorf("Unexpected last message %+v", last)
+	}
+	if !strings.Contains(a.pendingNote, "cancelled") {
+		t.Errorf("Expected a note for the model, got %q", a.pendingNote)
+	}
+}
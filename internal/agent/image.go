@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// MaxImageBytes is the largest image file the API accepts.
+const MaxImageBytes = 5 << 20
+
+// imageMediaTypes maps the image file extensions the model can view to their
+// media types.
+var imageMediaTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// Image is an image file to show the model.
+type Image struct {
+	Path      string
+	MediaType string
+	Data      []byte
+}
+
+// IsImagePath reports whether path has the extension of an image format the
+// model can view.
+func IsImagePath(path string) bool {
+	_, ok := imageMediaTypes[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// LoadImage reads a PNG, JPEG, GIF, or WebP file.
+func LoadImage(path string) (Image, error) {
+	mediaType, ok := imageMediaTypes[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return Image{}, fmt.Errorf("%s is not a PNG, JPEG, GIF, or WebP image", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return Image{}, err
+	}
+	if info.Size() > MaxImageBytes {
+		return Image{}, fmt.Errorf("%s is %d KiB; images must be at most %d MiB", path, info.Size()>>10, MaxImageBytes>>20)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Image{}, err
+	}
+	return Image{Path: path, MediaType: mediaType, Data: data}, nil
+}
+
+// imageAttachments collects the images a running tool attaches to its result.
+type imageAttachments struct {
+	mu     sync.Mutex
+	images []Image
+}
+
+// list returns the attached images.
+func (a *imageAttachments) list() []Image {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.images
+}
+
+// imageAttachmentsKey is the context key for the running tool's attachments.
+type imageAttachmentsKey struct{}
+
+// AttachImage attaches img to the result of the running tool call, for tools
+// that produce images rather than read them from a file the model names. It
+// reports false outside a tool call. Images attached by a failed call are
+// dropped.
+func AttachImage(ctx context.Context, img Image) bool {
+	attachments, ok := ctx.Value(imageAttachmentsKey{}).(*imageAttachments)
+	if !ok {
+		return false
+	}
+	attachments.mu.Lock()
+	defer attachments.mu.Unlock()
+	attachments.images = append(attachments.images, img)
+	return true
+}
+
+// encoded returns the image's data in base64.
+func (img Image) encoded() string {
+	return base64.StdEncoding.EncodeToString(img.Data)
+}
+
+// ImageReferences returns the paths of the images mentioned as @path in
+// text, such as "what is wrong in @screenshot.png?". Trailing punctuation is
+// not part of the path.
+func ImageReferences(text string) []string {
+	var paths []string
+	for _, word := range strings.Fields(text) {
+		path, ok := strings.CutPrefix(word, "@")
+		if !ok {
+			continue
+		}
+		path = strings.TrimRight(path, `.,;:!?)"'`)
+		if IsImagePath(path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// userContent returns the blocks of a user message: the text followed by the
+// files, directories, and images it references. Those that cannot be loaded
+// are reported in errs and left out.
+func userContent(text string) (blocks []anthropic.ContentBlockParamUnion, errs []error) {
+	blocks = append(blocks, anthropic.NewTextBlock(text))
+	for _, path := range FileReferences(text) {
+		content, err := mentionContent(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not attach @%s: %w", path, err))
+			continue
+		}
+		blocks = append(blocks, anthropic.NewTextBlock(content))
+	}
+	for _, path := range ImageReferences(text) {
+		img, err := LoadImage(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not attach @%s: %w", path, err))
+			continue
+		}
+		blocks = append(blocks, anthropic.NewImageBlockBase64(img.MediaType, img.encoded()))
+	}
+	return blocks, errs
+}
+
+// toolResultBlock returns a tool result with text and any images.
+func toolResultBlock(id, text string, images []Image, isError bool) anthropic.ContentBlockParamUnion {
+	block := anthropic.NewToolResultBlock(id, text, isError)
+	for _, img := range images {
+		block.OfToolResult.Content = append(block.OfToolResult.Content, anthropic.ToolResultBlockParamContentUnion{
+			OfImage: &anthropic.ImageBlockParam{
+				Source: anthropic.ImageBlockParamSourceUnion{
+					OfBase64: &anthropic.Base64ImageSourceParam{
+						Data:      img.encoded(),
+						MediaType: anthropic.Base64ImageSourceMediaType(img.MediaType),
+					},
+				},
+			},
+		})
+	}
+	return block
+}
@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+func TestImageReferences(t *testing.T) {
+	tests := map[string][]string{
+		"what is wrong in @screenshot.png?":          {"screenshot.png"},
+		"compare @docs/a.JPG and @b.webp.":           {"docs/a.JPG", "b.webp"},
+		"email me@example.com about @notes.txt":      nil,
+		"(see @ui/error.gif) and @missing-extension": {"ui/error.gif"},
+	}
+	for text, want := range tests {
+		if got := ImageReferences(text); !reflect.DeepEqual(got, want) {
+			t.Errorf("ImageReferences(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestLoadImage(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pixel.png")
+	if err := os.WriteFile(path, []byte("\x89PNG"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := LoadImage(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if img.MediaType != "image/png" || string(img.Data) != "\x89PNG" {
+		t.Errorf("Unexpected image %+v", img)
+	}
+
+	if _, err := LoadImage(filepath.Join(dir, "notes.txt")); err == nil {
+		t.Error("Expected error for a non-image extension")
+	}
+	if _, err := LoadImage(filepath.Join(dir, "missing.png")); err == nil {
+		t.Error("Expected error for a missing file")
+	}
+}
+
+func TestUserContentAttachesImages(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("shot.png", []byte("\x89PNG"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	blocks, errs := userContent("fix the layout in @shot.png and @gone.png")
+	if len(blocks) != 2 || blocks[1].OfImage == nil {
+		t.Fatalf("Expected text and one image block, got %+v", blocks)
+	}
+	if source := blocks[1].OfImage.Source.OfBase64; source == nil || source.MediaType != "image/png" {
+		t.Errorf("Unexpected image source %+v", blocks[1].OfImage.Source)
+	}
+	if len(errs) != 1 {
+		t.Errorf("Expected an error for the missing image, got %v", errs)
+	}
+}
+
+func TestExecuteToolAttachesImages(t *testing.T) {
+	tool := ToolDefinition{
+		Name: "view",
+		Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+			return "a picture", nil
+		},
+		Images: func(input json.RawMessage) ([]Image, error) {
+			return []Image{{MediaType: "image/png", Data: []byte("png")}}, nil
+		},
+	}
+	a := NewAgent(anthropic.Client{}, &Profile{Tools: []ToolDefinition{tool}}, &recordingFrontend{})
+
+	block := a.executeTool(context.Background(), "id", "view", json.RawMessage(`{}`))
+	content := block.OfToolResult.Content
+	if len(content) != 2 || !strings.HasPrefix(content[0].OfText.Text, "a picture\n") || content[1].OfImage == nil {
+		t.Errorf("Expected text and image content, got %+v", content)
+	}
+}
+
+func TestExecuteToolAttachedImages(t *testing.T) {
+	failed := false
+	tool := ToolDefinition{
+		Name: "capture",
+		Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+			AttachImage(ctx, Image{MediaType: "image/png", Data: []byte("png")})
+			if failed {
+				return "", errors.New("capture failed")
+			}
+			return "captured", nil
+		},
+	}
+	a := NewAgent(anthropic.Client{}, &Profile{Tools: []ToolDefinition{tool}}, &recordingFrontend{})
+
+	content := a.executeTool(context.Background(), "id", "capture", json.RawMessage(`{}`)).OfToolResult.Content
+	if len(content) != 2 || content[1].OfImage == nil {
+		t.Errorf("Expected text and image content, got %+v", content)
+	}
+
+	failed = true
+	content = a.executeTool(context.Background(), "id2", "capture", json.RawMessage(`{}`)).OfToolResult.Content
+	if len(content) != 1 {
+		t.Errorf("Expected images of a failed call to be dropped, got %+v", content)
+	}
+
+	if AttachImage(context.Background(), Image{}) {
+		t.Error("Expected AttachImage to fail outside a tool call")
+	}
+}
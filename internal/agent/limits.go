@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// Limits bound a non-interactive Run; zero values mean no limit. A run that
+// reaches one stops, asks the model for a summary of where it got to, and
+// returns ErrBudgetExceeded.
+type Limits struct {
+	// MaxTurns is the most responses the model may give.
+	MaxTurns int
+	// MaxTime is the longest the run may take.
+	MaxTime time.Duration
+}
+
+// summaryTimeout bounds the request for a summary once a limit is reached,
+// which is made after MaxTime has run out.
+const summaryTimeout = 2 * time.Minute
+
+// summaryRequest asks the model for a summary when a run stops at a limit.
+const summaryRequest = "The run has reached its %s and you cannot use tools any more. Briefly summarize what you completed, what is left to do, and how to continue."
+
+// SetLimits sets the limits of non-interactive runs.
+func (a *Agent) SetLimits(limits Limits) {
+	a.limits = limits
+}
+
+// limitedContext returns ctx bounded by the run's MaxTime, and whether the
+// bounded context has run out while ctx has not.
+func (a *Agent) limitedContext(ctx context.Context) (context.Context, context.CancelFunc, func() bool) {
+	if a.frontend.IsInteractive() || a.limits.MaxTime <= 0 {
+		return ctx, func() {}, func() bool { return false }
+	}
+	limited, cancel := context.WithTimeout(ctx, a.limits.MaxTime)
+	return limited, cancel, func() bool { return limited.Err() != nil && ctx.Err() == nil }
+}
+
+// turnsExhausted reports whether a non-interactive run has given as many
+// responses as MaxTurns allows.
+func (a *Agent) turnsExhausted(responses int) bool {
+	return !a.frontend.IsInteractive() && a.limits.MaxTurns > 0 && responses >= a.limits.MaxTurns
+}
+
+// stopAtLimit ends a run that reached limit, described like "time limit of
+// 10m0s": it asks the model, without tools, to summarize the conversation's
+// progress and makes that the final answer.
+func (a *Agent) stopAtLimit(ctx context.Context, conversation []anthropic.MessageParam, limit string) error {
+	a.frontend.SendMessage(Message{
+		Type:    MessageTypeSystemInfo,
+		Content: fmt.Sprintf("Stopping: the run reached its %s", limit),
+	})
+
+	// The request joins the last message if it is the user's, such as the
+	// results of the last tool calls, since roles must alternate
+	request := anthropic.NewTextBlock(fmt.Sprintf(summaryRequest, limit))
+	if last := len(conversation) - 1; last >= 0 && conversation[last].Role == anthropic.MessageParamRoleUser {
+		conversation = slices.Clone(conversation)
+		conversation[last].Content = append(slices.Clone(conversation[last].Content), request)
+	} else {
+		conversation = append(conversation, anthropic.NewUserMessage(request))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, summaryTimeout)
+	defer cancel()
+	message, err := a.runInference(ctx, conversation, func(params *anthropic.MessageNewParams) {
+		params.ToolChoice = anthropic.ToolChoiceUnionParam{OfNone: &anthropic.ToolChoiceNoneParam{}}
+	})
+	if err != nil {
+		a.frontend.SendMessage(Message{
+			Type:    MessageTypeError,
+			Content: fmt.Sprintf("Failed to summarize the run: %v", err),
+		})
+	} else {
+		a.recordUsage(message.Usage)
+		a.sendUsage()
+		var texts []string
+		for _, content := range message.Content {
+			if content.Type == "text" {
+				texts = append(texts, content.Text)
+				a.frontend.SendMessage(Message{Type: MessageTypeAssistant, Content: content.Text})
+			}
+		}
+		a.finalAnswer = strings.Join(texts, "\n\n")
+	}
+	return fmt.Errorf("%w: the run reached its %s", ErrBudgetExceeded, limit)
+}
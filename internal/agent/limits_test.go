@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+// busyModelServer calls the "work" tool in every reply, and answers
+// "Summary" when it may not use tools. It records the requests' bodies.
+func busyModelServer(requests *[]string, mu *sync.Mutex) *httptest.Server {
+	toolUse := []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[],"usage":{"input_tokens":1,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"work","input":{}}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":1}}`,
+		`{"type":"message_stop"}`,
+	}
+	summary := []string{
+		`{"type":"message_start","message":{"id":"msg_2","type":"message","role":"assistant","model":"claude","content":[],"usage":{"input_tokens":1,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Summary"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":1}}`,
+		`{"type":"message_stop"}`,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var request struct {
+			ToolChoice struct{ Type string } `json:"tool_choice"`
+		}
+		json.Unmarshal(body, &request)
+		mu.Lock()
+		*requests = append(*requests, string(body))
+		mu.Unlock()
+
+		events := toolUse
+		if request.ToolChoice.Type == "none" {
+			events = summary
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			var typed struct{ Type string }
+			json.Unmarshal([]byte(event), &typed)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typed.Type, event)
+		}
+	}))
+}
+
+func TestRunStopsAtLimits(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		limits       Limits
+		toolDuration time.Duration
+		wantRequests int
+	}{
+		{"turns", Limits{MaxTurns: 2}, 0, 3},
+		{"time", Limits{MaxTime: 100 * time.Millisecond}, time.Hour, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var requests []string
+			server := busyModelServer(&requests, &mu)
+			defer server.Close()
+
+			work := ToolDefinition{
+				Name: "work",
+				Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+					select {
+					case <-time.After(tc.toolDuration):
+						return "done", nil
+					case <-ctx.Done():
+						return "", ctx.Err()
+					}
+				},
+			}
+			client := NewClientWithOptions(option.WithBaseURL(server.URL), option.WithAPIKey("test"))
+			a := NewAgent(client, &Profile{Model: "claude", MaxTokens: 100, Tools: []ToolDefinition{work}}, &oneShotFrontend{})
+			a.SetLimits(tc.limits)
+
+			err := a.Run(context.Background(), "work forever")
+			if !errors.Is(err, ErrBudgetExceeded) {
+				t.Errorf("Expected ErrBudgetExceeded, got %v", err)
+			}
+			if a.FinalAnswer() != "Summary" {
+				t.Errorf("Expected the summary as the final answer, got %q", a.FinalAnswer())
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(requests) != tc.wantRequests {
+				t.Errorf("Expected %d requests, got %d", tc.wantRequests, len(requests))
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"tiny-trae/internal/ignore"
+)
+
+const (
+	// MaxMentionBytes caps the content of a file mentioned as @path.
+	MaxMentionBytes = 64 << 10
+	// maxMentionEntries caps the listing of a directory mentioned as @path.
+	maxMentionEntries = 200
+)
+
+// FileReferences returns the paths of the files and directories mentioned
+// as @path in text, such as "why does @main.go not build?", leaving out
+// images, which ImageReferences covers. Only paths that exist count, so
+// "@alice" naming a person is left alone.
+func FileReferences(text string) []string {
+	var paths []string
+	for _, word := range strings.Fields(text) {
+		path, ok := strings.CutPrefix(word, "@")
+		if !ok {
+			continue
+		}
+		path = strings.TrimRight(path, `,;:!?)"'`)
+		// A sentence may end right after the path
+		if _, err := os.Stat(path); err != nil {
+			path = strings.TrimRight(path, ".")
+		}
+		if path == "" || IsImagePath(path) || slices.Contains(paths, path) {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// mentionContent returns what a user message gets for a mentioned path:
+// the file's contents with line numbers, or the directory's listing.
+func mentionContent(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return directoryListing(path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return "", fmt.Errorf("%s is a binary file", path)
+	}
+	truncated := len(data) > MaxMentionBytes
+	if truncated {
+		data = data[:MaxMentionBytes]
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "<file path=%q>\n", path)
+	for i, line := range strings.SplitAfter(string(data), "\n") {
+		if line == "" {
+			break
+		}
+		fmt.Fprintf(&b, "%6d\t%s", i+1, strings.TrimSuffix(line, "\n")+"\n")
+	}
+	if truncated {
+		fmt.Fprintf(&b, "[file truncated: showing %d of %d bytes]\n", MaxMentionBytes, info.Size())
+	}
+	b.WriteString("</file>")
+	return b.String(), nil
+}
+
+// directoryListing lists the files under dir that are not ignored.
+func directoryListing(dir string) (string, error) {
+	var entries []string
+	more := false
+	err := ignore.Walk(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		if len(entries) == maxMentionEntries {
+			more = true
+			return fs.SkipAll
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			rel += "/"
+		}
+		entries = append(entries, rel)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<directory path=%q>\n", dir)
+	for _, entry := range entries {
+		b.WriteString(entry + "\n")
+	}
+	if more {
+		fmt.Fprintf(&b, "[listing truncated at %d entries]\n", maxMentionEntries)
+	}
+	b.WriteString("</directory>")
+	return b.String(), nil
+}
@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFileReferences(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.Mkdir("src", 0o755)
+	os.WriteFile("src/main.go", []byte("package main\n"), 0o644)
+	os.WriteFile("notes.txt", []byte("notes\n"), 0o644)
+
+	tests := map[string][]string{
+		"why does @src/main.go not build?":    {"src/main.go"},
+		"summarize @notes.txt.":               {"notes.txt"},
+		"look around @src, then @notes.txt":   {"src", "notes.txt"},
+		"ask @alice about @shot.png":          nil,
+		"email me@example.com about @missing": nil,
+		"@notes.txt again: @notes.txt":        {"notes.txt"},
+	}
+	for text, want := range tests {
+		if got := FileReferences(text); !reflect.DeepEqual(got, want) {
+			t.Errorf("FileReferences(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestUserContentAttachesFiles(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.Mkdir("src", 0o755)
+	os.WriteFile("src/main.go", []byte("package main\n\nfunc main() {}\n"), 0o644)
+	os.WriteFile("src/blob.bin", []byte("\x00\x01"), 0o644)
+	os.WriteFile("big.txt", []byte(strings.Repeat("x", MaxMentionBytes+10)), 0o644)
+
+	blocks, errs := userContent("check @src/main.go and @src, @big.txt and @src/blob.bin")
+	if len(blocks) != 4 {
+		t.Fatalf("Expected text and three attachments, got %+v", blocks)
+	}
+	if got := blocks[1].OfText.Text; got != "<file path=\"src/main.go\">\n     1\tpackage main\n     2\t\n     3\tfunc main() {}\n</file>" {
+		t.Errorf("Unexpected file block %q", got)
+	}
+	if got := blocks[2].OfText.Text; got != "<directory path=\"src\">\nblob.bin\nmain.go\n</directory>" {
+		t.Errorf("Unexpected directory block %q", got)
+	}
+	if got := blocks[3].OfText.Text; !strings.Contains(got, "[file truncated") {
+		t.Errorf("Expected the big file to be truncated, got %d bytes", len(got))
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "binary") {
+		t.Errorf("Expected an error for the binary file, got %v", errs)
+	}
+}
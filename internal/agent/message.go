@@ -12,6 +12,24 @@ const (
 	MessageTypeToolResult   MessageType = "tool_result"
 	MessageTypeError        MessageType = "error"
 	MessageTypeSystemInfo   MessageType = "system_info"
+
+	// MessageTypeAssistantDelta carries the next piece of an assistant reply
+	// while it is streamed. The whole text follows as MessageTypeAssistant,
+	// so frontends that do not render replies live can ignore it.
+	MessageTypeAssistantDelta MessageType = "assistant_delta"
+	// MessageTypeUsage reports the session's token usage, with UsageData,
+	// at the start and after each response.
+	MessageTypeUsage MessageType = "usage"
+	// MessageTypeRequest is sent, with RequestData, when a request to the
+	// model starts.
+	MessageTypeRequest MessageType = "request"
+	// MessageTypeThinking carries the model's extended thinking before its
+	// reply, when the profile turns thinking on.
+	MessageTypeThinking MessageType = "thinking"
+	// MessageTypeToolOutput carries output of a running tool, with
+	// ToolOutputData, as the tool reports it; the whole output still
+	// follows in the MessageTypeToolResult.
+	MessageTypeToolOutput MessageType = "tool_output"
 )
 
 // Message represents a message sent from the agent core to the frontend
@@ -26,6 +44,8 @@ type ToolCallData struct {
 	ToolName string          `json:"tool_name"`
 	ToolID   string          `json:"tool_id"`
 	Input    json.RawMessage `json:"input"`
+	// Preview is what the tool's Preview shows for the input, if it has one.
+	Preview string `json:"preview,omitempty"`
 }
 
 // ToolResultData represents additional data for tool result messages
@@ -34,6 +54,37 @@ type ToolResultData struct {
 	ToolID   string `json:"tool_id"`
 	Result   string `json:"result"`
 	IsError  bool   `json:"is_error"`
+	// Meta describes the result; it is nil for calls that did not run.
+	Meta *ToolResultMeta `json:"meta,omitempty"`
+}
+
+// ToolOutputData represents additional data for tool output messages
+type ToolOutputData struct {
+	ToolName string `json:"tool_name"`
+	ToolID   string `json:"tool_id"`
+}
+
+// RequestData represents additional data for request messages
+type RequestData struct {
+	// Tokens estimates the size of the request.
+	Tokens int64 `json:"tokens"`
+}
+
+// ApprovalDecision is the user's answer to an approval request
+type ApprovalDecision string
+
+const (
+	ApprovalApprove     ApprovalDecision = "approve"
+	ApprovalDeny        ApprovalDecision = "deny"
+	ApprovalAlwaysAllow ApprovalDecision = "always_allow"
+)
+
+// ApprovalRequest describes a tool call that needs the user's approval before it runs
+type ApprovalRequest struct {
+	ToolName string          `json:"tool_name"`
+	ToolID   string          `json:"tool_id"`
+	Input    json.RawMessage `json:"input"`
+	Preview  string          `json:"preview"`
 }
 
 // Frontend represents the interface that any frontend implementation must satisfy
@@ -42,6 +93,15 @@ type Frontend interface {
 	SendMessage(msg Message)
 	// GetUserInput requests user input from the frontend
 	GetUserInput() (string, bool)
+	// RequestApproval asks the user whether a dangerous tool call may run
+	RequestApproval(req ApprovalRequest) ApprovalDecision
+	// Interrupts delivers a value each time the user asks to cancel the running
+	// tool. Frontends without that ability may return nil.
+	Interrupts() <-chan struct{}
+	// TurnCancels delivers a value each time the user asks to stop the whole
+	// turn: the model's reply and any running tools. Frontends without that
+	// ability may return nil.
+	TurnCancels() <-chan struct{}
 	// IsInteractive returns whether the frontend is in interactive mode
 	IsInteractive() bool
 	// Close closes the frontend
@@ -0,0 +1,227 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"tiny-trae/internal/permission"
+)
+
+// MaxToolResultBytes caps the text of a tool result sent to the model. Tools
+// with their own, smaller limits explain them in their results.
+const MaxToolResultBytes = 256 << 10
+
+// ToolCall is a tool call on its way through the middleware chain.
+type ToolCall struct {
+	ID    string
+	Tool  ToolDefinition
+	Input json.RawMessage
+	// Approval records how the call was allowed to run, for the audit log:
+	// "not_required", "policy_allow", "auto_approved", or the user's
+	// ApprovalDecision.
+	Approval string
+	// Duration is how long the tool ran, set by Timing.
+	Duration time.Duration
+}
+
+// ToolFunc runs a tool call and returns its text result.
+type ToolFunc func(ctx context.Context, call *ToolCall) (string, error)
+
+// ToolMiddleware wraps a ToolFunc to add behavior around every tool call,
+// such as checking, timing, or rewriting results.
+type ToolMiddleware func(next ToolFunc) ToolFunc
+
+// Chain composes middlewares around fn. The first middleware is the
+// outermost: it sees the call first and the result last.
+func Chain(fn ToolFunc, middlewares ...ToolMiddleware) ToolFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		fn = middlewares[i](fn)
+	}
+	return fn
+}
+
+// ToolDeniedError is returned by a middleware that refuses a call before the
+// tool runs. The call is recorded as denied in the audit log.
+type ToolDeniedError struct {
+	Reason string
+}
+
+func (e *ToolDeniedError) Error() string {
+	return e.Reason
+}
+
+// Use adds middlewares to every tool call. They run after the call has been
+// approved and see results after secrets are redacted and long output is
+// truncated.
+func (a *Agent) Use(middlewares ...ToolMiddleware) {
+	a.middlewares = append(a.middlewares, middlewares...)
+}
+
+// toolChain returns the tool function wrapped in the built-in middlewares and
+// those added with Use.
+func (a *Agent) toolChain() ToolFunc {
+	middlewares := []ToolMiddleware{a.authorize, a.limit, a.announce}
+	middlewares = append(middlewares, a.middlewares...)
+	middlewares = append(middlewares, Timing, TruncateOutput(MaxToolResultBytes), RedactSecrets, a.enforceTimeout)
+	return Chain(a.runTool, middlewares...)
+}
+
+// authorize refuses calls to disabled tools and applies the permission
+// policy, asking the user when the policy or the tool requires it.
+func (a *Agent) authorize(next ToolFunc) ToolFunc {
+	return func(ctx context.Context, call *ToolCall) (string, error) {
+		if err := a.authorizeCall(ctx, call); err != nil {
+			return "", err
+		}
+		return next(ctx, call)
+	}
+}
+
+// authorizeCall decides whether call may run. Parallel calls are decided one
+// at a time, in the order the model made them.
+func (a *Agent) authorizeCall(ctx context.Context, call *ToolCall) error {
+	done := awaitApprovalTurn(ctx)
+	defer done()
+	if ctx.Err() != nil {
+		return errToolInterrupted
+	}
+
+	name := call.Tool.Name
+	if a.disabledTools[name] {
+		call.Approval = "disabled"
+		return &ToolDeniedError{Reason: fmt.Sprintf("tool %s was disabled by the user", name)}
+	}
+
+	decision := a.policy.Evaluate(name, call.Input)
+	switch decision.Action {
+	case permission.ActionDeny:
+		call.Approval = "policy_deny"
+		return &ToolDeniedError{Reason: fmt.Sprintf("tool call denied by permission policy (rule: %s)", decision.Rule)}
+	case permission.ActionAllow:
+		call.Approval = "policy_allow"
+	}
+
+	needsApproval := decision.Action == permission.ActionAsk ||
+		(decision.Action == permission.ActionDefault && call.Tool.requiresApproval(call.Input))
+	if needsApproval && a.autoApprove != nil && a.autoApprove(call.Tool) {
+		call.Approval = "auto_approved"
+		return nil
+	}
+	if needsApproval {
+		approvalDecision := a.requestApproval(call.Tool, call.ID, call.Input)
+		call.Approval = string(approvalDecision)
+		if approvalDecision == ApprovalDeny {
+			if !a.frontend.IsInteractive() {
+				return &ToolDeniedError{Reason: fmt.Sprintf("tool call denied: %s needs approval, and nobody can approve it in a non-interactive run. Do not retry it; do without it, or say what you needed it for", name)}
+			}
+			return &ToolDeniedError{Reason: "tool call denied by user"}
+		}
+	}
+	return nil
+}
+
+// requiresApproval reports whether a call of the tool with input needs the
+// user's approval by default.
+func (t ToolDefinition) requiresApproval(input json.RawMessage) bool {
+	if t.ApprovalFor != nil {
+		return t.ApprovalFor(input)
+	}
+	return t.RequiresApproval
+}
+
+// announce checkpoints the workspace before tools that change files and tells
+// the frontend the tool is running.
+func (a *Agent) announce(next ToolFunc) ToolFunc {
+	return func(ctx context.Context, call *ToolCall) (string, error) {
+		if call.Tool.MutatesFiles {
+			a.checkpointTurn(ctx)
+		}
+
+		content := fmt.Sprintf("Executing tool: %s", call.Tool.Name)
+		toolData := ToolCallData{
+			ToolName: call.Tool.Name,
+			ToolID:   call.ID,
+			Input:    call.Input,
+		}
+		if call.Tool.Preview != nil {
+			toolData.Preview = call.Tool.Preview(call.Input)
+		}
+		data, err := json.Marshal(toolData)
+		if err != nil {
+			// Fallback to sending message without data if marshaling fails
+			a.frontend.SendMessage(Message{Type: MessageTypeToolCall, Content: content})
+		} else {
+			a.frontend.SendMessage(Message{Type: MessageTypeToolCall, Content: content, Data: data})
+		}
+		return next(ctx, call)
+	}
+}
+
+// Timing records how long the rest of the chain took in call.Duration.
+func Timing(next ToolFunc) ToolFunc {
+	return func(ctx context.Context, call *ToolCall) (string, error) {
+		start := time.Now()
+		result, err := next(ctx, call)
+		call.Duration = time.Since(start)
+		return result, err
+	}
+}
+
+// TruncateOutput cuts results longer than max bytes, says how much was
+// dropped, and marks the result truncated.
+func TruncateOutput(max int) ToolMiddleware {
+	return func(next ToolFunc) ToolFunc {
+		return func(ctx context.Context, call *ToolCall) (string, error) {
+			result, err := next(ctx, call)
+			if len(result) <= max {
+				return result, err
+			}
+			cut := max
+			for cut > 0 && !utf8.RuneStart(result[cut]) {
+				cut--
+			}
+			ReportTruncated(ctx)
+			return fmt.Sprintf("%s\n[result truncated: showing %d of %d bytes]", result[:cut], cut, len(result)), err
+		}
+	}
+}
+
+// secretPatterns match credentials that should never reach the model. The
+// first group of each is kept so the redacted text still shows what kind of
+// secret was there.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(-----BEGIN [A-Z ]*PRIVATE KEY-----)[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	regexp.MustCompile(`\b(sk-)[A-Za-z0-9_-]{20,}`),
+	regexp.MustCompile(`\b(gh[pousr]_)[A-Za-z0-9]{36,}`),
+	regexp.MustCompile(`\b(github_pat_)[A-Za-z0-9_]{22,}`),
+	regexp.MustCompile(`\b((?:AKIA|ASIA))[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`\b(xox[abprs]-)[A-Za-z0-9-]{10,}`),
+	regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9._~+/-]{20,}=*`),
+}
+
+// redact replaces the secrets in text.
+func redact(text string) string {
+	for _, pattern := range secretPatterns {
+		text = pattern.ReplaceAllString(text, "${1}[REDACTED]")
+	}
+	return text
+}
+
+// RedactSecrets replaces API keys, access tokens, and private keys in results
+// and error messages with [REDACTED].
+func RedactSecrets(next ToolFunc) ToolFunc {
+	return func(ctx context.Context, call *ToolCall) (string, error) {
+		result, err := next(ctx, call)
+		if err != nil {
+			if message := redact(err.Error()); message != err.Error() {
+				err = errors.New(message)
+			}
+		}
+		return redact(result), err
+	}
+}
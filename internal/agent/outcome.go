@@ -0,0 +1,14 @@
+package agent
+
+import "errors"
+
+// ErrBudgetExceeded is returned by a non-interactive Run that stopped
+// because it ran out of a budget, such as the response's token limit,
+// rather than because the model finished.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// FinalAnswer returns the text of the model's latest response, which is
+// its answer once a non-interactive Run has returned.
+func (a *Agent) FinalAnswer() string {
+	return a.finalAnswer
+}
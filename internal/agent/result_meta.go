@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ToolResultMeta describes a tool result beyond its text, so frontends can
+// summarize it and callers can act on it without parsing the text.
+type ToolResultMeta struct {
+	// Bytes is the length of the result text.
+	Bytes int `json:"bytes"`
+	// DurationMs is how long the tool ran.
+	DurationMs int64 `json:"duration_ms"`
+	// Truncated reports whether output was cut, by the tool or by the
+	// agent's result size limit.
+	Truncated bool `json:"truncated,omitempty"`
+	// ExitCode is the exit status of the command a tool ran, if any.
+	ExitCode *int `json:"exit_code,omitempty"`
+	// FilesChanged lists the files the tool wrote.
+	FilesChanged []string `json:"files_changed,omitempty"`
+}
+
+// Summary returns a short description such as "exit 1, 2 files changed,
+// 1.2s, 3.4 KiB, truncated".
+func (m ToolResultMeta) Summary() string {
+	var parts []string
+	if m.ExitCode != nil {
+		parts = append(parts, fmt.Sprintf("exit %d", *m.ExitCode))
+	}
+	switch len(m.FilesChanged) {
+	case 0:
+	case 1:
+		parts = append(parts, "changed "+m.FilesChanged[0])
+	default:
+		parts = append(parts, fmt.Sprintf("%d files changed", len(m.FilesChanged)))
+	}
+	parts = append(parts, (time.Duration(m.DurationMs) * time.Millisecond).String(), formatBytes(m.Bytes))
+	if m.Truncated {
+		parts = append(parts, "truncated")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// line returns the metadata as the line the model sees after a tool's
+// result, such as [result metadata: {"bytes":12,"duration_ms":840,"exit_code":1}].
+func (m ToolResultMeta) line() string {
+	data, _ := json.Marshal(m)
+	return "[result metadata: " + string(data) + "]"
+}
+
+// formatBytes formats n as B, KiB, or MiB.
+func formatBytes(n int) string {
+	switch {
+	case n < 1<<10:
+		return fmt.Sprintf("%d B", n)
+	case n < 1<<20:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	}
+}
+
+// resultReport collects the metadata a running tool reports about its
+// result. A tool still running after an interrupt may report while the
+// agent reads it, hence the lock.
+type resultReport struct {
+	mu   sync.Mutex
+	meta ToolResultMeta
+}
+
+// snapshot returns a copy of the reported metadata.
+func (r *resultReport) snapshot() ToolResultMeta {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	meta := r.meta
+	meta.FilesChanged = slices.Clone(meta.FilesChanged)
+	return meta
+}
+
+// resultReportKey is the context key for the running tool's result report.
+type resultReportKey struct{}
+
+// report applies update to the running tool's result report. It reports
+// false outside a tool call.
+func report(ctx context.Context, update func(*ToolResultMeta)) bool {
+	r, ok := ctx.Value(resultReportKey{}).(*resultReport)
+	if !ok {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	update(&r.meta)
+	return true
+}
+
+// ReportExitCode records the exit status of a command the running tool ran.
+// If it runs several, the first non-zero status is kept. It reports false
+// outside a tool call.
+func ReportExitCode(ctx context.Context, code int) bool {
+	return report(ctx, func(m *ToolResultMeta) {
+		if m.ExitCode == nil || *m.ExitCode == 0 {
+			m.ExitCode = &code
+		}
+	})
+}
+
+// ReportFilesChanged records files the running tool wrote. It reports false
+// outside a tool call.
+func ReportFilesChanged(ctx context.Context, paths ...string) bool {
+	return report(ctx, func(m *ToolResultMeta) {
+		for _, path := range paths {
+			if !slices.Contains(m.FilesChanged, path) {
+				m.FilesChanged = append(m.FilesChanged, path)
+			}
+		}
+	})
+}
+
+// ReportTruncated records that the running tool cut its output. It reports
+// false outside a tool call.
+func ReportTruncated(ctx context.Context) bool {
+	return report(ctx, func(m *ToolResultMeta) { m.Truncated = true })
+}
@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+func TestToolResultMetaSummary(t *testing.T) {
+	code := 2
+	for _, tc := range []struct {
+		meta ToolResultMeta
+		want string
+	}{
+		{ToolResultMeta{Bytes: 12, DurationMs: 5}, "5ms, 12 B"},
+		{ToolResultMeta{Bytes: 3 << 10, DurationMs: 1500, ExitCode: &code, Truncated: true}, "exit 2, 1.5s, 3.0 KiB, truncated"},
+		{ToolResultMeta{FilesChanged: []string{"main.go"}}, "changed main.go, 0s, 0 B"},
+		{ToolResultMeta{FilesChanged: []string{"a.go", "b.go"}, Bytes: 2 << 20}, "2 files changed, 0s, 2.0 MiB"},
+	} {
+		if got := tc.meta.Summary(); got != tc.want {
+			t.Errorf("Summary() = %q, want %q", got, tc.want)
+		}
+	}
+}
+
+func TestCallToolReportsMeta(t *testing.T) {
+	profile := &Profile{Tools: []ToolDefinition{
+		{
+			Name: "make",
+			Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+				ReportExitCode(ctx, 0)
+				ReportExitCode(ctx, 2)
+				ReportExitCode(ctx, 0)
+				ReportFilesChanged(ctx, "a.go", "b.go", "a.go")
+				return "", errors.New("exit status 2")
+			},
+		},
+		{
+			Name: "dump",
+			Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+				return strings.Repeat("x", MaxToolResultBytes+1), nil
+			},
+		},
+	}}
+	frontend := &recordingFrontend{}
+	a := NewAgent(anthropic.Client{}, profile, frontend)
+
+	result := a.CallTool(context.Background(), "id", "make", json.RawMessage(`{}`))
+	if !result.IsError || result.Meta.ExitCode == nil || *result.Meta.ExitCode != 2 {
+		t.Errorf("Expected the first failing exit code, got %+v", result)
+	}
+	if got := strings.Join(result.Meta.FilesChanged, ","); got != "a.go,b.go" {
+		t.Errorf("Unexpected files changed %q", got)
+	}
+
+	result = a.CallTool(context.Background(), "id2", "dump", json.RawMessage(`{}`))
+	if !result.Meta.Truncated || result.Meta.Bytes != len(result.Text) {
+		t.Errorf("Expected a truncated result, got %+v", result.Meta)
+	}
+
+	// The metadata reaches the frontend with the result
+	var data ToolResultData
+	if err := json.Unmarshal(frontend.messages[len(frontend.messages)-1].Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Meta == nil || !data.Meta.Truncated {
+		t.Errorf("Expected metadata in the tool result message, got %+v", data)
+	}
+
+	// And the model, after the result text
+	text := a.executeTool(context.Background(), "id3", "make", json.RawMessage(`{}`)).OfToolResult.Content[0].OfText.Text
+	if !strings.HasSuffix(text, `"exit_code":2,"files_changed":["a.go","b.go"]}]`) {
+		t.Errorf("Expected metadata after the result, got %q", text)
+	}
+	text = a.executeTool(context.Background(), "id4", "missing", json.RawMessage(`{}`)).OfToolResult.Content[0].OfText.Text
+	if text != "tool not found" {
+		t.Errorf("Expected no metadata for a call that did not run, got %q", text)
+	}
+
+	if ReportTruncated(context.Background()) {
+		t.Error("Expected reporting outside a tool call to fail")
+	}
+}
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"tiny-trae/internal/semantic"
+)
+
+const (
+	// DefaultRetrievalMaxTokens caps the retrieved snippets when the
+	// profile's Retrieval sets no MaxTokens.
+	DefaultRetrievalMaxTokens = 2000
+	// retrievalTimeout bounds embedding a message, so that a slow embedding
+	// service does not hold up the turn for long.
+	retrievalTimeout = 10 * time.Second
+)
+
+// Retrieval configures adding the indexed code most relevant to each user
+// message to it, from the index 'tiny-trae index' builds.
+type Retrieval struct {
+	// TopK is how many snippets are added at most; zero turns retrieval
+	// off.
+	TopK int
+	// MaxTokens caps the snippets' combined size; zero means
+	// DefaultRetrievalMaxTokens.
+	MaxTokens int
+}
+
+// relevantCode returns the snippets relevant to the user's input as a
+// <relevant_code> block, or "" if retrieval is off or finds nothing. If the
+// index cannot be loaded, retrieval is turned off for the session; if one
+// message cannot be embedded, it goes without snippets. Either is reported
+// to the user.
+func (a *Agent) relevantCode(ctx context.Context, input string) string {
+	settings := a.profile.Retrieval
+	if settings.TopK <= 0 || a.retrievalFailed {
+		return ""
+	}
+	if a.retriever == nil {
+		retriever, err := semantic.NewRetriever(".")
+		if err != nil {
+			a.retrievalFailed = true
+			a.frontend.SendMessage(Message{Type: MessageTypeSystemInfo, Content: fmt.Sprintf("Retrieval of relevant code is off for this session: %v", err)})
+			return ""
+		}
+		a.retriever = retriever
+	}
+
+	maxTokens := settings.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = DefaultRetrievalMaxTokens
+	}
+	ctx, cancel := context.WithTimeout(ctx, retrievalTimeout)
+	defer cancel()
+	snippets, err := a.retriever.Retrieve(ctx, input, settings.TopK, 4*maxTokens)
+	if err != nil {
+		a.frontend.SendMessage(Message{Type: MessageTypeSystemInfo, Content: fmt.Sprintf("Failed to retrieve relevant code: %v", err)})
+		return ""
+	}
+	if len(snippets) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	var ranges []string
+	b.WriteString("This code from the repository may be relevant to the message, as found by searching the project's index; it may be incomplete, so read the files before editing them.\n<relevant_code>\n")
+	for _, s := range snippets {
+		r := fmt.Sprintf("%s:%d-%d", s.Path, s.StartLine, s.EndLine)
+		ranges = append(ranges, r)
+		fmt.Fprintf(&b, "<snippet path=%q lines=\"%d-%d\">\n%s</snippet>\n", s.Path, s.StartLine, s.EndLine, s.Text)
+	}
+	b.WriteString("</relevant_code>")
+	a.frontend.SendMessage(Message{Type: MessageTypeSystemInfo, Content: "Added relevant code: " + strings.Join(ranges, ", ")})
+	return b.String()
+}
@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"tiny-trae/internal/semantic"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+func TestRelevantCode(t *testing.T) {
+	t.Chdir(t.TempDir())
+	frontend := &recordingFrontend{}
+	a := NewAgent(anthropic.Client{}, &Profile{Retrieval: Retrieval{TopK: 2}}, frontend)
+
+	// Without an index, retrieval is turned off once
+	if code := a.relevantCode(context.Background(), "where is the password checked?"); code != "" || !a.retrievalFailed {
+		t.Fatalf("Expected retrieval to fail without an index, got %q", code)
+	}
+	a.relevantCode(context.Background(), "again")
+	if len(frontend.messages) != 1 || !strings.Contains(frontend.messages[0].Content, "off for this session") {
+		t.Errorf("Expected one message about retrieval being off, got %+v", frontend.messages)
+	}
+
+	if err := os.WriteFile("login.go", []byte("package auth\n\n// checkPassword verifies the user's password hash.\nfunc checkPassword(user, password string) bool { return false }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	index, err := semantic.Build(context.Background(), ".", semantic.LocalEmbedder{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Save("."); err != nil {
+		t.Fatal(err)
+	}
+	frontend = &recordingFrontend{}
+	a = NewAgent(anthropic.Client{}, &Profile{Retrieval: Retrieval{TopK: 2}}, frontend)
+
+	message := a.userMessage(context.Background(), "where is the password checked?")
+	if len(message.Content) != 2 || message.Content[1].OfText.Text != "where is the password checked?" {
+		t.Fatalf("Expected the relevant code before the message, got %+v", message.Content)
+	}
+	code := message.Content[0].OfText.Text
+	if !strings.Contains(code, "<snippet path=\"login.go\" lines=\"1-4\">\n     1\tpackage auth\n") {
+		t.Errorf("Unexpected relevant code %q", code)
+	}
+	if len(frontend.messages) != 1 || frontend.messages[0].Content != "Added relevant code: login.go:1-4" {
+		t.Errorf("Expected the user to be told about the code, got %+v", frontend.messages)
+	}
+
+	// Retrieval is off unless the profile turns it on
+	a = NewAgent(anthropic.Client{}, &Profile{}, &recordingFrontend{})
+	if code := a.relevantCode(context.Background(), "where is the password checked?"); code != "" {
+		t.Errorf("Expected no retrieval, got %q", code)
+	}
+}
@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"tiny-trae/internal/audit"
+)
+
+// StatsCommand shows how often each tool was called this session.
+const StatsCommand = "/stats"
+
+// ToolStats summarizes the calls of one tool.
+type ToolStats struct {
+	Calls int
+	// Failures counts calls that returned an error or were interrupted.
+	Failures int
+	// Denied counts calls refused by the user, the permission policy, or
+	// /tools; they are included in Calls.
+	Denied int
+	// Duration is the total time the tool ran.
+	Duration time.Duration
+}
+
+// toolStatsRecorder collects ToolStats per tool name. Parallel calls record
+// at the same time, hence the lock.
+type toolStatsRecorder struct {
+	mu    sync.Mutex
+	stats map[string]ToolStats
+}
+
+// record adds a call with the given audit status.
+func (r *toolStatsRecorder) record(name, status string, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stats == nil {
+		r.stats = make(map[string]ToolStats)
+	}
+	stats := r.stats[name]
+	stats.Calls++
+	switch status {
+	case audit.StatusDenied:
+		stats.Denied++
+	case audit.StatusError, audit.StatusInterrupted:
+		stats.Failures++
+	}
+	stats.Duration += duration
+	r.stats[name] = stats
+}
+
+// ToolStats returns the usage of each tool called this session, by name.
+func (a *Agent) ToolStats() map[string]ToolStats {
+	a.toolStats.mu.Lock()
+	defer a.toolStats.mu.Unlock()
+	stats := make(map[string]ToolStats, len(a.toolStats.stats))
+	for name, s := range a.toolStats.stats {
+		stats[name] = s
+	}
+	return stats
+}
+
+// statsReport describes the session's tool usage, busiest tools first.
+func (a *Agent) statsReport() string {
+	stats := a.ToolStats()
+	if len(stats) == 0 {
+		return "No tools have been called yet."
+	}
+
+	names := make([]string, 0, len(stats))
+	var total ToolStats
+	for name, s := range stats {
+		names = append(names, name)
+		total.Calls += s.Calls
+		total.Failures += s.Failures
+		total.Denied += s.Denied
+		total.Duration += s.Duration
+	}
+	slices.SortFunc(names, func(x, y string) int {
+		return cmp.Or(cmp.Compare(stats[y].Calls, stats[x].Calls), strings.Compare(x, y))
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Tool usage: %s\n", formatToolStats(total))
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\t%s\n", name, formatToolStats(stats[name]))
+	}
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// formatToolStats formats stats as "5 calls, 1 failed (20%), 1 denied, 2.9s".
+func formatToolStats(s ToolStats) string {
+	parts := []string{fmt.Sprintf("%d calls", s.Calls)}
+	if s.Calls == 1 {
+		parts[0] = "1 call"
+	}
+	if s.Failures > 0 {
+		parts = append(parts, fmt.Sprintf("%d failed (%d%%)", s.Failures, s.Failures*100/s.Calls))
+	}
+	if s.Denied > 0 {
+		parts = append(parts, fmt.Sprintf("%d denied", s.Denied))
+	}
+	parts = append(parts, s.Duration.Round(time.Millisecond).String())
+	return strings.Join(parts, ", ")
+}
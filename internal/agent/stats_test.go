@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+func TestToolStats(t *testing.T) {
+	ok := func(ctx context.Context, input json.RawMessage) (string, error) { return "ok", nil }
+	fail := func(ctx context.Context, input json.RawMessage) (string, error) { return "", errors.New("boom") }
+	profile := &Profile{Tools: []ToolDefinition{
+		{Name: "read_file", Function: ok},
+		{Name: "bash", Function: fail},
+	}}
+	a := NewAgent(anthropic.Client{}, profile, &recordingFrontend{})
+	if got := a.statsReport(); got != "No tools have been called yet." {
+		t.Errorf("Unexpected empty report %q", got)
+	}
+
+	ctx := context.Background()
+	for range 3 {
+		a.CallTool(ctx, "id", "read_file", json.RawMessage(`{}`))
+	}
+	a.CallTool(ctx, "id", "bash", json.RawMessage(`{}`))
+	a.disabledTools["bash"] = true
+	a.CallTool(ctx, "id", "bash", json.RawMessage(`{}`))
+	a.CallTool(ctx, "id", "missing", json.RawMessage(`{}`))
+
+	stats := a.ToolStats()
+	if len(stats) != 2 {
+		t.Errorf("Expected stats for the two known tools, got %v", stats)
+	}
+	if s := stats["read_file"]; s.Calls != 3 || s.Failures != 0 || s.Denied != 0 {
+		t.Errorf("Unexpected read_file stats %+v", s)
+	}
+	if s := stats["bash"]; s.Calls != 2 || s.Failures != 1 || s.Denied != 1 {
+		t.Errorf("Unexpected bash stats %+v", s)
+	}
+
+	lines := strings.Split(a.statsReport(), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "Tool usage: 5 calls, 1 failed (20%), 1 denied, ") {
+		t.Fatalf("Unexpected report %q", lines)
+	}
+	if !strings.HasPrefix(lines[1], "  read_file  3 calls, ") || !strings.HasPrefix(lines[2], "  bash       2 calls, 1 failed (50%), 1 denied, ") {
+		t.Errorf("Unexpected tool lines %q", lines[1:])
+	}
+}
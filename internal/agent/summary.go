@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// SummaryCommand shows the session's running summary.
+const SummaryCommand = "/summary"
+
+// maxSummaryItems caps each list of the summary, dropping the oldest
+// items, so that it stays small enough to send with every request.
+const maxSummaryItems = 30
+
+// Summary is a running, structured summary of the session. The model keeps
+// Decisions and OpenQuestions up to date with UpdateSummary, and the agent
+// adds the files tools report changing. It is kept apart from the
+// conversation and sent with every request, so it stays in context however
+// the conversation is shortened.
+type Summary struct {
+	Decisions     []string
+	FilesTouched  []string
+	OpenQuestions []string
+}
+
+// Empty reports whether nothing has been recorded.
+func (s Summary) Empty() bool {
+	return len(s.Decisions) == 0 && len(s.FilesTouched) == 0 && len(s.OpenQuestions) == 0
+}
+
+// String returns the summary as a <conversation_summary> block, or "" if it
+// is empty.
+func (s Summary) String() string {
+	if s.Empty() {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("<conversation_summary>\n")
+	for _, section := range []struct {
+		name  string
+		items []string
+	}{
+		{"Decisions made", s.Decisions},
+		{"Files touched", s.FilesTouched},
+		{"Open questions", s.OpenQuestions},
+	} {
+		if len(section.items) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s:\n", section.name)
+		for _, item := range section.items {
+			fmt.Fprintf(&b, "- %s\n", item)
+		}
+	}
+	b.WriteString("</conversation_summary>")
+	return b.String()
+}
+
+// summaryStore holds the session's summary. Tools running in parallel may
+// update it at the same time, hence the lock.
+type summaryStore struct {
+	mu      sync.Mutex
+	summary Summary
+}
+
+// update applies f to the summary and trims its lists.
+func (s *summaryStore) update(f func(*Summary)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(&s.summary)
+	for _, items := range []*[]string{&s.summary.Decisions, &s.summary.FilesTouched, &s.summary.OpenQuestions} {
+		if len(*items) > maxSummaryItems {
+			*items = (*items)[len(*items)-maxSummaryItems:]
+		}
+	}
+}
+
+// get returns a copy of the summary.
+func (s *summaryStore) get() Summary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Summary{
+		Decisions:     slices.Clone(s.summary.Decisions),
+		FilesTouched:  slices.Clone(s.summary.FilesTouched),
+		OpenQuestions: slices.Clone(s.summary.OpenQuestions),
+	}
+}
+
+// addFiles records files a tool changed.
+func (s *summaryStore) addFiles(paths []string) {
+	s.update(func(summary *Summary) {
+		for _, path := range paths {
+			if !slices.Contains(summary.FilesTouched, path) {
+				summary.FilesTouched = append(summary.FilesTouched, path)
+			}
+		}
+	})
+}
+
+// summaryKey is the context key for the agent's summary store.
+type summaryKey struct{}
+
+// UpdateSummary applies update to the session's running summary, for tools
+// that maintain it. It reports false outside a tool call.
+func UpdateSummary(ctx context.Context, update func(*Summary)) bool {
+	store, ok := ctx.Value(summaryKey{}).(*summaryStore)
+	if !ok {
+		return false
+	}
+	store.update(update)
+	return true
+}
+
+// Summary returns the session's running summary.
+func (a *Agent) Summary() Summary {
+	return a.summary.get()
+}
+
+// summaryPrompt returns the system prompt block carrying the summary, or
+// "" if it is empty.
+func (a *Agent) summaryPrompt() string {
+	text := a.summary.get().String()
+	if text == "" {
+		return ""
+	}
+	return "This is the running summary of the session so far, which you keep up to date with the session_summary tool:\n" + text
+}
+
+// summaryReport describes the summary for SummaryCommand.
+func (a *Agent) summaryReport() string {
+	text := a.summary.get().String()
+	if text == "" {
+		return "Nothing has been recorded in the summary yet."
+	}
+	return text
+}
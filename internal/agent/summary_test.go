@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+func TestSummary(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[],"usage":{"input_tokens":1,"output_tokens":1}}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":1}}`,
+		`{"type":"message_stop"}`,
+	}
+	var request struct {
+		System []struct{ Text string } `json:"system"`
+	}
+	server := streamServer(events, func(body []byte) { json.Unmarshal(body, &request) })
+	defer server.Close()
+
+	profile := &Profile{Model: "claude", MaxTokens: 100, SystemPrompt: "Be brief.", Tools: []ToolDefinition{
+		{Name: "decide", Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+			UpdateSummary(ctx, func(s *Summary) {
+				s.Decisions = append(s.Decisions, "Use SQLite")
+				s.OpenQuestions = []string{"Which schema version?"}
+			})
+			return "ok", nil
+		}},
+		{Name: "edit", Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+			ReportFilesChanged(ctx, "db.go")
+			return "ok", nil
+		}},
+	}}
+	client := NewClientWithOptions(option.WithBaseURL(server.URL), option.WithAPIKey("test"))
+	frontend := &recordingFrontend{}
+	a := NewAgent(client, profile, frontend)
+
+	// An empty summary is left out of requests
+	if _, err := a.runInference(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(request.System) != 1 {
+		t.Errorf("Expected only the system prompt, got %+v", request.System)
+	}
+	if a.summaryReport() != "Nothing has been recorded in the summary yet." {
+		t.Errorf("Unexpected report %q", a.summaryReport())
+	}
+
+	a.executeTool(context.Background(), "1", "decide", json.RawMessage(`{}`))
+	a.executeTool(context.Background(), "2", "edit", json.RawMessage(`{}`))
+	a.executeTool(context.Background(), "3", "edit", json.RawMessage(`{}`))
+	want := "<conversation_summary>\nDecisions made:\n- Use SQLite\nFiles touched:\n- db.go\nOpen questions:\n- Which schema version?\n</conversation_summary>"
+	if got := a.Summary().String(); got != want {
+		t.Errorf("Expected summary %q, got %q", want, got)
+	}
+
+	if _, err := a.runInference(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(request.System) != 2 || request.System[0].Text != "Be brief." || !strings.HasSuffix(request.System[1].Text, want) {
+		t.Errorf("Expected the summary after the system prompt, got %+v", request.System)
+	}
+
+	if UpdateSummary(context.Background(), func(*Summary) {}) {
+		t.Error("Expected no summary outside a tool call")
+	}
+}
+
+func TestSummaryKeepsRecentItems(t *testing.T) {
+	a := NewAgent(anthropic.Client{}, &Profile{}, &recordingFrontend{})
+	for i := range maxSummaryItems + 5 {
+		a.summary.addFiles([]string{fmt.Sprintf("file%d.go", i)})
+	}
+	files := a.Summary().FilesTouched
+	if len(files) != maxSummaryItems || files[0] != "file5.go" {
+		t.Errorf("Expected the %d most recent files, got %v", maxSummaryItems, files)
+	}
+}
@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultToolTimeout bounds every tool call unless the tool or the profile
+// sets another timeout, so a hung tool cannot stall the agent loop.
+const DefaultToolTimeout = 10 * time.Minute
+
+// ToolTimeoutError is the error of a tool call that ran out of time.
+type ToolTimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *ToolTimeoutError) Error() string {
+	return fmt.Sprintf("tool timed out after %s", e.Timeout)
+}
+
+// toolTimeout returns how long a call of tool may run, or 0 for no limit.
+// The profile's "timeout" option for the tool comes first, then the tool's
+// own Timeout, then the profile's ToolTimeout, then DefaultToolTimeout. A
+// negative value at any level means no limit.
+func (a *Agent) toolTimeout(tool ToolDefinition) time.Duration {
+	timeout := cmp.Or(
+		a.profile.ToolOptions[tool.Name].Duration("timeout", 0),
+		tool.Timeout,
+		a.profile.ToolTimeout,
+		DefaultToolTimeout,
+	)
+	return max(timeout, 0)
+}
+
+// enforceTimeout cancels the call's context once its timeout passes; runTool
+// then reports a ToolTimeoutError.
+func (a *Agent) enforceTimeout(next ToolFunc) ToolFunc {
+	return func(ctx context.Context, call *ToolCall) (string, error) {
+		timeout := a.toolTimeout(call.Tool)
+		if timeout == 0 {
+			return next(ctx, call)
+		}
+		ctx, cancel := context.WithTimeoutCause(ctx, timeout, &ToolTimeoutError{Timeout: timeout})
+		defer cancel()
+		return next(ctx, call)
+	}
+}
+
+// timeoutCause returns the ToolTimeoutError if ctx ended because the call
+// ran out of time.
+func timeoutCause(ctx context.Context) error {
+	var timeoutErr *ToolTimeoutError
+	if errors.As(context.Cause(ctx), &timeoutErr) {
+		return timeoutErr
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+func TestToolTimeout(t *testing.T) {
+	profile := &Profile{
+		ToolTimeout: time.Minute,
+		ToolOptions: map[string]ToolOptions{"slow": {"timeout": "2h"}},
+	}
+	a := NewAgent(anthropic.Client{}, profile, &recordingFrontend{})
+	for _, tc := range []struct {
+		tool ToolDefinition
+		want time.Duration
+	}{
+		{ToolDefinition{Name: "plain"}, time.Minute},
+		{ToolDefinition{Name: "quick", Timeout: time.Second}, time.Second},
+		{ToolDefinition{Name: "slow", Timeout: time.Second}, 2 * time.Hour},
+		{ToolDefinition{Name: "endless", Timeout: -1}, 0},
+	} {
+		if got := a.toolTimeout(tc.tool); got != tc.want {
+			t.Errorf("toolTimeout(%s) = %s, want %s", tc.tool.Name, got, tc.want)
+		}
+	}
+
+	profile.ToolTimeout = 0
+	if got := a.toolTimeout(ToolDefinition{Name: "plain"}); got != DefaultToolTimeout {
+		t.Errorf("Expected DefaultToolTimeout, got %s", got)
+	}
+}
+
+func TestCallToolTimesOut(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	profile := &Profile{Tools: []ToolDefinition{
+		{
+			// Ignores its context, so only the agent can stop waiting
+			Name: "hang",
+			Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+				<-block
+				return "done", nil
+			},
+			Timeout: 50 * time.Millisecond,
+		},
+		{
+			Name: "wait",
+			Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+				<-ctx.Done()
+				return "", ctx.Err()
+			},
+		},
+	}, ToolOptions: map[string]ToolOptions{"wait": {"timeout": 0.05}}}
+	a := NewAgent(anthropic.Client{}, profile, &recordingFrontend{})
+
+	for _, name := range []string{"hang", "wait"} {
+		result := a.CallTool(context.Background(), "id", name, json.RawMessage(`{}`))
+		if !result.IsError || result.Text != "tool timed out after 50ms" {
+			t.Errorf("%s: expected a timeout, got %+v", name, result)
+		}
+	}
+	if stats := a.ToolStats()["hang"]; stats.Failures != 1 {
+		t.Errorf("Expected the timeout to count as a failure, got %+v", stats)
+	}
+}
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"context"
+	"time"
+)
+
+// ToolOptions holds settings a profile passes to one tool, such as limits,
+// keyed by option name. Unlike ToolDefaults they are not part of the tool's
+// input, so the model cannot change them.
+type ToolOptions map[string]any
+
+// toolOptionsKey is the context key for the running tool's options.
+type toolOptionsKey struct{}
+
+// WithOptions returns a copy of ctx that gives options to the tool it is
+// passed to. The agent uses it for every tool call; tests and other callers
+// of tool functions can too.
+func WithOptions(ctx context.Context, options ToolOptions) context.Context {
+	return context.WithValue(ctx, toolOptionsKey{}, options)
+}
+
+// Options returns the profile's options for the running tool. It is empty
+// outside a tool call or if the profile sets none.
+func Options(ctx context.Context) ToolOptions {
+	options, _ := ctx.Value(toolOptionsKey{}).(ToolOptions)
+	return options
+}
+
+// Int returns the option key as an int, or fallback if it is unset or not a
+// whole number.
+func (o ToolOptions) Int(key string, fallback int) int {
+	switch v := o[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		if v == float64(int(v)) {
+			return int(v)
+		}
+	}
+	return fallback
+}
+
+// Duration returns the option key as a duration, or fallback if it is unset
+// or invalid. Durations are given as strings such as "90s" or as a number of
+// seconds.
+func (o ToolOptions) Duration(key string, fallback time.Duration) time.Duration {
+	switch v := o[key].(type) {
+	case time.Duration:
+		return v
+	case string:
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	case int:
+		return time.Duration(v) * time.Second
+	case int64:
+		return time.Duration(v) * time.Second
+	case float64:
+		return time.Duration(v * float64(time.Second))
+	}
+	return fallback
+}
@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestToolOptions(t *testing.T) {
+	options := ToolOptions{
+		"count":   12,
+		"float":   float64(3),
+		"half":    2.5,
+		"timeout": "90s",
+		"seconds": 30,
+		"bad":     "soon",
+	}
+	if got := options.Int("count", 1); got != 12 {
+		t.Errorf("Int(count) = %d", got)
+	}
+	if got := options.Int("float", 1); got != 3 {
+		t.Errorf("Int(float) = %d", got)
+	}
+	if got := options.Int("half", 1); got != 1 {
+		t.Errorf("Expected the fallback for a fraction, got %d", got)
+	}
+	if got := options.Duration("timeout", 0); got != 90*time.Second {
+		t.Errorf("Duration(timeout) = %s", got)
+	}
+	if got := options.Duration("seconds", 0); got != 30*time.Second {
+		t.Errorf("Duration(seconds) = %s", got)
+	}
+	if got := options.Duration("bad", time.Minute); got != time.Minute {
+		t.Errorf("Expected the fallback for an invalid duration, got %s", got)
+	}
+
+	// Reading options outside a tool call falls back to the defaults
+	if got := Options(context.Background()).Int("count", 7); got != 7 {
+		t.Errorf("Expected the fallback without options, got %d", got)
+	}
+	ctx := WithOptions(context.Background(), options)
+	if got := Options(ctx).Int("count", 7); got != 12 {
+		t.Errorf("Expected options from the context, got %d", got)
+	}
+}
@@ -0,0 +1,33 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// toolOutputKey is the context key for the function that passes the running
+// tool's output to the frontend.
+type toolOutputKey struct{}
+
+// withToolOutput returns a copy of ctx in which ReportOutput sends output to
+// the frontend as the call's MessageTypeToolOutput messages.
+func (a *Agent) withToolOutput(ctx context.Context, id, name string) context.Context {
+	data, _ := json.Marshal(ToolOutputData{ToolName: name, ToolID: id})
+	send := func(text string) {
+		a.frontend.SendMessage(Message{Type: MessageTypeToolOutput, Content: text, Data: data})
+	}
+	return context.WithValue(ctx, toolOutputKey{}, send)
+}
+
+// ReportOutput passes output of the running tool, such as the next lines a
+// command printed, to the frontend while the tool runs, so the user can
+// follow its progress. The result is still returned as usual. It reports
+// false outside a tool call.
+func ReportOutput(ctx context.Context, text string) bool {
+	send, ok := ctx.Value(toolOutputKey{}).(func(string))
+	if !ok {
+		return false
+	}
+	send(text)
+	return true
+}
@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+func TestCallToolReportsOutput(t *testing.T) {
+	profile := &Profile{Tools: []ToolDefinition{{
+		Name: "test",
+		Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+			ReportOutput(ctx, "ok  pkg/a\n")
+			ReportOutput(ctx, "ok  pkg/b\n")
+			return "ok  pkg/a\nok  pkg/b\n", nil
+		},
+	}}}
+	frontend := &recordingFrontend{}
+	a := NewAgent(anthropic.Client{}, profile, frontend)
+	a.CallTool(context.Background(), "id", "test", json.RawMessage(`{}`))
+
+	var output []string
+	for _, msg := range frontend.messages {
+		if msg.Type != MessageTypeToolOutput {
+			continue
+		}
+		var data ToolOutputData
+		if err := json.Unmarshal(msg.Data, &data); err != nil || data.ToolID != "id" || data.ToolName != "test" {
+			t.Errorf("Unexpected output data %s, %v", msg.Data, err)
+		}
+		output = append(output, msg.Content)
+	}
+	if strings.Join(output, "") != "ok  pkg/a\nok  pkg/b\n" {
+		t.Errorf("Unexpected output %q", output)
+	}
+
+	if ReportOutput(context.Background(), "x") {
+		t.Error("Expected no output to be reported outside a tool call")
+	}
+}
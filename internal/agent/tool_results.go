@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"sync"
+)
+
+// maxStoredToolResults is how many earlier tool results stay available to
+// ToolResult; older ones are forgotten.
+const maxStoredToolResults = 100
+
+// toolResultStore keeps the results of recent successful tool calls by
+// tool_use ID. A tool still running after an interrupt may read it while the
+// agent adds to it, hence the lock.
+type toolResultStore struct {
+	mu      sync.Mutex
+	results map[string]string
+	order   []string
+}
+
+// add records the result of a tool call, dropping the oldest one when full.
+func (s *toolResultStore) add(id, result string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.results == nil {
+		s.results = make(map[string]string)
+	}
+	if _, ok := s.results[id]; !ok {
+		s.order = append(s.order, id)
+	}
+	s.results[id] = result
+	if len(s.order) > maxStoredToolResults {
+		delete(s.results, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// get returns the result recorded for id.
+func (s *toolResultStore) get(id string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result, ok := s.results[id]
+	return result, ok
+}
+
+// toolResultsKey is the context key for the agent's tool result store.
+type toolResultsKey struct{}
+
+// ToolResult returns the full result of an earlier successful tool call in
+// this session, for tools that post-process other tools' output. It reports
+// false outside a tool call or if id is unknown.
+func ToolResult(ctx context.Context, id string) (string, bool) {
+	store, ok := ctx.Value(toolResultsKey{}).(*toolResultStore)
+	if !ok {
+		return "", false
+	}
+	return store.get(id)
+}
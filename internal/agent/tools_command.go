@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ToolsCommand lists the profile's tools, or turns them on and off for the
+// rest of the session: "/tools off bash", "/tools on bash", "/tools on all".
+const ToolsCommand = "/tools"
+
+// isToolsCommand reports whether input is a ToolsCommand invocation.
+func isToolsCommand(input string) bool {
+	return input == ToolsCommand || strings.HasPrefix(input, ToolsCommand+" ")
+}
+
+// enabledTools returns the profile's tools that the user has not disabled.
+func (a *Agent) enabledTools() []ToolDefinition {
+	tools := make([]ToolDefinition, 0, len(a.profile.Tools))
+	for _, tool := range a.profile.Tools {
+		if !a.disabledTools[tool.Name] {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+// toolsCommand runs a ToolsCommand and reports the result to the frontend.
+// Changes are announced to the model with a note in the next user message.
+func (a *Agent) toolsCommand(input string) {
+	args := strings.Fields(strings.TrimPrefix(input, ToolsCommand))
+	if len(args) == 0 {
+		a.frontend.SendMessage(Message{Type: MessageTypeSystemInfo, Content: a.toolList()})
+		return
+	}
+
+	action, names := args[0], args[1:]
+	if (action != "on" && action != "off") || len(names) == 0 {
+		a.frontend.SendMessage(Message{
+			Type:    MessageTypeError,
+			Content: fmt.Sprintf("Usage: %s [on|off <tool>... | all]", ToolsCommand),
+		})
+		return
+	}
+	if len(names) == 1 && names[0] == "all" {
+		names = names[:0]
+		for _, tool := range a.profile.Tools {
+			names = append(names, tool.Name)
+		}
+	}
+	for _, name := range names {
+		if !a.hasTool(name) {
+			a.frontend.SendMessage(Message{
+				Type:    MessageTypeError,
+				Content: fmt.Sprintf("Unknown tool %q. Type %s to list the tools.", name, ToolsCommand),
+			})
+			return
+		}
+	}
+
+	var changed []string
+	for _, name := range names {
+		if a.disabledTools[name] == (action == "off") {
+			continue
+		}
+		a.disabledTools[name] = action == "off"
+		changed = append(changed, name)
+	}
+	if len(changed) == 0 {
+		a.frontend.SendMessage(Message{Type: MessageTypeSystemInfo, Content: "No tools changed."})
+		return
+	}
+
+	state := "enabled"
+	if action == "off" {
+		state = "disabled"
+	}
+	a.frontend.SendMessage(Message{
+		Type:    MessageTypeSystemInfo,
+		Content: fmt.Sprintf("%s %s.", strings.ToUpper(state[:1])+state[1:], strings.Join(changed, ", ")),
+	})
+	a.addNote(fmt.Sprintf("[The user %s these tools: %s.]", state, strings.Join(changed, ", ")))
+}
+
+// toolList describes which of the profile's tools are enabled.
+func (a *Agent) toolList() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Tools (%d of %d enabled):\n", len(a.enabledTools()), len(a.profile.Tools))
+	for _, tool := range a.profile.Tools {
+		mark := "on "
+		if a.disabledTools[tool.Name] {
+			mark = "off"
+		}
+		fmt.Fprintf(&b, "  %s  %s\n", mark, tool.Name)
+	}
+	fmt.Fprintf(&b, "Use %s off <tool>... or %s on <tool>... to change them.", ToolsCommand, ToolsCommand)
+	return b.String()
+}
+
+// hasTool reports whether the profile has a tool called name.
+func (a *Agent) hasTool(name string) bool {
+	for _, tool := range a.profile.Tools {
+		if tool.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+func TestToolsCommand(t *testing.T) {
+	ran := false
+	run := func(ctx context.Context, input json.RawMessage) (string, error) {
+		ran = true
+		return "ok", nil
+	}
+	profile := &Profile{Tools: []ToolDefinition{
+		{Name: "read_file", Function: run},
+		{Name: "bash", Function: run},
+		{Name: "edit_file", Function: run},
+	}}
+	frontend := &recordingFrontend{}
+	a := NewAgent(anthropic.Client{}, profile, frontend)
+	last := func() Message { return frontend.messages[len(frontend.messages)-1] }
+
+	if !isToolsCommand("/tools") || !isToolsCommand("/tools off bash") || isToolsCommand("/toolsmith") {
+		t.Error("Unexpected isToolsCommand results")
+	}
+
+	a.toolsCommand("/tools off bash edit_file")
+	if got := last().Content; got != "Disabled bash, edit_file." {
+		t.Errorf("Unexpected message %q", got)
+	}
+	if tools := a.enabledTools(); len(tools) != 1 || tools[0].Name != "read_file" {
+		t.Errorf("Expected only read_file to be enabled, got %v", tools)
+	}
+	if a.pendingNote != "[The user disabled these tools: bash, edit_file.]" {
+		t.Errorf("Unexpected note %q", a.pendingNote)
+	}
+
+	a.toolsCommand("/tools")
+	if got := last().Content; !strings.HasPrefix(got, "Tools (1 of 3 enabled):\n  on   read_file\n  off  bash\n") {
+		t.Errorf("Unexpected list %q", got)
+	}
+
+	// Calls the model makes anyway are refused
+	result := a.CallTool(context.Background(), "id", "bash", json.RawMessage(`{}`))
+	if !result.IsError || ran {
+		t.Errorf("Expected the disabled tool not to run, got %+v", result)
+	}
+
+	a.toolsCommand("/tools on all")
+	if got := last().Content; got != "Enabled bash, edit_file." || len(a.enabledTools()) != 3 {
+		t.Errorf("Expected all tools to be enabled, got %q", got)
+	}
+
+	for _, input := range []string{"/tools off", "/tools toggle bash", "/tools off rm"} {
+		a.toolsCommand(input)
+		if last().Type != MessageTypeError {
+			t.Errorf("Expected an error for %q, got %+v", input, last())
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// DefaultContextWindow is the number of tokens a model can attend to at
+// once, which the current Claude models share.
+const DefaultContextWindow = 200_000
+
+// UsageData is the data of a MessageTypeUsage message: the session's token
+// usage so far.
+type UsageData struct {
+	Profile string `json:"profile"`
+	Model   string `json:"model"`
+	// Turn counts the user's messages.
+	Turn int `json:"turn"`
+	// InputTokens includes tokens written to and read from the prompt cache.
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+	// Cost is the estimated cost in US dollars; it is zero for models with
+	// unknown prices.
+	Cost float64 `json:"cost"`
+	// ContextTokens is the size of the conversation as of the last
+	// response, out of ContextWindow.
+	ContextTokens int64 `json:"context_tokens"`
+	ContextWindow int64 `json:"context_window"`
+}
+
+// ContextPercent returns how much of the context window the conversation
+// fills.
+func (u UsageData) ContextPercent() int {
+	if u.ContextWindow == 0 {
+		return 0
+	}
+	return int(u.ContextTokens * 100 / u.ContextWindow)
+}
+
+// modelPrice is the price of a model in US dollars per million tokens.
+type modelPrice struct {
+	input, output float64
+}
+
+// modelPrices holds the prices of model families, matched against the start
+// of the model name.
+var modelPrices = []struct {
+	prefix string
+	price  modelPrice
+}{
+	{"claude-opus-4", modelPrice{15, 75}},
+	{"claude-4-opus", modelPrice{15, 75}},
+	{"claude-3-opus", modelPrice{15, 75}},
+	{"claude-sonnet-4", modelPrice{3, 15}},
+	{"claude-4-sonnet", modelPrice{3, 15}},
+	{"claude-3-7-sonnet", modelPrice{3, 15}},
+	{"claude-3-5-sonnet", modelPrice{3, 15}},
+	{"claude-3-5-haiku", modelPrice{0.8, 4}},
+	{"claude-3-haiku", modelPrice{0.25, 1.25}},
+}
+
+// priceOf returns the price of model, and false if it is not known. Names
+// may carry a provider prefix, as with OpenRouter's "anthropic/".
+func priceOf(model string) (modelPrice, bool) {
+	model = model[strings.LastIndex(model, "/")+1:]
+	for _, p := range modelPrices {
+		if strings.HasPrefix(model, p.prefix) {
+			return p.price, true
+		}
+	}
+	return modelPrice{}, false
+}
+
+// cost estimates the cost of one response. Cache writes cost a quarter more
+// than plain input and cache reads a tenth of it.
+func (p modelPrice) cost(usage anthropic.Usage) float64 {
+	input := float64(usage.InputTokens) + 1.25*float64(usage.CacheCreationInputTokens) + 0.1*float64(usage.CacheReadInputTokens)
+	return (input*p.input + float64(usage.OutputTokens)*p.output) / 1_000_000
+}
+
+// recordUsage adds the usage of a response to the session's.
+func (a *Agent) recordUsage(usage anthropic.Usage) {
+	contextTokens := usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens
+	a.usage.InputTokens += contextTokens
+	a.usage.OutputTokens += usage.OutputTokens
+	if price, ok := priceOf(string(a.profile.Model)); ok {
+		a.usage.Cost += price.cost(usage)
+	}
+	a.usage.ContextTokens = contextTokens + usage.OutputTokens
+}
+
+// sendUsage tells the frontend the session's usage.
+func (a *Agent) sendUsage() {
+	usage := a.usage
+	usage.Profile = a.profile.Name
+	usage.Model = string(a.profile.Model)
+	usage.Turn = a.turn
+	usage.ContextWindow = DefaultContextWindow
+	data, _ := json.Marshal(usage)
+	a.frontend.SendMessage(Message{
+		Type:    MessageTypeUsage,
+		Content: fmt.Sprintf("%d input tokens, %d output tokens, $%.2f", usage.InputTokens, usage.OutputTokens, usage.Cost),
+		Data:    data,
+	})
+}
+
+// sendRequestStart tells the frontend that a request to the model starts,
+// with its estimated size.
+func (a *Agent) sendRequestStart(params anthropic.MessageNewParams) {
+	request, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	data, _ := json.Marshal(RequestData{Tokens: estimateTokens(len(request))})
+	a.frontend.SendMessage(Message{Type: MessageTypeRequest, Data: data})
+}
+
+// estimateTokens roughly converts a size in bytes to tokens, at about four
+// bytes per token.
+func estimateTokens(bytes int) int64 {
+	return int64(bytes+3) / 4
+}
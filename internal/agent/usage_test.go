@@ -0,0 +1,42 @@
+package agent
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+func TestRecordUsage(t *testing.T) {
+	frontend := &recordingFrontend{}
+	a := NewAgent(anthropic.Client{}, &Profile{Name: "default", Model: anthropic.ModelClaudeSonnet4_0}, frontend)
+	a.recordUsage(anthropic.Usage{InputTokens: 1_000, OutputTokens: 500})
+	a.recordUsage(anthropic.Usage{InputTokens: 200, CacheReadInputTokens: 10_000, OutputTokens: 100})
+	a.sendUsage()
+
+	var usage UsageData
+	if err := json.Unmarshal(frontend.messages[0].Data, &usage); err != nil {
+		t.Fatal(err)
+	}
+	if usage.Profile != "default" || usage.InputTokens != 11_200 || usage.OutputTokens != 600 {
+		t.Errorf("Unexpected usage %+v", usage)
+	}
+	// 1,000 + 200 input tokens at $3, 10,000 cache reads at $0.30, and 600
+	// output tokens at $15 per million
+	if want := 0.0036 + 0.003 + 0.009; math.Abs(usage.Cost-want) > 1e-9 {
+		t.Errorf("Expected cost %f, got %f", want, usage.Cost)
+	}
+	if usage.ContextTokens != 10_300 || usage.ContextPercent() != 5 {
+		t.Errorf("Unexpected context %d of %d", usage.ContextTokens, usage.ContextWindow)
+	}
+}
+
+func TestPriceOfUnknownModel(t *testing.T) {
+	if _, ok := priceOf("gpt-4o"); ok {
+		t.Error("Expected no price for an unknown model")
+	}
+	if _, ok := priceOf("anthropic/claude-3-5-haiku-latest"); !ok {
+		t.Error("Expected a price for a prefixed model name")
+	}
+}
@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Status values recorded for a tool execution.
+const (
+	StatusSuccess     = "success"
+	StatusError       = "error"
+	StatusDenied      = "denied"
+	StatusInterrupted = "interrupted"
+)
+
+// Entry is one line of the audit log.
+type Entry struct {
+	Time       time.Time       `json:"time"`
+	ToolName   string          `json:"tool_name"`
+	ToolID     string          `json:"tool_id"`
+	Input      json.RawMessage `json:"input"`
+	ResultHash string          `json:"result_hash"`
+	DurationMs int64           `json:"duration_ms"`
+	Status     string          `json:"status"`
+	Approval   string          `json:"approval"`
+}
+
+// Log is an append-only JSONL audit log of tool executions.
+type Log struct {
+	mu   sync.Mutex
+	file *os.File
+	path string
+}
+
+// Open opens (or creates) the audit log at path for appending.
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &Log{file: file, path: path}, nil
+}
+
+// SessionDir returns a new directory path for this session under the user's
+// config directory, named after the session start time and process ID.
+func SessionDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	id := fmt.Sprintf("%s-%d", time.Now().Format("20060102-150405"), os.Getpid())
+	return filepath.Join(dir, "tiny-trae", "sessions", id), nil
+}
+
+// Path returns the location of the audit log file.
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Record appends an entry to the log. A nil Log discards entries.
+func (l *Log) Record(entry Entry) error {
+	if l == nil {
+		return nil
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the underlying file.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// HashResult returns the hex-encoded SHA-256 of a tool result, so the log
+// records what the tool returned without storing potentially large output.
+func HashResult(result string) string {
+	sum := sha256.Sum256([]byte(result))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session", "audit.jsonl")
+
+	for i := 0; i < 2; i++ {
+		log, err := Open(path)
+		if err != nil {
+			t.Fatalf("Failed to open audit log: %v", err)
+		}
+		err = log.Record(Entry{
+			Time:       time.Now(),
+			ToolName:   "bash",
+			ToolID:     "toolu_1",
+			Input:      json.RawMessage(`{"command":"ls"}`),
+			ResultHash: HashResult("output"),
+			Status:     StatusSuccess,
+			Approval:   "approve",
+		})
+		if err != nil {
+			t.Fatalf("Failed to record entry: %v", err)
+		}
+		if err := log.Close(); err != nil {
+			t.Fatalf("Failed to close audit log: %v", err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open audit log for reading: %v", err)
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Line %d is not valid JSON: %v", lines+1, err)
+		}
+		if entry.ToolName != "bash" {
+			t.Errorf("Expected tool name 'bash', got %q", entry.ToolName)
+		}
+		lines++
+	}
+	if lines != 2 {
+		t.Errorf("Expected 2 entries, got %d", lines)
+	}
+}
+
+func TestNilLog(t *testing.T) {
+	var log *Log
+	if err := log.Record(Entry{ToolName: "bash"}); err != nil {
+		t.Errorf("Expected nil log to discard entries, got %v", err)
+	}
+	if err := log.Close(); err != nil {
+		t.Errorf("Expected nil log to close cleanly, got %v", err)
+	}
+}
+
+func TestHashResult(t *testing.T) {
+	if HashResult("a") == HashResult("b") {
+		t.Error("Expected different results to hash differently")
+	}
+	if len(HashResult("")) != 64 {
+		t.Errorf("Expected 64 hex characters, got %d", len(HashResult("")))
+	}
+}
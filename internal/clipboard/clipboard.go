@@ -0,0 +1,110 @@
+// Package clipboard reads and writes the system clipboard using the
+// platform's command-line helpers.
+package clipboard
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrUnavailable is returned when no clipboard helper is installed.
+var ErrUnavailable = errors.New("no clipboard helper found; install wl-clipboard, xclip, or xsel")
+
+// backend is a pair of commands that copy stdin to the clipboard and print
+// the clipboard to stdout.
+type backend struct {
+	name  string
+	copy  []string
+	paste []string
+}
+
+// backends lists the helpers tried on each platform, in order.
+var backends = map[string][]backend{
+	"darwin": {
+		{name: "pbcopy", copy: []string{"pbcopy"}, paste: []string{"pbpaste"}},
+	},
+	"windows": {
+		{name: "powershell", copy: []string{"powershell", "-NoProfile", "-Command", "$input | Set-Clipboard"}, paste: []string{"powershell", "-NoProfile", "-Command", "Get-Clipboard -Raw"}},
+	},
+	"linux": {
+		{name: "wl-copy", copy: []string{"wl-copy"}, paste: []string{"wl-paste", "--no-newline"}},
+		{name: "xclip", copy: []string{"xclip", "-selection", "clipboard"}, paste: []string{"xclip", "-selection", "clipboard", "-o"}},
+		{name: "xsel", copy: []string{"xsel", "--clipboard", "--input"}, paste: []string{"xsel", "--clipboard", "--output"}},
+		// WSL can reach the Windows clipboard
+		{name: "clip.exe", copy: []string{"clip.exe"}, paste: []string{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard -Raw"}},
+	},
+}
+
+// detect returns the first usable backend for goos. wl-copy is only used
+// under Wayland, since it fails without a compositor.
+func detect(goos string, getenv func(string) string, lookPath func(string) (string, error)) (backend, error) {
+	candidates, ok := backends[goos]
+	if !ok {
+		candidates = backends["linux"]
+	}
+	for _, b := range candidates {
+		if b.name == "wl-copy" && getenv("WAYLAND_DISPLAY") == "" {
+			continue
+		}
+		if _, err := lookPath(b.copy[0]); err != nil {
+			continue
+		}
+		if _, err := lookPath(b.paste[0]); err != nil {
+			continue
+		}
+		return b, nil
+	}
+	return backend{}, ErrUnavailable
+}
+
+// Read returns the text on the clipboard.
+func Read(ctx context.Context) (string, error) {
+	b, err := detect(runtime.GOOS, os.Getenv, exec.LookPath)
+	if err != nil {
+		return "", err
+	}
+	return b.read(ctx)
+}
+
+// Write replaces the clipboard contents with text.
+func Write(ctx context.Context, text string) error {
+	b, err := detect(runtime.GOOS, os.Getenv, exec.LookPath)
+	if err != nil {
+		return err
+	}
+	return b.write(ctx, text)
+}
+
+// read runs the paste command.
+func (b backend) read(ctx context.Context) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, b.paste[0], b.paste[1:]...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %w: %s", b.paste[0], err, strings.TrimSpace(stderr.String()))
+	}
+	text := string(out)
+	if b.name == "powershell" || b.name == "clip.exe" {
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+	return text, nil
+}
+
+// write runs the copy command with text on stdin.
+func (b backend) write(ctx context.Context, text string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, b.copy[0], b.copy[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", b.copy[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package clipboard
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	installed := func(names ...string) func(string) (string, error) {
+		return func(name string) (string, error) {
+			for _, n := range names {
+				if n == name {
+					return "/usr/bin/" + name, nil
+				}
+			}
+			return "", exec.ErrNotFound
+		}
+	}
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+
+	tests := []struct {
+		goos      string
+		env       map[string]string
+		installed []string
+		want      string
+	}{
+		{"darwin", nil, []string{"pbcopy", "pbpaste"}, "pbcopy"},
+		{"linux", map[string]string{"WAYLAND_DISPLAY": "wayland-0"}, []string{"wl-copy", "wl-paste", "xclip"}, "wl-copy"},
+		{"linux", nil, []string{"wl-copy", "wl-paste", "xclip"}, "xclip"},
+		{"linux", nil, []string{"xsel"}, "xsel"},
+		{"freebsd", nil, []string{"xclip"}, "xclip"},
+		{"linux", nil, []string{"clip.exe", "powershell.exe"}, "clip.exe"},
+	}
+	for _, test := range tests {
+		b, err := detect(test.goos, env(test.env), installed(test.installed...))
+		if err != nil || b.name != test.want {
+			t.Errorf("detect(%s, %v) = %q, %v; want %q", test.goos, test.installed, b.name, err, test.want)
+		}
+	}
+
+	if _, err := detect("linux", env(nil), installed("clip.exe")); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable, got %v", err)
+	}
+}
+
+func TestBackendRoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	file := filepath.Join(t.TempDir(), "clipboard")
+	b := backend{
+		name:  "file",
+		copy:  []string{"sh", "-c", `cat > "$0"`, file},
+		paste: []string{"cat", file},
+	}
+
+	ctx := context.Background()
+	text := "func main() {\n\tfmt.Println(\"hi\")\n}\n"
+	if err := b.write(ctx, text); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	got, err := b.read(ctx)
+	if err != nil || got != text {
+		t.Errorf("read = %q, %v; want %q", got, err, text)
+	}
+
+	b.paste = []string{"cat", filepath.Join(t.TempDir(), "missing")}
+	if _, err := b.read(ctx); err == nil {
+		t.Error("Expected error when the paste command fails")
+	}
+}
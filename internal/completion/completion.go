@@ -0,0 +1,168 @@
+// Package completion generates shell completion scripts for tiny-trae and
+// answers the completion requests they make. The scripts ask the binary
+// itself for candidates, so flags and user-defined profiles added later are
+// completed without regenerating them.
+package completion
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Shells are the shells Script supports.
+var Shells = []string{"bash", "zsh", "fish"}
+
+// Command is the hidden command the scripts run to get candidates.
+const Command = "__complete"
+
+// Script returns the completion script for shell, completing the command
+// called program.
+func Script(shell, program string) (string, error) {
+	var script string
+	switch shell {
+	case "bash":
+		script = bashScript
+	case "zsh":
+		script = zshScript
+	case "fish":
+		script = fishScript
+	default:
+		return "", fmt.Errorf("unsupported shell %q (want %s)", shell, strings.Join(Shells, ", "))
+	}
+	function := "_" + strings.NewReplacer("-", "_", ".", "_").Replace(program)
+	return strings.NewReplacer("PROGRAM", program, "FUNCTION", function, "COMMAND", Command).Replace(script), nil
+}
+
+// The scripts pass the words up to the cursor, the last one being the word
+// being completed. When there are no candidates, they complete file names.
+const bashScript = `# bash completion for PROGRAM
+FUNCTION() {
+    local IFS=$'\n'
+    COMPREPLY=($(PROGRAM COMMAND "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F FUNCTION PROGRAM
+`
+
+const zshScript = `#compdef PROGRAM
+# zsh completion for PROGRAM
+FUNCTION() {
+    local -a candidates
+    candidates=("${(@f)$(PROGRAM COMMAND "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if [[ -n ${candidates[1]} ]]; then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+compdef FUNCTION PROGRAM
+`
+
+const fishScript = `# fish completion for PROGRAM
+function FUNCTION
+    set -l words (commandline -opc)
+    set -e words[1]
+    PROGRAM COMMAND $words (commandline -ct) 2>/dev/null
+end
+complete -c PROGRAM -a '(FUNCTION)'
+`
+
+// Flag is a command-line flag.
+type Flag struct {
+	Name string
+	// TakesValue is false for boolean flags.
+	TakesValue bool
+}
+
+// usageLine matches a flag's line in the output of flag.PrintDefaults.
+// Short names have their usage on the same line, after a tab.
+var usageLine = regexp.MustCompile(`^  -(\S+)(?: (\S+))?(?:\t|$)`)
+
+// ParseUsage returns the flags listed in a command's usage message, as
+// printed by the flag package.
+func ParseUsage(usage string) []Flag {
+	var flags []Flag
+	for _, line := range strings.Split(usage, "\n") {
+		if m := usageLine.FindStringSubmatch(line); m != nil {
+			flags = append(flags, Flag{Name: m[1], TakesValue: m[2] != ""})
+		}
+	}
+	return flags
+}
+
+// Completer finds the candidates for the word being completed.
+type Completer struct {
+	// Subcommands are the names of the subcommands.
+	Subcommands []string
+	// Flags returns the flags of a subcommand, or of the main command for
+	// "".
+	Flags func(subcommand string) []Flag
+	// Values returns the values a flag can take, or nil to complete file
+	// names.
+	Values func(subcommand, flag string) []string
+}
+
+// Complete returns the candidates for the last of words, which are the
+// command-line words after the program's name up to the cursor.
+func (c *Completer) Complete(words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	subcommand := ""
+	if len(words) > 1 && slices.Contains(c.Subcommands, words[0]) {
+		subcommand = words[0]
+		words = words[1:]
+	}
+	current := words[len(words)-1]
+
+	if len(words) > 1 {
+		if flag, ok := c.flag(subcommand, words[len(words)-2]); ok && flag.TakesValue {
+			return matching(c.Values(subcommand, flag.Name), current)
+		}
+	}
+	if strings.HasPrefix(current, "-") {
+		if name, value, ok := strings.Cut(current, "="); ok {
+			flag, _ := c.flag(subcommand, name)
+			var candidates []string
+			for _, v := range matching(c.Values(subcommand, flag.Name), value) {
+				candidates = append(candidates, name+"="+v)
+			}
+			return candidates
+		}
+		var names []string
+		for _, flag := range c.Flags(subcommand) {
+			names = append(names, "--"+flag.Name)
+		}
+		return matching(names, current)
+	}
+	if subcommand == "" && len(words) == 1 {
+		return matching(c.Subcommands, current)
+	}
+	return nil
+}
+
+// flag returns the flag a word such as "--profile" or "-p" names.
+func (c *Completer) flag(subcommand, word string) (Flag, bool) {
+	if !strings.HasPrefix(word, "-") || strings.Contains(word, "=") {
+		return Flag{}, false
+	}
+	name := strings.TrimLeft(word, "-")
+	for _, flag := range c.Flags(subcommand) {
+		if flag.Name == name {
+			return flag, true
+		}
+	}
+	return Flag{}, false
+}
+
+// matching returns the candidates starting with prefix.
+func matching(candidates []string, prefix string) []string {
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			matches = append(matches, candidate)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,83 @@
+package completion
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const usage = `Usage of tiny-trae:
+  -audit-log string
+    	Path to the tool execution audit log
+  -list-profiles
+    	List all available profiles
+  -p string	Accept a string as user input
+  -profile string
+    	Specify which profile to use (default "default")
+  -q	Print less
+`
+
+func TestParseUsage(t *testing.T) {
+	got := ParseUsage(usage)
+	want := []Flag{{"audit-log", true}, {"list-profiles", false}, {"p", true}, {"profile", true}, {"q", false}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("ParseUsage() = %v, want %v", got, want)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	c := &Completer{
+		Subcommands: []string{"review", "serve"},
+		Flags: func(subcommand string) []Flag {
+			if subcommand == "review" {
+				return []Flag{{"format", true}}
+			}
+			return ParseUsage(usage)
+		},
+		Values: func(subcommand, flag string) []string {
+			switch flag {
+			case "profile":
+				return []string{"default", "minimal", "review"}
+			case "format":
+				return []string{"text", "json"}
+			}
+			return nil
+		},
+	}
+	tests := []struct {
+		words []string
+		want  []string
+	}{
+		{[]string{""}, []string{"review", "serve"}},
+		{[]string{"se"}, []string{"serve"}},
+		{[]string{"--pro"}, []string{"--profile"}},
+		{[]string{"--profile", "m"}, []string{"minimal"}},
+		{[]string{"-profile", ""}, []string{"default", "minimal", "review"}},
+		{[]string{"--profile=re"}, []string{"--profile=review"}},
+		{[]string{"--list-profiles", ""}, nil},
+		{[]string{"--audit-log", ""}, nil},
+		{[]string{"review", "--"}, []string{"--format"}},
+		{[]string{"review", "--format", "j"}, []string{"json"}},
+		{[]string{"review", ""}, nil},
+	}
+	for _, tt := range tests {
+		if got := c.Complete(tt.words); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("Complete(%q) = %q, want %q", tt.words, got, tt.want)
+		}
+	}
+}
+
+func TestScript(t *testing.T) {
+	for _, shell := range Shells {
+		script, err := Script(shell, "tiny-trae")
+		if err != nil {
+			t.Fatalf("Script(%s) failed: %v", shell, err)
+		}
+		if !strings.Contains(script, "tiny-trae __complete") || !strings.Contains(script, "_tiny_trae") {
+			t.Errorf("Unexpected %s script:\n%s", shell, script)
+		}
+	}
+	if _, err := Script("tcsh", "tiny-trae"); err == nil {
+		t.Error("Expected an error for an unsupported shell")
+	}
+}
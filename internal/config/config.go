@@ -0,0 +1,200 @@
+// Package config loads the user's settings file, which sets defaults for
+// what the command-line flags otherwise choose.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/permission"
+	"tiny-trae/internal/profile"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the user's settings. Every field is optional; command-line flags
+// and environment variables override what it sets.
+type Config struct {
+	// Profile is the profile used when --profile is not given.
+	Profile string `yaml:"profile"`
+	// Model and MaxTokens override the profile's, unless it is one of
+	// Profiles and sets its own.
+	Model     string `yaml:"model"`
+	MaxTokens int64  `yaml:"max_tokens"`
+	// Thinking is the default for --thinking.
+	Thinking int64 `yaml:"thinking"`
+	// BaseURL is the API's URL, for a proxy such as anthropic-proxy;
+	// ANTHROPIC_BASE_URL overrides it.
+	BaseURL string `yaml:"base_url"`
+	// CredentialCommand is a shell command printing the API key, used when
+	// ANTHROPIC_API_KEY is not set. Without it, the key is looked up in
+	// the system keychain.
+	CredentialCommand string `yaml:"credential_command"`
+	// Theme and Notify are the defaults for --theme and --notify.
+	Theme  string `yaml:"theme"`
+	Notify string `yaml:"notify"`
+	// Permissions are tool permission rules, checked after those of the
+	// permission policy file.
+	Permissions []permission.Rule `yaml:"permissions"`
+	// TestCommand is the run_tests tool's default command.
+	TestCommand string `yaml:"test_command"`
+	// BuildCommands are the build_and_lint tool's default commands.
+	BuildCommands []string `yaml:"build_commands"`
+	// Profiles are profiles to choose from besides the built-in ones.
+	Profiles []profile.Definition `yaml:"profiles"`
+
+	// The rest are only set by project settings, through Merge. Ignore
+	// lists paths to hide from the agent, in .traeignore format and
+	// relative to IgnoreDir; ProjectTools are the custom tools defined in
+	// the project settings file at ProjectPath, and ProjectPermissions its
+	// permission rules.
+	Ignore             []string          `yaml:"-"`
+	IgnoreDir          string            `yaml:"-"`
+	ProjectTools       []yaml.Node       `yaml:"-"`
+	ProjectPath        string            `yaml:"-"`
+	ProjectPermissions []permission.Rule `yaml:"-"`
+}
+
+// Path returns the location of the user's settings file: $TRAE_CONFIG, or
+// config.yaml in the user's configuration directory.
+func Path() (string, error) {
+	if path := os.Getenv(ConfigEnv); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tiny-trae", "config.yaml"), nil
+}
+
+// Load reads the settings file at Path over the settings of FromEnv. A
+// missing file leaves just those, unless TRAE_CONFIG names it.
+func Load() (*Config, error) {
+	env, err := FromEnv()
+	if err != nil {
+		return nil, err
+	}
+	path, err := Path()
+	if err != nil {
+		return env, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && os.Getenv(ConfigEnv) == "" {
+		return env, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	config, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	config.fillFrom(env)
+	for i, d := range config.Profiles {
+		if d.SystemPromptFile != "" && !filepath.IsAbs(d.SystemPromptFile) {
+			config.Profiles[i].SystemPromptFile = filepath.Join(filepath.Dir(path), d.SystemPromptFile)
+		}
+	}
+	return config, nil
+}
+
+// Parse parses a YAML settings document. Unknown settings are errors, so
+// typos do not go unnoticed.
+func Parse(data []byte) (*Config, error) {
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse settings: %w", err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid settings: %w", err)
+	}
+	return &config, nil
+}
+
+// Validate checks the settings' values.
+func (c *Config) Validate() error {
+	if c.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens must be positive")
+	}
+	// The API's smallest thinking budget
+	if c.Thinking != 0 && c.Thinking < 1024 {
+		return fmt.Errorf("thinking must be at least 1024 tokens, or 0 for off")
+	}
+	policy := permission.Policy{Rules: c.Permissions}
+	if err := policy.Validate(); err != nil {
+		return fmt.Errorf("permissions: %w", err)
+	}
+	return nil
+}
+
+// ApplyTo overrides the profile's settings with the ones set here.
+func (c *Config) ApplyTo(profile *agent.Profile) {
+	ownModel, ownMaxTokens := c.profileSets(profile.Name)
+	if c.Model != "" && !ownModel {
+		profile.Model = anthropic.Model(c.Model)
+	}
+	if c.MaxTokens != 0 && !ownMaxTokens {
+		profile.MaxTokens = c.MaxTokens
+	}
+	if c.TestCommand != "" {
+		setToolDefault(profile, "run_tests", "command", c.TestCommand)
+	}
+	if len(c.BuildCommands) > 0 {
+		setToolDefault(profile, "build_and_lint", "commands", c.BuildCommands)
+	}
+}
+
+// setToolDefault sets the default of the tool's input field.
+func setToolDefault(profile *agent.Profile, tool, field string, value any) {
+	if profile.ToolDefaults == nil {
+		profile.ToolDefaults = make(map[string]map[string]any)
+	}
+	if profile.ToolDefaults[tool] == nil {
+		profile.ToolDefaults[tool] = make(map[string]any)
+	}
+	profile.ToolDefaults[tool][field] = value
+}
+
+// profileSets reports whether the profile called name, or one it extends,
+// is among Profiles and sets its own model and max tokens.
+func (c *Config) profileSets(name string) (model, maxTokens bool) {
+	// Bounded, in case the profiles extend each other in a cycle
+	for range c.Profiles {
+		i := slices.IndexFunc(c.Profiles, func(d profile.Definition) bool { return d.Name == name })
+		if i < 0 {
+			break
+		}
+		d := c.Profiles[i]
+		model = model || d.Model != ""
+		maxTokens = maxTokens || d.MaxTokens != 0
+		if d.Extends == "" || d.Extends == d.Name {
+			break
+		}
+		name = d.Extends
+	}
+	return model, maxTokens
+}
+
+// ApplyPermissions adds the permission rules set here to policy: the
+// project's before the policy's own, and the user's after them.
+func (c *Config) ApplyPermissions(policy *permission.Policy) {
+	c.ApplyProjectPermissions(policy)
+	policy.Rules = append(policy.Rules, c.Permissions...)
+}
+
+// ApplyProjectPermissions adds the project's permission rules to the start
+// of policy. They only ask or deny, so checking them first means no rule of
+// the policy can allow what the project denies.
+func (c *Config) ApplyProjectPermissions(policy *permission.Policy) {
+	policy.Rules = append(slices.Clone(c.ProjectPermissions), policy.Rules...)
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/permission"
+)
+
+func TestParse(t *testing.T) {
+	config, err := Parse([]byte(`
+profile: minimal
+model: claude-opus-4-0
+max_tokens: 8192
+theme: light
+test_command: make test
+build_commands: [make build]
+permissions:
+  - tool: bash
+    match: "rm *"
+    action: deny
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if config.Profile != "minimal" || config.Theme != "light" || len(config.Permissions) != 1 {
+		t.Errorf("Unexpected settings %+v", config)
+	}
+
+	profile := &agent.Profile{Model: "claude-sonnet-4-0", MaxTokens: 1024}
+	config.ApplyTo(profile)
+	if profile.Model != "claude-opus-4-0" || profile.MaxTokens != 8192 {
+		t.Errorf("Expected the profile's model and max tokens to be overridden, got %+v", profile)
+	}
+	if profile.ToolDefaults["run_tests"]["command"] != "make test" {
+		t.Errorf("Expected the test command to be run_tests' default, got %v", profile.ToolDefaults)
+	}
+	if commands, _ := profile.ToolDefaults["build_and_lint"]["commands"].([]string); len(commands) != 1 || commands[0] != "make build" {
+		t.Errorf("Expected the build commands to be build_and_lint's default, got %v", profile.ToolDefaults)
+	}
+
+	policy := &permission.Policy{Rules: []permission.Rule{{Tool: "*", Action: permission.ActionAsk}}}
+	config.ApplyPermissions(policy)
+	if len(policy.Rules) != 2 || policy.Rules[1].Tool != "bash" {
+		t.Errorf("Expected the rules to be added after the policy's, got %+v", policy.Rules)
+	}
+}
+
+func TestApplyToUserProfile(t *testing.T) {
+	config, err := Parse([]byte(`
+model: claude-opus-4-0
+max_tokens: 8192
+profiles:
+  - name: fast
+    model: claude-3-5-haiku-latest
+  - name: fast-readonly
+    extends: fast
+    tools: [tag:readonly]
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	profile := &agent.Profile{Name: "fast-readonly", Model: "claude-3-5-haiku-latest", MaxTokens: 1024}
+	config.ApplyTo(profile)
+	if profile.Model != "claude-3-5-haiku-latest" || profile.MaxTokens != 8192 {
+		t.Errorf("Expected only the settings the profile leaves unset to be applied, got %+v", profile)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct {
+		yaml string
+		want string
+	}{
+		{"modle: claude", "field modle not found"},
+		{"max_tokens: -1", "max_tokens"},
+		{"thinking: 100", "thinking"},
+		{"permissions:\n  - tool: bash\n    action: maybe", "unknown action"},
+	} {
+		if _, err := Parse([]byte(tc.yaml)); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Parse(%q) = %v, want an error about %s", tc.yaml, err, tc.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	config, err := Load()
+	if err != nil || config.Profile != "" {
+		t.Fatalf("Expected empty settings without a file, got %+v, %v", config, err)
+	}
+
+	path, err := Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	os.WriteFile(path, []byte("profile: minimal\n"), 0o644)
+	if config, err := Load(); err != nil || config.Profile != "minimal" {
+		t.Errorf("Expected the file's profile, got %+v, %v", config, err)
+	}
+
+	os.WriteFile(path, []byte("profiles:\n  - name: mine\n    system_prompt_file: prompt.md\n"), 0o644)
+	if config, err := Load(); err != nil || config.Profiles[0].SystemPromptFile != filepath.Join(filepath.Dir(path), "prompt.md") {
+		t.Errorf("Expected the prompt file to be relative to the settings file, got %+v, %v", config, err)
+	}
+
+	os.WriteFile(path, []byte("profile: [\n"), 0o644)
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("Expected an error naming the file, got %v", err)
+	}
+}
+
+func TestLoadFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("TRAE_PROFILE", "minimal")
+	t.Setenv("TRAE_MODEL", "claude-opus-4-0")
+	t.Setenv("TRAE_MAX_TOKENS", "2048")
+
+	config, err := Load()
+	if err != nil || config.Profile != "minimal" || config.Model != "claude-opus-4-0" || config.MaxTokens != 2048 {
+		t.Fatalf("Expected the environment's settings, got %+v, %v", config, err)
+	}
+
+	path := filepath.Join(dir, "elsewhere.yaml")
+	t.Setenv("TRAE_CONFIG", path)
+	if _, err := Load(); err == nil {
+		t.Error("Expected an error for a missing TRAE_CONFIG file")
+	}
+	os.WriteFile(path, []byte("model: claude-sonnet-4-0\n"), 0o644)
+	config, err = Load()
+	if err != nil || config.Model != "claude-sonnet-4-0" || config.Profile != "minimal" {
+		t.Errorf("Expected the file's settings over the environment's, got %+v, %v", config, err)
+	}
+
+	t.Setenv("TRAE_MAX_TOKENS", "lots")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "TRAE_MAX_TOKENS") {
+		t.Errorf("Expected an error naming the variable, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// ConfigEnv names the environment variable holding the settings file's
+// path, in place of Path's.
+const ConfigEnv = "TRAE_CONFIG"
+
+// FromEnv returns the settings set by TRAE_* environment variables, such as
+// TRAE_MODEL for model. They are the lowest-precedence settings: the
+// settings file overrides them, and flags override both.
+func FromEnv() (*Config, error) {
+	config := &Config{
+		Profile:     os.Getenv("TRAE_PROFILE"),
+		Model:       os.Getenv("TRAE_MODEL"),
+		Theme:       os.Getenv("TRAE_THEME"),
+		Notify:      os.Getenv("TRAE_NOTIFY"),
+		TestCommand: os.Getenv("TRAE_TEST_COMMAND"),
+	}
+	for name, value := range map[string]*int64{
+		"TRAE_MAX_TOKENS": &config.MaxTokens,
+		"TRAE_THINKING":   &config.Thinking,
+	} {
+		if s := os.Getenv(name); s != "" {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %q is not a number", name, s)
+			}
+			*value = n
+		}
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid environment: %w", err)
+	}
+	return config, nil
+}
+
+// fillFrom sets the settings not set here to those of defaults.
+func (c *Config) fillFrom(defaults *Config) {
+	fill := func(value *string, fallback string) {
+		if *value == "" {
+			*value = fallback
+		}
+	}
+	fill(&c.Profile, defaults.Profile)
+	fill(&c.Model, defaults.Model)
+	fill(&c.Theme, defaults.Theme)
+	fill(&c.Notify, defaults.Notify)
+	fill(&c.TestCommand, defaults.TestCommand)
+	if c.MaxTokens == 0 {
+		c.MaxTokens = defaults.MaxTokens
+	}
+	if c.Thinking == 0 {
+		c.Thinking = defaults.Thinking
+	}
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"tiny-trae/internal/permission"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFile is the name of a project's settings file, which a team can
+// check in to share the agent's settings for its repository.
+const ProjectFile = ".trae.yaml"
+
+// Project is a project's settings. They are merged over the user's.
+type Project struct {
+	// Profile is the profile used when --profile is not given.
+	Profile string `yaml:"profile"`
+	// Permissions are tool permission rules, checked before any other,
+	// those of a --permissions file included. They may only ask or deny,
+	// since anyone who can change the repository can change them.
+	Permissions []permission.Rule `yaml:"permissions"`
+	// Tools are custom tools, in the format of the custom tool file, for
+	// tools.ProjectCustomTools.
+	Tools []yaml.Node `yaml:"tools"`
+	// TestCommand is the run_tests tool's default command.
+	TestCommand string `yaml:"test_command"`
+	// BuildCommands are the build_and_lint tool's default commands.
+	BuildCommands []string `yaml:"build_commands"`
+	// Ignore lists paths to hide from the agent, in .traeignore format and
+	// relative to Dir.
+	Ignore []string `yaml:"ignore"`
+
+	// Path is the file's path, and Dir its directory.
+	Path string `yaml:"-"`
+	Dir  string `yaml:"-"`
+}
+
+// FindProject looks for ProjectFile in dir and the directories above it and
+// loads the first one found. It returns nil if there is none.
+func FindProject(dir string) (*Project, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		path := filepath.Join(dir, ProjectFile)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			project, err := ParseProject(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			project.Path = path
+			project.Dir = dir
+			return project, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// ParseProject parses a YAML project settings document.
+func ParseProject(data []byte) (*Project, error) {
+	var project Project
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&project); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse project settings: %w", err)
+	}
+	policy := permission.Policy{Rules: project.Permissions}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid project settings: permissions: %w", err)
+	}
+	for i, rule := range project.Permissions {
+		if rule.Action == permission.ActionAllow {
+			return nil, fmt.Errorf("invalid project settings: permissions: rule %d: a project may only ask or deny", i+1)
+		}
+	}
+	return &project, nil
+}
+
+// Merge sets what the project sets over the user's settings.
+func (c *Config) Merge(project *Project) {
+	if project.Profile != "" {
+		c.Profile = project.Profile
+	}
+	if project.TestCommand != "" {
+		c.TestCommand = project.TestCommand
+	}
+	if len(project.BuildCommands) > 0 {
+		c.BuildCommands = project.BuildCommands
+	}
+	c.ProjectPermissions = project.Permissions
+	c.Ignore = project.Ignore
+	c.IgnoreDir = project.Dir
+	c.ProjectTools = project.Tools
+	c.ProjectPath = project.Path
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"tiny-trae/internal/permission"
+)
+
+func TestFindProject(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "a", "b")
+	os.MkdirAll(sub, 0o755)
+	os.WriteFile(filepath.Join(root, ProjectFile), []byte(`
+profile: minimal
+test_command: make test
+build_commands: [make build, make lint]
+ignore: [secrets/]
+permissions:
+  - tool: bash
+    match: "git push*"
+    action: ask
+tools:
+  - name: lint
+    description: Runs the linter
+    command: make lint
+`), 0o644)
+
+	project, err := FindProject(sub)
+	if err != nil {
+		t.Fatalf("FindProject failed: %v", err)
+	}
+	if project == nil || project.Dir != root || project.Profile != "minimal" || len(project.Tools) != 1 || project.Ignore[0] != "secrets/" {
+		t.Fatalf("Unexpected project %+v", project)
+	}
+
+	config := &Config{Profile: "default", Permissions: []permission.Rule{{Tool: "*", Action: permission.ActionAllow}}}
+	config.Merge(project)
+	if config.Profile != "minimal" || config.TestCommand != "make test" || len(config.BuildCommands) != 2 {
+		t.Errorf("Expected the project's settings to win, got %+v", config)
+	}
+	file := []permission.Rule{{Tool: "bash", Action: permission.ActionAllow}}
+	policy := &permission.Policy{Rules: slices.Clone(file)}
+	config.ApplyPermissions(policy)
+	if len(policy.Rules) != 3 || policy.Rules[0].Action != permission.ActionAsk || policy.Rules[2].Tool != "*" {
+		t.Errorf("Expected the project's rules first and the user's last, got %+v", policy.Rules)
+	}
+	// A policy given with --permissions replaces the user's rules, not the
+	// project's
+	policy = &permission.Policy{Rules: slices.Clone(file)}
+	config.ApplyProjectPermissions(policy)
+	if len(policy.Rules) != 2 || policy.Rules[0].Action != permission.ActionAsk {
+		t.Errorf("Expected the project's rules before the policy's, got %+v", policy.Rules)
+	}
+	if len(config.Ignore) != 1 || config.IgnoreDir != root || len(config.ProjectTools) != 1 || config.ProjectPath != project.Path {
+		t.Errorf("Expected the project's ignored paths and tools, got %+v", config)
+	}
+}
+
+func TestFindProjectWithoutFile(t *testing.T) {
+	if project, err := FindProject(t.TempDir()); project != nil || err != nil {
+		t.Errorf("Expected no project, got %+v, %v", project, err)
+	}
+}
+
+func TestProjectMayNotAllow(t *testing.T) {
+	_, err := ParseProject([]byte("permissions:\n  - tool: bash\n    action: allow\n"))
+	if err == nil || !strings.Contains(err.Error(), "only ask or deny") {
+		t.Errorf("Expected allow rules to be refused, got %v", err)
+	}
+}
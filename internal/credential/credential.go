@@ -0,0 +1,47 @@
+// Package credential finds the Anthropic API key when it is not in the
+// environment: in the output of a credential helper command, or in the
+// system keychain.
+package credential
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Service and Account identify the API key in the system keychain.
+const (
+	Service = "tiny-trae"
+	Account = "anthropic"
+)
+
+// APIKey returns the API key that command prints or, without a command, the
+// one stored in the system keychain. It returns "" if the keychain has none
+// or cannot be read on this system.
+func APIKey(ctx context.Context, command string) (string, error) {
+	if command != "" {
+		return runCommand(ctx, command)
+	}
+	return keychain(ctx)
+}
+
+// runCommand runs a credential helper command and returns what it prints.
+// Its stdin and stderr are the terminal's, so it can ask for a passphrase.
+func runCommand(ctx context.Context, command string) (string, error) {
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, "bash", "-c", command)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("credential command failed: %w", err)
+	}
+	key := strings.TrimSpace(stdout.String())
+	if key == "" {
+		return "", fmt.Errorf("credential command printed no key")
+	}
+	return key, nil
+}
@@ -0,0 +1,20 @@
+package credential
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAPIKeyFromCommand(t *testing.T) {
+	ctx := context.Background()
+	if key, err := APIKey(ctx, "echo '  sk-test  '"); err != nil || key != "sk-test" {
+		t.Errorf("Expected the command's key, got %q, %v", key, err)
+	}
+	if _, err := APIKey(ctx, "exit 3"); err == nil || !strings.Contains(err.Error(), "failed") {
+		t.Errorf("Expected the command's failure, got %v", err)
+	}
+	if _, err := APIKey(ctx, "true"); err == nil || !strings.Contains(err.Error(), "no key") {
+		t.Errorf("Expected an error for empty output, got %v", err)
+	}
+}
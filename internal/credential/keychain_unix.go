@@ -0,0 +1,39 @@
+//go:build !windows
+
+package credential
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// keychainCommand returns the command that prints the API key stored in the
+// keychain: the login keychain on macOS, and the Secret Service (GNOME
+// Keyring, KWallet) elsewhere.
+func keychainCommand(goos string) []string {
+	if goos == "darwin" {
+		return []string{"security", "find-generic-password", "-s", Service, "-a", Account, "-w"}
+	}
+	return []string{"secret-tool", "lookup", "service", Service, "account", Account}
+}
+
+// keychain returns the API key stored in the keychain.
+func keychain(ctx context.Context) (string, error) {
+	command := keychainCommand(runtime.GOOS)
+	if _, err := exec.LookPath(command[0]); err != nil {
+		return "", nil
+	}
+	out, err := exec.CommandContext(ctx, command[0], command[1:]...).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// Both helpers fail when there is no such item
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package credential
+
+import "testing"
+
+func TestKeychainCommand(t *testing.T) {
+	if got := keychainCommand("darwin"); got[0] != "security" || got[len(got)-1] != "-w" {
+		t.Errorf("Unexpected macOS command %v", got)
+	}
+	if got := keychainCommand("linux"); got[0] != "secret-tool" {
+		t.Errorf("Unexpected Linux command %v", got)
+	}
+}
@@ -0,0 +1,63 @@
+//go:build windows
+
+package credential
+
+import (
+	"context"
+	"errors"
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	advapi32     = windows.NewLazySystemDLL("advapi32.dll")
+	procCredRead = advapi32.NewProc("CredReadW")
+	procCredFree = advapi32.NewProc("CredFree")
+)
+
+// credentialW is the CREDENTIALW structure.
+type credentialW struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// credTypeGeneric is CRED_TYPE_GENERIC, the type cmdkey /generic creates.
+const credTypeGeneric = 1
+
+// keychain returns the API key stored in the Windows Credential Manager as
+// the generic credential named Service.
+func keychain(ctx context.Context) (string, error) {
+	target, err := windows.UTF16PtrFromString(Service)
+	if err != nil {
+		return "", err
+	}
+	var cred *credentialW
+	ok, _, err := procCredRead.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if ok == 0 {
+		if errors.Is(err, windows.ERROR_NOT_FOUND) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	// cmdkey and most tools store the secret as UTF-16
+	blob := unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)
+	chars := make([]uint16, len(blob)/2)
+	for i := range chars {
+		chars[i] = uint16(blob[2*i]) | uint16(blob[2*i+1])<<8
+	}
+	return string(utf16.Decode(chars)), nil
+}
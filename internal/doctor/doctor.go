@@ -0,0 +1,131 @@
+// Package doctor checks that tiny-trae's environment is set up: the API,
+// the programs its tools run, the terminal, and the settings files. Each
+// problem comes with what to do about it.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Status is how a check went.
+type Status int
+
+const (
+	StatusOK Status = iota
+	// StatusWarning is for problems that only some features suffer from.
+	StatusWarning
+	StatusFailed
+)
+
+// Result is the outcome of a check.
+type Result struct {
+	Status Status
+	// Detail says what was found.
+	Detail string
+	// Fix says what to do about a warning or failure.
+	Fix string
+}
+
+// OK returns a passing result.
+func OK(detail string) Result {
+	return Result{Status: StatusOK, Detail: detail}
+}
+
+// Warn returns a warning with the way to fix it.
+func Warn(detail, fix string) Result {
+	return Result{Status: StatusWarning, Detail: detail, Fix: fix}
+}
+
+// Fail returns a failure with the way to fix it.
+func Fail(detail, fix string) Result {
+	return Result{Status: StatusFailed, Detail: detail, Fix: fix}
+}
+
+// Check is one thing to check.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) Result
+}
+
+// Run runs checks in order, printing each result to w, and returns how
+// many failed.
+func Run(ctx context.Context, w io.Writer, checks []Check) int {
+	failed := 0
+	for _, check := range checks {
+		result := check.Run(ctx)
+		label := "ok"
+		switch result.Status {
+		case StatusWarning:
+			label = "warn"
+		case StatusFailed:
+			label = "FAIL"
+			failed++
+		}
+		fmt.Fprintf(w, "[%s] %s: %s\n", label, check.Name, result.Detail)
+		if result.Fix != "" && result.Status != StatusOK {
+			fmt.Fprintf(w, "       Fix: %s\n", result.Fix)
+		}
+	}
+	return failed
+}
+
+// lookPath finds programs; tests replace it.
+var lookPath = exec.LookPath
+
+// Program checks that a program is installed. A missing program fails the
+// check if required is set, and is a warning otherwise.
+func Program(name, purpose string, required bool, fix string) Check {
+	return Check{Name: name, Run: func(context.Context) Result {
+		path, err := lookPath(name)
+		if err == nil {
+			return OK(path)
+		}
+		detail := "not found; it is used " + purpose
+		if required {
+			return Fail(detail, fix)
+		}
+		return Warn(detail, fix)
+	}}
+}
+
+// Load checks that a settings file loads. load returns what was loaded, or
+// why it could not be.
+func Load(name string, load func() (string, error), fix string) Check {
+	return Check{Name: name, Run: func(context.Context) Result {
+		detail, err := load()
+		if err != nil {
+			return Fail(err.Error(), fix)
+		}
+		return OK(detail)
+	}}
+}
+
+// Terminal checks that the standard input and output are a terminal, which
+// interactive sessions need, and reports what it can display.
+func Terminal(getenv func(string) string) Check {
+	return Check{Name: "terminal", Run: func(context.Context) Result {
+		if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+			return Warn("not running in a terminal", "Interactive sessions need a terminal; elsewhere, give a prompt with -p")
+		}
+		term := getenv("TERM")
+		if term == "" || term == "dumb" {
+			return Warn(fmt.Sprintf("TERM is %q, so the TUI may not display properly", term), "Set TERM to your terminal's type, such as xterm-256color")
+		}
+		colors := "up to 256 colors"
+		if colorterm := strings.ToLower(getenv("COLORTERM")); colorterm == "truecolor" || colorterm == "24bit" {
+			colors = "true color"
+		}
+		return OK(fmt.Sprintf("%s, %s", term, colors))
+	}}
+}
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,41 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	defer func(saved func(string) (string, error)) { lookPath = saved }(lookPath)
+	lookPath = func(name string) (string, error) {
+		if name == "git" {
+			return "/usr/bin/git", nil
+		}
+		return "", exec.ErrNotFound
+	}
+
+	checks := []Check{
+		Program("git", "for checkpoints", true, "Install git"),
+		Program("rg", "by the ripgrep tool", false, "Install ripgrep"),
+		Program("bash", "by the bash tool", true, "Install bash"),
+		Load("settings", func() (string, error) { return "", errors.New("config.yaml: bad") }, "Fix the file"),
+	}
+	var out strings.Builder
+	if failed := Run(context.Background(), &out, checks); failed != 2 {
+		t.Errorf("Expected 2 failures, got %d", failed)
+	}
+	want := `[ok] git: /usr/bin/git
+[warn] rg: not found; it is used by the ripgrep tool
+       Fix: Install ripgrep
+[FAIL] bash: not found; it is used by the bash tool
+       Fix: Install bash
+[FAIL] settings: config.yaml: bad
+       Fix: Fix the file
+`
+	if out.String() != want {
+		t.Errorf("Unexpected report:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,115 @@
+// Package export writes conversations out as markdown documents.
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"tiny-trae/internal/agent"
+)
+
+// FileName returns the name a session saved at t is written to, such as
+// trae-session-20261016-150405.md.
+func FileName(t time.Time) string {
+	return "trae-session-" + t.Format("20060102-150405") + ".md"
+}
+
+// Markdown renders the messages a frontend received as a markdown document:
+// the prompts and replies, and each tool call with its input and result.
+// Streamed pieces of replies and status messages are left out.
+func Markdown(messages []agent.Message, saved time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# tiny-trae session\n\nSaved %s\n", saved.Format("2006-01-02 15:04:05"))
+	for _, msg := range messages {
+		switch msg.Type {
+		case agent.MessageTypeUserInput:
+			fmt.Fprintf(&b, "\n## You\n\n%s\n", msg.Content)
+		case agent.MessageTypeAssistant:
+			fmt.Fprintf(&b, "\n## Trae\n\n%s\n", msg.Content)
+		case agent.MessageTypeThinking:
+			fmt.Fprintf(&b, "\n<details>\n<summary>Thinking</summary>\n\n%s\n\n</details>\n", msg.Content)
+		case agent.MessageTypeToolCall:
+			writeToolCall(&b, msg)
+		case agent.MessageTypeToolResult:
+			writeToolResult(&b, msg)
+		case agent.MessageTypeError:
+			fmt.Fprintf(&b, "\n> **Error:** %s\n", quote(msg.Content))
+		case agent.MessageTypeSystemInfo:
+			fmt.Fprintf(&b, "\n> %s\n", quote(msg.Content))
+		}
+	}
+	return b.String()
+}
+
+// writeToolCall writes a tool call's name and input.
+func writeToolCall(b *strings.Builder, msg agent.Message) {
+	var call agent.ToolCallData
+	if err := json.Unmarshal(msg.Data, &call); err != nil {
+		fmt.Fprintf(b, "\n### Tool call\n\n%s\n", msg.Content)
+		return
+	}
+	fmt.Fprintf(b, "\n### Tool: %s\n", call.ToolName)
+	if len(call.Input) > 0 {
+		var input bytes.Buffer
+		if err := json.Indent(&input, call.Input, "", "  "); err != nil {
+			input.Reset()
+			input.Write(call.Input)
+		}
+		writeFence(b, "json", input.String())
+	}
+}
+
+// writeToolResult writes a tool call's result.
+func writeToolResult(b *strings.Builder, msg agent.Message) {
+	var result agent.ToolResultData
+	if err := json.Unmarshal(msg.Data, &result); err != nil {
+		fmt.Fprintf(b, "\nResult:\n")
+		writeFence(b, "", msg.Content)
+		return
+	}
+	label := "Result"
+	if result.IsError {
+		label = "Error"
+	}
+	if result.Meta != nil {
+		label += " (" + result.Meta.Summary() + ")"
+	}
+	fmt.Fprintf(b, "\n%s of %s:\n", label, result.ToolName)
+	writeFence(b, "", result.Result)
+}
+
+// writeFence writes text as a fenced code block, with a fence longer than
+// any run of backticks in text.
+func writeFence(b *strings.Builder, language, text string) {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(b, "\n%s%s\n%s\n%s\n", fence, language, strings.TrimRight(text, "\n"), fence)
+}
+
+// quote continues a block quote over the lines of text.
+func quote(text string) string {
+	return strings.ReplaceAll(text, "\n", "\n> ")
+}
+
+// Save writes the messages as markdown to a new file named by FileName in
+// dir and returns its path.
+func Save(dir string, messages []agent.Message) (string, error) {
+	now := time.Now()
+	path := filepath.Join(dir, FileName(now))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	_, err = file.WriteString(Markdown(messages, now))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return path, err
+}
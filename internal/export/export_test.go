@@ -0,0 +1,51 @@
+package export
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"tiny-trae/internal/agent"
+)
+
+func TestMarkdown(t *testing.T) {
+	call, _ := json.Marshal(agent.ToolCallData{ToolName: "bash", ToolID: "t1", Input: json.RawMessage(`{"command":"ls"}`)})
+	result, _ := json.Marshal(agent.ToolResultData{ToolName: "bash", ToolID: "t1", Result: "main.go\n```\n"})
+	messages := []agent.Message{
+		{Type: agent.MessageTypeUserInput, Content: "list the files"},
+		{Type: agent.MessageTypeAssistantDelta, Content: "Sure"},
+		{Type: agent.MessageTypeToolCall, Content: "Executing bash", Data: call},
+		{Type: agent.MessageTypeToolResult, Content: "main.go", Data: result},
+		{Type: agent.MessageTypeAssistant, Content: "There is **one** file."},
+		{Type: agent.MessageTypeError, Content: "rate limited\nretrying"},
+	}
+
+	saved := time.Date(2026, 10, 16, 15, 4, 5, 0, time.UTC)
+	got := Markdown(messages, saved)
+	want := "# tiny-trae session\n\nSaved 2026-10-16 15:04:05\n" +
+		"\n## You\n\nlist the files\n" +
+		"\n### Tool: bash\n\n```json\n{\n  \"command\": \"ls\"\n}\n```\n" +
+		"\nResult of bash:\n\n````\nmain.go\n```\n````\n" +
+		"\n## Trae\n\nThere is **one** file.\n" +
+		"\n> **Error:** rate limited\n> retrying\n"
+	if got != want {
+		t.Errorf("Unexpected markdown:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSave(t *testing.T) {
+	dir := t.TempDir()
+	path, err := Save(dir, []agent.Message{{Type: agent.MessageTypeUserInput, Content: "hi"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(path, dir) || !strings.HasSuffix(path, ".md") || !strings.Contains(path, "trae-session-") {
+		t.Errorf("Unexpected path %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), "## You\n\nhi") {
+		t.Errorf("Unexpected file %q, %v", data, err)
+	}
+}
@@ -0,0 +1,128 @@
+package frontend
+
+import (
+	"fmt"
+	"strings"
+
+	"tiny-trae/internal/agent"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// approvalOption is a choice in the approval dialog.
+type approvalOption struct {
+	label    string
+	decision agent.ApprovalDecision
+}
+
+// approvalOptions are the approval dialog's buttons, in order.
+var approvalOptions = []approvalOption{
+	{"Accept", agent.ApprovalApprove},
+	{"Reject", agent.ApprovalDeny},
+	{"Always allow this tool", agent.ApprovalAlwaysAllow},
+}
+
+// approvalDialog is the modal that asks the user to approve a tool call,
+// showing the command or diff it will run.
+type approvalDialog struct {
+	req      agent.ApprovalRequest
+	selected int
+	// width and height are the space the dialog is centered in
+	width, height int
+	// preview scrolls previews too long for the dialog
+	preview viewport.Model
+}
+
+// dialogStyle is the dialog's frame.
+func dialogStyle() lipgloss.Style {
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(toolStyle.GetForeground()).
+		Padding(0, 1)
+}
+
+// newApprovalDialog returns a dialog for req with Accept selected, sized to
+// fit within width by height cells.
+func newApprovalDialog(req agent.ApprovalRequest, width, height int) approvalDialog {
+	d := approvalDialog{req: req, preview: viewport.New(0, 0)}
+	d.resize(width, height)
+	return d
+}
+
+// resize fits the dialog within width by height cells, wrapping the
+// preview to the dialog's width.
+func (d *approvalDialog) resize(width, height int) {
+	d.width, d.height = width, height
+	d.preview.Width = d.innerWidth()
+	preview := d.req.Preview
+	if isDiff(preview) {
+		preview = renderDiff(preview)
+	}
+	d.preview.SetContent(lipgloss.NewStyle().Width(d.preview.Width).Render(preview))
+	// The title, buttons, help, scroll note, and blank lines between them
+	// take 7 lines
+	d.preview.Height = max(min(d.preview.TotalLineCount(), height-dialogStyle().GetVerticalFrameSize()-7), 1)
+}
+
+// innerWidth returns the width of the dialog's content.
+func (d approvalDialog) innerWidth() int {
+	return max(min(d.width-dialogStyle().GetHorizontalFrameSize()-4, 100), 20)
+}
+
+// update handles a key and returns the decision once the user has made
+// one. Left/Right and Tab move between the buttons and Enter presses the
+// selected one; y, n, and a choose directly, and Esc rejects. Up/Down and
+// PgUp/PgDn scroll the preview.
+func (d approvalDialog) update(msg tea.KeyMsg) (approvalDialog, agent.ApprovalDecision, bool) {
+	switch msg.String() {
+	case "left", "shift+tab", "h":
+		d.selected = (d.selected + len(approvalOptions) - 1) % len(approvalOptions)
+	case "right", "tab", "l":
+		d.selected = (d.selected + 1) % len(approvalOptions)
+	case "enter", " ":
+		return d, approvalOptions[d.selected].decision, true
+	case "y":
+		return d, agent.ApprovalApprove, true
+	case "n", "esc":
+		return d, agent.ApprovalDeny, true
+	case "a":
+		return d, agent.ApprovalAlwaysAllow, true
+	case "up", "k":
+		d.preview.ScrollUp(1)
+	case "down", "j":
+		d.preview.ScrollDown(1)
+	case "pgup":
+		d.preview.PageUp()
+	case "pgdown":
+		d.preview.PageDown()
+	}
+	return d, "", false
+}
+
+// view renders the dialog centered in its space.
+func (d approvalDialog) view() string {
+	title := toolStyle.Render(fmt.Sprintf("Allow %s to run?", d.req.ToolName))
+
+	var buttons []string
+	for i, option := range approvalOptions {
+		button := lipgloss.NewStyle().Padding(0, 1)
+		if i == d.selected {
+			button = button.Reverse(true).Bold(true)
+		}
+		buttons = append(buttons, button.Render(option.label))
+	}
+	buttonRow := strings.Join(buttons, "  ")
+	help := systemStyle.Render("←/→ select · Enter confirm · y/n/a · Esc reject · ↑/↓ scroll")
+
+	preview := d.preview.View()
+	if !d.preview.AtBottom() {
+		preview += "\n" + systemStyle.Render(fmt.Sprintf("… %d%% shown, ↓ for more", int(d.preview.ScrollPercent()*100)))
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left, title, "", preview, "", buttonRow, help)
+	box := dialogStyle()
+	box = box.Width(d.innerWidth() + box.GetHorizontalPadding())
+	return lipgloss.Place(d.width, d.height, lipgloss.Center, lipgloss.Center, box.Render(content))
+}
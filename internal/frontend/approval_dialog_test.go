@@ -0,0 +1,49 @@
+package frontend
+
+import (
+	"strings"
+	"testing"
+
+	"tiny-trae/internal/agent"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestApprovalDialog(t *testing.T) {
+	req := agent.ApprovalRequest{ToolName: "bash", Preview: "rm -rf build"}
+	press := func(d approvalDialog, keys ...tea.KeyMsg) (approvalDialog, agent.ApprovalDecision, bool) {
+		var decision agent.ApprovalDecision
+		var done bool
+		for _, key := range keys {
+			d, decision, done = d.update(key)
+		}
+		return d, decision, done
+	}
+	right := tea.KeyMsg{Type: tea.KeyRight}
+	left := tea.KeyMsg{Type: tea.KeyLeft}
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+
+	for _, tc := range []struct {
+		keys []tea.KeyMsg
+		want agent.ApprovalDecision
+	}{
+		{[]tea.KeyMsg{enter}, agent.ApprovalApprove},
+		{[]tea.KeyMsg{right, enter}, agent.ApprovalDeny},
+		{[]tea.KeyMsg{left, enter}, agent.ApprovalAlwaysAllow},
+		{[]tea.KeyMsg{{Type: tea.KeyEsc}}, agent.ApprovalDeny},
+		{[]tea.KeyMsg{right, {Type: tea.KeyRunes, Runes: []rune("a")}}, agent.ApprovalAlwaysAllow},
+	} {
+		d := newApprovalDialog(req, 80, 20)
+		if _, decision, done := press(d, tc.keys...); !done || decision != tc.want {
+			t.Errorf("Keys %v: got %q, %v, want %q", tc.keys, decision, done, tc.want)
+		}
+	}
+
+	d, _, done := press(newApprovalDialog(req, 80, 20), right)
+	if done || d.selected != 1 {
+		t.Errorf("Expected Right to only move the selection, got %d, %v", d.selected, done)
+	}
+	if view := d.view(); !strings.Contains(view, "Allow bash to run?") || !strings.Contains(view, "rm -rf build") {
+		t.Errorf("Expected the tool and its preview in the dialog, got %q", view)
+	}
+}
@@ -0,0 +1,66 @@
+package frontend
+
+import (
+	"fmt"
+	"io"
+
+	"tiny-trae/internal/agent"
+)
+
+// Verbosity says how much a non-interactive run prints.
+type Verbosity int
+
+const (
+	// VerbosityNormal prints the replies, errors, and the summary of the
+	// tools used.
+	VerbosityNormal Verbosity = iota
+	// VerbosityQuiet prints only the final reply, and errors on stderr.
+	VerbosityQuiet
+	// VerbosityVerbose also prints each tool call's full input and result,
+	// and the model's thinking.
+	VerbosityVerbose
+)
+
+// console prints a non-interactive run's messages, as much of them as its
+// verbosity asks for.
+type console struct {
+	verbosity Verbosity
+	out       io.Writer
+	errOut    io.Writer
+	// answer is the latest reply, which a quiet console prints at the end
+	answer string
+}
+
+// print prints a message.
+func (c *console) print(msg agent.Message) {
+	if c.verbosity == VerbosityQuiet {
+		switch msg.Type {
+		case agent.MessageTypeAssistant:
+			c.answer = msg.Content
+		case agent.MessageTypeToolCall:
+			// Replies before a tool call are not the answer
+			c.answer = ""
+		case agent.MessageTypeError:
+			fmt.Fprintf(c.errOut, "Error: %s\n", msg.Content)
+		}
+		return
+	}
+
+	switch msg.Type {
+	case agent.MessageTypeAssistant, agent.MessageTypeError, agent.MessageTypeSystemInfo:
+	case agent.MessageTypeThinking, agent.MessageTypeToolCall, agent.MessageTypeToolResult:
+		if c.verbosity != VerbosityVerbose {
+			return
+		}
+	default:
+		return
+	}
+	fmt.Fprintln(c.out, messageLine(msg))
+}
+
+// finish prints the final reply of a quiet run.
+func (c *console) finish() {
+	if c.verbosity == VerbosityQuiet && c.answer != "" {
+		fmt.Fprintln(c.out, c.answer)
+	}
+}
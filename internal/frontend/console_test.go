@@ -0,0 +1,50 @@
+package frontend
+
+import (
+	"strings"
+	"testing"
+
+	"tiny-trae/internal/agent"
+)
+
+func TestConsoleVerbosity(t *testing.T) {
+	run := []agent.Message{
+		{Type: agent.MessageTypeSystemInfo, Content: "Using 1 tool"},
+		{Type: agent.MessageTypeAssistant, Content: "Let me look."},
+		{Type: agent.MessageTypeToolCall, Content: "Executing tool: read_file", Data: []byte(`{"tool_name":"read_file","tool_id":"t1","input":{"path":"a.txt"}}`)},
+		{Type: agent.MessageTypeToolResult, Data: []byte(`{"tool_name":"read_file","tool_id":"t1","result":"hello\n","is_error":false}`)},
+		{Type: agent.MessageTypeAssistant, Content: "It says hello."},
+		{Type: agent.MessageTypeError, Content: "oops"},
+	}
+	tests := []struct {
+		verbosity Verbosity
+		want      string
+		wantErr   string
+	}{
+		{VerbosityQuiet, "It says hello.\n", "Error: oops\n"},
+		{VerbosityNormal, "Using 1 tool\nTrae: Let me look.\nTrae: It says hello.\nError: oops\n", ""},
+		{VerbosityVerbose, "Using 1 tool\nTrae: Let me look.\nTool: read_file {\"path\":\"a.txt\"}\nResult (read_file): hello\nTrae: It says hello.\nError: oops\n", ""},
+	}
+	for _, tt := range tests {
+		var out, errOut strings.Builder
+		c := &console{verbosity: tt.verbosity, out: &out, errOut: &errOut}
+		for _, msg := range run {
+			c.print(msg)
+		}
+		c.finish()
+		if out.String() != tt.want || errOut.String() != tt.wantErr {
+			t.Errorf("Verbosity %d printed %q and %q on stderr, want %q and %q", tt.verbosity, out.String(), errOut.String(), tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestQuietConsoleDropsRepliesBeforeToolCalls(t *testing.T) {
+	var out strings.Builder
+	c := &console{verbosity: VerbosityQuiet, out: &out}
+	c.print(agent.Message{Type: agent.MessageTypeAssistant, Content: "Let me look."})
+	c.print(agent.Message{Type: agent.MessageTypeToolCall, Content: "Executing tool: read_file"})
+	c.finish()
+	if out.String() != "" {
+		t.Errorf("Expected no answer, got %q", out.String())
+	}
+}
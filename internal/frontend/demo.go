@@ -0,0 +1,100 @@
+package frontend
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"tiny-trae/internal/agent"
+)
+
+// Demo plays a made-up session on f, using every message type and an
+// approval request, so a frontend can be checked without a model. It
+// pauses for delay between messages, then answers each message the user
+// sends with a streamed echo until they quit.
+func Demo(f agent.Frontend, delay time.Duration) {
+	send := func(messageType agent.MessageType, content string, data any) {
+		msg := agent.Message{Type: messageType, Content: content}
+		if data != nil {
+			msg.Data, _ = json.Marshal(data)
+		}
+		f.SendMessage(msg)
+		time.Sleep(delay)
+	}
+	usage := agent.UsageData{Profile: "demo", Model: "demo-model", ContextWindow: agent.DefaultContextWindow}
+	sendUsage := func(turn int, input, output int64) {
+		usage.Turn, usage.InputTokens, usage.OutputTokens, usage.ContextTokens = turn, input, output, input+output
+		usage.Cost = float64(input)*3/1e6 + float64(output)*15/1e6
+		send(agent.MessageTypeUsage, fmt.Sprintf("%d input tokens, %d output tokens, $%.2f", input, output, usage.Cost), usage)
+	}
+	stream := func(text string) {
+		for _, word := range strings.SplitAfter(text, " ") {
+			send(agent.MessageTypeAssistantDelta, word, nil)
+		}
+		send(agent.MessageTypeAssistant, text, nil)
+	}
+
+	sendUsage(0, 0, 0)
+	send(agent.MessageTypeSystemInfo, "This is a demo session: nothing is sent to a model and no tool runs.", nil)
+	send(agent.MessageTypeUserInput, "Why does `go test` fail?", nil)
+	send(agent.MessageTypeRequest, "", agent.RequestData{Tokens: 2400})
+	send(agent.MessageTypeThinking, "The user wants to know why the tests fail. I should run them first and read the failure before changing anything.", nil)
+	stream("Let me run the tests to see the failure.")
+
+	exitCode := 1
+	send(agent.MessageTypeToolCall, "Executing tool: bash", agent.ToolCallData{
+		ToolName: "bash",
+		ToolID:   "demo_1",
+		Input:    json.RawMessage(`{"command":"go test ./..."}`),
+	})
+	output := []string{
+		"ok  \tdemo/internal/config\t0.012s\n",
+		"--- FAIL: TestParse (0.00s)\n",
+		"    parse_test.go:14: Parse(\"1,2\") = 12, want 3\n",
+		"FAIL\tdemo/internal/parse\t0.008s\n",
+	}
+	for _, line := range output {
+		send(agent.MessageTypeToolOutput, line, agent.ToolOutputData{ToolName: "bash", ToolID: "demo_1"})
+	}
+	result := strings.Join(output, "")
+	send(agent.MessageTypeToolResult, result, agent.ToolResultData{
+		ToolName: "bash",
+		ToolID:   "demo_1",
+		Result:   result,
+		IsError:  true,
+		Meta:     &agent.ToolResultMeta{Bytes: len(result), DurationMs: 1840, ExitCode: &exitCode},
+	})
+	sendUsage(1, 2400, 180)
+
+	send(agent.MessageTypeRequest, "", agent.RequestData{Tokens: 2900})
+	stream("`Parse` adds the digits instead of the numbers. Here is the fix:\n\n```go\nfor _, field := range strings.Split(s, \",\") {\n\tn, err := strconv.Atoi(field)\n\tif err != nil {\n\t\treturn 0, err\n\t}\n\tsum += n\n}\n```")
+	input := json.RawMessage(`{"path":"internal/parse/parse.go","old_str":"sum += int(c - '0')","new_str":"sum += n"}`)
+	preview := "--- internal/parse/parse.go\n+++ internal/parse/parse.go\n@@ -12 +12 @@\n-\t\tsum += int(c - '0')\n+\t\tsum += n\n"
+	decision := f.RequestApproval(agent.ApprovalRequest{ToolName: "edit_file", ToolID: "demo_2", Input: input, Preview: preview})
+	if decision == agent.ApprovalDeny {
+		send(agent.MessageTypeToolResult, "The user denied this tool call", agent.ToolResultData{ToolName: "edit_file", ToolID: "demo_2", Result: "The user denied this tool call", IsError: true})
+		send(agent.MessageTypeError, "Demo error: the edit was denied, so the tests still fail.", nil)
+	} else {
+		send(agent.MessageTypeToolCall, "Executing tool: edit_file", agent.ToolCallData{ToolName: "edit_file", ToolID: "demo_2", Input: input, Preview: preview})
+		send(agent.MessageTypeToolResult, "OK", agent.ToolResultData{
+			ToolName: "edit_file",
+			ToolID:   "demo_2",
+			Result:   "OK",
+			Meta:     &agent.ToolResultMeta{Bytes: 2, DurationMs: 3, FilesChanged: []string{"internal/parse/parse.go"}},
+		})
+		send(agent.MessageTypeError, "Demo error: this is how errors look.", nil)
+	}
+	sendUsage(1, 5300, 420)
+
+	for turn := 2; ; turn++ {
+		text, ok := f.GetUserInput()
+		if !ok {
+			return
+		}
+		send(agent.MessageTypeUserInput, text, nil)
+		send(agent.MessageTypeRequest, "", agent.RequestData{Tokens: usage.ContextTokens + int64(len(text)/4)})
+		stream("You said: " + text)
+		sendUsage(turn, usage.InputTokens+usage.ContextTokens, usage.OutputTokens+int64(len(text)/4)+3)
+	}
+}
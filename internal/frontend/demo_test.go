@@ -0,0 +1,29 @@
+package frontend
+
+import (
+	"testing"
+
+	"tiny-trae/internal/agent"
+)
+
+func TestDemo(t *testing.T) {
+	for _, decision := range []agent.ApprovalDecision{agent.ApprovalApprove, agent.ApprovalDeny} {
+		f := &fakeFrontend{decision: decision}
+		Demo(f, 0)
+
+		sent := make(map[agent.MessageType]bool)
+		for _, msg := range f.messages {
+			sent[msg.Type] = true
+		}
+		for _, messageType := range []agent.MessageType{
+			agent.MessageTypeUserInput, agent.MessageTypeAssistant, agent.MessageTypeToolCall,
+			agent.MessageTypeToolResult, agent.MessageTypeError, agent.MessageTypeSystemInfo,
+			agent.MessageTypeAssistantDelta, agent.MessageTypeUsage, agent.MessageTypeRequest,
+			agent.MessageTypeThinking, agent.MessageTypeToolOutput,
+		} {
+			if !sent[messageType] {
+				t.Errorf("With %s, the demo sent no %s message", decision, messageType)
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package frontend
+
+import (
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Diff styles, set from the theme by applyTheme
+var (
+	diffHeaderStyle = lipgloss.NewStyle().Bold(true)
+	diffHunkStyle   lipgloss.Style
+	diffAddStyle    lipgloss.Style
+	diffRemoveStyle lipgloss.Style
+)
+
+// isDiff reports whether text is a unified diff, like those from git diff
+// or the edit_file approval preview.
+func isDiff(text string) bool {
+	if strings.HasPrefix(text, "diff --git ") {
+		return true
+	}
+	header, rest, _ := strings.Cut(text, "\n")
+	return strings.HasPrefix(header, "--- ") && strings.HasPrefix(rest, "+++ ")
+}
+
+// renderDiff colors the lines of a unified diff: additions green, removals
+// red, and hunk headers cyan.
+func renderDiff(diff string) string {
+	lines := strings.Split(diff, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "diff --git "):
+			lines[i] = diffHeaderStyle.Render(line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = diffHunkStyle.Render(line)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = diffAddStyle.Render(line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = diffRemoveStyle.Render(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// labelCodeFences gives fenced code blocks without a language one detected
+// from their content, so glamour highlights them too. Blocks whose language
+// cannot be detected are left as they are.
+func labelCodeFences(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	for i := 0; i < len(lines); i++ {
+		fence, info, ok := codeFence(lines[i])
+		if !ok {
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && strings.TrimSpace(lines[end]) != fence {
+			end++
+		}
+		if info == "" && end < len(lines) {
+			if language := detectLanguage(strings.Join(lines[i+1:end], "\n")); language != "" {
+				lines[i] += language
+			}
+		}
+		i = end
+	}
+	return strings.Join(lines, "\n")
+}
+
+// codeFence reports whether line opens a fenced code block, and returns the
+// fence and the info string after it.
+func codeFence(line string) (fence, info string, ok bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return "", "", false
+	}
+	for _, marker := range []string{"```", "~~~"} {
+		if strings.HasPrefix(trimmed, marker) {
+			rest := strings.TrimLeft(trimmed, marker[:1])
+			fence = trimmed[:len(trimmed)-len(rest)]
+			return fence, strings.TrimSpace(rest), true
+		}
+	}
+	return "", "", false
+}
+
+// detectLanguage guesses the language of code for syntax highlighting and
+// returns its name, or "" if it cannot tell.
+func detectLanguage(code string) string {
+	if isDiff(code) {
+		return "diff"
+	}
+	lexer := lexers.Analyse(code)
+	if lexer == nil {
+		return ""
+	}
+	if aliases := lexer.Config().Aliases; len(aliases) > 0 {
+		return aliases[0]
+	}
+	return strings.ToLower(lexer.Config().Name)
+}
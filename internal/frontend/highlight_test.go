@@ -0,0 +1,24 @@
+package frontend
+
+import "testing"
+
+func TestIsDiff(t *testing.T) {
+	for text, want := range map[string]bool{
+		"--- main.go\n+++ main.go\n-old\n+new": true,
+		"diff --git a/x b/x\nindex 1..2":       true,
+		"--- not a diff":                       false,
+		"hello":                                false,
+	} {
+		if got := isDiff(text); got != want {
+			t.Errorf("isDiff(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestLabelCodeFences(t *testing.T) {
+	markdown := "Try this:\n\n```\n--- a.go\n+++ a.go\n-x\n+y\n```\n\n```go\nfunc main() {}\n```\n\n```\nno idea\n```"
+	want := "Try this:\n\n```diff\n--- a.go\n+++ a.go\n-x\n+y\n```\n\n```go\nfunc main() {}\n```\n\n```\nno idea\n```"
+	if got := labelCodeFences(markdown); got != want {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+}
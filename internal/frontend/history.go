@@ -0,0 +1,146 @@
+package frontend
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxHistoryEntries caps the prompts kept in the history file; older ones
+// are dropped.
+const maxHistoryEntries = 1000
+
+// HistoryPath returns the location of the prompt history file, under
+// $XDG_DATA_HOME or ~/.local/share.
+func HistoryPath() (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "tiny-trae", "history"), nil
+}
+
+// inputHistory holds the prompts submitted this session and earlier ones,
+// and the position of Up/Down navigation through them.
+type inputHistory struct {
+	// path is the file prompts are saved to, or "" to keep them for this
+	// session only
+	path    string
+	entries []string
+	// index is the entry shown in the input, or len(entries) for the draft
+	index int
+	// draft is what the user was typing before navigating the history
+	draft string
+}
+
+// add records a submitted prompt and ends any navigation. Repeating the
+// previous prompt does not add it again.
+func (h *inputHistory) add(entry string) {
+	if strings.TrimSpace(entry) != "" && (len(h.entries) == 0 || h.entries[len(h.entries)-1] != entry) {
+		h.entries = append(h.entries, entry)
+		h.save(entry)
+	}
+	h.reset()
+}
+
+// loadHistory returns a history with the prompts saved in the file at path,
+// to which new prompts are added. A missing file holds no prompts.
+func loadHistory(path string) (inputHistory, error) {
+	h := inputHistory{path: path}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return h, err
+	}
+	// Each line is a JSON string, so prompts can span lines
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		var entry string
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil && entry != "" {
+			h.entries = append(h.entries, entry)
+		}
+	}
+	h.entries = h.entries[max(len(h.entries)-maxHistoryEntries, 0):]
+	h.reset()
+	return h, nil
+}
+
+// save appends entry to the history file, rewriting the file without the
+// oldest entries once it holds more than maxHistoryEntries. Failing to save
+// only loses the entry for later sessions, so errors are ignored.
+func (h *inputHistory) save(entry string) {
+	if h.path == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return
+	}
+	if len(h.entries) > maxHistoryEntries {
+		h.entries = h.entries[len(h.entries)-maxHistoryEntries:]
+		var b bytes.Buffer
+		for _, e := range h.entries {
+			line, _ := json.Marshal(e)
+			b.Write(append(line, '\n'))
+		}
+		os.WriteFile(h.path, b.Bytes(), 0600)
+		return
+	}
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	line, _ := json.Marshal(entry)
+	file.Write(append(line, '\n'))
+}
+
+// reset ends navigation, so the next prev starts at the newest entry.
+func (h *inputHistory) reset() {
+	h.index = len(h.entries)
+	h.draft = ""
+}
+
+// prev returns the entry before the one shown, saving current as the draft
+// when navigation starts. It returns false at the oldest entry.
+func (h *inputHistory) prev(current string) (string, bool) {
+	if h.index == 0 {
+		return "", false
+	}
+	if h.index == len(h.entries) {
+		h.draft = current
+	}
+	h.index--
+	return h.entries[h.index], true
+}
+
+// next returns the entry after the one shown, or the draft after the newest
+// entry. It returns false when the draft is already shown.
+func (h *inputHistory) next() (string, bool) {
+	if h.index >= len(h.entries) {
+		return "", false
+	}
+	h.index++
+	if h.index == len(h.entries) {
+		return h.draft, true
+	}
+	return h.entries[h.index], true
+}
+
+// search returns the index of the newest entry before index before that
+// contains query, or -1 if there is none.
+func (h *inputHistory) search(query string, before int) int {
+	for i := min(before, len(h.entries)) - 1; i >= 0; i-- {
+		if strings.Contains(h.entries[i], query) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,92 @@
+package frontend
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestInputHistoryNavigation(t *testing.T) {
+	var h inputHistory
+	for _, entry := range []string{"first", "second", "second", "", "third"} {
+		h.add(entry)
+	}
+	if len(h.entries) != 3 {
+		t.Fatalf("Expected empty and repeated prompts to be skipped, got %q", h.entries)
+	}
+
+	var got []string
+	for {
+		entry, ok := h.prev("draft")
+		if !ok {
+			break
+		}
+		got = append(got, entry)
+	}
+	for {
+		entry, ok := h.next()
+		if !ok {
+			break
+		}
+		got = append(got, entry)
+	}
+	want := []string{"third", "second", "first", "second", "third", "draft"}
+	if len(got) != len(want) {
+		t.Fatalf("Got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestInputHistorySearch(t *testing.T) {
+	var h inputHistory
+	for _, entry := range []string{"fix the tests", "explain main.go", "run the tests"} {
+		h.add(entry)
+	}
+	newest := h.search("tests", len(h.entries))
+	if newest != 2 {
+		t.Errorf("Expected the newest match, got %d", newest)
+	}
+	if older := h.search("tests", newest); older != 0 {
+		t.Errorf("Expected the older match, got %d", older)
+	}
+	if none := h.search("deploy", len(h.entries)); none != -1 {
+		t.Errorf("Expected no match, got %d", none)
+	}
+}
+
+func TestInputHistoryPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tiny-trae", "history")
+	h, err := loadHistory(path)
+	if err != nil || len(h.entries) != 0 {
+		t.Fatalf("Expected an empty history, got %q, %v", h.entries, err)
+	}
+	h.add("first")
+	h.add("a prompt\nover two lines")
+
+	h, err = loadHistory(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(h.entries) != 2 || h.entries[1] != "a prompt\nover two lines" {
+		t.Errorf("Expected the saved prompts, got %q", h.entries)
+	}
+	if entry, _ := h.prev(""); entry != "a prompt\nover two lines" {
+		t.Errorf("Expected Up to recall the last prompt, got %q", entry)
+	}
+}
+
+func TestInputHistoryCap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	h, _ := loadHistory(path)
+	for i := range maxHistoryEntries + 5 {
+		h.add(fmt.Sprint(i))
+	}
+	h, _ = loadHistory(path)
+	if len(h.entries) != maxHistoryEntries || h.entries[0] != "5" {
+		t.Errorf("Expected the newest %d prompts, got %d starting with %q", maxHistoryEntries, len(h.entries), h.entries[0])
+	}
+}
@@ -0,0 +1,132 @@
+package frontend
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"tiny-trae/internal/agent"
+)
+
+// JSONLEvent is a line of the JSONL output.
+type JSONLEvent struct {
+	// Type is user, assistant, tool_call, tool_result, usage, error, or,
+	// last, result
+	Type string `json:"type"`
+	// Text is the prompt, the reply, the tool's result, or the error; for
+	// the result, it is the final reply or the error that ended the run
+	Text    string                `json:"text,omitempty"`
+	Tool    string                `json:"tool,omitempty"`
+	ToolID  string                `json:"tool_id,omitempty"`
+	Input   json.RawMessage       `json:"input,omitempty"`
+	IsError bool                  `json:"is_error,omitempty"`
+	Meta    *agent.ToolResultMeta `json:"meta,omitempty"`
+	Usage   *agent.UsageData      `json:"usage,omitempty"`
+	// Status is success or error, for the result
+	Status string `json:"status,omitempty"`
+}
+
+// JSONLFrontend reports a non-interactive run as JSON events, one per line,
+// for scripts and CI pipelines to parse. Tool calls are approved, as in the
+// TUI's non-interactive mode.
+type JSONLFrontend struct {
+	mu         sync.Mutex
+	encoder    *json.Encoder
+	lastAnswer string
+	usage      *agent.UsageData
+}
+
+// NewJSONLFrontend creates a frontend that writes events to w.
+func NewJSONLFrontend(w io.Writer) *JSONLFrontend {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return &JSONLFrontend{encoder: encoder}
+}
+
+// SendMessage writes the event for msg. Streamed pieces of replies and
+// other progress messages are left out.
+func (f *JSONLFrontend) SendMessage(msg agent.Message) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch msg.Type {
+	case agent.MessageTypeUserInput:
+		f.write(JSONLEvent{Type: "user", Text: msg.Content})
+	case agent.MessageTypeAssistant:
+		f.lastAnswer = msg.Content
+		f.write(JSONLEvent{Type: "assistant", Text: msg.Content})
+	case agent.MessageTypeToolCall:
+		var call agent.ToolCallData
+		if err := json.Unmarshal(msg.Data, &call); err != nil {
+			return
+		}
+		event := JSONLEvent{Type: "tool_call", Tool: call.ToolName, ToolID: call.ToolID}
+		if json.Valid(call.Input) {
+			event.Input = call.Input
+		}
+		f.write(event)
+	case agent.MessageTypeToolResult:
+		var result agent.ToolResultData
+		if err := json.Unmarshal(msg.Data, &result); err != nil {
+			f.write(JSONLEvent{Type: "tool_result", Text: msg.Content})
+			return
+		}
+		f.write(JSONLEvent{Type: "tool_result", Tool: result.ToolName, ToolID: result.ToolID, Text: result.Result, IsError: result.IsError, Meta: result.Meta})
+	case agent.MessageTypeUsage:
+		var usage agent.UsageData
+		if err := json.Unmarshal(msg.Data, &usage); err != nil {
+			return
+		}
+		f.usage = &usage
+		f.write(JSONLEvent{Type: "usage", Usage: &usage})
+	case agent.MessageTypeError:
+		f.write(JSONLEvent{Type: "error", Text: msg.Content})
+	}
+}
+
+// Finish writes the result event for a run that ended with err: the final
+// reply and the session's usage, or the error.
+func (f *JSONLFrontend) Finish(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	event := JSONLEvent{Type: "result", Status: "success", Text: f.lastAnswer, Usage: f.usage}
+	if err != nil {
+		event.Status = "error"
+		event.Text = err.Error()
+	}
+	f.write(event)
+}
+
+// write writes an event. A failed write cannot be reported anywhere better
+// than the output itself, so it is dropped.
+func (f *JSONLFrontend) write(event JSONLEvent) {
+	f.encoder.Encode(event)
+}
+
+// GetUserInput implements agent.Frontend; a JSONL run has only its prompt.
+func (f *JSONLFrontend) GetUserInput() (string, bool) {
+	return "", false
+}
+
+// RequestApproval implements agent.Frontend. There is nobody to ask in a
+// JSONL run, so the call is denied.
+func (f *JSONLFrontend) RequestApproval(req agent.ApprovalRequest) agent.ApprovalDecision {
+	return agent.ApprovalDeny
+}
+
+// Interrupts implements agent.Frontend.
+func (f *JSONLFrontend) Interrupts() <-chan struct{} {
+	return nil
+}
+
+// TurnCancels implements agent.Frontend.
+func (f *JSONLFrontend) TurnCancels() <-chan struct{} {
+	return nil
+}
+
+// IsInteractive implements agent.Frontend.
+func (f *JSONLFrontend) IsInteractive() bool {
+	return false
+}
+
+// Close implements agent.Frontend.
+func (f *JSONLFrontend) Close() {}
@@ -0,0 +1,49 @@
+package frontend
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"tiny-trae/internal/agent"
+)
+
+func TestJSONLFrontend(t *testing.T) {
+	var out bytes.Buffer
+	f := NewJSONLFrontend(&out)
+	call, _ := json.Marshal(agent.ToolCallData{ToolName: "bash", ToolID: "t1", Input: json.RawMessage(`{"command":"ls"}`)})
+	result, _ := json.Marshal(agent.ToolResultData{ToolName: "bash", ToolID: "t1", Result: "main.go"})
+	usage, _ := json.Marshal(agent.UsageData{Model: "claude", InputTokens: 10, OutputTokens: 2})
+	for _, msg := range []agent.Message{
+		{Type: agent.MessageTypeUserInput, Content: "list the files"},
+		{Type: agent.MessageTypeAssistantDelta, Content: "One"},
+		{Type: agent.MessageTypeToolCall, Content: "Executing bash", Data: call},
+		{Type: agent.MessageTypeToolResult, Data: result},
+		{Type: agent.MessageTypeUsage, Data: usage},
+		{Type: agent.MessageTypeAssistant, Content: "One file."},
+	} {
+		f.SendMessage(msg)
+	}
+	f.Finish(nil)
+
+	want := []string{
+		`{"type":"user","text":"list the files"}`,
+		`{"type":"tool_call","tool":"bash","tool_id":"t1","input":{"command":"ls"}}`,
+		`{"type":"tool_result","text":"main.go","tool":"bash","tool_id":"t1"}`,
+		`{"type":"usage","usage":{"profile":"","model":"claude","turn":0,"input_tokens":10,"output_tokens":2,"cost":0,"context_tokens":0,"context_window":0}}`,
+		`{"type":"assistant","text":"One file."}`,
+		`{"type":"result","text":"One file.","usage":{"profile":"","model":"claude","turn":0,"input_tokens":10,"output_tokens":2,"cost":0,"context_tokens":0,"context_window":0},"status":"success"}`,
+	}
+	if got := strings.Split(strings.TrimSpace(out.String()), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected events:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	out.Reset()
+	f.Finish(errors.New("rate limited"))
+	var event JSONLEvent
+	if err := json.Unmarshal(out.Bytes(), &event); err != nil || event.Status != "error" || event.Text != "rate limited" {
+		t.Errorf("Unexpected result %s, %v", out.String(), err)
+	}
+}
@@ -0,0 +1,141 @@
+package frontend
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/glamour"
+)
+
+const (
+	// resizeDebounce is how long the window must keep its size before the
+	// markdown is rendered again for the new width.
+	resizeDebounce = 150 * time.Millisecond
+	// maxMarkdownCache is how many rendered blocks are kept before the cache
+	// starts over.
+	maxMarkdownCache = 500
+)
+
+// markdownKey identifies a block of markdown rendered at a wrap width.
+type markdownKey struct {
+	content string
+	width   int
+}
+
+// markdownRenderer renders markdown with glamour at one wrap width at a
+// time, caching the blocks it renders so that resizing the window back and
+// forth, or toggling the preview pane, does not render them again.
+type markdownRenderer struct {
+	style    string
+	width    int
+	renderer *glamour.TermRenderer
+	cache    map[markdownKey]string
+}
+
+// newMarkdownRenderer returns a renderer for the glamour style at wrap width.
+func newMarkdownRenderer(style string, width int) *markdownRenderer {
+	r := &markdownRenderer{style: style, cache: make(map[markdownKey]string)}
+	r.setWidth(width)
+	return r
+}
+
+// setWidth changes the wrap width. The glamour renderer is only recreated
+// if the width changed.
+func (r *markdownRenderer) setWidth(width int) {
+	if r.renderer != nil && width == r.width {
+		return
+	}
+	renderer, err := glamour.NewTermRenderer(
+		glamour.WithStylePath(r.style),
+		glamour.WithWordWrap(width),
+	)
+	if err != nil {
+		// Fallback to minimal renderer if initialization fails
+		renderer, _ = glamour.NewTermRenderer()
+	}
+	r.renderer = renderer
+	r.width = width
+}
+
+// render renders content, or returns the cached rendering for this width.
+func (r *markdownRenderer) render(content string) (string, error) {
+	key := markdownKey{content: content, width: r.width}
+	if rendered, ok := r.cache[key]; ok {
+		return rendered, nil
+	}
+	rendered, err := r.renderer.Render(labelCodeFences(content))
+	if err != nil {
+		return "", err
+	}
+	// Clean up the rendered content (remove trailing newlines)
+	rendered = strings.TrimRight(rendered, "\n\r")
+	if len(r.cache) >= maxMarkdownCache {
+		clear(r.cache)
+	}
+	r.cache[key] = rendered
+	return rendered, nil
+}
+
+// assistantReply is a finished reply in the transcript, kept as markdown so
+// it can be rendered again when the width changes.
+type assistantReply struct {
+	// message is the reply's index in the transcript
+	message   int
+	timestamp string
+	content   string
+}
+
+// resizeSettledMsg is sent resizeDebounce after a window resize; id tells
+// whether another resize came since.
+type resizeSettledMsg struct {
+	id int
+}
+
+// settleResize returns a command that reports when the resize with the
+// given id has settled.
+func settleResize(id int) tea.Cmd {
+	return tea.Tick(resizeDebounce, func(time.Time) tea.Msg {
+		return resizeSettledMsg{id: id}
+	})
+}
+
+// formatReply renders an assistant reply with its timestamp and label.
+func (m *tuiModel) formatReply(timestamp, content string) string {
+	if m.markdown != nil {
+		if rendered, err := m.markdown.render(content); err == nil {
+			return fmt.Sprintf("[%s] %s\n%s", timestamp, assistantStyle.Render("Trae:"), rendered)
+		}
+	}
+	// Fallback to plain text with wrapping if rendering fails
+	content = wrapText(content, max(m.transcript.width-12, 20)-6)
+	return fmt.Sprintf("[%s] %s %s", timestamp, assistantStyle.Render("Trae:"), content)
+}
+
+// rewrap renders the replies and tool entries again if the transcript's
+// width changed since they were rendered.
+func (m *tuiModel) rewrap() {
+	width := m.markdownWidth()
+	if m.markdown == nil || width == m.markdown.width {
+		return
+	}
+	m.markdown.setWidth(width)
+	for _, reply := range m.replies {
+		m.messages[reply.message] = m.formatReply(reply.timestamp, reply.content)
+	}
+	entryWidth := max(m.transcript.width-12, 20)
+	for i, entry := range m.tools {
+		m.messages[entry.line] = entry.render(entryWidth, i == m.focusedTool)
+	}
+	m.transcript.rebuild(m.messages)
+	if m.follow {
+		m.transcript.gotoBottom()
+	}
+}
+
+// markdownWidth returns the wrap width for markdown in the transcript,
+// leaving some margin.
+func (m tuiModel) markdownWidth() int {
+	return max(m.transcript.width-10, 20)
+}
@@ -0,0 +1,38 @@
+package frontend
+
+import (
+	"strings"
+	"testing"
+
+	"tiny-trae/internal/agent"
+)
+
+func TestRewrapRendersRepliesForNewWidth(t *testing.T) {
+	m := tuiModel{width: 100, focusedTool: -1, markdown: newMarkdownRenderer("notty", 90)}
+	m.transcript.width = 100
+	reply := strings.Repeat("word ", 30)
+	m.addMessage(agent.Message{Type: agent.MessageTypeAssistant, Content: reply})
+	wide := m.messages[0]
+
+	m.width = 40
+	m.resizeTranscript()
+	m.rewrap()
+	narrow := m.messages[0]
+	if strings.Count(narrow, "\n") <= strings.Count(wide, "\n") {
+		t.Errorf("Expected the reply to wrap to more lines, got %q", narrow)
+	}
+	if got := strings.Join(m.transcript.lines, "\n"); got != narrow {
+		t.Errorf("Expected the transcript to show the rewrapped reply, got %q", got)
+	}
+
+	// Back at the old width, the cached rendering is used
+	if len(m.markdown.cache) != 2 {
+		t.Errorf("Expected a cached rendering per width, got %d", len(m.markdown.cache))
+	}
+	m.width = 100
+	m.resizeTranscript()
+	m.rewrap()
+	if m.messages[0] != wide || len(m.markdown.cache) != 2 {
+		t.Errorf("Expected the cached wide rendering, got %q", m.messages[0])
+	}
+}
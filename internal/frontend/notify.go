@@ -0,0 +1,90 @@
+package frontend
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Notify says how the TUI gets the user's attention when a turn finishes or
+// a tool call needs approval.
+type Notify string
+
+const (
+	// NotifyOff does not notify.
+	NotifyOff Notify = "off"
+	// NotifyBell rings the terminal bell and sends a terminal notification
+	// (OSC 9 and OSC 777), which terminals that support them show as
+	// desktop notifications.
+	NotifyBell Notify = "bell"
+	// NotifyDesktop also shows a desktop notification with notify-send on
+	// Linux or osascript on macOS.
+	NotifyDesktop Notify = "desktop"
+)
+
+// ParseNotify parses the value of --notify; "" means NotifyOff.
+func ParseNotify(value string) (Notify, error) {
+	switch notify := Notify(value); notify {
+	case "":
+		return NotifyOff, nil
+	case NotifyOff, NotifyBell, NotifyDesktop:
+		return notify, nil
+	}
+	return "", fmt.Errorf("unknown notification setting %q (want off, bell, or desktop)", value)
+}
+
+// notifyTitle is the title of notifications.
+const notifyTitle = "tiny-trae"
+
+// terminalNotification returns the bell and the terminal notification
+// sequences for body. Terminals ignore the sequences they do not know.
+func terminalNotification(body string) string {
+	// Control characters would end the sequences early
+	body = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, body)
+	body = strings.ReplaceAll(body, ";", ",")
+	return "\a" + // Bell
+		"\x1b]9;" + body + "\a" + // OSC 9, used by iTerm2, WezTerm, and Windows Terminal
+		"\x1b]777;notify;" + notifyTitle + ";" + body + "\a" // OSC 777, used by rxvt, foot, and Ghostty
+}
+
+// notify returns a command that notifies the user with body, or nil if the
+// setting is off or the terminal is known to have the focus, since then
+// the user is already looking.
+func (m tuiModel) notify(body string) tea.Cmd {
+	if m.notifySetting == NotifyOff || m.notifySetting == "" || m.focused {
+		return nil
+	}
+	setting := m.notifySetting
+	return func() tea.Msg {
+		os.Stdout.WriteString(terminalNotification(body))
+		if setting == NotifyDesktop {
+			desktopNotification(body)
+		}
+		return nil
+	}
+}
+
+// desktopNotification shows body as a desktop notification where a
+// notifier is available. Failures are ignored, as the bell still rang.
+func desktopNotification(body string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd":
+		cmd = exec.Command("notify-send", notifyTitle, body)
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", body, notifyTitle)
+		cmd = exec.Command("osascript", "-e", script)
+	default:
+		return
+	}
+	cmd.Run()
+}
@@ -0,0 +1,36 @@
+package frontend
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseNotify(t *testing.T) {
+	for value, want := range map[string]Notify{"": NotifyOff, "off": NotifyOff, "bell": NotifyBell, "desktop": NotifyDesktop} {
+		if got, err := ParseNotify(value); err != nil || got != want {
+			t.Errorf("ParseNotify(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	if _, err := ParseNotify("loud"); err == nil {
+		t.Error("Expected an error for an unknown setting")
+	}
+}
+
+func TestTerminalNotification(t *testing.T) {
+	sequence := terminalNotification("bash needs\x07 approval; now")
+	if !strings.HasPrefix(sequence, "\a\x1b]9;bash needs  approval, now\a") {
+		t.Errorf("Expected a bell and an OSC 9 notification, got %q", sequence)
+	}
+	if strings.Count(sequence, "\a") != 3 {
+		t.Errorf("Expected control characters in the body to be removed, got %q", sequence)
+	}
+
+	m := tuiModel{notifySetting: NotifyBell, focused: true}
+	if m.notify("done") != nil {
+		t.Error("Expected no notification while the terminal has the focus")
+	}
+	m.focused = false
+	if m.notify("done") == nil {
+		t.Error("Expected a notification in the background")
+	}
+}
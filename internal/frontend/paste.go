@@ -0,0 +1,34 @@
+package frontend
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pasteWindow is how long an Enter waits before it sends the input. Keys of
+// a paste arrive together, so if another key comes within this time, the
+// Enter was a pasted newline. Terminals with bracketed paste send pastes as
+// one message and do not need this.
+const pasteWindow = 15 * time.Millisecond
+
+// submitMsg is sent pasteWindow after an Enter; id tells whether another
+// Enter came since.
+type submitMsg struct {
+	id int
+}
+
+// submitAfterPaste returns a command that sends the submitMsg for the Enter
+// with the given id once pasteWindow has passed.
+func submitAfterPaste(id int) tea.Cmd {
+	return tea.Tick(pasteWindow, func(time.Time) tea.Msg {
+		return submitMsg{id: id}
+	})
+}
+
+// normalizeNewlines turns the CRLF and CR line endings of pasted text into
+// LF, since the input would otherwise see two line breaks, or none.
+func normalizeNewlines(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+}
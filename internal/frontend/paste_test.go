@@ -0,0 +1,53 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// inputModel returns a model waiting for the user's input.
+func inputModel() tuiModel {
+	input := textarea.New()
+	input.Focus()
+	return tuiModel{width: 80, height: 24, focusedTool: -1, textInput: input, waitingForInput: true, inputCh: make(chan string, 1)}
+}
+
+func TestBracketedPaste(t *testing.T) {
+	m := inputModel()
+	model, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("panic: boom\r\n\nmain.go:12\r\n"), Paste: true})
+	m = model.(tuiModel)
+	if got := m.textInput.Value(); got != "panic: boom\n\nmain.go:12\n" {
+		t.Errorf("Expected the paste intact, got %q", got)
+	}
+	if len(m.inputCh) != 0 {
+		t.Error("Expected the paste not to send the input")
+	}
+}
+
+func TestPasteWithoutBracketedPaste(t *testing.T) {
+	m := inputModel()
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("panic: boom")},
+		{Type: tea.KeyEnter},
+		{Type: tea.KeyRunes, Runes: []rune("main.go:12")},
+	} {
+		model, _ := m.Update(key)
+		m = model.(tuiModel)
+	}
+	// The submitMsg of the first Enter comes after the rest of the paste
+	model, _ := m.Update(submitMsg{id: 1})
+	m = model.(tuiModel)
+	if got := m.textInput.Value(); got != "panic: boom\nmain.go:12" || len(m.inputCh) != 0 {
+		t.Errorf("Expected the pasted Enter to be a newline, got %q", got)
+	}
+
+	model, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = model.(tuiModel)
+	model, _ = m.Update(submitMsg{id: m.submitID})
+	m = model.(tuiModel)
+	if len(m.inputCh) != 1 || <-m.inputCh != "panic: boom\nmain.go:12" {
+		t.Error("Expected a typed Enter to send the input")
+	}
+}
@@ -0,0 +1,94 @@
+package frontend
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// minSplitWidth is the narrowest window the preview pane is shown in; in
+// narrower windows the transcript keeps the whole width.
+const minSplitWidth = 100
+
+// previewPane is the optional side pane, toggled with Ctrl+O, that shows
+// the file the agent last read or the diff of its last edit.
+type previewPane struct {
+	visible  bool
+	title    string
+	content  string
+	viewport viewport.Model
+}
+
+// showDiff shows a diff, such as an edit_file preview.
+func (p *previewPane) showDiff(title, diff string) {
+	p.title = title
+	p.content = renderDiff(diff)
+	p.viewport.SetContent(p.content)
+	p.viewport.GotoTop()
+}
+
+// showFile shows a file's content.
+func (p *previewPane) showFile(path, content string) {
+	p.title = path
+	p.content = content
+	p.viewport.SetContent(p.content)
+	p.viewport.GotoTop()
+}
+
+// update shows what a finished tool call is about: the diff of a call with
+// a diff preview, or the result of a call with a path, such as read_file.
+func (p *previewPane) update(entry toolEntry) {
+	if isDiff(entry.call.Preview) {
+		p.showDiff(entry.call.ToolName, entry.call.Preview)
+		return
+	}
+	if entry.result == nil || entry.result.IsError {
+		return
+	}
+	var input struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal(entry.call.Input, &input); err == nil && input.Path != "" {
+		p.showFile(input.Path, entry.result.Result)
+	}
+}
+
+// width returns the pane's width in a window of the given width, or 0 if
+// the pane is not shown.
+func (p previewPane) width(windowWidth int) int {
+	if !p.visible || windowWidth < minSplitWidth {
+		return 0
+	}
+	return windowWidth * 2 / 5
+}
+
+// view renders the pane with the given size, border included.
+func (p previewPane) view(width, height int) string {
+	style := lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		BorderForeground(disabledInputStyle.GetForeground()).
+		PaddingLeft(1)
+	title := p.title
+	if title == "" {
+		title = "No file yet"
+	}
+	p.viewport.Width = width - style.GetHorizontalFrameSize()
+	p.viewport.Height = max(height-1, 1)
+	header := toolStyle.Render(truncateWidth(title, p.viewport.Width))
+	return style.Height(height).Render(header + "\n" + p.viewport.View())
+}
+
+// truncateWidth shortens s to fit width cells, keeping its end, which for
+// paths is the more telling part.
+func truncateWidth(s string, width int) string {
+	if lipgloss.Width(s) <= width || width < 2 {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && lipgloss.Width(string(runes))+1 > width {
+		runes = runes[1:]
+	}
+	return "…" + strings.TrimLeft(string(runes), " ")
+}
@@ -0,0 +1,47 @@
+package frontend
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"tiny-trae/internal/agent"
+)
+
+func TestPreviewPaneUpdate(t *testing.T) {
+	var pane previewPane
+	read := toolEntry{
+		call:   agent.ToolCallData{ToolName: "read_file", Input: json.RawMessage(`{"path":"main.go"}`)},
+		result: &agent.ToolResultData{Result: "package main\n"},
+	}
+	pane.update(read)
+	if pane.title != "main.go" || pane.content != "package main\n" {
+		t.Errorf("Expected the file, got %q: %q", pane.title, pane.content)
+	}
+
+	edit := toolEntry{call: agent.ToolCallData{
+		ToolName: "edit_file",
+		Input:    json.RawMessage(`{"path":"main.go"}`),
+		Preview:  "--- main.go\n+++ main.go\n-old\n+new",
+	}}
+	pane.update(edit)
+	if pane.title != "edit_file" || !strings.Contains(pane.content, "+new") {
+		t.Errorf("Expected the diff, got %q: %q", pane.title, pane.content)
+	}
+
+	// Failed calls leave the pane as it is
+	pane.update(toolEntry{
+		call:   agent.ToolCallData{ToolName: "read_file", Input: json.RawMessage(`{"path":"missing.go"}`)},
+		result: &agent.ToolResultData{Result: "no such file", IsError: true},
+	})
+	if pane.title != "edit_file" {
+		t.Errorf("Expected the pane to keep the diff, got %q", pane.title)
+	}
+}
+
+func TestPreviewPaneWidth(t *testing.T) {
+	pane := previewPane{visible: true}
+	if pane.width(minSplitWidth-1) != 0 || pane.width(150) != 60 {
+		t.Errorf("Unexpected widths %d, %d", pane.width(minSplitWidth-1), pane.width(150))
+	}
+}
@@ -0,0 +1,68 @@
+package frontend
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// progressLines is how many lines of a running tool's output the progress
+// panel shows.
+const progressLines = 6
+
+// outputTail keeps the last lines of a running tool's output.
+type outputTail struct {
+	toolName string
+	start    time.Time
+	lines    []string
+	// partial is the line being written, which has no newline yet
+	partial string
+}
+
+// write adds output. Styling and other escape sequences are dropped, and a
+// carriage return starts its line over, as progress bars expect.
+func (t *outputTail) write(text string) {
+	text = strings.ReplaceAll(ansi.Strip(t.partial+text), "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	t.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		t.lines = append(t.lines, lastSegment(line))
+	}
+	if len(t.lines) > progressLines {
+		t.lines = t.lines[len(t.lines)-progressLines:]
+	}
+}
+
+// tail returns the last progressLines lines, the partial one included.
+func (t outputTail) tail() []string {
+	lines := t.lines
+	if partial := lastSegment(t.partial); partial != "" {
+		lines = append(lines[:len(lines):len(lines)], partial)
+	}
+	return lines[max(len(lines)-progressLines, 0):]
+}
+
+// lastSegment returns what is left of line after its carriage returns.
+func lastSegment(line string) string {
+	return line[strings.LastIndex(line, "\r")+1:]
+}
+
+// view renders the panel: a header with the spinner, the tool, and how
+// long it has run, above the tail of its output, to fit width.
+func (t outputTail) view(spinner string, width int) string {
+	header := fmt.Sprintf(" %s %s %s", spinner, t.toolName,
+		systemStyle.Render(fmt.Sprintf("%.1fs · Esc to cancel", time.Since(t.start).Seconds())))
+	rows := []string{header}
+	for _, line := range t.tail() {
+		line = strings.ReplaceAll(line, "\t", "    ")
+		rows = append(rows, systemStyle.Render(" │ ")+ansi.Truncate(line, max(width-3, 1), "…"))
+	}
+	// Keep the panel's height while the output grows, so the transcript
+	// does not jump
+	for len(rows) < progressLines+1 {
+		rows = append(rows, systemStyle.Render(" │"))
+	}
+	return strings.Join(rows, "\n")
+}
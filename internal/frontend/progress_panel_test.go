@@ -0,0 +1,29 @@
+package frontend
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestOutputTail(t *testing.T) {
+	var tail outputTail
+	tail.write("building\r\nok  pkg/a\nok  pkg/")
+	tail.write("b\ndownloading 10%\rdownloading 50%")
+	if got := strings.Join(tail.tail(), "|"); got != "building|ok  pkg/a|ok  pkg/b|downloading 50%" {
+		t.Errorf("Unexpected tail %q", got)
+	}
+
+	tail.write("\x1b[31mFAIL\x1b[0m pkg/c\n" + strings.Repeat("line\n", 10))
+	lines := tail.tail()
+	if len(lines) != progressLines || lines[0] != "line" {
+		t.Errorf("Expected the last %d lines, got %q", progressLines, lines)
+	}
+
+	tail.toolName = "bash"
+	view := ansi.Strip(tail.view("*", 40))
+	if rows := strings.Split(view, "\n"); len(rows) != progressLines+1 || !strings.Contains(rows[0], "bash") {
+		t.Errorf("Expected a header and %d lines, got %q", progressLines, view)
+	}
+}
@@ -0,0 +1,49 @@
+package frontend
+
+import (
+	"fmt"
+
+	"tiny-trae/internal/agent"
+)
+
+// Sink receives a session's messages without taking part in it, like a
+// transcript written to a file.
+type Sink interface {
+	SendMessage(msg agent.Message)
+}
+
+// Tee is a Frontend that passes every message to its Frontend and to its
+// sinks. The user's input, approvals, and interrupts come from the Frontend
+// alone; the sinks are also told how each approval request was answered.
+// Sinks must be safe to call from several goroutines, since parallel tool
+// calls send messages at once.
+type Tee struct {
+	agent.Frontend
+	sinks []Sink
+}
+
+// NewTee creates a Tee of frontend and sinks.
+func NewTee(frontend agent.Frontend, sinks ...Sink) *Tee {
+	return &Tee{Frontend: frontend, sinks: sinks}
+}
+
+// SendMessage implements agent.Frontend.
+func (t *Tee) SendMessage(msg agent.Message) {
+	t.Frontend.SendMessage(msg)
+	for _, sink := range t.sinks {
+		sink.SendMessage(msg)
+	}
+}
+
+// RequestApproval implements agent.Frontend.
+func (t *Tee) RequestApproval(req agent.ApprovalRequest) agent.ApprovalDecision {
+	decision := t.Frontend.RequestApproval(req)
+	msg := agent.Message{
+		Type:    agent.MessageTypeSystemInfo,
+		Content: fmt.Sprintf("Approval: %s %s", req.ToolName, approvalOutcome(decision)),
+	}
+	for _, sink := range t.sinks {
+		sink.SendMessage(msg)
+	}
+	return decision
+}
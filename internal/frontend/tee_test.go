@@ -0,0 +1,49 @@
+package frontend
+
+import (
+	"testing"
+
+	"tiny-trae/internal/agent"
+)
+
+// fakeFrontend answers approval requests with a fixed decision and
+// records its messages.
+type fakeFrontend struct {
+	JSONLFrontend
+	decision agent.ApprovalDecision
+	messages []agent.Message
+}
+
+func (f *fakeFrontend) SendMessage(msg agent.Message) {
+	f.messages = append(f.messages, msg)
+}
+
+func (f *fakeFrontend) RequestApproval(agent.ApprovalRequest) agent.ApprovalDecision {
+	return f.decision
+}
+
+// sinkFunc is a Sink that calls itself.
+type sinkFunc func(agent.Message)
+
+func (f sinkFunc) SendMessage(msg agent.Message) { f(msg) }
+
+func TestTee(t *testing.T) {
+	primary := &fakeFrontend{decision: agent.ApprovalDeny}
+	var logged []agent.Message
+	tee := NewTee(primary, sinkFunc(func(msg agent.Message) { logged = append(logged, msg) }))
+
+	tee.SendMessage(agent.Message{Type: agent.MessageTypeAssistant, Content: "Hi"})
+	if decision := tee.RequestApproval(agent.ApprovalRequest{ToolName: "bash"}); decision != agent.ApprovalDeny {
+		t.Errorf("Expected the frontend's decision, got %s", decision)
+	}
+
+	if len(primary.messages) != 1 || primary.messages[0].Content != "Hi" {
+		t.Errorf("Expected the frontend to get the message, got %+v", primary.messages)
+	}
+	if len(logged) != 2 || logged[0].Content != "Hi" || logged[1].Content != "Approval: bash rejected" {
+		t.Errorf("Expected the sink to get the message and the decision, got %+v", logged)
+	}
+	if tee.IsInteractive() {
+		t.Error("Expected the tee to be as interactive as its frontend")
+	}
+}
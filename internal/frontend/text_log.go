@@ -0,0 +1,77 @@
+package frontend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"tiny-trae/internal/agent"
+)
+
+// TextLog is a Sink that writes a session as a plain-text transcript: the
+// user's messages, the replies, tool calls with their input and result,
+// errors, and notices, each line stamped with the time.
+type TextLog struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+}
+
+// NewTextLog creates a TextLog that writes to w.
+func NewTextLog(w io.Writer) *TextLog {
+	return &TextLog{w: w, now: time.Now}
+}
+
+// SendMessage implements Sink. Streamed pieces of replies and other
+// progress messages are left out.
+func (l *TextLog) SendMessage(msg agent.Message) {
+	text := messageLine(msg)
+	if text == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// A transcript that cannot be written is not worth stopping the
+	// session for
+	fmt.Fprintf(l.w, "[%s] %s\n", l.now().Format("15:04:05"), text)
+}
+
+// messageLine describes a message in plain text, or returns "" for
+// messages that only report progress.
+func messageLine(msg agent.Message) string {
+	var text string
+	switch msg.Type {
+	case agent.MessageTypeUserInput:
+		text = "You: " + msg.Content
+	case agent.MessageTypeAssistant:
+		text = "Trae: " + msg.Content
+	case agent.MessageTypeThinking:
+		text = "Thinking: " + msg.Content
+	case agent.MessageTypeToolCall:
+		var call agent.ToolCallData
+		if json.Unmarshal(msg.Data, &call) != nil {
+			text = msg.Content
+			break
+		}
+		text = fmt.Sprintf("Tool: %s %s", call.ToolName, call.Input)
+	case agent.MessageTypeToolResult:
+		var result agent.ToolResultData
+		if json.Unmarshal(msg.Data, &result) != nil {
+			text = "Result: " + msg.Content
+			break
+		}
+		label := "Result"
+		if result.IsError {
+			label = "Failed"
+		}
+		text = fmt.Sprintf("%s (%s): %s", label, result.ToolName, result.Result)
+	case agent.MessageTypeError:
+		text = "Error: " + msg.Content
+	case agent.MessageTypeSystemInfo:
+		text = msg.Content
+	}
+	return strings.TrimRight(text, "\n")
+}
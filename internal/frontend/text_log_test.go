@@ -0,0 +1,34 @@
+package frontend
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"tiny-trae/internal/agent"
+)
+
+func TestTextLog(t *testing.T) {
+	var out strings.Builder
+	log := NewTextLog(&out)
+	log.now = func() time.Time { return time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC) }
+
+	for _, msg := range []agent.Message{
+		{Type: agent.MessageTypeUserInput, Content: "read a.txt"},
+		{Type: agent.MessageTypeAssistantDelta, Content: "Let"},
+		{Type: agent.MessageTypeToolCall, Data: []byte(`{"tool_name":"read_file","tool_id":"t1","input":{"path":"a.txt"}}`)},
+		{Type: agent.MessageTypeToolResult, Data: []byte(`{"tool_name":"read_file","tool_id":"t1","result":"no such file\n","is_error":true}`)},
+		{Type: agent.MessageTypeAssistant, Content: "There is no a.txt."},
+	} {
+		log.SendMessage(msg)
+	}
+
+	want := `[15:04:05] You: read a.txt
+[15:04:05] Tool: read_file {"path":"a.txt"}
+[15:04:05] Failed (read_file): no such file
+[15:04:05] Trae: There is no a.txt.
+`
+	if out.String() != want {
+		t.Errorf("Unexpected transcript:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
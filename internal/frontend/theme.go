@@ -0,0 +1,154 @@
+package frontend
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/lipgloss"
+	"gopkg.in/yaml.v3"
+)
+
+// Theme maps the roles of the TUI's text to colors. Colors are ANSI color
+// numbers such as "42" or hex colors such as "#5f8700".
+type Theme struct {
+	// Markdown is the glamour style assistant replies are rendered with:
+	// the name of a standard style such as "dark" or "light", or the path
+	// to a glamour JSON style file.
+	Markdown string `yaml:"markdown"`
+
+	User      string `yaml:"user"`
+	Assistant string `yaml:"assistant"`
+	Tool      string `yaml:"tool"`
+	Error     string `yaml:"error"`
+	System    string `yaml:"system"`
+	// Accent colors the title and the spinner.
+	Accent              string `yaml:"accent"`
+	Border              string `yaml:"border"`
+	StatusBar           string `yaml:"status_bar"`
+	StatusBarBackground string `yaml:"status_bar_background"`
+	DiffAdd             string `yaml:"diff_add"`
+	DiffRemove          string `yaml:"diff_remove"`
+	DiffHunk            string `yaml:"diff_hunk"`
+}
+
+// DarkTheme suits terminals with a dark background.
+var DarkTheme = Theme{
+	Markdown:            "dark",
+	User:                "10",
+	Assistant:           "14",
+	Tool:                "11",
+	Error:               "196",
+	System:              "240",
+	Accent:              "13",
+	Border:              "12",
+	StatusBar:           "250",
+	StatusBarBackground: "236",
+	DiffAdd:             "2",
+	DiffRemove:          "1",
+	DiffHunk:            "6",
+}
+
+// LightTheme suits terminals with a light background.
+var LightTheme = Theme{
+	Markdown:            "light",
+	User:                "28",
+	Assistant:           "25",
+	Tool:                "130",
+	Error:               "160",
+	System:              "244",
+	Accent:              "127",
+	Border:              "26",
+	StatusBar:           "236",
+	StatusBarBackground: "253",
+	DiffAdd:             "28",
+	DiffRemove:          "160",
+	DiffHunk:            "31",
+}
+
+// fields returns the theme's settings, for overriding them one by one.
+func (t *Theme) fields() []*string {
+	return []*string{
+		&t.Markdown, &t.User, &t.Assistant, &t.Tool, &t.Error, &t.System, &t.Accent, &t.Border,
+		&t.StatusBar, &t.StatusBarBackground, &t.DiffAdd, &t.DiffRemove, &t.DiffHunk,
+	}
+}
+
+// override replaces the settings of t that other sets.
+func (t *Theme) override(other Theme) {
+	for i, field := range other.fields() {
+		if *field != "" {
+			*t.fields()[i] = *field
+		}
+	}
+}
+
+// themeConfig is the format of the user's theme file.
+type themeConfig struct {
+	// Theme is the base theme: dark, light, or auto.
+	Theme string `yaml:"theme"`
+	// Colors overrides settings of the base theme.
+	Colors Theme `yaml:"colors"`
+}
+
+// ThemeConfigPath returns the location of the user's theme file.
+func ThemeConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tiny-trae", "theme.yaml"), nil
+}
+
+// LoadTheme returns the named theme, dark, light, or auto, with the colors
+// from the user's theme file applied. An empty name uses the theme the file
+// names, or auto, which picks dark or light to match the terminal.
+func LoadTheme(name string) (Theme, error) {
+	var config themeConfig
+	if path, err := ThemeConfigPath(); err == nil {
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return Theme{}, err
+		}
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return Theme{}, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	var theme Theme
+	switch name = cmp.Or(name, config.Theme, "auto"); name {
+	case "dark":
+		theme = DarkTheme
+	case "light":
+		theme = LightTheme
+	case "auto":
+		theme = LightTheme
+		if lipgloss.HasDarkBackground() {
+			theme = DarkTheme
+		}
+	default:
+		return Theme{}, fmt.Errorf("unknown theme %q (want dark, light, or auto)", name)
+	}
+	theme.override(config.Colors)
+	return theme, nil
+}
+
+// applyTheme sets the TUI's styles to the theme's colors.
+func applyTheme(t Theme) {
+	color := func(c string) lipgloss.Color { return lipgloss.Color(c) }
+	titleStyle = lipgloss.NewStyle().Bold(true).Foreground(color(t.Accent)).MarginLeft(1)
+	userStyle = lipgloss.NewStyle().Bold(true).Foreground(color(t.User))
+	assistantStyle = lipgloss.NewStyle().Bold(true).Foreground(color(t.Assistant))
+	toolStyle = lipgloss.NewStyle().Bold(true).Foreground(color(t.Tool))
+	errorStyle = lipgloss.NewStyle().Bold(true).Foreground(color(t.Error))
+	systemStyle = lipgloss.NewStyle().Foreground(color(t.System))
+	statusBarStyle = lipgloss.NewStyle().Foreground(color(t.StatusBar)).Background(color(t.StatusBarBackground))
+	inputStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(color(t.Border)).Padding(0, 1)
+	disabledInputStyle = inputStyle.BorderForeground(color(t.System)).Foreground(color(t.System))
+	spinnerStyle = lipgloss.NewStyle().Foreground(color(t.Accent))
+	diffAddStyle = lipgloss.NewStyle().Foreground(color(t.DiffAdd))
+	diffRemoveStyle = lipgloss.NewStyle().Foreground(color(t.DiffRemove))
+	diffHunkStyle = lipgloss.NewStyle().Foreground(color(t.DiffHunk))
+}
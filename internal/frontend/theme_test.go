@@ -0,0 +1,43 @@
+package frontend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadTheme(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	theme, err := LoadTheme("light")
+	if err != nil || theme != LightTheme {
+		t.Errorf("Expected the light theme without a theme file, got %+v, %v", theme, err)
+	}
+
+	dir := filepath.Join(configHome, "tiny-trae")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := "theme: light\ncolors:\n  user: \"#00ff00\"\n  markdown: notty\n"
+	if err := os.WriteFile(filepath.Join(dir, "theme.yaml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	theme, err = LoadTheme("")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if theme.User != "#00ff00" || theme.Markdown != "notty" || theme.Assistant != LightTheme.Assistant {
+		t.Errorf("Expected the light theme with overrides, got %+v", theme)
+	}
+
+	// The flag picks the base theme; the file's colors still apply
+	if theme, _ := LoadTheme("dark"); theme.User != "#00ff00" || theme.Assistant != DarkTheme.Assistant {
+		t.Errorf("Expected the dark theme with overrides, got %+v", theme)
+	}
+
+	if _, err := LoadTheme("solarized"); err == nil {
+		t.Error("Expected an error for an unknown theme")
+	}
+}
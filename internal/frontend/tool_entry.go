@@ -0,0 +1,182 @@
+package frontend
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"tiny-trae/internal/agent"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	// collapsedResultLength is how many cells of a result a collapsed entry
+	// shows.
+	collapsedResultLength = 200
+	// collapsedDiffLines is how many lines of an edit's diff a collapsed
+	// entry shows.
+	collapsedDiffLines = 20
+)
+
+// focusedStyle marks the tool entry focused with Tab.
+var focusedStyle = lipgloss.NewStyle().Reverse(true)
+
+// toolEntry is a tool call shown in the transcript together with its
+// result. It is collapsed to one summary line unless expanded, which shows
+// the full input and result.
+type toolEntry struct {
+	// line is the entry's index in tuiModel.messages
+	line      int
+	timestamp string
+	call      agent.ToolCallData
+	result    *agent.ToolResultData
+	expanded  bool
+	// thinking, if set, makes the entry the model's extended thinking
+	// instead of a tool call; it expands and collapses the same way
+	thinking string
+}
+
+// render formats the entry to fit width.
+func (e toolEntry) render(width int, focused bool) string {
+	marker := "▸"
+	if e.expanded {
+		marker = "▾"
+	}
+	if focused {
+		marker = focusedStyle.Render(marker)
+	}
+	if e.thinking != "" {
+		return e.renderThinking(width, marker)
+	}
+
+	diff := e.diff()
+	var header string
+	switch {
+	case e.result == nil && diff != "" && e.path() != "":
+		header = fmt.Sprintf("%s %s", toolStyle.Render("Tool:"), wrapText(fmt.Sprintf("Editing %s (%s)", e.path(), e.call.ToolName), width-6))
+	case e.result == nil:
+		header = fmt.Sprintf("%s %s", toolStyle.Render("Tool:"), wrapText("Executing "+e.call.ToolName, width-6))
+	case e.expanded:
+		label := toolStyle.Render("Result")
+		if e.result.IsError {
+			label = errorStyle.Render("Error")
+		}
+		header = fmt.Sprintf("%s %s%s", label, e.call.ToolName, e.summary())
+	case e.result.IsError:
+		text := wrapText(fmt.Sprintf("%s%s: %s", e.call.ToolName, e.summary(), oneLine(e.result.Result)), width-8)
+		header = fmt.Sprintf("%s %s", errorStyle.Render("Error"), errorStyle.Render(text))
+	default:
+		result := ansi.Truncate(oneLine(e.result.Result), collapsedResultLength, "...")
+		header = fmt.Sprintf("%s %s", toolStyle.Render("Result"), wrapText(fmt.Sprintf("%s%s: %s", e.call.ToolName, e.summary(), result), width-8))
+	}
+	text := fmt.Sprintf("[%s] %s %s", e.timestamp, marker, header)
+	body := lipgloss.NewStyle().Width(width).PaddingLeft(2)
+	if !e.expanded {
+		if diff == "" {
+			return text
+		}
+		// Edits show what they change even when collapsed
+		lines := strings.Split(diff, "\n")
+		if len(lines) > collapsedDiffLines {
+			more := fmt.Sprintf("... %d more lines", len(lines)-collapsedDiffLines)
+			lines = append(lines[:collapsedDiffLines], systemStyle.Render(more))
+		}
+		return text + "\n" + body.Render(renderDiff(strings.Join(lines, "\n")))
+	}
+
+	var b strings.Builder
+	b.WriteString(text)
+	if diff != "" {
+		// The diff shows the input more readably than its JSON
+		fmt.Fprintf(&b, "\n%s\n%s", systemStyle.Render("  Diff:"), body.Render(renderDiff(diff)))
+	} else if len(e.call.Input) > 0 {
+		var input bytes.Buffer
+		if err := json.Indent(&input, e.call.Input, "", "  "); err != nil {
+			input.Reset()
+			input.Write(e.call.Input)
+		}
+		fmt.Fprintf(&b, "\n%s\n%s", systemStyle.Render("  Input:"), body.Render(input.String()))
+	}
+	if e.result != nil && e.result.Result != diff {
+		result := e.result.Result
+		if isDiff(result) {
+			result = renderDiff(result)
+		}
+		fmt.Fprintf(&b, "\n%s\n%s", systemStyle.Render("  Result:"), body.Render(result))
+	}
+	return b.String()
+}
+
+// renderThinking formats a thinking entry, dimmed so it stands back from
+// the replies.
+func (e toolEntry) renderThinking(width int, marker string) string {
+	style := systemStyle.Italic(true)
+	if !e.expanded {
+		return fmt.Sprintf("[%s] %s %s", e.timestamp, marker, style.Render("Thinking… (click or Enter to expand)"))
+	}
+	body := style.Width(width).PaddingLeft(2).Render(e.thinking)
+	return fmt.Sprintf("[%s] %s %s\n%s", e.timestamp, marker, style.Render("Thinking"), body)
+}
+
+// editInput is the input of edit_file.
+type editInput struct {
+	Path   string `json:"path"`
+	OldStr string `json:"old_str"`
+	NewStr string `json:"new_str"`
+}
+
+// diff returns the change an edit makes as a diff: the one the tool
+// reports, or else its preview, or for edit_file, one made from old_str
+// and new_str. It returns "" for other calls.
+func (e toolEntry) diff() string {
+	switch {
+	case e.result != nil && !e.result.IsError && isDiff(e.result.Result):
+		return e.result.Result
+	case isDiff(e.call.Preview):
+		return e.call.Preview
+	case e.call.ToolName != "edit_file":
+		return ""
+	}
+	var input editInput
+	if err := json.Unmarshal(e.call.Input, &input); err != nil || input.Path == "" {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", input.Path, input.Path)
+	if input.OldStr != "" {
+		for _, line := range strings.Split(input.OldStr, "\n") {
+			fmt.Fprintf(&b, "-%s\n", line)
+		}
+	}
+	for _, line := range strings.Split(input.NewStr, "\n") {
+		fmt.Fprintf(&b, "+%s\n", line)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// path returns the path in the call's input, or "" if it has none.
+func (e toolEntry) path() string {
+	var input editInput
+	if err := json.Unmarshal(e.call.Input, &input); err != nil {
+		return ""
+	}
+	return input.Path
+}
+
+// summary returns the result's metadata as " (summary)", or "" if it has
+// none.
+func (e toolEntry) summary() string {
+	if e.result == nil || e.result.Meta == nil {
+		return ""
+	}
+	return " (" + e.result.Meta.Summary() + ")"
+}
+
+// oneLine joins the lines of text, collapsing runs of whitespace, for the
+// summary line of a collapsed entry.
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,85 @@
+package frontend
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"tiny-trae/internal/agent"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestToolResultJoinsItsCall(t *testing.T) {
+	m := tuiModel{width: 80, focusedTool: -1}
+	m.transcript.width = 80
+	call, _ := json.Marshal(agent.ToolCallData{ToolName: "bash", ToolID: "t1", Input: json.RawMessage(`{"command":"ls"}`)})
+	m.addMessage(agent.Message{Type: agent.MessageTypeToolCall, Data: call})
+	result, _ := json.Marshal(agent.ToolResultData{ToolName: "bash", ToolID: "t1", Result: strings.Repeat("x", 300)})
+	m.addMessage(agent.Message{Type: agent.MessageTypeToolResult, Data: result})
+
+	if len(m.messages) != 1 || len(m.tools) != 1 {
+		t.Fatalf("Expected one entry for the call and its result, got %q", m.messages)
+	}
+	if strings.Contains(m.messages[0], "command") || strings.Count(m.messages[0], "x") > collapsedResultLength {
+		t.Errorf("Expected a collapsed entry, got %q", m.messages[0])
+	}
+
+	m.tools[0].expanded = true
+	m.renderToolEntry(0)
+	if !strings.Contains(m.messages[0], `"command": "ls"`) || !strings.Contains(m.messages[0], strings.Repeat("x", 60)) {
+		t.Errorf("Expected the full input and result, got %q", m.messages[0])
+	}
+
+	// Denied calls are not announced, so their result is an entry of its own
+	denied, _ := json.Marshal(agent.ToolResultData{ToolName: "edit_file", ToolID: "t2", Result: "denied", IsError: true})
+	m.addMessage(agent.Message{Type: agent.MessageTypeToolResult, Data: denied})
+	if len(m.messages) != 2 || len(m.tools) != 2 {
+		t.Errorf("Expected a second entry, got %q", m.messages)
+	}
+}
+
+func TestEditShowsDiffInline(t *testing.T) {
+	input := json.RawMessage(`{"path":"main.go","old_str":"a := 1","new_str":"a := 2"}`)
+	entry := toolEntry{timestamp: "10:00:00", call: agent.ToolCallData{ToolName: "edit_file", ToolID: "t1", Input: input}}
+
+	text := ansi.Strip(entry.render(80, false))
+	if !strings.Contains(text, "Editing main.go") || !strings.Contains(text, "-a := 1") || !strings.Contains(text, "+a := 2") {
+		t.Errorf("Expected the running edit to show its diff, got %q", text)
+	}
+
+	entry.result = &agent.ToolResultData{ToolName: "edit_file", ToolID: "t1", Result: "OK"}
+	if text := ansi.Strip(entry.render(80, false)); !strings.Contains(text, "edit_file: OK") || !strings.Contains(text, "+a := 2") {
+		t.Errorf("Expected the collapsed result to keep the diff, got %q", text)
+	}
+
+	long := strings.Repeat("line\n", 50)
+	entry.call.Input, _ = json.Marshal(map[string]string{"path": "big.txt", "new_str": long})
+	if text := ansi.Strip(entry.render(80, false)); strings.Count(text, "+line") != collapsedDiffLines-2 || !strings.Contains(text, "more lines") {
+		t.Errorf("Expected a collapsed entry to cut long diffs short, got %q", text)
+	}
+	entry.expanded = true
+	if text := ansi.Strip(entry.render(80, false)); strings.Count(text, "+line") != 50 {
+		t.Errorf("Expected an expanded entry to show the whole diff, got %q", text)
+	}
+}
+
+func TestThinkingExpandsOnClick(t *testing.T) {
+	m := tuiModel{width: 80, height: 24, focusedTool: -1, textInput: textarea.New()}
+	m.transcript.width, m.transcript.height = 80, 20
+	m.addMessage(agent.Message{Type: agent.MessageTypeUserInput, Content: "why?"})
+	m.addMessage(agent.Message{Type: agent.MessageTypeThinking, Content: "Because of the parser."})
+
+	if strings.Contains(m.messages[1], "parser") || !strings.Contains(ansi.Strip(m.messages[1]), "Thinking…") {
+		t.Errorf("Expected a collapsed thinking block, got %q", m.messages[1])
+	}
+
+	click := tea.MouseMsg{X: 5, Y: 1, Action: tea.MouseActionPress, Button: tea.MouseButtonLeft}
+	model, _ := m.Update(click)
+	m = model.(tuiModel)
+	if !m.tools[0].expanded || !strings.Contains(m.messages[1], "parser") {
+		t.Errorf("Expected the click to expand the thinking, got %q", m.messages[1])
+	}
+}
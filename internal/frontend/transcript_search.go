@@ -0,0 +1,97 @@
+package frontend
+
+import (
+	"regexp"
+	"slices"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Search highlight styles
+var (
+	searchMatchStyle   = lipgloss.NewStyle().Reverse(true)
+	searchCurrentStyle = lipgloss.NewStyle().Reverse(true).Bold(true).Underline(true)
+)
+
+// transcriptSearch is the state of a search through the transcript, opened
+// with Ctrl+/, or / while the input is unavailable.
+type transcriptSearch struct {
+	active bool
+	query  string
+	// pattern matches query case-insensitively
+	pattern *regexp.Regexp
+	// matches are the transcript lines that match, top to bottom, and
+	// current is the index of the selected one
+	matches []int
+	current int
+	// found is set once matches are found in the transcript at version
+	found   bool
+	version int
+}
+
+// setQuery changes the query and selects the last, newest match.
+func (s *transcriptSearch) setQuery(query string) {
+	s.query = query
+	s.pattern = nil
+	if query != "" {
+		s.pattern = regexp.MustCompile("(?i)" + regexp.QuoteMeta(query))
+	}
+	s.matches = nil
+	s.current = -1
+	s.found = false
+}
+
+// find finds the lines of the transcript that match, unless they were found
+// since it last changed.
+func (s *transcriptSearch) find(v transcriptView) {
+	if s.found && s.version == v.version {
+		return
+	}
+	s.found, s.version = true, v.version
+	s.matches = s.matches[:0]
+	if s.pattern == nil {
+		return
+	}
+	for i := range v.lineCount() {
+		if s.pattern.MatchString(ansi.Strip(v.line(i))) {
+			s.matches = append(s.matches, i)
+		}
+	}
+	if s.current < 0 || s.current >= len(s.matches) {
+		s.current = len(s.matches) - 1
+	}
+}
+
+// decorate highlights the matches in the i-th transcript line. Matching
+// lines lose their other styling.
+func (s transcriptSearch) decorate(i int, line string) string {
+	n, ok := slices.BinarySearch(s.matches, i)
+	if !ok {
+		return line
+	}
+	style := searchMatchStyle
+	if n == s.current {
+		style = searchCurrentStyle
+	}
+	return s.pattern.ReplaceAllStringFunc(ansi.Strip(line), func(match string) string {
+		return style.Render(match)
+	})
+}
+
+// move selects the match delta places further down, wrapping around.
+func (s *transcriptSearch) move(delta int) {
+	if len(s.matches) == 0 {
+		return
+	}
+	s.current = ((s.current+delta)%len(s.matches) + len(s.matches)) % len(s.matches)
+}
+
+// line returns the transcript line of the selected match, or -1 if there
+// is none.
+func (s transcriptSearch) line() int {
+	if s.current < 0 || s.current >= len(s.matches) {
+		return -1
+	}
+	return s.matches[s.current]
+}
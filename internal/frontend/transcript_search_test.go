@@ -0,0 +1,43 @@
+package frontend
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestTranscriptSearch(t *testing.T) {
+	var v transcriptView
+	v.append("[10:00:00] You: fix the " + toolStyle.Render("Parser"))
+	v.append("[10:00:05] Trae: done\n[10:01:00] You: now test the parser")
+
+	var s transcriptSearch
+	s.setQuery("parser")
+	s.find(v)
+	if len(s.matches) != 2 || s.matches[0] != 0 || s.matches[1] != 2 {
+		t.Fatalf("Expected matches on lines 0 and 2, got %v", s.matches)
+	}
+	if s.line() != 2 {
+		t.Errorf("Expected the newest match to be selected, got line %d", s.line())
+	}
+	for i := range v.lineCount() {
+		if highlighted := s.decorate(i, v.line(i)); ansi.Strip(highlighted) != ansi.Strip(v.line(i)) {
+			t.Errorf("Expected highlighting to keep the text, got %q", ansi.Strip(highlighted))
+		}
+	}
+
+	s.move(-1)
+	if s.line() != 0 {
+		t.Errorf("Expected the previous match, got line %d", s.line())
+	}
+	s.move(-1)
+	if s.line() != 2 {
+		t.Errorf("Expected the search to wrap around, got line %d", s.line())
+	}
+
+	s.setQuery("missing")
+	s.find(v)
+	if s.line() != -1 {
+		t.Errorf("Expected no match, got line %d", s.line())
+	}
+}
@@ -0,0 +1,193 @@
+package frontend
+
+import (
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// mouseWheelLines is how many lines one turn of the mouse wheel scrolls.
+const mouseWheelLines = 3
+
+// transcriptView shows the transcript and scrolls through it. Unlike
+// viewport.Model, which splits and measures all of its content whenever it
+// changes, it keeps the transcript as lines and updates them message by
+// message, so adding a message or redrawing the screen costs the same
+// however long the session has been.
+type transcriptView struct {
+	width   int
+	height  int
+	yOffset int
+	// lines are the rendered lines of all messages, and starts the index of
+	// each message's first line
+	lines  []string
+	starts []int
+	// tail are lines shown after the messages, such as the reply being
+	// streamed
+	tail []string
+	// version changes whenever the lines do
+	version int
+}
+
+// append adds a message's rendered text below the others.
+func (v *transcriptView) append(text string) {
+	v.starts = append(v.starts, len(v.lines))
+	v.lines = append(v.lines, strings.Split(text, "\n")...)
+	v.version++
+}
+
+// replace changes the rendered text of the i-th message. Only the lines
+// below it move, and only if its line count changes.
+func (v *transcriptView) replace(i int, text string) {
+	lines := strings.Split(text, "\n")
+	start, end := v.starts[i], len(v.lines)
+	if i+1 < len(v.starts) {
+		end = v.starts[i+1]
+	}
+	if len(lines) == end-start {
+		copy(v.lines[start:end], lines)
+	} else {
+		rest := append([]string(nil), v.lines[end:]...)
+		v.lines = append(append(v.lines[:start], lines...), rest...)
+		for j := i + 1; j < len(v.starts); j++ {
+			v.starts[j] += len(lines) - (end - start)
+		}
+	}
+	v.version++
+}
+
+// rebuild replaces all messages with texts.
+func (v *transcriptView) rebuild(texts []string) {
+	v.lines, v.starts = v.lines[:0], v.starts[:0]
+	for _, text := range texts {
+		v.append(text)
+	}
+}
+
+// setTail sets the lines shown after the messages.
+func (v *transcriptView) setTail(tail []string) {
+	v.tail = tail
+	v.version++
+}
+
+// start returns the first line of the i-th message.
+func (v transcriptView) start(i int) int {
+	return v.starts[i]
+}
+
+// messageAt returns the message shown on the given row of the screen, or -1
+// if the row shows none.
+func (v transcriptView) messageAt(row int) int {
+	line := v.yOffset + row
+	if row < 0 || row >= v.height || line >= len(v.lines) {
+		return -1
+	}
+	i, found := slices.BinarySearch(v.starts, line)
+	if !found {
+		i--
+	}
+	return i
+}
+
+// lineCount returns the number of lines, including the tail.
+func (v transcriptView) lineCount() int {
+	return len(v.lines) + len(v.tail)
+}
+
+// line returns the i-th line, counting the tail after the messages.
+func (v transcriptView) line(i int) string {
+	if i < len(v.lines) {
+		return v.lines[i]
+	}
+	return v.tail[i-len(v.lines)]
+}
+
+// maxYOffset returns the offset that shows the last line at the bottom.
+func (v transcriptView) maxYOffset() int {
+	return max(v.lineCount()-v.height, 0)
+}
+
+// setYOffset scrolls so line n is at the top, as far as the lines allow.
+func (v *transcriptView) setYOffset(n int) {
+	v.yOffset = min(max(n, 0), v.maxYOffset())
+}
+
+// scrollUp scrolls up n lines.
+func (v *transcriptView) scrollUp(n int) {
+	v.setYOffset(v.yOffset - n)
+}
+
+// scrollDown scrolls down n lines.
+func (v *transcriptView) scrollDown(n int) {
+	v.setYOffset(v.yOffset + n)
+}
+
+// pageUp scrolls up one screen.
+func (v *transcriptView) pageUp() {
+	v.scrollUp(v.height)
+}
+
+// pageDown scrolls down one screen.
+func (v *transcriptView) pageDown() {
+	v.scrollDown(v.height)
+}
+
+// gotoTop scrolls to the first line.
+func (v *transcriptView) gotoTop() {
+	v.yOffset = 0
+}
+
+// gotoBottom scrolls to the last line.
+func (v *transcriptView) gotoBottom() {
+	v.yOffset = v.maxYOffset()
+}
+
+// atBottom reports whether the last line is shown.
+func (v transcriptView) atBottom() bool {
+	return v.yOffset >= v.maxYOffset()
+}
+
+// scrollPercent returns how far down the transcript is scrolled, from 0 to 1.
+func (v transcriptView) scrollPercent() float64 {
+	if v.maxYOffset() == 0 {
+		return 1
+	}
+	return float64(v.yOffset) / float64(v.maxYOffset())
+}
+
+// update scrolls with the mouse wheel.
+func (v *transcriptView) update(msg tea.MouseMsg) {
+	if msg.Action != tea.MouseActionPress {
+		return
+	}
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		v.scrollUp(mouseWheelLines)
+	case tea.MouseButtonWheelDown:
+		v.scrollDown(mouseWheelLines)
+	}
+}
+
+// view renders the lines on screen, each passed through decorate if it is
+// not nil. Only these lines are measured, so drawing does not depend on the
+// length of the transcript.
+func (v transcriptView) view(decorate func(i int, line string) string) string {
+	v.setYOffset(v.yOffset)
+	rows := make([]string, v.height)
+	for row := range rows {
+		i := v.yOffset + row
+		line := ""
+		if i < v.lineCount() {
+			line = v.line(i)
+			if decorate != nil {
+				line = decorate(i, line)
+			}
+			line = ansi.Truncate(line, v.width, "")
+		}
+		// Pad to the full width so the preview pane lines up beside it
+		rows[row] = line + strings.Repeat(" ", max(v.width-ansi.StringWidth(line), 0))
+	}
+	return strings.Join(rows, "\n")
+}
@@ -0,0 +1,50 @@
+package frontend
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTranscriptViewReplace(t *testing.T) {
+	var v transcriptView
+	v.append("one")
+	v.append("two\ntwo")
+	v.append("three")
+
+	v.replace(1, "2")
+	if got := strings.Join(v.lines, "|"); got != "one|2|three" || v.start(2) != 2 {
+		t.Errorf("Expected the message to shrink in place, got %q starting %v", got, v.starts)
+	}
+	v.replace(0, "1\n1\n1")
+	if got := strings.Join(v.lines, "|"); got != "1|1|1|2|three" || v.start(1) != 3 || v.start(2) != 4 {
+		t.Errorf("Expected the later messages to move down, got %q starting %v", got, v.starts)
+	}
+}
+
+func TestTranscriptViewScroll(t *testing.T) {
+	v := transcriptView{width: 4, height: 2}
+	v.append("a\nb\nc")
+	v.setTail([]string{"d"})
+
+	v.gotoBottom()
+	if v.yOffset != 2 || !v.atBottom() {
+		t.Errorf("Expected to scroll to the tail, got offset %d", v.yOffset)
+	}
+	if view := v.view(nil); view != "c   \nd   " {
+		t.Errorf("Expected the last two lines padded to the width, got %q", view)
+	}
+	v.pageUp()
+	if v.yOffset != 0 || v.atBottom() {
+		t.Errorf("Expected to scroll to the top, got offset %d", v.yOffset)
+	}
+	v.scrollUp(5)
+	if v.yOffset != 0 {
+		t.Errorf("Expected scrolling to stop at the top, got offset %d", v.yOffset)
+	}
+
+	short := transcriptView{width: 2, height: 3}
+	short.append("wide")
+	if view := short.view(nil); view != "wi\n  \n  " {
+		t.Errorf("Expected a truncated line and blank rows, got %q", view)
+	}
+}
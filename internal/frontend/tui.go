@@ -1,21 +1,27 @@
 package frontend
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"strings"
+	"sync"
 	"time"
 	"unicode/utf8"
 
 	"tiny-trae/internal/agent"
+	"tiny-trae/internal/clipboard"
+	"tiny-trae/internal/export"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
-	"github.com/charmbracelet/bubbles/textinput"
-	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 // TUIFrontend implements the Frontend interface for terminal UI interaction using bubbletea
@@ -24,27 +30,99 @@ type TUIFrontend struct {
 	model       tuiModel
 	inputCh     chan string
 	messageCh   chan agent.Message
+	approvalCh  chan agent.ApprovalDecision
+	interruptCh chan struct{}
+	cancelCh    chan struct{}
 	interactive bool
-	done        chan bool
+	// done is closed once the TUI program has exited and given the
+	// terminal back.
+	done      chan struct{}
+	closeOnce sync.Once
+	// console prints the messages of a non-interactive run
+	console *console
 }
 
 // tuiModel represents the state of the TUI
 type tuiModel struct {
-	viewport           viewport.Model
-	textInput          textinput.Model
+	transcript         transcriptView
+	textInput          textarea.Model
 	spinner            spinner.Model
-	renderer           *glamour.TermRenderer
+	markdown           *markdownRenderer
 	messages           []string
 	width              int
 	height             int
 	inputCh            chan string
 	messageCh          chan agent.Message
+	approvalCh         chan agent.ApprovalDecision
+	interruptCh        chan struct{}
+	cancelCh           chan struct{}
 	interactive        bool
 	waitingForInput    bool
+	awaitingApproval   bool
+	approval           approvalDialog
 	waitingForResponse bool
 	processingTool     bool
 	currentToolName    string
-	ready              bool
+	lastAnswer         string
+	// streamText is the assistant reply streamed so far; it is shown as a
+	// live block below the messages until the finished reply replaces it
+	streamText  string
+	streamStart string
+	// shownStreamText is the streamed text the transcript shows
+	shownStreamText string
+	// follow keeps the transcript scrolled to the latest message; scrolling
+	// up turns it off and scrolling back to the bottom turns it on again
+	follow bool
+	// history holds the prompts sent this session; while searching it with
+	// Ctrl+R, searchIndex is the matching entry or -1
+	history     inputHistory
+	searching   bool
+	searchQuery string
+	searchIndex int
+	// tools are the tool calls shown in the transcript; focusedTool is the
+	// one focused with Tab, or -1 while the input has the focus
+	tools       []toolEntry
+	focusedTool int
+	// usage is the session's token usage shown in the status bar, or nil
+	// until the agent first reports it
+	usage *agent.UsageData
+	// waitStart is when the current request to the model started, and
+	// requestTokens its estimated size, for the waiting status
+	waitStart     time.Time
+	requestTokens int64
+	// pane is the side pane previewing files and diffs
+	pane previewPane
+	// search is the search through the transcript
+	search transcriptSearch
+	// replies are the finished assistant replies, rendered again when the
+	// transcript's width settles after a resize, which resizeID counts
+	replies  []assistantReply
+	resizeID int
+	// notifySetting says how to notify when a turn finishes, which
+	// turnRunning tracks, or a tool needs approval; focused is whether the
+	// terminal reported having the focus
+	notifySetting Notify
+	turnRunning   bool
+	focused       bool
+	// outputs are the tails of the running tools' output by tool ID, and
+	// outputID the tool that wrote last, shown in the progress panel
+	outputs  map[string]*outputTail
+	outputID string
+	// submitPending is set while an Enter waits to tell whether it was
+	// typed or pasted; submitID counts the Enters
+	submitPending bool
+	submitID      int
+	// keyBindings are the key bindings in use, and vim the state of the
+	// vim ones
+	keyBindings KeyBindings
+	vim         vimState
+	// received are the messages from the agent, other than streamed pieces
+	// and status, for saving the conversation
+	received []agent.Message
+	// cancelling is set from the Ctrl+C that cancels a turn until the
+	// turn ends; another Ctrl+C meanwhile quits
+	cancelling bool
+	ready      bool
 }
 
 // messageReceivedMsg is sent when a new message is received
@@ -55,107 +133,159 @@ type messageReceivedMsg struct {
 // inputRequestMsg is sent when input is requested
 type inputRequestMsg struct{}
 
-// Define styles
+// approvalRequestMsg is sent when a tool call needs the user's approval
+type approvalRequestMsg struct {
+	req agent.ApprovalRequest
+}
+
+// editorFinishedMsg is sent when the external editor opened with Ctrl+E
+// exits, with the edited draft
+type editorFinishedMsg struct {
+	text string
+	err  error
+}
+
+// transcriptSavedMsg is sent when saving the conversation with Ctrl+S has
+// finished
+type transcriptSavedMsg struct {
+	path string
+	err  error
+}
+
+// clipboardCopiedMsg is sent when copying the last answer has finished
+type clipboardCopiedMsg struct {
+	err error
+}
+
+// Styles, set from the theme by applyTheme
 var (
-	titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("magenta")).
-			MarginLeft(1)
-
-	userStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("green"))
-
-	assistantStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("cyan"))
-
-	toolStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("yellow"))
-
-	errorStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("196"))
-
-	systemStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240"))
-
-	inputStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("blue")).
-			Padding(0, 1)
+	titleStyle         lipgloss.Style
+	userStyle          lipgloss.Style
+	assistantStyle     lipgloss.Style
+	toolStyle          lipgloss.Style
+	errorStyle         lipgloss.Style
+	systemStyle        lipgloss.Style
+	statusBarStyle     lipgloss.Style
+	inputStyle         lipgloss.Style
+	disabledInputStyle lipgloss.Style
+	spinnerStyle       lipgloss.Style
 )
 
-// NewTUIFrontend creates a new TUI frontend
-func NewTUIFrontend(interactive bool) *TUIFrontend {
+func init() {
+	applyTheme(DarkTheme)
+}
+
+// TUIOptions configures the TUI frontend.
+type TUIOptions struct {
+	Theme Theme
+	// HistoryPath is the file prompts are saved to and recalled from; if it
+	// is "", they are only kept for the session.
+	HistoryPath string
+	// Notify says how to get the user's attention while the terminal is in
+	// the background.
+	Notify      Notify
+	KeyBindings KeyBindings
+	// Verbosity says how much a non-interactive run prints.
+	Verbosity Verbosity
+	// Input and Output replace the terminal, as in tests.
+	Input  io.Reader
+	Output io.Writer
+}
+
+// NewTUIFrontend creates a new TUI frontend with the given options.
+func NewTUIFrontend(interactive bool, options TUIOptions) *TUIFrontend {
+	theme := options.Theme
+	applyTheme(theme)
+
 	inputCh := make(chan string, 1)
 	messageCh := make(chan agent.Message, 10)
-	done := make(chan bool, 1)
+	approvalCh := make(chan agent.ApprovalDecision, 1)
+	interruptCh := make(chan struct{}, 1)
+	cancelCh := make(chan struct{}, 1)
+	done := make(chan struct{})
 
 	s := spinner.New()
 	s.Spinner = spinner.Dot
-	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("magenta"))
+	s.Style = spinnerStyle
 
-	textInput := textinput.New()
+	textInput := textarea.New()
 	textInput.Placeholder = "Type your message here..."
-	textInput.CharLimit = 1000
-	textInput.Width = 72   // Initial width (80 - 8), will be updated on window resize
-	textInput.SetValue("") // Ensure clean initialization
-
-	// Initialize glamour renderer with dark theme (simplified for faster startup)
-	renderer, err := glamour.NewTermRenderer(
-		glamour.WithStandardStyle("dark"),
-		glamour.WithWordWrap(80),
-	)
-	if err != nil {
-		// Fallback to minimal renderer if initialization fails
-		renderer, _ = glamour.NewTermRenderer()
-	}
-
-	// Initialize viewport with default dimensions
-	viewport := viewport.New(80, 20)
-	viewport.YPosition = 3
+	textInput.Prompt = ""
+	textInput.ShowLineNumbers = false
+	textInput.CharLimit = 0 // No limit, code snippets can be long
+	textInput.MaxHeight = 0 // The input grows up to maxInputHeight, then scrolls
+	textInput.FocusedStyle.CursorLine = lipgloss.NewStyle()
+	// Enter sends the message, so newlines need another key. Most terminals
+	// send Shift+Enter as Enter, or as Alt+Enter if configured to.
+	textInput.KeyMap.InsertNewline = key.NewBinding(key.WithKeys("shift+enter", "alt+enter", "ctrl+j"))
+	// Ctrl+E opens the external editor instead of jumping to the line end
+	textInput.KeyMap.LineEnd = key.NewBinding(key.WithKeys("end"))
+	textInput.SetWidth(72) // Initial width (80 - 8), will be updated on window resize
+	textInput.SetHeight(1)
 
 	model := tuiModel{
-		viewport:           viewport,
+		transcript:         transcriptView{width: 80, height: 20},
 		textInput:          textInput,
 		spinner:            s,
-		renderer:           renderer,
+		markdown:           newMarkdownRenderer(theme.Markdown, 80),
 		inputCh:            inputCh,
 		messageCh:          messageCh,
+		approvalCh:         approvalCh,
+		interruptCh:        interruptCh,
+		cancelCh:           cancelCh,
 		interactive:        interactive,
 		waitingForInput:    false,
 		waitingForResponse: false,
 		processingTool:     false,
 		messages:           []string{},
+		follow:             true,
+		focusedTool:        -1,
+		notifySetting:      options.Notify,
+		keyBindings:        options.KeyBindings,
 		ready:              true, // Start ready with default dimensions
 		width:              80,
 		height:             24,
 	}
 
+	history, err := loadHistory(options.HistoryPath)
+	model.history = history
+	if err != nil {
+		model.addMessage(agent.Message{Type: agent.MessageTypeError, Content: fmt.Sprintf("Failed to load the prompt history: %v", err)})
+	}
+
 	tui := &TUIFrontend{
 		inputCh:     inputCh,
 		messageCh:   messageCh,
+		approvalCh:  approvalCh,
+		interruptCh: interruptCh,
+		cancelCh:    cancelCh,
 		interactive: interactive,
 		done:        done,
 		model:       model,
+		console:     &console{verbosity: options.Verbosity, out: os.Stdout, errOut: os.Stderr},
 	}
 
 	if interactive {
-		tui.program = tea.NewProgram(model, tea.WithAltScreen())
+		programOptions := []tea.ProgramOption{tea.WithAltScreen(), tea.WithMouseCellMotion(), tea.WithReportFocus()}
+		if options.Input != nil {
+			programOptions = append(programOptions, tea.WithInput(options.Input))
+		}
+		if options.Output != nil {
+			programOptions = append(programOptions, tea.WithOutput(options.Output))
+		}
+		tui.program = tea.NewProgram(model, programOptions...)
 		go tui.run()
 	}
 
 	return tui
 }
 
-// run starts the TUI program
+// run starts the TUI program, and closes done once it has exited.
 func (t *TUIFrontend) run() {
+	defer close(t.done)
 	if _, err := t.program.Run(); err != nil {
 		fmt.Printf("Error running TUI: %v\n", err)
 	}
-	t.done <- true
 }
 
 // Init initializes the TUI model
@@ -179,79 +309,284 @@ func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 
-		// Update viewport dimensions
-		footerHeight := 4
-		verticalMarginHeight := footerHeight
-
-		m.viewport.Width = msg.Width
-		m.viewport.Height = msg.Height - verticalMarginHeight
-
 		// Update text input width accounting for border (2) + padding (2)
 		// Leave some margin for proper display
 		if msg.Width > 8 {
-			m.textInput.Width = msg.Width - 8
+			m.textInput.SetWidth(msg.Width - 8)
+		}
+
+		m.resizeTranscript()
+		m.resizeID++
+		cmds = append(cmds, settleResize(m.resizeID))
+		if m.awaitingApproval {
+			m.updateViewport()
+			m.approval.resize(m.transcript.width, m.transcript.height)
 		}
 
-		// Update glamour renderer width only if it's significantly different to avoid unnecessary recreations
-		if m.renderer != nil && msg.Width > 20 {
-			newRenderer, err := glamour.NewTermRenderer(
-				glamour.WithStandardStyle("dark"),
-				glamour.WithWordWrap(msg.Width-10), // Leave some margin
-			)
-			if err == nil {
-				m.renderer = newRenderer
+	case tea.FocusMsg:
+		m.focused = true
+
+	case tea.BlurMsg:
+		m.focused = false
+
+	case tea.MouseMsg:
+		// Clicking a tool call or thinking block expands or collapses it
+		if msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft && msg.X < m.transcript.width && !m.awaitingApproval {
+			if i := m.entryAt(msg.Y); i >= 0 {
+				m.tools[i].expanded = !m.tools[i].expanded
+				m.renderToolEntry(i)
+				m.updateViewport()
 			}
+			return m, nil
 		}
+		m.transcript.update(msg)
+		m.follow = m.transcript.atBottom()
+		return m, nil
 
 	case tea.KeyMsg:
+		// A key right after Enter means the Enter was a pasted newline
+		if m.submitPending {
+			m.submitPending = false
+			m.textInput.InsertRune('\n')
+		}
+
+		// The approval dialog is modal
+		if m.awaitingApproval {
+			dialog, decision, done := m.approval.update(msg)
+			if msg.String() == "ctrl+c" {
+				// Deny the call and stop the turn it belongs to, or quit if
+				// the turn is being stopped already
+				if m.cancelling {
+					return m, tea.Quit
+				}
+				m.cancelTurn()
+				decision, done = agent.ApprovalDeny, true
+			}
+			m.approval = dialog
+			if !done {
+				return m, nil
+			}
+			m.approvalCh <- decision
+			m.awaitingApproval = false
+			m.addApprovalDecision(m.approval.req, decision)
+			m.waitingForResponse = true
+			m.updateViewport()
+			return m, m.spinner.Tick
+		}
+
+		if m.search.active {
+			if msg.String() != "ctrl+c" {
+				m = m.updateTranscriptSearch(msg)
+				return m, nil
+			}
+			// Ctrl+C ends the search and then does what it does outside it
+			m.search = transcriptSearch{}
+		}
+		inputActive := m.waitingForInput && !m.waitingForResponse && !m.processingTool && m.focusedTool < 0 && !m.vim.normal
+		// Most terminals send Ctrl+/ as Ctrl+_
+		if msg.String() == "ctrl+_" || (msg.String() == "/" && !inputActive) {
+			m.search = transcriptSearch{active: true}
+			m.search.setQuery("")
+			return m, nil
+		}
+		if msg.String() == "ctrl+s" {
+			return m, m.saveTranscript()
+		}
+
+		// Scrolling works in every other state
+		if m.scroll(msg.String()) {
+			return m, nil
+		}
+
+		// Tab moves the focus from the input to the tool calls
+		if !m.searching {
+			if m.focusedTool >= 0 {
+				var handled bool
+				if m, handled = m.updateToolFocus(msg); handled {
+					m.updateViewport()
+					return m, nil
+				}
+			} else if msg.String() == "tab" && len(m.tools) > 0 {
+				m.focusTool(len(m.tools) - 1)
+				return m, nil
+			}
+		}
+
+		if m.keyBindings == KeyBindingsVim && m.focusedTool < 0 {
+			var handled bool
+			if m, handled = m.updateVim(msg); handled {
+				m.updateViewport()
+				return m, nil
+			}
+		}
+
 		if !m.interactive {
 			switch msg.String() {
-			case "ctrl+c":
-				os.Exit(0)
-			case "q":
+			case "ctrl+c", "q":
 				return m, tea.Quit
 			}
 		}
 
 		if m.waitingForInput && !m.waitingForResponse && !m.processingTool {
+			if m.searching {
+				return m.updateSearch(msg), nil
+			}
+			if msg.Paste {
+				m.textInput.InsertString(normalizeNewlines(string(msg.Runes)))
+				m.resizeInput()
+				return m, nil
+			}
 			switch msg.String() {
+			case "up":
+				// Older prompts, once the cursor is on the first line
+				if m.textInput.Line() == 0 {
+					if entry, ok := m.history.prev(m.textInput.Value()); ok {
+						m.textInput.SetValue(entry)
+					}
+					m.resizeInput()
+					return m, nil
+				}
+			case "down":
+				if m.textInput.Line() == m.textInput.LineCount()-1 {
+					if entry, ok := m.history.next(); ok {
+						m.textInput.SetValue(entry)
+					}
+					m.resizeInput()
+					return m, nil
+				}
+			case "ctrl+r":
+				m.searching = true
+				m.searchQuery = ""
+				m.searchIndex = -1
+				return m, nil
 			case "enter":
-				input := m.textInput.Value()
-				if input != "" {
-					m.inputCh <- input
-					m.textInput.SetValue("")
-					m.textInput.Blur()
-					m.waitingForInput = false
-					m.waitingForResponse = true
-					// Start spinner for response waiting
-					cmds = append(cmds, m.spinner.Tick)
+				// Sending waits for pasteWindow, in case this Enter is part
+				// of a paste from a terminal without bracketed paste
+				if m.textInput.Value() == "" {
+					return m, nil
 				}
-				return m, tea.Batch(cmds...)
+				m.submitID++
+				m.submitPending = true
+				return m, submitAfterPaste(m.submitID)
+			case "ctrl+e":
+				return m, m.openEditor()
+			case "ctrl+y":
+				return m, m.copyLastAnswer()
 			case "ctrl+c":
-				os.Exit(0)
+				// Quitting ends GetUserInput, so the session ends and
+				// cleans up after itself
+				return m, tea.Quit
 			}
 			m.textInput, cmd = m.textInput.Update(msg)
 			cmds = append(cmds, cmd)
 		} else {
 			switch msg.String() {
+			case "esc":
+				// Cancel only the running tool, not the whole program
+				if m.processingTool {
+					select {
+					case m.interruptCh <- struct{}{}:
+					default:
+					}
+				}
+			case "ctrl+y":
+				return m, m.copyLastAnswer()
 			case "ctrl+c":
-				os.Exit(0)
-			case "q":
-				return m, tea.Quit
+				// The first Ctrl+C stops the turn, the second quits once
+				// it has stopped
+				if m.cancelling {
+					return m, tea.Quit
+				}
+				m.cancelTurn()
 			}
 		}
 
+	case submitMsg:
+		if msg.id == m.submitID && m.submitPending {
+			m.submitPending = false
+			return m.submit()
+		}
+
+	case resizeSettledMsg:
+		if msg.id == m.resizeID {
+			m.rewrap()
+		}
+
+	case editorFinishedMsg:
+		if msg.err != nil {
+			m.addMessage(agent.Message{Type: agent.MessageTypeError, Content: fmt.Sprintf("Editor failed: %v", msg.err)})
+		} else if m.waitingForInput {
+			m.textInput.SetValue(msg.text)
+			m.textInput.Focus()
+		}
+
+	case clipboardCopiedMsg:
+		if msg.err != nil {
+			m.addMessage(agent.Message{Type: agent.MessageTypeError, Content: fmt.Sprintf("Failed to copy: %v", msg.err)})
+		} else {
+			m.addMessage(agent.Message{Type: agent.MessageTypeSystemInfo, Content: "Copied the last answer to the clipboard"})
+		}
+
+	case transcriptSavedMsg:
+		if msg.err != nil {
+			m.addMessage(agent.Message{Type: agent.MessageTypeError, Content: fmt.Sprintf("Failed to save the conversation: %v", msg.err)})
+		} else {
+			m.addMessage(agent.Message{Type: agent.MessageTypeSystemInfo, Content: "Saved the conversation to " + msg.path})
+		}
+
 	case messageReceivedMsg:
+		if msg.msg.Type == agent.MessageTypeUsage {
+			var usage agent.UsageData
+			if err := json.Unmarshal(msg.msg.Data, &usage); err == nil {
+				m.usage = &usage
+			}
+			break
+		}
+		if msg.msg.Type == agent.MessageTypeRequest {
+			var request agent.RequestData
+			if err := json.Unmarshal(msg.msg.Data, &request); err == nil {
+				m.waitStart = time.Now()
+				m.requestTokens = request.Tokens
+			}
+			break
+		}
+		if msg.msg.Type == agent.MessageTypeToolOutput {
+			var output agent.ToolOutputData
+			if err := json.Unmarshal(msg.msg.Data, &output); err == nil {
+				m.addToolOutput(output, msg.msg.Content)
+			}
+			break
+		}
+		if msg.msg.Type == agent.MessageTypeAssistantDelta {
+			if m.streamText == "" {
+				m.streamStart = time.Now().Format("15:04:05")
+			}
+			m.streamText += msg.msg.Content
+			break
+		}
+		if msg.msg.Type == agent.MessageTypeAssistant || msg.msg.Type == agent.MessageTypeError {
+			m.streamText = ""
+		}
+		m.received = append(m.received, msg.msg)
 		m.addMessage(msg.msg)
 		if msg.msg.Type == agent.MessageTypeToolCall {
 			m.processingTool = true
 			var toolData agent.ToolCallData
 			if err := json.Unmarshal(msg.msg.Data, &toolData); err == nil {
 				m.currentToolName = toolData.ToolName
+				// Start the clock of the progress panel
+				m.addToolOutput(agent.ToolOutputData{ToolName: toolData.ToolName, ToolID: toolData.ToolID}, "")
 			}
 			// Start spinner for tool processing
 			cmds = append(cmds, m.spinner.Tick)
 		} else if msg.msg.Type == agent.MessageTypeToolResult {
+			var toolResult agent.ToolResultData
+			if err := json.Unmarshal(msg.msg.Data, &toolResult); err == nil {
+				delete(m.outputs, toolResult.ToolID)
+				if m.outputID == toolResult.ToolID {
+					m.outputID = ""
+				}
+			}
 			m.processingTool = false
 			m.currentToolName = ""
 			m.waitingForResponse = true
@@ -259,12 +594,26 @@ func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else if msg.msg.Type == agent.MessageTypeAssistant {
 			// Assistant response received, no longer waiting
 			m.waitingForResponse = false
+			m.lastAnswer = msg.msg.Content
 			// Allow free typing again
 			m.waitingForInput = true
 			m.textInput.Focus()
 		}
 
+	case approvalRequestMsg:
+		m.awaitingApproval = true
+		m.approval = newApprovalDialog(msg.req, m.transcript.width, m.transcript.height)
+		cmds = append(cmds, m.notify(msg.req.ToolName+" needs your approval"))
+
 	case inputRequestMsg:
+		m.vim = vimState{}
+		m.cancelling = false
+		// A reply cancelled while streaming is left unfinished
+		m.streamText = ""
+		if m.turnRunning {
+			m.turnRunning = false
+			cmds = append(cmds, m.notify("Trae has finished and is waiting for you"))
+		}
 		m.waitingForInput = true
 		m.waitingForResponse = false
 		m.textInput.SetValue("") // Clear any residual content
@@ -278,41 +627,325 @@ func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	}
 
-	// Update viewport
-	m.viewport.SetContent(strings.Join(m.messages, "\n"))
+	m.updateViewport()
 
 	return m, tea.Batch(cmds...)
 }
 
+// updateViewport fits the input to its draft and the transcript to the
+// rest of the window. Messages are added to the transcript as they arrive,
+// so only the streamed reply is rendered again here, and only while it
+// grows.
+func (m *tuiModel) updateViewport() {
+	m.resizeInput()
+	if m.streamText != m.shownStreamText {
+		m.shownStreamText = m.streamText
+		var tail []string
+		if m.streamText != "" {
+			tail = strings.Split(m.streamBlock(), "\n")
+		}
+		m.transcript.setTail(tail)
+	}
+	if m.search.active {
+		m.search.find(m.transcript)
+	}
+	if m.follow {
+		m.transcript.gotoBottom()
+	}
+}
+
+// updateTranscriptSearch handles a key while searching the transcript.
+// Typing changes the query, Enter or Up selects the match above, Down the
+// one below, and Esc ends the search.
+func (m tuiModel) updateTranscriptSearch(msg tea.KeyMsg) tuiModel {
+	switch msg.String() {
+	case "esc":
+		m.search = transcriptSearch{}
+		m.updateViewport()
+		return m
+	case "enter", "up", "shift+tab", "ctrl+p":
+		m.search.move(-1)
+	case "down", "tab", "ctrl+n":
+		m.search.move(1)
+	case "backspace":
+		if runes := []rune(m.search.query); len(runes) > 0 {
+			m.search.setQuery(string(runes[:len(runes)-1]))
+		}
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			m.search.setQuery(m.search.query + string(msg.Runes))
+		} else if m.scroll(msg.String()) {
+			return m
+		}
+	}
+
+	m.updateViewport()
+	if line := m.search.line(); line >= 0 {
+		m.transcript.setYOffset(line - m.transcript.height/2)
+		m.follow = false
+	}
+	return m
+}
+
+// updateSearch handles a key while searching the history with Ctrl+R.
+// Typing narrows the search, Ctrl+R again finds an older match, Enter puts
+// the match in the input, and Esc or Ctrl+G cancels.
+func (m tuiModel) updateSearch(msg tea.KeyMsg) tuiModel {
+	switch msg.Type {
+	case tea.KeyCtrlR:
+		if i := m.history.search(m.searchQuery, m.searchIndex); i >= 0 {
+			m.searchIndex = i
+		}
+		return m
+	case tea.KeyBackspace:
+		if m.searchQuery != "" {
+			runes := []rune(m.searchQuery)
+			m.searchQuery = string(runes[:len(runes)-1])
+			m.searchIndex = m.history.search(m.searchQuery, len(m.history.entries))
+		}
+		return m
+	case tea.KeyRunes, tea.KeySpace:
+		m.searchQuery += string(msg.Runes)
+		m.searchIndex = m.history.search(m.searchQuery, len(m.history.entries))
+		return m
+	case tea.KeyEnter:
+		if m.searchIndex >= 0 {
+			m.textInput.SetValue(m.history.entries[m.searchIndex])
+			m.history.reset()
+		}
+	case tea.KeyEsc, tea.KeyCtrlG, tea.KeyCtrlC:
+	default:
+		return m
+	}
+	m.searching = false
+	m.resizeInput()
+	return m
+}
+
+// resizeTranscript fits the transcript to the space the preview pane
+// leaves. The messages are wrapped for the new width by rewrap, which
+// window resizes only call once the size settles.
+func (m *tuiModel) resizeTranscript() {
+	m.transcript.width = m.width - m.pane.width(m.width)
+}
+
+// maxInputHeight is the most lines the input box grows to before it scrolls.
+const maxInputHeight = 8
+
+// resizeInput fits the input box to its draft and gives the rest of the
+// window to the transcript.
+func (m *tuiModel) resizeInput() {
+	height := min(max(m.textInput.LineCount(), 1), maxInputHeight)
+	m.textInput.SetHeight(height)
+	// The footer is the status line, the status bar, and the input box
+	// with its border
+	footerHeight := height + 4
+	if m.runningOutput() != nil {
+		footerHeight += progressLines
+	}
+	m.transcript.height = max(m.height-footerHeight, 1)
+}
+
+// openEditor returns a command that opens the draft in $EDITOR, falling
+// back to vi, and sends the edited text back as an editorFinishedMsg.
+func (m tuiModel) openEditor() tea.Cmd {
+	file, err := os.CreateTemp("", "tiny-trae-*.md")
+	if err != nil {
+		return func() tea.Msg { return editorFinishedMsg{err: err} }
+	}
+	path := file.Name()
+	_, err = file.WriteString(m.textInput.Value())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return func() tea.Msg { return editorFinishedMsg{err: err} }
+	}
+
+	// $EDITOR may include arguments, such as "code --wait"
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+	cmd := exec.Command(editor[0], append(editor[1:], path)...)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		defer os.Remove(path)
+		if err != nil {
+			return editorFinishedMsg{err: err}
+		}
+		data, err := os.ReadFile(path)
+		return editorFinishedMsg{text: strings.TrimRight(string(data), "\n"), err: err}
+	})
+}
+
+// scroll moves the transcript for the scrolling keys and reports whether key
+// was one of them.
+func (m *tuiModel) scroll(key string) bool {
+	switch key {
+	case "pgup":
+		m.transcript.pageUp()
+	case "pgdown":
+		m.transcript.pageDown()
+	case "ctrl+up", "shift+up":
+		m.transcript.scrollUp(1)
+	case "ctrl+down", "shift+down":
+		m.transcript.scrollDown(1)
+	case "ctrl+home":
+		m.transcript.gotoTop()
+	case "ctrl+end":
+		m.transcript.gotoBottom()
+	case "ctrl+o":
+		m.pane.visible = !m.pane.visible
+		m.resizeTranscript()
+		m.rewrap()
+		return true
+	case "ctrl+f":
+		// Toggle between following the latest message and free scrolling
+		m.follow = !m.follow
+		if m.follow {
+			m.transcript.gotoBottom()
+		}
+		return true
+	default:
+		return false
+	}
+	m.follow = m.transcript.atBottom()
+	return true
+}
+
+// copyLastAnswer returns a command that copies the last assistant answer, as
+// markdown, to the system clipboard
+func (m tuiModel) copyLastAnswer() tea.Cmd {
+	text := m.lastAnswer
+	return func() tea.Msg {
+		if text == "" {
+			return clipboardCopiedMsg{err: fmt.Errorf("there is no answer yet")}
+		}
+		return clipboardCopiedMsg{err: clipboard.Write(context.Background(), text)}
+	}
+}
+
+// cancelTurn asks the agent to stop the current turn: the model's reply and
+// any running tools.
+func (m *tuiModel) cancelTurn() {
+	m.cancelling = true
+	select {
+	case m.cancelCh <- struct{}{}:
+	default:
+	}
+}
+
+// addToolOutput adds output of a running tool to its tail.
+func (m *tuiModel) addToolOutput(data agent.ToolOutputData, text string) {
+	if m.outputs == nil {
+		m.outputs = make(map[string]*outputTail)
+	}
+	output, ok := m.outputs[data.ToolID]
+	if !ok {
+		output = &outputTail{toolName: data.ToolName, start: time.Now()}
+		m.outputs[data.ToolID] = output
+	}
+	output.write(text)
+	m.outputID = data.ToolID
+}
+
+// runningOutput returns the output of the running tool that wrote last, or
+// nil if no running tool has written any.
+func (m tuiModel) runningOutput() *outputTail {
+	output, ok := m.outputs[m.outputID]
+	if !m.processingTool || !ok || len(output.tail()) == 0 {
+		return nil
+	}
+	return output
+}
+
+// submit sends the input to the agent.
+func (m tuiModel) submit() (tea.Model, tea.Cmd) {
+	input := m.textInput.Value()
+	m.history.add(input)
+	m.inputCh <- input
+	m.textInput.SetValue("")
+	m.textInput.Blur()
+	m.waitingForInput = false
+	m.waitingForResponse = true
+	m.turnRunning = true
+	m.waitStart = time.Now()
+	m.requestTokens = 0
+	m.updateViewport()
+	// Start spinner for response waiting
+	return m, m.spinner.Tick
+}
+
+// saveTranscript returns a command that saves the conversation as markdown
+// to a new file in the working directory.
+func (m tuiModel) saveTranscript() tea.Cmd {
+	messages := m.received
+	return func() tea.Msg {
+		path, err := export.Save(".", messages)
+		return transcriptSavedMsg{path: path, err: err}
+	}
+}
+
 // View renders the TUI
 func (m tuiModel) View() string {
 	// Footer
 	var footer string
 	var statusLine string
 
-	if m.processingTool {
-		statusLine = fmt.Sprintf(" %s Processing tool: %s", m.spinner.View(), m.currentToolName)
+	if m.awaitingApproval {
+		statusLine = toolStyle.Render(fmt.Sprintf(" %s needs your approval", m.approval.req.ToolName))
+	} else if m.cancelling {
+		statusLine = fmt.Sprintf(" %s Cancelling the turn... %s", m.spinner.View(), systemStyle.Render("(Ctrl+C again to quit)"))
+	} else if m.search.active {
+		count := "no matches"
+		if len(m.search.matches) > 0 {
+			count = fmt.Sprintf("%d/%d", m.search.current+1, len(m.search.matches))
+		}
+		statusLine = fmt.Sprintf(" Search: %s %s", m.search.query, systemStyle.Render(fmt.Sprintf("(%s) · Enter/↑ previous · ↓ next · Esc close", count)))
+	} else if output := m.runningOutput(); output != nil {
+		statusLine = output.view(m.spinner.View(), m.width)
+	} else if m.processingTool {
+		statusLine = fmt.Sprintf(" %s Processing tool: %s %s", m.spinner.View(), m.currentToolName, systemStyle.Render("(Esc to cancel, Ctrl+C to stop the turn)"))
+	} else if m.waitingForResponse && m.streamText != "" {
+		statusLine = fmt.Sprintf(" Receiving response... %s", systemStyle.Render(fmt.Sprintf("%s · ~%d tokens", m.elapsed(), estimateTokens(m.streamText))))
 	} else if m.waitingForResponse {
-		statusLine = fmt.Sprintf(" %s Waiting for response...", m.spinner.View())
+		// The time and request size tell a slow model from a hung connection
+		details := m.elapsed()
+		if m.requestTokens > 0 {
+			details += fmt.Sprintf(" · %s tokens sent", formatTokens(m.requestTokens))
+		}
+		statusLine = fmt.Sprintf(" %s Waiting for response... %s", m.spinner.View(), systemStyle.Render(details))
+	} else if m.searching {
+		match := ""
+		if m.searchIndex >= 0 {
+			match, _, _ = strings.Cut(m.history.entries[m.searchIndex], "\n")
+		} else if m.searchQuery != "" {
+			match = errorStyle.Render("no match")
+		}
+		statusLine = fmt.Sprintf(" (reverse-i-search)`%s': %s", m.searchQuery, match)
+	} else if m.interactive && m.keyBindings == KeyBindingsVim && m.waitingForInput {
+		mode := "-- INSERT -- Esc for normal mode"
+		if m.vim.normal {
+			mode = "-- NORMAL -- i to insert, j/k to scroll, / to search"
+		}
+		statusLine = systemStyle.Render(" " + mode + ", Ctrl+C to quit")
 	} else if m.interactive {
-		statusLine = systemStyle.Render(" Press 'q' or Ctrl+C to quit")
+		statusLine = systemStyle.Render(" Alt+Enter for a new line, Ctrl+E to edit in $EDITOR, Ctrl+Y to copy the last answer, Ctrl+C to quit")
 	} else {
 		statusLine = systemStyle.Render(" Press 'q' or Ctrl+C to quit")
 	}
 
+	if !m.follow {
+		statusLine += systemStyle.Render(fmt.Sprintf(" [scrolled, %d%%, Ctrl+F to follow]", int(m.transcript.scrollPercent()*100)))
+	}
+
 	// Always show input box, but disable it when waiting for response or processing
 	if m.waitingForResponse || m.processingTool {
-		// Show disabled input box with muted style
-		disabledStyle := lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("240")).
-			Foreground(lipgloss.Color("240")).
-			Padding(0, 1)
-
-		// Create a copy of the input to show disabled state
+		// Create a copy of the input to show disabled state with muted style
 		disabledInput := m.textInput
 		disabledInput.Blur()
-		inputBox := disabledStyle.Render(disabledInput.View())
+		inputBox := disabledInputStyle.Render(disabledInput.View())
 		footer = lipgloss.PlaceHorizontal(m.width, lipgloss.Center, inputBox)
 	} else {
 		// Show normal input box
@@ -320,52 +953,75 @@ func (m tuiModel) View() string {
 		footer = lipgloss.PlaceHorizontal(m.width, lipgloss.Center, inputBox)
 	}
 
+	var decorate func(int, string) string
+	if m.search.active {
+		decorate = m.search.decorate
+	}
+	transcript := m.transcript.view(decorate)
+	if m.awaitingApproval {
+		transcript = m.approval.view()
+	}
+	if paneWidth := m.pane.width(m.width); paneWidth > 0 {
+		transcript = lipgloss.JoinHorizontal(lipgloss.Top, transcript, m.pane.view(paneWidth, m.transcript.height))
+	}
+
 	// Main view
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
-		m.viewport.View(),
+		transcript,
 		statusLine,
+		m.statusBar(),
 		footer,
 	)
 }
 
-// wrapText wraps text to fit within the specified width
-func wrapText(text string, width int) string {
-	if width <= 0 {
-		return text
+// statusBar renders the profile, model, turn, token usage, estimated cost,
+// and how full the context window is.
+func (m tuiModel) statusBar() string {
+	text := ""
+	if u := m.usage; u != nil {
+		parts := []string{
+			fmt.Sprintf("%s · %s", u.Profile, u.Model),
+			fmt.Sprintf("turn %d", u.Turn),
+			fmt.Sprintf("%s in / %s out", formatTokens(u.InputTokens), formatTokens(u.OutputTokens)),
+		}
+		if u.Cost > 0 {
+			parts = append(parts, fmt.Sprintf("$%.2f", u.Cost))
+		}
+		parts = append(parts, fmt.Sprintf("context %d%%", u.ContextPercent()))
+		text = " " + strings.Join(parts, " │ ")
 	}
+	return statusBarStyle.Width(m.width).MaxWidth(m.width).Render(text)
+}
 
-	words := strings.Fields(text)
-	if len(words) == 0 {
-		return text
+// elapsed returns the time since the current request started, such as 12.4s.
+func (m tuiModel) elapsed() string {
+	if m.waitStart.IsZero() {
+		return "0.0s"
 	}
+	return fmt.Sprintf("%.1fs", time.Since(m.waitStart).Seconds())
+}
 
-	var lines []string
-	var currentLine strings.Builder
-
-	for _, word := range words {
-		wordLen := utf8.RuneCountInString(word)
-		lineLen := utf8.RuneCountInString(currentLine.String())
-
-		// If adding this word would exceed the width, start a new line
-		if lineLen+wordLen+1 > width && lineLen > 0 {
-			lines = append(lines, currentLine.String())
-			currentLine.Reset()
-			currentLine.WriteString(word)
-		} else {
-			if currentLine.Len() > 0 {
-				currentLine.WriteString(" ")
-			}
-			currentLine.WriteString(word)
-		}
+// formatTokens formats a token count briefly, such as 950, 12.3k, or 1.2M.
+func formatTokens(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	default:
+		return fmt.Sprint(n)
 	}
+}
 
-	// Add the last line
-	if currentLine.Len() > 0 {
-		lines = append(lines, currentLine.String())
+// wrapText wraps text to fit within width terminal cells. Widths are
+// measured per grapheme, so wide CJK characters and emoji take two cells;
+// line breaks and styling are kept, and words longer than width are broken.
+func wrapText(text string, width int) string {
+	if width <= 0 {
+		return text
 	}
-
-	return strings.Join(lines, "\n")
+	return ansi.Wrap(text, width, "")
 }
 
 // addMessage adds a message to the display
@@ -374,7 +1030,7 @@ func (m *tuiModel) addMessage(msg agent.Message) {
 	timestamp := time.Now().Format("15:04:05")
 
 	// Calculate available width for content (account for timestamp, labels, and margins)
-	availableWidth := m.width - 12
+	availableWidth := m.transcript.width - 12
 	if availableWidth < 20 {
 		availableWidth = 20
 	}
@@ -385,46 +1041,43 @@ func (m *tuiModel) addMessage(msg agent.Message) {
 		formattedMsg = fmt.Sprintf("[%s] %s %s", timestamp, userStyle.Render("You:"), content)
 	case agent.MessageTypeAssistant:
 		// Use glamour to render markdown content from the assistant
-		renderedContent, err := m.renderer.Render(msg.Content)
-		if err != nil {
-			// Fallback to plain text with wrapping if rendering fails
-			content := wrapText(msg.Content, availableWidth-6)
-			formattedMsg = fmt.Sprintf("[%s] %s %s", timestamp, assistantStyle.Render("Trae:"), content)
-		} else {
-			// Clean up the rendered content (remove trailing newlines)
-			renderedContent = strings.TrimRight(renderedContent, "\n\r")
-			// Add timestamp and label
-			formattedMsg = fmt.Sprintf("[%s] %s\n%s", timestamp, assistantStyle.Render("Trae:"), renderedContent)
-		}
+		reply := assistantReply{timestamp: timestamp, content: msg.Content}
+		reply.message = m.appendMessage(m.formatReply(timestamp, msg.Content))
+		m.replies = append(m.replies, reply)
+		return
 	case agent.MessageTypeToolCall:
 		var toolData agent.ToolCallData
 		if err := json.Unmarshal(msg.Data, &toolData); err == nil {
-			content := wrapText(fmt.Sprintf("Executing %s", toolData.ToolName), availableWidth-6)
-			formattedMsg = fmt.Sprintf("[%s] %s %s", timestamp, toolStyle.Render("Tool:"), content)
-		} else {
-			content := wrapText(msg.Content, availableWidth-6)
-			formattedMsg = fmt.Sprintf("[%s] %s %s", timestamp, toolStyle.Render("Tool:"), content)
+			m.addToolEntry(toolEntry{timestamp: timestamp, call: toolData})
+			return
 		}
+		content := wrapText(msg.Content, availableWidth-6)
+		formattedMsg = fmt.Sprintf("[%s] %s %s", timestamp, toolStyle.Render("Tool:"), content)
 	case agent.MessageTypeToolResult:
 		var toolResult agent.ToolResultData
 		if err := json.Unmarshal(msg.Data, &toolResult); err == nil {
-			if toolResult.IsError {
-				errorText := fmt.Sprintf("%s: %s", toolResult.ToolName, toolResult.Result)
-				wrappedError := wrapText(errorText, availableWidth-8)
-				formattedMsg = fmt.Sprintf("[%s] %s %s", timestamp, errorStyle.Render("Error"), errorStyle.Render(wrappedError))
-			} else {
-				// Truncate long results
-				result := toolResult.Result
-				if len(result) > 200 {
-					result = result[:200] + "..."
+			// Show the result in its call's entry; calls that were denied
+			// were never announced and get an entry of their own
+			for i := range m.tools {
+				if m.tools[i].call.ToolID == toolResult.ToolID && m.tools[i].result == nil {
+					m.tools[i].result = &toolResult
+					m.renderToolEntry(i)
+					m.pane.update(m.tools[i])
+					return
 				}
-				content := wrapText(fmt.Sprintf("%s: %s", toolResult.ToolName, result), availableWidth-8)
-				formattedMsg = fmt.Sprintf("[%s] %s %s", timestamp, toolStyle.Render("Result"), content)
 			}
-		} else {
-			content := wrapText(msg.Content, availableWidth-6)
-			formattedMsg = fmt.Sprintf("[%s] %s %s", timestamp, toolStyle.Render("Result:"), content)
+			m.addToolEntry(toolEntry{
+				timestamp: timestamp,
+				call:      agent.ToolCallData{ToolName: toolResult.ToolName, ToolID: toolResult.ToolID},
+				result:    &toolResult,
+			})
+			return
 		}
+		content := wrapText(msg.Content, availableWidth-6)
+		formattedMsg = fmt.Sprintf("[%s] %s %s", timestamp, toolStyle.Render("Result:"), content)
+	case agent.MessageTypeThinking:
+		m.addToolEntry(toolEntry{timestamp: timestamp, thinking: msg.Content})
+		return
 	case agent.MessageTypeError:
 		// Wrap error messages to prevent overflow
 		wrappedError := wrapText(msg.Content, availableWidth-8)
@@ -437,7 +1090,122 @@ func (m *tuiModel) addMessage(msg agent.Message) {
 		formattedMsg = fmt.Sprintf("[%s] %s", timestamp, content)
 	}
 
-	m.messages = append(m.messages, formattedMsg)
+	m.appendMessage(formattedMsg)
+}
+
+// appendMessage adds rendered text to the transcript and returns its index.
+func (m *tuiModel) appendMessage(text string) int {
+	m.messages = append(m.messages, text)
+	m.transcript.append(text)
+	return len(m.messages) - 1
+}
+
+// streamBlock renders the assistant reply streamed so far. It is plain
+// wrapped text, since partial markdown does not render reliably; the
+// finished reply is rendered with glamour.
+func (m tuiModel) streamBlock() string {
+	width := max(m.transcript.width-12, 20)
+	text := lipgloss.NewStyle().Width(width).Render(m.streamText)
+	return fmt.Sprintf("[%s] %s\n%s", m.streamStart, assistantStyle.Render("Trae:"), text)
+}
+
+// estimateTokens roughly counts the tokens in text, at about four characters
+// per token.
+func estimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// addToolEntry adds a tool entry to the display.
+func (m *tuiModel) addToolEntry(entry toolEntry) {
+	entry.line = m.appendMessage("")
+	m.tools = append(m.tools, entry)
+	m.renderToolEntry(len(m.tools) - 1)
+	m.pane.update(entry)
+}
+
+// renderToolEntry updates the display of the i-th tool entry.
+func (m *tuiModel) renderToolEntry(i int) {
+	width := max(m.transcript.width-12, 20)
+	line := m.tools[i].line
+	m.messages[line] = m.tools[i].render(width, i == m.focusedTool)
+	m.transcript.replace(line, m.messages[line])
+}
+
+// entryAt returns the index of the tool entry shown on the given row of the
+// screen, or -1 if there is none.
+func (m tuiModel) entryAt(row int) int {
+	message := m.transcript.messageAt(row)
+	for i, entry := range m.tools {
+		if message >= 0 && entry.line == message {
+			return i
+		}
+	}
+	return -1
+}
+
+// updateToolFocus handles a key while a tool entry is focused. Up/Down and
+// Tab/Shift+Tab move between entries, Enter or Space expands or collapses
+// the focused one, and Esc, or Tab past the last entry, returns to the input.
+func (m tuiModel) updateToolFocus(msg tea.KeyMsg) (tuiModel, bool) {
+	switch msg.String() {
+	case "up", "shift+tab":
+		m.focusTool(max(m.focusedTool-1, 0))
+	case "down", "tab":
+		m.focusTool(m.focusedTool + 1)
+	case "enter", " ":
+		m.tools[m.focusedTool].expanded = !m.tools[m.focusedTool].expanded
+		m.renderToolEntry(m.focusedTool)
+	case "esc":
+		m.focusTool(-1)
+	default:
+		// Other keys go to the input
+		m.focusTool(-1)
+		return m, false
+	}
+	return m, true
+}
+
+// focusTool focuses the i-th tool entry and scrolls to it, or returns the
+// focus to the input if there is no such entry.
+func (m *tuiModel) focusTool(i int) {
+	previous := m.focusedTool
+	if i < 0 || i >= len(m.tools) {
+		i = -1
+	}
+	m.focusedTool = i
+	if previous >= 0 {
+		m.renderToolEntry(previous)
+	}
+	if i < 0 {
+		m.follow = true
+		return
+	}
+	m.renderToolEntry(i)
+
+	// Scroll so the entry is at the top of the transcript
+	m.updateViewport()
+	m.transcript.setYOffset(m.transcript.start(m.tools[i].line))
+	m.follow = false
+}
+
+// addApprovalDecision records the user's answer to an approval request in
+// the display
+func (m *tuiModel) addApprovalDecision(req agent.ApprovalRequest, decision agent.ApprovalDecision) {
+	timestamp := time.Now().Format("15:04:05")
+	formattedMsg := fmt.Sprintf("[%s] %s %s %s", timestamp, toolStyle.Render("Approval:"), req.ToolName, approvalOutcome(decision))
+	m.appendMessage(formattedMsg)
+}
+
+// approvalOutcome describes an approval decision.
+func approvalOutcome(decision agent.ApprovalDecision) string {
+	switch decision {
+	case agent.ApprovalApprove:
+		return "approved"
+	case agent.ApprovalAlwaysAllow:
+		return "approved, and always allowed from now on"
+	default:
+		return "rejected"
+	}
 }
 
 // SendMessage sends a message to the TUI for display
@@ -446,18 +1214,12 @@ func (t *TUIFrontend) SendMessage(msg agent.Message) {
 		t.program.Send(messageReceivedMsg{msg: msg})
 	} else {
 		// Fallback to stdout for non-interactive mode
-		switch msg.Type {
-		case agent.MessageTypeAssistant:
-			fmt.Printf("Trae: %s\n", msg.Content)
-		case agent.MessageTypeError:
-			fmt.Printf("Error: %s\n", msg.Content)
-		case agent.MessageTypeSystemInfo:
-			fmt.Printf("%s\n", msg.Content)
-		}
+		t.console.print(msg)
 	}
 }
 
-// GetUserInput requests user input from the TUI
+// GetUserInput requests user input from the TUI. It returns false once the
+// user has quit.
 func (t *TUIFrontend) GetUserInput() (string, bool) {
 	if !t.interactive {
 		return "", false
@@ -477,22 +1239,51 @@ func (t *TUIFrontend) GetUserInput() (string, bool) {
 	}
 }
 
+// RequestApproval asks the user to approve a tool call. In non-interactive
+// mode there is nobody to ask, so the call is denied; --yes or a permission
+// rule can allow it instead.
+func (t *TUIFrontend) RequestApproval(req agent.ApprovalRequest) agent.ApprovalDecision {
+	if !t.interactive || t.program == nil {
+		return agent.ApprovalDeny
+	}
+
+	t.program.Send(approvalRequestMsg{req: req})
+
+	select {
+	case decision := <-t.approvalCh:
+		return decision
+	case <-t.done:
+		return agent.ApprovalDeny
+	}
+}
+
+// Interrupts returns the channel that receives a value when the user presses
+// Esc while a tool is running
+func (t *TUIFrontend) Interrupts() <-chan struct{} {
+	return t.interruptCh
+}
+
+// TurnCancels returns the channel that receives a value when the user
+// presses Ctrl+C during a turn
+func (t *TUIFrontend) TurnCancels() <-chan struct{} {
+	return t.cancelCh
+}
+
 // IsInteractive returns whether the TUI frontend is in interactive mode
 func (t *TUIFrontend) IsInteractive() bool {
 	return t.interactive
 }
 
-// Close closes the TUI frontend
+// Close closes the TUI frontend, returning once the TUI has given the
+// terminal back. Calling it again does nothing.
 func (t *TUIFrontend) Close() {
-	if t.interactive && t.program != nil {
-		t.program.Quit()
-		// Don't wait for done channel if we're already done
-		select {
-		case <-t.done:
-			// Program has exited
-		default:
-			// Program is still running, wait for it to finish
+	t.closeOnce.Do(func() {
+		if t.interactive && t.program != nil {
+			// Quitting a program that has exited already does nothing
+			t.program.Quit()
 			<-t.done
+		} else {
+			t.console.finish()
 		}
-	}
+	})
 }
@@ -0,0 +1,86 @@
+package frontend
+
+import (
+	"strings"
+	"testing"
+
+	"tiny-trae/internal/agent"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestWrapTextDisplayWidth(t *testing.T) {
+	for _, text := range []string{
+		"你好世界 这是一个 很长的 中文句子 需要 正确地 换行",
+		"emoji 🎉🎉🎉 party 🎉🎉🎉 time 🎉🎉🎉",
+		"a line\nanother line that is long enough to wrap around",
+		"averyveryverylongwordwithoutanyspacesatall",
+	} {
+		for _, line := range strings.Split(wrapText(text, 12), "\n") {
+			if w := lipgloss.Width(line); w > 12 {
+				t.Errorf("Line %q of %q is %d cells wide", line, text, w)
+			}
+		}
+	}
+	if got := wrapText("first\nsecond", 40); got != "first\nsecond" {
+		t.Errorf("Expected line breaks to be kept, got %q", got)
+	}
+}
+
+func TestCtrlCCancelsTurn(t *testing.T) {
+	m := inputModel()
+	m.interactive = true
+	m.waitingForResponse = true
+	m.cancelCh = make(chan struct{}, 1)
+	for _, key := range []string{"q", "ctrl+c"} {
+		keyMsg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+		if key == "ctrl+c" {
+			keyMsg = tea.KeyMsg{Type: tea.KeyCtrlC}
+		}
+		model, cmd := m.Update(keyMsg)
+		m = model.(tuiModel)
+		if cmd != nil {
+			if _, quit := cmd().(tea.QuitMsg); quit {
+				t.Fatalf("Expected %s not to quit during a turn", key)
+			}
+		}
+	}
+	if !m.cancelling || len(m.cancelCh) != 1 {
+		t.Error("Expected Ctrl+C to cancel the turn")
+	}
+
+	// A second Ctrl+C quits, without exiting, so the session can clean up
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC}); cmd == nil {
+		t.Error("Expected a second Ctrl+C to quit")
+	} else if _, quit := cmd().(tea.QuitMsg); !quit {
+		t.Error("Expected a second Ctrl+C to quit")
+	}
+
+	model, _ := m.Update(inputRequestMsg{})
+	if model.(tuiModel).cancelling {
+		t.Error("Expected the cancel to end with the turn")
+	}
+}
+
+func TestCtrlCQuitsWhenIdle(t *testing.T) {
+	m := inputModel()
+	m.interactive = true
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if cmd == nil {
+		t.Fatal("Expected Ctrl+C to quit")
+	}
+	if _, quit := cmd().(tea.QuitMsg); !quit {
+		t.Error("Expected Ctrl+C to quit")
+	}
+}
+
+func TestNonInteractiveApprovalDenies(t *testing.T) {
+	f := NewTUIFrontend(false, TUIOptions{})
+	if decision := f.RequestApproval(agent.ApprovalRequest{ToolName: "bash"}); decision != agent.ApprovalDeny {
+		t.Errorf("Expected a non-interactive run to deny, got %q", decision)
+	}
+	if decision := (&JSONLFrontend{}).RequestApproval(agent.ApprovalRequest{ToolName: "bash"}); decision != agent.ApprovalDeny {
+		t.Errorf("Expected a JSONL run to deny, got %q", decision)
+	}
+}
@@ -0,0 +1,172 @@
+package frontend
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"gopkg.in/yaml.v3"
+)
+
+// KeyBindings selects the TUI's key bindings.
+type KeyBindings string
+
+const (
+	// KeyBindingsDefault edits the input like a plain text field.
+	KeyBindingsDefault KeyBindings = "default"
+	// KeyBindingsVim adds vim's normal and insert modes to the input, and
+	// j/k, Ctrl+D/Ctrl+U, gg, and G to scroll the transcript.
+	KeyBindingsVim KeyBindings = "vim"
+)
+
+// tuiConfig is the format of the TUI's config file.
+type tuiConfig struct {
+	KeyBindings KeyBindings `yaml:"keybindings"`
+}
+
+// TUIConfigPath returns the location of the user's TUI config file.
+func TUIConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tiny-trae", "tui.yaml"), nil
+}
+
+// LoadKeyBindings returns the key bindings set in the user's TUI config
+// file, or the default ones if there is no file.
+func LoadKeyBindings() (KeyBindings, error) {
+	path, err := TUIConfigPath()
+	if err != nil {
+		return KeyBindingsDefault, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return KeyBindingsDefault, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var config tuiConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	switch config.KeyBindings {
+	case "":
+		return KeyBindingsDefault, nil
+	case KeyBindingsDefault, KeyBindingsVim:
+		return config.KeyBindings, nil
+	}
+	return "", fmt.Errorf("%s: unknown keybindings %q (want default or vim)", path, config.KeyBindings)
+}
+
+// vimState is the state of the vim key bindings.
+type vimState struct {
+	// normal is set in normal mode; the input starts in insert mode
+	normal bool
+	// pending is the first key of a two-key command, g or d
+	pending string
+}
+
+// updateVim handles a key with the vim bindings and reports whether it
+// did. Keys it leaves, such as Enter, Ctrl+C, or typing in insert mode, get
+// their usual meaning.
+func (m tuiModel) updateVim(msg tea.KeyMsg) (tuiModel, bool) {
+	inputActive := m.waitingForInput && !m.waitingForResponse && !m.processingTool && !m.searching
+	key := msg.String()
+	if inputActive && !m.vim.normal {
+		if key == "esc" {
+			m.vim.normal = true
+			return m, true
+		}
+		return m, false
+	}
+
+	if pending := m.vim.pending; pending != "" {
+		m.vim.pending = ""
+		switch pending + key {
+		case "gg":
+			m.transcript.gotoTop()
+			m.follow = m.transcript.atBottom()
+		case "dd":
+			if inputActive {
+				m.textInput.CursorStart()
+				m.textInput, _ = m.textInput.Update(tea.KeyMsg{Type: tea.KeyCtrlK})
+			}
+		}
+		return m, true
+	}
+
+	// Scrolling works whether or not the input is active
+	switch key {
+	case "j":
+		m.transcript.scrollDown(1)
+	case "k":
+		m.transcript.scrollUp(1)
+	case "ctrl+d":
+		m.transcript.scrollDown(m.transcript.height / 2)
+	case "ctrl+u":
+		m.transcript.scrollUp(m.transcript.height / 2)
+	case "G":
+		m.transcript.gotoBottom()
+	case "g":
+		m.vim.pending = key
+		return m, true
+	default:
+		if !inputActive {
+			return m, false
+		}
+		return m.updateVimInput(msg)
+	}
+	m.follow = m.transcript.atBottom()
+	return m, true
+}
+
+// updateVimInput handles a normal mode key that edits the input.
+func (m tuiModel) updateVimInput(msg tea.KeyMsg) (tuiModel, bool) {
+	// send passes an equivalent key to the input
+	send := func(keys ...tea.KeyMsg) {
+		for _, key := range keys {
+			m.textInput, _ = m.textInput.Update(key)
+		}
+	}
+	switch msg.String() {
+	case "i":
+		m.vim.normal = false
+	case "a":
+		send(tea.KeyMsg{Type: tea.KeyRight})
+		m.vim.normal = false
+	case "I":
+		m.textInput.CursorStart()
+		m.vim.normal = false
+	case "A":
+		m.textInput.CursorEnd()
+		m.vim.normal = false
+	case "o":
+		m.textInput.CursorEnd()
+		m.textInput.InsertRune('\n')
+		m.vim.normal = false
+	case "h":
+		send(tea.KeyMsg{Type: tea.KeyLeft})
+	case "l":
+		send(tea.KeyMsg{Type: tea.KeyRight})
+	case "0":
+		m.textInput.CursorStart()
+	case "$":
+		m.textInput.CursorEnd()
+	case "w":
+		send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}, Alt: true})
+	case "b":
+		send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'b'}, Alt: true})
+	case "x":
+		send(tea.KeyMsg{Type: tea.KeyDelete})
+	case "d":
+		m.vim.pending = "d"
+	default:
+		// Other letters do nothing in normal mode rather than being typed
+		return m, msg.Type == tea.KeyRunes && !msg.Alt && !msg.Paste
+	}
+	return m, true
+}
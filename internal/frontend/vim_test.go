@@ -0,0 +1,76 @@
+package frontend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestLoadKeyBindings(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	if keys, err := LoadKeyBindings(); err != nil || keys != KeyBindingsDefault {
+		t.Errorf("Expected the default bindings without a file, got %q, %v", keys, err)
+	}
+
+	path := filepath.Join(configHome, "tiny-trae", "tui.yaml")
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	os.WriteFile(path, []byte("keybindings: vim\n"), 0o644)
+	if keys, err := LoadKeyBindings(); err != nil || keys != KeyBindingsVim {
+		t.Errorf("Expected the vim bindings, got %q, %v", keys, err)
+	}
+
+	os.WriteFile(path, []byte("keybindings: emacs\n"), 0o644)
+	if _, err := LoadKeyBindings(); err == nil {
+		t.Error("Expected an error for unknown bindings")
+	}
+}
+
+func TestVimBindings(t *testing.T) {
+	m := inputModel()
+	m.keyBindings = KeyBindingsVim
+	// The transcript gets two rows above the one-line input
+	m.height = 7
+	m.transcript.append(strings.Repeat("line\n", 9) + "line")
+	m.follow = true
+
+	press := func(keys ...string) {
+		for _, key := range keys {
+			msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+			if key == "esc" {
+				msg = tea.KeyMsg{Type: tea.KeyEsc}
+			}
+			model, _ := m.Update(msg)
+			m = model.(tuiModel)
+		}
+	}
+
+	press("h", "i")
+	if m.textInput.Value() != "hi" {
+		t.Fatalf("Expected insert mode to type, got %q", m.textInput.Value())
+	}
+
+	press("esc", "k", "k", "z")
+	if !m.vim.normal || m.transcript.yOffset != 6 || m.follow {
+		t.Errorf("Expected k to scroll up in normal mode, got offset %d", m.transcript.yOffset)
+	}
+	if m.textInput.Value() != "hi" {
+		t.Errorf("Expected normal mode keys not to be typed, got %q", m.textInput.Value())
+	}
+	press("g", "g")
+	if m.transcript.yOffset != 0 {
+		t.Errorf("Expected gg to go to the top, got offset %d", m.transcript.yOffset)
+	}
+	press("G")
+	if !m.follow {
+		t.Error("Expected G to go to the bottom and follow")
+	}
+
+	press("d", "d", "A", "ok")
+	if m.textInput.Value() != "ok" || m.vim.normal {
+		t.Errorf("Expected dd to clear the line and A to insert, got %q", m.textInput.Value())
+	}
+}
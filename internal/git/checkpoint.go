@@ -0,0 +1,179 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"tiny-trae/internal/ignore"
+)
+
+// checkpointRefPrefix is where checkpoint commits are kept so that git's
+// garbage collection does not remove them.
+const checkpointRefPrefix = "refs/tiny-trae/checkpoints/"
+
+// Checkpoint is a snapshot of a work tree's files, including uncommitted
+// and untracked changes, stored as a commit that is not on any branch.
+type Checkpoint struct {
+	// Root is the top-level directory of the work tree.
+	Root string
+	// Ref is the ref that keeps the checkpoint commit alive.
+	Ref string
+	// Commit is the hash of the checkpoint commit.
+	Commit string
+}
+
+// CreateCheckpoint records the current state of the work tree containing dir
+// under refs/tiny-trae/checkpoints/<name>. Files ignored by git or listed in
+// a .traeignore file are not recorded. The user's index, branches, and stash are left untouched.
+func CreateCheckpoint(ctx context.Context, dir, name string) (Checkpoint, error) {
+	root, err := Root(ctx, dir)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	tree, err := snapshotTree(ctx, root)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+
+	args := []string{"commit-tree", tree, "-m", "tiny-trae checkpoint " + name}
+	if head, err := Run(ctx, root, "rev-parse", "--verify", "--quiet", "HEAD"); err == nil {
+		args = append(args, "-p", strings.TrimSpace(head))
+	}
+	commit, err := run(ctx, root, checkpointIdentity, nil, args...)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	commit = strings.TrimSpace(commit)
+
+	ref := checkpointRefPrefix + name
+	if _, err := Run(ctx, root, "update-ref", ref, commit); err != nil {
+		return Checkpoint{}, err
+	}
+	return Checkpoint{Root: root, Ref: ref, Commit: commit}, nil
+}
+
+// DeleteCheckpoint deletes cp's ref, so that git's garbage collection can
+// remove the checkpoint commit once nothing else refers to it. It is not an
+// error if the ref is already gone.
+func DeleteCheckpoint(ctx context.Context, cp Checkpoint) error {
+	if _, err := Run(ctx, cp.Root, "rev-parse", "--verify", "--quiet", cp.Ref); err != nil {
+		return nil
+	}
+	_, err := Run(ctx, cp.Root, "update-ref", "-d", cp.Ref, cp.Commit)
+	return err
+}
+
+// checkpointIdentity lets checkpoint commits be created even when the user
+// has not configured a git identity.
+var checkpointIdentity = []string{
+	"GIT_AUTHOR_NAME=tiny-trae", "GIT_AUTHOR_EMAIL=tiny-trae@localhost",
+	"GIT_COMMITTER_NAME=tiny-trae", "GIT_COMMITTER_EMAIL=tiny-trae@localhost",
+}
+
+// snapshotTree writes a tree object of every non-ignored file in the work
+// tree, using a copy of the index so the real one is not modified. Starting
+// from the real index lets git skip rehashing files that have not changed.
+func snapshotTree(ctx context.Context, root string) (string, error) {
+	index, err := tempIndex()
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(index)
+	if realIndex, err := Run(ctx, root, "rev-parse", "--path-format=absolute", "--git-path", "index"); err == nil {
+		if data, err := os.ReadFile(strings.TrimSpace(realIndex)); err == nil {
+			if err := os.WriteFile(index, data, 0600); err != nil {
+				return "", err
+			}
+		}
+	}
+	env := []string{"GIT_INDEX_FILE=" + index}
+
+	if _, err := run(ctx, root, env, nil, "add", "--all", "--", "."); err != nil {
+		return "", err
+	}
+	if err := removeTraeIgnored(ctx, root, env); err != nil {
+		return "", err
+	}
+	tree, err := run(ctx, root, env, nil, "write-tree")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(tree), nil
+}
+
+// removeTraeIgnored drops the paths matched by .traeignore files and the
+// project's ignore patterns from the index selected by env, so snapshots
+// never contain them.
+func removeTraeIgnored(ctx context.Context, root string, env []string) error {
+	ignoreFiles, err := run(ctx, root, env, nil, "ls-files", "-z", "--", ":(glob)**/"+ignore.TraeIgnoreFile)
+	if err != nil || (ignoreFiles == "" && len(ignore.ProjectPatterns()) == 0) {
+		return err
+	}
+
+	m := ignore.NewTraeIgnoreMatcher(root)
+	files, err := run(ctx, root, env, nil, "ls-files", "-z")
+	if err != nil {
+		return err
+	}
+	var remove strings.Builder
+	for _, name := range strings.Split(files, "\x00") {
+		if name != "" && m.IgnoredPath(filepath.Join(root, filepath.FromSlash(name)), false) {
+			remove.WriteString(name + "\x00")
+		}
+	}
+	if remove.Len() == 0 {
+		return nil
+	}
+	_, err = run(ctx, root, env, strings.NewReader(remove.String()), "update-index", "-z", "--force-remove", "--stdin")
+	return err
+}
+
+// RestoreCheckpoint puts the work tree's files back to their state when cp
+// was created: changed and deleted files are restored and files created
+// since then are removed. Ignored files, including those listed in
+// .traeignore, and the user's index are left untouched.
+func RestoreCheckpoint(ctx context.Context, cp Checkpoint) error {
+	current, err := snapshotTree(ctx, cp.Root)
+	if err != nil {
+		return err
+	}
+	added, err := Run(ctx, cp.Root, "diff-tree", "-r", "-z", "--name-only", "--diff-filter=A", cp.Commit, current)
+	if err != nil {
+		return err
+	}
+	for _, name := range strings.Split(added, "\x00") {
+		if name == "" {
+			continue
+		}
+		if err := os.Remove(filepath.Join(cp.Root, filepath.FromSlash(name))); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+	}
+
+	index, err := tempIndex()
+	if err != nil {
+		return err
+	}
+	defer os.Remove(index)
+	env := []string{"GIT_INDEX_FILE=" + index}
+
+	if _, err := run(ctx, cp.Root, env, nil, "read-tree", cp.Commit); err != nil {
+		return err
+	}
+	_, err = run(ctx, cp.Root, env, nil, "checkout-index", "--all", "--force")
+	return err
+}
+
+// tempIndex returns the path of a new, not yet existing index file. git
+// refuses to read an empty file as an index, so none is created.
+func tempIndex() (string, error) {
+	file, err := os.CreateTemp("", "tiny-trae-index-*")
+	if err != nil {
+		return "", err
+	}
+	file.Close()
+	return file.Name(), os.Remove(file.Name())
+}
@@ -0,0 +1,152 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	dir := initRepo(t)
+	ctx := context.Background()
+
+	// Uncommitted and untracked changes belong to the checkpoint too
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return "<missing>"
+		}
+		return string(data)
+	}
+	write("a.txt", "a changed\n")
+	write("notes.txt", "untracked\n")
+	write(".gitignore", "*.log\n")
+	if _, err := Run(ctx, dir, "add", "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	stagedBefore, _ := Run(ctx, dir, "diff", "--cached", "--name-only")
+
+	cp, err := CreateCheckpoint(ctx, dir, "turn-1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cp.Ref != "refs/tiny-trae/checkpoints/turn-1" {
+		t.Errorf("Unexpected ref %q", cp.Ref)
+	}
+	if stagedAfter, _ := Run(ctx, dir, "diff", "--cached", "--name-only"); stagedAfter != stagedBefore {
+		t.Errorf("Expected the index to be untouched, staged %q became %q", stagedBefore, stagedAfter)
+	}
+
+	// Make the changes a turn would make
+	write("a.txt", "edited by agent\n")
+	write("new.go", "package main\n")
+	write("debug.log", "ignored\n")
+	if err := os.Remove(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RestoreCheckpoint(ctx, cp); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := read("a.txt"); got != "a changed\n" {
+		t.Errorf("Expected a.txt to be restored, got %q", got)
+	}
+	if got := read("notes.txt"); got != "untracked\n" {
+		t.Errorf("Expected notes.txt to be restored, got %q", got)
+	}
+	if got := read("new.go"); got != "<missing>" {
+		t.Errorf("Expected new.go to be removed, got %q", got)
+	}
+	if got := read("debug.log"); got != "ignored\n" {
+		t.Errorf("Expected ignored file to be left alone, got %q", got)
+	}
+
+	log, _ := Run(ctx, dir, "log", "--oneline")
+	if strings.Count(log, "\n") != 1 {
+		t.Errorf("Expected the branch history to be unchanged, got:\n%s", log)
+	}
+}
+
+func TestCheckpointSkipsTraeIgnored(t *testing.T) {
+	dir := initRepo(t)
+	ctx := context.Background()
+
+	for name, content := range map[string]string{
+		".traeignore":     "secrets/\n",
+		"secrets/key":     "original\n",
+		"sub/.traeignore": "*.bin\n",
+		"sub/data.bin":    "original\n",
+		"sub/main.go":     "package sub\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cp, err := CreateCheckpoint(ctx, dir, "turn-1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	files, err := Run(ctx, dir, "ls-tree", "-r", "--name-only", cp.Commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(files, "secrets/key") || strings.Contains(files, "sub/data.bin") {
+		t.Errorf("Expected .traeignore paths to be left out of the checkpoint, got:\n%s", files)
+	}
+	if !strings.Contains(files, "sub/main.go") {
+		t.Errorf("Expected sub/main.go in the checkpoint, got:\n%s", files)
+	}
+
+	// Ignored files are neither removed nor restored
+	if err := os.WriteFile(filepath.Join(dir, "secrets/key"), []byte("rotated\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreCheckpoint(ctx, cp); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "secrets/key")); string(data) != "rotated\n" {
+		t.Errorf("Expected secrets/key to be left alone, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub/data.bin")); err != nil {
+		t.Errorf("Expected sub/data.bin to be kept: %v", err)
+	}
+}
+
+func TestDeleteCheckpoint(t *testing.T) {
+	dir := initRepo(t)
+	ctx := context.Background()
+
+	cp, err := CreateCheckpoint(ctx, dir, "turn-1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := DeleteCheckpoint(ctx, cp); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if refs, _ := Run(ctx, dir, "for-each-ref", checkpointRefPrefix); refs != "" {
+		t.Errorf("Expected the checkpoint ref to be deleted, got %q", refs)
+	}
+	if err := DeleteCheckpoint(ctx, cp); err != nil {
+		t.Errorf("Expected deleting it again to succeed, got %v", err)
+	}
+}
+
+func TestCreateCheckpointOutsideRepo(t *testing.T) {
+	if _, err := CreateCheckpoint(context.Background(), t.TempDir(), "x"); err == nil {
+		t.Error("Expected error outside a git repository")
+	}
+}
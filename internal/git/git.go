@@ -0,0 +1,88 @@
+// Package git runs git commands for the tools and session features that
+// work with the user's repository.
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Run runs git with args in dir and returns its standard output. If git exits
+// with an error, the error includes its standard error output.
+func Run(ctx context.Context, dir string, args ...string) (string, error) {
+	return RunInput(ctx, dir, nil, args...)
+}
+
+// RunInput is like Run but feeds stdin to git.
+func RunInput(ctx context.Context, dir string, stdin io.Reader, args ...string) (string, error) {
+	return run(ctx, dir, nil, stdin, args...)
+}
+
+// run runs git with extra environment variables added to the process's own.
+func run(ctx context.Context, dir string, env []string, stdin io.Reader, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdin = stdin
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			msg := strings.TrimSpace(stderr.String())
+			if msg == "" {
+				msg = strings.TrimSpace(stdout.String())
+			}
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("failed to run git: %w", err)
+	}
+	return stdout.String(), nil
+}
+
+// Root returns the top-level directory of the work tree containing dir.
+func Root(ctx context.Context, dir string) (string, error) {
+	out, err := Run(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// CurrentBranch returns the name of the checked-out branch, or an error if
+// HEAD is detached.
+func CurrentBranch(ctx context.Context, dir string) (string, error) {
+	out, err := Run(ctx, dir, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("HEAD is not on a branch")
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// AddWorktree creates a new branch starting at base (HEAD if empty) and
+// checks it out in a new worktree at path.
+func AddWorktree(ctx context.Context, dir, path, branch, base string) error {
+	args := []string{"worktree", "add", "-b", branch, path}
+	if base != "" {
+		args = append(args, base)
+	}
+	_, err := Run(ctx, dir, args...)
+	return err
+}
+
+// DefaultWorktreePath returns where a worktree for branch is created when no
+// path is given: a sibling of the repository root named after both, so
+// "/src/app" and branch "fix/login" give "/src/app-fix-login".
+func DefaultWorktreePath(root, branch string) string {
+	return filepath.Join(filepath.Dir(root), filepath.Base(root)+"-"+strings.ReplaceAll(branch, "/", "-"))
+}
@@ -0,0 +1,89 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initRepo creates a repository with one commit on branch main.
+func initRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	ctx := context.Background()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+	} {
+		if _, err := Run(ctx, dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Run(ctx, dir, "add", "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Run(ctx, dir, "commit", "-q", "-m", "initial"); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestRootAndBranch(t *testing.T) {
+	dir := initRepo(t)
+	ctx := context.Background()
+
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	root, err := Root(ctx, sub)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// The temp dir may be behind a symlink, so compare resolved paths
+	expected, _ := filepath.EvalSymlinks(dir)
+	if resolved, _ := filepath.EvalSymlinks(root); resolved != expected {
+		t.Errorf("Expected root %s, got %s", expected, root)
+	}
+
+	branch, err := CurrentBranch(ctx, dir)
+	if err != nil || branch != "main" {
+		t.Errorf("Expected branch main, got %q (%v)", branch, err)
+	}
+}
+
+func TestRunError(t *testing.T) {
+	dir := initRepo(t)
+	_, err := Run(context.Background(), dir, "checkout", "no-such-branch")
+	if err == nil || !strings.Contains(err.Error(), "no-such-branch") {
+		t.Errorf("Expected error mentioning the branch, got %v", err)
+	}
+}
+
+func TestRunInput(t *testing.T) {
+	dir := initRepo(t)
+	out, err := RunInput(context.Background(), dir, strings.NewReader("hello\n"), "hash-object", "--stdin")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.TrimSpace(out) != "ce013625030ba8dba906f756967f9e9ca394464a" {
+		t.Errorf("Unexpected hash %q", out)
+	}
+}
+
+func TestDefaultWorktreePath(t *testing.T) {
+	got := DefaultWorktreePath(filepath.FromSlash("/src/app"), "fix/login")
+	if got != filepath.FromSlash("/src/app-fix-login") {
+		t.Errorf("Unexpected path %q", got)
+	}
+}
@@ -0,0 +1,96 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// sessionBranchPrefix is prepended to the names of session branches.
+const sessionBranchPrefix = "tiny-trae/session-"
+
+// SessionWorktree is a throwaway worktree and branch that a whole session
+// runs in, so the user's own checkout is untouched until they merge the
+// result.
+type SessionWorktree struct {
+	// Repo is the top-level directory of the user's work tree.
+	Repo string
+	// Path is the directory of the session's worktree.
+	Path string
+	// Branch is the branch checked out in the worktree.
+	Branch string
+	// Base is the commit the branch started from.
+	Base string
+}
+
+// StartSession creates the branch tiny-trae/session-<name> from HEAD of the
+// repository containing dir and checks it out in a new sibling worktree.
+func StartSession(ctx context.Context, dir, name string) (SessionWorktree, error) {
+	root, err := Root(ctx, dir)
+	if err != nil {
+		return SessionWorktree{}, err
+	}
+	base, err := Run(ctx, root, "rev-parse", "--verify", "HEAD")
+	if err != nil {
+		return SessionWorktree{}, fmt.Errorf("the repository has no commits to start a worktree from")
+	}
+
+	session := SessionWorktree{
+		Repo:   root,
+		Branch: sessionBranchPrefix + name,
+		Base:   strings.TrimSpace(base),
+	}
+	session.Path = DefaultWorktreePath(root, session.Branch)
+	if err := AddWorktree(ctx, root, session.Path, session.Branch, session.Base); err != nil {
+		return SessionWorktree{}, err
+	}
+	return session, nil
+}
+
+// Changed reports whether the session made commits or left uncommitted
+// changes in its worktree.
+func (s SessionWorktree) Changed(ctx context.Context) (bool, error) {
+	status, err := Run(ctx, s.Path, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	if strings.TrimSpace(status) != "" {
+		return true, nil
+	}
+	count, err := Run(ctx, s.Path, "rev-list", "--count", s.Base+"..HEAD")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(count) != "0", nil
+}
+
+// Merge commits any uncommitted changes in the worktree with message, merges
+// the session branch into the branch checked out in the user's work tree,
+// and then discards the worktree. If the merge fails, the worktree and
+// branch are kept so the user can finish it by hand.
+func (s SessionWorktree) Merge(ctx context.Context, message string) error {
+	if _, err := Run(ctx, s.Path, "add", "--all"); err != nil {
+		return err
+	}
+	if _, err := Run(ctx, s.Path, "diff", "--cached", "--quiet"); err != nil {
+		if _, err := Run(ctx, s.Path, "commit", "--quiet", "-m", message); err != nil {
+			return err
+		}
+	}
+	if _, err := Run(ctx, s.Repo, "merge", "--no-edit", s.Branch); err != nil {
+		// Leave the user's work tree as it was before the merge was attempted
+		Run(ctx, s.Repo, "merge", "--abort")
+		return err
+	}
+	return s.Discard(ctx)
+}
+
+// Discard removes the worktree, including any uncommitted changes in it, and
+// deletes the session branch.
+func (s SessionWorktree) Discard(ctx context.Context) error {
+	if _, err := Run(ctx, s.Repo, "worktree", "remove", "--force", s.Path); err != nil {
+		return err
+	}
+	_, err := Run(ctx, s.Repo, "branch", "-D", s.Branch)
+	return err
+}
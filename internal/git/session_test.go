@@ -0,0 +1,70 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionMerge(t *testing.T) {
+	dir := initRepo(t)
+	ctx := context.Background()
+
+	session, err := StartSession(ctx, dir, "1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if session.Branch != "tiny-trae/session-1" {
+		t.Errorf("Unexpected branch %q", session.Branch)
+	}
+	if changed, err := session.Changed(ctx); err != nil || changed {
+		t.Errorf("Expected a new session to be unchanged, got %v, %v", changed, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(session.Path, "b.txt"), []byte("b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.txt")); !os.IsNotExist(err) {
+		t.Fatalf("Expected the user's checkout to be untouched")
+	}
+	if changed, err := session.Changed(ctx); err != nil || !changed {
+		t.Errorf("Expected the session to be changed, got %v, %v", changed, err)
+	}
+
+	if err := session.Merge(ctx, "Add b"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "b.txt")); err != nil || string(data) != "b\n" {
+		t.Errorf("Expected b.txt to be merged, got %q, %v", data, err)
+	}
+	if _, err := os.Stat(session.Path); !os.IsNotExist(err) {
+		t.Errorf("Expected the worktree to be removed")
+	}
+	if _, err := Run(ctx, dir, "rev-parse", "--verify", session.Branch); err == nil {
+		t.Errorf("Expected the session branch to be deleted")
+	}
+}
+
+func TestSessionDiscard(t *testing.T) {
+	dir := initRepo(t)
+	ctx := context.Background()
+
+	session, err := StartSession(ctx, dir, "2")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(session.Path, "a.txt"), []byte("changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := session.Discard(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(data) != "a\n" {
+		t.Errorf("Expected a.txt to be unchanged, got %q", data)
+	}
+	if _, err := os.Stat(session.Path); !os.IsNotExist(err) {
+		t.Errorf("Expected the worktree to be removed")
+	}
+}
@@ -0,0 +1,273 @@
+// Package github fetches issues and pull requests from the GitHub REST API.
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"tiny-trae/internal/git"
+)
+
+// DefaultAPIURL is the GitHub REST API used when GITHUB_API_URL is not set.
+const DefaultAPIURL = "https://api.github.com"
+
+// Client calls the GitHub REST API.
+type Client struct {
+	// BaseURL is the API root, e.g. https://api.github.com.
+	BaseURL string
+	// Token authenticates requests; public repositories work without one,
+	// at a lower rate limit.
+	Token string
+}
+
+// NewClient returns a client configured from GITHUB_API_URL and GITHUB_TOKEN
+// (or GH_TOKEN, as used by the gh CLI).
+func NewClient() *Client {
+	client := &Client{BaseURL: DefaultAPIURL, Token: os.Getenv("GITHUB_TOKEN")}
+	if url := os.Getenv("GITHUB_API_URL"); url != "" {
+		client.BaseURL = strings.TrimRight(url, "/")
+	}
+	if client.Token == "" {
+		client.Token = os.Getenv("GH_TOKEN")
+	}
+	return client
+}
+
+// Issue is an issue or pull request with its discussion.
+type Issue struct {
+	Number    int
+	Title     string
+	Body      string
+	State     string
+	Author    string
+	URL       string
+	Labels    []string
+	CreatedAt time.Time
+	Comments  []Comment
+
+	// PullRequest is set for pull requests.
+	PullRequest *PullRequest
+}
+
+// PullRequest holds the fields only pull requests have.
+type PullRequest struct {
+	Base   string
+	Head   string
+	Merged bool
+	Draft  bool
+	// ReviewComments are the comments left on lines of the diff.
+	ReviewComments []Comment
+	// Diff is the unified diff of the pull request, if it was requested.
+	Diff string
+}
+
+// Comment is a comment on an issue or on a line of a pull request.
+type Comment struct {
+	Author    string
+	Body      string
+	CreatedAt time.Time
+	// Path and Line locate review comments in the diff.
+	Path string
+	Line int
+}
+
+// Reference identifies an issue or pull request.
+type Reference struct {
+	// Repo is "owner/name", or empty for the current repository.
+	Repo   string
+	Number int
+}
+
+// referencePattern matches "owner/repo#123", "#123", and "123".
+var referencePattern = regexp.MustCompile(`^(?:([\w.-]+/[\w.-]+)#|#)?(\d+)$`)
+
+// urlPattern matches issue and pull request URLs on github.com or GitHub
+// Enterprise hosts.
+var urlPattern = regexp.MustCompile(`^https?://[^/]+/([\w.-]+/[\w.-]+)/(?:issues|pull)/(\d+)`)
+
+// ParseReference parses an issue or pull request URL, "owner/repo#123",
+// "#123", or "123".
+func ParseReference(s string) (Reference, error) {
+	s = strings.TrimSpace(s)
+	match := urlPattern.FindStringSubmatch(s)
+	if match == nil {
+		match = referencePattern.FindStringSubmatch(s)
+	}
+	if match == nil {
+		return Reference{}, fmt.Errorf("%q is not an issue or pull request URL or number", s)
+	}
+	number, _ := strconv.Atoi(match[2])
+	return Reference{Repo: match[1], Number: number}, nil
+}
+
+// remotePattern extracts "owner/repo" from SSH and HTTPS remote URLs.
+var remotePattern = regexp.MustCompile(`[:/]([\w.-]+/[\w.-]+?)(?:\.git)?/?$`)
+
+// RepoFromRemote returns the "owner/repo" of the git remote named remote in
+// the repository containing dir.
+func RepoFromRemote(ctx context.Context, dir, remote string) (string, error) {
+	url, err := git.Run(ctx, dir, "remote", "get-url", remote)
+	if err != nil {
+		return "", err
+	}
+	match := remotePattern.FindStringSubmatch(strings.TrimSpace(url))
+	if match == nil {
+		return "", fmt.Errorf("cannot tell the GitHub repository from remote %s (%s)", remote, strings.TrimSpace(url))
+	}
+	return match[1], nil
+}
+
+// apiUser is a user object in API responses.
+type apiUser struct {
+	Login string `json:"login"`
+}
+
+// apiComment is an issue or review comment in API responses.
+type apiComment struct {
+	User      apiUser   `json:"user"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	Path      string    `json:"path"`
+	Line      int       `json:"line"`
+}
+
+// FetchIssue fetches an issue or pull request with its comments. For pull
+// requests, the review comments and, if withDiff is set, the diff are
+// fetched too.
+func (c *Client) FetchIssue(ctx context.Context, repo string, number int, withDiff bool) (*Issue, error) {
+	var raw struct {
+		Number    int       `json:"number"`
+		Title     string    `json:"title"`
+		Body      string    `json:"body"`
+		State     string    `json:"state"`
+		HTMLURL   string    `json:"html_url"`
+		User      apiUser   `json:"user"`
+		CreatedAt time.Time `json:"created_at"`
+		Labels    []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+		PullRequest *struct{} `json:"pull_request"`
+	}
+	issuePath := fmt.Sprintf("/repos/%s/issues/%d", repo, number)
+	if err := c.getJSON(ctx, issuePath, &raw); err != nil {
+		return nil, err
+	}
+
+	issue := &Issue{
+		Number:    raw.Number,
+		Title:     raw.Title,
+		Body:      raw.Body,
+		State:     raw.State,
+		Author:    raw.User.Login,
+		URL:       raw.HTMLURL,
+		CreatedAt: raw.CreatedAt,
+	}
+	for _, label := range raw.Labels {
+		issue.Labels = append(issue.Labels, label.Name)
+	}
+
+	var comments []apiComment
+	if err := c.getJSON(ctx, issuePath+"/comments?per_page=100", &comments); err != nil {
+		return nil, err
+	}
+	issue.Comments = convertComments(comments)
+
+	if raw.PullRequest == nil {
+		return issue, nil
+	}
+
+	var pull struct {
+		Merged bool `json:"merged"`
+		Draft  bool `json:"draft"`
+		Base   struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+		Head struct {
+			Label string `json:"label"`
+		} `json:"head"`
+	}
+	pullPath := fmt.Sprintf("/repos/%s/pulls/%d", repo, number)
+	if err := c.getJSON(ctx, pullPath, &pull); err != nil {
+		return nil, err
+	}
+	issue.PullRequest = &PullRequest{Base: pull.Base.Ref, Head: pull.Head.Label, Merged: pull.Merged, Draft: pull.Draft}
+
+	var reviewComments []apiComment
+	if err := c.getJSON(ctx, pullPath+"/comments?per_page=100", &reviewComments); err != nil {
+		return nil, err
+	}
+	issue.PullRequest.ReviewComments = convertComments(reviewComments)
+
+	if withDiff {
+		diff, err := c.get(ctx, pullPath, "application/vnd.github.diff")
+		if err != nil {
+			return nil, err
+		}
+		issue.PullRequest.Diff = string(diff)
+	}
+	return issue, nil
+}
+
+// convertComments converts API comments.
+func convertComments(raw []apiComment) []Comment {
+	comments := make([]Comment, 0, len(raw))
+	for _, c := range raw {
+		comments = append(comments, Comment{Author: c.User.Login, Body: c.Body, CreatedAt: c.CreatedAt, Path: c.Path, Line: c.Line})
+	}
+	return comments
+}
+
+// getJSON fetches path and decodes the JSON response into v.
+func (c *Client) getJSON(ctx context.Context, path string, v any) error {
+	body, err := c.get(ctx, path, "application/vnd.github+json")
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// get fetches path from the API, asking for the given media type.
+func (c *Client) get(ctx context.Context, path, accept string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GitHub request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return body, nil
+	case resp.StatusCode == http.StatusNotFound && c.Token == "":
+		return nil, fmt.Errorf("GitHub returned 404 for %s; set GITHUB_TOKEN if the repository is private", path)
+	default:
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+			return nil, fmt.Errorf("GitHub returned %s for %s: %s", resp.Status, path, apiErr.Message)
+		}
+		return nil, fmt.Errorf("GitHub returned %s for %s", resp.Status, path)
+	}
+}
@@ -0,0 +1,116 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"testing"
+
+	"tiny-trae/internal/git"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := map[string]Reference{
+		"https://github.com/golang/go/issues/123":      {Repo: "golang/go", Number: 123},
+		"https://github.com/golang/go/pull/45/files":   {Repo: "golang/go", Number: 45},
+		"https://ghe.example.com/team/app.js/issues/7": {Repo: "team/app.js", Number: 7},
+		"golang/go#9": {Repo: "golang/go", Number: 9},
+		"#42":         {Number: 42},
+		" 42 ":        {Number: 42},
+	}
+	for input, want := range tests {
+		got, err := ParseReference(input)
+		if err != nil {
+			t.Errorf("ParseReference(%q) failed: %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseReference(%q) = %+v, want %+v", input, got, want)
+		}
+	}
+
+	for _, input := range []string{"", "issue 42", "golang/go123", "https://github.com/golang/go"} {
+		if _, err := ParseReference(input); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
+
+func TestRepoFromRemote(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	if _, err := git.Run(ctx, dir, "init", "-q"); err != nil {
+		t.Fatal(err)
+	}
+
+	for url, want := range map[string]string{
+		"git@github.com:lldong/tiny-trae.git":     "lldong/tiny-trae",
+		"https://github.com/lldong/tiny-trae":     "lldong/tiny-trae",
+		"https://github.com/lldong/tiny-trae.git": "lldong/tiny-trae",
+		"ssh://git@github.com/a/b.c.git":          "a/b.c",
+	} {
+		git.Run(ctx, dir, "remote", "remove", "origin")
+		if _, err := git.Run(ctx, dir, "remote", "add", "origin", url); err != nil {
+			t.Fatal(err)
+		}
+		got, err := RepoFromRemote(ctx, dir, "origin")
+		if err != nil || got != want {
+			t.Errorf("RepoFromRemote for %s = %q, %v; want %q", url, got, err, want)
+		}
+	}
+}
+
+func TestFetchPullRequest(t *testing.T) {
+	responses := map[string]string{
+		"/repos/o/r/issues/5":          `{"number":5,"title":"Fix crash","body":"Fixes #4","state":"open","html_url":"https://github.com/o/r/pull/5","user":{"login":"alice"},"labels":[{"name":"bug"}],"pull_request":{}}`,
+		"/repos/o/r/issues/5/comments": `[{"user":{"login":"bob"},"body":"Thanks!"}]`,
+		"/repos/o/r/pulls/5":           `{"merged":false,"draft":true,"base":{"ref":"main"},"head":{"label":"alice:fix"}}`,
+		"/repos/o/r/pulls/5/comments":  `[{"user":{"login":"bob"},"body":"Check nil here","path":"main.go","line":12}]`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Expected the token to be sent")
+		}
+		if r.URL.Path == "/repos/o/r/pulls/5" && r.Header.Get("Accept") == "application/vnd.github.diff" {
+			w.Write([]byte("diff --git a/main.go b/main.go\n"))
+			return
+		}
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	client := &Client{BaseURL: server.URL, Token: "secret"}
+	issue, err := client.FetchIssue(context.Background(), "o/r", 5, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if issue.Title != "Fix crash" || issue.Author != "alice" || len(issue.Labels) != 1 || len(issue.Comments) != 1 {
+		t.Errorf("Unexpected issue %+v", issue)
+	}
+	pr := issue.PullRequest
+	if pr == nil {
+		t.Fatal("Expected pull request details")
+	}
+	if pr.Base != "main" || pr.Head != "alice:fix" || !pr.Draft {
+		t.Errorf("Unexpected pull request %+v", pr)
+	}
+	if len(pr.ReviewComments) != 1 || pr.ReviewComments[0].Path != "main.go" || pr.ReviewComments[0].Line != 12 {
+		t.Errorf("Unexpected review comments %+v", pr.ReviewComments)
+	}
+	if pr.Diff != "diff --git a/main.go b/main.go\n" {
+		t.Errorf("Unexpected diff %q", pr.Diff)
+	}
+
+	if _, err := client.FetchIssue(context.Background(), "o/r", 6, false); err == nil {
+		t.Error("Expected error for a missing issue")
+	}
+}
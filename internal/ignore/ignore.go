@@ -0,0 +1,321 @@
+package ignore
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Pattern is a single gitignore-style pattern.
+type Pattern struct {
+	negate  bool
+	dirOnly bool
+	re      *regexp.Regexp
+	// source is the pattern as written, without a leading '!'
+	source string
+}
+
+// ParsePattern parses one line of a .gitignore file. It returns false for
+// blank lines and comments.
+func ParsePattern(line string) (Pattern, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return Pattern{}, false
+	}
+
+	var p Pattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	p.source = line
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return Pattern{}, false
+	}
+
+	// A pattern with a slash anywhere but the end is relative to the
+	// directory of the .gitignore file; otherwise it matches at any depth.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+	b.WriteString(globToRegexp(line))
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return Pattern{}, false
+	}
+	p.re = re
+	return p, true
+}
+
+// Negated reports whether the pattern started with '!'.
+func (p Pattern) Negated() bool {
+	return p.negate
+}
+
+// Match reports whether the slash-separated path, relative to the
+// pattern's base directory, matches the pattern.
+func (p Pattern) Match(relPath string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	return p.re.MatchString(relPath)
+}
+
+// globToRegexp translates gitignore glob syntax into a regular expression.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					// "**/" matches zero or more directories
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// TraeIgnoreFile names files that list paths tiny-trae should leave alone,
+// such as secrets or bulky generated data, in .gitignore format. They apply
+// on top of .gitignore, even outside a git repository.
+const TraeIgnoreFile = ".traeignore"
+
+// rule is a pattern together with the directory of the file it came from.
+type rule struct {
+	base    string
+	pattern Pattern
+}
+
+// Matcher decides whether paths are ignored according to the .gitignore
+// and .traeignore files of a directory tree. The .git directory itself is
+// always ignored.
+type Matcher struct {
+	dir    string
+	files  []string
+	rules  []rule
+	loaded map[string]bool
+}
+
+// NewMatcher returns a matcher for paths under dir. It loads .gitignore and
+// .traeignore files from the enclosing repository root down to dir, along
+// with the repository's .git/info/exclude file.
+func NewMatcher(dir string) *Matcher {
+	return newMatcher(dir, []string{".gitignore", TraeIgnoreFile}, true)
+}
+
+// NewTraeIgnoreMatcher is like NewMatcher but only loads .traeignore files,
+// for callers such as git that already apply the .gitignore rules.
+func NewTraeIgnoreMatcher(dir string) *Matcher {
+	return newMatcher(dir, []string{TraeIgnoreFile}, false)
+}
+
+// projectRules are the ignore patterns of the project's settings file; see
+// SetProjectPatterns.
+var projectRules []rule
+
+// SetProjectPatterns makes every matcher created afterwards also apply
+// patterns, in .gitignore format and relative to dir, as if dir had a
+// .traeignore file listing them. They come from the project's settings
+// file.
+func SetProjectPatterns(dir string, patterns []string) {
+	projectRules = nil
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return
+	}
+	for _, line := range patterns {
+		if p, ok := ParsePattern(line); ok {
+			projectRules = append(projectRules, rule{base: abs, pattern: p})
+		}
+	}
+}
+
+// ProjectPatterns returns the patterns set by SetProjectPatterns that
+// ignore paths, leaving out negated ones.
+func ProjectPatterns() []string {
+	var patterns []string
+	for _, r := range projectRules {
+		if !r.pattern.negate {
+			patterns = append(patterns, r.pattern.source)
+		}
+	}
+	return patterns
+}
+
+// newMatcher loads the named ignore files from the repository root down to
+// dir, and the repository's exclude file if exclude is set.
+func newMatcher(dir string, files []string, exclude bool) *Matcher {
+	m := &Matcher{files: files, loaded: make(map[string]bool), rules: slices.Clone(projectRules)}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return m
+	}
+	m.dir = abs
+
+	// Collect ancestors up to the repository root (or the filesystem root)
+	var dirs []string
+	for d := abs; ; d = filepath.Dir(d) {
+		dirs = append(dirs, d)
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			if exclude {
+				m.loadFile(d, filepath.Join(d, ".git", "info", "exclude"))
+			}
+			break
+		}
+		if filepath.Dir(d) == d {
+			// No repository found; only the directory itself applies
+			dirs = dirs[:1]
+			break
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		m.LoadDir(dirs[i])
+	}
+	return m
+}
+
+// LoadDir adds the rules from dir/.gitignore and dir/.traeignore, if
+// present. Loading the same directory twice has no effect.
+func (m *Matcher) LoadDir(dir string) {
+	abs, err := filepath.Abs(dir)
+	if err != nil || m.loaded[abs] {
+		return
+	}
+	m.loaded[abs] = true
+	for _, name := range m.files {
+		m.loadFile(abs, filepath.Join(abs, name))
+	}
+}
+
+// loadFile adds rules from a gitignore-format file whose patterns are
+// relative to base.
+func (m *Matcher) loadFile(base, path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if p, ok := ParsePattern(scanner.Text()); ok {
+			m.rules = append(m.rules, rule{base: base, pattern: p})
+		}
+	}
+}
+
+// Ignored reports whether path should be ignored. The last matching rule
+// wins, so negated patterns in deeper .gitignore files can re-include paths.
+func (m *Matcher) Ignored(path string, isDir bool) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	if filepath.Base(abs) == ".git" {
+		return true
+	}
+
+	ignored := false
+	for _, r := range m.rules {
+		rel, err := filepath.Rel(r.base, abs)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if r.pattern.Match(filepath.ToSlash(rel), isDir) {
+			ignored = !r.pattern.negate
+		}
+	}
+	return ignored
+}
+
+// IgnoredPath is like Ignored but also reports paths inside an ignored
+// directory, so it can be used on paths that were not reached by walking
+// the tree. The ignore files of the directories in between are loaded first.
+func (m *Matcher) IgnoredPath(path string, isDir bool) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(m.dir, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return m.Ignored(abs, isDir)
+	}
+
+	dir := m.dir
+	parts := strings.Split(rel, string(filepath.Separator))
+	for _, part := range parts[:len(parts)-1] {
+		dir = filepath.Join(dir, part)
+		if m.Ignored(dir, true) {
+			return true
+		}
+		m.LoadDir(dir)
+	}
+	return m.Ignored(abs, isDir)
+}
+
+// Walk walks the tree rooted at root like filepath.WalkDir, skipping ignored
+// files and directories and picking up nested .gitignore and .traeignore
+// files on the way.
+func Walk(root string, fn fs.WalkDirFunc) error {
+	m := NewMatcher(root)
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fn(path, d, err)
+		}
+		if path != root && m.Ignored(path, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			m.LoadDir(path)
+		}
+		return fn(path, d, nil)
+	})
+}
@@ -0,0 +1,185 @@
+package ignore
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		match   bool
+	}{
+		{"*.log", "debug.log", false, true},
+		{"*.log", "logs/debug.log", false, true},
+		{"*.log", "debug.txt", false, false},
+		{"/build", "build", true, true},
+		{"/build", "src/build", true, false},
+		{"build/", "build", false, false},
+		{"build/", "src/build", true, true},
+		{"docs/*.md", "docs/a.md", false, true},
+		{"docs/*.md", "docs/sub/a.md", false, false},
+		{"**/vendor", "a/b/vendor", true, true},
+		{"vendor/**", "vendor/lib/a.go", false, true},
+		{"a/**/b", "a/b", true, true},
+		{"a/**/b", "a/x/y/b", true, true},
+		{"file?.txt", "file1.txt", false, true},
+		{"file[0-9].txt", "fileA.txt", false, false},
+	}
+
+	for _, tt := range tests {
+		p, ok := ParsePattern(tt.pattern)
+		if !ok {
+			t.Fatalf("Failed to parse pattern %q", tt.pattern)
+		}
+		if got := p.Match(tt.path, tt.isDir); got != tt.match {
+			t.Errorf("Pattern %q on %q (dir=%v): expected %v, got %v", tt.pattern, tt.path, tt.isDir, tt.match, got)
+		}
+	}
+}
+
+func TestParsePatternSkipsCommentsAndBlanks(t *testing.T) {
+	for _, line := range []string{"", "   ", "# comment"} {
+		if _, ok := ParsePattern(line); ok {
+			t.Errorf("Expected %q to be skipped", line)
+		}
+	}
+	if p, ok := ParsePattern("!keep.log"); !ok || !p.Negated() {
+		t.Error("Expected negated pattern")
+	}
+}
+
+func TestWalk(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".gitignore":        "*.log\nbuild/\n!important.log\n",
+		"main.go":           "",
+		"debug.log":         "",
+		"important.log":     "",
+		"build/out.bin":     "",
+		"sub/.gitignore":    "*.tmp\n",
+		"sub/a.go":          "",
+		"sub/scratch.tmp":   "",
+		"other/scratch.tmp": "",
+		".git/HEAD":         "",
+	}
+	writeTree(t, root, files)
+
+	expected := []string{".gitignore", "important.log", "main.go", "other/scratch.tmp", "sub/.gitignore", "sub/a.go"}
+	assertWalk(t, root, expected)
+}
+
+func TestWalkTraeIgnore(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".gitignore":       "*.log\n",
+		".traeignore":      "secrets/\n*.csv\n",
+		"main.go":          "",
+		"debug.log":        "",
+		"data.csv":         "",
+		"secrets/key.pem":  "",
+		"sub/.traeignore":  "fixtures/\n",
+		"sub/a.go":         "",
+		"sub/fixtures/big": "",
+	})
+
+	expected := []string{".gitignore", ".traeignore", "main.go", "sub/.traeignore", "sub/a.go"}
+	assertWalk(t, root, expected)
+}
+
+func TestWalkProjectPatterns(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"main.go":         "",
+		"data.csv":        "",
+		"secrets/key.pem": "",
+	})
+	SetProjectPatterns(root, []string{"secrets/", "*.csv", "!keep.csv"})
+	defer SetProjectPatterns("", nil)
+
+	assertWalk(t, root, []string{"main.go"})
+	if got := ProjectPatterns(); len(got) != 2 || got[0] != "secrets/" || got[1] != "*.csv" {
+		t.Errorf("Unexpected project patterns %q", got)
+	}
+}
+
+func TestIgnoredPath(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".git/HEAD":         "",
+		".gitignore":        "*.log\n",
+		".traeignore":       "secrets/\n",
+		"sub/.traeignore":   "*.bin\n",
+		"secrets/deep/key":  "",
+		"sub/data.bin":      "",
+		"sub/main.go":       "",
+		"sub/debug.log":     "",
+		"other/secrets.txt": "",
+	})
+
+	m := NewTraeIgnoreMatcher(root)
+	tests := map[string]bool{
+		"secrets/deep/key":  true,
+		"sub/data.bin":      true,
+		"sub/main.go":       false,
+		"sub/debug.log":     false, // only .traeignore rules apply
+		"other/secrets.txt": false,
+	}
+	for path, want := range tests {
+		if got := m.IgnoredPath(filepath.Join(root, path), false); got != want {
+			t.Errorf("IgnoredPath(%q) = %v, want %v", path, got, want)
+		}
+	}
+	if !NewMatcher(root).IgnoredPath(filepath.Join(root, "sub/debug.log"), false) {
+		t.Errorf("Expected NewMatcher to apply .gitignore rules")
+	}
+}
+
+// writeTree creates the given files, relative to root.
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+}
+
+// assertWalk checks that Walk visits exactly the expected files under root.
+func assertWalk(t *testing.T, root string, expected []string) {
+	t.Helper()
+	var got []string
+	err := Walk(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			rel, _ := filepath.Rel(root, path)
+			got = append(got, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	sort.Strings(got)
+
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, got)
+			break
+		}
+	}
+}
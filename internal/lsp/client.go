@@ -0,0 +1,601 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Position is a zero-based line and UTF-16 character offset, as in the LSP spec.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a span between two positions.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range inside a document.
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+// Path returns the local file path of the location's URI.
+func (l Location) Path() string {
+	return URIToPath(l.URI)
+}
+
+// SymbolKind is the kind of a symbol, such as a function or struct.
+type SymbolKind int
+
+// symbolKindNames holds the names of the SymbolKind values defined by LSP.
+var symbolKindNames = []string{
+	"", "file", "module", "namespace", "package", "class", "method", "property",
+	"field", "constructor", "enum", "interface", "function", "variable",
+	"constant", "string", "number", "boolean", "array", "object", "key", "null",
+	"enum member", "struct", "event", "operator", "type parameter",
+}
+
+// String returns the lowercase name of the kind.
+func (k SymbolKind) String() string {
+	if k > 0 && int(k) < len(symbolKindNames) {
+		return symbolKindNames[k]
+	}
+	return "symbol"
+}
+
+// symbolKindValues lists every SymbolKind the client understands.
+func symbolKindValues() []int {
+	values := make([]int, 0, len(symbolKindNames)-1)
+	for k := 1; k < len(symbolKindNames); k++ {
+		values = append(values, k)
+	}
+	return values
+}
+
+// SymbolInformation describes a symbol found in the workspace.
+type SymbolInformation struct {
+	Name          string     `json:"name"`
+	Kind          SymbolKind `json:"kind"`
+	ContainerName string     `json:"containerName,omitempty"`
+	Location      Location   `json:"location"`
+}
+
+// rpcMessage is a JSON-RPC 2.0 request, response, or notification.
+type rpcMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+// rpcError is a JSON-RPC error object.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("language server error %d: %s", e.Code, e.Message)
+}
+
+// Client is a minimal LSP client speaking JSON-RPC over a stream.
+type Client struct {
+	writer io.WriteCloser
+	reader *bufio.Reader
+	cmd    *exec.Cmd
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	nextID  int
+	pending map[int]chan rpcMessage
+	closed  chan struct{}
+	// openMu serializes OpenFile, so that a file's notifications are sent
+	// in version order; opened holds the files the server was told about.
+	openMu  sync.Mutex
+	opened  map[string]*openFile
+	readErr error
+}
+
+// NewClient returns a client that reads server messages from r and writes
+// client messages to w. It starts a goroutine that dispatches responses.
+func NewClient(r io.Reader, w io.WriteCloser) *Client {
+	c := &Client{
+		writer:  w,
+		reader:  bufio.NewReader(r),
+		pending: make(map[int]chan rpcMessage),
+		opened:  make(map[string]*openFile),
+		closed:  make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+// Start launches a language server process and initializes it for the
+// workspace rooted at root.
+func Start(ctx context.Context, command []string, root string) (*Client, error) {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = root
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", command[0], err)
+	}
+
+	c := NewClient(stdout, stdin)
+	c.cmd = cmd
+	if err := c.Initialize(ctx, root); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Initialize performs the initialize handshake.
+func (c *Client) Initialize(ctx context.Context, root string) error {
+	params := map[string]any{
+		"processId": os.Getpid(),
+		"rootUri":   PathToURI(root),
+		"workspaceFolders": []map[string]string{
+			{"uri": PathToURI(root), "name": filepath.Base(root)},
+		},
+		"capabilities": map[string]any{
+			"textDocument": map[string]any{
+				"hover": map[string]any{
+					"contentFormat": []string{"markdown", "plaintext"},
+				},
+				"definition": map[string]any{"linkSupport": true},
+			},
+			"workspace": map[string]any{
+				"symbol": map[string]any{
+					"symbolKind": map[string]any{"valueSet": symbolKindValues()},
+				},
+				"workspaceFolders": true,
+				"configuration":    true,
+			},
+		},
+	}
+	if err := c.Call(ctx, "initialize", params, nil); err != nil {
+		return fmt.Errorf("initialize failed: %w", err)
+	}
+	return c.Notify("initialized", map[string]any{})
+}
+
+// Call sends a request and decodes its result into result, which may be nil.
+func (c *Client) Call(ctx context.Context, method string, params any, result any) error {
+	c.mu.Lock()
+	if c.readErr != nil {
+		c.mu.Unlock()
+		return c.readErr
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan rpcMessage, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	rawID := json.RawMessage(strconv.Itoa(id))
+	if err := c.send(rpcMessage{ID: &rawID, Method: method}, params); err != nil {
+		c.forget(id)
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		c.forget(id)
+		return ctx.Err()
+	case <-c.closed:
+		return c.readErr
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, result)
+	}
+}
+
+// Notify sends a notification, which has no response.
+func (c *Client) Notify(method string, params any) error {
+	return c.send(rpcMessage{Method: method}, params)
+}
+
+// openFile is the version and text of a file as last sent to the server.
+type openFile struct {
+	version int
+	text    string
+}
+
+// OpenFile tells the server about a file so it can answer requests for it.
+// The first call opens the file; later ones send its full text again if it
+// changed on disk since, so that answers match what is on disk.
+func (c *Client) OpenFile(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	c.openMu.Lock()
+	defer c.openMu.Unlock()
+	if err := c.sync(abs); err != nil {
+		return err
+	}
+	if c.opened[abs] == nil {
+		return fmt.Errorf("%s no longer exists", path)
+	}
+	return nil
+}
+
+// syncOpenFiles sends the changes to every open file, and closes those
+// that were deleted, so that requests about one file see edits to others.
+func (c *Client) syncOpenFiles() error {
+	c.openMu.Lock()
+	defer c.openMu.Unlock()
+	for abs := range c.opened {
+		if err := c.sync(abs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sync brings the server's copy of the file at abs up to date with the
+// disk. Callers hold openMu.
+func (c *Client) sync(abs string) error {
+	file := c.opened[abs]
+	content, err := os.ReadFile(abs)
+	if err != nil {
+		if file == nil || !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		delete(c.opened, abs)
+		return c.Notify("textDocument/didClose", map[string]any{
+			"textDocument": map[string]any{"uri": PathToURI(abs)},
+		})
+	}
+	text := string(content)
+
+	if file == nil {
+		err := c.Notify("textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{
+				"uri":        PathToURI(abs),
+				"languageId": LanguageID(abs),
+				"version":    1,
+				"text":       text,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		c.opened[abs] = &openFile{version: 1, text: text}
+		return nil
+	}
+	if file.text == text {
+		return nil
+	}
+	err = c.Notify("textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": PathToURI(abs), "version": file.version + 1},
+		"contentChanges": []map[string]any{{"text": text}},
+	})
+	if err != nil {
+		return err
+	}
+	file.version++
+	file.text = text
+	return nil
+}
+
+// Definition returns the locations where the symbol at pos is defined.
+func (c *Client) Definition(ctx context.Context, path string, pos Position) ([]Location, error) {
+	var raw json.RawMessage
+	if err := c.positionRequest(ctx, "textDocument/definition", path, pos, nil, &raw); err != nil {
+		return nil, err
+	}
+	return parseLocations(raw)
+}
+
+// References returns the locations that refer to the symbol at pos,
+// including its declaration.
+func (c *Client) References(ctx context.Context, path string, pos Position) ([]Location, error) {
+	var raw json.RawMessage
+	extra := map[string]any{"context": map[string]bool{"includeDeclaration": true}}
+	if err := c.positionRequest(ctx, "textDocument/references", path, pos, extra, &raw); err != nil {
+		return nil, err
+	}
+	return parseLocations(raw)
+}
+
+// Hover returns the hover text (usually the signature and documentation)
+// for the symbol at pos.
+func (c *Client) Hover(ctx context.Context, path string, pos Position) (string, error) {
+	var result struct {
+		Contents json.RawMessage `json:"contents"`
+	}
+	if err := c.positionRequest(ctx, "textDocument/hover", path, pos, nil, &result); err != nil {
+		return "", err
+	}
+	return parseHoverContents(result.Contents), nil
+}
+
+// WorkspaceSymbols returns the symbols in the workspace that match query.
+// Servers decide how to match; gopls uses fuzzy matching by default.
+func (c *Client) WorkspaceSymbols(ctx context.Context, query string) ([]SymbolInformation, error) {
+	if err := c.syncOpenFiles(); err != nil {
+		return nil, err
+	}
+	var symbols []SymbolInformation
+	if err := c.Call(ctx, "workspace/symbol", map[string]string{"query": query}, &symbols); err != nil {
+		return nil, err
+	}
+	return symbols, nil
+}
+
+// Close shuts the server down and releases the connection.
+func (c *Client) Close() error {
+	if c.cmd != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		c.Call(ctx, "shutdown", nil, nil)
+		c.Notify("exit", nil)
+	}
+	err := c.writer.Close()
+	if c.cmd != nil {
+		c.cmd.Wait()
+	}
+	return err
+}
+
+// positionRequest opens the file, brings the other open files up to date,
+// and sends a request with a text document position, plus any extra
+// parameters.
+func (c *Client) positionRequest(ctx context.Context, method, path string, pos Position, extra map[string]any, result any) error {
+	if err := c.OpenFile(path); err != nil {
+		return err
+	}
+	if err := c.syncOpenFiles(); err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	params := map[string]any{
+		"textDocument": map[string]string{"uri": PathToURI(abs)},
+		"position":     pos,
+	}
+	for k, v := range extra {
+		params[k] = v
+	}
+	return c.Call(ctx, method, params, result)
+}
+
+// send writes a message with the Content-Length framing used by LSP.
+func (c *Client) send(msg rpcMessage, params any) error {
+	msg.JSONRPC = "2.0"
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = data
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.writer.Write(body)
+	return err
+}
+
+// forget drops a pending request whose caller stopped waiting.
+func (c *Client) forget(id int) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+// readLoop reads messages from the server until the stream ends, routing
+// responses to their callers and answering server-initiated requests.
+func (c *Client) readLoop() {
+	for {
+		msg, err := readMessage(c.reader)
+		if err != nil {
+			c.mu.Lock()
+			c.readErr = fmt.Errorf("language server connection closed: %w", err)
+			c.mu.Unlock()
+			close(c.closed)
+			return
+		}
+
+		switch {
+		case msg.ID != nil && msg.Method != "":
+			c.replyToServer(msg)
+		case msg.ID != nil:
+			id, err := strconv.Atoi(string(*msg.ID))
+			if err != nil {
+				continue
+			}
+			c.mu.Lock()
+			ch, ok := c.pending[id]
+			delete(c.pending, id)
+			c.mu.Unlock()
+			if ok {
+				ch <- msg
+			}
+		}
+		// Notifications such as diagnostics and progress are ignored
+	}
+}
+
+// replyToServer answers requests the server sends to the client. Servers
+// block on some of these (e.g. workspace/configuration), so each gets an
+// empty but valid response.
+func (c *Client) replyToServer(req rpcMessage) {
+	var result any
+	if req.Method == "workspace/configuration" {
+		var params struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result = make([]any, len(params.Items))
+	}
+	data, _ := json.Marshal(result)
+
+	body, err := json.Marshal(rpcMessage{JSONRPC: "2.0", ID: req.ID, Result: data})
+	if err != nil {
+		return
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n", len(body))
+	c.writer.Write(body)
+}
+
+// readMessage reads one Content-Length framed message.
+func readMessage(r *bufio.Reader) (rpcMessage, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return rpcMessage{}, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return rpcMessage{}, fmt.Errorf("invalid Content-Length: %w", err)
+			}
+		}
+	}
+	if length < 0 {
+		return rpcMessage{}, errors.New("missing Content-Length header")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return rpcMessage{}, err
+	}
+	var msg rpcMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return rpcMessage{}, err
+	}
+	return msg, nil
+}
+
+// parseLocations decodes a definition or references result, which may be
+// null, a Location, a list of Locations, or a list of LocationLinks.
+func parseLocations(raw json.RawMessage) ([]Location, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var single Location
+	if err := json.Unmarshal(raw, &single); err == nil && single.URI != "" {
+		return []Location{single}, nil
+	}
+
+	var items []struct {
+		Location
+		TargetURI            string `json:"targetUri"`
+		TargetSelectionRange Range  `json:"targetSelectionRange"`
+	}
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("unexpected location result: %w", err)
+	}
+	locations := make([]Location, 0, len(items))
+	for _, item := range items {
+		if item.TargetURI != "" {
+			locations = append(locations, Location{URI: item.TargetURI, Range: item.TargetSelectionRange})
+		} else {
+			locations = append(locations, item.Location)
+		}
+	}
+	return locations, nil
+}
+
+// parseHoverContents flattens the several shapes hover contents can take
+// (MarkupContent, MarkedString, or a list of MarkedStrings) into text.
+func parseHoverContents(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+
+	var markup struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &markup); err == nil && markup.Value != "" {
+		return markup.Value
+	}
+
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err == nil {
+		var parts []string
+		for _, item := range list {
+			if part := parseHoverContents(item); part != "" {
+				parts = append(parts, part)
+			}
+		}
+		return strings.Join(parts, "\n\n")
+	}
+	return ""
+}
+
+// PathToURI converts an absolute file path into a file:// URI.
+func PathToURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		// Windows paths such as C:/src need a leading slash in URIs
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// URIToPath converts a file:// URI into a local file path.
+func URIToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	path := u.Path
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path)
+}
@@ -0,0 +1,375 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServer answers LSP requests from a client with canned results.
+type fakeServer struct {
+	reader  *bufio.Reader
+	writer  io.WriteCloser
+	results map[string]string
+	methods chan string
+	// notifications receives the notifications the client sends.
+	notifications chan rpcMessage
+}
+
+// newFakeServer connects a client to a fake server over in-memory pipes.
+func newFakeServer(t *testing.T, results map[string]string) (*Client, *fakeServer) {
+	t.Helper()
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	server := &fakeServer{
+		reader:  bufio.NewReader(serverReader),
+		writer:  serverWriter,
+		results: results,
+		methods: make(chan string, 100),
+
+		notifications: make(chan rpcMessage, 100),
+	}
+	go server.serve()
+
+	client := NewClient(clientReader, clientWriter)
+	t.Cleanup(func() {
+		client.Close()
+		serverWriter.Close()
+	})
+	return client, server
+}
+
+func (s *fakeServer) serve() {
+	for {
+		msg, err := readMessage(s.reader)
+		if err != nil {
+			return
+		}
+		s.methods <- msg.Method
+		if msg.ID == nil {
+			s.notifications <- msg
+			continue
+		}
+		result, ok := s.results[msg.Method]
+		if !ok {
+			result = "null"
+		}
+		body := fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":%s}`, *msg.ID, result)
+		fmt.Fprintf(s.writer, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+}
+
+func TestClientDefinition(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	target := PathToURI(filepath.Join(dir, "other.go"))
+
+	client, server := newFakeServer(t, map[string]string{
+		"textDocument/definition": fmt.Sprintf(`[{"uri":%q,"range":{"start":{"line":4,"character":5},"end":{"line":4,"character":9}}}]`, target),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	locations, err := client.Definition(ctx, path, Position{Line: 0, Character: 8})
+	if err != nil {
+		t.Fatalf("Definition() error = %v", err)
+	}
+	if len(locations) != 1 {
+		t.Fatalf("Definition() returned %d locations, want 1", len(locations))
+	}
+	if got, want := locations[0].Path(), filepath.Join(dir, "other.go"); got != want {
+		t.Errorf("Path() = %q, want %q", got, want)
+	}
+	if locations[0].Range.Start.Line != 4 || locations[0].Range.Start.Character != 5 {
+		t.Errorf("unexpected range start %+v", locations[0].Range.Start)
+	}
+
+	// The file is opened before the first request on it
+	if method := <-server.methods; method != "textDocument/didOpen" {
+		t.Errorf("first method = %q, want textDocument/didOpen", method)
+	}
+}
+
+func TestClientHover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(path, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client, _ := newFakeServer(t, map[string]string{
+		"textDocument/hover": `{"contents":{"kind":"markdown","value":"func main()"}}`,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	text, err := client.Hover(ctx, path, Position{})
+	if err != nil {
+		t.Fatalf("Hover() error = %v", err)
+	}
+	if text != "func main()" {
+		t.Errorf("Hover() = %q, want %q", text, "func main()")
+	}
+}
+
+func TestClientCallError(t *testing.T) {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+	go func() {
+		reader := bufio.NewReader(serverReader)
+		msg, err := readMessage(reader)
+		if err != nil {
+			return
+		}
+		body := fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"error":{"code":-32601,"message":"method not found"}}`, *msg.ID)
+		fmt.Fprintf(serverWriter, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}()
+
+	client := NewClient(clientReader, clientWriter)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := client.Call(ctx, "unknown/method", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "method not found") {
+		t.Errorf("Call() error = %v, want method not found", err)
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	body := `{"jsonrpc":"2.0","id":1,"result":null}`
+	input := fmt.Sprintf("Content-Length: %d\r\nContent-Type: application/vscode-jsonrpc\r\n\r\n%s", len(body), body)
+
+	msg, err := readMessage(bufio.NewReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatalf("readMessage() error = %v", err)
+	}
+	if msg.ID == nil || string(*msg.ID) != "1" {
+		t.Errorf("unexpected id %v", msg.ID)
+	}
+
+	_, err = readMessage(bufio.NewReader(strings.NewReader("\r\n{}")))
+	if err == nil {
+		t.Error("expected error for missing Content-Length")
+	}
+}
+
+func TestParseLocations(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want int
+	}{
+		{"null", `null`, 0},
+		{"single", `{"uri":"file:///a.go","range":{"start":{"line":1,"character":0},"end":{"line":1,"character":1}}}`, 1},
+		{"list", `[{"uri":"file:///a.go","range":{}},{"uri":"file:///b.go","range":{}}]`, 2},
+		{"links", `[{"targetUri":"file:///a.go","targetRange":{},"targetSelectionRange":{"start":{"line":3,"character":2},"end":{"line":3,"character":4}}}]`, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locations, err := parseLocations(json.RawMessage(tt.raw))
+			if err != nil {
+				t.Fatalf("parseLocations() error = %v", err)
+			}
+			if len(locations) != tt.want {
+				t.Errorf("parseLocations() returned %d locations, want %d", len(locations), tt.want)
+			}
+			for _, loc := range locations {
+				if loc.URI == "" {
+					t.Error("location has empty URI")
+				}
+			}
+		})
+	}
+}
+
+func TestParseHoverContents(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"null", `null`, ""},
+		{"string", `"plain text"`, "plain text"},
+		{"markup", `{"kind":"markdown","value":"**bold**"}`, "**bold**"},
+		{"marked string", `{"language":"go","value":"func f()"}`, "func f()"},
+		{"list", `["first", {"language":"go","value":"second"}]`, "first\n\nsecond"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseHoverContents(json.RawMessage(tt.raw)); got != tt.want {
+				t.Errorf("parseHoverContents() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestURIRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dir with space", "file.go")
+	uri := PathToURI(path)
+	if !strings.HasPrefix(uri, "file://") {
+		t.Errorf("PathToURI() = %q, want file:// prefix", uri)
+	}
+	if got := URIToPath(uri); got != path {
+		t.Errorf("URIToPath(PathToURI(%q)) = %q", path, got)
+	}
+}
+
+func TestServerFor(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{"main.go", "gopls", true},
+		{"app.py", "pyright", true},
+		{"index.tsx", "typescript-language-server", true},
+		{"lib.rs", "rust-analyzer", true},
+		{"main.cpp", "clangd", true},
+		{"README.md", "", false},
+	}
+
+	for _, tt := range tests {
+		server, ok := ServerFor(tt.path)
+		if ok != tt.ok || server.Name != tt.want {
+			t.Errorf("ServerFor(%q) = %q, %v; want %q, %v", tt.path, server.Name, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFindRoot(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	nested := filepath.Join(root, "pkg", "sub")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := FindRoot(filepath.Join(nested, "a.go"), []string{"go.mod"}); got != root {
+		t.Errorf("FindRoot() = %q, want %q", got, root)
+	}
+}
+
+func TestClientWorkspaceSymbols(t *testing.T) {
+	client, _ := newFakeServer(t, map[string]string{
+		"workspace/symbol": `[{"name":"Agent","kind":23,"containerName":"agent","location":{"uri":"file:///src/agent.go","range":{"start":{"line":10,"character":5},"end":{"line":10,"character":10}}}}]`,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	symbols, err := client.WorkspaceSymbols(ctx, "Agent")
+	if err != nil {
+		t.Fatalf("WorkspaceSymbols() error = %v", err)
+	}
+	if len(symbols) != 1 {
+		t.Fatalf("WorkspaceSymbols() returned %d symbols, want 1", len(symbols))
+	}
+	if symbols[0].Name != "Agent" || symbols[0].Kind.String() != "struct" || symbols[0].ContainerName != "agent" {
+		t.Errorf("unexpected symbol %+v", symbols[0])
+	}
+}
+
+func TestSymbolKindString(t *testing.T) {
+	tests := map[SymbolKind]string{12: "function", 6: "method", 23: "struct", 11: "interface", 0: "symbol", 99: "symbol"}
+	for kind, want := range tests {
+		if got := kind.String(); got != want {
+			t.Errorf("SymbolKind(%d).String() = %q, want %q", int(kind), got, want)
+		}
+	}
+}
+
+func TestClientOpenFileSendsChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	client, server := newFakeServer(t, nil)
+
+	// A file that cannot be read is not marked open
+	if err := client.OpenFile(path); err == nil {
+		t.Fatal("Expected an error for a missing file")
+	}
+	if err := os.WriteFile(path, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	next := func() (string, map[string]any) {
+		t.Helper()
+		select {
+		case msg := <-server.notifications:
+			var params map[string]any
+			json.Unmarshal(msg.Params, &params)
+			return msg.Method, params
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected a notification")
+			return "", nil
+		}
+	}
+
+	if err := client.OpenFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if method, _ := next(); method != "textDocument/didOpen" {
+		t.Errorf("method = %q, want textDocument/didOpen", method)
+	}
+
+	// Unchanged files are not sent again; changed ones are, whole
+	if err := client.OpenFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.OpenFile(path); err != nil {
+		t.Fatal(err)
+	}
+	method, params := next()
+	if method != "textDocument/didChange" {
+		t.Fatalf("method = %q, want textDocument/didChange", method)
+	}
+	document := params["textDocument"].(map[string]any)
+	changes := params["contentChanges"].([]any)
+	if document["version"] != 2.0 || changes[0].(map[string]any)["text"] != "package main\n\nfunc main() {}\n" {
+		t.Errorf("unexpected didChange params %v", params)
+	}
+	select {
+	case msg := <-server.notifications:
+		t.Errorf("unexpected notification %s", msg.Method)
+	default:
+	}
+
+	// Requests about other files bring it up to date, or close it once
+	// deleted
+	other := filepath.Join(filepath.Dir(path), "other.go")
+	if err := os.WriteFile(other, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.Hover(ctx, other, Position{}); err != nil {
+		t.Fatal(err)
+	}
+	if method, _ := next(); method != "textDocument/didOpen" {
+		t.Errorf("method = %q, want textDocument/didOpen", method)
+	}
+	method, params = next()
+	if method != "textDocument/didClose" || params["textDocument"].(map[string]any)["uri"] != PathToURI(path) {
+		t.Errorf("Expected the deleted file to be closed, got %s %v", method, params)
+	}
+	if err := client.OpenFile(path); err == nil {
+		t.Error("Expected an error opening the deleted file")
+	}
+}
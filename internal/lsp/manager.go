@@ -0,0 +1,191 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// shutdownTimeout bounds how long Close waits for a server to shut down.
+const shutdownTimeout = 2 * time.Second
+
+// ServerConfig describes how to launch a language server.
+type ServerConfig struct {
+	Name        string
+	Command     []string
+	Extensions  []string
+	RootMarkers []string
+}
+
+// Servers lists the language servers tiny-trae knows how to launch.
+var Servers = []ServerConfig{
+	{
+		Name:        "gopls",
+		Command:     []string{"gopls"},
+		Extensions:  []string{".go"},
+		RootMarkers: []string{"go.work", "go.mod"},
+	},
+	{
+		Name:        "pyright",
+		Command:     []string{"pyright-langserver", "--stdio"},
+		Extensions:  []string{".py", ".pyi"},
+		RootMarkers: []string{"pyproject.toml", "setup.py", "setup.cfg", "requirements.txt"},
+	},
+	{
+		Name:        "typescript-language-server",
+		Command:     []string{"typescript-language-server", "--stdio"},
+		Extensions:  []string{".ts", ".tsx", ".js", ".jsx", ".mjs"},
+		RootMarkers: []string{"tsconfig.json", "jsconfig.json", "package.json"},
+	},
+	{
+		Name:        "rust-analyzer",
+		Command:     []string{"rust-analyzer"},
+		Extensions:  []string{".rs"},
+		RootMarkers: []string{"Cargo.toml"},
+	},
+	{
+		Name:        "clangd",
+		Command:     []string{"clangd"},
+		Extensions:  []string{".c", ".h", ".cc", ".cpp", ".hpp"},
+		RootMarkers: []string{"compile_commands.json", "CMakeLists.txt"},
+	},
+}
+
+// languageIDs maps file extensions to LSP language identifiers.
+var languageIDs = map[string]string{
+	".go":  "go",
+	".py":  "python",
+	".pyi": "python",
+	".ts":  "typescript",
+	".tsx": "typescriptreact",
+	".js":  "javascript",
+	".jsx": "javascriptreact",
+	".mjs": "javascript",
+	".rs":  "rust",
+	".c":   "c",
+	".h":   "c",
+	".cc":  "cpp",
+	".cpp": "cpp",
+	".hpp": "cpp",
+}
+
+// LanguageID returns the LSP language identifier for a file.
+func LanguageID(path string) string {
+	if id, ok := languageIDs[strings.ToLower(filepath.Ext(path))]; ok {
+		return id
+	}
+	return "plaintext"
+}
+
+// ServerFor returns the server configuration that handles a file.
+func ServerFor(path string) (ServerConfig, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, server := range Servers {
+		for _, e := range server.Extensions {
+			if e == ext {
+				return server, true
+			}
+		}
+	}
+	return ServerConfig{}, false
+}
+
+// ServerNamed returns the server configuration with the given name.
+func ServerNamed(name string) (ServerConfig, bool) {
+	for _, server := range Servers {
+		if server.Name == name {
+			return server, true
+		}
+	}
+	return ServerConfig{}, false
+}
+
+// FindRoot returns the workspace root for a file or directory: the nearest
+// ancestor that contains one of the server's root markers, then the nearest
+// git repository, and finally the file's own directory.
+func FindRoot(path string, markers []string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Dir(path)
+	}
+	start := abs
+	if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+		start = filepath.Dir(abs)
+	}
+
+	for _, candidates := range [][]string{markers, {".git"}} {
+		for dir := start; ; dir = filepath.Dir(dir) {
+			for _, marker := range candidates {
+				if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+					return dir
+				}
+			}
+			if filepath.Dir(dir) == dir {
+				break
+			}
+		}
+	}
+	return start
+}
+
+// Manager keeps one running language server per server and workspace root.
+type Manager struct {
+	mu      sync.Mutex
+	clients map[string]*Client
+}
+
+// NewManager returns an empty manager.
+func NewManager() *Manager {
+	return &Manager{clients: make(map[string]*Client)}
+}
+
+// DefaultManager is the manager used by the LSP tools.
+var DefaultManager = NewManager()
+
+// ClientFor returns a client for the server that handles path, starting the
+// server for the file's workspace if it is not already running.
+func (m *Manager) ClientFor(ctx context.Context, path string) (*Client, error) {
+	server, ok := ServerFor(path)
+	if !ok {
+		return nil, fmt.Errorf("no language server configured for %s files", filepath.Ext(path))
+	}
+	return m.ClientForServer(ctx, server, FindRoot(path, server.RootMarkers))
+}
+
+// ClientForServer returns a client for server in the workspace rooted at
+// root, starting the server if it is not already running.
+func (m *Manager) ClientForServer(ctx context.Context, server ServerConfig, root string) (*Client, error) {
+	if _, err := exec.LookPath(server.Command[0]); err != nil {
+		return nil, fmt.Errorf("language server %s is not installed (looked for %q in PATH)", server.Name, server.Command[0])
+	}
+
+	key := server.Name + "\x00" + root
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if client, ok := m.clients[key]; ok {
+		return client, nil
+	}
+
+	client, err := Start(ctx, server.Command, root)
+	if err != nil {
+		return nil, err
+	}
+	m.clients[key] = client
+	return client, nil
+}
+
+// Close shuts down every running server.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, client := range m.clients {
+		client.Close()
+		delete(m.clients, key)
+	}
+}
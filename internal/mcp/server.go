@@ -0,0 +1,313 @@
+// Package mcp serves the agent's tools over the Model Context Protocol, so
+// editors and other agents can use them.
+//
+// The server speaks JSON-RPC 2.0 with one message per line on stdin and
+// stdout (the MCP stdio transport) and supports the initialize, ping,
+// tools/list, and tools/call methods. Tool calls go through the agent, so the
+// permission policy, approval, and audit log apply as in a chat session.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/audit"
+	"tiny-trae/internal/permission"
+	"tiny-trae/internal/version"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// ProtocolVersion is the newest MCP version the server implements.
+const ProtocolVersion = "2025-06-18"
+
+// supportedVersions are the MCP versions the server accepts from clients.
+var supportedVersions = map[string]bool{
+	"2024-11-05": true,
+	"2025-03-26": true,
+	"2025-06-18": true,
+}
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Server exposes a profile's tools over MCP.
+type Server struct {
+	// Profile supplies the tools and their defaults.
+	Profile *agent.Profile
+	// Policy is consulted before every tool call; may be nil.
+	Policy *permission.Policy
+	// AuditLog records every tool call; may be nil.
+	AuditLog *audit.Log
+	// AutoApprove runs tools that need approval without asking. Otherwise
+	// they are denied unless a permission rule allows them, since there is
+	// nobody to ask.
+	AutoApprove bool
+	// Log receives diagnostics. Defaults to os.Stderr.
+	Log io.Writer
+}
+
+// rpcMessage is a JSON-RPC 2.0 request, response, or notification.
+type rpcMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  any              `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+// rpcError is a JSON-RPC error object.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// incoming is a line read from the client.
+type incoming struct {
+	msg rpcMessage
+	err error
+}
+
+// tool is a tool in a tools/list response.
+type tool struct {
+	Name        string                         `json:"name"`
+	Description string                         `json:"description"`
+	InputSchema anthropic.ToolInputSchemaParam `json:"inputSchema"`
+}
+
+// content is an item of a tools/call result.
+type content struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// Serve reads requests from in and writes responses to out until in is
+// closed or ctx is cancelled. Requests are handled one at a time; a
+// notifications/cancelled for the running tools/call interrupts the tool.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	log := s.Log
+	if log == nil {
+		log = os.Stderr
+	}
+	front := &frontend{log: log, autoApprove: s.AutoApprove, interrupts: make(chan struct{}, 1)}
+	a := agent.NewAgent(anthropic.Client{}, s.Profile, front)
+	a.SetPermissionPolicy(s.Policy)
+	a.SetAuditLog(s.AuditLog)
+
+	// Read in the background so cancellations arrive while a tool runs
+	messages := make(chan incoming)
+	readErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64<<10), 64<<20)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if len(line) == 0 {
+				continue
+			}
+			var msg rpcMessage
+			err := json.Unmarshal(line, &msg)
+			if err == nil && msg.Method == "notifications/cancelled" {
+				front.cancel(msg.Params)
+				continue
+			}
+			select {
+			case messages <- incoming{msg: msg, err: err}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	encoder := json.NewEncoder(out)
+	for {
+		var next incoming
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			return err
+		case next = <-messages:
+		}
+
+		msg := next.msg
+		if next.err != nil {
+			// The line could not be parsed, so there is no ID to answer
+			null := json.RawMessage("null")
+			if err := encoder.Encode(rpcMessage{JSONRPC: "2.0", ID: &null, Error: &rpcError{Code: codeParseError, Message: next.err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if msg.ID == nil || msg.Method == "" && (msg.Result != nil || msg.Error != nil) {
+			// Notifications such as notifications/initialized need no answer,
+			// and the server sends no requests whose responses it would wait for
+			continue
+		}
+
+		result, rpcErr := s.handle(ctx, a, front, msg)
+		response := rpcMessage{JSONRPC: "2.0", ID: msg.ID, Result: result, Error: rpcErr}
+		if rpcErr != nil {
+			response.Result = nil
+		}
+		if err := encoder.Encode(response); err != nil {
+			return err
+		}
+	}
+}
+
+// handle answers one request.
+func (s *Server) handle(ctx context.Context, a *agent.Agent, front *frontend, msg rpcMessage) (any, *rpcError) {
+	switch msg.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		protocolVersion := ProtocolVersion
+		if supportedVersions[params.ProtocolVersion] {
+			protocolVersion = params.ProtocolVersion
+		}
+		return map[string]any{
+			"protocolVersion": protocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{"listChanged": false}},
+			"serverInfo":      map[string]any{"name": "tiny-trae", "version": version.Get().Version},
+		}, nil
+
+	case "ping":
+		return map[string]any{}, nil
+
+	case "tools/list":
+		tools := make([]tool, 0, len(s.Profile.Tools))
+		for _, def := range s.Profile.Tools {
+			tools = append(tools, tool{Name: def.Name, Description: def.Description, InputSchema: def.InputSchema})
+		}
+		return map[string]any{"tools": tools}, nil
+
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		if !s.hasTool(params.Name) {
+			return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool %q", params.Name)}
+		}
+		if len(params.Arguments) == 0 {
+			params.Arguments = json.RawMessage("{}")
+		}
+
+		front.start(*msg.ID)
+		result := a.CallTool(ctx, "mcp-"+strings.Trim(string(*msg.ID), `"`), params.Name, params.Arguments)
+		front.finish()
+
+		items := []content{{Type: "text", Text: result.Text}}
+		for _, img := range result.Images {
+			items = append(items, content{Type: "image", Data: base64.StdEncoding.EncodeToString(img.Data), MimeType: img.MediaType})
+		}
+		return map[string]any{"content": items, "isError": result.IsError}, nil
+
+	case "":
+		return nil, &rpcError{Code: codeInvalidRequest, Message: "missing method"}
+	default:
+		return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", msg.Method)}
+	}
+}
+
+// hasTool reports whether the profile has a tool called name.
+func (s *Server) hasTool(name string) bool {
+	for _, def := range s.Profile.Tools {
+		if def.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// frontend is the agent frontend used while serving. There is no user to
+// talk to: messages go to the log and approval follows autoApprove.
+type frontend struct {
+	log         io.Writer
+	autoApprove bool
+	interrupts  chan struct{}
+
+	mu      sync.Mutex
+	running string
+}
+
+// start records the ID of the request whose tool is running.
+func (f *frontend) start(id json.RawMessage) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running = string(id)
+}
+
+// finish clears the running request.
+func (f *frontend) finish() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.running = ""
+}
+
+// cancel interrupts the running tool if the notifications/cancelled params
+// name its request.
+func (f *frontend) cancel(params json.RawMessage) {
+	var cancelled struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	if json.Unmarshal(params, &cancelled) != nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.running == "" || f.running != string(cancelled.RequestID) {
+		return
+	}
+	select {
+	case f.interrupts <- struct{}{}:
+	default:
+	}
+}
+
+func (f *frontend) SendMessage(msg agent.Message) {
+	switch msg.Type {
+	case agent.MessageTypeError:
+		fmt.Fprintf(f.log, "Error: %s\n", msg.Content)
+	case agent.MessageTypeSystemInfo:
+		fmt.Fprintln(f.log, msg.Content)
+	}
+}
+
+func (f *frontend) GetUserInput() (string, bool) { return "", false }
+
+func (f *frontend) RequestApproval(req agent.ApprovalRequest) agent.ApprovalDecision {
+	if f.autoApprove {
+		return agent.ApprovalApprove
+	}
+	fmt.Fprintf(f.log, "Denied %s: it needs approval; allow it in the permission policy or pass --auto-approve\n", req.ToolName)
+	return agent.ApprovalDeny
+}
+
+func (f *frontend) Interrupts() <-chan struct{}  { return f.interrupts }
+func (f *frontend) TurnCancels() <-chan struct{} { return nil }
+func (f *frontend) IsInteractive() bool          { return false }
+func (f *frontend) Close()                       {}
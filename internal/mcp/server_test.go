@@ -0,0 +1,164 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"tiny-trae/internal/agent"
+)
+
+// testProfile has an echo tool, a tool that needs approval, and a tool that
+// runs until it is cancelled.
+func testProfile() *agent.Profile {
+	return &agent.Profile{Tools: []agent.ToolDefinition{
+		{
+			Name:        "echo",
+			Description: "Echo the input.",
+			InputSchema: agent.GenerateSchema[struct {
+				Text string `json:"text"`
+			}](),
+			Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+				return string(input), nil
+			},
+		},
+		{
+			Name:             "delete_everything",
+			Description:      "Dangerous.",
+			RequiresApproval: true,
+			Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+				return "deleted", nil
+			},
+		},
+		{
+			Name:        "wait",
+			Description: "Wait until cancelled.",
+			Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+				<-ctx.Done()
+				return "", ctx.Err()
+			},
+		},
+	}}
+}
+
+// session runs a server on a pipe and returns functions to send a line and
+// read the next response.
+func session(t *testing.T, server *Server) (send func(string), receive func() map[string]any) {
+	t.Helper()
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		server.Serve(ctx, inReader, outWriter)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		inWriter.Close()
+		outReader.Close()
+		<-done
+	})
+
+	responses := bufio.NewScanner(outReader)
+	send = func(line string) {
+		t.Helper()
+		if _, err := io.WriteString(inWriter, line+"\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	receive = func() map[string]any {
+		t.Helper()
+		if !responses.Scan() {
+			t.Fatalf("No response: %v", responses.Err())
+		}
+		var response map[string]any
+		if err := json.Unmarshal(responses.Bytes(), &response); err != nil {
+			t.Fatalf("Invalid response %s: %v", responses.Bytes(), err)
+		}
+		return response
+	}
+	return send, receive
+}
+
+func TestServe(t *testing.T) {
+	send, receive := session(t, &Server{Profile: testProfile(), Log: io.Discard})
+
+	send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test"}}}`)
+	response := receive()
+	result := response["result"].(map[string]any)
+	if result["protocolVersion"] != "2025-03-26" || result["serverInfo"].(map[string]any)["name"] != "tiny-trae" {
+		t.Errorf("Unexpected initialize result %v", result)
+	}
+	send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+	send(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	tools := receive()["result"].(map[string]any)["tools"].([]any)
+	if len(tools) != 3 {
+		t.Fatalf("Expected 3 tools, got %v", tools)
+	}
+	echo := tools[0].(map[string]any)
+	if echo["name"] != "echo" || echo["inputSchema"].(map[string]any)["type"] != "object" {
+		t.Errorf("Unexpected tool %v", echo)
+	}
+
+	send(`{"jsonrpc":"2.0","id":"call-1","method":"tools/call","params":{"name":"echo","arguments":{"text":"hi"}}}`)
+	response = receive()
+	if response["id"] != "call-1" {
+		t.Errorf("Expected the request ID to be echoed, got %v", response["id"])
+	}
+	result = response["result"].(map[string]any)
+	text := result["content"].([]any)[0].(map[string]any)["text"]
+	if text != `{"text":"hi"}` || result["isError"] != false {
+		t.Errorf("Unexpected call result %v", result)
+	}
+
+	// Without an allow rule or --auto-approve, tools that need approval are denied
+	send(`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"delete_everything","arguments":{}}}`)
+	result = receive()["result"].(map[string]any)
+	if result["isError"] != true {
+		t.Errorf("Expected the call to be denied, got %v", result)
+	}
+
+	send(`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"missing"}}`)
+	if err := receive()["error"].(map[string]any); err["code"] != float64(codeInvalidParams) {
+		t.Errorf("Unexpected error %v", err)
+	}
+	send(`{"jsonrpc":"2.0","id":6,"method":"resources/list"}`)
+	if err := receive()["error"].(map[string]any); err["code"] != float64(codeMethodNotFound) {
+		t.Errorf("Unexpected error %v", err)
+	}
+	send(`{not json`)
+	if err := receive()["error"].(map[string]any); err["code"] != float64(codeParseError) {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestServeCancel(t *testing.T) {
+	send, receive := session(t, &Server{Profile: testProfile(), Log: io.Discard})
+
+	send(`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"wait"}}`)
+	// Give the tool time to start before cancelling it
+	time.Sleep(50 * time.Millisecond)
+	send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":7}}`)
+
+	result := receive()["result"].(map[string]any)
+	text := result["content"].([]any)[0].(map[string]any)["text"].(string)
+	if result["isError"] != true || !strings.Contains(text, "interrupted") {
+		t.Errorf("Expected the call to be interrupted, got %v", result)
+	}
+}
+
+func TestServeAutoApprove(t *testing.T) {
+	send, receive := session(t, &Server{Profile: testProfile(), AutoApprove: true, Log: io.Discard})
+
+	send(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"delete_everything","arguments":{}}}`)
+	result := receive()["result"].(map[string]any)
+	if result["isError"] != false {
+		t.Errorf("Expected the call to run, got %v", result)
+	}
+}
@@ -0,0 +1,205 @@
+// Package memory keeps facts about a project that should outlive a
+// session, such as build commands, architecture notes, and the user's
+// preferences, in a Markdown file that the user can edit too.
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// File is where a project's memory is kept, relative to the project.
+const File = ".tiny-trae/memory.md"
+
+// DefaultTopic is the topic of entries recorded without one.
+const DefaultTopic = "Notes"
+
+// maxPromptBytes caps the memory added to the system prompt, so that it
+// cannot crowd out the conversation.
+const maxPromptBytes = 16 << 10
+
+// Memory is a project's remembered facts, grouped by topic. In the file,
+// each topic is a "##" heading and each entry a list item under it.
+type Memory struct {
+	Topics []Topic
+}
+
+// Topic is a group of related entries.
+type Topic struct {
+	Name    string
+	Entries []string
+}
+
+// Load reads the memory file at path. A missing file is an empty memory.
+func Load(path string) (*Memory, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Memory{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(data)), nil
+}
+
+// Parse parses a memory file. Text outside list items, such as a paragraph
+// the user wrote, becomes an entry of its own; the title is dropped.
+func Parse(text string) *Memory {
+	m := &Memory{}
+	topic := DefaultTopic
+	continuing := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			continuing = false
+		case strings.HasPrefix(trimmed, "## "):
+			topic = strings.TrimSpace(trimmed[3:])
+			continuing = false
+		case strings.HasPrefix(trimmed, "# "):
+			continuing = false
+		case strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* "):
+			m.add(topic, strings.TrimSpace(trimmed[2:]))
+			continuing = true
+		case continuing && strings.HasPrefix(line, " "):
+			t := m.topic(topic)
+			t.Entries[len(t.Entries)-1] += "\n" + trimmed
+		default:
+			m.add(topic, trimmed)
+			continuing = true
+		}
+	}
+	return m
+}
+
+// Save writes the memory to path, creating its directory if needed.
+func (m *Memory) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(m.String()), 0o644)
+}
+
+// String returns the memory in the file's format.
+func (m *Memory) String() string {
+	var b strings.Builder
+	b.WriteString("# Project Memory\n")
+	for _, t := range m.Topics {
+		fmt.Fprintf(&b, "\n## %s\n\n", t.Name)
+		for _, entry := range t.Entries {
+			fmt.Fprintf(&b, "- %s\n", strings.ReplaceAll(entry, "\n", "\n  "))
+		}
+	}
+	return b.String()
+}
+
+// Empty reports whether nothing is remembered.
+func (m *Memory) Empty() bool {
+	return len(m.Topics) == 0
+}
+
+// Add records entry under topic, or DefaultTopic if topic is "".
+func (m *Memory) Add(topic, entry string) error {
+	entry = strings.TrimSpace(entry)
+	if entry == "" {
+		return fmt.Errorf("the entry is empty")
+	}
+	for _, t := range m.Topics {
+		if slices.Contains(t.Entries, entry) {
+			return fmt.Errorf("this is already remembered")
+		}
+	}
+	m.add(strings.TrimSpace(topic), entry)
+	return nil
+}
+
+// Update replaces the entry containing old with entry.
+func (m *Memory) Update(old, entry string) error {
+	entry = strings.TrimSpace(entry)
+	if entry == "" {
+		return fmt.Errorf("the entry is empty; use remove to forget it")
+	}
+	t, i, err := m.find(old)
+	if err != nil {
+		return err
+	}
+	m.Topics[t].Entries[i] = entry
+	return nil
+}
+
+// Remove forgets the entry containing old.
+func (m *Memory) Remove(old string) error {
+	t, i, err := m.find(old)
+	if err != nil {
+		return err
+	}
+	topic := &m.Topics[t]
+	topic.Entries = append(topic.Entries[:i], topic.Entries[i+1:]...)
+	if len(topic.Entries) == 0 {
+		m.Topics = append(m.Topics[:t], m.Topics[t+1:]...)
+	}
+	return nil
+}
+
+// Prompt returns the memory as a section of the system prompt, or "" if
+// nothing is remembered.
+func (m *Memory) Prompt() string {
+	if m.Empty() {
+		return ""
+	}
+	text := m.String()
+	if len(text) > maxPromptBytes {
+		text = text[:maxPromptBytes] + "\n[truncated]"
+	}
+	return fmt.Sprintf("You remember these facts about the project from earlier sessions. They may be out of date; trust what you see in the code over them.\n\n<memory>\n%s</memory>\n", text)
+}
+
+// add appends entry to the topic called name, creating it if needed.
+func (m *Memory) add(name, entry string) {
+	if name == "" {
+		name = DefaultTopic
+	}
+	t := m.topic(name)
+	t.Entries = append(t.Entries, entry)
+}
+
+// topic returns the topic called name, creating it if needed.
+func (m *Memory) topic(name string) *Topic {
+	for i := range m.Topics {
+		if strings.EqualFold(m.Topics[i].Name, name) {
+			return &m.Topics[i]
+		}
+	}
+	m.Topics = append(m.Topics, Topic{Name: name})
+	return &m.Topics[len(m.Topics)-1]
+}
+
+// find returns the topic and entry indices of the one entry containing
+// text.
+func (m *Memory) find(text string) (topic, entry int, err error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, 0, fmt.Errorf("no entry given")
+	}
+	found := 0
+	for t, tp := range m.Topics {
+		for i, e := range tp.Entries {
+			if strings.Contains(e, text) {
+				topic, entry = t, i
+				found++
+			}
+		}
+	}
+	switch found {
+	case 0:
+		return 0, 0, fmt.Errorf("no entry contains %q", text)
+	case 1:
+		return topic, entry, nil
+	default:
+		return 0, 0, fmt.Errorf("%d entries contain %q; give more of the entry", found, text)
+	}
+}
@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	m := Parse(`# Project Memory
+
+Prefers small commits.
+
+## Build
+
+- Run make generate before go build.
+- The integration tests need Docker:
+  start it with make services.
+`)
+	want := "# Project Memory\n\n## Notes\n\n- Prefers small commits.\n\n## Build\n\n- Run make generate before go build.\n- The integration tests need Docker:\n  start it with make services.\n"
+	if got := m.String(); got != want {
+		t.Errorf("Parse().String() = %q, want %q", got, want)
+	}
+}
+
+func TestEdit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), File)
+	m, err := Load(path)
+	if err != nil || !m.Empty() || m.Prompt() != "" {
+		t.Fatalf("Expected an empty memory without a file, got %+v, %v", m, err)
+	}
+
+	if err := m.Add("build", "Run make generate first."); err != nil {
+		t.Fatal(err)
+	}
+	m.Add("", "The user prefers table-driven tests.")
+	m.Add("Build", "Lint with golangci-lint.")
+	if err := m.Add("build", "Run make generate first."); err == nil {
+		t.Error("Expected an error for an entry already remembered")
+	}
+	if err := m.Update("golangci", "Lint with make lint."); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Remove("make"); err == nil || !strings.Contains(err.Error(), "2 entries") {
+		t.Errorf("Expected an error for an ambiguous entry, got %v", err)
+	}
+	if err := m.Remove("table-driven"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Remove("table-driven"); err == nil {
+		t.Error("Expected an error for a missing entry")
+	}
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Project Memory\n\n## build\n\n- Run make generate first.\n- Lint with make lint.\n"
+	if got := loaded.String(); got != want {
+		t.Errorf("Saved memory = %q, want %q", got, want)
+	}
+	if prompt := loaded.Prompt(); !strings.Contains(prompt, "<memory>\n"+want+"</memory>") {
+		t.Errorf("Unexpected prompt:\n%s", prompt)
+	}
+}
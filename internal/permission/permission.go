@@ -0,0 +1,167 @@
+package permission
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Action is what the policy decides for a tool call.
+type Action string
+
+const (
+	// ActionDefault means no rule matched; the tool's own approval setting applies.
+	ActionDefault Action = ""
+	ActionAllow   Action = "allow"
+	ActionAsk     Action = "ask"
+	ActionDeny    Action = "deny"
+)
+
+// Rule applies an action to calls of a tool. Match is an optional glob that is
+// compared against the call's command (for shell tools) or path (for file tools);
+// '*' matches any sequence of characters and '?' matches a single character.
+type Rule struct {
+	Tool   string `yaml:"tool"`
+	Match  string `yaml:"match,omitempty"`
+	Action Action `yaml:"action"`
+}
+
+// Policy is an ordered list of rules. The first matching rule wins.
+type Policy struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Decision is the outcome of evaluating a policy against a tool call.
+type Decision struct {
+	Action Action
+	Rule   *Rule
+}
+
+// Evaluate returns the action for a call of the named tool with the given input.
+func (p *Policy) Evaluate(toolName string, input json.RawMessage) Decision {
+	if p == nil {
+		return Decision{Action: ActionDefault}
+	}
+
+	subject := subjectOf(input)
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.Tool != "*" && rule.Tool != toolName {
+			continue
+		}
+		if rule.Match != "" && !matchGlob(rule.Match, subject) {
+			continue
+		}
+		return Decision{Action: rule.Action, Rule: rule}
+	}
+
+	return Decision{Action: ActionDefault}
+}
+
+// String describes the rule for messages reported back to the model.
+func (r Rule) String() string {
+	if r.Match == "" {
+		return fmt.Sprintf("%s %s", r.Action, r.Tool)
+	}
+	return fmt.Sprintf("%s %s matching %q", r.Action, r.Tool, r.Match)
+}
+
+// Validate checks that every rule names a tool and a known action.
+func (p *Policy) Validate() error {
+	for i, rule := range p.Rules {
+		if rule.Tool == "" {
+			return fmt.Errorf("rule %d: tool is required", i+1)
+		}
+		switch rule.Action {
+		case ActionAllow, ActionAsk, ActionDeny:
+		default:
+			return fmt.Errorf("rule %d: unknown action %q (want allow, ask, or deny)", i+1, rule.Action)
+		}
+	}
+	return nil
+}
+
+// Parse parses a YAML policy document.
+func Parse(data []byte) (*Policy, error) {
+	var policy Policy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse permission policy: %w", err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid permission policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// Load reads a YAML policy file.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// DefaultPath returns the location of the user's permission policy file.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tiny-trae", "permissions.yaml"), nil
+}
+
+// LoadDefault loads the policy from DefaultPath. A missing file yields an
+// empty policy rather than an error.
+func LoadDefault() (*Policy, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return &Policy{}, nil
+	}
+	policy, err := Load(path)
+	if os.IsNotExist(err) {
+		return &Policy{}, nil
+	}
+	return policy, err
+}
+
+// subjectOf extracts the value that Match globs are compared against.
+func subjectOf(input json.RawMessage) string {
+	var fields map[string]any
+	if err := json.Unmarshal(input, &fields); err != nil {
+		return ""
+	}
+	for _, key := range []string{"command", "path"} {
+		if v, ok := fields[key].(string); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// matchGlob reports whether s matches the glob pattern in its entirety.
+func matchGlob(pattern, s string) bool {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return false
+	}
+	return re.MatchString(s)
+}
@@ -0,0 +1,79 @@
+package permission
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	policy, err := Parse([]byte(`
+rules:
+  - tool: bash
+    match: "rm *"
+    action: deny
+  - tool: bash
+    match: "go test*"
+    action: allow
+  - tool: edit_file
+    match: "*.md"
+    action: allow
+  - tool: bash
+    action: ask
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse policy: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		tool     string
+		input    any
+		expected Action
+	}{
+		{"denied command", "bash", map[string]string{"command": "rm -rf /"}, ActionDeny},
+		{"allowed command", "bash", map[string]string{"command": "go test ./..."}, ActionAllow},
+		{"fallback rule", "bash", map[string]string{"command": "ls"}, ActionAsk},
+		{"allowed path", "edit_file", map[string]string{"path": "docs/README.md"}, ActionAllow},
+		{"unmatched path", "edit_file", map[string]string{"path": "main.go"}, ActionDefault},
+		{"unlisted tool", "read_file", map[string]string{"path": "main.go"}, ActionDefault},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := json.Marshal(tt.input)
+			if err != nil {
+				t.Fatalf("Failed to marshal input: %v", err)
+			}
+			decision := policy.Evaluate(tt.tool, input)
+			if decision.Action != tt.expected {
+				t.Errorf("Expected action %q, got %q", tt.expected, decision.Action)
+			}
+		})
+	}
+}
+
+func TestEvaluateNilPolicy(t *testing.T) {
+	var policy *Policy
+	if decision := policy.Evaluate("bash", json.RawMessage(`{}`)); decision.Action != ActionDefault {
+		t.Errorf("Expected default action for nil policy, got %q", decision.Action)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"missing tool", "rules:\n  - action: allow\n"},
+		{"unknown action", "rules:\n  - tool: bash\n    action: maybe\n"},
+		{"invalid yaml", "rules: [\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.data)); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+// Package plugin loads tools implemented by external executables.
+//
+// Every executable in the plugin directory is a tool. At startup it is run
+// with the single argument --describe and must print a JSON description:
+//
+//	{
+//	  "name": "jira_issue",
+//	  "description": "Fetch a Jira issue by key.",
+//	  "input_schema": {"type": "object", "properties": {"key": {"type": "string"}}, "required": ["key"]},
+//	  "requires_approval": false,
+//	  "mutates_files": false
+//	}
+//
+// When the model calls the tool, the executable is run with no arguments and
+// the tool input JSON on stdin. Whatever it prints to stdout is the result.
+// A non-zero exit status makes the call fail with stderr (or stdout, if
+// stderr is empty) as the error message.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"tiny-trae/internal/agent"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+const (
+	// DescribeArg is the argument a plugin is run with to describe itself.
+	DescribeArg = "--describe"
+	// describeTimeout bounds how long a plugin may take to describe itself.
+	describeTimeout = 10 * time.Second
+	// callTimeout bounds how long a plugin may take to run.
+	callTimeout = 5 * time.Minute
+	// maxOutputBytes caps the result a plugin can return.
+	maxOutputBytes = 1 << 20
+)
+
+// namePattern matches the tool names the API accepts.
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Description is what a plugin prints when run with DescribeArg.
+type Description struct {
+	Name             string          `json:"name"`
+	Description      string          `json:"description"`
+	InputSchema      json.RawMessage `json:"input_schema"`
+	RequiresApproval bool            `json:"requires_approval"`
+	MutatesFiles     bool            `json:"mutates_files"`
+}
+
+// Dir returns the directory plugins are loaded from.
+func Dir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tiny-trae", "tools"), nil
+}
+
+// Discover describes every executable in dir and returns the tools they
+// implement, sorted by name. Plugins that fail to describe themselves, or
+// whose name is taken by a built-in tool or another plugin, are skipped and
+// reported in errs. A missing directory yields no tools.
+func Discover(ctx context.Context, dir string, taken []string) (tools []agent.ToolDefinition, errs []error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	names := make(map[string]string)
+	for _, name := range taken {
+		names[name] = "a built-in tool"
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if !isExecutable(path) {
+			continue
+		}
+		tool, err := Load(ctx, path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if owner, ok := names[tool.Name]; ok {
+			errs = append(errs, fmt.Errorf("plugin %s: tool name %q is already used by %s", path, tool.Name, owner))
+			continue
+		}
+		names[tool.Name] = path
+		tools = append(tools, tool)
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools, errs
+}
+
+// Load describes the plugin at path and returns its tool.
+func Load(ctx context.Context, path string) (agent.ToolDefinition, error) {
+	ctx, cancel := context.WithTimeout(ctx, describeTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, DescribeArg)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return agent.ToolDefinition{}, fmt.Errorf("plugin %s: %s failed: %w %s", path, DescribeArg, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var desc Description
+	if err := json.Unmarshal(stdout.Bytes(), &desc); err != nil {
+		return agent.ToolDefinition{}, fmt.Errorf("plugin %s: invalid description: %w", path, err)
+	}
+	if !namePattern.MatchString(desc.Name) {
+		return agent.ToolDefinition{}, fmt.Errorf("plugin %s: invalid tool name %q", path, desc.Name)
+	}
+	if desc.Description == "" {
+		return agent.ToolDefinition{}, fmt.Errorf("plugin %s: description is empty", path)
+	}
+	schema, err := inputSchema(desc.InputSchema)
+	if err != nil {
+		return agent.ToolDefinition{}, fmt.Errorf("plugin %s: %w", path, err)
+	}
+
+	return agent.ToolDefinition{
+		Name:        desc.Name,
+		Description: desc.Description,
+		InputSchema: schema,
+		Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+			return call(ctx, path, input)
+		},
+		RequiresApproval: desc.RequiresApproval,
+		MutatesFiles:     desc.MutatesFiles,
+	}, nil
+}
+
+// inputSchema converts a plugin's JSON schema, which must describe an
+// object. A missing schema means the tool takes no input.
+func inputSchema(raw json.RawMessage) (anthropic.ToolInputSchemaParam, error) {
+	if len(raw) == 0 {
+		return anthropic.ToolInputSchemaParam{Properties: map[string]any{}}, nil
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return anthropic.ToolInputSchemaParam{}, fmt.Errorf("invalid input_schema: %w", err)
+	}
+	if t, ok := schema["type"]; ok && t != "object" {
+		return anthropic.ToolInputSchemaParam{}, fmt.Errorf("input_schema must have type object, not %v", t)
+	}
+
+	param := anthropic.ToolInputSchemaParam{Properties: schema["properties"]}
+	if required, ok := schema["required"].([]any); ok {
+		for _, name := range required {
+			if s, ok := name.(string); ok {
+				param.Required = append(param.Required, s)
+			}
+		}
+	}
+	// Keep other keywords, such as additionalProperties
+	for key, value := range schema {
+		if key == "type" || key == "properties" || key == "required" {
+			continue
+		}
+		if param.ExtraFields == nil {
+			param.ExtraFields = make(map[string]any)
+		}
+		param.ExtraFields[key] = value
+	}
+	return param, nil
+}
+
+// call runs the plugin at path with input on stdin and returns its stdout.
+func call(ctx context.Context, path string, input json.RawMessage) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, callTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if cmd.ProcessState != nil && cmd.ProcessState.ExitCode() >= 0 {
+		agent.ReportExitCode(ctx, cmd.ProcessState.ExitCode())
+	}
+
+	output := stdout.String()
+	if len(output) > maxOutputBytes {
+		agent.ReportTruncated(ctx)
+		output = output[:maxOutputBytes] + fmt.Sprintf("\n[output truncated at %d bytes]", maxOutputBytes)
+	}
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = strings.TrimSpace(output)
+		}
+		return "", fmt.Errorf("%s failed: %w: %s", filepath.Base(path), err, message)
+	}
+	return output, nil
+}
+
+// isExecutable reports whether path is a regular file the user can run. On
+// Windows, where there is no executable bit, the extension decides.
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".exe", ".bat", ".cmd":
+			return true
+		}
+		return false
+	}
+	return info.Mode().Perm()&0111 != 0
+}
@@ -0,0 +1,84 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writePlugin writes a shell script plugin that prints description for
+// --describe and otherwise runs body.
+func writePlugin(t *testing.T, dir, name, description, body string, mode os.FileMode) {
+	t.Helper()
+	script := "#!/bin/sh\nif [ \"$1\" = --describe ]; then\ncat <<'EOF'\n" + description + "\nEOF\nexit 0\nfi\n" + body + "\n"
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(script), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	dir := t.TempDir()
+	writePlugin(t, dir, "upper", `{"name":"upper","description":"Upper-case text.","input_schema":{"type":"object","properties":{"text":{"type":"string"}},"required":["text"],"additionalProperties":false}}`,
+		`tr a-z A-Z`, 0755)
+	writePlugin(t, dir, "fail", `{"name":"fail","description":"Always fails.","requires_approval":true}`,
+		`echo "something broke" >&2; exit 3`, 0755)
+	writePlugin(t, dir, "shadow", `{"name":"read_file","description":"Shadows a built-in."}`, `true`, 0755)
+	writePlugin(t, dir, "broken", `not json`, `true`, 0755)
+	writePlugin(t, dir, "notes.txt", `{"name":"notes","description":"Not executable."}`, `true`, 0644)
+
+	tools, errs := Discover(context.Background(), dir, []string{"read_file"})
+	if len(tools) != 2 || tools[0].Name != "fail" || tools[1].Name != "upper" {
+		t.Fatalf("Expected the fail and upper tools, got %+v", tools)
+	}
+	if len(errs) != 2 {
+		t.Errorf("Expected errors for the broken and shadowing plugins, got %v", errs)
+	}
+	if !tools[0].RequiresApproval || tools[1].RequiresApproval {
+		t.Error("Expected requires_approval to be kept")
+	}
+
+	schema, err := json.Marshal(tools[1].InputSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"required":["text"]`, `"additionalProperties":false`, `"type":"object"`} {
+		if !strings.Contains(string(schema), want) {
+			t.Errorf("Expected %s in schema %s", want, schema)
+		}
+	}
+
+	result, err := tools[1].Function(context.Background(), json.RawMessage(`{"text":"hello"}`))
+	if err != nil || result != `{"TEXT":"HELLO"}` {
+		t.Errorf("Unexpected result %q, %v", result, err)
+	}
+	if _, err := tools[0].Function(context.Background(), json.RawMessage(`{}`)); err == nil || !strings.Contains(err.Error(), "something broke") {
+		t.Errorf("Expected the plugin's stderr in the error, got %v", err)
+	}
+}
+
+func TestDiscoverMissingDir(t *testing.T) {
+	tools, errs := Discover(context.Background(), filepath.Join(t.TempDir(), "missing"), nil)
+	if len(tools) != 0 || len(errs) != 0 {
+		t.Errorf("Expected nothing for a missing directory, got %v, %v", tools, errs)
+	}
+}
+
+func TestInputSchema(t *testing.T) {
+	if _, err := inputSchema(json.RawMessage(`{"type":"array"}`)); err == nil {
+		t.Error("Expected error for a non-object schema")
+	}
+	schema, err := inputSchema(nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data, _ := json.Marshal(schema); string(data) != `{"properties":{},"type":"object"}` {
+		t.Errorf("Unexpected empty schema %s", data)
+	}
+}
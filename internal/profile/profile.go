@@ -17,22 +17,53 @@ func DefaultProfile() *agent.Profile {
 		Name:         "default",
 		Model:        anthropic.ModelClaudeSonnet4_0,
 		MaxTokens:    1024,
-		Tools:        tools.GetAllTools(),
+		Tools:        tools.Tagged(tools.TagDefault),
 		SystemPrompt: prompt.GetSystemPrompt(),
+		ToolOptions: map[string]agent.ToolOptions{
+			"ripgrep": {
+				"max_per_file": tools.DefaultRipgrepMaxPerFile,
+				"max_results":  tools.DefaultRipgrepMaxResults,
+			},
+			"read_file": {
+				"max_bytes": tools.DefaultReadFileMaxBytes,
+			},
+		},
 	}
 }
 
+// CodingProfile returns a profile for software engineering tasks, with every
+// tool, a prompt that asks for tested changes, and room for longer replies.
+func CodingProfile() *agent.Profile {
+	profile := DefaultProfile()
+	profile.Name = "coding"
+	profile.MaxTokens = 8192
+	profile.SystemPrompt = prompt.GetCodingSystemPrompt()
+	return profile
+}
+
 // MinimalProfile returns a profile with minimal tools for basic tasks.
 func MinimalProfile() *agent.Profile {
 	return &agent.Profile{
 		Name:         "minimal",
 		Model:        anthropic.ModelClaudeSonnet4_0,
 		MaxTokens:    1024,
-		Tools:        tools.GetMinimalTools(),
+		Tools:        tools.Tagged(tools.TagMinimal),
 		SystemPrompt: prompt.GetMinimalSystemPrompt(),
 	}
 }
 
+// ReviewProfile returns a profile that reviews staged changes with read-only
+// tools and answers with structured comments.
+func ReviewProfile() *agent.Profile {
+	return &agent.Profile{
+		Name:         "review",
+		Model:        anthropic.ModelClaudeSonnet4_0,
+		MaxTokens:    4096,
+		Tools:        tools.Tagged(tools.TagReadOnly),
+		SystemPrompt: prompt.GetReviewSystemPrompt(),
+	}
+}
+
 // NewProfile creates a custom profile with the specified configuration.
 func NewProfile(name string, model anthropic.Model, maxTokens int64, tools []agent.ToolDefinition, systemPrompt string) *agent.Profile {
 	return &agent.Profile{
@@ -44,12 +75,39 @@ func NewProfile(name string, model anthropic.Model, maxTokens int64, tools []age
 	}
 }
 
-// GetAvailableProfiles returns a map of all available built-in profiles.
+// GetAvailableProfiles returns a map of all available profiles: the
+// built-in ones and those declared in the user's settings.
 func GetAvailableProfiles() map[string]*agent.Profile {
-	return map[string]*agent.Profile{
-		"default": DefaultProfile(),
-		"minimal": MinimalProfile(),
+	profiles := make(map[string]*agent.Profile)
+	for _, name := range builtinNames {
+		profiles[name] = builtinProfile(name)
+	}
+	for _, d := range userProfiles {
+		// SetUserProfiles checked that it resolves; only a prompt file can
+		// have gone missing since
+		if profile, err := resolve(d.Name, userProfiles, nil); err == nil {
+			profiles[d.Name] = profile
+		}
+	}
+	return profiles
+}
+
+// builtinNames are the names of the built-in profiles.
+var builtinNames = []string{"default", "coding", "minimal", "review"}
+
+// builtinProfile returns the built-in profile called name, or nil.
+func builtinProfile(name string) *agent.Profile {
+	switch name {
+	case "default":
+		return DefaultProfile()
+	case "coding":
+		return CodingProfile()
+	case "minimal":
+		return MinimalProfile()
+	case "review":
+		return ReviewProfile()
 	}
+	return nil
 }
 
 // ListProfiles prints all available profiles with their descriptions.
@@ -60,11 +118,19 @@ func ListProfiles() {
 
 	for name, profile := range profiles {
 		var description string
-		switch name {
-		case "default":
-			description = "General-purpose profile with all tools and standard prompt"
-		case "minimal":
-			description = "Lightweight profile with minimal tools for basic tasks"
+		if d, ok := UserProfile(name); ok {
+			description = d.describe()
+		} else {
+			switch name {
+			case "default":
+				description = "General-purpose profile with all tools and standard prompt"
+			case "coding":
+				description = "Software engineering tasks: all tools, a prompt that asks for tested changes, and longer replies"
+			case "minimal":
+				description = "Lightweight profile with minimal tools for basic tasks"
+			case "review":
+				description = "Reviews staged changes with read-only tools (used by 'tiny-trae review')"
+			}
 		}
 
 		fmt.Printf("  %s:\n", name)
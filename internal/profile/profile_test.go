@@ -60,3 +60,34 @@ func TestNewProfile(t *testing.T) {
 		t.Errorf("Expected %d tools, got %d", len(tools), len(profile.Tools))
 	}
 }
+
+func TestReviewProfile(t *testing.T) {
+	profile := GetProfileByName("review")
+	if profile == nil {
+		t.Fatal("Expected review profile to be available")
+	}
+	for _, tool := range profile.Tools {
+		if tool.RequiresApproval {
+			t.Errorf("Review profile includes tool %s that needs approval", tool.Name)
+		}
+	}
+}
+
+func TestCodingProfile(t *testing.T) {
+	profile := GetProfileByName("coding")
+	if profile == nil {
+		t.Fatal("Expected coding profile to be available")
+	}
+	if profile.MaxTokens <= DefaultProfile().MaxTokens {
+		t.Errorf("Expected more max tokens than the default profile, got %d", profile.MaxTokens)
+	}
+	names := make(map[string]bool)
+	for _, tool := range profile.Tools {
+		names[tool.Name] = true
+	}
+	for _, name := range []string{"edit_file", "run_tests", "build_and_lint"} {
+		if !names[name] {
+			t.Errorf("Expected coding profile to include %s", name)
+		}
+	}
+}
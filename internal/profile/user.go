@@ -0,0 +1,207 @@
+package profile
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/tools"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// Definition is a profile declared in the user's settings rather than in
+// Go. It extends another profile and unset fields keep that profile's
+// values. Unless it says otherwise, a definition named like a built-in
+// profile is an overlay on it, and any other extends default.
+type Definition struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// Extends names the profile this one is a variant of, built-in or
+	// declared by the user.
+	Extends string `yaml:"extends"`
+	// Provider is the API serving the model; only "anthropic" is supported.
+	Provider    string   `yaml:"provider"`
+	Model       string   `yaml:"model"`
+	MaxTokens   int64    `yaml:"max_tokens"`
+	Temperature *float64 `yaml:"temperature"`
+	// Tools are tool names and "tag:"-prefixed tags, as tools.Select takes;
+	// they replace the extended profile's tools. WithoutTools, in the same
+	// form, are then left out.
+	Tools        []string `yaml:"tools"`
+	WithoutTools []string `yaml:"without_tools"`
+	// SystemPrompt is the prompt itself, or SystemPromptFile a file holding
+	// it; at most one may be set.
+	SystemPrompt     string `yaml:"system_prompt"`
+	SystemPromptFile string `yaml:"system_prompt_file"`
+	// Retrieval turns on adding indexed code relevant to each user message
+	// to it.
+	Retrieval *Retrieval `yaml:"retrieval"`
+}
+
+// Retrieval is a definition's agent.Retrieval.
+type Retrieval struct {
+	TopK      int `yaml:"top_k"`
+	MaxTokens int `yaml:"max_tokens"`
+}
+
+// validate checks the definition's own values.
+func (d Definition) validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("a profile needs a name")
+	}
+	if d.Provider != "" && d.Provider != "anthropic" {
+		return fmt.Errorf("profile %s: unsupported provider %q", d.Name, d.Provider)
+	}
+	if d.MaxTokens < 0 {
+		return fmt.Errorf("profile %s: max_tokens must be positive", d.Name)
+	}
+	if t := d.Temperature; t != nil && (*t < 0 || *t > 1) {
+		return fmt.Errorf("profile %s: temperature must be between 0 and 1", d.Name)
+	}
+	if d.SystemPrompt != "" && d.SystemPromptFile != "" {
+		return fmt.Errorf("profile %s: set system_prompt or system_prompt_file, not both", d.Name)
+	}
+	if r := d.Retrieval; r != nil && (r.TopK < 0 || r.MaxTokens < 0) {
+		return fmt.Errorf("profile %s: retrieval's top_k and max_tokens must be positive", d.Name)
+	}
+	return nil
+}
+
+// applyTo overrides the extended profile's settings with the ones set here.
+func (d Definition) applyTo(profile *agent.Profile) error {
+	profile.Name = d.Name
+	if d.Model != "" {
+		profile.Model = anthropic.Model(d.Model)
+	}
+	if d.MaxTokens != 0 {
+		profile.MaxTokens = d.MaxTokens
+	}
+	if d.Temperature != nil {
+		profile.Temperature = d.Temperature
+	}
+	if d.Retrieval != nil {
+		profile.Retrieval = agent.Retrieval{TopK: d.Retrieval.TopK, MaxTokens: d.Retrieval.MaxTokens}
+	}
+	if d.Tools != nil {
+		selected, err := tools.Select(d.Tools)
+		if err != nil {
+			return fmt.Errorf("profile %s: %w", d.Name, err)
+		}
+		profile.Tools = selected
+	}
+	if d.WithoutTools != nil {
+		left, err := tools.Select(d.WithoutTools)
+		if err != nil {
+			return fmt.Errorf("profile %s: %w", d.Name, err)
+		}
+		profile.Tools = slices.DeleteFunc(profile.Tools, func(tool agent.ToolDefinition) bool {
+			return slices.ContainsFunc(left, func(l agent.ToolDefinition) bool { return l.Name == tool.Name })
+		})
+	}
+	switch {
+	case d.SystemPrompt != "":
+		profile.SystemPrompt = d.SystemPrompt
+	case d.SystemPromptFile != "":
+		data, err := os.ReadFile(d.SystemPromptFile)
+		if err != nil {
+			return fmt.Errorf("profile %s: %w", d.Name, err)
+		}
+		profile.SystemPrompt = string(data)
+	}
+	return nil
+}
+
+// describe returns the definition's description, or a summary of it.
+func (d Definition) describe() string {
+	if d.Description != "" {
+		return d.Description
+	}
+	return fmt.Sprintf("User-defined variant of the %s profile", d.base())
+}
+
+// base returns the name of the profile the definition extends.
+func (d Definition) base() string {
+	if d.Extends != "" {
+		return d.Extends
+	}
+	if builtinProfile(d.Name) != nil {
+		return d.Name
+	}
+	return "default"
+}
+
+// resolve returns the profile called name: its definition among
+// definitions applied over the profile it extends, or else the built-in
+// profile. extending holds the profiles whose resolution led here, to catch
+// cycles.
+func resolve(name string, definitions []Definition, extending []string) (*agent.Profile, error) {
+	if slices.Contains(extending, name) {
+		return nil, fmt.Errorf("profile %s extends itself through %s", name, strings.Join(extending, " -> "))
+	}
+	i := slices.IndexFunc(definitions, func(d Definition) bool { return d.Name == name })
+	if i < 0 {
+		if profile := builtinProfile(name); profile != nil {
+			return profile, nil
+		}
+		return nil, fmt.Errorf("unknown profile %s", name)
+	}
+
+	d := definitions[i]
+	base := d.base()
+	var profile *agent.Profile
+	if base == d.Name {
+		if profile = builtinProfile(base); profile == nil {
+			return nil, fmt.Errorf("profile %s extends itself", d.Name)
+		}
+	} else {
+		var err error
+		if profile, err = resolve(base, definitions, append(extending, name)); err != nil {
+			return nil, fmt.Errorf("profile %s: %w", d.Name, err)
+		}
+	}
+	if err := d.applyTo(profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// userProfiles are the profiles declared in the user's settings; see
+// SetUserProfiles.
+var userProfiles []Definition
+
+// SetUserProfiles makes definitions available by name, alongside the
+// built-in profiles. A definition named like a built-in profile replaces
+// it. Definitions that do not resolve are errors, so mistakes show up at
+// startup rather than when the profile is chosen.
+func SetUserProfiles(definitions []Definition) error {
+	seen := make(map[string]bool)
+	for _, d := range definitions {
+		if err := d.validate(); err != nil {
+			return err
+		}
+		if seen[d.Name] {
+			return fmt.Errorf("profile %s is declared twice", d.Name)
+		}
+		seen[d.Name] = true
+	}
+	for _, d := range definitions {
+		if _, err := resolve(d.Name, definitions, nil); err != nil {
+			return err
+		}
+	}
+	userProfiles = definitions
+	return nil
+}
+
+// UserProfile returns the definition of the user's profile called name.
+func UserProfile(name string) (Definition, bool) {
+	for _, d := range userProfiles {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return Definition{}, false
+}
@@ -0,0 +1,92 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUserProfiles(t *testing.T) {
+	defer SetUserProfiles(nil)
+
+	promptFile := filepath.Join(t.TempDir(), "prompt.md")
+	os.WriteFile(promptFile, []byte("Be terse."), 0o644)
+	temperature := 0.2
+	err := SetUserProfiles([]Definition{
+		{Name: "terse", Model: "claude-opus-4-0", Temperature: &temperature, Tools: []string{"read_file", "tag:readonly"}, SystemPromptFile: promptFile},
+		{Name: "minimal", MaxTokens: 2048},
+	})
+	if err != nil {
+		t.Fatalf("SetUserProfiles failed: %v", err)
+	}
+
+	terse := GetProfileByName("terse")
+	if terse == nil {
+		t.Fatal("Expected the user's profile to be available")
+	}
+	if terse.Model != "claude-opus-4-0" || terse.MaxTokens != 1024 || *terse.Temperature != 0.2 || terse.SystemPrompt != "Be terse." {
+		t.Errorf("Unexpected profile %+v", terse)
+	}
+	for _, tool := range terse.Tools {
+		if tool.RequiresApproval {
+			t.Errorf("Expected only read-only tools, got %s", tool.Name)
+		}
+	}
+	if minimal := GetProfileByName("minimal"); minimal.MaxTokens != 2048 || len(minimal.Tools) != len(MinimalProfile().Tools) {
+		t.Errorf("Expected the user's profile to be an overlay on the built-in one, got %+v", minimal)
+	}
+	if GetProfileByName("review") == nil {
+		t.Error("Expected the other built-in profiles to be kept")
+	}
+}
+
+func TestUserProfileExtends(t *testing.T) {
+	defer SetUserProfiles(nil)
+
+	err := SetUserProfiles([]Definition{
+		{Name: "careful", Extends: "safe", MaxTokens: 4096, Retrieval: &Retrieval{TopK: 5}},
+		{Name: "safe", WithoutTools: []string{"bash"}, Model: "claude-opus-4-0"},
+		{Name: "default", SystemPrompt: "Be brief."},
+	})
+	if err != nil {
+		t.Fatalf("SetUserProfiles failed: %v", err)
+	}
+
+	careful := GetProfileByName("careful")
+	if careful.Name != "careful" || careful.Model != "claude-opus-4-0" || careful.MaxTokens != 4096 || careful.SystemPrompt != "Be brief." || careful.Retrieval.TopK != 5 {
+		t.Errorf("Expected the settings of the whole chain, got %+v", careful)
+	}
+	for _, tool := range careful.Tools {
+		if tool.Name == "bash" {
+			t.Error("Expected bash to be left out")
+		}
+	}
+	if len(careful.Tools) != len(DefaultProfile().Tools)-1 {
+		t.Errorf("Expected the default tools but bash, got %d tools", len(careful.Tools))
+	}
+}
+
+func TestSetUserProfilesErrors(t *testing.T) {
+	defer SetUserProfiles(nil)
+
+	for _, tc := range []struct {
+		definitions []Definition
+		want        string
+	}{
+		{[]Definition{{Model: "claude"}}, "needs a name"},
+		{[]Definition{{Name: "a", Provider: "openai"}}, "unsupported provider"},
+		{[]Definition{{Name: "a", Tools: []string{"no_such_tool"}}}, "no_such_tool"},
+		{[]Definition{{Name: "a", SystemPromptFile: "/no/such/file"}}, "/no/such/file"},
+		{[]Definition{{Name: "a", SystemPrompt: "x", SystemPromptFile: "y"}}, "not both"},
+		{[]Definition{{Name: "a", Retrieval: &Retrieval{TopK: -1}}}, "top_k"},
+		{[]Definition{{Name: "a"}, {Name: "a"}}, "twice"},
+		{[]Definition{{Name: "a", Extends: "nope"}}, "unknown profile nope"},
+		{[]Definition{{Name: "a", Extends: "b"}, {Name: "b", Extends: "a"}}, "extends itself"},
+		{[]Definition{{Name: "a", Extends: "a"}}, "extends itself"},
+	} {
+		if err := SetUserProfiles(tc.definitions); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("SetUserProfiles(%+v) = %v, want an error about %s", tc.definitions, err, tc.want)
+		}
+	}
+}
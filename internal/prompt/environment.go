@@ -0,0 +1,108 @@
+package prompt
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"tiny-trae/internal/git"
+)
+
+// projectMarkers map files found at a project's root to the kind of
+// project they mark, in the order they are reported.
+var projectMarkers = []struct {
+	file, kind string
+}{
+	{"go.mod", "Go"},
+	{"package.json", "Node.js"},
+	{"tsconfig.json", "TypeScript"},
+	{"Cargo.toml", "Rust"},
+	{"pyproject.toml", "Python"},
+	{"requirements.txt", "Python"},
+	{"setup.py", "Python"},
+	{"pom.xml", "Java (Maven)"},
+	{"build.gradle", "Java (Gradle)"},
+	{"build.gradle.kts", "Kotlin (Gradle)"},
+	{"Gemfile", "Ruby"},
+	{"composer.json", "PHP"},
+	{"mix.exs", "Elixir"},
+	{"CMakeLists.txt", "C/C++ (CMake)"},
+	{"Makefile", "Make"},
+	{"Dockerfile", "Docker"},
+}
+
+// Environment describes where the agent is working, so that the model
+// does not have to ask or guess.
+type Environment struct {
+	OS, Arch string
+	// Dir is the working directory.
+	Dir string
+	// Branch is the checked-out git branch, "" if HEAD is detached, and
+	// Changed the number of files with uncommitted changes. Both are only
+	// set when InRepo is.
+	InRepo  bool
+	Branch  string
+	Changed int
+	Date    time.Time
+	// Project lists the kinds of project Dir holds, such as "Go", each
+	// with the file that shows it.
+	Project []string
+}
+
+// DetectEnvironment describes the environment of dir.
+func DetectEnvironment(ctx context.Context, dir string) Environment {
+	env := Environment{OS: runtime.GOOS, Arch: runtime.GOARCH, Dir: dir, Date: time.Now()}
+	if abs, err := filepath.Abs(dir); err == nil {
+		env.Dir = abs
+	}
+
+	if status, err := git.Run(ctx, dir, "status", "--porcelain"); err == nil {
+		env.InRepo = true
+		env.Branch, _ = git.CurrentBranch(ctx, dir)
+		env.Changed = strings.Count(status, "\n")
+	}
+
+	seen := make(map[string]bool)
+	for _, marker := range projectMarkers {
+		if _, err := os.Stat(filepath.Join(dir, marker.file)); err != nil || seen[marker.kind] {
+			continue
+		}
+		seen[marker.kind] = true
+		env.Project = append(env.Project, fmt.Sprintf("%s (%s)", marker.kind, marker.file))
+	}
+	return env
+}
+
+// String returns the environment as an <env> block for the system prompt.
+func (e Environment) String() string {
+	var b strings.Builder
+	b.WriteString("<env>\n")
+	fmt.Fprintf(&b, "os: %s/%s\n", e.OS, e.Arch)
+	fmt.Fprintf(&b, "working_directory: %s\n", e.Dir)
+	if e.InRepo {
+		fmt.Fprintf(&b, "git_branch: %s\n", cmp.Or(e.Branch, "(detached HEAD)"))
+		if e.Changed == 0 {
+			b.WriteString("git_status: clean\n")
+		} else {
+			fmt.Fprintf(&b, "git_status: %d files with uncommitted changes\n", e.Changed)
+		}
+	} else {
+		b.WriteString("git_repository: no\n")
+	}
+	fmt.Fprintf(&b, "date: %s\n", e.Date.Format("2006-01-02 (Monday)"))
+	if len(e.Project) > 0 {
+		fmt.Fprintf(&b, "project: %s\n", strings.Join(e.Project, ", "))
+	}
+	b.WriteString("</env>")
+	return b.String()
+}
+
+// WithEnvironment returns systemPrompt followed by env.
+func WithEnvironment(systemPrompt string, env Environment) string {
+	return fmt.Sprintf("%s\n\nThis is the environment you are working in, as of the start of the session:\n%s\n", strings.TrimRight(systemPrompt, "\n"), env)
+}
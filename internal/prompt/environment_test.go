@@ -0,0 +1,50 @@
+package prompt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDetectEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"go.mod", "Makefile", "requirements.txt", "setup.py"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0o644)
+	}
+	env := DetectEnvironment(context.Background(), dir)
+	if env.Dir != dir || len(env.Project) != 3 || env.Project[0] != "Go (go.mod)" || env.Project[1] != "Python (requirements.txt)" || env.Project[2] != "Make (Makefile)" {
+		t.Errorf("Unexpected environment %+v", env)
+	}
+}
+
+func TestEnvironmentString(t *testing.T) {
+	env := Environment{
+		OS:      "linux",
+		Arch:    "amd64",
+		Dir:     "/src/app",
+		InRepo:  true,
+		Branch:  "main",
+		Changed: 2,
+		Date:    time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC),
+		Project: []string{"Go (go.mod)"},
+	}
+	want := `<env>
+os: linux/amd64
+working_directory: /src/app
+git_branch: main
+git_status: 2 files with uncommitted changes
+date: 2025-03-14 (Friday)
+project: Go (go.mod)
+</env>`
+	if got := env.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	env = Environment{OS: "darwin", Arch: "arm64", Dir: "/tmp", Date: env.Date}
+	want = "<env>\nos: darwin/arm64\nworking_directory: /tmp\ngit_repository: no\ndate: 2025-03-14 (Friday)\n</env>"
+	if got := env.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
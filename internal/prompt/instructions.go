@@ -0,0 +1,76 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// InstructionFiles are the names of the files in which a project gives
+// coding agents its conventions. Only a directory's first one found is
+// read, so a repository keeping both for different agents is not read
+// twice.
+var InstructionFiles = []string{"AGENTS.md", "CLAUDE.md"}
+
+// maxInstructionBytes caps each instruction file, so that a huge one cannot
+// crowd out the conversation.
+const maxInstructionBytes = 32 << 10
+
+// Instructions is a project's instruction file.
+type Instructions struct {
+	Path    string
+	Content string
+}
+
+// FindInstructions returns the instruction files in dir and the directories
+// above it, outermost first so that the more specific ones come last.
+func FindInstructions(dir string) ([]Instructions, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	var found []Instructions
+	for {
+		for _, name := range InstructionFiles {
+			path := filepath.Join(dir, name)
+			data, err := os.ReadFile(path)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			content := string(data)
+			if len(content) > maxInstructionBytes {
+				content = content[:maxInstructionBytes] + "\n[truncated]"
+			}
+			found = append(found, Instructions{Path: path, Content: content})
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	slices.Reverse(found)
+	return found, nil
+}
+
+// WithInstructions returns systemPrompt followed by the project's
+// instructions.
+func WithInstructions(systemPrompt string, instructions []Instructions) string {
+	if len(instructions) == 0 {
+		return systemPrompt
+	}
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(systemPrompt, "\n"))
+	b.WriteString("\n\nThe project you are working in gives these instructions. Follow them unless the user asks otherwise; where they disagree, later files take precedence.\n")
+	for _, i := range instructions {
+		fmt.Fprintf(&b, "\n<instructions path=%q>\n%s\n</instructions>\n", i.Path, strings.TrimSpace(i.Content))
+	}
+	return b.String()
+}
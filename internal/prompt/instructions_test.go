@@ -0,0 +1,37 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindInstructions(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	os.MkdirAll(sub, 0o755)
+	os.WriteFile(filepath.Join(root, "CLAUDE.md"), []byte("Use tabs.\n"), 0o644)
+	os.WriteFile(filepath.Join(sub, "AGENTS.md"), []byte("Run make test.\n"), 0o644)
+	os.WriteFile(filepath.Join(sub, "CLAUDE.md"), []byte("Not read, AGENTS.md comes first.\n"), 0o644)
+
+	found, err := FindInstructions(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Files above the temporary directory may be found too
+	if len(found) < 2 || found[len(found)-2].Content != "Use tabs.\n" || found[len(found)-1].Content != "Run make test.\n" {
+		t.Fatalf("Expected the root's CLAUDE.md, then sub's AGENTS.md, got %+v", found)
+	}
+
+	systemPrompt := WithInstructions("You are an agent.\n", found[len(found)-2:])
+	if !strings.HasPrefix(systemPrompt, "You are an agent.\n\n") || strings.Index(systemPrompt, "Use tabs.") > strings.Index(systemPrompt, "Run make test.") {
+		t.Errorf("Unexpected system prompt:\n%s", systemPrompt)
+	}
+	if !strings.Contains(systemPrompt, `<instructions path="`+filepath.Join(sub, "AGENTS.md")+`">`) {
+		t.Errorf("Expected the instructions to name their file:\n%s", systemPrompt)
+	}
+	if WithInstructions("You are an agent.", nil) != "You are an agent." {
+		t.Error("Expected the prompt to be unchanged without instructions")
+	}
+}
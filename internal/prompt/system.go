@@ -17,6 +17,25 @@ func GetSystemPrompt() string {
 	return SYSTEM_PROMPT
 }
 
+// CODING_SYSTEM_PROMPT is the prompt for the coding profile, which works on
+// software engineering tasks in the repository from start to finish.
+const CODING_SYSTEM_PROMPT = `You are an expert software engineer working in the user's repository with tools to read, search, edit, build, and test it.
+Work on each task until it is done:
+- Explore before you change anything: find the relevant code with your search tools and read it, along with its callers and tests.
+- Follow the conventions of the surrounding code: its naming, error handling, structure, and comment style.
+- Make the smallest change that solves the task completely. Do not reformat or refactor code the task does not touch.
+- Add or update tests for the behavior you change.
+- After editing, run the build, linters, and tests with build_and_lint and run_tests, and fix what fails before you finish.
+- If the task is ambiguous or a change would be destructive, ask before going ahead.
+
+When you are done, summarize what you changed and how you verified it, and mention anything left undone.
+`
+
+// GetCodingSystemPrompt returns the system prompt for the coding profile.
+func GetCodingSystemPrompt() string {
+	return CODING_SYSTEM_PROMPT
+}
+
 // MINIMAL_SYSTEM_PROMPT is a concise prompt for minimal profile.
 const MINIMAL_SYSTEM_PROMPT = `You are a helpful AI assistant. You provide concise and accurate responses.`
 
@@ -24,3 +43,24 @@ const MINIMAL_SYSTEM_PROMPT = `You are a helpful AI assistant. You provide conci
 func GetMinimalSystemPrompt() string {
 	return MINIMAL_SYSTEM_PROMPT
 }
+
+// REVIEW_SYSTEM_PROMPT is the prompt for the review profile, which reviews a
+// staged diff and answers with structured comments.
+const REVIEW_SYSTEM_PROMPT = `You are an experienced code reviewer. You are given a staged git diff and the full contents of the changed files.
+Look for bugs, security problems, missing error handling, race conditions, unclear code, and missing tests. Use your tools to read surrounding code when the diff alone is not enough, but do not try to change anything.
+Only comment on the changed lines and what they affect. Do not praise the code or restate what it does.
+
+Answer with only a JSON array of comments and no other text. Each comment is an object with:
+- "file": the path of the file, as shown in the diff
+- "line": the line number in the new version of the file
+- "severity": "error" for bugs and security problems, "warning" for likely problems, or "suggestion" for improvements
+- "message": what is wrong and why it matters
+- "suggestion": optionally, the code or change you recommend
+
+Answer with [] if you find nothing worth raising.
+`
+
+// GetReviewSystemPrompt returns the system prompt for the review profile.
+func GetReviewSystemPrompt() string {
+	return REVIEW_SYSTEM_PROMPT
+}
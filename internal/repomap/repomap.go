@@ -0,0 +1,309 @@
+// Package repomap builds a condensed map of a repository: its directory
+// layout, the files at its root, and the declarations of its most
+// referenced source files. Given to the model up front, it saves much of
+// the listing and searching a session otherwise starts with.
+package repomap
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"tiny-trae/internal/ignore"
+	"tiny-trae/internal/tools"
+)
+
+// Options limits the work Build does and the size of the map.
+type Options struct {
+	// MaxFiles is how many files are looked at; the rest of a large
+	// repository is left out.
+	MaxFiles int
+	// CentralFiles is how many of the most referenced source files have
+	// their declarations listed.
+	CentralFiles int
+	// MaxBytes caps the map's length.
+	MaxBytes int
+}
+
+// DefaultOptions keeps the map to about two thousand tokens.
+var DefaultOptions = Options{MaxFiles: 5000, CentralFiles: 10, MaxBytes: 8 << 10}
+
+const (
+	// maxSourceBytes skips source files too big to be hand-written.
+	maxSourceBytes = 256 << 10
+	// maxDirectories and maxRootFiles cap those parts of the map.
+	maxDirectories = 40
+	maxRootFiles   = 30
+	// maxDeclarations caps the declarations listed per file.
+	maxDeclarations = 15
+	// maxSignature caps the length of a declaration's line.
+	maxSignature = 120
+)
+
+// sourceFile is a source file with its top-level declarations.
+type sourceFile struct {
+	path         string
+	declarations []tools.OutlineEntry
+	lines        []string
+	score        int
+}
+
+// generatedRegexp matches the comment marking generated Go files, and the
+// like in other languages.
+var generatedRegexp = regexp.MustCompile(`(?m)^\W*(Code generated .* DO NOT EDIT|@generated)`)
+
+// identifierRegexp matches the identifiers counted as references.
+var identifierRegexp = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]{3,}`)
+
+// Build returns the map of the repository at root, or "" if there is
+// nothing to map.
+func Build(root string, options Options) (string, error) {
+	dirFiles := make(map[string]int)
+	var rootFiles []string
+	var sources []*sourceFile
+	// documents counts the source files each identifier appears in
+	documents := make(map[string]int)
+
+	files := 0
+	err := ignore.Walk(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable directories are left out of the map
+			return nil
+		}
+		if path == root {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if files == options.MaxFiles {
+			return fs.SkipAll
+		}
+		files++
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		dir := filepath.ToSlash(filepath.Dir(rel))
+		if dir == "." {
+			rootFiles = append(rootFiles, rel)
+		}
+		for ; dir != "."; dir = filepath.ToSlash(filepath.Dir(dir)) {
+			dirFiles[dir]++
+		}
+
+		source := readSource(path, rel)
+		if source == nil {
+			return nil
+		}
+		seen := make(map[string]bool)
+		for _, line := range source.lines {
+			for _, identifier := range identifierRegexp.FindAllString(line, -1) {
+				if !seen[identifier] {
+					seen[identifier] = true
+					documents[identifier]++
+				}
+			}
+		}
+		sources = append(sources, source)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if files == 0 {
+		return "", nil
+	}
+
+	var b strings.Builder
+	b.WriteString("<repo_map>\n")
+	writeDirectories(&b, dirFiles)
+	if len(rootFiles) > 0 {
+		more := ""
+		if len(rootFiles) > maxRootFiles {
+			more = fmt.Sprintf(", and %d more", len(rootFiles)-maxRootFiles)
+			rootFiles = rootFiles[:maxRootFiles]
+		}
+		fmt.Fprintf(&b, "Files at the root: %s%s\n", strings.Join(rootFiles, ", "), more)
+	}
+	writeCentralFiles(&b, sources, documents, options)
+	text := b.String()
+	if options.MaxBytes > 0 && len(text) > options.MaxBytes {
+		text = text[:strings.LastIndex(text[:options.MaxBytes], "\n")+1] + "[repository map truncated]\n"
+	}
+	return text + "</repo_map>", nil
+}
+
+// readSource reads and outlines a source file, or returns nil for other
+// files.
+func readSource(path, rel string) *sourceFile {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxSourceBytes {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || bytes.IndexByte(content, 0) >= 0 || generatedRegexp.Match(content[:min(len(content), 1024)]) {
+		return nil
+	}
+	entries, err := tools.Outline(rel, content)
+	if err != nil {
+		return nil
+	}
+	source := &sourceFile{path: rel, lines: strings.Split(string(content), "\n")}
+	for _, entry := range entries {
+		if entry.Depth == 0 {
+			source.declarations = append(source.declarations, entry)
+		}
+	}
+	return source
+}
+
+// writeDirectories writes the directories two levels deep, with the
+// number of files in each.
+func writeDirectories(b *strings.Builder, dirFiles map[string]int) {
+	var dirs []string
+	for dir := range dirFiles {
+		if strings.Count(dir, "/") < 2 {
+			dirs = append(dirs, dir)
+		}
+	}
+	if len(dirs) == 0 {
+		return
+	}
+	slices.Sort(dirs)
+	b.WriteString("Directories (files in each):\n")
+	for i, dir := range dirs {
+		if i == maxDirectories {
+			fmt.Fprintf(b, "  ... and %d more\n", len(dirs)-maxDirectories)
+			break
+		}
+		indent := "  "
+		if strings.Contains(dir, "/") {
+			indent = "    "
+		}
+		fmt.Fprintf(b, "%s%s/ (%d)\n", indent, dir, dirFiles[dir])
+	}
+}
+
+// writeCentralFiles writes the declarations of the source files whose
+// declarations appear in the most other files. Names declared in more
+// than one file, such as String, say little about which file is meant,
+// so they do not count.
+func writeCentralFiles(b *strings.Builder, sources []*sourceFile, documents map[string]int, options Options) {
+	declaredIn := make(map[string]int)
+	for _, source := range sources {
+		for _, name := range names(source) {
+			declaredIn[name]++
+		}
+	}
+	var central []*sourceFile
+	for _, source := range sources {
+		if isTest(source.path) {
+			continue
+		}
+		for _, name := range names(source) {
+			if declaredIn[name] == 1 {
+				source.score += documents[name] - 1
+			}
+		}
+		if source.score > 0 {
+			central = append(central, source)
+		}
+	}
+	if len(central) == 0 {
+		return
+	}
+	slices.SortStableFunc(central, func(a, b *sourceFile) int { return b.score - a.score })
+	central = central[:min(len(central), options.CentralFiles)]
+
+	b.WriteString("Most referenced source files:\n")
+	for _, source := range central {
+		fmt.Fprintf(b, "%s\n", source.path)
+		listed := 0
+		for _, entry := range source.declarations {
+			line := signature(source, entry)
+			// Members of const and var groups are too many to list
+			if !exported(source.path, entry.Name) || (entry.Kind == "const" || entry.Kind == "var") && !strings.HasPrefix(line, entry.Kind+" ") {
+				continue
+			}
+			if listed == maxDeclarations {
+				b.WriteString("  ...\n")
+				break
+			}
+			listed++
+			fmt.Fprintf(b, "  %s\n", line)
+		}
+	}
+}
+
+// names returns the names a source file declares at the top level, method
+// names without their receivers.
+func names(source *sourceFile) []string {
+	var names []string
+	for _, entry := range source.declarations {
+		name := entry.Name[strings.LastIndex(entry.Name, " ")+1:]
+		if len(name) >= 4 && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// exported reports whether a declaration is part of its file's interface:
+// in Go, whether it and any method's receiver type are exported; elsewhere,
+// whether it is not marked private with a leading underscore.
+func exported(path, name string) bool {
+	receiver, name, isMethod := strings.Cut(name, ") ")
+	if !isMethod {
+		name = receiver
+	}
+	if name == "" {
+		return false
+	}
+	if !strings.HasSuffix(path, ".go") {
+		return !strings.HasPrefix(name, "_")
+	}
+	receiver = strings.TrimLeft(receiver, "(*")
+	return unicode.IsUpper([]rune(name)[0]) && (!isMethod || receiver != "" && unicode.IsUpper([]rune(receiver)[0]))
+}
+
+// signature returns a declaration's first line, without a body and
+// shortened.
+func signature(source *sourceFile, entry tools.OutlineEntry) string {
+	line := ""
+	if entry.StartLine >= 1 && entry.StartLine <= len(source.lines) {
+		line = strings.TrimSpace(source.lines[entry.StartLine-1])
+	}
+	// A body on the same line is left out
+	if i := strings.Index(line, " { "); i >= 0 {
+		line = line[:i]
+	}
+	line = strings.TrimSpace(strings.TrimSuffix(line, "{"))
+	if line == "" {
+		return entry.Kind + " " + entry.Name
+	}
+	if len(line) > maxSignature {
+		line = line[:maxSignature] + "..."
+	}
+	return line
+}
+
+// isTest reports whether path looks like a test file.
+func isTest(path string) bool {
+	base := filepath.Base(path)
+	return strings.Contains(base, "_test.") || strings.Contains(base, ".test.") || strings.Contains(base, ".spec.") || strings.HasPrefix(base, "test_")
+}
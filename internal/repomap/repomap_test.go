@@ -0,0 +1,55 @@
+package repomap
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":              "module example\n",
+		"README.md":           "# Example\n",
+		"store/store.go":      "package store\n\n// Store keeps records.\ntype Store struct{}\n\nfunc (s *Store) Save(record string) error {\n\treturn nil\n}\n\nfunc helper() {}\n",
+		"store/store_test.go": "package store\n\nfunc TestSave() { _ = Store{} }\n",
+		"api/api.go":          "package api\n\nfunc Serve(s *store.Store) { s.Save(\"\") }\n",
+		"api/handlers.go":     "package api\n\nfunc Handle(s *store.Store) { Serve(s) }\n",
+		"cmd/tool/main.go":    "package main\n\nfunc main() { api.Handle(nil); var _ store.Store }\n",
+		"gen/types.pb.go":     "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage gen\n\ntype Generated struct{}\n",
+		".hidden/secret.go":   "package hidden\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, []byte(content), 0o644)
+	}
+
+	got, err := Build(root, DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"  api/ (2)\n",
+		"  cmd/ (1)\n    cmd/tool/ (1)\n",
+		"Files at the root: README.md, go.mod\n",
+		"Most referenced source files:\nstore/store.go\n  type Store struct{}\n  func (s *Store) Save(record string) error\napi/api.go\n  func Serve(s *store.Store)\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected the map to contain %q:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"helper", ".hidden", "Generated", "store_test.go"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("Expected no %q in the map:\n%s", unwanted, got)
+		}
+	}
+
+	if got, err := Build(root, Options{MaxFiles: 100, CentralFiles: 1, MaxBytes: 100}); err != nil || len(got) > 150 || !strings.Contains(got, "[repository map truncated]") {
+		t.Errorf("Expected a truncated map, got %q, %v", got, err)
+	}
+	if got, err := Build(t.TempDir(), DefaultOptions); err != nil || got != "" {
+		t.Errorf("Expected no map of an empty directory, got %q, %v", got, err)
+	}
+}
@@ -0,0 +1,75 @@
+package review
+
+import (
+	"fmt"
+	"io"
+
+	"tiny-trae/internal/agent"
+)
+
+// Collector is a non-interactive agent.Frontend that keeps the agent's last
+// answer for parsing. Progress is written to Log, which may be nil.
+type Collector struct {
+	Log    io.Writer
+	answer string
+	err    string
+}
+
+// Answer returns the last text the agent sent.
+func (c *Collector) Answer() string {
+	return c.answer
+}
+
+// Err returns the last error the agent reported, or "" if none.
+func (c *Collector) Err() string {
+	return c.err
+}
+
+// SendMessage implements agent.Frontend.
+func (c *Collector) SendMessage(msg agent.Message) {
+	switch msg.Type {
+	case agent.MessageTypeAssistant:
+		c.answer = msg.Content
+	case agent.MessageTypeError:
+		c.err = msg.Content
+		c.logf("error: %s\n", msg.Content)
+	case agent.MessageTypeToolCall:
+		c.logf("%s\n", msg.Content)
+	}
+}
+
+// logf writes a progress line to Log, if set.
+func (c *Collector) logf(format string, args ...any) {
+	if c.Log != nil {
+		fmt.Fprintf(c.Log, format, args...)
+	}
+}
+
+// GetUserInput implements agent.Frontend. A review is a single request.
+func (c *Collector) GetUserInput() (string, bool) {
+	return "", false
+}
+
+// RequestApproval implements agent.Frontend. Reviews must not change
+// anything, so every tool that needs approval is denied.
+func (c *Collector) RequestApproval(req agent.ApprovalRequest) agent.ApprovalDecision {
+	return agent.ApprovalDeny
+}
+
+// Interrupts implements agent.Frontend.
+func (c *Collector) Interrupts() <-chan struct{} {
+	return nil
+}
+
+// TurnCancels implements agent.Frontend.
+func (c *Collector) TurnCancels() <-chan struct{} {
+	return nil
+}
+
+// IsInteractive implements agent.Frontend.
+func (c *Collector) IsInteractive() bool {
+	return false
+}
+
+// Close implements agent.Frontend.
+func (c *Collector) Close() {}
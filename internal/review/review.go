@@ -0,0 +1,158 @@
+// Package review builds code review requests from staged git changes and
+// parses the structured comments the agent returns.
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"tiny-trae/internal/git"
+)
+
+const (
+	// maxContextFileBytes skips including files larger than this in full.
+	maxContextFileBytes = 32 * 1024
+	// maxContextBytes caps the total size of the full files included as
+	// context; the agent can read any others with its tools.
+	maxContextBytes = 128 * 1024
+)
+
+// Severity ranks how important a review comment is.
+type Severity string
+
+const (
+	SeverityError      Severity = "error"
+	SeverityWarning    Severity = "warning"
+	SeveritySuggestion Severity = "suggestion"
+)
+
+// rank orders severities from least to most important.
+var rank = map[Severity]int{
+	SeveritySuggestion: 1,
+	SeverityWarning:    2,
+	SeverityError:      3,
+}
+
+// ParseSeverity parses a severity name.
+func ParseSeverity(s string) (Severity, error) {
+	severity := Severity(strings.ToLower(strings.TrimSpace(s)))
+	if rank[severity] == 0 {
+		return "", fmt.Errorf("unknown severity %q (want error, warning, or suggestion)", s)
+	}
+	return severity, nil
+}
+
+// Comment is one review finding.
+type Comment struct {
+	File       string   `json:"file"`
+	Line       int      `json:"line"`
+	Severity   Severity `json:"severity"`
+	Message    string   `json:"message"`
+	Suggestion string   `json:"suggestion,omitempty"`
+}
+
+// ErrNothingStaged is returned by BuildRequest when there is no staged diff.
+var ErrNothingStaged = fmt.Errorf("no staged changes to review; stage them with 'git add' first")
+
+// BuildRequest returns the message that asks the agent to review the changes
+// staged in the repository containing dir. It contains the staged diff and
+// the staged contents of the changed files, as far as they fit.
+func BuildRequest(ctx context.Context, dir string) (string, error) {
+	diff, err := git.Run(ctx, dir, "diff", "--staged", "--no-color", "--no-ext-diff")
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(diff) == "" {
+		return "", ErrNothingStaged
+	}
+	names, err := git.Run(ctx, dir, "diff", "--staged", "--name-only", "--diff-filter=AM", "-z")
+	if err != nil {
+		return "", err
+	}
+	root, err := git.Root(ctx, dir)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("Review the following staged changes.\n\n<diff>\n")
+	b.WriteString(diff)
+	b.WriteString("</diff>\n")
+
+	total := 0
+	var skipped []string
+	for _, name := range strings.Split(names, "\x00") {
+		if name == "" {
+			continue
+		}
+		// The staged version is what would be committed
+		content, err := git.Run(ctx, root, "show", ":"+name)
+		if err != nil || strings.ContainsRune(content, 0) {
+			continue
+		}
+		if len(content) > maxContextFileBytes || total+len(content) > maxContextBytes {
+			skipped = append(skipped, name)
+			continue
+		}
+		total += len(content)
+		fmt.Fprintf(&b, "\n<file path=%q>\n%s</file>\n", name, content)
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(&b, "\nThese changed files were too large to include; read them with your tools if needed: %s\n", strings.Join(skipped, ", "))
+	}
+	return b.String(), nil
+}
+
+// ParseComments extracts the JSON array of comments from the agent's final
+// answer. The array may be wrapped in prose or a Markdown code fence.
+func ParseComments(answer string) ([]Comment, error) {
+	start := strings.Index(answer, "[")
+	end := strings.LastIndex(answer, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("the review did not contain a JSON array of comments")
+	}
+
+	var comments []Comment
+	if err := json.Unmarshal([]byte(answer[start:end+1]), &comments); err != nil {
+		return nil, fmt.Errorf("failed to parse review comments: %w", err)
+	}
+	for i := range comments {
+		severity, err := ParseSeverity(string(comments[i].Severity))
+		if err != nil {
+			// Keep the comment rather than lose a finding over its label
+			severity = SeverityWarning
+		}
+		comments[i].Severity = severity
+	}
+	return comments, nil
+}
+
+// FormatText renders comments for the console, one per finding.
+func FormatText(comments []Comment) string {
+	if len(comments) == 0 {
+		return "No issues found."
+	}
+	var b strings.Builder
+	for i, c := range comments {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s:%d: %s: %s\n", c.File, c.Line, c.Severity, c.Message)
+		if c.Suggestion != "" {
+			b.WriteString("    suggestion: " + strings.ReplaceAll(strings.TrimSpace(c.Suggestion), "\n", "\n    ") + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// AtLeast reports whether any comment is at least as severe as min.
+func AtLeast(comments []Comment, min Severity) bool {
+	for _, c := range comments {
+		if rank[c.Severity] >= rank[min] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tiny-trae/internal/git"
+)
+
+func TestBuildRequest(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	ctx := context.Background()
+	if _, err := git.Run(ctx, dir, "init", "-q"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := BuildRequest(ctx, dir); !errors.Is(err, ErrNothingStaged) {
+		t.Errorf("Expected ErrNothingStaged, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git.Run(ctx, dir, "add", "main.go"); err != nil {
+		t.Fatal(err)
+	}
+	// Unstaged edits are not part of the review
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\n// unstaged\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := BuildRequest(ctx, dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(request, "+func main() {}") {
+		t.Errorf("Expected the staged diff in the request:\n%s", request)
+	}
+	if !strings.Contains(request, "<file path=\"main.go\">\npackage main\n\nfunc main() {}\n</file>") {
+		t.Errorf("Expected the staged file contents in the request:\n%s", request)
+	}
+	if strings.Contains(request, "unstaged") {
+		t.Errorf("Expected unstaged changes to be left out:\n%s", request)
+	}
+}
+
+func TestParseComments(t *testing.T) {
+	answer := "Here is my review:\n```json\n" + `[
+  {"file": "main.go", "line": 3, "severity": "Error", "message": "nil dereference", "suggestion": "check err first"},
+  {"file": "util.go", "line": 10, "severity": "critical", "message": "odd label"}
+]` + "\n```"
+	comments, err := ParseComments(answer)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(comments) != 2 {
+		t.Fatalf("Expected 2 comments, got %d", len(comments))
+	}
+	if comments[0].Severity != SeverityError || comments[0].Suggestion != "check err first" {
+		t.Errorf("Unexpected first comment %+v", comments[0])
+	}
+	if comments[1].Severity != SeverityWarning {
+		t.Errorf("Expected unknown severity to become a warning, got %q", comments[1].Severity)
+	}
+
+	if comments, err := ParseComments("[]"); err != nil || len(comments) != 0 {
+		t.Errorf("Expected no comments, got %v (%v)", comments, err)
+	}
+	if _, err := ParseComments("Looks good to me!"); err == nil {
+		t.Error("Expected error for an answer without JSON")
+	}
+}
+
+func TestFormatTextAndAtLeast(t *testing.T) {
+	comments := []Comment{
+		{File: "a.go", Line: 1, Severity: SeverityWarning, Message: "shadowed err", Suggestion: "rename it"},
+		{File: "b.go", Line: 2, Severity: SeveritySuggestion, Message: "simplify"},
+	}
+	expected := "a.go:1: warning: shadowed err\n    suggestion: rename it\n\nb.go:2: suggestion: simplify"
+	if got := FormatText(comments); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	if FormatText(nil) != "No issues found." {
+		t.Errorf("Unexpected output for no comments")
+	}
+
+	if !AtLeast(comments, SeverityWarning) || AtLeast(comments, SeverityError) {
+		t.Error("Unexpected AtLeast result")
+	}
+}
@@ -0,0 +1,1717 @@
+// The agent service of 'tiny-trae serve --grpc-addr': the chat sessions of
+// the HTTP server, for clients that want typed messages.
+//
+// A client creates a session, streams its events, and answers the
+// InputRequest and ApprovalRequest events with SendMessage and Approve.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: agent.proto
+
+package agentpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ApprovalDecision int32
+
+const (
+	ApprovalDecision_APPROVAL_DECISION_UNSPECIFIED ApprovalDecision = 0
+	ApprovalDecision_APPROVAL_DECISION_APPROVE     ApprovalDecision = 1
+	ApprovalDecision_APPROVAL_DECISION_DENY        ApprovalDecision = 2
+	// Approve this call and later calls of the same tool in the session.
+	ApprovalDecision_APPROVAL_DECISION_ALWAYS_ALLOW ApprovalDecision = 3
+)
+
+// Enum value maps for ApprovalDecision.
+var (
+	ApprovalDecision_name = map[int32]string{
+		0: "APPROVAL_DECISION_UNSPECIFIED",
+		1: "APPROVAL_DECISION_APPROVE",
+		2: "APPROVAL_DECISION_DENY",
+		3: "APPROVAL_DECISION_ALWAYS_ALLOW",
+	}
+	ApprovalDecision_value = map[string]int32{
+		"APPROVAL_DECISION_UNSPECIFIED":  0,
+		"APPROVAL_DECISION_APPROVE":      1,
+		"APPROVAL_DECISION_DENY":         2,
+		"APPROVAL_DECISION_ALWAYS_ALLOW": 3,
+	}
+)
+
+func (x ApprovalDecision) Enum() *ApprovalDecision {
+	p := new(ApprovalDecision)
+	*p = x
+	return p
+}
+
+func (x ApprovalDecision) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ApprovalDecision) Descriptor() protoreflect.EnumDescriptor {
+	return file_agent_proto_enumTypes[0].Descriptor()
+}
+
+func (ApprovalDecision) Type() protoreflect.EnumType {
+	return &file_agent_proto_enumTypes[0]
+}
+
+func (x ApprovalDecision) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ApprovalDecision.Descriptor instead.
+func (ApprovalDecision) EnumDescriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{0}
+}
+
+type CreateSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSessionRequest) Reset() {
+	*x = CreateSessionRequest{}
+	mi := &file_agent_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSessionRequest) ProtoMessage() {}
+
+func (x *CreateSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSessionRequest.ProtoReflect.Descriptor instead.
+func (*CreateSessionRequest) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{0}
+}
+
+type CreateSessionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSessionResponse) Reset() {
+	*x = CreateSessionResponse{}
+	mi := &file_agent_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSessionResponse) ProtoMessage() {}
+
+func (x *CreateSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSessionResponse.ProtoReflect.Descriptor instead.
+func (*CreateSessionResponse) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CreateSessionResponse) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type StreamEventsRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	SessionId string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	// after_id resumes a stream after the event with this ID.
+	AfterId       int64 `protobuf:"varint,2,opt,name=after_id,json=afterId,proto3" json:"after_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamEventsRequest) Reset() {
+	*x = StreamEventsRequest{}
+	mi := &file_agent_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEventsRequest) ProtoMessage() {}
+
+func (x *StreamEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEventsRequest.ProtoReflect.Descriptor instead.
+func (*StreamEventsRequest) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *StreamEventsRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *StreamEventsRequest) GetAfterId() int64 {
+	if x != nil {
+		return x.AfterId
+	}
+	return 0
+}
+
+type SendMessageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendMessageRequest) Reset() {
+	*x = SendMessageRequest{}
+	mi := &file_agent_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendMessageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendMessageRequest) ProtoMessage() {}
+
+func (x *SendMessageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendMessageRequest.ProtoReflect.Descriptor instead.
+func (*SendMessageRequest) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SendMessageRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *SendMessageRequest) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type SendMessageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendMessageResponse) Reset() {
+	*x = SendMessageResponse{}
+	mi := &file_agent_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendMessageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendMessageResponse) ProtoMessage() {}
+
+func (x *SendMessageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendMessageResponse.ProtoReflect.Descriptor instead.
+func (*SendMessageResponse) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{4}
+}
+
+type ApproveRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Decision      ApprovalDecision       `protobuf:"varint,2,opt,name=decision,proto3,enum=tinytrae.agent.v1.ApprovalDecision" json:"decision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApproveRequest) Reset() {
+	*x = ApproveRequest{}
+	mi := &file_agent_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApproveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApproveRequest) ProtoMessage() {}
+
+func (x *ApproveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApproveRequest.ProtoReflect.Descriptor instead.
+func (*ApproveRequest) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ApproveRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ApproveRequest) GetDecision() ApprovalDecision {
+	if x != nil {
+		return x.Decision
+	}
+	return ApprovalDecision_APPROVAL_DECISION_UNSPECIFIED
+}
+
+type ApproveResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApproveResponse) Reset() {
+	*x = ApproveResponse{}
+	mi := &file_agent_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApproveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApproveResponse) ProtoMessage() {}
+
+func (x *ApproveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApproveResponse.ProtoReflect.Descriptor instead.
+func (*ApproveResponse) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{6}
+}
+
+type InterruptRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InterruptRequest) Reset() {
+	*x = InterruptRequest{}
+	mi := &file_agent_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InterruptRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InterruptRequest) ProtoMessage() {}
+
+func (x *InterruptRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InterruptRequest.ProtoReflect.Descriptor instead.
+func (*InterruptRequest) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *InterruptRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type InterruptResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InterruptResponse) Reset() {
+	*x = InterruptResponse{}
+	mi := &file_agent_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InterruptResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InterruptResponse) ProtoMessage() {}
+
+func (x *InterruptResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InterruptResponse.ProtoReflect.Descriptor instead.
+func (*InterruptResponse) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{8}
+}
+
+type CancelTurnRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelTurnRequest) Reset() {
+	*x = CancelTurnRequest{}
+	mi := &file_agent_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelTurnRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelTurnRequest) ProtoMessage() {}
+
+func (x *CancelTurnRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelTurnRequest.ProtoReflect.Descriptor instead.
+func (*CancelTurnRequest) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CancelTurnRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type CancelTurnResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelTurnResponse) Reset() {
+	*x = CancelTurnResponse{}
+	mi := &file_agent_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelTurnResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelTurnResponse) ProtoMessage() {}
+
+func (x *CancelTurnResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelTurnResponse.ProtoReflect.Descriptor instead.
+func (*CancelTurnResponse) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{10}
+}
+
+type EndSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EndSessionRequest) Reset() {
+	*x = EndSessionRequest{}
+	mi := &file_agent_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EndSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EndSessionRequest) ProtoMessage() {}
+
+func (x *EndSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EndSessionRequest.ProtoReflect.Descriptor instead.
+func (*EndSessionRequest) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *EndSessionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type EndSessionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EndSessionResponse) Reset() {
+	*x = EndSessionResponse{}
+	mi := &file_agent_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EndSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EndSessionResponse) ProtoMessage() {}
+
+func (x *EndSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EndSessionResponse.ProtoReflect.Descriptor instead.
+func (*EndSessionResponse) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{12}
+}
+
+// Event is an event of a session.
+type Event struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id numbers the session's events from 1.
+	Id int64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*Event_UserInput
+	//	*Event_AssistantDelta
+	//	*Event_Assistant
+	//	*Event_Thinking
+	//	*Event_ToolCall
+	//	*Event_ToolOutput
+	//	*Event_ToolResult
+	//	*Event_Error
+	//	*Event_SystemInfo
+	//	*Event_Usage
+	//	*Event_Request
+	//	*Event_InputRequest
+	//	*Event_ApprovalRequest
+	//	*Event_SessionEnd
+	Payload       isEvent_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_agent_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Event) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Event) GetPayload() isEvent_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *Event) GetUserInput() *Text {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_UserInput); ok {
+			return x.UserInput
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetAssistantDelta() *Text {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_AssistantDelta); ok {
+			return x.AssistantDelta
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetAssistant() *Text {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_Assistant); ok {
+			return x.Assistant
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetThinking() *Text {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_Thinking); ok {
+			return x.Thinking
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetToolCall() *ToolCall {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_ToolCall); ok {
+			return x.ToolCall
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetToolOutput() *ToolOutput {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_ToolOutput); ok {
+			return x.ToolOutput
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetToolResult() *ToolResult {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_ToolResult); ok {
+			return x.ToolResult
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetError() *Text {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_Error); ok {
+			return x.Error
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetSystemInfo() *Text {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_SystemInfo); ok {
+			return x.SystemInfo
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetUsage() *Usage {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_Usage); ok {
+			return x.Usage
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetRequest() *Request {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_Request); ok {
+			return x.Request
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetInputRequest() *InputRequest {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_InputRequest); ok {
+			return x.InputRequest
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetApprovalRequest() *ToolCall {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_ApprovalRequest); ok {
+			return x.ApprovalRequest
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetSessionEnd() *SessionEnd {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_SessionEnd); ok {
+			return x.SessionEnd
+		}
+	}
+	return nil
+}
+
+type isEvent_Payload interface {
+	isEvent_Payload()
+}
+
+type Event_UserInput struct {
+	// The user's message.
+	UserInput *Text `protobuf:"bytes,2,opt,name=user_input,json=userInput,proto3,oneof"`
+}
+
+type Event_AssistantDelta struct {
+	// The next piece of a reply being streamed; the whole reply follows as
+	// assistant.
+	AssistantDelta *Text `protobuf:"bytes,3,opt,name=assistant_delta,json=assistantDelta,proto3,oneof"`
+}
+
+type Event_Assistant struct {
+	Assistant *Text `protobuf:"bytes,4,opt,name=assistant,proto3,oneof"`
+}
+
+type Event_Thinking struct {
+	// The model's extended thinking before its reply.
+	Thinking *Text `protobuf:"bytes,5,opt,name=thinking,proto3,oneof"`
+}
+
+type Event_ToolCall struct {
+	ToolCall *ToolCall `protobuf:"bytes,6,opt,name=tool_call,json=toolCall,proto3,oneof"`
+}
+
+type Event_ToolOutput struct {
+	// Output of a running tool; the whole output follows in its result.
+	ToolOutput *ToolOutput `protobuf:"bytes,7,opt,name=tool_output,json=toolOutput,proto3,oneof"`
+}
+
+type Event_ToolResult struct {
+	ToolResult *ToolResult `protobuf:"bytes,8,opt,name=tool_result,json=toolResult,proto3,oneof"`
+}
+
+type Event_Error struct {
+	Error *Text `protobuf:"bytes,9,opt,name=error,proto3,oneof"`
+}
+
+type Event_SystemInfo struct {
+	SystemInfo *Text `protobuf:"bytes,10,opt,name=system_info,json=systemInfo,proto3,oneof"`
+}
+
+type Event_Usage struct {
+	Usage *Usage `protobuf:"bytes,11,opt,name=usage,proto3,oneof"`
+}
+
+type Event_Request struct {
+	// A request to the model started.
+	Request *Request `protobuf:"bytes,12,opt,name=request,proto3,oneof"`
+}
+
+type Event_InputRequest struct {
+	// The agent waits for the next message.
+	InputRequest *InputRequest `protobuf:"bytes,13,opt,name=input_request,json=inputRequest,proto3,oneof"`
+}
+
+type Event_ApprovalRequest struct {
+	// A tool call waits for approval.
+	ApprovalRequest *ToolCall `protobuf:"bytes,14,opt,name=approval_request,json=approvalRequest,proto3,oneof"`
+}
+
+type Event_SessionEnd struct {
+	// The last event of the session.
+	SessionEnd *SessionEnd `protobuf:"bytes,15,opt,name=session_end,json=sessionEnd,proto3,oneof"`
+}
+
+func (*Event_UserInput) isEvent_Payload() {}
+
+func (*Event_AssistantDelta) isEvent_Payload() {}
+
+func (*Event_Assistant) isEvent_Payload() {}
+
+func (*Event_Thinking) isEvent_Payload() {}
+
+func (*Event_ToolCall) isEvent_Payload() {}
+
+func (*Event_ToolOutput) isEvent_Payload() {}
+
+func (*Event_ToolResult) isEvent_Payload() {}
+
+func (*Event_Error) isEvent_Payload() {}
+
+func (*Event_SystemInfo) isEvent_Payload() {}
+
+func (*Event_Usage) isEvent_Payload() {}
+
+func (*Event_Request) isEvent_Payload() {}
+
+func (*Event_InputRequest) isEvent_Payload() {}
+
+func (*Event_ApprovalRequest) isEvent_Payload() {}
+
+func (*Event_SessionEnd) isEvent_Payload() {}
+
+type Text struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Text) Reset() {
+	*x = Text{}
+	mi := &file_agent_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Text) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Text) ProtoMessage() {}
+
+func (x *Text) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Text.ProtoReflect.Descriptor instead.
+func (*Text) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Text) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type ToolCall struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ToolName string                 `protobuf:"bytes,1,opt,name=tool_name,json=toolName,proto3" json:"tool_name,omitempty"`
+	ToolId   string                 `protobuf:"bytes,2,opt,name=tool_id,json=toolId,proto3" json:"tool_id,omitempty"`
+	// input_json is the tool's input as JSON.
+	InputJson string `protobuf:"bytes,3,opt,name=input_json,json=inputJson,proto3" json:"input_json,omitempty"`
+	// preview is what the tool shows for the input, such as an edit's diff.
+	Preview       string `protobuf:"bytes,4,opt,name=preview,proto3" json:"preview,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ToolCall) Reset() {
+	*x = ToolCall{}
+	mi := &file_agent_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToolCall) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolCall) ProtoMessage() {}
+
+func (x *ToolCall) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolCall.ProtoReflect.Descriptor instead.
+func (*ToolCall) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ToolCall) GetToolName() string {
+	if x != nil {
+		return x.ToolName
+	}
+	return ""
+}
+
+func (x *ToolCall) GetToolId() string {
+	if x != nil {
+		return x.ToolId
+	}
+	return ""
+}
+
+func (x *ToolCall) GetInputJson() string {
+	if x != nil {
+		return x.InputJson
+	}
+	return ""
+}
+
+func (x *ToolCall) GetPreview() string {
+	if x != nil {
+		return x.Preview
+	}
+	return ""
+}
+
+type ToolOutput struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ToolName      string                 `protobuf:"bytes,1,opt,name=tool_name,json=toolName,proto3" json:"tool_name,omitempty"`
+	ToolId        string                 `protobuf:"bytes,2,opt,name=tool_id,json=toolId,proto3" json:"tool_id,omitempty"`
+	Text          string                 `protobuf:"bytes,3,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ToolOutput) Reset() {
+	*x = ToolOutput{}
+	mi := &file_agent_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToolOutput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolOutput) ProtoMessage() {}
+
+func (x *ToolOutput) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolOutput.ProtoReflect.Descriptor instead.
+func (*ToolOutput) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ToolOutput) GetToolName() string {
+	if x != nil {
+		return x.ToolName
+	}
+	return ""
+}
+
+func (x *ToolOutput) GetToolId() string {
+	if x != nil {
+		return x.ToolId
+	}
+	return ""
+}
+
+func (x *ToolOutput) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type ToolResult struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ToolName string                 `protobuf:"bytes,1,opt,name=tool_name,json=toolName,proto3" json:"tool_name,omitempty"`
+	ToolId   string                 `protobuf:"bytes,2,opt,name=tool_id,json=toolId,proto3" json:"tool_id,omitempty"`
+	Result   string                 `protobuf:"bytes,3,opt,name=result,proto3" json:"result,omitempty"`
+	IsError  bool                   `protobuf:"varint,4,opt,name=is_error,json=isError,proto3" json:"is_error,omitempty"`
+	// meta is unset for calls that did not run.
+	Meta          *ResultMeta `protobuf:"bytes,5,opt,name=meta,proto3" json:"meta,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ToolResult) Reset() {
+	*x = ToolResult{}
+	mi := &file_agent_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToolResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolResult) ProtoMessage() {}
+
+func (x *ToolResult) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolResult.ProtoReflect.Descriptor instead.
+func (*ToolResult) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ToolResult) GetToolName() string {
+	if x != nil {
+		return x.ToolName
+	}
+	return ""
+}
+
+func (x *ToolResult) GetToolId() string {
+	if x != nil {
+		return x.ToolId
+	}
+	return ""
+}
+
+func (x *ToolResult) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+func (x *ToolResult) GetIsError() bool {
+	if x != nil {
+		return x.IsError
+	}
+	return false
+}
+
+func (x *ToolResult) GetMeta() *ResultMeta {
+	if x != nil {
+		return x.Meta
+	}
+	return nil
+}
+
+type ResultMeta struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Bytes      int64                  `protobuf:"varint,1,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	DurationMs int64                  `protobuf:"varint,2,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	Truncated  bool                   `protobuf:"varint,3,opt,name=truncated,proto3" json:"truncated,omitempty"`
+	// exit_code is the exit status of the command the tool ran, if any.
+	ExitCode      *int32   `protobuf:"varint,4,opt,name=exit_code,json=exitCode,proto3,oneof" json:"exit_code,omitempty"`
+	FilesChanged  []string `protobuf:"bytes,5,rep,name=files_changed,json=filesChanged,proto3" json:"files_changed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResultMeta) Reset() {
+	*x = ResultMeta{}
+	mi := &file_agent_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResultMeta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResultMeta) ProtoMessage() {}
+
+func (x *ResultMeta) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResultMeta.ProtoReflect.Descriptor instead.
+func (*ResultMeta) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ResultMeta) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *ResultMeta) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *ResultMeta) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+func (x *ResultMeta) GetExitCode() int32 {
+	if x != nil && x.ExitCode != nil {
+		return *x.ExitCode
+	}
+	return 0
+}
+
+func (x *ResultMeta) GetFilesChanged() []string {
+	if x != nil {
+		return x.FilesChanged
+	}
+	return nil
+}
+
+type Usage struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Profile      string                 `protobuf:"bytes,1,opt,name=profile,proto3" json:"profile,omitempty"`
+	Model        string                 `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"`
+	Turn         int64                  `protobuf:"varint,3,opt,name=turn,proto3" json:"turn,omitempty"`
+	InputTokens  int64                  `protobuf:"varint,4,opt,name=input_tokens,json=inputTokens,proto3" json:"input_tokens,omitempty"`
+	OutputTokens int64                  `protobuf:"varint,5,opt,name=output_tokens,json=outputTokens,proto3" json:"output_tokens,omitempty"`
+	// cost is the estimated cost in US dollars.
+	Cost          float64 `protobuf:"fixed64,6,opt,name=cost,proto3" json:"cost,omitempty"`
+	ContextTokens int64   `protobuf:"varint,7,opt,name=context_tokens,json=contextTokens,proto3" json:"context_tokens,omitempty"`
+	ContextWindow int64   `protobuf:"varint,8,opt,name=context_window,json=contextWindow,proto3" json:"context_window,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Usage) Reset() {
+	*x = Usage{}
+	mi := &file_agent_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Usage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Usage) ProtoMessage() {}
+
+func (x *Usage) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Usage.ProtoReflect.Descriptor instead.
+func (*Usage) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Usage) GetProfile() string {
+	if x != nil {
+		return x.Profile
+	}
+	return ""
+}
+
+func (x *Usage) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *Usage) GetTurn() int64 {
+	if x != nil {
+		return x.Turn
+	}
+	return 0
+}
+
+func (x *Usage) GetInputTokens() int64 {
+	if x != nil {
+		return x.InputTokens
+	}
+	return 0
+}
+
+func (x *Usage) GetOutputTokens() int64 {
+	if x != nil {
+		return x.OutputTokens
+	}
+	return 0
+}
+
+func (x *Usage) GetCost() float64 {
+	if x != nil {
+		return x.Cost
+	}
+	return 0
+}
+
+func (x *Usage) GetContextTokens() int64 {
+	if x != nil {
+		return x.ContextTokens
+	}
+	return 0
+}
+
+func (x *Usage) GetContextWindow() int64 {
+	if x != nil {
+		return x.ContextWindow
+	}
+	return 0
+}
+
+type Request struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// tokens estimates the size of the request.
+	Tokens        int64 `protobuf:"varint,1,opt,name=tokens,proto3" json:"tokens,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Request) Reset() {
+	*x = Request{}
+	mi := &file_agent_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Request) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Request) ProtoMessage() {}
+
+func (x *Request) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Request.ProtoReflect.Descriptor instead.
+func (*Request) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *Request) GetTokens() int64 {
+	if x != nil {
+		return x.Tokens
+	}
+	return 0
+}
+
+type InputRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InputRequest) Reset() {
+	*x = InputRequest{}
+	mi := &file_agent_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InputRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InputRequest) ProtoMessage() {}
+
+func (x *InputRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InputRequest.ProtoReflect.Descriptor instead.
+func (*InputRequest) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{21}
+}
+
+type SessionEnd struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// error is the error that ended the session, if any.
+	Error         string `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SessionEnd) Reset() {
+	*x = SessionEnd{}
+	mi := &file_agent_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SessionEnd) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionEnd) ProtoMessage() {}
+
+func (x *SessionEnd) ProtoReflect() protoreflect.Message {
+	mi := &file_agent_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionEnd.ProtoReflect.Descriptor instead.
+func (*SessionEnd) Descriptor() ([]byte, []int) {
+	return file_agent_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *SessionEnd) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_agent_proto protoreflect.FileDescriptor
+
+const file_agent_proto_rawDesc = "" +
+	"\n" +
+	"\vagent.proto\x12\x11tinytrae.agent.v1\"\x16\n" +
+	"\x14CreateSessionRequest\"6\n" +
+	"\x15CreateSessionResponse\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"O\n" +
+	"\x13StreamEventsRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x19\n" +
+	"\bafter_id\x18\x02 \x01(\x03R\aafterId\"G\n" +
+	"\x12SendMessageRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\"\x15\n" +
+	"\x13SendMessageResponse\"p\n" +
+	"\x0eApproveRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12?\n" +
+	"\bdecision\x18\x02 \x01(\x0e2#.tinytrae.agent.v1.ApprovalDecisionR\bdecision\"\x11\n" +
+	"\x0fApproveResponse\"1\n" +
+	"\x10InterruptRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"\x13\n" +
+	"\x11InterruptResponse\"2\n" +
+	"\x11CancelTurnRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"\x14\n" +
+	"\x12CancelTurnResponse\"2\n" +
+	"\x11EndSessionRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"\x14\n" +
+	"\x12EndSessionResponse\"\xfb\x06\n" +
+	"\x05Event\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x128\n" +
+	"\n" +
+	"user_input\x18\x02 \x01(\v2\x17.tinytrae.agent.v1.TextH\x00R\tuserInput\x12B\n" +
+	"\x0fassistant_delta\x18\x03 \x01(\v2\x17.tinytrae.agent.v1.TextH\x00R\x0eassistantDelta\x127\n" +
+	"\tassistant\x18\x04 \x01(\v2\x17.tinytrae.agent.v1.TextH\x00R\tassistant\x125\n" +
+	"\bthinking\x18\x05 \x01(\v2\x17.tinytrae.agent.v1.TextH\x00R\bthinking\x12:\n" +
+	"\ttool_call\x18\x06 \x01(\v2\x1b.tinytrae.agent.v1.ToolCallH\x00R\btoolCall\x12@\n" +
+	"\vtool_output\x18\a \x01(\v2\x1d.tinytrae.agent.v1.ToolOutputH\x00R\n" +
+	"toolOutput\x12@\n" +
+	"\vtool_result\x18\b \x01(\v2\x1d.tinytrae.agent.v1.ToolResultH\x00R\n" +
+	"toolResult\x12/\n" +
+	"\x05error\x18\t \x01(\v2\x17.tinytrae.agent.v1.TextH\x00R\x05error\x12:\n" +
+	"\vsystem_info\x18\n" +
+	" \x01(\v2\x17.tinytrae.agent.v1.TextH\x00R\n" +
+	"systemInfo\x120\n" +
+	"\x05usage\x18\v \x01(\v2\x18.tinytrae.agent.v1.UsageH\x00R\x05usage\x126\n" +
+	"\arequest\x18\f \x01(\v2\x1a.tinytrae.agent.v1.RequestH\x00R\arequest\x12F\n" +
+	"\rinput_request\x18\r \x01(\v2\x1f.tinytrae.agent.v1.InputRequestH\x00R\finputRequest\x12H\n" +
+	"\x10approval_request\x18\x0e \x01(\v2\x1b.tinytrae.agent.v1.ToolCallH\x00R\x0fapprovalRequest\x12@\n" +
+	"\vsession_end\x18\x0f \x01(\v2\x1d.tinytrae.agent.v1.SessionEndH\x00R\n" +
+	"sessionEndB\t\n" +
+	"\apayload\"\x1a\n" +
+	"\x04Text\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\"y\n" +
+	"\bToolCall\x12\x1b\n" +
+	"\ttool_name\x18\x01 \x01(\tR\btoolName\x12\x17\n" +
+	"\atool_id\x18\x02 \x01(\tR\x06toolId\x12\x1d\n" +
+	"\n" +
+	"input_json\x18\x03 \x01(\tR\tinputJson\x12\x18\n" +
+	"\apreview\x18\x04 \x01(\tR\apreview\"V\n" +
+	"\n" +
+	"ToolOutput\x12\x1b\n" +
+	"\ttool_name\x18\x01 \x01(\tR\btoolName\x12\x17\n" +
+	"\atool_id\x18\x02 \x01(\tR\x06toolId\x12\x12\n" +
+	"\x04text\x18\x03 \x01(\tR\x04text\"\xa8\x01\n" +
+	"\n" +
+	"ToolResult\x12\x1b\n" +
+	"\ttool_name\x18\x01 \x01(\tR\btoolName\x12\x17\n" +
+	"\atool_id\x18\x02 \x01(\tR\x06toolId\x12\x16\n" +
+	"\x06result\x18\x03 \x01(\tR\x06result\x12\x19\n" +
+	"\bis_error\x18\x04 \x01(\bR\aisError\x121\n" +
+	"\x04meta\x18\x05 \x01(\v2\x1d.tinytrae.agent.v1.ResultMetaR\x04meta\"\xb6\x01\n" +
+	"\n" +
+	"ResultMeta\x12\x14\n" +
+	"\x05bytes\x18\x01 \x01(\x03R\x05bytes\x12\x1f\n" +
+	"\vduration_ms\x18\x02 \x01(\x03R\n" +
+	"durationMs\x12\x1c\n" +
+	"\ttruncated\x18\x03 \x01(\bR\ttruncated\x12 \n" +
+	"\texit_code\x18\x04 \x01(\x05H\x00R\bexitCode\x88\x01\x01\x12#\n" +
+	"\rfiles_changed\x18\x05 \x03(\tR\ffilesChangedB\f\n" +
+	"\n" +
+	"_exit_code\"\xf5\x01\n" +
+	"\x05Usage\x12\x18\n" +
+	"\aprofile\x18\x01 \x01(\tR\aprofile\x12\x14\n" +
+	"\x05model\x18\x02 \x01(\tR\x05model\x12\x12\n" +
+	"\x04turn\x18\x03 \x01(\x03R\x04turn\x12!\n" +
+	"\finput_tokens\x18\x04 \x01(\x03R\vinputTokens\x12#\n" +
+	"\routput_tokens\x18\x05 \x01(\x03R\foutputTokens\x12\x12\n" +
+	"\x04cost\x18\x06 \x01(\x01R\x04cost\x12%\n" +
+	"\x0econtext_tokens\x18\a \x01(\x03R\rcontextTokens\x12%\n" +
+	"\x0econtext_window\x18\b \x01(\x03R\rcontextWindow\"!\n" +
+	"\aRequest\x12\x16\n" +
+	"\x06tokens\x18\x01 \x01(\x03R\x06tokens\"\x0e\n" +
+	"\fInputRequest\"\"\n" +
+	"\n" +
+	"SessionEnd\x12\x14\n" +
+	"\x05error\x18\x01 \x01(\tR\x05error*\x94\x01\n" +
+	"\x10ApprovalDecision\x12!\n" +
+	"\x1dAPPROVAL_DECISION_UNSPECIFIED\x10\x00\x12\x1d\n" +
+	"\x19APPROVAL_DECISION_APPROVE\x10\x01\x12\x1a\n" +
+	"\x16APPROVAL_DECISION_DENY\x10\x02\x12\"\n" +
+	"\x1eAPPROVAL_DECISION_ALWAYS_ALLOW\x10\x032\x84\x05\n" +
+	"\fAgentService\x12b\n" +
+	"\rCreateSession\x12'.tinytrae.agent.v1.CreateSessionRequest\x1a(.tinytrae.agent.v1.CreateSessionResponse\x12R\n" +
+	"\fStreamEvents\x12&.tinytrae.agent.v1.StreamEventsRequest\x1a\x18.tinytrae.agent.v1.Event0\x01\x12\\\n" +
+	"\vSendMessage\x12%.tinytrae.agent.v1.SendMessageRequest\x1a&.tinytrae.agent.v1.SendMessageResponse\x12P\n" +
+	"\aApprove\x12!.tinytrae.agent.v1.ApproveRequest\x1a\".tinytrae.agent.v1.ApproveResponse\x12V\n" +
+	"\tInterrupt\x12#.tinytrae.agent.v1.InterruptRequest\x1a$.tinytrae.agent.v1.InterruptResponse\x12Y\n" +
+	"\n" +
+	"CancelTurn\x12$.tinytrae.agent.v1.CancelTurnRequest\x1a%.tinytrae.agent.v1.CancelTurnResponse\x12Y\n" +
+	"\n" +
+	"EndSession\x12$.tinytrae.agent.v1.EndSessionRequest\x1a%.tinytrae.agent.v1.EndSessionResponseB Z\x1etiny-trae/internal/rpc/agentpbb\x06proto3"
+
+var (
+	file_agent_proto_rawDescOnce sync.Once
+	file_agent_proto_rawDescData []byte
+)
+
+func file_agent_proto_rawDescGZIP() []byte {
+	file_agent_proto_rawDescOnce.Do(func() {
+		file_agent_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_agent_proto_rawDesc), len(file_agent_proto_rawDesc)))
+	})
+	return file_agent_proto_rawDescData
+}
+
+var file_agent_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 23)
+var file_agent_proto_goTypes = []any{
+	(ApprovalDecision)(0),         // 0: tinytrae.agent.v1.ApprovalDecision
+	(*CreateSessionRequest)(nil),  // 1: tinytrae.agent.v1.CreateSessionRequest
+	(*CreateSessionResponse)(nil), // 2: tinytrae.agent.v1.CreateSessionResponse
+	(*StreamEventsRequest)(nil),   // 3: tinytrae.agent.v1.StreamEventsRequest
+	(*SendMessageRequest)(nil),    // 4: tinytrae.agent.v1.SendMessageRequest
+	(*SendMessageResponse)(nil),   // 5: tinytrae.agent.v1.SendMessageResponse
+	(*ApproveRequest)(nil),        // 6: tinytrae.agent.v1.ApproveRequest
+	(*ApproveResponse)(nil),       // 7: tinytrae.agent.v1.ApproveResponse
+	(*InterruptRequest)(nil),      // 8: tinytrae.agent.v1.InterruptRequest
+	(*InterruptResponse)(nil),     // 9: tinytrae.agent.v1.InterruptResponse
+	(*CancelTurnRequest)(nil),     // 10: tinytrae.agent.v1.CancelTurnRequest
+	(*CancelTurnResponse)(nil),    // 11: tinytrae.agent.v1.CancelTurnResponse
+	(*EndSessionRequest)(nil),     // 12: tinytrae.agent.v1.EndSessionRequest
+	(*EndSessionResponse)(nil),    // 13: tinytrae.agent.v1.EndSessionResponse
+	(*Event)(nil),                 // 14: tinytrae.agent.v1.Event
+	(*Text)(nil),                  // 15: tinytrae.agent.v1.Text
+	(*ToolCall)(nil),              // 16: tinytrae.agent.v1.ToolCall
+	(*ToolOutput)(nil),            // 17: tinytrae.agent.v1.ToolOutput
+	(*ToolResult)(nil),            // 18: tinytrae.agent.v1.ToolResult
+	(*ResultMeta)(nil),            // 19: tinytrae.agent.v1.ResultMeta
+	(*Usage)(nil),                 // 20: tinytrae.agent.v1.Usage
+	(*Request)(nil),               // 21: tinytrae.agent.v1.Request
+	(*InputRequest)(nil),          // 22: tinytrae.agent.v1.InputRequest
+	(*SessionEnd)(nil),            // 23: tinytrae.agent.v1.SessionEnd
+}
+var file_agent_proto_depIdxs = []int32{
+	0,  // 0: tinytrae.agent.v1.ApproveRequest.decision:type_name -> tinytrae.agent.v1.ApprovalDecision
+	15, // 1: tinytrae.agent.v1.Event.user_input:type_name -> tinytrae.agent.v1.Text
+	15, // 2: tinytrae.agent.v1.Event.assistant_delta:type_name -> tinytrae.agent.v1.Text
+	15, // 3: tinytrae.agent.v1.Event.assistant:type_name -> tinytrae.agent.v1.Text
+	15, // 4: tinytrae.agent.v1.Event.thinking:type_name -> tinytrae.agent.v1.Text
+	16, // 5: tinytrae.agent.v1.Event.tool_call:type_name -> tinytrae.agent.v1.ToolCall
+	17, // 6: tinytrae.agent.v1.Event.tool_output:type_name -> tinytrae.agent.v1.ToolOutput
+	18, // 7: tinytrae.agent.v1.Event.tool_result:type_name -> tinytrae.agent.v1.ToolResult
+	15, // 8: tinytrae.agent.v1.Event.error:type_name -> tinytrae.agent.v1.Text
+	15, // 9: tinytrae.agent.v1.Event.system_info:type_name -> tinytrae.agent.v1.Text
+	20, // 10: tinytrae.agent.v1.Event.usage:type_name -> tinytrae.agent.v1.Usage
+	21, // 11: tinytrae.agent.v1.Event.request:type_name -> tinytrae.agent.v1.Request
+	22, // 12: tinytrae.agent.v1.Event.input_request:type_name -> tinytrae.agent.v1.InputRequest
+	16, // 13: tinytrae.agent.v1.Event.approval_request:type_name -> tinytrae.agent.v1.ToolCall
+	23, // 14: tinytrae.agent.v1.Event.session_end:type_name -> tinytrae.agent.v1.SessionEnd
+	19, // 15: tinytrae.agent.v1.ToolResult.meta:type_name -> tinytrae.agent.v1.ResultMeta
+	1,  // 16: tinytrae.agent.v1.AgentService.CreateSession:input_type -> tinytrae.agent.v1.CreateSessionRequest
+	3,  // 17: tinytrae.agent.v1.AgentService.StreamEvents:input_type -> tinytrae.agent.v1.StreamEventsRequest
+	4,  // 18: tinytrae.agent.v1.AgentService.SendMessage:input_type -> tinytrae.agent.v1.SendMessageRequest
+	6,  // 19: tinytrae.agent.v1.AgentService.Approve:input_type -> tinytrae.agent.v1.ApproveRequest
+	8,  // 20: tinytrae.agent.v1.AgentService.Interrupt:input_type -> tinytrae.agent.v1.InterruptRequest
+	10, // 21: tinytrae.agent.v1.AgentService.CancelTurn:input_type -> tinytrae.agent.v1.CancelTurnRequest
+	12, // 22: tinytrae.agent.v1.AgentService.EndSession:input_type -> tinytrae.agent.v1.EndSessionRequest
+	2,  // 23: tinytrae.agent.v1.AgentService.CreateSession:output_type -> tinytrae.agent.v1.CreateSessionResponse
+	14, // 24: tinytrae.agent.v1.AgentService.StreamEvents:output_type -> tinytrae.agent.v1.Event
+	5,  // 25: tinytrae.agent.v1.AgentService.SendMessage:output_type -> tinytrae.agent.v1.SendMessageResponse
+	7,  // 26: tinytrae.agent.v1.AgentService.Approve:output_type -> tinytrae.agent.v1.ApproveResponse
+	9,  // 27: tinytrae.agent.v1.AgentService.Interrupt:output_type -> tinytrae.agent.v1.InterruptResponse
+	11, // 28: tinytrae.agent.v1.AgentService.CancelTurn:output_type -> tinytrae.agent.v1.CancelTurnResponse
+	13, // 29: tinytrae.agent.v1.AgentService.EndSession:output_type -> tinytrae.agent.v1.EndSessionResponse
+	23, // [23:30] is the sub-list for method output_type
+	16, // [16:23] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	16, // [16:16] is the sub-list for extension extendee
+	0,  // [0:16] is the sub-list for field type_name
+}
+
+func init() { file_agent_proto_init() }
+func file_agent_proto_init() {
+	if File_agent_proto != nil {
+		return
+	}
+	file_agent_proto_msgTypes[13].OneofWrappers = []any{
+		(*Event_UserInput)(nil),
+		(*Event_AssistantDelta)(nil),
+		(*Event_Assistant)(nil),
+		(*Event_Thinking)(nil),
+		(*Event_ToolCall)(nil),
+		(*Event_ToolOutput)(nil),
+		(*Event_ToolResult)(nil),
+		(*Event_Error)(nil),
+		(*Event_SystemInfo)(nil),
+		(*Event_Usage)(nil),
+		(*Event_Request)(nil),
+		(*Event_InputRequest)(nil),
+		(*Event_ApprovalRequest)(nil),
+		(*Event_SessionEnd)(nil),
+	}
+	file_agent_proto_msgTypes[18].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_agent_proto_rawDesc), len(file_agent_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   23,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_agent_proto_goTypes,
+		DependencyIndexes: file_agent_proto_depIdxs,
+		EnumInfos:         file_agent_proto_enumTypes,
+		MessageInfos:      file_agent_proto_msgTypes,
+	}.Build()
+	File_agent_proto = out.File
+	file_agent_proto_goTypes = nil
+	file_agent_proto_depIdxs = nil
+}
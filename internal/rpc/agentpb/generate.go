@@ -0,0 +1,5 @@
+// Package agentpb holds the agent service's protocol buffer definitions,
+// agent.proto, and the Go code generated from them.
+package agentpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative agent.proto
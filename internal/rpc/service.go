@@ -0,0 +1,192 @@
+// Package rpc serves the HTTP server's chat sessions over gRPC as well,
+// with the typed messages of agentpb/agent.proto, for non-Go clients.
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/rpc/agentpb"
+	"tiny-trae/internal/server"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Service implements agentpb.AgentServiceServer on a server's sessions.
+type Service struct {
+	agentpb.UnimplementedAgentServiceServer
+	sessions *server.Server
+}
+
+// Register registers a Service for sessions with registrar, such as a
+// *grpc.Server.
+func Register(registrar grpc.ServiceRegistrar, sessions *server.Server) {
+	agentpb.RegisterAgentServiceServer(registrar, &Service{sessions: sessions})
+}
+
+// decisions maps the protocol's approval decisions to the agent's.
+var decisions = map[agentpb.ApprovalDecision]agent.ApprovalDecision{
+	agentpb.ApprovalDecision_APPROVAL_DECISION_APPROVE:      agent.ApprovalApprove,
+	agentpb.ApprovalDecision_APPROVAL_DECISION_DENY:         agent.ApprovalDeny,
+	agentpb.ApprovalDecision_APPROVAL_DECISION_ALWAYS_ALLOW: agent.ApprovalAlwaysAllow,
+}
+
+func (s *Service) CreateSession(ctx context.Context, req *agentpb.CreateSessionRequest) (*agentpb.CreateSessionResponse, error) {
+	id, err := s.sessions.CreateSession()
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &agentpb.CreateSessionResponse{SessionId: id}, nil
+}
+
+func (s *Service) StreamEvents(req *agentpb.StreamEventsRequest, stream grpc.ServerStreamingServer[agentpb.Event]) error {
+	err := s.sessions.Events(stream.Context(), req.GetSessionId(), int(req.GetAfterId()), func(event server.Event) error {
+		if converted := convertEvent(event); converted != nil {
+			return stream.Send(converted)
+		}
+		return nil
+	})
+	return statusError(err)
+}
+
+func (s *Service) SendMessage(ctx context.Context, req *agentpb.SendMessageRequest) (*agentpb.SendMessageResponse, error) {
+	if err := s.sessions.SendMessage(req.GetSessionId(), req.GetText()); err != nil {
+		return nil, statusError(err)
+	}
+	return &agentpb.SendMessageResponse{}, nil
+}
+
+func (s *Service) Approve(ctx context.Context, req *agentpb.ApproveRequest) (*agentpb.ApproveResponse, error) {
+	decision, ok := decisions[req.GetDecision()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown decision %v", req.GetDecision())
+	}
+	if err := s.sessions.Approve(req.GetSessionId(), decision); err != nil {
+		return nil, statusError(err)
+	}
+	return &agentpb.ApproveResponse{}, nil
+}
+
+func (s *Service) Interrupt(ctx context.Context, req *agentpb.InterruptRequest) (*agentpb.InterruptResponse, error) {
+	if err := s.sessions.Interrupt(req.GetSessionId()); err != nil {
+		return nil, statusError(err)
+	}
+	return &agentpb.InterruptResponse{}, nil
+}
+
+func (s *Service) CancelTurn(ctx context.Context, req *agentpb.CancelTurnRequest) (*agentpb.CancelTurnResponse, error) {
+	if err := s.sessions.CancelTurn(req.GetSessionId()); err != nil {
+		return nil, statusError(err)
+	}
+	return &agentpb.CancelTurnResponse{}, nil
+}
+
+func (s *Service) EndSession(ctx context.Context, req *agentpb.EndSessionRequest) (*agentpb.EndSessionResponse, error) {
+	if err := s.sessions.EndSession(req.GetSessionId()); err != nil {
+		return nil, statusError(err)
+	}
+	return &agentpb.EndSessionResponse{}, nil
+}
+
+// statusError turns an error of the session operations into a gRPC status.
+func statusError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, server.ErrNoSession):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, server.ErrNotWaitingForInput), errors.Is(err, server.ErrNoApprovalRequest):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, server.ErrEmptyMessage), errors.Is(err, server.ErrUnknownDecision):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// convertEvent converts a session event to its protocol message, or
+// returns nil for events the protocol does not know.
+func convertEvent(event server.Event) *agentpb.Event {
+	converted := &agentpb.Event{Id: int64(event.ID)}
+	text := &agentpb.Text{Text: event.Content}
+	switch event.Type {
+	case string(agent.MessageTypeUserInput):
+		converted.Payload = &agentpb.Event_UserInput{UserInput: text}
+	case string(agent.MessageTypeAssistantDelta):
+		converted.Payload = &agentpb.Event_AssistantDelta{AssistantDelta: text}
+	case string(agent.MessageTypeAssistant):
+		converted.Payload = &agentpb.Event_Assistant{Assistant: text}
+	case string(agent.MessageTypeThinking):
+		converted.Payload = &agentpb.Event_Thinking{Thinking: text}
+	case string(agent.MessageTypeError):
+		converted.Payload = &agentpb.Event_Error{Error: text}
+	case string(agent.MessageTypeSystemInfo):
+		converted.Payload = &agentpb.Event_SystemInfo{SystemInfo: text}
+	case string(agent.MessageTypeToolCall):
+		var call agent.ToolCallData
+		json.Unmarshal(event.Data, &call)
+		converted.Payload = &agentpb.Event_ToolCall{ToolCall: &agentpb.ToolCall{
+			ToolName: call.ToolName, ToolId: call.ToolID, InputJson: string(call.Input), Preview: call.Preview,
+		}}
+	case string(agent.MessageTypeToolOutput):
+		var output agent.ToolOutputData
+		json.Unmarshal(event.Data, &output)
+		converted.Payload = &agentpb.Event_ToolOutput{ToolOutput: &agentpb.ToolOutput{
+			ToolName: output.ToolName, ToolId: output.ToolID, Text: event.Content,
+		}}
+	case string(agent.MessageTypeToolResult):
+		var result agent.ToolResultData
+		json.Unmarshal(event.Data, &result)
+		converted.Payload = &agentpb.Event_ToolResult{ToolResult: &agentpb.ToolResult{
+			ToolName: result.ToolName, ToolId: result.ToolID, Result: result.Result, IsError: result.IsError, Meta: convertMeta(result.Meta),
+		}}
+	case string(agent.MessageTypeUsage):
+		var usage agent.UsageData
+		json.Unmarshal(event.Data, &usage)
+		converted.Payload = &agentpb.Event_Usage{Usage: &agentpb.Usage{
+			Profile: usage.Profile, Model: usage.Model, Turn: int64(usage.Turn),
+			InputTokens: usage.InputTokens, OutputTokens: usage.OutputTokens, Cost: usage.Cost,
+			ContextTokens: usage.ContextTokens, ContextWindow: usage.ContextWindow,
+		}}
+	case string(agent.MessageTypeRequest):
+		var request agent.RequestData
+		json.Unmarshal(event.Data, &request)
+		converted.Payload = &agentpb.Event_Request{Request: &agentpb.Request{Tokens: request.Tokens}}
+	case server.EventInputRequest:
+		converted.Payload = &agentpb.Event_InputRequest{InputRequest: &agentpb.InputRequest{}}
+	case server.EventApprovalRequest:
+		var req agent.ApprovalRequest
+		json.Unmarshal(event.Data, &req)
+		converted.Payload = &agentpb.Event_ApprovalRequest{ApprovalRequest: &agentpb.ToolCall{
+			ToolName: req.ToolName, ToolId: req.ToolID, InputJson: string(req.Input), Preview: req.Preview,
+		}}
+	case server.EventSessionEnd:
+		converted.Payload = &agentpb.Event_SessionEnd{SessionEnd: &agentpb.SessionEnd{Error: event.Content}}
+	default:
+		return nil
+	}
+	return converted
+}
+
+// convertMeta converts a tool result's metadata, which may be nil.
+func convertMeta(meta *agent.ToolResultMeta) *agentpb.ResultMeta {
+	if meta == nil {
+		return nil
+	}
+	converted := &agentpb.ResultMeta{
+		Bytes:        int64(meta.Bytes),
+		DurationMs:   meta.DurationMs,
+		Truncated:    meta.Truncated,
+		FilesChanged: meta.FilesChanged,
+	}
+	if meta.ExitCode != nil {
+		code := int32(*meta.ExitCode)
+		converted.ExitCode = &code
+	}
+	return converted
+}
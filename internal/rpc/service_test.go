@@ -0,0 +1,108 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/rpc/agentpb"
+	"tiny-trae/internal/server"
+
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestSession(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[],"usage":{"input_tokens":1,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":1}}`,
+		`{"type":"message_stop"}`,
+	}
+	model := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			var typed struct{ Type string }
+			json.Unmarshal([]byte(event), &typed)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typed.Type, event)
+		}
+	}))
+	defer model.Close()
+
+	sessions := &server.Server{
+		Client:  agent.NewClientWithOptions(option.WithBaseURL(model.URL), option.WithAPIKey("test")),
+		Profile: &agent.Profile{Model: "claude", MaxTokens: 100},
+	}
+	defer sessions.Close()
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	Register(grpcServer, sessions)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	client := agentpb.NewAgentServiceClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	created, err := client.CreateSession(ctx, &agentpb.CreateSessionRequest{})
+	if err != nil {
+		t.Fatalf("Failed to create a session: %v", err)
+	}
+	id := created.GetSessionId()
+	stream, err := client.StreamEvents(ctx, &agentpb.StreamEventsRequest{SessionId: id})
+	if err != nil {
+		t.Fatalf("Failed to stream the events: %v", err)
+	}
+	// next returns the next event with a payload of the given kind
+	next := func(matches func(*agentpb.Event) bool) *agentpb.Event {
+		t.Helper()
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				t.Fatalf("The stream failed: %v", err)
+			}
+			if matches(event) {
+				return event
+			}
+		}
+	}
+
+	next(func(e *agentpb.Event) bool { return e.GetInputRequest() != nil })
+	_, err = client.Approve(ctx, &agentpb.ApproveRequest{SessionId: id, Decision: agentpb.ApprovalDecision_APPROVAL_DECISION_APPROVE})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected an approval with no request to fail its precondition, got %v", err)
+	}
+	if _, err := client.SendMessage(ctx, &agentpb.SendMessageRequest{SessionId: id, Text: "hi"}); err != nil {
+		t.Fatalf("Failed to send a message: %v", err)
+	}
+	if reply := next(func(e *agentpb.Event) bool { return e.GetAssistant() != nil }); reply.GetAssistant().GetText() != "Hello" {
+		t.Errorf("Unexpected reply %v", reply)
+	}
+
+	if _, err := client.EndSession(ctx, &agentpb.EndSessionRequest{SessionId: id}); err != nil {
+		t.Fatalf("Failed to end the session: %v", err)
+	}
+	next(func(e *agentpb.Event) bool { return e.GetSessionEnd() != nil })
+	if _, err := client.Interrupt(ctx, &agentpb.InterruptRequest{SessionId: id}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected an ended session to be not found, got %v", err)
+	}
+}
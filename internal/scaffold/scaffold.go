@@ -0,0 +1,167 @@
+// Package scaffold sets a repository up for tiny-trae, writing starter
+// project settings, agent instructions, and an ignore file for the team to
+// edit and check in.
+package scaffold
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Options configures the files Init writes.
+type Options struct {
+	// TestCommand is the project's test_command; if it is "", the setting
+	// is left commented out.
+	TestCommand string
+	// Force overwrites existing files instead of leaving them alone.
+	Force bool
+}
+
+// File is a file Init writes, relative to the repository root.
+type File struct {
+	Name    string
+	Content string
+}
+
+// Files returns the files Init writes.
+func Files(options Options) []File {
+	testCommand := "# test_command: make test"
+	if options.TestCommand != "" {
+		testCommand = "test_command: " + quote(options.TestCommand)
+	}
+	return []File{
+		{".trae.yaml", strings.Replace(projectTemplate, "TEST_COMMAND", testCommand, 1)},
+		{"AGENTS.md", agentsTemplate},
+		{".traeignore", ignoreTemplate},
+	}
+}
+
+// Init writes Files to dir and returns the names of those it wrote and of
+// those it skipped because they already exist.
+func Init(dir string, options Options) (written, skipped []string, err error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if options.Force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	for _, file := range Files(options) {
+		f, err := os.OpenFile(filepath.Join(dir, file.Name), flags, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			skipped = append(skipped, file.Name)
+			continue
+		}
+		if err != nil {
+			return written, skipped, err
+		}
+		_, err = f.WriteString(file.Content)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return written, skipped, fmt.Errorf("failed to write %s: %w", file.Name, err)
+		}
+		written = append(written, file.Name)
+	}
+	return written, skipped, nil
+}
+
+// TestCommand returns the command that runs the tests of the project in
+// dir, going by its go.mod or package.json, or "" if it cannot tell.
+func TestCommand(dir string) string {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+	if exists("go.mod") {
+		// run_tests summarizes go test's JSON output
+		return "go test -json ./..."
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return ""
+	}
+	var manifest struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	// npm init's placeholder fails on purpose
+	if json.Unmarshal(data, &manifest) != nil || manifest.Scripts["test"] == "" || strings.Contains(manifest.Scripts["test"], "no test specified") {
+		return ""
+	}
+	switch {
+	case exists("pnpm-lock.yaml"):
+		return "pnpm test"
+	case exists("yarn.lock"):
+		return "yarn test"
+	case exists("bun.lock"), exists("bun.lockb"):
+		return "bun run test"
+	}
+	return "npm test"
+}
+
+// quote returns s as a YAML scalar, quoted if it needs to be.
+func quote(s string) string {
+	if strings.ContainsAny(s, ":#'\"{}[],&*!|>%@`") || strings.TrimSpace(s) != s {
+		data, _ := json.Marshal(s)
+		return string(data)
+	}
+	return s
+}
+
+const projectTemplate = `# tiny-trae settings for everyone working on this repository; see
+# "Settings" in the tiny-trae README. They are merged over each user's own.
+
+# profile: coding
+
+# What the run_tests tool runs unless the model asks for another command
+TEST_COMMAND
+
+# What the build_and_lint tool runs, instead of go build and go vet
+# build_commands:
+#   - make build
+#   - make lint
+
+# Paths hidden from the agent, as in .traeignore
+# ignore:
+#   - fixtures/large/
+
+# Checked before each user's own rules; only ask and deny are allowed
+# permissions:
+#   - tool: bash
+#     match: "*deploy*"
+#     action: deny
+`
+
+const agentsTemplate = `# Agent Instructions
+
+Guidance for coding agents working in this repository, which tiny-trae
+adds to its system prompt. Keep it short and specific: what an agent
+cannot easily find out from the code itself.
+
+## Project
+
+<!-- What the project does, and where its main parts live. -->
+
+## Building and Testing
+
+<!-- The commands to build, test, and lint, and anything they need first. -->
+
+## Conventions
+
+<!-- Code style, naming, error handling, and how tests are laid out. -->
+
+## Off Limits
+
+<!-- Files or directories not to change, such as generated code. -->
+`
+
+const ignoreTemplate = `# Paths tiny-trae's tools leave alone, on top of .gitignore, in the same
+# format; see "Ignored Paths" in the tiny-trae README.
+.env
+.env.*
+*.pem
+*.key
+`
@@ -0,0 +1,60 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"tiny-trae/internal/config"
+)
+
+func TestTestCommand(t *testing.T) {
+	for _, tc := range []struct {
+		files map[string]string
+		want  string
+	}{
+		{map[string]string{"go.mod": "module example\n"}, "go test -json ./..."},
+		{map[string]string{"package.json": `{"scripts": {"test": "vitest"}}`}, "npm test"},
+		{map[string]string{"package.json": `{"scripts": {"test": "jest"}}`, "yarn.lock": ""}, "yarn test"},
+		{map[string]string{"package.json": `{"scripts": {"test": "echo \"Error: no test specified\" && exit 1"}}`}, ""},
+		{map[string]string{"package.json": `{"name": "example"}`}, ""},
+		{map[string]string{"README.md": "# Example\n"}, ""},
+	} {
+		dir := t.TempDir()
+		for name, content := range tc.files {
+			os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		}
+		if got := TestCommand(dir); got != tc.want {
+			t.Errorf("TestCommand() with %v = %q, want %q", tc.files, got, tc.want)
+		}
+	}
+}
+
+func TestInit(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "AGENTS.md"), []byte("# Ours\n"), 0o644)
+
+	written, skipped, err := Init(dir, Options{TestCommand: "make test: all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(written, []string{".trae.yaml", ".traeignore"}) || !slices.Equal(skipped, []string{"AGENTS.md"}) {
+		t.Errorf("Init() wrote %v and skipped %v", written, skipped)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "AGENTS.md")); string(data) != "# Ours\n" {
+		t.Errorf("Expected the existing AGENTS.md to be kept, got %q", data)
+	}
+
+	project, err := config.FindProject(dir)
+	if err != nil || project.TestCommand != "make test: all" {
+		t.Errorf("Expected valid project settings with the test command, got %+v, %v", project, err)
+	}
+
+	if written, _, err := Init(dir, Options{Force: true}); err != nil || len(written) != 3 {
+		t.Errorf("Expected --force to write every file, got %v, %v", written, err)
+	}
+	if project, err := config.FindProject(dir); err != nil || project.TestCommand != "" {
+		t.Errorf("Expected valid project settings without a test command, got %+v, %v", project, err)
+	}
+}
@@ -0,0 +1,232 @@
+package semantic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Embedder turns text into vectors whose cosine similarity reflects how
+// related the texts are.
+type Embedder interface {
+	// Name identifies the provider and model, e.g. "openai/text-embedding-3-small".
+	Name() string
+	// Embed returns one vector per text.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Providers lists the embedding providers NewEmbedder accepts.
+var Providers = []string{"local", "openai", "voyage", "ollama"}
+
+// NewEmbedder returns the embedder for a provider. An empty model selects the
+// provider's default model.
+//
+//   - local: a hashed bag-of-words embedding computed in-process, so indexing
+//     works offline and without an API key.
+//   - openai: the OpenAI embeddings API, using OPENAI_API_KEY. Set
+//     OPENAI_BASE_URL to use another OpenAI-compatible server.
+//   - voyage: the Voyage AI embeddings API, using VOYAGE_API_KEY.
+//   - ollama: a local Ollama server at OLLAMA_HOST (default localhost:11434).
+func NewEmbedder(provider, model string) (Embedder, error) {
+	switch provider {
+	case "", "local":
+		return LocalEmbedder{}, nil
+	case "openai":
+		return newHTTPEmbedder("openai", envOr("OPENAI_BASE_URL", "https://api.openai.com/v1"), "OPENAI_API_KEY", model, "text-embedding-3-small")
+	case "voyage":
+		return newHTTPEmbedder("voyage", "https://api.voyageai.com/v1", "VOYAGE_API_KEY", model, "voyage-code-3")
+	case "ollama":
+		host := envOr("OLLAMA_HOST", "http://localhost:11434")
+		if !strings.Contains(host, "://") {
+			host = "http://" + host
+		}
+		return newHTTPEmbedder("ollama", strings.TrimRight(host, "/")+"/v1", "", model, "nomic-embed-text")
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q (want one of %s)", provider, strings.Join(Providers, ", "))
+	}
+}
+
+// envOr returns the environment variable key, or fallback if it is unset.
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// localDimensions is the size of LocalEmbedder vectors.
+const localDimensions = 1024
+
+// LocalEmbedder embeds text by hashing its identifiers and words into a fixed
+// number of buckets. It only captures shared vocabulary, not meaning, but
+// splits identifiers so that "parseConfig" matches "config parsing".
+type LocalEmbedder struct{}
+
+// Name implements Embedder.
+func (LocalEmbedder) Name() string {
+	return "local"
+}
+
+// Embed implements Embedder.
+func (LocalEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, localDimensions)
+		for _, token := range tokenize(text) {
+			h := fnv.New32a()
+			h.Write([]byte(token))
+			vector[h.Sum32()%localDimensions]++
+		}
+		for j, count := range vector {
+			// Dampen repeated tokens so one common word does not dominate
+			if count > 0 {
+				vector[j] = float32(1 + math.Log(float64(count)))
+			}
+		}
+		vectors[i] = normalize(vector)
+	}
+	return vectors, nil
+}
+
+// tokenize splits text into lower-case words, breaking identifiers at
+// underscores and camelCase boundaries. Words shorter than three letters are
+// dropped.
+func tokenize(text string) []string {
+	var tokens []string
+	var word []rune
+	flush := func() {
+		if len(word) >= 3 {
+			tokens = append(tokens, strings.ToLower(string(word)))
+		}
+		word = word[:0]
+	}
+	runes := []rune(text)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) {
+			flush()
+			continue
+		}
+		// Break "parseJSONConfig" into parse, JSON, Config
+		if unicode.IsUpper(r) && len(word) > 0 {
+			prevLower := unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || nextLower {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return tokens
+}
+
+// httpEmbedder calls an OpenAI-compatible /embeddings endpoint.
+type httpEmbedder struct {
+	provider string
+	baseURL  string
+	apiKey   string
+	model    string
+	client   *http.Client
+}
+
+// newHTTPEmbedder returns an httpEmbedder that reads its API key from
+// keyEnv. An empty keyEnv means the server needs no key.
+func newHTTPEmbedder(provider, baseURL, keyEnv, model, defaultModel string) (*httpEmbedder, error) {
+	var apiKey string
+	if keyEnv != "" {
+		apiKey = os.Getenv(keyEnv)
+		if apiKey == "" {
+			return nil, fmt.Errorf("%s must be set to use the %s embedding provider", keyEnv, provider)
+		}
+	}
+	if model == "" {
+		model = defaultModel
+	}
+	return &httpEmbedder{
+		provider: provider,
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		apiKey:   apiKey,
+		model:    model,
+		client:   http.DefaultClient,
+	}, nil
+}
+
+// Name implements Embedder.
+func (e *httpEmbedder) Name() string {
+	return e.provider + "/" + e.model
+}
+
+// Embed implements Embedder.
+func (e *httpEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]any{"model": e.model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s embeddings request failed: %w", e.provider, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s embeddings request failed: %s: %s", e.provider, resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse %s embeddings response: %w", e.provider, err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("%s returned %d embeddings for %d inputs", e.provider, len(result.Data), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("%s returned an embedding for unknown input %d", e.provider, item.Index)
+		}
+		vectors[item.Index] = normalize(item.Embedding)
+	}
+	return vectors, nil
+}
+
+// normalize scales v to unit length in place, so cosine similarity is a dot
+// product.
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	scale := float32(1 / math.Sqrt(sum))
+	for i := range v {
+		v[i] *= scale
+	}
+	return v
+}
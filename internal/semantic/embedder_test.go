@@ -0,0 +1,90 @@
+package semantic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("func parseJSONConfig(path_name string) // is ok")
+	expected := []string{"func", "parse", "json", "config", "path", "name", "string"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestLocalEmbedderSimilarity(t *testing.T) {
+	vectors, err := LocalEmbedder{}.Embed(context.Background(), []string{
+		"config parsing",
+		"func parseConfig(path string) (*Config, error)",
+		"render the markdown table in the terminal",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	related := dot(vectors[0], vectors[1])
+	unrelated := dot(vectors[0], vectors[2])
+	if related <= unrelated {
+		t.Errorf("Expected related text to score higher: related %.3f, unrelated %.3f", related, unrelated)
+	}
+}
+
+func TestNewEmbedder(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	if _, err := NewEmbedder("openai", ""); err == nil {
+		t.Error("Expected error when OPENAI_API_KEY is not set")
+	}
+	if _, err := NewEmbedder("nope", ""); err == nil {
+		t.Error("Expected error for unknown provider")
+	}
+
+	t.Setenv("VOYAGE_API_KEY", "key")
+	embedder, err := NewEmbedder("voyage", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if embedder.Name() != "voyage/voyage-code-3" {
+		t.Errorf("Unexpected name %q", embedder.Name())
+	}
+}
+
+func TestHTTPEmbedder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Unexpected Authorization header %q", r.Header.Get("Authorization"))
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "m" || len(req.Input) != 2 {
+			t.Errorf("Unexpected request %+v", req)
+		}
+		// Results may come back in any order
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0,2]},{"index":0,"embedding":[3,4]}]}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("OPENAI_BASE_URL", server.URL+"/v1")
+	t.Setenv("OPENAI_API_KEY", "secret")
+	embedder, err := NewEmbedder("openai", "m")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	vectors, err := embedder.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := [][]float32{{0.6, 0.8}, {0, 1}}
+	if !reflect.DeepEqual(vectors, expected) {
+		t.Errorf("Expected normalized vectors %v, got %v", expected, vectors)
+	}
+}
@@ -0,0 +1,219 @@
+package semantic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"tiny-trae/internal/ignore"
+)
+
+const (
+	// IndexFile is where the index is stored, relative to the indexed root.
+	IndexFile = ".tiny-trae/index.json"
+	// chunkLines is the number of lines in each indexed chunk.
+	chunkLines = 40
+	// chunkOverlap is how many lines consecutive chunks share, so code near a
+	// chunk boundary is still seen with its surroundings.
+	chunkOverlap = 10
+	// maxFileSize skips files too large to be hand-written source.
+	maxFileSize = 512 * 1024
+	// embedBatchSize is the number of chunks sent per Embed call.
+	embedBatchSize = 64
+)
+
+// Chunk is an indexed range of lines in a file.
+type Chunk struct {
+	Path      string    `json:"path"`
+	StartLine int       `json:"start_line"`
+	EndLine   int       `json:"end_line"`
+	Vector    []float32 `json:"vector"`
+}
+
+// Index holds the embedded chunks of a directory tree.
+type Index struct {
+	Embedder string    `json:"embedder"`
+	Created  time.Time `json:"created"`
+	Files    int       `json:"files"`
+	Chunks   []Chunk   `json:"chunks"`
+}
+
+// Result is a chunk matched by Search.
+type Result struct {
+	Chunk
+	Score float32
+}
+
+// Build chunks every text file under root that git does not ignore and embeds
+// the chunks with embedder. progress, if not nil, is called after each batch.
+func Build(ctx context.Context, root string, embedder Embedder, progress func(done, total int)) (*Index, error) {
+	var chunks []Chunk
+	var texts []string
+	files := 0
+	err := ignore.Walk(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > maxFileSize {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil || bytes.IndexByte(content, 0) >= 0 {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		fileChunks, fileTexts := chunkFile(rel, string(content))
+		if len(fileChunks) > 0 {
+			files++
+		}
+		chunks = append(chunks, fileChunks...)
+		texts = append(texts, fileTexts...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for start := 0; start < len(chunks); start += embedBatchSize {
+		end := min(start+embedBatchSize, len(chunks))
+		vectors, err := embedder.Embed(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for i, vector := range vectors {
+			chunks[start+i].Vector = vector
+		}
+		if progress != nil {
+			progress(end, len(chunks))
+		}
+	}
+
+	return &Index{
+		Embedder: embedder.Name(),
+		Created:  time.Now(),
+		Files:    files,
+		Chunks:   chunks,
+	}, nil
+}
+
+// chunkFile splits a file into overlapping line ranges and returns the chunks
+// along with the text to embed for each. The path is part of the text so that
+// file names contribute to matches. Blank chunks are skipped.
+func chunkFile(path, content string) ([]Chunk, []string) {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	var chunks []Chunk
+	var texts []string
+	for start := 0; start < len(lines); start += chunkLines - chunkOverlap {
+		end := min(start+chunkLines, len(lines))
+		text := strings.Join(lines[start:end], "\n")
+		if strings.TrimSpace(text) != "" {
+			chunks = append(chunks, Chunk{Path: path, StartLine: start + 1, EndLine: end})
+			texts = append(texts, path+"\n"+text)
+		}
+		if end == len(lines) {
+			break
+		}
+	}
+	return chunks, texts
+}
+
+// Search returns the limit chunks most similar to the query vector, best
+// first.
+func (idx *Index) Search(query []float32, limit int) []Result {
+	results := make([]Result, 0, len(idx.Chunks))
+	for _, chunk := range idx.Chunks {
+		results = append(results, Result{Chunk: chunk, Score: dot(query, chunk.Vector)})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// dot returns the dot product of two vectors, which for the unit-length
+// vectors stored in an index is their cosine similarity.
+func dot(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// Save writes the index to root/IndexFile.
+func (idx *Index) Save(root string) error {
+	path := filepath.Join(root, IndexFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Load reads the index stored under root.
+func Load(root string) (*Index, error) {
+	data, err := os.ReadFile(filepath.Join(root, IndexFile))
+	if err != nil {
+		return nil, err
+	}
+	var idx Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("failed to parse semantic index: %w", err)
+	}
+	return &idx, nil
+}
+
+// FindRoot returns the nearest directory at or above dir that has an index.
+func FindRoot(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(abs, IndexFile)); err == nil {
+			return abs, nil
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return "", fmt.Errorf("no semantic index found in %s or its parents; run 'tiny-trae index' first", dir)
+		}
+		abs = parent
+	}
+}
+
+// EmbedderFor returns an embedder matching the one the index was built with,
+// so queries land in the same vector space.
+func (idx *Index) EmbedderFor() (Embedder, error) {
+	provider, model, _ := strings.Cut(idx.Embedder, "/")
+	return NewEmbedder(provider, model)
+}
@@ -0,0 +1,90 @@
+package semantic
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChunkFile(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 75; i++ {
+		lines = append(lines, "line")
+	}
+	chunks, texts := chunkFile("a.go", strings.Join(lines, "\n")+"\n")
+
+	expected := [][2]int{{1, 40}, {31, 70}, {61, 75}}
+	if len(chunks) != len(expected) {
+		t.Fatalf("Expected %d chunks, got %d: %+v", len(expected), len(chunks), chunks)
+	}
+	for i, r := range expected {
+		if chunks[i].StartLine != r[0] || chunks[i].EndLine != r[1] {
+			t.Errorf("Chunk %d: expected lines %d-%d, got %d-%d", i, r[0], r[1], chunks[i].StartLine, chunks[i].EndLine)
+		}
+	}
+	if !strings.HasPrefix(texts[0], "a.go\n") {
+		t.Errorf("Expected chunk text to start with the path, got %q", texts[0][:10])
+	}
+}
+
+func TestBuildSearchAndLoad(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"auth/login.go":    "package auth\n\n// checkPassword verifies the user's password hash.\nfunc checkPassword(user, password string) bool { return false }\n",
+		"render/table.go":  "package render\n\n// drawTable renders rows as a markdown table.\nfunc drawTable(rows [][]string) string { return \"\" }\n",
+		"ignored/skip.go":  "package ignored\n\nfunc password() {}\n",
+		".hidden/login.go": "package hidden\n\nfunc password() {}\n",
+		".gitignore":       "ignored/\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var progressCalls int
+	index, err := Build(context.Background(), root, LocalEmbedder{}, func(done, total int) { progressCalls++ })
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if index.Files != 2 || len(index.Chunks) != 2 {
+		t.Errorf("Expected 2 files and chunks, got %d files and %d chunks", index.Files, len(index.Chunks))
+	}
+	if progressCalls != 1 {
+		t.Errorf("Expected 1 progress call, got %d", progressCalls)
+	}
+
+	if err := index.Save(root); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	found, err := FindRoot(filepath.Join(root, "auth"))
+	if err != nil || found != root {
+		t.Fatalf("Expected root %s, got %s (%v)", root, found, err)
+	}
+	loaded, err := Load(root)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	embedder, err := loaded.EmbedderFor()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	query, _ := embedder.Embed(context.Background(), []string{"verify password"})
+	results := loaded.Search(query[0], 1)
+	if len(results) != 1 || results[0].Path != "auth/login.go" {
+		t.Errorf("Expected auth/login.go to match best, got %+v", results)
+	}
+}
+
+func TestFindRootMissing(t *testing.T) {
+	if _, err := FindRoot(t.TempDir()); err == nil {
+		t.Error("Expected error when no index exists")
+	}
+}
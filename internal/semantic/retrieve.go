@@ -0,0 +1,114 @@
+package semantic
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Snippet is the current text of a chunk found by a Retriever.
+type Snippet struct {
+	Path      string
+	StartLine int
+	EndLine   int
+	Score     float32
+	// Text holds the chunk's lines, each prefixed with its number.
+	Text string
+}
+
+// Retriever finds the indexed code most relevant to a message, such as a
+// user's request, for adding to it.
+type Retriever struct {
+	root     string
+	index    *Index
+	embedder Embedder
+}
+
+// NewRetriever loads the index of the project containing dir.
+func NewRetriever(dir string) (*Retriever, error) {
+	root, err := FindRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	index, err := Load(root)
+	if err != nil {
+		return nil, err
+	}
+	embedder, err := index.EmbedderFor()
+	if err != nil {
+		return nil, err
+	}
+	return &Retriever{root: root, index: index, embedder: embedder}, nil
+}
+
+// Retrieve returns up to limit snippets most relevant to query, best first,
+// as many as fit in maxBytes together. Chunks overlapping a better one,
+// and those whose files have changed too much since indexing, are skipped.
+func (r *Retriever) Retrieve(ctx context.Context, query string, limit, maxBytes int) ([]Snippet, error) {
+	if strings.TrimSpace(query) == "" || limit <= 0 {
+		return nil, nil
+	}
+	vectors, err := r.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+
+	var snippets []Snippet
+	size := 0
+	// Some candidates are skipped, so more than limit are looked at
+	for _, result := range r.index.Search(vectors[0], 3*limit) {
+		if len(snippets) == limit {
+			break
+		}
+		if result.Score <= 0 || overlaps(snippets, result.Chunk) {
+			continue
+		}
+		text, ok := r.read(result.Chunk)
+		if !ok {
+			continue
+		}
+		if size+len(text) > maxBytes {
+			// A smaller snippet further down may still fit
+			continue
+		}
+		size += len(text)
+		snippets = append(snippets, Snippet{
+			Path:      result.Path,
+			StartLine: result.StartLine,
+			EndLine:   result.EndLine,
+			Score:     result.Score,
+			Text:      text,
+		})
+	}
+	return snippets, nil
+}
+
+// read returns the current lines of chunk with their numbers, or false if
+// its file no longer has them.
+func (r *Retriever) read(chunk Chunk) (string, bool) {
+	content, err := os.ReadFile(filepath.Join(r.root, filepath.FromSlash(chunk.Path)))
+	if err != nil {
+		return "", false
+	}
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	if chunk.StartLine > len(lines) {
+		return "", false
+	}
+	var b strings.Builder
+	for n := chunk.StartLine; n <= min(chunk.EndLine, len(lines)); n++ {
+		fmt.Fprintf(&b, "%6d\t%s\n", n, strings.TrimRight(lines[n-1], "\r"))
+	}
+	return b.String(), true
+}
+
+// overlaps reports whether chunk shares lines with any of snippets.
+func overlaps(snippets []Snippet, chunk Chunk) bool {
+	for _, s := range snippets {
+		if s.Path == chunk.Path && s.StartLine <= chunk.EndLine && chunk.StartLine <= s.EndLine {
+			return true
+		}
+	}
+	return false
+}
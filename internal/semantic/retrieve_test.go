@@ -0,0 +1,60 @@
+package semantic
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRetrieve(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"auth/login.go":   "package auth\n\n// checkPassword verifies the user's password hash.\nfunc checkPassword(user, password string) bool { return false }\n",
+		"render/table.go": "package render\n\n// drawTable renders rows as a markdown table.\nfunc drawTable(rows [][]string) string { return \"\" }\n",
+		"gone/removed.go": "package gone\n\n// hashPassword hashes a password for storage.\nfunc hashPassword(password string) string { return \"\" }\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index, err := Build(context.Background(), root, LocalEmbedder{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Save(root); err != nil {
+		t.Fatal(err)
+	}
+	// Files deleted since indexing are skipped
+	if err := os.RemoveAll(filepath.Join(root, "gone")); err != nil {
+		t.Fatal(err)
+	}
+
+	retriever, err := NewRetriever(filepath.Join(root, "auth"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	snippets, err := retriever.Retrieve(context.Background(), "where is the password checked?", 1, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snippets) != 1 || snippets[0].Path != "auth/login.go" || snippets[0].StartLine != 1 || snippets[0].EndLine != 4 {
+		t.Fatalf("Expected the first lines of auth/login.go, got %+v", snippets)
+	}
+	if !strings.HasPrefix(snippets[0].Text, "     1\tpackage auth\n") || !strings.Contains(snippets[0].Text, "     4\tfunc checkPassword") {
+		t.Errorf("Expected numbered lines, got %q", snippets[0].Text)
+	}
+
+	if snippets, _ := retriever.Retrieve(context.Background(), "where is the password checked?", 3, 50); len(snippets) != 0 {
+		t.Errorf("Expected no snippet to fit in 50 bytes, got %+v", snippets)
+	}
+	if _, err := NewRetriever(t.TempDir()); err == nil {
+		t.Error("Expected an error without an index")
+	}
+}
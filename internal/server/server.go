@@ -0,0 +1,226 @@
+// Package server runs chat sessions over HTTP, so browsers and other
+// programs can drive the agent.
+//
+// POST /sessions starts a session. Its events, the agent's messages and its
+// requests for input and approval, are streamed from
+// GET /sessions/{id}/events as Server-Sent Events; a client that reconnects
+// with Last-Event-ID gets the events it missed. The client answers with
+// POST /sessions/{id}/messages and POST /sessions/{id}/approval, stops the
+// running tool or the whole turn with POST /sessions/{id}/interrupt and
+// POST /sessions/{id}/cancel, and ends the session with
+// DELETE /sessions/{id}. Request bodies are JSON.
+//
+// GET / serves a chat UI built on these, embedded from the web directory.
+package server
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"tiny-trae/internal/agent"
+)
+
+// webFiles are the chat UI's files.
+//
+//go:embed web
+var webFiles embed.FS
+
+// Handler returns the server's HTTP handler.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	web, _ := fs.Sub(webFiles, "web")
+	mux.Handle("GET /", http.FileServerFS(web))
+	mux.Handle("POST /sessions", handlerFunc(s.createSession))
+	mux.Handle("DELETE /sessions/{id}", handlerFunc(s.deleteSession))
+	mux.Handle("GET /sessions/{id}/events", handlerFunc(s.streamEvents))
+	mux.Handle("POST /sessions/{id}/messages", handlerFunc(s.postMessage))
+	mux.Handle("POST /sessions/{id}/approval", handlerFunc(s.postApproval))
+	mux.Handle("POST /sessions/{id}/interrupt", postSignal(s.Interrupt))
+	mux.Handle("POST /sessions/{id}/cancel", postSignal(s.CancelTurn))
+	return checkHost(mux)
+}
+
+// checkHost refuses requests addressed to a host name other than
+// localhost. A web page whose own name an attacker points at the server's
+// address (DNS rebinding) could otherwise use the chat UI's requests.
+func checkHost(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		host = strings.Trim(host, "[]")
+		if host != "localhost" && net.ParseIP(host) == nil {
+			http.Error(w, "use the server's IP address or localhost", http.StatusMisdirectedRequest)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// handlerFunc is an HTTP handler whose errors are answered by serveError.
+type handlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+func (h handlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h(w, r); err != nil {
+		serveError(w, err)
+	}
+}
+
+// serveError answers with err and the status that goes with it.
+func serveError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, errNotJSON):
+		status = http.StatusUnsupportedMediaType
+	case errors.Is(err, ErrNoSession):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrNotWaitingForInput), errors.Is(err, ErrNoApprovalRequest):
+		status = http.StatusConflict
+	case errors.Is(err, ErrEmptyMessage), errors.Is(err, ErrUnknownDecision), errors.Is(err, errBadJSON):
+		status = http.StatusBadRequest
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// createSession starts a session and answers with its ID.
+func (s *Server) createSession(w http.ResponseWriter, r *http.Request) error {
+	if !isJSON(r) {
+		return errNotJSON
+	}
+	id, err := s.CreateSession()
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{"id": id})
+	return nil
+}
+
+// deleteSession ends a session.
+func (s *Server) deleteSession(w http.ResponseWriter, r *http.Request) error {
+	if err := s.EndSession(r.PathValue("id")); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// streamEvents sends a session's events as Server-Sent Events, from the
+// first one or the one after Last-Event-ID, until the session ends or the
+// client goes away.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if _, err := s.lookup(id); err != nil {
+		return err
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return errors.New("streaming is not supported")
+	}
+	last, _ := strconv.Atoi(r.Header.Get("Last-Event-ID"))
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// Once the stream has started, errors can only end it
+	s.Events(r.Context(), id, last, func(event Event) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return nil
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+	return nil
+}
+
+// postMessage sends the user's next message, {"text": "..."}, to an agent
+// waiting for one.
+func (s *Server) postMessage(w http.ResponseWriter, r *http.Request) error {
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := readJSON(w, r, &body); err != nil {
+		return err
+	}
+	if err := s.SendMessage(r.PathValue("id"), body.Text); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusAccepted)
+	return nil
+}
+
+// postApproval answers the pending approval request with
+// {"decision": "approve" | "deny" | "always_allow"}.
+func (s *Server) postApproval(w http.ResponseWriter, r *http.Request) error {
+	var body struct {
+		Decision agent.ApprovalDecision `json:"decision"`
+	}
+	if err := readJSON(w, r, &body); err != nil {
+		return err
+	}
+	if err := s.Approve(r.PathValue("id"), body.Decision); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusAccepted)
+	return nil
+}
+
+// postSignal returns a handler that calls signal with the session's ID.
+func postSignal(signal func(id string) error) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if !isJSON(r) {
+			return errNotJSON
+		}
+		if err := signal(r.PathValue("id")); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return nil
+	}
+}
+
+// isJSON reports whether the request's body is JSON. Requiring it keeps web
+// pages from other sites from posting to the server, since browsers only
+// send such requests from other origins after a CORS preflight, which the
+// server does not answer.
+func isJSON(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+// Errors of malformed requests.
+var (
+	errNotJSON = errors.New("the request must be JSON")
+	errBadJSON = errors.New("invalid JSON")
+)
+
+// readJSON decodes the request's JSON body into v.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	if !isJSON(r) {
+		return errNotJSON
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", errBadJSON, err)
+	}
+	return nil
+}
+
+// writeJSON answers with v as JSON.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"tiny-trae/internal/agent"
+
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+// modelServer answers every request to the model with "Hello".
+func modelServer() *httptest.Server {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[],"usage":{"input_tokens":1,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":1}}`,
+		`{"type":"message_stop"}`,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			var typed struct{ Type string }
+			json.Unmarshal([]byte(event), &typed)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typed.Type, event)
+		}
+	}))
+}
+
+// subscribe streams a session's events, from the one after last, to the
+// returned channel, which is closed when the stream ends.
+func subscribe(t *testing.T, url string, last int) <-chan Event {
+	t.Helper()
+	req, _ := http.NewRequest("GET", url, nil)
+	if last > 0 {
+		req.Header.Set("Last-Event-ID", strconv.Itoa(last))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to stream the events: %v", err)
+	}
+	events := make(chan Event, 100)
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+				var event Event
+				json.Unmarshal([]byte(data), &event)
+				events <- event
+			}
+		}
+	}()
+	return events
+}
+
+// next returns the next event of the given type.
+func next(t *testing.T, events <-chan Event, eventType string) Event {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("The stream ended before a %s event", eventType)
+			}
+			if event.Type == eventType {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for a %s event", eventType)
+		}
+	}
+}
+
+func post(t *testing.T, url, body string) int {
+	t.Helper()
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to post to %s: %v", url, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestSessionEvents(t *testing.T) {
+	model := modelServer()
+	defer model.Close()
+	srv := &Server{
+		Client:  agent.NewClientWithOptions(option.WithBaseURL(model.URL), option.WithAPIKey("test")),
+		Profile: &agent.Profile{Model: "claude", MaxTokens: 100},
+	}
+	defer srv.Close()
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/sessions", "application/json", nil)
+	if err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Failed to create a session: %v %v", resp, err)
+	}
+	var created struct{ ID string }
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	session := ts.URL + "/sessions/" + created.ID
+
+	events := subscribe(t, session+"/events", 0)
+	next(t, events, EventInputRequest)
+	if status := post(t, session+"/approval", `{"decision":"approve"}`); status != http.StatusConflict {
+		t.Errorf("Expected an approval with no request to conflict, got %d", status)
+	}
+	if status := post(t, session+"/messages", `{"text":"hi"}`); status != http.StatusAccepted {
+		t.Fatalf("Failed to send a message: %d", status)
+	}
+	if status := post(t, session+"/messages", `{"text":"again"}`); status != http.StatusConflict {
+		t.Errorf("Expected a message while the agent works to conflict, got %d", status)
+	}
+	if delta := next(t, events, string(agent.MessageTypeAssistantDelta)); delta.Content != "Hello" {
+		t.Errorf("Unexpected delta %+v", delta)
+	}
+	reply := next(t, events, string(agent.MessageTypeAssistant))
+	if reply.Content != "Hello" {
+		t.Errorf("Unexpected reply %+v", reply)
+	}
+
+	// A client that reconnects gets the events it missed
+	if event := <-subscribe(t, session+"/events", reply.ID); event.ID != reply.ID+1 {
+		t.Errorf("Expected the stream to resume after event %d, got %+v", reply.ID, event)
+	}
+
+	req, _ := http.NewRequest("DELETE", session, nil)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Failed to end the session: %v %v", resp, err)
+	}
+	next(t, events, EventSessionEnd)
+	if _, ok := <-events; ok {
+		t.Error("Expected the stream to end with the session")
+	}
+}
+
+func TestRequestsMustBeJSON(t *testing.T) {
+	ts := httptest.NewServer((&Server{}).Handler())
+	defer ts.Close()
+	resp, err := http.Post(ts.URL+"/sessions", "text/plain", nil)
+	if err != nil || resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected a form post to be refused, got %v %v", resp, err)
+	}
+	if status := post(t, ts.URL+"/sessions/nope/cancel", `{}`); status != http.StatusNotFound {
+		t.Errorf("Expected an unknown session to be not found, got %d", status)
+	}
+}
+
+func TestWebUI(t *testing.T) {
+	ts := httptest.NewServer((&Server{}).Handler())
+	defer ts.Close()
+	for path, want := range map[string]string{"/": "<title>tiny-trae</title>", "/app.js": "EventSource"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("Failed to get %s: %v", path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), want) {
+			t.Errorf("Unexpected %s: %d %.80q", path, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestHostNamesAreRefused(t *testing.T) {
+	handler := (&Server{}).Handler()
+	for host, want := range map[string]int{
+		"localhost:8080":      http.StatusOK,
+		"127.0.0.1:8080":      http.StatusOK,
+		"[::1]:8080":          http.StatusOK,
+		"attacker.example":    http.StatusMisdirectedRequest,
+		"rebind.example:8080": http.StatusMisdirectedRequest,
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Host = host
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		if recorder.Code != want {
+			t.Errorf("Host %s got %d, want %d", host, recorder.Code, want)
+		}
+	}
+}
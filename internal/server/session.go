@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"tiny-trae/internal/agent"
+)
+
+// Event types besides the agent's message types.
+const (
+	// EventInputRequest is sent when the agent waits for the next message.
+	EventInputRequest = "input_request"
+	// EventApprovalRequest is sent, with the agent.ApprovalRequest as data,
+	// when a tool call waits for approval.
+	EventApprovalRequest = "approval_request"
+	// EventSessionEnd is the last event of a session; its content is the
+	// error that ended the session, if any.
+	EventSessionEnd = "session_end"
+)
+
+// Event is an event of a session: a message from the agent, or one of the
+// Event types above.
+type Event struct {
+	// ID numbers the session's events from 1.
+	ID      int             `json:"id"`
+	Type    string          `json:"type"`
+	Content string          `json:"content,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// waiting says what a session's agent waits for from the client.
+type waiting int
+
+const (
+	waitingForNothing waiting = iota
+	waitingForInput
+	waitingForApproval
+)
+
+// session is a chat session. It is the frontend of its agent and keeps the
+// events the agent produced, so every client sees the whole session.
+type session struct {
+	id     string
+	cancel context.CancelFunc
+
+	inputs     chan string
+	approvals  chan agent.ApprovalDecision
+	interrupts chan struct{}
+	cancels    chan struct{}
+	// done is closed when the session ends
+	done chan struct{}
+
+	mu      sync.Mutex
+	events  []Event
+	waiting waiting
+	ended   bool
+	// changed is closed, and replaced, when an event is added
+	changed chan struct{}
+}
+
+// newSession creates a session; cancel stops its agent.
+func newSession(id string, cancel context.CancelFunc) *session {
+	return &session{
+		id:         id,
+		cancel:     cancel,
+		inputs:     make(chan string, 1),
+		approvals:  make(chan agent.ApprovalDecision, 1),
+		interrupts: make(chan struct{}, 1),
+		cancels:    make(chan struct{}, 1),
+		done:       make(chan struct{}),
+		changed:    make(chan struct{}),
+	}
+}
+
+// publish adds an event and wakes the clients waiting for one.
+func (s *session) publish(event Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	event.ID = len(s.events) + 1
+	s.events = append(s.events, event)
+	if event.Type == EventSessionEnd {
+		s.ended = true
+		s.waiting = waitingForNothing
+		close(s.done)
+	}
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// since returns the events after the one numbered id, a channel that is
+// closed when there are more, and whether the session has ended.
+func (s *session) since(id int) ([]Event, <-chan struct{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id = min(max(id, 0), len(s.events))
+	return s.events[id:len(s.events):len(s.events)], s.changed, s.ended
+}
+
+// end ends the session with err, which may be nil.
+func (s *session) end(err error) {
+	event := Event{Type: EventSessionEnd}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		event.Content = err.Error()
+	}
+	s.publish(event)
+}
+
+// wait sets what the agent waits for and publishes the event asking for it.
+func (s *session) wait(w waiting, event Event) {
+	s.mu.Lock()
+	s.waiting = w
+	s.mu.Unlock()
+	s.publish(event)
+}
+
+// answer reports whether the agent waits for w, and stops it waiting.
+func (s *session) answer(w waiting) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.waiting != w {
+		return false
+	}
+	s.waiting = waitingForNothing
+	return true
+}
+
+// signal sends a value on an interrupt channel unless one is pending.
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// SendMessage implements agent.Frontend.
+func (s *session) SendMessage(msg agent.Message) {
+	s.publish(Event{Type: string(msg.Type), Content: msg.Content, Data: msg.Data})
+}
+
+// GetUserInput implements agent.Frontend.
+func (s *session) GetUserInput() (string, bool) {
+	s.wait(waitingForInput, Event{Type: EventInputRequest})
+	select {
+	case input := <-s.inputs:
+		return input, true
+	case <-s.done:
+		return "", false
+	}
+}
+
+// RequestApproval implements agent.Frontend.
+func (s *session) RequestApproval(req agent.ApprovalRequest) agent.ApprovalDecision {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return agent.ApprovalDeny
+	}
+	s.wait(waitingForApproval, Event{Type: EventApprovalRequest, Content: req.ToolName, Data: data})
+	select {
+	case decision := <-s.approvals:
+		return decision
+	case <-s.done:
+		return agent.ApprovalDeny
+	}
+}
+
+// Interrupts implements agent.Frontend.
+func (s *session) Interrupts() <-chan struct{} {
+	return s.interrupts
+}
+
+// TurnCancels implements agent.Frontend.
+func (s *session) TurnCancels() <-chan struct{} {
+	return s.cancels
+}
+
+// IsInteractive implements agent.Frontend.
+func (s *session) IsInteractive() bool {
+	return true
+}
+
+// Close implements agent.Frontend.
+func (s *session) Close() {}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/audit"
+	"tiny-trae/internal/permission"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// Errors of the session operations.
+var (
+	ErrNoSession          = errors.New("no such session")
+	ErrNotWaitingForInput = errors.New("the agent is not waiting for a message")
+	ErrNoApprovalRequest  = errors.New("no tool call is waiting for approval")
+	ErrEmptyMessage       = errors.New("the message is empty")
+	ErrUnknownDecision    = errors.New("unknown decision (want approve, deny, or always_allow)")
+)
+
+// Server serves chat sessions, each with its own agent. Its methods are the
+// operations on sessions; Handler serves them over HTTP.
+type Server struct {
+	// Client is the API client the agents use.
+	Client anthropic.Client
+	// Profile configures every session's agent.
+	Profile *agent.Profile
+	// Policy is consulted before every tool call; may be nil.
+	Policy *permission.Policy
+	// AuditLog records every tool call; may be nil.
+	AuditLog *audit.Log
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// CreateSession starts a session and returns its ID.
+func (s *Server) CreateSession() (string, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return "", err
+	}
+	// Sessions outlive the request that started them
+	ctx, cancel := context.WithCancel(context.Background())
+	sess := newSession(id, cancel)
+	a := agent.NewAgent(s.Client, s.Profile, sess)
+	a.SetPermissionPolicy(s.Policy)
+	a.SetAuditLog(s.AuditLog)
+
+	s.mu.Lock()
+	if s.sessions == nil {
+		s.sessions = make(map[string]*session)
+	}
+	s.sessions[id] = sess
+	s.mu.Unlock()
+
+	go func() {
+		sess.end(a.Run(ctx, ""))
+	}()
+	return id, nil
+}
+
+// EndSession stops a session's agent and ends its event stream.
+func (s *Server) EndSession(id string) error {
+	s.mu.Lock()
+	sess := s.sessions[id]
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	if sess == nil {
+		return ErrNoSession
+	}
+	sess.cancel()
+	sess.end(nil)
+	return nil
+}
+
+// Close ends every session.
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, sess := range s.sessions {
+		sess.cancel()
+		sess.end(nil)
+		delete(s.sessions, id)
+	}
+}
+
+// Events passes a session's events after the one numbered after to send,
+// as they happen, until the session ends, ctx is done, or send fails.
+func (s *Server) Events(ctx context.Context, id string, after int, send func(Event) error) error {
+	sess, err := s.lookup(id)
+	if err != nil {
+		return err
+	}
+	for {
+		events, changed, ended := sess.since(after)
+		for _, event := range events {
+			if err := send(event); err != nil {
+				return err
+			}
+			after = event.ID
+		}
+		if ended {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// SendMessage sends the user's next message to a session's agent, which
+// must be waiting for one.
+func (s *Server) SendMessage(id, text string) error {
+	sess, err := s.lookup(id)
+	if err != nil {
+		return err
+	}
+	if text == "" {
+		return ErrEmptyMessage
+	}
+	if !sess.answer(waitingForInput) {
+		return ErrNotWaitingForInput
+	}
+	sess.inputs <- text
+	return nil
+}
+
+// Approve answers a session's pending approval request.
+func (s *Server) Approve(id string, decision agent.ApprovalDecision) error {
+	sess, err := s.lookup(id)
+	if err != nil {
+		return err
+	}
+	switch decision {
+	case agent.ApprovalApprove, agent.ApprovalDeny, agent.ApprovalAlwaysAllow:
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownDecision, decision)
+	}
+	if !sess.answer(waitingForApproval) {
+		return ErrNoApprovalRequest
+	}
+	sess.approvals <- decision
+	return nil
+}
+
+// Interrupt cancels the tool a session's agent is running.
+func (s *Server) Interrupt(id string) error {
+	sess, err := s.lookup(id)
+	if err != nil {
+		return err
+	}
+	signal(sess.interrupts)
+	return nil
+}
+
+// CancelTurn stops a session's current turn.
+func (s *Server) CancelTurn(id string) error {
+	sess, err := s.lookup(id)
+	if err != nil {
+		return err
+	}
+	signal(sess.cancels)
+	return nil
+}
+
+// lookup returns the session with the ID.
+func (s *Server) lookup(id string) (*session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess := s.sessions[id]
+	if sess == nil {
+		return nil, ErrNoSession
+	}
+	return sess, nil
+}
+
+// newSessionID returns a random session ID, which is hard to guess, as
+// knowing it is enough to drive the session.
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
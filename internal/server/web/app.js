@@ -0,0 +1,364 @@
+// The chat UI of 'tiny-trae serve'. It starts a session, renders the
+// session's events as they stream in, and posts the user's messages,
+// approvals, and cancels back.
+"use strict";
+
+const transcript = document.getElementById("transcript");
+const input = document.getElementById("input");
+const send = document.getElementById("send");
+const stop = document.getElementById("stop");
+const statusLine = document.getElementById("status");
+const usageLine = document.getElementById("usage");
+const approval = document.getElementById("approval");
+
+// The event types the server sends: the agent's messages and the session's
+// own events.
+const eventTypes = [
+  "user_input", "assistant_delta", "assistant", "thinking", "tool_call",
+  "tool_output", "tool_result", "error", "system_info", "usage", "request",
+  "input_request", "approval_request", "session_end",
+];
+
+let sessionURL = "";
+// streaming is the element of the reply being streamed, if any
+let streaming = null;
+// tools holds the element of each tool call by its ID
+const tools = new Map();
+
+// element creates an element with a class and text.
+function element(tag, className, text) {
+  const el = document.createElement(tag);
+  if (className) {
+    el.className = className;
+  }
+  if (text !== undefined) {
+    el.textContent = text;
+  }
+  return el;
+}
+
+// append adds el to the transcript, keeping the view at the bottom if it
+// was there.
+function append(el) {
+  const atBottom = transcript.scrollTop + transcript.clientHeight >= transcript.scrollHeight - 8;
+  transcript.appendChild(el);
+  if (atBottom) {
+    transcript.scrollTop = transcript.scrollHeight;
+  }
+  return el;
+}
+
+// renderInline adds text to parent, with `code` and **bold** styled.
+function renderInline(parent, text) {
+  for (const part of text.split(/(`[^`\n]+`|\*\*[^*\n]+\*\*)/)) {
+    if (part.startsWith("`") && part.endsWith("`") && part.length > 1) {
+      parent.appendChild(element("code", "", part.slice(1, -1)));
+    } else if (part.startsWith("**") && part.endsWith("**") && part.length > 3) {
+      parent.appendChild(element("strong", "", part.slice(2, -2)));
+    } else if (part) {
+      parent.appendChild(document.createTextNode(part));
+    }
+  }
+}
+
+// renderMarkdown renders the markdown the model writes most: paragraphs,
+// headings, lists, fenced code, inline code, and bold.
+function renderMarkdown(text) {
+  const root = element("div", "markdown");
+  const blocks = text.split(/^```[^\n]*\n?/m);
+  blocks.forEach((block, i) => {
+    // Odd blocks are inside a fence
+    if (i % 2 === 1) {
+      const pre = element("pre");
+      pre.appendChild(element("code", "", block.replace(/\n$/, "")));
+      root.appendChild(pre);
+      return;
+    }
+    for (const paragraph of block.split(/\n\s*\n/)) {
+      const lines = paragraph.split("\n").filter((line) => line.trim() !== "");
+      if (lines.length === 0) {
+        continue;
+      }
+      if (lines.every((line) => /^\s*([-*]|\d+\.)\s/.test(line))) {
+        const list = element(/^\s*\d/.test(lines[0]) ? "ol" : "ul");
+        for (const line of lines) {
+          const item = element("li");
+          renderInline(item, line.replace(/^\s*([-*]|\d+\.)\s/, ""));
+          list.appendChild(item);
+        }
+        root.appendChild(list);
+        continue;
+      }
+      const heading = lines[0].match(/^(#{1,6})\s+(.*)/);
+      if (heading && lines.length === 1) {
+        const h = element("h" + Math.min(heading[1].length + 2, 6));
+        renderInline(h, heading[2]);
+        root.appendChild(h);
+        continue;
+      }
+      const p = element("p");
+      renderInline(p, lines.join("\n"));
+      root.appendChild(p);
+    }
+  });
+  return root;
+}
+
+// renderDiff renders a unified diff with added and removed lines colored.
+function renderDiff(text) {
+  const pre = element("pre", "diff");
+  for (const line of text.split("\n")) {
+    let className = "";
+    if (line.startsWith("+++") || line.startsWith("---")) {
+      className = "file";
+    } else if (line.startsWith("@@")) {
+      className = "hunk";
+    } else if (line.startsWith("+")) {
+      className = "add";
+    } else if (line.startsWith("-")) {
+      className = "del";
+    }
+    pre.appendChild(element("span", className, line + "\n"));
+  }
+  return pre;
+}
+
+// isDiff reports whether a preview is a diff rather than, say, a command.
+function isDiff(text) {
+  return /^(---|\+\+\+|@@)/m.test(text);
+}
+
+// renderPreview renders a tool call's preview, or its input if it has none.
+function renderPreview(preview, toolInput) {
+  if (preview && isDiff(preview)) {
+    return renderDiff(preview);
+  }
+  if (preview) {
+    return element("pre", "", preview);
+  }
+  return element("pre", "", JSON.stringify(toolInput ?? {}, null, 2));
+}
+
+// summary describes a tool result's metadata, like the TUI does.
+function summary(meta) {
+  if (!meta) {
+    return "";
+  }
+  const parts = [];
+  if (meta.exit_code !== undefined && meta.exit_code !== null) {
+    parts.push("exit " + meta.exit_code);
+  }
+  if (meta.files_changed && meta.files_changed.length === 1) {
+    parts.push("changed " + meta.files_changed[0]);
+  } else if (meta.files_changed && meta.files_changed.length > 1) {
+    parts.push(meta.files_changed.length + " files changed");
+  }
+  parts.push((meta.duration_ms / 1000).toFixed(1) + "s");
+  if (meta.truncated) {
+    parts.push("truncated");
+  }
+  return parts.join(", ");
+}
+
+// setWorking switches between the agent working and waiting for the user.
+function setWorking(working, text) {
+  input.disabled = working;
+  send.disabled = working;
+  stop.hidden = !working;
+  statusLine.textContent = text;
+  if (!working) {
+    input.focus();
+  }
+}
+
+function showError(text) {
+  append(element("div", "message error", text));
+}
+
+// handle renders an event from the session.
+function handle(event) {
+  const data = event.data ?? {};
+  switch (event.type) {
+  case "user_input":
+    append(element("div", "message user", event.content));
+    setWorking(true, "Waiting for response...");
+    break;
+  case "assistant_delta":
+    if (!streaming) {
+      streaming = append(element("div", "message assistant streaming", ""));
+    }
+    streaming.textContent += event.content;
+    statusLine.textContent = "Receiving response...";
+    break;
+  case "assistant":
+    if (streaming) {
+      streaming.remove();
+      streaming = null;
+    }
+    append(element("div", "message assistant")).appendChild(renderMarkdown(event.content));
+    break;
+  case "thinking": {
+    const details = append(element("details", "thinking"));
+    details.appendChild(element("summary", "", "Thinking"));
+    details.appendChild(element("pre", "", event.content));
+    break;
+  }
+  case "tool_call": {
+    const details = element("details", "tool running");
+    const head = element("summary");
+    head.appendChild(element("span", "name", data.tool_name ?? "tool"));
+    head.appendChild(element("span", "state", "running"));
+    details.appendChild(head);
+    details.appendChild(renderPreview(data.preview, data.input));
+    // Edits show their diff without being expanded
+    details.open = Boolean(data.preview && isDiff(data.preview));
+    tools.set(data.tool_id, details);
+    append(details);
+    statusLine.textContent = "Running " + (data.tool_name ?? "a tool") + "... (Esc to cancel it)";
+    break;
+  }
+  case "tool_output": {
+    const details = tools.get(data.tool_id);
+    if (!details) {
+      break;
+    }
+    let output = details.querySelector("pre.output");
+    if (!output) {
+      output = details.appendChild(element("pre", "output", ""));
+      details.open = true;
+    }
+    output.textContent += event.content;
+    output.scrollTop = output.scrollHeight;
+    break;
+  }
+  case "tool_result": {
+    let details = tools.get(data.tool_id);
+    if (!details) {
+      details = append(element("details", "tool"));
+      details.appendChild(element("summary", "", data.tool_name ?? "tool"));
+    }
+    details.classList.remove("running");
+    details.classList.toggle("failed", Boolean(data.is_error));
+    details.querySelector("pre.output")?.remove();
+    const state = details.querySelector(".state");
+    if (state) {
+      state.textContent = [data.is_error ? "failed" : "done", summary(data.meta)].filter(Boolean).join(" · ");
+    }
+    details.appendChild(element("pre", "result", data.result ?? event.content));
+    statusLine.textContent = "Waiting for response...";
+    break;
+  }
+  case "error":
+    if (streaming) {
+      streaming.remove();
+      streaming = null;
+    }
+    showError(event.content);
+    break;
+  case "system_info":
+    append(element("div", "message system", event.content));
+    break;
+  case "usage":
+    usageLine.textContent = `${data.model} · ${data.input_tokens + data.output_tokens} tokens · $${(data.cost ?? 0).toFixed(2)}`;
+    break;
+  case "input_request":
+    setWorking(false, "Ready");
+    break;
+  case "approval_request":
+    showApproval(event.content, data);
+    break;
+  case "session_end":
+    setWorking(true, event.content ? "Session ended: " + event.content : "Session ended");
+    stop.hidden = true;
+    break;
+  }
+}
+
+// showApproval shows the approval panel for a tool call.
+function showApproval(toolName, request) {
+  document.getElementById("approval-tool").textContent = toolName;
+  const preview = document.getElementById("approval-preview");
+  preview.replaceChildren(renderPreview(request.preview, request.input));
+  approval.hidden = false;
+  statusLine.textContent = toolName + " needs your approval";
+  approval.querySelector("button").focus();
+}
+
+async function post(path, body) {
+  const response = await fetch(sessionURL + path, {
+    method: "POST",
+    headers: {"Content-Type": "application/json"},
+    body: JSON.stringify(body ?? {}),
+  });
+  if (!response.ok) {
+    showError(await response.text());
+  }
+  return response.ok;
+}
+
+for (const button of approval.querySelectorAll("button")) {
+  button.addEventListener("click", async () => {
+    approval.hidden = true;
+    statusLine.textContent = "Waiting for response...";
+    await post("/approval", {decision: button.dataset.decision});
+  });
+}
+
+document.getElementById("composer").addEventListener("submit", async (event) => {
+  event.preventDefault();
+  const text = input.value;
+  if (text.trim() === "") {
+    return;
+  }
+  setWorking(true, "Sending...");
+  if (await post("/messages", {text})) {
+    input.value = "";
+  } else {
+    setWorking(false, "Ready");
+  }
+});
+
+input.addEventListener("keydown", (event) => {
+  if (event.key === "Enter" && !event.shiftKey && !event.isComposing) {
+    event.preventDefault();
+    send.click();
+  }
+});
+
+stop.addEventListener("click", () => post("/cancel"));
+
+document.addEventListener("keydown", (event) => {
+  if (event.key === "Escape" && !stop.hidden) {
+    post("/interrupt");
+  }
+});
+
+async function start() {
+  const response = await fetch("/sessions", {
+    method: "POST",
+    headers: {"Content-Type": "application/json"},
+    body: "{}",
+  });
+  if (!response.ok) {
+    statusLine.textContent = "Failed to start a session: " + await response.text();
+    return;
+  }
+  sessionURL = "/sessions/" + (await response.json()).id;
+  // The browser reconnects with Last-Event-ID by itself, and the server
+  // sends what was missed
+  const events = new EventSource(sessionURL + "/events");
+  for (const type of eventTypes) {
+    events.addEventListener(type, (message) => {
+      const event = JSON.parse(message.data);
+      handle(event);
+      if (event.type === "session_end") {
+        events.close();
+      }
+    });
+  }
+  window.addEventListener("beforeunload", () => {
+    fetch(sessionURL, {method: "DELETE", keepalive: true});
+  });
+}
+
+start();
@@ -0,0 +1,151 @@
+// Package telegram relays a chat session to a Telegram chat, so a long run
+// on a server can be followed and its tool calls approved from a phone.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// DefaultAPIURL is the Telegram Bot API.
+const DefaultAPIURL = "https://api.telegram.org"
+
+// maxMessageLength is how many characters of text a message gets, a little
+// under Telegram's limit of 4096 UTF-16 code units.
+const maxMessageLength = 4000
+
+// api calls the Telegram Bot API.
+type api struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// update is an incoming update: a message or a press of an inline button.
+type update struct {
+	UpdateID      int64          `json:"update_id"`
+	Message       *message       `json:"message"`
+	CallbackQuery *callbackQuery `json:"callback_query"`
+}
+
+type message struct {
+	MessageID int64  `json:"message_id"`
+	Chat      chat   `json:"chat"`
+	Text      string `json:"text"`
+}
+
+type chat struct {
+	ID int64 `json:"id"`
+}
+
+type callbackQuery struct {
+	ID      string   `json:"id"`
+	Data    string   `json:"data"`
+	Message *message `json:"message"`
+}
+
+// button is an inline keyboard button.
+type button struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+type replyMarkup struct {
+	InlineKeyboard [][]button `json:"inline_keyboard"`
+}
+
+// call calls method with params and decodes its result into result, which
+// may be nil.
+func (a *api) call(ctx context.Context, method string, params any, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/bot%s/%s", a.baseURL, url.PathEscape(a.token), method)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		// The error's URL has the token in it
+		return fmt.Errorf("telegram %s: %w", method, unwrapURLError(err))
+	}
+	defer resp.Body.Close()
+
+	var reply struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return fmt.Errorf("telegram %s: %s", method, resp.Status)
+	}
+	if !reply.OK {
+		return fmt.Errorf("telegram %s: %s", method, reply.Description)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(reply.Result, result)
+}
+
+// unwrapURLError returns the cause of a *url.Error, whose message includes
+// the request's URL.
+func unwrapURLError(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+	return err
+}
+
+// getUpdates waits up to timeout seconds for updates after offset.
+func (a *api) getUpdates(ctx context.Context, offset int64, timeout int) ([]update, error) {
+	var updates []update
+	err := a.call(ctx, "getUpdates", map[string]any{
+		"offset":          offset,
+		"timeout":         timeout,
+		"allowed_updates": []string{"message", "callback_query"},
+	}, &updates)
+	return updates, err
+}
+
+// sendMessage sends text to the chat, with buttons under it if markup is
+// not nil, and returns the message's ID.
+func (a *api) sendMessage(ctx context.Context, chatID int64, text string, markup *replyMarkup) (int64, error) {
+	params := map[string]any{"chat_id": chatID, "text": truncate(text)}
+	if markup != nil {
+		params["reply_markup"] = markup
+	}
+	var sent message
+	err := a.call(ctx, "sendMessage", params, &sent)
+	return sent.MessageID, err
+}
+
+// answerCallback acknowledges a button press, showing text to the user.
+func (a *api) answerCallback(ctx context.Context, queryID, text string) error {
+	return a.call(ctx, "answerCallbackQuery", map[string]any{"callback_query_id": queryID, "text": text}, nil)
+}
+
+// removeButtons removes the buttons under a message.
+func (a *api) removeButtons(ctx context.Context, chatID, messageID int64) error {
+	return a.call(ctx, "editMessageReplyMarkup", map[string]any{
+		"chat_id":      chatID,
+		"message_id":   messageID,
+		"reply_markup": replyMarkup{InlineKeyboard: [][]button{}},
+	}, nil)
+}
+
+// truncate shortens text to fit in a message.
+func truncate(text string) string {
+	runes := []rune(text)
+	if len(runes) <= maxMessageLength {
+		return text
+	}
+	return string(runes[:maxMessageLength-1]) + "…"
+}
@@ -0,0 +1,225 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/server"
+)
+
+// pollTimeout is how many seconds a request for updates waits for one.
+const pollTimeout = 30
+
+// retryDelay is how long the bot waits after a failed request for updates.
+var retryDelay = 5 * time.Second
+
+// Bot runs a session and relays it to one Telegram chat. The agent's
+// replies, tool calls, failed tool results, and errors are sent to the
+// chat; approval requests come with Accept, Reject, and Always allow
+// buttons. Messages from the chat are sent to the agent, apart from the
+// commands /interrupt, which cancels the running tool, /cancel, which stops
+// the turn, and /end, which ends the session. Everything from other chats
+// is ignored.
+type Bot struct {
+	// Sessions runs the session.
+	Sessions *server.Server
+	// Token is the bot's token from @BotFather.
+	Token string
+	// ChatID is the only chat the bot talks to.
+	ChatID int64
+	// APIURL is the Bot API's URL. Defaults to DefaultAPIURL.
+	APIURL string
+	// Log receives diagnostics. Defaults to os.Stderr.
+	Log io.Writer
+
+	api       *api
+	sessionID string
+}
+
+// Run starts a session, sends it prompt if it is not empty, and relays the
+// session until it ends, by /end or by ctx being done.
+func (b *Bot) Run(ctx context.Context, prompt string) error {
+	if b.APIURL == "" {
+		b.APIURL = DefaultAPIURL
+	}
+	if b.Log == nil {
+		b.Log = os.Stderr
+	}
+	b.api = &api{baseURL: b.APIURL, token: b.Token, client: &http.Client{Timeout: (pollTimeout + 10) * time.Second}}
+
+	id, err := b.Sessions.CreateSession()
+	if err != nil {
+		return err
+	}
+	b.sessionID = id
+	defer b.Sessions.EndSession(id)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go b.poll(ctx)
+
+	started := false
+	err = b.Sessions.Events(ctx, id, 0, func(event server.Event) error {
+		if event.Type == server.EventInputRequest && !started {
+			started = true
+			if prompt != "" {
+				b.send(ctx, "Starting: "+prompt, nil)
+				return b.Sessions.SendMessage(id, prompt)
+			}
+			b.send(ctx, "Session started. Send a message to begin.", nil)
+			return nil
+		}
+		b.relay(ctx, event)
+		return nil
+	})
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// relay sends an event to the chat, if it is one the chat should see.
+func (b *Bot) relay(ctx context.Context, event server.Event) {
+	switch event.Type {
+	case string(agent.MessageTypeAssistant):
+		b.send(ctx, event.Content, nil)
+	case string(agent.MessageTypeError):
+		b.send(ctx, "Error: "+event.Content, nil)
+	case string(agent.MessageTypeSystemInfo):
+		b.send(ctx, event.Content, nil)
+	case string(agent.MessageTypeToolCall):
+		var call agent.ToolCallData
+		if err := json.Unmarshal(event.Data, &call); err == nil {
+			b.send(ctx, fmt.Sprintf("Running %s %s", call.ToolName, shorten(string(call.Input), 200)), nil)
+		}
+	case string(agent.MessageTypeToolResult):
+		// Successful results would flood the chat; the replies say what
+		// came of them
+		var result agent.ToolResultData
+		if err := json.Unmarshal(event.Data, &result); err == nil && result.IsError {
+			b.send(ctx, fmt.Sprintf("%s failed: %s", result.ToolName, shorten(result.Result, 500)), nil)
+		}
+	case server.EventApprovalRequest:
+		var req agent.ApprovalRequest
+		if err := json.Unmarshal(event.Data, &req); err != nil {
+			return
+		}
+		preview := req.Preview
+		if preview == "" {
+			preview = string(req.Input)
+		}
+		b.send(ctx, fmt.Sprintf("%s needs your approval:\n\n%s", req.ToolName, preview), &replyMarkup{
+			InlineKeyboard: [][]button{{
+				{Text: "Accept", CallbackData: string(agent.ApprovalApprove)},
+				{Text: "Reject", CallbackData: string(agent.ApprovalDeny)},
+				{Text: "Always allow", CallbackData: string(agent.ApprovalAlwaysAllow)},
+			}},
+		})
+	case server.EventInputRequest:
+		b.send(ctx, "Done. Waiting for your next message.", nil)
+	case server.EventSessionEnd:
+		text := "Session ended."
+		if event.Content != "" {
+			text = "Session ended: " + event.Content
+		}
+		b.send(ctx, text, nil)
+	}
+}
+
+// send sends text to the chat and returns the message's ID, or 0 if it
+// could not be sent.
+func (b *Bot) send(ctx context.Context, text string, markup *replyMarkup) int64 {
+	if strings.TrimSpace(text) == "" {
+		return 0
+	}
+	id, err := b.api.sendMessage(ctx, b.ChatID, text, markup)
+	if err != nil && ctx.Err() == nil {
+		fmt.Fprintf(b.Log, "Failed to send to Telegram: %v\n", err)
+	}
+	return id
+}
+
+// poll handles the chat's messages and button presses until ctx is done.
+func (b *Bot) poll(ctx context.Context) {
+	var offset int64
+	for ctx.Err() == nil {
+		updates, err := b.api.getUpdates(ctx, offset, pollTimeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			fmt.Fprintf(b.Log, "Failed to get Telegram updates: %v\n", err)
+			select {
+			case <-time.After(retryDelay):
+			case <-ctx.Done():
+			}
+			continue
+		}
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			switch {
+			case u.Message != nil && u.Message.Chat.ID == b.ChatID:
+				b.handleMessage(ctx, u.Message.Text)
+			case u.CallbackQuery != nil && u.CallbackQuery.Message != nil && u.CallbackQuery.Message.Chat.ID == b.ChatID:
+				b.handleButton(ctx, u.CallbackQuery)
+			}
+		}
+	}
+}
+
+// handleMessage sends a message from the chat to the agent, or runs it as
+// a command.
+func (b *Bot) handleMessage(ctx context.Context, text string) {
+	var err error
+	switch strings.TrimSpace(text) {
+	case "":
+		return
+	case "/interrupt":
+		err = b.Sessions.Interrupt(b.sessionID)
+	case "/cancel":
+		err = b.Sessions.CancelTurn(b.sessionID)
+	case "/end":
+		err = b.Sessions.EndSession(b.sessionID)
+	default:
+		err = b.Sessions.SendMessage(b.sessionID, text)
+		if errors.Is(err, server.ErrNotWaitingForInput) {
+			b.send(ctx, "The agent is still working. /cancel stops the turn.", nil)
+			return
+		}
+	}
+	if err != nil {
+		b.send(ctx, "Error: "+err.Error(), nil)
+	}
+}
+
+// handleButton answers the pending approval with the pressed button's
+// decision.
+func (b *Bot) handleButton(ctx context.Context, query *callbackQuery) {
+	answer := map[agent.ApprovalDecision]string{
+		agent.ApprovalApprove:     "Accepted",
+		agent.ApprovalDeny:        "Rejected",
+		agent.ApprovalAlwaysAllow: "Always allowed",
+	}[agent.ApprovalDecision(query.Data)]
+	if err := b.Sessions.Approve(b.sessionID, agent.ApprovalDecision(query.Data)); err != nil {
+		answer = "This request was already answered"
+	}
+	b.api.answerCallback(ctx, query.ID, answer)
+	b.api.removeButtons(ctx, b.ChatID, query.Message.MessageID)
+}
+
+// shorten cuts text to at most n characters.
+func shorten(text string, n int) string {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) <= n {
+		return string(runes)
+	}
+	return string(runes[:n]) + "…"
+}
@@ -0,0 +1,202 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/server"
+
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+// modelServer calls the "touch" tool in its first reply and says "Done"
+// in the next.
+func modelServer() *httptest.Server {
+	toolUse := []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude","content":[],"usage":{"input_tokens":1,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"touch","input":{}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"path\":\"a.txt\"}"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":1}}`,
+		`{"type":"message_stop"}`,
+	}
+	text := []string{
+		`{"type":"message_start","message":{"id":"msg_2","type":"message","role":"assistant","model":"claude","content":[],"usage":{"input_tokens":1,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Done"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":1}}`,
+		`{"type":"message_stop"}`,
+	}
+	var mu sync.Mutex
+	requests := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		events := text
+		if requests == 1 {
+			events = toolUse
+		}
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			var typed struct{ Type string }
+			json.Unmarshal([]byte(event), &typed)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typed.Type, event)
+		}
+	}))
+}
+
+// fakeAPI is a Telegram Bot API that records the bot's calls and hands it
+// the updates queued for it.
+type fakeAPI struct {
+	mu       sync.Mutex
+	sent     []string
+	answered []string
+	updates  []update
+	nextID   int64
+	// onSend is called with each message the bot sends, with mu held
+	onSend func(text string, markup *replyMarkup)
+}
+
+// queue adds an update for the bot; mu must be held.
+func (f *fakeAPI) queue(u update) {
+	f.nextID++
+	u.UpdateID = f.nextID
+	f.updates = append(f.updates, u)
+}
+
+// queueText queues a message from a chat; mu must be held.
+func (f *fakeAPI) queueText(chatID int64, text string) {
+	f.queue(update{Message: &message{Chat: chat{ID: chatID}, Text: text}})
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var params struct {
+		Offset      int64        `json:"offset"`
+		Text        string       `json:"text"`
+		ReplyMarkup *replyMarkup `json:"reply_markup"`
+		QueryID     string       `json:"callback_query_id"`
+	}
+	json.NewDecoder(r.Body).Decode(&params)
+	method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	if !strings.HasPrefix(r.URL.Path, "/bottoken/") {
+		http.Error(w, `{"ok":false,"description":"Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	f.mu.Lock()
+	var result any = true
+	switch method {
+	case "sendMessage":
+		f.sent = append(f.sent, params.Text)
+		if f.onSend != nil {
+			f.onSend(params.Text, params.ReplyMarkup)
+		}
+		result = message{MessageID: int64(len(f.sent))}
+	case "answerCallbackQuery":
+		f.answered = append(f.answered, params.QueryID)
+	case "getUpdates":
+		var pending []update
+		for _, u := range f.updates {
+			if u.UpdateID >= params.Offset {
+				pending = append(pending, u)
+			}
+		}
+		result = pending
+	}
+	f.mu.Unlock()
+
+	if method == "getUpdates" && result.([]update) == nil {
+		// Long polling, shortened
+		time.Sleep(10 * time.Millisecond)
+		result = []update{}
+	}
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+}
+
+func TestBot(t *testing.T) {
+	const chatID = 42
+	model := modelServer()
+	defer model.Close()
+
+	touched := make(chan string, 1)
+	touch := agent.ToolDefinition{
+		Name:             "touch",
+		RequiresApproval: true,
+		Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+			touched <- string(input)
+			return "touched", nil
+		},
+	}
+
+	fake := &fakeAPI{}
+	fake.onSend = func(text string, markup *replyMarkup) {
+		switch {
+		case markup != nil:
+			// Press Accept, after someone in another chat pressed Reject
+			fake.queue(update{CallbackQuery: &callbackQuery{ID: "other", Data: "deny", Message: &message{MessageID: 1, Chat: chat{ID: 7}}}})
+			fake.queue(update{CallbackQuery: &callbackQuery{ID: "q1", Data: markup.InlineKeyboard[0][0].CallbackData, Message: &message{MessageID: 1, Chat: chat{ID: chatID}}}})
+		case strings.HasPrefix(text, "Done. Waiting"):
+			fake.queueText(7, "/end")
+			fake.queueText(chatID, "/end")
+		}
+	}
+	telegram := httptest.NewServer(fake)
+	defer telegram.Close()
+
+	sessions := &server.Server{
+		Client:  agent.NewClientWithOptions(option.WithBaseURL(model.URL), option.WithAPIKey("test")),
+		Profile: &agent.Profile{Model: "claude", MaxTokens: 100, Tools: []agent.ToolDefinition{touch}},
+	}
+	defer sessions.Close()
+	bot := &Bot{Sessions: sessions, Token: "token", ChatID: chatID, APIURL: telegram.URL}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := bot.Run(ctx, "touch a.txt"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("Timed out before /end ended the session")
+	}
+
+	select {
+	case input := <-touched:
+		if input != `{"path":"a.txt"}` {
+			t.Errorf("Unexpected tool input %s", input)
+		}
+	default:
+		t.Error("Expected the approved tool call to run")
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if want := []string{"q1"}; fmt.Sprint(fake.answered) != fmt.Sprint(want) {
+		t.Errorf("Expected only the chat's button press to be answered, got %v", fake.answered)
+	}
+	transcript := strings.Join(fake.sent, "\n")
+	for _, want := range []string{"Starting: touch a.txt", "touch needs your approval", "Done", "Session ended."} {
+		if !strings.Contains(transcript, want) {
+			t.Errorf("Expected the chat to get %q, got:\n%s", want, transcript)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	long := strings.Repeat("é", maxMessageLength+10)
+	got := []rune(truncate(long))
+	if len(got) != maxMessageLength || got[len(got)-1] != '…' {
+		t.Errorf("Expected a message cut to %d characters, got %d", maxMessageLength, len(got))
+	}
+	if truncate("short") != "short" {
+		t.Error("Expected short text to be kept")
+	}
+}
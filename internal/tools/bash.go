@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os/exec"
@@ -14,6 +15,9 @@ var BashDefinition = agent.ToolDefinition{
 	Description: "Execute a bash command.",
 	InputSchema: BashInputSchema,
 	Function:    Bash,
+
+	RequiresApproval: true,
+	Preview:          BashPreview,
 }
 
 // BashInput defines the input schema for the 'bash' tool.
@@ -24,16 +28,25 @@ type BashInput struct {
 // BashInputSchema is the JSON schema for the 'bash' tool's input.
 var BashInputSchema = agent.GenerateSchema[BashInput]()
 
+// BashPreview returns the command text so the user can review it before approving.
+func BashPreview(input json.RawMessage) string {
+	bashInput := BashInput{}
+	if err := json.Unmarshal(input, &bashInput); err != nil {
+		return string(input)
+	}
+	return bashInput.Command
+}
+
 // Bash implements the 'bash' tool.
-func Bash(input json.RawMessage) (string, error) {
+func Bash(ctx context.Context, input json.RawMessage) (string, error) {
 	bashInput := BashInput{}
 	err := json.Unmarshal(input, &bashInput)
 	if err != nil {
 		return "", err
 	}
 
-	cmd := exec.Command("bash", "-c", bashInput.Command)
-	output, err := cmd.CombinedOutput()
+	cmd := exec.CommandContext(ctx, "bash", "-c", bashInput.Command)
+	output, err := runWithLimits(ctx, cmd, CommandLimits)
 	if err != nil {
 		return "", fmt.Errorf("command execution error: %v - %s", err, string(output))
 	}
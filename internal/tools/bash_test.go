@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 )
@@ -51,7 +52,7 @@ func TestBash(t *testing.T) {
 				t.Fatalf("Failed to marshal input: %v", err)
 			}
 
-			result, err := Bash(inputJSON)
+			result, err := Bash(context.Background(), inputJSON)
 
 			if tt.expectError {
 				if err == nil {
@@ -71,7 +72,7 @@ func TestBash(t *testing.T) {
 
 func TestBashInvalidJSON(t *testing.T) {
 	invalidJSON := []byte(`{"invalid": json}`)
-	_, err := Bash(invalidJSON)
+	_, err := Bash(context.Background(), invalidJSON)
 	if err == nil {
 		t.Error("Expected error for invalid JSON input")
 	}
@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"tiny-trae/internal/agent"
+)
+
+const (
+	// maxDiagnostics caps the diagnostics reported for each command.
+	maxDiagnostics = 50
+	// maxRawBuildOutputLines is how much output is returned for a failing
+	// command that produced no recognizable diagnostics.
+	maxRawBuildOutputLines = 40
+)
+
+// DefaultBuildCommands are run when neither the tool input nor the settings'
+// build_commands, which are filled in as its default, name any commands.
+var DefaultBuildCommands = []string{"go build ./...", "go vet ./..."}
+
+// BuildAndLintDefinition defines the 'build_and_lint' tool.
+var BuildAndLintDefinition = agent.ToolDefinition{
+	Name: "build_and_lint",
+	Description: `Build and lint the project and return only the diagnostics.
+
+Runs the project's build and lint commands, set as build_commands in its .trae.yaml, or 'go build ./...' and 'go vet ./...' if it sets none. Each command's compiler and linter messages are reported as file:line:col references; other output is dropped unless the command fails without any.
+Use this after editing code to find compile errors instead of running the build through the shell tool.`,
+	InputSchema: BuildAndLintInputSchema,
+	Function:    BuildAndLint,
+
+	RequiresApproval: true,
+	Preview:          BuildAndLintPreview,
+}
+
+// BuildAndLintInput defines the input schema for the 'build_and_lint' tool.
+type BuildAndLintInput struct {
+	Path     string   `json:"path,omitempty" jsonschema_description:"The directory to build in. Defaults to the current directory"`
+	Commands []string `json:"commands,omitempty" jsonschema_description:"Shell commands to run instead of the project's configured build and lint commands"`
+}
+
+// BuildAndLintInputSchema is the JSON schema for the 'build_and_lint' tool's input.
+var BuildAndLintInputSchema = agent.GenerateSchema[BuildAndLintInput]()
+
+// BuildAndLintPreview returns the commands that will run.
+func BuildAndLintPreview(input json.RawMessage) string {
+	buildInput := BuildAndLintInput{}
+	if err := json.Unmarshal(input, &buildInput); err != nil {
+		return string(input)
+	}
+	return strings.Join(buildInput.commands(), "\n")
+}
+
+// commands returns the commands to run: those in the input, else
+// DefaultBuildCommands.
+func (i BuildAndLintInput) commands() []string {
+	if len(i.Commands) > 0 {
+		return i.Commands
+	}
+	return DefaultBuildCommands
+}
+
+// BuildAndLint implements the 'build_and_lint' tool.
+func BuildAndLint(ctx context.Context, input json.RawMessage) (string, error) {
+	buildInput := BuildAndLintInput{}
+	if err := json.Unmarshal(input, &buildInput); err != nil {
+		return "", err
+	}
+
+	var sections []string
+	for _, command := range buildInput.commands() {
+		cmd := exec.CommandContext(ctx, "bash", "-c", command)
+		cmd.Dir = buildInput.Path
+		output, err := runWithLimits(ctx, cmd, CommandLimits)
+
+		// Build errors exit non-zero; they are reported as diagnostics
+		var exitErr *exec.ExitError
+		if err != nil && !errors.As(err, &exitErr) {
+			return "", fmt.Errorf("failed to run %q: %w", command, err)
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		sections = append(sections, summarizeBuildOutput(command, output, err))
+	}
+	return strings.Join(sections, "\n\n"), nil
+}
+
+// diagnosticPattern matches a compiler or linter message of the form
+// file:line[:col]: message.
+var diagnosticPattern = regexp.MustCompile(`^([^:\s]+\.\w+):(\d+)(?::(\d+))?: (.+)$`)
+
+// parseDiagnostics returns the file:line diagnostics in a command's output.
+// Indented lines directly after a diagnostic, such as the "have/want" notes
+// of a type error, are kept with it.
+func parseDiagnostics(output []byte) []string {
+	var diagnostics []string
+	inDiagnostic := false
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimRight(line, "\r")
+		// go vet prefixes diagnostics with "vet: " when it cannot type-check
+		candidate := strings.TrimPrefix(strings.TrimPrefix(line, "vet: "), "./")
+		if diagnosticPattern.MatchString(candidate) {
+			diagnostics = append(diagnostics, candidate)
+			inDiagnostic = true
+			continue
+		}
+		if inDiagnostic && (strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "    ")) {
+			diagnostics[len(diagnostics)-1] += "\n    " + strings.TrimSpace(line)
+			continue
+		}
+		inDiagnostic = false
+	}
+	return diagnostics
+}
+
+// summarizeBuildOutput reports a command's status and its diagnostics. If a
+// failing command printed no diagnostics, the end of its output is shown
+// instead.
+func summarizeBuildOutput(command string, output []byte, runErr error) string {
+	diagnostics := parseDiagnostics(output)
+
+	var exitErr *exec.ExitError
+	failed := errors.As(runErr, &exitErr)
+	switch {
+	case !failed && len(diagnostics) == 0:
+		return fmt.Sprintf("$ %s\nOK", command)
+	case len(diagnostics) == 0:
+		lines := strings.Split(strings.TrimRight(string(output), "\n"), "\n")
+		if len(lines) > maxRawBuildOutputLines {
+			omitted := len(lines) - maxRawBuildOutputLines
+			lines = append([]string{fmt.Sprintf("... (%d earlier lines omitted)", omitted)}, lines[omitted:]...)
+		}
+		return fmt.Sprintf("$ %s\nFailed (exit status %d)\n%s", command, exitErr.ExitCode(), strings.Join(lines, "\n"))
+	}
+
+	count := len(diagnostics)
+	if count > maxDiagnostics {
+		diagnostics = append(diagnostics[:maxDiagnostics], fmt.Sprintf("... (%d more diagnostics)", count-maxDiagnostics))
+	}
+	status := "OK"
+	if failed {
+		status = fmt.Sprintf("Failed (exit status %d)", exitErr.ExitCode())
+	}
+	return fmt.Sprintf("$ %s\n%s, %d diagnostics\n%s", command, status, count, strings.Join(diagnostics, "\n"))
+}
@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	output := `# tiny-trae/internal/tools
+./grep.go:42:9: undefined: foo
+./grep.go:50:2: cannot use x (variable of type int) as string value in return statement
+	have (int)
+	want (string)
+vet: internal/agent/agent.go:10:1: missing return
+note: module requires Go 1.24
+`
+	diagnostics := parseDiagnostics([]byte(output))
+	expected := []string{
+		"grep.go:42:9: undefined: foo",
+		"grep.go:50:2: cannot use x (variable of type int) as string value in return statement\n    have (int)\n    want (string)",
+		"internal/agent/agent.go:10:1: missing return",
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %d: %q", len(expected), len(diagnostics), diagnostics)
+	}
+	for i := range expected {
+		if diagnostics[i] != expected[i] {
+			t.Errorf("Diagnostic %d: expected %q, got %q", i, expected[i], diagnostics[i])
+		}
+	}
+}
+
+func TestBuildAndLintCommands(t *testing.T) {
+	commands := BuildAndLintInput{}.commands()
+	if strings.Join(commands, ";") != strings.Join(DefaultBuildCommands, ";") {
+		t.Errorf("Expected default commands, got %q", commands)
+	}
+
+	commands = BuildAndLintInput{Commands: []string{"make build", "make lint"}}.commands()
+	if strings.Join(commands, ";") != "make build;make lint" {
+		t.Errorf("Expected the input's commands, got %q", commands)
+	}
+}
+
+func TestBuildAndLint(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands []string
+		expected string
+	}{
+		{
+			name:     "clean build",
+			commands: []string{"echo building"},
+			expected: "$ echo building\nOK",
+		},
+		{
+			name:     "diagnostics",
+			commands: []string{"echo '# pkg'; echo './main.go:3:1: syntax error'; exit 1"},
+			expected: "$ echo '# pkg'; echo './main.go:3:1: syntax error'; exit 1\nFailed (exit status 1), 1 diagnostics\nmain.go:3:1: syntax error",
+		},
+		{
+			name:     "failure without diagnostics",
+			commands: []string{"echo no such target; exit 2"},
+			expected: "$ echo no such target; exit 2\nFailed (exit status 2)\nno such target",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, _ := json.Marshal(BuildAndLintInput{Path: t.TempDir(), Commands: tt.commands})
+			result, err := BuildAndLint(context.Background(), input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestBuildAndLintInvalidJSON(t *testing.T) {
+	if _, err := BuildAndLint(context.Background(), []byte(`{"invalid": json}`)); err == nil {
+		t.Error("Expected error for invalid JSON input")
+	}
+}
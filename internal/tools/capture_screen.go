@@ -0,0 +1,250 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"tiny-trae/internal/agent"
+)
+
+const (
+	// DefaultCaptureWidth and DefaultCaptureHeight are the browser window
+	// size used for URL screenshots.
+	DefaultCaptureWidth  = 1280
+	DefaultCaptureHeight = 800
+	// captureTimeout bounds how long a capture may take.
+	captureTimeout = 60 * time.Second
+)
+
+// CaptureScreenDefinition defines the 'capture_screen' tool. It is not tagged
+// TagDefault; main adds it when the user passes --screen-capture.
+var CaptureScreenDefinition = agent.ToolDefinition{
+	Name: "capture_screen",
+	Description: `Capture what an app you are working on looks like, so you can check its rendered state.
+
+Targets:
+- 'url': load 'url' in a headless Chrome or Chromium window ('width' x 'height', default 1280x800) and attach a screenshot. Only hosts allowed for http_request can be loaded
+- 'tmux': return the text of the tmux pane 'pane' (e.g. 'dev:1.0'), including 'lines' lines of scrollback. Use this for terminal UIs
+- 'screen': attach a screenshot of the user's whole screen
+
+Screenshots are attached as images and must be under 5 MiB.`,
+	InputSchema: CaptureScreenInputSchema,
+	Function:    CaptureScreen,
+
+	RequiresApproval: true,
+	Preview:          CaptureScreenPreview,
+}
+
+// CaptureScreenInput defines the input schema for the 'capture_screen' tool.
+type CaptureScreenInput struct {
+	Target string `json:"target" jsonschema:"enum=url,enum=tmux,enum=screen" jsonschema_description:"What to capture: url, tmux, or screen"`
+	URL    string `json:"url,omitempty" jsonschema_description:"The page to screenshot, for url"`
+	Width  int    `json:"width,omitempty" jsonschema_description:"The browser window width in pixels, for url. Defaults to 1280"`
+	Height int    `json:"height,omitempty" jsonschema_description:"The browser window height in pixels, for url. Defaults to 800"`
+	Pane   string `json:"pane,omitempty" jsonschema_description:"The tmux target pane, for tmux. Defaults to the current pane"`
+	Lines  int    `json:"lines,omitempty" jsonschema_description:"Lines of scrollback to include above the visible pane, for tmux"`
+}
+
+// CaptureScreenInputSchema is the JSON schema for the 'capture_screen' tool's input.
+var CaptureScreenInputSchema = agent.GenerateSchema[CaptureScreenInput]()
+
+// CaptureScreenPreview describes the capture for approval.
+func CaptureScreenPreview(input json.RawMessage) string {
+	captureInput := CaptureScreenInput{}
+	if err := json.Unmarshal(input, &captureInput); err != nil {
+		return string(input)
+	}
+	switch captureInput.Target {
+	case "url":
+		return "Screenshot " + captureInput.URL
+	case "tmux":
+		if captureInput.Pane == "" {
+			return "Capture the current tmux pane"
+		}
+		return "Capture tmux pane " + captureInput.Pane
+	case "screen":
+		return "Screenshot the whole screen"
+	default:
+		return string(input)
+	}
+}
+
+// CaptureScreen implements the 'capture_screen' tool.
+func CaptureScreen(ctx context.Context, input json.RawMessage) (string, error) {
+	captureInput := CaptureScreenInput{}
+	if err := json.Unmarshal(input, &captureInput); err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, captureTimeout)
+	defer cancel()
+
+	switch captureInput.Target {
+	case "tmux":
+		return captureTmuxPane(ctx, captureInput.Pane, captureInput.Lines)
+	case "url", "screen":
+	default:
+		return "", fmt.Errorf("unknown target %q; use url, tmux, or screen", captureInput.Target)
+	}
+
+	dir, err := os.MkdirTemp("", "tiny-trae-capture-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "screenshot.png")
+
+	var command []string
+	var subject string
+	if captureInput.Target == "url" {
+		command, err = browserScreenshotCommand(captureInput, path, dir)
+		subject = captureInput.URL
+	} else {
+		command, err = screenshotCommand(runtime.GOOS, os.Getenv, exec.LookPath, path)
+		subject = "the screen"
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s failed: %w\n%s", filepath.Base(command[0]), err, strings.TrimSpace(output.String()))
+	}
+	img, err := agent.LoadImage(path)
+	if err != nil {
+		return "", fmt.Errorf("no screenshot was saved: %w", err)
+	}
+	agent.AttachImage(ctx, img)
+	return fmt.Sprintf("Screenshot of %s (%s)", subject, describeImage(img)), nil
+}
+
+// captureTmuxPane returns the text of a tmux pane.
+func captureTmuxPane(ctx context.Context, pane string, lines int) (string, error) {
+	if _, err := exec.LookPath("tmux"); err != nil {
+		return "", fmt.Errorf("tmux is not installed")
+	}
+	args := []string{"capture-pane", "-p", "-J"}
+	if pane != "" {
+		args = append(args, "-t", pane)
+	}
+	if lines > 0 {
+		args = append(args, "-S", strconv.Itoa(-lines))
+	}
+	out, err := exec.CommandContext(ctx, "tmux", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("tmux capture-pane failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	text := strings.TrimRight(string(out), "\n ")
+	if text == "" {
+		return "(the pane is empty)", nil
+	}
+	return text, nil
+}
+
+// browserScreenshotCommand returns the headless browser command that saves a
+// screenshot of the requested page to path, using profileDir as a fresh
+// browser profile so the user's cookies are not used.
+func browserScreenshotCommand(input CaptureScreenInput, path, profileDir string) ([]string, error) {
+	target, err := url.Parse(input.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	switch target.Scheme {
+	case "http", "https":
+		allowed, err := httpAllowedHosts()
+		if err != nil {
+			return nil, err
+		}
+		if !hostAllowed(target, allowed) {
+			return nil, fmt.Errorf("host %s is not in the allowed hosts; ask the user to add it to allowed_hosts in ~/.config/tiny-trae/http.yaml", target.Host)
+		}
+	case "file":
+	default:
+		return nil, fmt.Errorf("url must start with http://, https://, or file://")
+	}
+
+	browser, err := findBrowser(runtime.GOOS, os.Getenv, exec.LookPath)
+	if err != nil {
+		return nil, err
+	}
+	width, height := input.Width, input.Height
+	if width <= 0 {
+		width = DefaultCaptureWidth
+	}
+	if height <= 0 {
+		height = DefaultCaptureHeight
+	}
+	return []string{
+		browser,
+		"--headless=new",
+		"--disable-gpu",
+		"--hide-scrollbars",
+		"--no-first-run",
+		"--user-data-dir=" + profileDir,
+		"--screenshot=" + path,
+		fmt.Sprintf("--window-size=%d,%d", width, height),
+		target.String(),
+	}, nil
+}
+
+// browserNames are the Chrome and Chromium executables looked up in PATH.
+var browserNames = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome"}
+
+// findBrowser returns the browser for URL screenshots. CHROME_PATH overrides
+// the search.
+func findBrowser(goos string, getenv func(string) string, lookPath func(string) (string, error)) (string, error) {
+	if path := getenv("CHROME_PATH"); path != "" {
+		return path, nil
+	}
+	for _, name := range browserNames {
+		if path, err := lookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	if goos == "darwin" {
+		const macChrome = "/Applications/Google Chrome.app/Contents/MacOS/Google Chrome"
+		if _, err := os.Stat(macChrome); err == nil {
+			return macChrome, nil
+		}
+	}
+	return "", fmt.Errorf("no Chrome or Chromium browser found; install one or set CHROME_PATH")
+}
+
+// screenshotCommand returns the command that saves a screenshot of the whole
+// screen to path.
+func screenshotCommand(goos string, getenv func(string) string, lookPath func(string) (string, error), path string) ([]string, error) {
+	switch goos {
+	case "darwin":
+		return []string{"screencapture", "-x", "-t", "png", path}, nil
+	case "windows":
+		return nil, fmt.Errorf("screen capture is not supported on Windows; use the url target")
+	}
+
+	candidates := [][]string{
+		{"gnome-screenshot", "-f", path},
+		{"scrot", "--overwrite", path},
+		{"import", "-window", "root", path},
+	}
+	if getenv("WAYLAND_DISPLAY") != "" {
+		candidates = append([][]string{{"grim", path}}, candidates...)
+	}
+	for _, command := range candidates {
+		if _, err := lookPath(command[0]); err == nil {
+			return command, nil
+		}
+	}
+	return nil, fmt.Errorf("no screenshot tool found; install grim, gnome-screenshot, scrot, or ImageMagick")
+}
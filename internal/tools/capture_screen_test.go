@@ -0,0 +1,125 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestScreenshotCommand(t *testing.T) {
+	installed := func(names ...string) func(string) (string, error) {
+		return func(name string) (string, error) {
+			for _, n := range names {
+				if n == name {
+					return "/usr/bin/" + name, nil
+				}
+			}
+			return "", exec.ErrNotFound
+		}
+	}
+	wayland := func(key string) string {
+		if key == "WAYLAND_DISPLAY" {
+			return "wayland-0"
+		}
+		return ""
+	}
+	noEnv := func(string) string { return "" }
+
+	tests := []struct {
+		goos      string
+		getenv    func(string) string
+		installed []string
+		expected  []string
+	}{
+		{"darwin", noEnv, nil, []string{"screencapture", "-x", "-t", "png", "shot.png"}},
+		{"linux", wayland, []string{"grim", "scrot"}, []string{"grim", "shot.png"}},
+		{"linux", noEnv, []string{"grim", "scrot"}, []string{"scrot", "--overwrite", "shot.png"}},
+		{"linux", noEnv, []string{"import"}, []string{"import", "-window", "root", "shot.png"}},
+	}
+	for _, test := range tests {
+		command, err := screenshotCommand(test.goos, test.getenv, installed(test.installed...), "shot.png")
+		if err != nil || !reflect.DeepEqual(command, test.expected) {
+			t.Errorf("screenshotCommand(%s, %v) = %v, %v; want %v", test.goos, test.installed, command, err, test.expected)
+		}
+	}
+
+	if _, err := screenshotCommand("linux", noEnv, installed(), "shot.png"); err == nil {
+		t.Error("Expected error when no screenshot tool is installed")
+	}
+	if _, err := screenshotCommand("windows", noEnv, installed(), "shot.png"); err == nil {
+		t.Error("Expected error on Windows")
+	}
+}
+
+func TestCaptureScreenURL(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a fake browser script")
+	}
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// A fake browser that copies a PNG to the --screenshot path
+	shot := filepath.Join(dir, "page.png")
+	file, err := os.Create(shot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(file, image.NewRGBA(image.Rect(0, 0, 32, 20))); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	writeTestFile(t, dir, "chrome", "#!/bin/sh\nfor arg; do case $arg in --screenshot=*) cp \"$PAGE_PNG\" \"${arg#--screenshot=}\";; esac; done\n")
+	if err := os.Chmod(filepath.Join(dir, "chrome"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CHROME_PATH", filepath.Join(dir, "chrome"))
+	t.Setenv("PAGE_PNG", shot)
+
+	run := func(input CaptureScreenInput) (string, error) {
+		data, _ := json.Marshal(input)
+		return CaptureScreen(context.Background(), data)
+	}
+
+	result, err := run(CaptureScreenInput{Target: "url", URL: "http://localhost:3000/"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != "Screenshot of http://localhost:3000/ (image/png, 1 KiB, 32x20)" {
+		t.Errorf("Unexpected result %q", result)
+	}
+
+	if _, err := run(CaptureScreenInput{Target: "url", URL: "https://example.com/"}); err == nil || !strings.Contains(err.Error(), "not in the allowed hosts") {
+		t.Errorf("Expected the host to be rejected, got %v", err)
+	}
+	if _, err := run(CaptureScreenInput{Target: "window"}); err == nil {
+		t.Error("Expected error for an unknown target")
+	}
+}
+
+func TestBrowserScreenshotCommand(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("CHROME_PATH", "/opt/chrome")
+
+	command, err := browserScreenshotCommand(CaptureScreenInput{URL: "http://127.0.0.1:8080/app", Width: 390, Height: 844}, "/tmp/shot.png", "/tmp/profile")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	joined := strings.Join(command, " ")
+	for _, want := range []string{"/opt/chrome --headless=new", "--user-data-dir=/tmp/profile", "--screenshot=/tmp/shot.png", "--window-size=390,844", "http://127.0.0.1:8080/app"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected %q in %q", want, joined)
+		}
+	}
+
+	if _, err := browserScreenshotCommand(CaptureScreenInput{URL: "ftp://localhost/"}, "shot.png", "profile"); err == nil {
+		t.Error("Expected error for an unsupported scheme")
+	}
+}
@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/clipboard"
+)
+
+// maxClipboardBytes caps the clipboard text returned to the model.
+const maxClipboardBytes = 64 << 10
+
+// ClipboardDefinition defines the 'clipboard' tool.
+var ClipboardDefinition = agent.ToolDefinition{
+	Name: "clipboard",
+	Description: `Read or write the user's system clipboard.
+
+Actions:
+- 'read': return the text on the clipboard, e.g. when the user says "fix the code on my clipboard" (up to 64 KiB)
+- 'write': replace the clipboard with 'text', e.g. a snippet, command, or diff the user asked to copy
+
+The user approves each call.`,
+	InputSchema: ClipboardInputSchema,
+	Function:    Clipboard,
+
+	RequiresApproval: true,
+	Preview:          ClipboardPreview,
+}
+
+// ClipboardInput defines the input schema for the 'clipboard' tool.
+type ClipboardInput struct {
+	Action string `json:"action" jsonschema:"enum=read,enum=write" jsonschema_description:"What to do: read or write"`
+	Text   string `json:"text,omitempty" jsonschema_description:"The text to copy to the clipboard, for write"`
+}
+
+// ClipboardInputSchema is the JSON schema for the 'clipboard' tool's input.
+var ClipboardInputSchema = agent.GenerateSchema[ClipboardInput]()
+
+// ClipboardPreview describes the clipboard access for approval.
+func ClipboardPreview(input json.RawMessage) string {
+	clipboardInput := ClipboardInput{}
+	if err := json.Unmarshal(input, &clipboardInput); err != nil {
+		return string(input)
+	}
+	switch clipboardInput.Action {
+	case "read":
+		return "Read the clipboard"
+	case "write":
+		return "Copy to the clipboard:\n" + clipboardInput.Text
+	default:
+		return string(input)
+	}
+}
+
+// Clipboard implements the 'clipboard' tool.
+func Clipboard(ctx context.Context, input json.RawMessage) (string, error) {
+	clipboardInput := ClipboardInput{}
+	if err := json.Unmarshal(input, &clipboardInput); err != nil {
+		return "", err
+	}
+
+	switch clipboardInput.Action {
+	case "read":
+		text, err := clipboard.Read(ctx)
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(text) == "" {
+			return "The clipboard is empty.", nil
+		}
+		if len(text) > maxClipboardBytes {
+			agent.ReportTruncated(ctx)
+			return fmt.Sprintf("%s\n[clipboard truncated: showing %d of %d bytes]", text[:maxClipboardBytes], maxClipboardBytes, len(text)), nil
+		}
+		return text, nil
+	case "write":
+		if clipboardInput.Text == "" {
+			return "", fmt.Errorf("text is required for write")
+		}
+		if err := clipboard.Write(ctx, clipboardInput.Text); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Copied %d lines to the clipboard.", strings.Count(strings.TrimRight(clipboardInput.Text, "\n"), "\n")+1), nil
+	default:
+		return "", fmt.Errorf("unknown action %q; use read or write", clipboardInput.Action)
+	}
+}
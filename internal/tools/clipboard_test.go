@@ -0,0 +1,55 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestClipboard(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("uses a fake xclip")
+	}
+	// A fake xclip that keeps the clipboard in a file
+	dir := t.TempDir()
+	writeTestFile(t, dir, "xclip", "#!/bin/sh\nif [ \"$3\" = -o ]; then cat \"$CLIPBOARD_FILE\"; else cat > \"$CLIPBOARD_FILE\"; fi\n")
+	if err := os.Chmod(filepath.Join(dir, "xclip"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("WAYLAND_DISPLAY", "")
+	t.Setenv("CLIPBOARD_FILE", filepath.Join(dir, "clipboard"))
+
+	run := func(input ClipboardInput) (string, error) {
+		data, _ := json.Marshal(input)
+		return Clipboard(context.Background(), data)
+	}
+
+	result, err := run(ClipboardInput{Action: "write", Text: "a\nb\n"})
+	if err != nil || result != "Copied 2 lines to the clipboard." {
+		t.Fatalf("Unexpected write result %q, %v", result, err)
+	}
+	result, err = run(ClipboardInput{Action: "read"})
+	if err != nil || result != "a\nb\n" {
+		t.Errorf("Unexpected read result %q, %v", result, err)
+	}
+
+	if _, err := run(ClipboardInput{Action: "write"}); err == nil {
+		t.Error("Expected error for write without text")
+	}
+	if _, err := run(ClipboardInput{Action: "clear"}); err == nil {
+		t.Error("Expected error for an unknown action")
+	}
+}
+
+func TestClipboardPreview(t *testing.T) {
+	if got := ClipboardPreview(json.RawMessage(`{"action":"read"}`)); got != "Read the clipboard" {
+		t.Errorf("Unexpected read preview %q", got)
+	}
+	if got := ClipboardPreview(json.RawMessage(`{"action":"write","text":"go test ./..."}`)); got != "Copy to the clipboard:\ngo test ./..." {
+		t.Errorf("Unexpected write preview %q", got)
+	}
+}
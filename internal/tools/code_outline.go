@@ -0,0 +1,330 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"tiny-trae/internal/agent"
+)
+
+// CodeOutlineDefinition defines the 'code_outline' tool.
+var CodeOutlineDefinition = agent.ToolDefinition{
+	Name: "code_outline",
+	Description: `Show the structure of a source file: its functions, methods, types, and classes with their line ranges.
+
+Use this to get an overview of a large file before reading it, then read only the parts you need.
+Supported languages: Go, Python, JavaScript, TypeScript, Rust, Java, C, and C++.
+Go files are parsed, so their outlines are exact. The other languages are not parsed: their outlines are heuristic, matching declarations line by line, so declarations split across lines, generated by macros, or formatted unusually may be missing, and line ranges may be off. Read the file where it matters.`,
+	InputSchema: CodeOutlineInputSchema,
+	Function:    CodeOutline,
+}
+
+// CodeOutlineInput defines the input schema for the 'code_outline' tool.
+type CodeOutlineInput struct {
+	Path string `json:"path" jsonschema:"description=The relative path of a source file"`
+}
+
+// CodeOutlineInputSchema is the JSON schema for the 'code_outline' tool's input.
+var CodeOutlineInputSchema = agent.GenerateSchema[CodeOutlineInput]()
+
+// OutlineEntry is a declaration in a source file.
+type OutlineEntry struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Depth     int    `json:"depth"`
+}
+
+// CodeOutline implements the 'code_outline' tool.
+func CodeOutline(ctx context.Context, input json.RawMessage) (string, error) {
+	codeOutlineInput := CodeOutlineInput{}
+	err := json.Unmarshal(input, &codeOutlineInput)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(codeOutlineInput.Path)
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := Outline(codeOutlineInput.Path, content)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "No declarations found.", nil
+	}
+
+	var b strings.Builder
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "%s%s %s (lines %d-%d)\n", strings.Repeat("  ", entry.Depth), entry.Kind, entry.Name, entry.StartLine, entry.EndLine)
+	}
+	if !strings.EqualFold(filepath.Ext(codeOutlineInput.Path), ".go") {
+		// The description says so too, but the model should not have to
+		// remember it when reading the result
+		b.WriteString(heuristicOutlineNote)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// heuristicOutlineNote ends the outlines of files that were not parsed.
+const heuristicOutlineNote = "(heuristic outline: this language is matched line by line, not parsed, so declarations may be missing)"
+
+// Outline returns the declarations in a source file, picking an outliner
+// based on the file extension. Go is parsed with go/parser; the other
+// languages are matched line by line with patterns, so their outlines are
+// heuristic. Unsupported file types are errors.
+func Outline(path string, content []byte) ([]OutlineEntry, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".go":
+		return outlineGo(path, content)
+	case ".py", ".pyi":
+		return outlinePython(content), nil
+	default:
+		if rules, ok := braceLanguageRules[ext]; ok {
+			return outlineBraceLanguage(content, rules), nil
+		}
+		return nil, fmt.Errorf("unsupported file type for outline: %s", ext)
+	}
+}
+
+// outlineGo outlines Go source using the standard library parser.
+func outlineGo(path string, content []byte) ([]OutlineEntry, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Go file: %w", err)
+	}
+
+	lines := func(node ast.Node) (int, int) {
+		return fset.Position(node.Pos()).Line, fset.Position(node.End()).Line
+	}
+
+	var entries []OutlineEntry
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			kind, name := "func", d.Name.Name
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				kind = "method"
+				name = fmt.Sprintf("(%s) %s", exprString(d.Recv.List[0].Type), name)
+			}
+			start, end := lines(d)
+			entries = append(entries, OutlineEntry{Kind: kind, Name: name, StartLine: start, EndLine: end})
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch sp := spec.(type) {
+				case *ast.TypeSpec:
+					kind := "type"
+					switch sp.Type.(type) {
+					case *ast.StructType:
+						kind = "struct"
+					case *ast.InterfaceType:
+						kind = "interface"
+					}
+					start, end := lines(sp)
+					entries = append(entries, OutlineEntry{Kind: kind, Name: sp.Name.Name, StartLine: start, EndLine: end})
+				case *ast.ValueSpec:
+					kind := "var"
+					if d.Tok == token.CONST {
+						kind = "const"
+					}
+					start, end := lines(sp)
+					for _, name := range sp.Names {
+						entries = append(entries, OutlineEntry{Kind: kind, Name: name.Name, StartLine: start, EndLine: end})
+					}
+				}
+			}
+		}
+	}
+	return entries, nil
+}
+
+// exprString renders a receiver type expression such as *Agent or List[T].
+func exprString(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.StarExpr:
+		return "*" + exprString(e.X)
+	case *ast.IndexExpr:
+		return exprString(e.X) + "[" + exprString(e.Index) + "]"
+	case *ast.IndexListExpr:
+		params := make([]string, len(e.Indices))
+		for i, index := range e.Indices {
+			params[i] = exprString(index)
+		}
+		return exprString(e.X) + "[" + strings.Join(params, ", ") + "]"
+	default:
+		return "?"
+	}
+}
+
+// pythonDeclRegexp matches Python class and function definitions.
+var pythonDeclRegexp = regexp.MustCompile(`^(\s*)(?:async\s+)?(def|class)\s+(\w+)`)
+
+// outlinePython outlines Python source. A declaration ends before the next
+// non-blank line that is indented no deeper than the declaration itself.
+func outlinePython(content []byte) []OutlineEntry {
+	lines := strings.Split(string(content), "\n")
+	var entries []OutlineEntry
+	for i, line := range lines {
+		m := pythonDeclRegexp.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		indent := len(m[1])
+
+		end := i + 1
+		for j := i + 1; j < len(lines); j++ {
+			trimmed := strings.TrimSpace(lines[j])
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			if len(lines[j])-len(strings.TrimLeft(lines[j], " \t")) <= indent {
+				break
+			}
+			end = j + 1
+		}
+
+		kind := "function"
+		if m[2] == "class" {
+			kind = "class"
+		}
+		entries = append(entries, OutlineEntry{Kind: kind, Name: m[3], StartLine: i + 1, EndLine: end, Depth: nestingDepth(entries, i+1)})
+	}
+	return entries
+}
+
+// braceRule matches a declaration in a brace-delimited language. The first
+// capture group of Pattern is the declaration's name.
+type braceRule struct {
+	Kind    string
+	Pattern *regexp.Regexp
+}
+
+var (
+	jsRules = []braceRule{
+		{"class", regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:abstract\s+)?class\s+(\w+)`)},
+		{"interface", regexp.MustCompile(`^\s*(?:export\s+)?interface\s+(\w+)`)},
+		{"function", regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*(\w+)`)},
+		{"function", regexp.MustCompile(`^\s*(?:export\s+)?(?:const|let|var)\s+(\w+)\s*=\s*(?:async\s+)?(?:function\b|\([^)]*\)\s*(?::\s*[^=]+)?=>|\w+\s*=>)`)},
+		{"method", regexp.MustCompile(`^\s+(?:(?:public|private|protected|static|async|readonly|get|set)\s+)*(\w+)\s*\([^)]*\)\s*(?::\s*[^{]+)?\{`)},
+	}
+	rustRules = []braceRule{
+		{"fn", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:const\s+)?(?:async\s+)?(?:unsafe\s+)?fn\s+(\w+)`)},
+		{"struct", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?struct\s+(\w+)`)},
+		{"enum", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?enum\s+(\w+)`)},
+		{"trait", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?trait\s+(\w+)`)},
+		{"impl", regexp.MustCompile(`^\s*impl(?:<[^>]*>)?\s+([\w:<>, ]+?)\s*(?:where\b.*)?\{?\s*$`)},
+		{"mod", regexp.MustCompile(`^\s*(?:pub\s+)?mod\s+(\w+)\s*\{`)},
+	}
+	javaRules = []braceRule{
+		{"class", regexp.MustCompile(`^\s*(?:(?:public|private|protected|static|final|abstract|sealed)\s+)*(?:class|record)\s+(\w+)`)},
+		{"interface", regexp.MustCompile(`^\s*(?:(?:public|private|protected|static)\s+)*interface\s+(\w+)`)},
+		{"enum", regexp.MustCompile(`^\s*(?:(?:public|private|protected|static)\s+)*enum\s+(\w+)`)},
+		{"method", regexp.MustCompile(`^\s*(?:(?:public|private|protected|static|final|abstract|synchronized|native)\s+)+[\w<>\[\], ?]+\s+(\w+)\s*\([^;]*$`)},
+	}
+	cRules = []braceRule{
+		{"struct", regexp.MustCompile(`^\s*(?:typedef\s+)?struct\s+(\w+)\s*\{`)},
+		{"class", regexp.MustCompile(`^\s*class\s+(\w+)[^;]*$`)},
+		{"namespace", regexp.MustCompile(`^\s*namespace\s+(\w+)`)},
+		{"function", regexp.MustCompile(`^[A-Za-z_][\w\s\*&:<>,]*?\b([A-Za-z_][\w:~]*)\s*\([^;]*\)\s*(?:const\s*)?\{?\s*$`)},
+	}
+)
+
+// braceLanguageRules maps file extensions to their declaration rules.
+var braceLanguageRules = map[string][]braceRule{
+	".js":   jsRules,
+	".jsx":  jsRules,
+	".mjs":  jsRules,
+	".ts":   jsRules,
+	".tsx":  jsRules,
+	".rs":   rustRules,
+	".java": javaRules,
+	".c":    cRules,
+	".h":    cRules,
+	".cc":   cRules,
+	".cpp":  cRules,
+	".hpp":  cRules,
+}
+
+// braceControlKeywords are names that look like declarations to the method
+// patterns but are control flow.
+var braceControlKeywords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true,
+	"return": true, "else": true, "do": true, "try": true, "match": true,
+}
+
+// outlineBraceLanguage outlines a brace-delimited language line by line. A
+// declaration ends at the brace that closes the first brace opened on or
+// after its first line. Braces inside strings and comments are not
+// distinguished, so ranges are a best effort.
+func outlineBraceLanguage(content []byte, rules []braceRule) []OutlineEntry {
+	lines := strings.Split(string(content), "\n")
+	var entries []OutlineEntry
+	for i, line := range lines {
+		for _, rule := range rules {
+			m := rule.Pattern.FindStringSubmatch(line)
+			if m == nil || braceControlKeywords[m[1]] {
+				continue
+			}
+			end := closingBraceLine(lines, i)
+			if end < 0 {
+				// A declaration without a body, e.g. a prototype
+				break
+			}
+			name := strings.TrimSpace(m[1])
+			entries = append(entries, OutlineEntry{Kind: rule.Kind, Name: name, StartLine: i + 1, EndLine: end + 1, Depth: nestingDepth(entries, i+1)})
+			break
+		}
+	}
+	return entries
+}
+
+// closingBraceLine returns the index of the line that closes the first brace
+// opened at or after line start, or -1 if a ';' ends the declaration first.
+func closingBraceLine(lines []string, start int) int {
+	depth := 0
+	opened := false
+	for i := start; i < len(lines); i++ {
+		for _, c := range lines[i] {
+			switch c {
+			case '{':
+				depth++
+				opened = true
+			case '}':
+				depth--
+				if opened && depth == 0 {
+					return i
+				}
+			case ';':
+				if !opened {
+					return -1
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// nestingDepth returns how many of the existing entries enclose line.
+func nestingDepth(entries []OutlineEntry, line int) int {
+	depth := 0
+	for _, entry := range entries {
+		if entry.StartLine < line && entry.EndLine >= line {
+			depth++
+		}
+	}
+	return depth
+}
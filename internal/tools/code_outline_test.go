@@ -0,0 +1,175 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCodeOutline(t *testing.T) {
+	tempDir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		expected []string
+	}{
+		{
+			name: "go",
+			file: "main.go",
+			content: `package main
+
+const Version = "1.0"
+
+type Agent struct {
+	name string
+}
+
+type Runner interface {
+	Run() error
+}
+
+func (a *Agent) Run() error {
+	return nil
+}
+
+func main() {
+}
+`,
+			expected: []string{
+				"const Version (lines 3-3)",
+				"struct Agent (lines 5-7)",
+				"interface Runner (lines 9-11)",
+				"method (*Agent) Run (lines 13-15)",
+				"func main (lines 17-18)",
+			},
+		},
+		{
+			name: "python",
+			file: "app.py",
+			content: `import os
+
+class Server:
+    def start(self):
+        pass
+
+    async def stop(self):
+        pass
+
+def main():
+    Server().start()
+`,
+			expected: []string{
+				"class Server (lines 3-8)",
+				"  function start (lines 4-5)",
+				"  function stop (lines 7-8)",
+				"function main (lines 10-11)",
+			},
+		},
+		{
+			name: "typescript",
+			file: "app.ts",
+			content: `export class Store {
+  private items: string[] = [];
+
+  add(item: string): void {
+    if (item) {
+      this.items.push(item);
+    }
+  }
+}
+
+export function create(): Store {
+  return new Store();
+}
+
+const helper = (x: number) => {
+  return x * 2;
+};
+`,
+			expected: []string{
+				"class Store (lines 1-9)",
+				"  method add (lines 4-8)",
+				"function create (lines 11-13)",
+				"function helper (lines 15-17)",
+			},
+		},
+		{
+			name: "rust",
+			file: "lib.rs",
+			content: `pub struct Point {
+    x: i32,
+}
+
+impl Point {
+    pub fn new(x: i32) -> Self {
+        Point { x }
+    }
+}
+`,
+			expected: []string{
+				"struct Point (lines 1-3)",
+				"impl Point (lines 5-9)",
+				"  fn new (lines 6-8)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tempDir, tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to create file: %v", err)
+			}
+
+			inputJSON, err := json.Marshal(CodeOutlineInput{Path: path})
+			if err != nil {
+				t.Fatalf("Failed to marshal input: %v", err)
+			}
+
+			result, err := CodeOutline(context.Background(), inputJSON)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			expected := tt.expected
+			if filepath.Ext(tt.file) != ".go" {
+				expected = append(expected, heuristicOutlineNote)
+			}
+			if result != strings.Join(expected, "\n") {
+				t.Errorf("Expected outline:\n%s\ngot:\n%s", strings.Join(expected, "\n"), result)
+			}
+		})
+	}
+}
+
+func TestCodeOutlineErrors(t *testing.T) {
+	tempDir := t.TempDir()
+	unsupported := filepath.Join(tempDir, "notes.txt")
+	if err := os.WriteFile(unsupported, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	for _, path := range []string{unsupported, filepath.Join(tempDir, "missing.go")} {
+		inputJSON, _ := json.Marshal(CodeOutlineInput{Path: path})
+		if _, err := CodeOutline(context.Background(), inputJSON); err == nil {
+			t.Errorf("Expected error for %s", path)
+		}
+	}
+}
+
+func TestCodeOutlineDefinition(t *testing.T) {
+	if CodeOutlineDefinition.Name != "code_outline" {
+		t.Errorf("Expected name 'code_outline', got %q", CodeOutlineDefinition.Name)
+	}
+	if CodeOutlineDefinition.Description == "" {
+		t.Error("Expected non-empty description")
+	}
+	if CodeOutlineDefinition.Function == nil {
+		t.Error("Expected non-nil function")
+	}
+}
@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/git"
+)
+
+// DefaultPRRemote is the remote 'create_pr' pushes to when none is given.
+const DefaultPRRemote = "origin"
+
+// CreatePRDefinition defines the 'create_pr' tool.
+var CreatePRDefinition = agent.ToolDefinition{
+	Name: "create_pr",
+	Description: `Push the current branch and open a GitHub pull request for it with the gh CLI.
+
+Commit the changes first. Write the title like a commit subject, and use the body to explain what the change does, why, and how it was tested.
+The branch is pushed to 'remote' (default: origin) and the PR targets 'base' (default: the repository's default branch). Returns the PR's URL.`,
+	InputSchema: CreatePRInputSchema,
+	Function:    CreatePR,
+
+	RequiresApproval: true,
+	Preview:          CreatePRPreview,
+}
+
+// CreatePRInput defines the input schema for the 'create_pr' tool.
+type CreatePRInput struct {
+	Title  string `json:"title" jsonschema_description:"The pull request title"`
+	Body   string `json:"body" jsonschema_description:"The pull request description in Markdown"`
+	Base   string `json:"base,omitempty" jsonschema_description:"The branch to merge into. Defaults to the repository's default branch"`
+	Remote string `json:"remote,omitempty" jsonschema_description:"The remote to push the branch to. Defaults to origin"`
+	Draft  bool   `json:"draft,omitempty" jsonschema_description:"Open the pull request as a draft"`
+	Path   string `json:"path,omitempty" jsonschema_description:"A directory inside the repository. Defaults to the current directory"`
+}
+
+// CreatePRInputSchema is the JSON schema for the 'create_pr' tool's input.
+var CreatePRInputSchema = agent.GenerateSchema[CreatePRInput]()
+
+// remote returns the remote to push to.
+func (i CreatePRInput) remote() string {
+	if i.Remote == "" {
+		return DefaultPRRemote
+	}
+	return i.Remote
+}
+
+// CreatePRPreview shows where the PR will be opened and its title and body.
+func CreatePRPreview(input json.RawMessage) string {
+	prInput := CreatePRInput{}
+	if err := json.Unmarshal(input, &prInput); err != nil {
+		return string(input)
+	}
+	base := prInput.Base
+	if base == "" {
+		base = "(default branch)"
+	}
+	kind := "pull request"
+	if prInput.Draft {
+		kind = "draft pull request"
+	}
+	return fmt.Sprintf("Push to %s and open a %s into %s\n\n%s\n\n%s", prInput.remote(), kind, base, prInput.Title, prInput.Body)
+}
+
+// CreatePR implements the 'create_pr' tool.
+func CreatePR(ctx context.Context, input json.RawMessage) (string, error) {
+	prInput := CreatePRInput{}
+	if err := json.Unmarshal(input, &prInput); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(prInput.Title) == "" {
+		return "", fmt.Errorf("title must not be empty")
+	}
+	if _, err := exec.LookPath("gh"); err != nil {
+		return "", fmt.Errorf("the gh CLI is not installed; see https://cli.github.com")
+	}
+
+	dir := prInput.Path
+	branch, err := git.CurrentBranch(ctx, dir)
+	if err != nil {
+		return "", err
+	}
+	if branch == prInput.Base {
+		return "", fmt.Errorf("the current branch is the base branch %s; create a branch for the change first", branch)
+	}
+
+	if _, err := git.Run(ctx, dir, "push", "--set-upstream", prInput.remote(), branch); err != nil {
+		return "", err
+	}
+
+	args := []string{"pr", "create", "--title", prInput.Title, "--body-file", "-", "--head", branch}
+	if prInput.Base != "" {
+		args = append(args, "--base", prInput.Base)
+	}
+	if prInput.Draft {
+		args = append(args, "--draft")
+	}
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(prInput.Body)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("gh pr create failed: %v - %s", err, strings.TrimSpace(string(output)))
+	}
+
+	// gh prints progress before the URL on its last line
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	return fmt.Sprintf("Pushed %s to %s and opened %s", branch, prInput.remote(), lines[len(lines)-1]), nil
+}
@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"tiny-trae/internal/git"
+)
+
+// installFakeGH puts a gh script on PATH that records its arguments and
+// stdin in dir and prints a PR URL.
+func installFakeGH(t *testing.T, dir string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake gh script requires a POSIX shell")
+	}
+	bin := t.TempDir()
+	script := "#!/bin/sh\necho \"$@\" > " + filepath.Join(dir, "gh-args") + "\ncat > " + filepath.Join(dir, "gh-body") + "\necho 'Creating pull request...'\necho https://github.com/o/r/pull/7\n"
+	if err := os.WriteFile(filepath.Join(bin, "gh"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestCreatePR(t *testing.T) {
+	dir := initGitRepo(t)
+	ctx := context.Background()
+
+	remote := t.TempDir()
+	if _, err := git.Run(ctx, remote, "init", "-q", "--bare"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git.Run(ctx, dir, "remote", "add", "origin", remote); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git.Run(ctx, dir, "switch", "-q", "-c", "feature"); err != nil {
+		t.Fatal(err)
+	}
+	logDir := t.TempDir()
+	installFakeGH(t, logDir)
+
+	input, _ := json.Marshal(CreatePRInput{Title: "Add feature", Body: "Adds the feature.", Base: "main", Draft: true, Path: dir})
+	result, err := CreatePR(ctx, input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result != "Pushed feature to origin and opened https://github.com/o/r/pull/7" {
+		t.Errorf("Unexpected result %q", result)
+	}
+
+	if _, err := git.Run(ctx, remote, "rev-parse", "--verify", "feature"); err != nil {
+		t.Errorf("Expected feature to be pushed: %v", err)
+	}
+	args, _ := os.ReadFile(filepath.Join(logDir, "gh-args"))
+	if strings.TrimSpace(string(args)) != "pr create --title Add feature --body-file - --head feature --base main --draft" {
+		t.Errorf("Unexpected gh arguments %q", args)
+	}
+	body, _ := os.ReadFile(filepath.Join(logDir, "gh-body"))
+	if string(body) != "Adds the feature." {
+		t.Errorf("Unexpected PR body %q", body)
+	}
+}
+
+func TestCreatePROnBaseBranch(t *testing.T) {
+	dir := initGitRepo(t)
+	installFakeGH(t, t.TempDir())
+
+	input, _ := json.Marshal(CreatePRInput{Title: "Add feature", Base: "main", Path: dir})
+	if _, err := CreatePR(context.Background(), input); err == nil {
+		t.Error("Expected error when opening a PR from the base branch")
+	}
+}
+
+func TestCreatePRPreview(t *testing.T) {
+	preview := CreatePRPreview(json.RawMessage(`{"title":"Fix bug","body":"Details."}`))
+	if preview != "Push to origin and open a pull request into (default branch)\n\nFix bug\n\nDetails." {
+		t.Errorf("Unexpected preview %q", preview)
+	}
+}
@@ -0,0 +1,288 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"tiny-trae/internal/agent"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// TagCustom marks the tools defined in custom tool files.
+	TagCustom = "custom"
+	// defaultCustomToolTimeout bounds a custom tool's command when its
+	// definition sets no timeout.
+	defaultCustomToolTimeout = 2 * time.Minute
+)
+
+// CustomToolsPath returns the location of the user's custom tool file.
+func CustomToolsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tiny-trae", "tools.yaml"), nil
+}
+
+// customToolsConfig is the format of a custom tool file.
+type customToolsConfig struct {
+	Tools []customTool `yaml:"tools"`
+}
+
+// customTool is a shell-backed tool defined in a custom tool file.
+type customTool struct {
+	Name        string                     `yaml:"name"`
+	Description string                     `yaml:"description"`
+	Parameters  map[string]customParameter `yaml:"parameters"`
+	// Command is run with bash -c after each {{name}} is replaced by the
+	// shell-quoted value of that parameter.
+	Command string `yaml:"command"`
+	// Dir is the working directory, relative to the directory the agent
+	// runs in.
+	Dir              string `yaml:"dir"`
+	Timeout          string `yaml:"timeout"`
+	RequiresApproval *bool  `yaml:"requires_approval"`
+}
+
+// customParameter describes one input of a custom tool.
+type customParameter struct {
+	Type        string   `yaml:"type"`
+	Description string   `yaml:"description"`
+	Required    bool     `yaml:"required"`
+	Enum        []string `yaml:"enum"`
+}
+
+// placeholderPattern matches a {{name}} placeholder in a command template.
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// toolNamePattern is the set of names the API accepts for tools.
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// LoadCustomTools reads the custom tools defined in the user's file at
+// path. A missing file defines no tools. The tools require approval unless
+// they set requires_approval: false.
+func LoadCustomTools(path string) ([]agent.ToolDefinition, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var config customToolsConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return customDefinitions(path, config.Tools, false)
+}
+
+// ProjectCustomTools returns the custom tools under the tools key of the
+// project settings file at path, as parsed into nodes. They always require
+// approval, since anyone who can change the repository can change them.
+func ProjectCustomTools(path string, nodes []yaml.Node) ([]agent.ToolDefinition, error) {
+	tools := make([]customTool, len(nodes))
+	for i := range nodes {
+		if err := nodes[i].Decode(&tools[i]); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: tools: %w", path, err)
+		}
+	}
+	return customDefinitions(path, tools, true)
+}
+
+// customDefinitions turns the tools defined in the file at path into
+// ToolDefinitions.
+func customDefinitions(path string, tools []customTool, project bool) ([]agent.ToolDefinition, error) {
+	var definitions []agent.ToolDefinition
+	for _, tool := range tools {
+		definition, err := tool.definition(project)
+		if err != nil {
+			return nil, fmt.Errorf("%s: tool %q: %w", path, tool.Name, err)
+		}
+		definitions = append(definitions, definition)
+	}
+	return definitions, nil
+}
+
+// definition validates the tool and turns it into a ToolDefinition.
+func (t customTool) definition(project bool) (agent.ToolDefinition, error) {
+	if !toolNamePattern.MatchString(t.Name) {
+		return agent.ToolDefinition{}, fmt.Errorf("name must be 1 to 64 letters, digits, underscores, or dashes")
+	}
+	if strings.TrimSpace(t.Description) == "" {
+		return agent.ToolDefinition{}, fmt.Errorf("description is required")
+	}
+	if strings.TrimSpace(t.Command) == "" {
+		return agent.ToolDefinition{}, fmt.Errorf("command is required")
+	}
+	for name, param := range t.Parameters {
+		switch param.Type {
+		case "", "string", "integer", "number", "boolean":
+		default:
+			return agent.ToolDefinition{}, fmt.Errorf("parameter %s: unknown type %q (want string, integer, number, or boolean)", name, param.Type)
+		}
+	}
+	for _, match := range placeholderPattern.FindAllStringSubmatch(t.Command, -1) {
+		if _, ok := t.Parameters[match[1]]; !ok {
+			return agent.ToolDefinition{}, fmt.Errorf("command uses {{%s}}, which is not a parameter", match[1])
+		}
+	}
+	timeout := defaultCustomToolTimeout
+	if t.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(t.Timeout); err != nil || timeout <= 0 {
+			return agent.ToolDefinition{}, fmt.Errorf("invalid timeout %q", t.Timeout)
+		}
+	}
+
+	requiresApproval := project || t.RequiresApproval == nil || *t.RequiresApproval
+	return agent.ToolDefinition{
+		Name:        t.Name,
+		Description: t.Description,
+		InputSchema: t.inputSchema(),
+		Function: func(ctx context.Context, input json.RawMessage) (string, error) {
+			return t.run(ctx, input, timeout)
+		},
+		RequiresApproval: requiresApproval,
+		Preview: func(input json.RawMessage) string {
+			command, err := t.expand(input)
+			if err != nil {
+				return err.Error()
+			}
+			return command
+		},
+	}, nil
+}
+
+// inputSchema returns the JSON schema of the tool's parameters.
+func (t customTool) inputSchema() anthropic.ToolInputSchemaParam {
+	properties := make(map[string]any, len(t.Parameters))
+	var required []string
+	for name, param := range t.Parameters {
+		property := map[string]any{"type": param.paramType()}
+		if param.Description != "" {
+			property["description"] = param.Description
+		}
+		if len(param.Enum) > 0 {
+			property["enum"] = param.Enum
+		}
+		properties[name] = property
+		if param.Required {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(required)
+	return anthropic.ToolInputSchemaParam{Properties: properties, Required: required}
+}
+
+// paramType returns the parameter's JSON schema type; strings are the default.
+func (p customParameter) paramType() string {
+	if p.Type == "" {
+		return "string"
+	}
+	return p.Type
+}
+
+// expand returns the command with each placeholder replaced by the
+// shell-quoted value of its parameter. Missing optional parameters expand to
+// an empty string.
+func (t customTool) expand(input json.RawMessage) (string, error) {
+	var values map[string]any
+	if err := json.Unmarshal(input, &values); err != nil {
+		return "", err
+	}
+	args := make(map[string]string, len(t.Parameters))
+	for name, param := range t.Parameters {
+		value, ok := values[name]
+		if !ok || value == nil {
+			if param.Required {
+				return "", fmt.Errorf("missing required parameter %s", name)
+			}
+			continue
+		}
+		arg, err := param.format(value)
+		if err != nil {
+			return "", fmt.Errorf("parameter %s: %w", name, err)
+		}
+		args[name] = arg
+	}
+	return placeholderPattern.ReplaceAllStringFunc(t.Command, func(placeholder string) string {
+		name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+		return shellQuote(args[name])
+	}), nil
+}
+
+// format checks value against the parameter's type and enum and returns it
+// as a string.
+func (p customParameter) format(value any) (string, error) {
+	var arg string
+	switch p.paramType() {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("want a string")
+		}
+		arg = s
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != float64(int64(n)) {
+			return "", fmt.Errorf("want an integer")
+		}
+		arg = strconv.FormatInt(int64(n), 10)
+	case "number":
+		n, ok := value.(float64)
+		if !ok {
+			return "", fmt.Errorf("want a number")
+		}
+		arg = strconv.FormatFloat(n, 'f', -1, 64)
+	case "boolean":
+		b, ok := value.(bool)
+		if !ok {
+			return "", fmt.Errorf("want true or false")
+		}
+		arg = strconv.FormatBool(b)
+	}
+	if len(p.Enum) > 0 && !slices.Contains(p.Enum, arg) {
+		return "", fmt.Errorf("%q is not one of %s", arg, strings.Join(p.Enum, ", "))
+	}
+	return arg, nil
+}
+
+// shellQuote quotes s as a single word for bash.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// run expands and runs the command.
+func (t customTool) run(ctx context.Context, input json.RawMessage, timeout time.Duration) (string, error) {
+	command, err := t.expand(input)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "bash", "-c", command)
+	cmd.Dir = t.Dir
+	output, err := runWithLimits(ctx, cmd, CommandLimits)
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("%s timed out after %s - %s", t.Name, timeout, output)
+	}
+	if err != nil {
+		return "", fmt.Errorf("command execution error: %v - %s", err, output)
+	}
+	return string(output), nil
+}
@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestLoadCustomTools(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("custom tools run bash")
+	}
+	dir := t.TempDir()
+	writeTestFile(t, dir, "tools.yaml", `tools:
+  - name: greet
+    description: Greet someone.
+    parameters:
+      name:
+        description: Who to greet
+        required: true
+      times:
+        type: integer
+      tone:
+        enum: [polite, loud]
+    command: echo hello {{name}} {{ times }} {{tone}}
+    requires_approval: false
+  - name: slow
+    description: Takes too long.
+    command: sleep 5
+    timeout: 100ms
+`)
+
+	tools, err := LoadCustomTools(filepath.Join(dir, "tools.yaml"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tools) != 2 || tools[0].Name != "greet" || tools[1].Name != "slow" {
+		t.Fatalf("Unexpected tools %+v", tools)
+	}
+	greet := tools[0]
+	if greet.RequiresApproval || !tools[1].RequiresApproval {
+		t.Error("Expected approval to default to required and be overridable")
+	}
+	if len(greet.InputSchema.Required) != 1 || greet.InputSchema.Required[0] != "name" {
+		t.Errorf("Unexpected required parameters %v", greet.InputSchema.Required)
+	}
+
+	// Values are quoted, so they cannot inject commands
+	input := json.RawMessage(`{"name":"o'brien; rm -rf /","times":3}`)
+	if preview := greet.Preview(input); preview != `echo hello 'o'\''brien; rm -rf /' '3' ''` {
+		t.Errorf("Unexpected preview %q", preview)
+	}
+	result, err := greet.Function(context.Background(), input)
+	if err != nil || result != "hello o'brien; rm -rf / 3 \n" {
+		t.Errorf("Unexpected result %q, %v", result, err)
+	}
+
+	for _, bad := range []string{`{}`, `{"name":"x","times":1.5}`, `{"name":"x","tone":"rude"}`, `{"name":7}`} {
+		if _, err := greet.Function(context.Background(), json.RawMessage(bad)); err == nil {
+			t.Errorf("Expected error for input %s", bad)
+		}
+	}
+
+	if _, err := tools[1].Function(context.Background(), json.RawMessage(`{}`)); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+}
+
+func TestProjectCustomTools(t *testing.T) {
+	var project struct {
+		Tools []yaml.Node `yaml:"tools"`
+	}
+	if err := yaml.Unmarshal([]byte(`tools:
+  - name: make_test
+    description: Run the tests.
+    command: make test
+    requires_approval: false
+`), &project); err != nil {
+		t.Fatal(err)
+	}
+	tools, err := ProjectCustomTools(".trae.yaml", project.Tools)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tools) != 1 || tools[0].Name != "make_test" || !tools[0].RequiresApproval {
+		t.Errorf("Expected project tools to always require approval, got %+v", tools)
+	}
+
+	if err := yaml.Unmarshal([]byte("tools:\n  - name: x\n    command: true\n"), &project); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ProjectCustomTools(".trae.yaml", project.Tools); err == nil || !strings.Contains(err.Error(), ".trae.yaml") {
+		t.Errorf("Expected an error naming the file, got %v", err)
+	}
+}
+
+func TestLoadCustomToolsInvalid(t *testing.T) {
+	if tools, err := LoadCustomTools(filepath.Join(t.TempDir(), "missing.yaml")); tools != nil || err != nil {
+		t.Errorf("Expected nothing for a missing file, got %v, %v", tools, err)
+	}
+
+	for _, config := range []string{
+		"tools:\n  - name: bad name\n    description: x\n    command: true\n",
+		"tools:\n  - name: x\n    command: true\n",
+		"tools:\n  - name: x\n    description: x\n    command: echo {{missing}}\n",
+		"tools:\n  - name: x\n    description: x\n    command: true\n    parameters:\n      n:\n        type: list\n",
+		"tools:\n  - name: x\n    description: x\n    command: true\n    timeout: soon\n",
+	} {
+		dir := t.TempDir()
+		writeTestFile(t, dir, "tools.yaml", config)
+		if _, err := LoadCustomTools(filepath.Join(dir, "tools.yaml")); err == nil {
+			t.Errorf("Expected error for config %q", config)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"tiny-trae/internal/agent"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultDBMaxRows is the number of rows 'db_query' returns by default.
+	DefaultDBMaxRows = 100
+	// maxDBRows caps the rows the model can ask for.
+	maxDBRows = 1000
+	// maxDBCellLength caps the characters shown per value.
+	maxDBCellLength = 200
+	// dbQueryTimeout bounds how long a query may run.
+	dbQueryTimeout = 30 * time.Second
+)
+
+// dbDrivers maps the driver names accepted in the database config to the
+// database/sql drivers that implement them.
+var dbDrivers = map[string]string{
+	"postgres": "postgres",
+	"mysql":    "mysql",
+	"sqlite":   "sqlite3",
+}
+
+// readOnlyStatements are the statement keywords 'db_query' accepts.
+var readOnlyStatements = []string{"SELECT", "WITH", "EXPLAIN", "SHOW", "DESCRIBE", "DESC", "VALUES", "TABLE", "PRAGMA"}
+
+// DBQueryDefinition defines the 'db_query' tool.
+var DBQueryDefinition = agent.ToolDefinition{
+	Name: "db_query",
+	Description: `Run a read-only SQL query against one of the user's configured databases and return the rows as a table.
+
+Only a single SELECT, WITH, EXPLAIN, SHOW, DESCRIBE, VALUES, or PRAGMA statement is accepted, and it runs in a read-only transaction that is rolled back, so nothing can be changed.
+At most 100 rows are returned by default (set 'max_rows', up to 1000); add ORDER BY and LIMIT to pick the rows you need. Call with an empty query to list the configured databases.`,
+	InputSchema: DBQueryInputSchema,
+	Function:    DBQuery,
+}
+
+// DBQueryInput defines the input schema for the 'db_query' tool.
+type DBQueryInput struct {
+	Database string `json:"database,omitempty" jsonschema_description:"The name of the database in the user's config. May be omitted if only one is configured"`
+	Query    string `json:"query" jsonschema_description:"The SQL query to run"`
+	MaxRows  int    `json:"max_rows,omitempty" jsonschema_description:"The maximum number of rows to return. Defaults to 100, at most 1000"`
+}
+
+// DBQueryInputSchema is the JSON schema for the 'db_query' tool's input.
+var DBQueryInputSchema = agent.GenerateSchema[DBQueryInput]()
+
+// dbConfig is the format of the user's database config file.
+type dbConfig struct {
+	Databases map[string]dbConnection `yaml:"databases"`
+}
+
+// dbConnection is one configured database.
+type dbConnection struct {
+	Driver string `yaml:"driver"`
+	DSN    string `yaml:"dsn"`
+}
+
+// DBConfigPath returns the location of the user's database config file. It
+// lives outside the project because DSNs often contain credentials.
+func DBConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tiny-trae", "databases.yaml"), nil
+}
+
+// loadDBConfig reads the database config file. Environment variables in
+// DSNs, such as ${PGPASSWORD}, are expanded.
+func loadDBConfig() (dbConfig, error) {
+	path, err := DBConfigPath()
+	if err != nil {
+		return dbConfig{}, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return dbConfig{}, fmt.Errorf("no databases are configured; ask the user to add them to %s", path)
+	}
+	if err != nil {
+		return dbConfig{}, err
+	}
+	var config dbConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return dbConfig{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for name, conn := range config.Databases {
+		if _, ok := dbDrivers[conn.Driver]; !ok {
+			return dbConfig{}, fmt.Errorf("database %s: unknown driver %q (want postgres, mysql, or sqlite)", name, conn.Driver)
+		}
+		conn.DSN = os.ExpandEnv(conn.DSN)
+		config.Databases[name] = conn
+	}
+	return config, nil
+}
+
+// names returns the configured database names, sorted.
+func (c dbConfig) names() []string {
+	names := make([]string, 0, len(c.Databases))
+	for name := range c.Databases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// connection returns the named database, or the only one if name is empty.
+func (c dbConfig) connection(name string) (dbConnection, error) {
+	if name == "" && len(c.Databases) == 1 {
+		return c.Databases[c.names()[0]], nil
+	}
+	conn, ok := c.Databases[name]
+	if !ok {
+		return dbConnection{}, fmt.Errorf("unknown database %q (configured: %s)", name, strings.Join(c.names(), ", "))
+	}
+	return conn, nil
+}
+
+// checkReadOnlyQuery rejects anything but a single statement that starts
+// with one of readOnlyStatements. The read-only transaction is the real
+// guard; this catches mistakes early with a clearer message.
+func checkReadOnlyQuery(query string) error {
+	code := stripSQLLiterals(query)
+	code = strings.TrimRight(strings.TrimSpace(code), ";")
+	if strings.TrimSpace(code) == "" {
+		return fmt.Errorf("query must not be empty")
+	}
+	if strings.Contains(code, ";") {
+		return fmt.Errorf("only a single statement can be run")
+	}
+	keyword := strings.ToUpper(strings.Fields(code)[0])
+	for _, allowed := range readOnlyStatements {
+		if keyword == allowed {
+			// PRAGMA name = value changes SQLite settings
+			if keyword == "PRAGMA" && strings.Contains(code, "=") {
+				return fmt.Errorf("PRAGMA statements that set a value are not allowed")
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%s statements are not allowed; only read-only queries can be run", keyword)
+}
+
+// stripSQLLiterals blanks out comments and quoted strings and identifiers so
+// that keywords and semicolons inside them are ignored.
+func stripSQLLiterals(query string) string {
+	var b strings.Builder
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return b.String()
+			}
+			i += end
+			b.WriteByte('\n')
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return b.String()
+			}
+			i += end + 3
+			b.WriteByte(' ')
+		case c == '\'' || c == '"' || c == '`':
+			// Doubled quotes inside a literal are handled as two adjacent literals
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				return b.String()
+			}
+			i += end + 1
+			b.WriteString("''")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// DBQuery implements the 'db_query' tool.
+func DBQuery(ctx context.Context, input json.RawMessage) (string, error) {
+	queryInput := DBQueryInput{}
+	if err := json.Unmarshal(input, &queryInput); err != nil {
+		return "", err
+	}
+	config, err := loadDBConfig()
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(queryInput.Query) == "" {
+		var lines []string
+		for _, name := range config.names() {
+			lines = append(lines, fmt.Sprintf("%s (%s)", name, config.Databases[name].Driver))
+		}
+		return "Configured databases:\n" + strings.Join(lines, "\n"), nil
+	}
+	if err := checkReadOnlyQuery(queryInput.Query); err != nil {
+		return "", err
+	}
+	conn, err := config.connection(queryInput.Database)
+	if err != nil {
+		return "", err
+	}
+	maxRows := queryInput.MaxRows
+	if maxRows <= 0 {
+		maxRows = DefaultDBMaxRows
+	}
+	maxRows = min(maxRows, maxDBRows)
+
+	dsn := conn.DSN
+	if conn.Driver == "sqlite" {
+		// The SQLite driver ignores read-only transactions; open the file read-only
+		dsn = withSQLiteReadOnly(dsn)
+	}
+	db, err := sql.Open(dbDrivers[conn.Driver], dsn)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, dbQueryTimeout)
+	defer cancel()
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, queryInput.Query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	return formatRows(rows, maxRows)
+}
+
+// withSQLiteReadOnly makes a SQLite DSN open the database read-only.
+func withSQLiteReadOnly(dsn string) string {
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	if !strings.HasPrefix(dsn, "file:") {
+		dsn = "file:" + dsn
+	}
+	return dsn + separator + "mode=ro&_query_only=1"
+}
+
+// formatRows renders at most maxRows rows as a pipe-separated table and
+// notes how many more there were.
+func formatRows(rows *sql.Rows, maxRows int) (string, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.Join(columns, " | ") + "\n")
+	count := 0
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		count++
+		if count > maxRows {
+			continue
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return "", err
+		}
+		cells := make([]string, len(values))
+		for i, value := range values {
+			cells[i] = formatCell(value)
+		}
+		b.WriteString(strings.Join(cells, " | ") + "\n")
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	switch {
+	case count > maxRows:
+		fmt.Fprintf(&b, "(%d of %d rows shown)", maxRows, count)
+	case count == 1:
+		b.WriteString("(1 row)")
+	default:
+		fmt.Fprintf(&b, "(%d rows)", count)
+	}
+	return b.String(), nil
+}
+
+// formatCell renders one value on a single line.
+func formatCell(value any) string {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		s = string(v)
+	case time.Time:
+		s = v.Format(time.RFC3339)
+	default:
+		s = fmt.Sprint(v)
+	}
+	s = strings.NewReplacer("\n", `\n`, "\r", `\r`, "|", `\|`).Replace(s)
+	if len(s) > maxDBCellLength {
+		s = s[:maxDBCellLength] + "..."
+	}
+	return s
+}
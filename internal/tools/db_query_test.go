@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckReadOnlyQuery(t *testing.T) {
+	allowed := []string{
+		"SELECT * FROM users",
+		"  select 1;",
+		"WITH recent AS (SELECT * FROM orders) SELECT count(*) FROM recent",
+		"EXPLAIN SELECT 1",
+		"PRAGMA table_info(users)",
+		"SELECT 'a; DELETE FROM users' AS s",
+		"-- DROP TABLE users;\nSELECT 1",
+		"SELECT /* ; */ 1",
+	}
+	for _, query := range allowed {
+		if err := checkReadOnlyQuery(query); err != nil {
+			t.Errorf("checkReadOnlyQuery(%q) failed: %v", query, err)
+		}
+	}
+
+	rejected := []string{
+		"",
+		"  ;",
+		"DELETE FROM users",
+		"update users set name = 'x'",
+		"SELECT 1; DROP TABLE users",
+		"SELECT 1 # ; COMMIT; DELETE FROM users",
+		"PRAGMA journal_mode = WAL",
+		"/* SELECT */ INSERT INTO users VALUES (1)",
+	}
+	for _, query := range rejected {
+		if err := checkReadOnlyQuery(query); err == nil {
+			t.Errorf("Expected checkReadOnlyQuery(%q) to fail", query)
+		}
+	}
+}
+
+func TestDBQuerySQLite(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "app.db")
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, bio TEXT)",
+		"INSERT INTO users (name, bio) VALUES ('alice', 'line one\nline two'), ('bob', NULL), ('carol', 'a|b')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("TEST_DB_DIR", dir)
+	writeTestFile(t, configHome, "tiny-trae/databases.yaml", "databases:\n  app:\n    driver: sqlite\n    dsn: ${TEST_DB_DIR}/app.db\n")
+
+	run := func(input DBQueryInput) (string, error) {
+		data, _ := json.Marshal(input)
+		return DBQuery(context.Background(), data)
+	}
+
+	result, err := run(DBQueryInput{Query: "SELECT id, name, bio FROM users ORDER BY id"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "id | name | bio\n1 | alice | line one\\nline two\n2 | bob | NULL\n3 | carol | a\\|b\n(3 rows)"
+	if result != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, result)
+	}
+
+	result, err = run(DBQueryInput{Database: "app", Query: "SELECT name FROM users ORDER BY id", MaxRows: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(result, "bob\n(2 of 3 rows shown)") {
+		t.Errorf("Expected truncated rows, got:\n%s", result)
+	}
+
+	result, err = run(DBQueryInput{})
+	if err != nil || result != "Configured databases:\napp (sqlite)" {
+		t.Errorf("Unexpected database list %q, %v", result, err)
+	}
+
+	if _, err := run(DBQueryInput{Query: "DELETE FROM users"}); err == nil {
+		t.Error("Expected DELETE to be rejected")
+	}
+	// Writes hidden from the statement check are stopped by the connection
+	if _, err := run(DBQueryInput{Query: "WITH x AS (SELECT 1) INSERT INTO users (name) SELECT 'mallory' FROM x"}); err == nil {
+		t.Error("Expected the write to fail on the read-only connection")
+	}
+	if _, err := run(DBQueryInput{Database: "other", Query: "SELECT 1"}); err == nil || !strings.Contains(err.Error(), "configured: app") {
+		t.Errorf("Expected unknown database error, got %v", err)
+	}
+
+	db, err = sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM users").Scan(&count); err != nil || count != 3 {
+		t.Errorf("Expected the table to be unchanged, got %d rows (%v)", count, err)
+	}
+}
+
+func TestDBQueryNotConfigured(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	_, err := DBQuery(context.Background(), json.RawMessage(`{"query":"SELECT 1"}`))
+	if err == nil || !strings.Contains(err.Error(), "no databases are configured") {
+		t.Errorf("Expected not configured error, got %v", err)
+	}
+}
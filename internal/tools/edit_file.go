@@ -1,10 +1,11 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"path"
+	"path/filepath"
 	"strings"
 
 	"tiny-trae/internal/agent"
@@ -16,6 +17,10 @@ var EditFileDefinition = agent.ToolDefinition{
 	Description: `Make edits to a text file. Replaces 'old_str' with 'new_str' in the given file. 'old_str' and 'new_str' MUST be different from each other. If the file specified with path doesn't exist, it will be created.`,
 	InputSchema: EditFileInputSchema,
 	Function:    EditFile,
+
+	RequiresApproval: true,
+	Preview:          EditFilePreview,
+	MutatesFiles:     true,
 }
 
 // EditFileInput defines the input schema for the 'edit_file' tool.
@@ -29,7 +34,7 @@ type EditFileInput struct {
 var EditFileInputSchema = agent.GenerateSchema[EditFileInput]()
 
 // EditFile implements the 'edit_file' tool.
-func EditFile(input json.RawMessage) (string, error) {
+func EditFile(ctx context.Context, input json.RawMessage) (string, error) {
 	editFileInput := EditFileInput{}
 	err := json.Unmarshal(input, &editFileInput)
 	if err != nil {
@@ -43,7 +48,11 @@ func EditFile(input json.RawMessage) (string, error) {
 	content, err := os.ReadFile(editFileInput.Path)
 	if err != nil {
 		if os.IsNotExist(err) && editFileInput.OldStr == "" {
-			return createNewFile(editFileInput.Path, editFileInput.NewStr)
+			result, err := createNewFile(editFileInput.Path, editFileInput.NewStr)
+			if err == nil {
+				agent.ReportFilesChanged(ctx, editFileInput.Path)
+			}
+			return result, err
 		}
 		return "", err
 	}
@@ -59,13 +68,35 @@ func EditFile(input json.RawMessage) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	agent.ReportFilesChanged(ctx, editFileInput.Path)
 
 	return "OK", nil
 }
 
+// EditFilePreview renders the requested edit as a diff so the user can review
+// it before approving.
+func EditFilePreview(input json.RawMessage) string {
+	editFileInput := EditFileInput{}
+	if err := json.Unmarshal(input, &editFileInput); err != nil {
+		return string(input)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", editFileInput.Path, editFileInput.Path)
+	if editFileInput.OldStr != "" {
+		for _, line := range strings.Split(editFileInput.OldStr, "\n") {
+			fmt.Fprintf(&b, "-%s\n", line)
+		}
+	}
+	for _, line := range strings.Split(editFileInput.NewStr, "\n") {
+		fmt.Fprintf(&b, "+%s\n", line)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
 // createNewFile creates a new file with the given content.
 func createNewFile(filePath, content string) (string, error) {
-	dir := path.Dir(filePath)
+	dir := filepath.Dir(filePath)
 	if dir != "." {
 		err := os.MkdirAll(dir, 0755)
 		if err != nil {
@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"path/filepath"
@@ -117,7 +118,7 @@ func TestEditFile(t *testing.T) {
 				t.Fatalf("Failed to marshal input: %v", err)
 			}
 
-			result, err := EditFile(inputJSON)
+			result, err := EditFile(context.Background(), inputJSON)
 
 			if tt.expectError {
 				if err == nil {
@@ -169,7 +170,7 @@ func TestCreateNewFile(t *testing.T) {
 
 func TestEditFileInvalidJSON(t *testing.T) {
 	invalidJSON := []byte(`{"invalid": json}`)
-	_, err := EditFile(invalidJSON)
+	_, err := EditFile(context.Background(), invalidJSON)
 	if err == nil {
 		t.Error("Expected error for invalid JSON input")
 	}
@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/git"
+)
+
+// GitBranchDefinition defines the 'git_branch' tool.
+var GitBranchDefinition = agent.ToolDefinition{
+	Name: "git_branch",
+	Description: `List, create, or switch git branches.
+
+Actions:
+- 'list': show local branches, marking the current one
+- 'create': create 'branch' from 'base' (default: HEAD) and switch to it
+- 'switch': switch to an existing 'branch'
+
+Switching fails rather than discarding uncommitted changes that would be overwritten. Create a branch before starting an experiment so the user's branch stays untouched.`,
+	InputSchema: GitBranchInputSchema,
+	Function:    GitBranch,
+
+	RequiresApproval: true,
+	Preview:          GitBranchPreview,
+}
+
+// GitBranchInput defines the input schema for the 'git_branch' tool.
+type GitBranchInput struct {
+	Action string `json:"action" jsonschema:"enum=list,enum=create,enum=switch" jsonschema_description:"What to do: list, create, or switch"`
+	Branch string `json:"branch,omitempty" jsonschema_description:"The branch to create or switch to"`
+	Base   string `json:"base,omitempty" jsonschema_description:"The commit or branch a new branch starts from. Defaults to HEAD"`
+	Path   string `json:"path,omitempty" jsonschema_description:"A directory inside the repository. Defaults to the current directory"`
+}
+
+// GitBranchInputSchema is the JSON schema for the 'git_branch' tool's input.
+var GitBranchInputSchema = agent.GenerateSchema[GitBranchInput]()
+
+// GitBranchPreview returns the git command that will run.
+func GitBranchPreview(input json.RawMessage) string {
+	branchInput := GitBranchInput{}
+	if err := json.Unmarshal(input, &branchInput); err != nil {
+		return string(input)
+	}
+	args, err := branchInput.args()
+	if err != nil {
+		return err.Error()
+	}
+	return "git " + strings.Join(args, " ")
+}
+
+// args returns the git arguments for the requested action.
+func (i GitBranchInput) args() ([]string, error) {
+	if i.Action != "list" && i.Branch == "" {
+		return nil, fmt.Errorf("branch is required for %q", i.Action)
+	}
+	switch i.Action {
+	case "list":
+		return []string{"branch", "--list", "--format=%(HEAD) %(refname:short) %(objectname:short) %(subject)"}, nil
+	case "create":
+		args := []string{"switch", "--create", i.Branch}
+		if i.Base != "" {
+			args = append(args, i.Base)
+		}
+		return args, nil
+	case "switch":
+		return []string{"switch", i.Branch}, nil
+	default:
+		return nil, fmt.Errorf("unknown action %q (want list, create, or switch)", i.Action)
+	}
+}
+
+// GitBranch implements the 'git_branch' tool.
+func GitBranch(ctx context.Context, input json.RawMessage) (string, error) {
+	branchInput := GitBranchInput{}
+	if err := json.Unmarshal(input, &branchInput); err != nil {
+		return "", err
+	}
+	args, err := branchInput.args()
+	if err != nil {
+		return "", err
+	}
+
+	out, err := git.Run(ctx, branchInput.Path, args...)
+	if err != nil {
+		return "", err
+	}
+	if branchInput.Action == "list" {
+		return strings.TrimRight(out, "\n"), nil
+	}
+	return fmt.Sprintf("Switched to branch %s", branchInput.Branch), nil
+}
+
+// GitWorktreeDefinition defines the 'git_worktree' tool.
+var GitWorktreeDefinition = agent.ToolDefinition{
+	Name: "git_worktree",
+	Description: `Manage git worktrees, separate checkouts of the repository that share its history.
+
+Actions:
+- 'list': show existing worktrees with their branches
+- 'add': create 'branch' from 'base' (default: HEAD) and check it out in a new worktree at 'worktree_path'
+  (default: a sibling of the repository named <repo>-<branch>)
+- 'remove': delete the worktree at 'worktree_path'; fails if it has uncommitted changes
+
+Use a worktree to make changes without touching the user's working directory; pass its path as 'path' to other tools to work inside it.`,
+	InputSchema: GitWorktreeInputSchema,
+	Function:    GitWorktree,
+
+	RequiresApproval: true,
+	Preview:          GitWorktreePreview,
+}
+
+// GitWorktreeInput defines the input schema for the 'git_worktree' tool.
+type GitWorktreeInput struct {
+	Action       string `json:"action" jsonschema:"enum=list,enum=add,enum=remove" jsonschema_description:"What to do: list, add, or remove"`
+	Branch       string `json:"branch,omitempty" jsonschema_description:"The new branch to check out in the worktree"`
+	Base         string `json:"base,omitempty" jsonschema_description:"The commit or branch the new branch starts from. Defaults to HEAD"`
+	WorktreePath string `json:"worktree_path,omitempty" jsonschema_description:"Where the worktree is or should be created"`
+	Path         string `json:"path,omitempty" jsonschema_description:"A directory inside the repository. Defaults to the current directory"`
+}
+
+// GitWorktreeInputSchema is the JSON schema for the 'git_worktree' tool's input.
+var GitWorktreeInputSchema = agent.GenerateSchema[GitWorktreeInput]()
+
+// GitWorktreePreview describes the worktree operation.
+func GitWorktreePreview(input json.RawMessage) string {
+	worktreeInput := GitWorktreeInput{}
+	if err := json.Unmarshal(input, &worktreeInput); err != nil {
+		return string(input)
+	}
+	switch worktreeInput.Action {
+	case "add":
+		path := worktreeInput.WorktreePath
+		if path == "" {
+			path = "<repo>-" + strings.ReplaceAll(worktreeInput.Branch, "/", "-")
+		}
+		return strings.TrimSpace(fmt.Sprintf("git worktree add -b %s %s %s", worktreeInput.Branch, path, worktreeInput.Base))
+	case "remove":
+		return "git worktree remove " + worktreeInput.WorktreePath
+	default:
+		return "git worktree " + worktreeInput.Action
+	}
+}
+
+// GitWorktree implements the 'git_worktree' tool.
+func GitWorktree(ctx context.Context, input json.RawMessage) (string, error) {
+	worktreeInput := GitWorktreeInput{}
+	if err := json.Unmarshal(input, &worktreeInput); err != nil {
+		return "", err
+	}
+	dir := worktreeInput.Path
+
+	switch worktreeInput.Action {
+	case "list":
+		out, err := git.Run(ctx, dir, "worktree", "list")
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(out, "\n"), nil
+
+	case "add":
+		if worktreeInput.Branch == "" {
+			return "", fmt.Errorf("branch is required for \"add\"")
+		}
+		path := worktreeInput.WorktreePath
+		if path == "" {
+			root, err := git.Root(ctx, dir)
+			if err != nil {
+				return "", err
+			}
+			path = git.DefaultWorktreePath(root, worktreeInput.Branch)
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		if err := git.AddWorktree(ctx, dir, path, worktreeInput.Branch, worktreeInput.Base); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Created worktree at %s on new branch %s", path, worktreeInput.Branch), nil
+
+	case "remove":
+		if worktreeInput.WorktreePath == "" {
+			return "", fmt.Errorf("worktree_path is required for \"remove\"")
+		}
+		if _, err := git.Run(ctx, dir, "worktree", "remove", worktreeInput.WorktreePath); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Removed worktree %s", worktreeInput.WorktreePath), nil
+
+	default:
+		return "", fmt.Errorf("unknown action %q (want list, add, or remove)", worktreeInput.Action)
+	}
+}
@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tiny-trae/internal/git"
+)
+
+func TestGitBranch(t *testing.T) {
+	dir := initGitRepo(t)
+	ctx := context.Background()
+
+	input, _ := json.Marshal(GitBranchInput{Action: "create", Branch: "feature", Path: dir})
+	if _, err := GitBranch(ctx, input); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if branch, _ := git.CurrentBranch(ctx, dir); branch != "feature" {
+		t.Errorf("Expected to be on feature, got %q", branch)
+	}
+
+	input, _ = json.Marshal(GitBranchInput{Action: "list", Path: dir})
+	result, err := GitBranch(ctx, input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "* feature") || !strings.Contains(result, "  main") {
+		t.Errorf("Unexpected branch list:\n%s", result)
+	}
+
+	input, _ = json.Marshal(GitBranchInput{Action: "switch", Branch: "main", Path: dir})
+	if _, err := GitBranch(ctx, input); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if branch, _ := git.CurrentBranch(ctx, dir); branch != "main" {
+		t.Errorf("Expected to be on main, got %q", branch)
+	}
+}
+
+func TestGitBranchInvalidInput(t *testing.T) {
+	tests := []GitBranchInput{
+		{Action: "create"},
+		{Action: "switch"},
+		{Action: "delete", Branch: "main"},
+	}
+	for _, input := range tests {
+		if _, err := input.args(); err == nil {
+			t.Errorf("Expected error for %+v", input)
+		}
+	}
+}
+
+func TestGitWorktree(t *testing.T) {
+	dir := initGitRepo(t)
+	ctx := context.Background()
+
+	input, _ := json.Marshal(GitWorktreeInput{Action: "add", Branch: "agent/try", Path: dir})
+	result, err := GitWorktree(ctx, input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	worktree := filepath.Join(filepath.Dir(dir), filepath.Base(dir)+"-agent-try")
+	if !strings.Contains(result, worktree) {
+		t.Errorf("Expected worktree at %s, got %q", worktree, result)
+	}
+	t.Cleanup(func() { os.RemoveAll(worktree) })
+
+	if branch, _ := git.CurrentBranch(ctx, worktree); branch != "agent/try" {
+		t.Errorf("Expected worktree on agent/try, got %q", branch)
+	}
+	if branch, _ := git.CurrentBranch(ctx, dir); branch != "main" {
+		t.Errorf("Expected the main checkout to stay on main, got %q", branch)
+	}
+
+	input, _ = json.Marshal(GitWorktreeInput{Action: "list", Path: dir})
+	if result, _ := GitWorktree(ctx, input); !strings.Contains(result, "[agent/try]") {
+		t.Errorf("Expected worktree in list, got:\n%s", result)
+	}
+
+	input, _ = json.Marshal(GitWorktreeInput{Action: "remove", WorktreePath: worktree, Path: dir})
+	if _, err := GitWorktree(ctx, input); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(worktree); !os.IsNotExist(err) {
+		t.Errorf("Expected worktree directory to be removed")
+	}
+}
+
+func TestGitWorktreePreview(t *testing.T) {
+	preview := GitWorktreePreview(json.RawMessage(`{"action":"add","branch":"fix/x"}`))
+	if preview != "git worktree add -b fix/x <repo>-fix-x" {
+		t.Errorf("Unexpected preview %q", preview)
+	}
+}
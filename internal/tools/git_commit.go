@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/git"
+)
+
+const (
+	// maxCommitSubjectLength is the longest allowed commit subject line.
+	maxCommitSubjectLength = 72
+	// maxCommitBodyLineLength is the width commit body lines must wrap at.
+	maxCommitBodyLineLength = 72
+)
+
+// GitCommitDefinition defines the 'git_commit' tool.
+var GitCommitDefinition = agent.ToolDefinition{
+	Name: "git_commit",
+	Description: `Stage the given paths and commit them with a message you write.
+
+The subject must be a single line of at most 72 characters, in the imperative mood ("Fix crash when config is missing"), without a trailing period.
+The optional body explains what changed and why; wrap it at 72 characters. Only the listed paths are staged, so unrelated changes stay uncommitted.
+Returns the new commit's hash and a summary of the files it changed.`,
+	InputSchema: GitCommitInputSchema,
+	Function:    GitCommit,
+
+	RequiresApproval: true,
+	Preview:          GitCommitPreview,
+}
+
+// GitCommitInput defines the input schema for the 'git_commit' tool.
+type GitCommitInput struct {
+	Paths   []string `json:"paths" jsonschema_description:"Files or directories to stage and commit"`
+	Subject string   `json:"subject" jsonschema_description:"The commit subject line"`
+	Body    string   `json:"body,omitempty" jsonschema_description:"The commit message body, wrapped at 72 characters"`
+	Path    string   `json:"path,omitempty" jsonschema_description:"A directory inside the repository. Defaults to the current directory"`
+}
+
+// GitCommitInputSchema is the JSON schema for the 'git_commit' tool's input.
+var GitCommitInputSchema = agent.GenerateSchema[GitCommitInput]()
+
+// GitCommitPreview shows the paths to stage and the commit message.
+func GitCommitPreview(input json.RawMessage) string {
+	commitInput := GitCommitInput{}
+	if err := json.Unmarshal(input, &commitInput); err != nil {
+		return string(input)
+	}
+	return "git add " + strings.Join(commitInput.Paths, " ") + "\n\n" + commitInput.message()
+}
+
+// message returns the full commit message.
+func (i GitCommitInput) message() string {
+	subject := strings.TrimSpace(i.Subject)
+	body := strings.TrimSpace(i.Body)
+	if body == "" {
+		return subject + "\n"
+	}
+	return subject + "\n\n" + body + "\n"
+}
+
+// validate enforces the commit message conventions described to the model.
+func (i GitCommitInput) validate() error {
+	if len(i.Paths) == 0 {
+		return fmt.Errorf("paths must list at least one file to commit")
+	}
+	subject := strings.TrimSpace(i.Subject)
+	switch {
+	case subject == "":
+		return fmt.Errorf("subject must not be empty")
+	case strings.Contains(subject, "\n"):
+		return fmt.Errorf("subject must be a single line; put details in the body")
+	case len(subject) > maxCommitSubjectLength:
+		return fmt.Errorf("subject is %d characters; keep it to %d", len(subject), maxCommitSubjectLength)
+	case strings.HasSuffix(subject, "."):
+		return fmt.Errorf("subject must not end with a period")
+	}
+	for n, line := range strings.Split(strings.TrimSpace(i.Body), "\n") {
+		// Long unbreakable tokens such as URLs cannot be wrapped
+		if len(line) > maxCommitBodyLineLength && strings.Contains(strings.TrimSpace(line), " ") {
+			return fmt.Errorf("body line %d is %d characters; wrap the body at %d", n+1, len(line), maxCommitBodyLineLength)
+		}
+	}
+	return nil
+}
+
+// GitCommit implements the 'git_commit' tool.
+func GitCommit(ctx context.Context, input json.RawMessage) (string, error) {
+	commitInput := GitCommitInput{}
+	if err := json.Unmarshal(input, &commitInput); err != nil {
+		return "", err
+	}
+	if err := commitInput.validate(); err != nil {
+		return "", err
+	}
+
+	dir := commitInput.Path
+	if _, err := git.Run(ctx, dir, append([]string{"add", "--"}, commitInput.Paths...)...); err != nil {
+		return "", err
+	}
+	// Commit only the listed paths, even if other changes were staged earlier
+	args := append([]string{"commit", "--quiet", "--file", "-", "--"}, commitInput.Paths...)
+	if _, err := git.RunInput(ctx, dir, strings.NewReader(commitInput.message()), args...); err != nil {
+		return "", err
+	}
+
+	summary, err := git.Run(ctx, dir, "show", "--stat", "--format=%h %s", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return "Committed " + strings.TrimSpace(summary), nil
+}
@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tiny-trae/internal/git"
+)
+
+// initGitRepo creates a repository with one commit for the git tool tests.
+func initGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	ctx := context.Background()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+	} {
+		if _, err := git.Run(ctx, dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTestFile(t, dir, "README.md", "hello\n")
+	if _, err := git.Run(ctx, dir, "add", "README.md"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git.Run(ctx, dir, "commit", "-q", "-m", "Initial commit"); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// writeTestFile writes content to name under dir, creating parent directories.
+func writeTestFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitCommit(t *testing.T) {
+	dir := initGitRepo(t)
+	writeTestFile(t, dir, "a.go", "package a\n")
+	writeTestFile(t, dir, "b.go", "package b\n")
+
+	input, _ := json.Marshal(GitCommitInput{
+		Paths:   []string{"a.go"},
+		Subject: "Add package a",
+		Body:    "Package a will hold the shared helpers.",
+		Path:    dir,
+	})
+	result, err := GitCommit(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "Add package a") || !strings.Contains(result, "a.go") {
+		t.Errorf("Unexpected result: %s", result)
+	}
+
+	message, _ := git.Run(context.Background(), dir, "log", "-1", "--format=%B")
+	if message != "Add package a\n\nPackage a will hold the shared helpers.\n\n" {
+		t.Errorf("Unexpected commit message %q", message)
+	}
+	status, _ := git.Run(context.Background(), dir, "status", "--porcelain")
+	if strings.TrimSpace(status) != "?? b.go" {
+		t.Errorf("Expected b.go to stay uncommitted, got status %q", status)
+	}
+}
+
+func TestGitCommitValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		input GitCommitInput
+	}{
+		{"no paths", GitCommitInput{Subject: "Fix bug"}},
+		{"empty subject", GitCommitInput{Paths: []string{"a"}}},
+		{"multi-line subject", GitCommitInput{Paths: []string{"a"}, Subject: "Fix bug\nand more"}},
+		{"long subject", GitCommitInput{Paths: []string{"a"}, Subject: strings.Repeat("x", 73)}},
+		{"trailing period", GitCommitInput{Paths: []string{"a"}, Subject: "Fix bug."}},
+		{"unwrapped body", GitCommitInput{Paths: []string{"a"}, Subject: "Fix bug", Body: strings.Repeat("word ", 20)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.input.validate(); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+
+	valid := GitCommitInput{Paths: []string{"a"}, Subject: "Fix bug", Body: "See:\nhttps://example.com/" + strings.Repeat("x", 80)}
+	if err := valid.validate(); err != nil {
+		t.Errorf("Expected long URL in body to be allowed, got %v", err)
+	}
+}
+
+func TestGitCommitPreview(t *testing.T) {
+	preview := GitCommitPreview(json.RawMessage(`{"paths":["a.go","b.go"],"subject":"Fix bug","body":"Details."}`))
+	if preview != "git add a.go b.go\n\nFix bug\n\nDetails.\n" {
+		t.Errorf("Unexpected preview %q", preview)
+	}
+}
@@ -0,0 +1,306 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/git"
+)
+
+const (
+	// DefaultGitLogMaxCount is the number of commits 'git_log' returns by default.
+	DefaultGitLogMaxCount = 20
+	// maxGitLogBodyLines caps the message body lines shown per commit.
+	maxGitLogBodyLines = 6
+	// maxBlameLines caps the lines 'git_blame' annotates in one call.
+	maxBlameLines = 200
+)
+
+// GitLogDefinition defines the 'git_log' tool.
+var GitLogDefinition = agent.ToolDefinition{
+	Name: "git_log",
+	Description: `Show condensed commit history, optionally limited to a file or directory.
+
+Each commit is one line: short hash, date, author, and subject, followed by the changed files' line counts when 'stat' is set and the start of the message body when 'body' is set.
+Use 'grep' to search commit messages, 'author' and 'since' (e.g. "2 weeks ago", "2024-01-01") to narrow the range, and 'revision' for a range such as 'main..HEAD'.`,
+	InputSchema: GitLogInputSchema,
+	Function:    GitLog,
+}
+
+// GitLogInput defines the input schema for the 'git_log' tool.
+type GitLogInput struct {
+	Path     string `json:"path,omitempty" jsonschema_description:"Only show commits touching this file or directory. Defaults to the whole repository in the current directory"`
+	MaxCount int    `json:"max_count,omitempty" jsonschema_description:"The maximum number of commits to show. Defaults to 20"`
+	Revision string `json:"revision,omitempty" jsonschema_description:"A revision or range to show, e.g. 'main..HEAD'. Defaults to HEAD"`
+	Author   string `json:"author,omitempty" jsonschema_description:"Only show commits by authors matching this pattern"`
+	Since    string `json:"since,omitempty" jsonschema_description:"Only show commits after this date"`
+	Grep     string `json:"grep,omitempty" jsonschema_description:"Only show commits whose message matches this regular expression"`
+	Body     bool   `json:"body,omitempty" jsonschema_description:"Include the first lines of each commit message body"`
+	Stat     bool   `json:"stat,omitempty" jsonschema_description:"Include the files each commit changed"`
+}
+
+// GitLogInputSchema is the JSON schema for the 'git_log' tool's input.
+var GitLogInputSchema = agent.GenerateSchema[GitLogInput]()
+
+// gitTarget splits a file or directory path into the directory git should
+// run in and the pathspec that selects it. An empty path is the current
+// directory's repository with no pathspec.
+func gitTarget(path string) (dir, pathspec string, err error) {
+	if path == "" {
+		return "", "", nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", "", err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		// Deleted files still have history
+		return filepath.Dir(abs), abs, nil
+	}
+	if info.IsDir() {
+		return abs, abs, nil
+	}
+	return filepath.Dir(abs), abs, nil
+}
+
+// GitLog implements the 'git_log' tool.
+func GitLog(ctx context.Context, input json.RawMessage) (string, error) {
+	logInput := GitLogInput{}
+	if err := json.Unmarshal(input, &logInput); err != nil {
+		return "", err
+	}
+	maxCount := logInput.MaxCount
+	if maxCount <= 0 {
+		maxCount = DefaultGitLogMaxCount
+	}
+
+	// Fields are separated by NUL and commits start with a record separator
+	args := []string{"log", "--no-color", "--date=short", "--format=%x1e%h%x00%ad%x00%an%x00%s%x00%b%x00", "-n", strconv.Itoa(maxCount)}
+	if logInput.Stat {
+		args = append(args, "--numstat")
+	}
+	if logInput.Author != "" {
+		args = append(args, "--author="+logInput.Author)
+	}
+	if logInput.Since != "" {
+		args = append(args, "--since="+logInput.Since)
+	}
+	if logInput.Grep != "" {
+		args = append(args, "-E", "--grep="+logInput.Grep)
+	}
+	if logInput.Revision != "" {
+		args = append(args, logInput.Revision)
+	}
+	dir, pathspec, err := gitTarget(logInput.Path)
+	if err != nil {
+		return "", err
+	}
+	if pathspec != "" {
+		args = append(args, "--", pathspec)
+	}
+
+	out, err := git.Run(ctx, dir, args...)
+	if err != nil {
+		return "", err
+	}
+
+	var entries []string
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.SplitN(record, "\x00", 6)
+		if len(fields) < 6 {
+			continue
+		}
+		entry := fmt.Sprintf("%s %s %s: %s", fields[0], fields[1], fields[2], fields[3])
+		if logInput.Body {
+			if body := condenseCommitBody(fields[4]); body != "" {
+				entry += "\n" + body
+			}
+		}
+		if logInput.Stat {
+			if stat := formatNumstat(fields[5]); stat != "" {
+				entry += "\n" + stat
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return "No commits found.", nil
+	}
+
+	separator := "\n"
+	if logInput.Body || logInput.Stat {
+		separator = "\n\n"
+	}
+	return strings.Join(entries, separator), nil
+}
+
+// condenseCommitBody indents a commit body and keeps its first
+// maxGitLogBodyLines non-blank lines.
+func condenseCommitBody(body string) string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if len(lines) == maxGitLogBodyLines {
+			lines = append(lines, "    ...")
+			break
+		}
+		lines = append(lines, "    "+strings.TrimSpace(line))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatNumstat renders 'git log --numstat' lines as "+added -deleted path".
+func formatNumstat(numstat string) string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(numstat), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if fields[0] == "-" {
+			lines = append(lines, "    binary "+fields[2])
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("    +%s -%s %s", fields[0], fields[1], fields[2]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// GitBlameDefinition defines the 'git_blame' tool.
+var GitBlameDefinition = agent.ToolDefinition{
+	Name: "git_blame",
+	Description: `Show which commit last changed each line in a range of a file.
+
+Each line is shown as 'line hash author date | text', followed by a legend with the subject of every commit that appears, so you can see who changed the code and why.
+At most 200 lines are annotated per call; pass 'start_line' and 'end_line' to pick the range. Use git_log with 'body' for a commit's full message.`,
+	InputSchema: GitBlameInputSchema,
+	Function:    GitBlame,
+}
+
+// GitBlameInput defines the input schema for the 'git_blame' tool.
+type GitBlameInput struct {
+	Path      string `json:"path" jsonschema_description:"The file to annotate"`
+	StartLine int    `json:"start_line,omitempty" jsonschema_description:"The first line to annotate (1-based). Defaults to 1"`
+	EndLine   int    `json:"end_line,omitempty" jsonschema_description:"The last line to annotate. Defaults to 200 lines after start_line"`
+	Revision  string `json:"revision,omitempty" jsonschema_description:"Annotate the file as of this revision instead of the working tree"`
+}
+
+// GitBlameInputSchema is the JSON schema for the 'git_blame' tool's input.
+var GitBlameInputSchema = agent.GenerateSchema[GitBlameInput]()
+
+// blameCommit is the commit information from 'git blame --porcelain'.
+type blameCommit struct {
+	author  string
+	date    string
+	summary string
+}
+
+// GitBlame implements the 'git_blame' tool.
+func GitBlame(ctx context.Context, input json.RawMessage) (string, error) {
+	blameInput := GitBlameInput{}
+	if err := json.Unmarshal(input, &blameInput); err != nil {
+		return "", err
+	}
+	if blameInput.Path == "" {
+		return "", fmt.Errorf("path must not be empty")
+	}
+	start := max(blameInput.StartLine, 1)
+	end := blameInput.EndLine
+	if end <= 0 || end-start+1 > maxBlameLines {
+		end = start + maxBlameLines - 1
+	}
+	if end < start {
+		return "", fmt.Errorf("end_line %d is before start_line %d", end, start)
+	}
+
+	dir, pathspec, err := gitTarget(blameInput.Path)
+	if err != nil {
+		return "", err
+	}
+	args := []string{"blame", "--porcelain", "-L", fmt.Sprintf("%d,%d", start, end)}
+	if blameInput.Revision != "" {
+		args = append(args, blameInput.Revision)
+	}
+	out, err := git.Run(ctx, dir, append(args, "--", pathspec)...)
+	if err != nil && strings.Contains(err.Error(), "has only") {
+		// The range ran past the end of the file; annotate to the end instead
+		args[3] = fmt.Sprintf("%d,", start)
+		out, err = git.Run(ctx, dir, append(args, "--", pathspec)...)
+	}
+	if err != nil {
+		return "", err
+	}
+	return formatBlame(out), nil
+}
+
+// formatBlame condenses 'git blame --porcelain' output into one line per
+// source line plus a legend of commit subjects.
+func formatBlame(porcelain string) string {
+	commits := map[string]*blameCommit{}
+	var order []string
+	var lines []string
+
+	var current *blameCommit
+	var hash string
+	var lineNo string
+	scanner := bufio.NewScanner(strings.NewReader(porcelain))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "\t") {
+			lines = append(lines, fmt.Sprintf("%5s %s %s %s | %s", lineNo, hash[:min(8, len(hash))], current.author, current.date, line[1:]))
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "author":
+			current.author = value
+		case "author-time":
+			if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+				current.date = time.Unix(secs, 0).UTC().Format("2006-01-02")
+			}
+		case "summary":
+			current.summary = value
+		default:
+			// A header line: <hash> <original line> <final line> [<group size>]
+			fields := strings.Fields(line)
+			if (len(key) != 40 && len(key) != 64) || len(fields) < 3 {
+				continue
+			}
+			hash = key
+			lineNo = fields[2]
+			if commits[hash] == nil {
+				commits[hash] = &blameCommit{}
+				order = append(order, hash)
+			}
+			current = commits[hash]
+		}
+	}
+	if len(lines) == 0 {
+		return "No lines to annotate."
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.Join(lines, "\n"))
+	b.WriteString("\n\nCommits:")
+	for _, hash := range order {
+		commit := commits[hash]
+		if strings.Trim(hash, "0") == "" {
+			fmt.Fprintf(&b, "\n  %s (not committed yet)", hash[:8])
+			continue
+		}
+		fmt.Fprintf(&b, "\n  %s %s %s: %s", hash[:8], commit.date, commit.author, commit.summary)
+	}
+	return b.String()
+}
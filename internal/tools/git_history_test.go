@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tiny-trae/internal/git"
+)
+
+// commitFile writes a file and commits it with the given message and author.
+func commitFile(t *testing.T, dir, name, content, message, author string) {
+	t.Helper()
+	writeTestFile(t, dir, name, content)
+	ctx := context.Background()
+	if _, err := git.Run(ctx, dir, "add", name); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := git.Run(ctx, dir, "commit", "-q", "-m", message, "--author", author+" <"+strings.ToLower(author)+"@example.com>"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitLog(t *testing.T) {
+	dir := initGitRepo(t)
+	commitFile(t, dir, "a.go", "package a\n", "Add package a\n\nIt holds helpers.", "Alice")
+	commitFile(t, dir, "b.go", "package b\n", "Add package b", "Bob")
+
+	input, _ := json.Marshal(GitLogInput{Path: dir})
+	result, err := GitLog(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	lines := strings.Split(result, "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[0], " Bob: Add package b") || !strings.HasSuffix(lines[2], " Test: Initial commit") {
+		t.Errorf("Unexpected log:\n%s", result)
+	}
+
+	input, _ = json.Marshal(GitLogInput{Path: filepath.Join(dir, "a.go"), Body: true, Stat: true})
+	result, err = GitLog(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "Alice: Add package a\n    It holds helpers.\n    +1 -0 a.go") {
+		t.Errorf("Unexpected file log:\n%s", result)
+	}
+	if strings.Contains(result, "package b") {
+		t.Errorf("Expected only commits touching a.go:\n%s", result)
+	}
+
+	input, _ = json.Marshal(GitLogInput{Path: dir, Author: "Nobody"})
+	if result, _ := GitLog(context.Background(), input); result != "No commits found." {
+		t.Errorf("Expected no commits, got %q", result)
+	}
+}
+
+func TestCondenseCommitBody(t *testing.T) {
+	body := strings.Repeat("line\n\n", maxGitLogBodyLines+2)
+	lines := strings.Split(condenseCommitBody(body), "\n")
+	if len(lines) != maxGitLogBodyLines+1 || lines[len(lines)-1] != "    ..." {
+		t.Errorf("Unexpected condensed body %q", lines)
+	}
+}
+
+func TestGitBlame(t *testing.T) {
+	dir := initGitRepo(t)
+	commitFile(t, dir, "main.go", "package main\n\nfunc main() {}\n", "Add main", "Alice")
+	commitFile(t, dir, "main.go", "package main\n\nfunc main() { run() }\n", "Call run from main", "Bob")
+
+	input, _ := json.Marshal(GitBlameInput{Path: filepath.Join(dir, "main.go"), StartLine: 2, EndLine: 10})
+	result, err := GitBlame(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lines := strings.Split(result, "\n")
+	if len(lines) != 6 {
+		t.Fatalf("Expected 2 blame lines and 2 commits, got:\n%s", result)
+	}
+	if !strings.HasPrefix(lines[0], "    2 ") || !strings.Contains(lines[0], " Alice ") {
+		t.Errorf("Unexpected blame line %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "    3 ") || !strings.Contains(lines[1], " Bob ") || !strings.HasSuffix(lines[1], "| func main() { run() }") {
+		t.Errorf("Unexpected blame line %q", lines[1])
+	}
+	if !strings.Contains(result, "Alice: Add main") || !strings.Contains(result, "Bob: Call run from main") {
+		t.Errorf("Expected commit legend, got:\n%s", result)
+	}
+}
+
+func TestGitBlameInvalidRange(t *testing.T) {
+	input, _ := json.Marshal(GitBlameInput{Path: "main.go", StartLine: 10, EndLine: 5})
+	if _, err := GitBlame(context.Background(), input); err == nil {
+		t.Error("Expected error for an inverted range")
+	}
+}
@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/github"
+)
+
+const (
+	// maxIssueComments caps the comments 'github_issue' shows; the most recent are kept.
+	maxIssueComments = 30
+	// maxIssueDiffLines caps the pull request diff 'github_issue' shows.
+	maxIssueDiffLines = 500
+)
+
+// GitHubIssueDefinition defines the 'github_issue' tool.
+var GitHubIssueDefinition = agent.ToolDefinition{
+	Name: "github_issue",
+	Description: `Fetch a GitHub issue or pull request: its title, state, labels, description, and comments.
+
+Accepts a URL, 'owner/repo#123', or a number such as '#123' for the repository of the 'origin' remote. For pull requests the branches and line comments are included, and set 'diff' to also get the changes (up to 500 lines).
+Use this when the user refers to an issue or PR instead of asking them to paste it.`,
+	InputSchema: GitHubIssueInputSchema,
+	Function:    GitHubIssue,
+}
+
+// GitHubIssueInput defines the input schema for the 'github_issue' tool.
+type GitHubIssueInput struct {
+	Reference string `json:"reference" jsonschema_description:"The issue or pull request: a URL, 'owner/repo#123', '#123', or '123'"`
+	Diff      bool   `json:"diff,omitempty" jsonschema_description:"For pull requests, include the diff"`
+	Path      string `json:"path,omitempty" jsonschema_description:"A directory inside the repository whose origin remote is used for bare numbers. Defaults to the current directory"`
+}
+
+// GitHubIssueInputSchema is the JSON schema for the 'github_issue' tool's input.
+var GitHubIssueInputSchema = agent.GenerateSchema[GitHubIssueInput]()
+
+// GitHubIssue implements the 'github_issue' tool.
+func GitHubIssue(ctx context.Context, input json.RawMessage) (string, error) {
+	issueInput := GitHubIssueInput{}
+	if err := json.Unmarshal(input, &issueInput); err != nil {
+		return "", err
+	}
+	ref, err := github.ParseReference(issueInput.Reference)
+	if err != nil {
+		return "", err
+	}
+	if ref.Repo == "" {
+		ref.Repo, err = github.RepoFromRemote(ctx, issueInput.Path, DefaultPRRemote)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	issue, err := github.NewClient().FetchIssue(ctx, ref.Repo, ref.Number, issueInput.Diff)
+	if err != nil {
+		return "", err
+	}
+	return formatIssue(issue), nil
+}
+
+// formatIssue renders an issue or pull request compactly.
+func formatIssue(issue *github.Issue) string {
+	var b strings.Builder
+	kind, state := "Issue", issue.State
+	if pr := issue.PullRequest; pr != nil {
+		kind = "Pull request"
+		switch {
+		case pr.Merged:
+			state = "merged"
+		case pr.Draft && state == "open":
+			state = "draft"
+		}
+	}
+	fmt.Fprintf(&b, "%s #%d: %s\n", kind, issue.Number, issue.Title)
+	fmt.Fprintf(&b, "State: %s, opened by %s on %s\n", state, issue.Author, issue.CreatedAt.Format("2006-01-02"))
+	if issue.PullRequest != nil {
+		fmt.Fprintf(&b, "Branches: %s into %s\n", issue.PullRequest.Head, issue.PullRequest.Base)
+	}
+	if len(issue.Labels) > 0 {
+		fmt.Fprintf(&b, "Labels: %s\n", strings.Join(issue.Labels, ", "))
+	}
+	fmt.Fprintf(&b, "URL: %s\n", issue.URL)
+
+	body := strings.TrimSpace(issue.Body)
+	if body == "" {
+		body = "(no description)"
+	}
+	b.WriteString("\n" + body + "\n")
+
+	writeComments := func(title string, comments []github.Comment) {
+		if len(comments) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n%s (%d):\n", title, len(comments))
+		if len(comments) > maxIssueComments {
+			fmt.Fprintf(&b, "(%d older comments omitted)\n", len(comments)-maxIssueComments)
+			comments = comments[len(comments)-maxIssueComments:]
+		}
+		for _, comment := range comments {
+			location := ""
+			if comment.Path != "" {
+				location = fmt.Sprintf(" on %s:%d", comment.Path, comment.Line)
+			}
+			fmt.Fprintf(&b, "\n@%s%s, %s:\n%s\n", comment.Author, location, comment.CreatedAt.Format("2006-01-02"), strings.TrimSpace(comment.Body))
+		}
+	}
+	writeComments("Comments", issue.Comments)
+	if pr := issue.PullRequest; pr != nil {
+		writeComments("Review comments", pr.ReviewComments)
+		if pr.Diff != "" {
+			lines := strings.Split(strings.TrimRight(pr.Diff, "\n"), "\n")
+			b.WriteString("\nDiff:\n")
+			if len(lines) > maxIssueDiffLines {
+				b.WriteString(strings.Join(lines[:maxIssueDiffLines], "\n"))
+				fmt.Fprintf(&b, "\n... (%d more lines)\n", len(lines)-maxIssueDiffLines)
+			} else {
+				b.WriteString(strings.Join(lines, "\n") + "\n")
+			}
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
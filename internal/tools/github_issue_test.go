@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"tiny-trae/internal/github"
+)
+
+func TestFormatIssue(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	issue := &github.Issue{
+		Number:    7,
+		Title:     "Crash on empty config",
+		Body:      "Steps to reproduce:\n1. Delete config\n",
+		State:     "open",
+		Author:    "alice",
+		URL:       "https://github.com/o/r/issues/7",
+		Labels:    []string{"bug", "p1"},
+		CreatedAt: created,
+		Comments:  []github.Comment{{Author: "bob", Body: "Confirmed.", CreatedAt: created}},
+	}
+	expected := `Issue #7: Crash on empty config
+State: open, opened by alice on 2024-03-01
+Labels: bug, p1
+URL: https://github.com/o/r/issues/7
+
+Steps to reproduce:
+1. Delete config
+
+Comments (1):
+
+@bob, 2024-03-01:
+Confirmed.`
+	if got := formatIssue(issue); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	issue.PullRequest = &github.PullRequest{
+		Base:           "main",
+		Head:           "alice:fix",
+		Merged:         true,
+		ReviewComments: []github.Comment{{Author: "bob", Body: "nit", Path: "a.go", Line: 3, CreatedAt: created}},
+		Diff:           strings.Repeat("+line\n", maxIssueDiffLines+5),
+	}
+	got := formatIssue(issue)
+	for _, want := range []string{"Pull request #7", "State: merged", "Branches: alice:fix into main", "@bob on a.go:3, 2024-03-01:\nnit", "... (5 more lines)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestGitHubIssue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/o/r/issues/3":
+			w.Write([]byte(`{"number":3,"title":"Add dark mode","state":"closed","user":{"login":"carol"},"html_url":"https://github.com/o/r/issues/3"}`))
+		case "/repos/o/r/issues/3/comments":
+			w.Write([]byte(`[]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("GITHUB_API_URL", server.URL)
+	t.Setenv("GITHUB_TOKEN", "token")
+
+	input, _ := json.Marshal(GitHubIssueInput{Reference: "https://github.com/o/r/issues/3"})
+	result, err := GitHubIssue(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(result, "Issue #3: Add dark mode\nState: closed") || !strings.Contains(result, "(no description)") {
+		t.Errorf("Unexpected result:\n%s", result)
+	}
+
+	if _, err := GitHubIssue(context.Background(), json.RawMessage(`{"reference":"the login bug"}`)); err == nil {
+		t.Error("Expected error for an invalid reference")
+	}
+}
@@ -0,0 +1,241 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"tiny-trae/internal/agent"
+)
+
+// GoDepsDefinition defines the 'go_deps' tool.
+var GoDepsDefinition = agent.ToolDefinition{
+	Name: "go_deps",
+	Description: `Show where a Go package sits in the dependency graph.
+
+Reports the package's direct imports grouped into standard library, same-module, and third-party packages (with module versions), and the packages in the main module that import it, directly or transitively.
+Use this before changing a package's exported API to see which packages a change will ripple into.`,
+	InputSchema: GoDepsInputSchema,
+	Function:    GoDeps,
+}
+
+// GoDepsInput defines the input schema for the 'go_deps' tool.
+type GoDepsInput struct {
+	Package string `json:"package" jsonschema_description:"The import path or relative directory of the package, e.g. './internal/agent'"`
+	Path    string `json:"path,omitempty" jsonschema_description:"A directory inside the Go module. Defaults to the current directory"`
+	Modules bool   `json:"modules,omitempty" jsonschema_description:"Also list every third-party module the package depends on transitively, with versions"`
+}
+
+// GoDepsInputSchema is the JSON schema for the 'go_deps' tool's input.
+var GoDepsInputSchema = agent.GenerateSchema[GoDepsInput]()
+
+// goModule is the module information reported by 'go list -json'.
+type goModule struct {
+	Path    string    `json:"Path"`
+	Version string    `json:"Version"`
+	Main    bool      `json:"Main"`
+	Replace *goModule `json:"Replace"`
+}
+
+// goPackage is the subset of 'go list -json' output used by 'go_deps'.
+type goPackage struct {
+	ImportPath string    `json:"ImportPath"`
+	Dir        string    `json:"Dir"`
+	Name       string    `json:"Name"`
+	Standard   bool      `json:"Standard"`
+	DepOnly    bool      `json:"DepOnly"`
+	Module     *goModule `json:"Module"`
+	Imports    []string  `json:"Imports"`
+	Deps       []string  `json:"Deps"`
+	Error      *struct {
+		Err string `json:"Err"`
+	} `json:"Error"`
+}
+
+// GoDeps implements the 'go_deps' tool.
+func GoDeps(ctx context.Context, input json.RawMessage) (string, error) {
+	depsInput := GoDepsInput{}
+	if err := json.Unmarshal(input, &depsInput); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(depsInput.Package) == "" {
+		return "", fmt.Errorf("package must not be empty")
+	}
+
+	deps, err := goList(ctx, depsInput.Path, "-deps", depsInput.Package)
+	if err != nil {
+		return "", err
+	}
+	var target *goPackage
+	byPath := map[string]*goPackage{}
+	for i := range deps {
+		pkg := &deps[i]
+		byPath[pkg.ImportPath] = pkg
+		if !pkg.DepOnly {
+			if target != nil {
+				return "", fmt.Errorf("%q matches more than one package; name a single package", depsInput.Package)
+			}
+			target = pkg
+		}
+	}
+	if target == nil {
+		return "", fmt.Errorf("no package found for %q", depsInput.Package)
+	}
+	if target.Error != nil {
+		return "", fmt.Errorf("failed to load %s: %s", target.ImportPath, target.Error.Err)
+	}
+
+	// Importers are searched for in the target's own module
+	pattern := "all"
+	if target.Module != nil {
+		pattern = target.Module.Path + "/..."
+	}
+	workspace, err := goList(ctx, target.Dir, pattern)
+	if err != nil {
+		return "", err
+	}
+
+	return formatGoDeps(target, byPath, workspace, depsInput.Modules), nil
+}
+
+// goList runs 'go list -e -json' with args in dir and decodes the packages it
+// prints.
+func goList(ctx context.Context, dir string, args ...string) ([]goPackage, error) {
+	cmd := exec.CommandContext(ctx, "go", append([]string{"list", "-e", "-json"}, args...)...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("go list failed: %s", strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("failed to run go list: %w", err)
+	}
+
+	var packages []goPackage
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var pkg goPackage
+		if err := decoder.Decode(&pkg); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse go list output: %w", err)
+		}
+		packages = append(packages, pkg)
+	}
+	return packages, nil
+}
+
+// formatGoDeps renders the imports and importers of target. byPath holds
+// target's transitive dependencies and workspace the packages of target's
+// module.
+func formatGoDeps(target *goPackage, byPath map[string]*goPackage, workspace []goPackage, listModules bool) string {
+	var std, local, external []string
+	for _, path := range target.Imports {
+		dep := byPath[path]
+		switch {
+		case dep == nil || dep.Standard:
+			std = append(std, path)
+		case sameModule(dep.Module, target.Module):
+			local = append(local, path)
+		default:
+			external = append(external, path+" ("+moduleVersion(dep.Module)+")")
+		}
+	}
+
+	var direct, indirect []string
+	for _, pkg := range workspace {
+		if pkg.DepOnly || pkg.ImportPath == target.ImportPath || !sameModule(pkg.Module, target.Module) {
+			continue
+		}
+		if contains(pkg.Imports, target.ImportPath) {
+			direct = append(direct, pkg.ImportPath)
+		} else if contains(pkg.Deps, target.ImportPath) {
+			indirect = append(indirect, pkg.ImportPath)
+		}
+	}
+	sort.Strings(direct)
+	sort.Strings(indirect)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Package %s (%s)\n", target.ImportPath, target.Dir)
+	if target.Module != nil {
+		fmt.Fprintf(&b, "Module: %s\n", moduleVersion(target.Module))
+	}
+	writeGoDepsSection(&b, "Imports (standard library)", std)
+	writeGoDepsSection(&b, "Imports (same module)", local)
+	writeGoDepsSection(&b, "Imports (third-party)", external)
+	writeGoDepsSection(&b, "Imported directly by", direct)
+	writeGoDepsSection(&b, "Imported indirectly by", indirect)
+	if len(direct) == 0 && len(indirect) == 0 {
+		b.WriteString("\nNot imported by any other package in the module.\n")
+	}
+
+	if listModules {
+		modules := map[string]bool{}
+		for _, dep := range byPath {
+			if dep.Module != nil && !dep.Standard && !sameModule(dep.Module, target.Module) {
+				modules[moduleVersion(dep.Module)] = true
+			}
+		}
+		var names []string
+		for name := range modules {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		writeGoDepsSection(&b, "Modules depended on", names)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// writeGoDepsSection writes a titled list, or nothing if items is empty.
+func writeGoDepsSection(b *strings.Builder, title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n%s (%d):\n", title, len(items))
+	for _, item := range items {
+		fmt.Fprintf(b, "  %s\n", item)
+	}
+}
+
+// sameModule reports whether a and b are the same module.
+func sameModule(a, b *goModule) bool {
+	return a != nil && b != nil && a.Path == b.Path
+}
+
+// moduleVersion formats a module as "path version", following replacements.
+func moduleVersion(m *goModule) string {
+	if m == nil {
+		return "unknown module"
+	}
+	s := m.Path
+	if m.Version != "" {
+		s += " " + m.Version
+	}
+	if m.Replace != nil {
+		s += " => " + m.Replace.Path
+		if m.Replace.Version != "" {
+			s += " " + m.Replace.Version
+		}
+	}
+	return s
+}
+
+// contains reports whether list contains s.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeGoDepsModule creates a module where app imports lib and main imports
+// app, so lib is imported directly by app and indirectly by main.
+func writeGoDepsModule(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":     "module example.com/m\n\ngo 1.21\n",
+		"lib/lib.go": "package lib\n\nimport \"strings\"\n\nfunc Upper(s string) string { return strings.ToUpper(s) }\n",
+		"app/app.go": "package app\n\nimport \"example.com/m/lib\"\n\nfunc Run() string { return lib.Upper(\"x\") }\n",
+		"main.go":    "package main\n\nimport \"example.com/m/app\"\n\nfunc main() { println(app.Run()) }\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestGoDeps(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+	dir := writeGoDepsModule(t)
+
+	input, _ := json.Marshal(GoDepsInput{Package: "./lib", Path: dir})
+	result, err := GoDeps(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{
+		"Package example.com/m/lib",
+		"Imports (standard library) (1):\n  strings",
+		"Imported directly by (1):\n  example.com/m/app",
+		"Imported indirectly by (1):\n  example.com/m",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected result to contain %q, got:\n%s", expected, result)
+		}
+	}
+
+	input, _ = json.Marshal(GoDepsInput{Package: "./app", Path: dir})
+	result, err = GoDeps(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "Imports (same module) (1):\n  example.com/m/lib") {
+		t.Errorf("Expected app to import lib, got:\n%s", result)
+	}
+}
+
+func TestGoDepsMultiplePackages(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+	dir := writeGoDepsModule(t)
+
+	input, _ := json.Marshal(GoDepsInput{Package: "./...", Path: dir})
+	if _, err := GoDeps(context.Background(), input); err == nil {
+		t.Error("Expected error for a pattern matching several packages")
+	}
+}
+
+func TestFormatGoDepsThirdParty(t *testing.T) {
+	main := &goModule{Path: "example.com/m", Main: true}
+	dep := &goModule{Path: "github.com/x/y", Version: "v1.2.3"}
+	target := &goPackage{ImportPath: "example.com/m", Dir: "/m", Module: main, Imports: []string{"github.com/x/y/z"}}
+	byPath := map[string]*goPackage{
+		"github.com/x/y/z": {ImportPath: "github.com/x/y/z", Module: dep},
+		"example.com/m":    target,
+	}
+
+	result := formatGoDeps(target, byPath, nil, true)
+	for _, expected := range []string{
+		"Imports (third-party) (1):\n  github.com/x/y/z (github.com/x/y v1.2.3)",
+		"Not imported by any other package in the module.",
+		"Modules depended on (1):\n  github.com/x/y v1.2.3",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected result to contain %q, got:\n%s", expected, result)
+		}
+	}
+}
+
+func TestGoDepsEmptyPackage(t *testing.T) {
+	if _, err := GoDeps(context.Background(), []byte(`{"package": ""}`)); err == nil {
+		t.Error("Expected error for empty package")
+	}
+}
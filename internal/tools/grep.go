@@ -0,0 +1,236 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/ignore"
+)
+
+// goGrepNote is appended to results produced by the built-in search engine.
+const goGrepNote = "\n\n(searched with the built-in Go engine because ripgrep (rg) is not installed)"
+
+// fileTypeGlobs maps common ripgrep file type names to the globs they cover.
+var fileTypeGlobs = map[string][]string{
+	"c":    {"*.c", "*.h"},
+	"cpp":  {"*.cpp", "*.cc", "*.cxx", "*.hpp", "*.hh", "*.h"},
+	"css":  {"*.css"},
+	"go":   {"*.go"},
+	"html": {"*.html", "*.htm"},
+	"java": {"*.java"},
+	"js":   {"*.js", "*.jsx", "*.mjs", "*.cjs"},
+	"json": {"*.json"},
+	"md":   {"*.md", "*.markdown"},
+	"py":   {"*.py", "*.pyi"},
+	"rb":   {"*.rb"},
+	"rust": {"*.rs"},
+	"sh":   {"*.sh", "*.bash"},
+	"toml": {"*.toml"},
+	"ts":   {"*.ts", "*.tsx"},
+	"txt":  {"*.txt"},
+	"yaml": {"*.yaml", "*.yml"},
+}
+
+// goGrep searches files with Go's regexp package. It is used when rg is not
+// installed and supports the same inputs as the 'ripgrep' tool, skipping
+// gitignored, hidden, and binary files like ripgrep does, along with paths
+// listed in .traeignore files.
+func goGrep(ctx context.Context, input RipgrepInput) (string, error) {
+	pattern := input.Pattern
+	if !input.CaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	fileFilter, err := newFileFilter(input.Glob, input.Type)
+	if err != nil {
+		return "", err
+	}
+
+	root := input.Path
+	if root == "" {
+		root = "."
+	}
+	if _, err := os.Stat(root); err != nil {
+		return "", err
+	}
+
+	var matches []RipgrepMatch
+	err = ignore.Walk(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if path != root && !fileFilter.allows(filepath.ToSlash(rel)) {
+			return nil
+		}
+
+		fileMatches, err := grepFile(path, re, input.Context)
+		if err != nil {
+			// Unreadable files are skipped, as ripgrep does
+			return nil
+		}
+		matches = append(matches, fileMatches...)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(matches) == 0 {
+		return "No matches found." + goGrepNote, nil
+	}
+	options := agent.Options(ctx)
+	matches, omitted := limitMatches(matches, input.maxPerFile(options), input.maxResults(options))
+	return formatRipgrepMatches(matches, input.FilesWithMatches, omitted, false) + goGrepNote, nil
+}
+
+// grepFile returns the matching lines in a file, with contextLines lines of
+// context around each. Binary files yield no matches.
+func grepFile(path string, re *regexp.Regexp, contextLines int) ([]RipgrepMatch, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.IndexByte(content, 0) >= 0 {
+		return nil, nil
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(content)+1)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+	}
+
+	var matches []RipgrepMatch
+	lastEmitted := -1
+	for i := 0; i < len(lines); i++ {
+		loc := re.FindStringIndex(lines[i])
+		if loc == nil {
+			continue
+		}
+
+		for j := max(lastEmitted+1, i-contextLines); j < i; j++ {
+			matches = append(matches, RipgrepMatch{File: path, Line: j + 1, Text: lines[j], Context: true})
+		}
+		matches = append(matches, RipgrepMatch{
+			File:   path,
+			Line:   i + 1,
+			Column: loc[0] + 1,
+			Text:   lines[i],
+			Match:  lines[i][loc[0]:loc[1]],
+		})
+		lastEmitted = i
+
+		// Trailing context stops at the next match, which is emitted on its own
+		for j := i + 1; j <= i+contextLines && j < len(lines); j++ {
+			if re.MatchString(lines[j]) {
+				break
+			}
+			matches = append(matches, RipgrepMatch{File: path, Line: j + 1, Text: lines[j], Context: true})
+			lastEmitted = j
+		}
+	}
+	return matches, nil
+}
+
+// fileFilter applies ripgrep-style --glob and --type filters.
+type fileFilter struct {
+	includes []ignore.Pattern
+	excludes []ignore.Pattern
+	types    []ignore.Pattern
+}
+
+// newFileFilter builds a filter from glob and type arguments. Globs starting
+// with '!' exclude matching files; other globs restrict the search to them.
+func newFileFilter(globs, types []string) (*fileFilter, error) {
+	f := &fileFilter{}
+	for _, glob := range globs {
+		negate := strings.HasPrefix(glob, "!")
+		p, ok := ignore.ParsePattern(strings.TrimPrefix(glob, "!"))
+		if !ok {
+			continue
+		}
+		if negate {
+			f.excludes = append(f.excludes, p)
+		} else {
+			f.includes = append(f.includes, p)
+		}
+	}
+	for _, fileType := range types {
+		typeGlobs, ok := fileTypeGlobs[fileType]
+		if !ok {
+			return nil, fmt.Errorf("unrecognized file type: %s", fileType)
+		}
+		for _, glob := range typeGlobs {
+			p, _ := ignore.ParsePattern(glob)
+			f.types = append(f.types, p)
+		}
+	}
+	return f, nil
+}
+
+// allows reports whether the slash-separated relative path passes the filter.
+func (f *fileFilter) allows(rel string) bool {
+	for _, p := range f.excludes {
+		if p.Match(rel, false) || matchesParentDir(p, rel) {
+			return false
+		}
+	}
+	if len(f.includes) > 0 && !matchesAny(f.includes, rel) {
+		return false
+	}
+	if len(f.types) > 0 && !matchesAny(f.types, rel) {
+		return false
+	}
+	return true
+}
+
+// matchesAny reports whether any pattern matches the file path.
+func matchesAny(patterns []ignore.Pattern, rel string) bool {
+	for _, p := range patterns {
+		if p.Match(rel, false) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesParentDir reports whether the pattern matches a directory that
+// contains the path, e.g. '!vendor' excluding 'vendor/lib/a.go'.
+func matchesParentDir(p ignore.Pattern, rel string) bool {
+	for dir := filepath.ToSlash(filepath.Dir(rel)); dir != "." && dir != "/"; dir = filepath.ToSlash(filepath.Dir(dir)) {
+		if p.Match(dir, true) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoGrep(t *testing.T) {
+	tempDir := t.TempDir()
+	testFiles := map[string]string{
+		".gitignore":      "ignored/\n",
+		"main.go":         "package main\n// TODO: first\nfunc main() {}\n",
+		"notes.md":        "# Notes\nTODO: write docs\n",
+		"vendor/lib/a.go": "package lib\n// TODO: vendored\n",
+		"ignored/b.go":    "// TODO: ignored\n",
+		".hidden/c.go":    "// TODO: hidden\n",
+		"binary.bin":      "TODO\x00binary",
+	}
+	for filename, content := range testFiles {
+		fullPath := filepath.Join(tempDir, filename)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", fullPath, err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		input       RipgrepInput
+		expected    []string
+		notExpected []string
+	}{
+		{
+			name:        "respects gitignore, hidden, and binary files",
+			input:       RipgrepInput{Pattern: "todo", Path: tempDir},
+			expected:    []string{"main.go", "notes.md", "a.go", "2:4: // TODO: first", "3 matches in 3 files", "built-in Go engine"},
+			notExpected: []string{"b.go", "c.go", "binary.bin"},
+		},
+		{
+			name:     "case sensitive",
+			input:    RipgrepInput{Pattern: "todo", Path: tempDir, CaseSensitive: true},
+			expected: []string{"No matches found."},
+		},
+		{
+			name:        "glob include and exclude",
+			input:       RipgrepInput{Pattern: "TODO", Path: tempDir, Glob: []string{"*.go", "!vendor"}},
+			expected:    []string{"main.go"},
+			notExpected: []string{"notes.md", "a.go"},
+		},
+		{
+			name:        "file type",
+			input:       RipgrepInput{Pattern: "TODO", Path: tempDir, Type: []string{"md"}},
+			expected:    []string{"notes.md"},
+			notExpected: []string{"main.go"},
+		},
+		{
+			name:     "context lines",
+			input:    RipgrepInput{Pattern: "TODO: first", Path: filepath.Join(tempDir, "main.go"), Context: 1},
+			expected: []string{"1-  package main", "3-  func main() {}"},
+		},
+		{
+			name:     "result limits",
+			input:    RipgrepInput{Pattern: "TODO", Path: tempDir, MaxResults: 1},
+			expected: []string{"1 matches in 1 files (2 more omitted"},
+		},
+		{
+			name:        "files with matches",
+			input:       RipgrepInput{Pattern: "TODO", Path: tempDir, FilesWithMatches: true},
+			expected:    []string{"main.go", "3 files with matches"},
+			notExpected: []string{"// TODO"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := goGrep(context.Background(), tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(result, expected) {
+					t.Errorf("Expected %q to be in output, but got: %s", expected, result)
+				}
+			}
+			for _, notExpected := range tt.notExpected {
+				if strings.Contains(result, notExpected) {
+					t.Errorf("Expected %q not to be in output, but got: %s", notExpected, result)
+				}
+			}
+		})
+	}
+}
+
+func TestGoGrepErrors(t *testing.T) {
+	if _, err := goGrep(context.Background(), RipgrepInput{Pattern: "(", Path: "."}); err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+	if _, err := goGrep(context.Background(), RipgrepInput{Pattern: "x", Path: "/nonexistent/path"}); err == nil {
+		t.Error("Expected error for non-existent path")
+	}
+	if _, err := goGrep(context.Background(), RipgrepInput{Pattern: "x", Path: ".", Type: []string{"nope"}}); err == nil {
+		t.Error("Expected error for unknown file type")
+	}
+}
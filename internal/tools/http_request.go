@@ -0,0 +1,285 @@
+package tools
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"tiny-trae/internal/agent"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultHTTPTimeout is how long 'http_request' waits for a response by default.
+	DefaultHTTPTimeout = 30 * time.Second
+	// maxHTTPTimeout caps the timeout the model can ask for.
+	maxHTTPTimeout = 5 * time.Minute
+	// maxHTTPBodyBytes caps the response body returned to the model.
+	maxHTTPBodyBytes = 32 << 10
+)
+
+// DefaultHTTPAllowedHosts are the hosts 'http_request' may contact when the
+// user has no HTTP config file: only services on the local machine.
+var DefaultHTTPAllowedHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// HTTPRequestDefinition defines the 'http_request' tool.
+var HTTPRequestDefinition = agent.ToolDefinition{
+	Name: "http_request",
+	Description: `Send an HTTP request and return the response status, headers, and body, like curl.
+
+Use it to exercise and debug web services the user is building, e.g. a server running on localhost.
+Only hosts on the user's allowlist can be contacted; by default that is the local machine. Requests with methods other than GET and HEAD, which may change things, need the user's approval. Bodies longer than 32 KiB are truncated and binary bodies are summarized.`,
+	InputSchema: HTTPRequestInputSchema,
+	Function:    HTTPRequest,
+
+	ApprovalFor: HTTPRequestNeedsApproval,
+	Preview:     HTTPRequestPreview,
+}
+
+// HTTPRequestInput defines the input schema for the 'http_request' tool.
+type HTTPRequestInput struct {
+	Method  string            `json:"method,omitempty" jsonschema_description:"The HTTP method. Defaults to GET"`
+	URL     string            `json:"url" jsonschema_description:"The URL to request, including the scheme"`
+	Headers map[string]string `json:"headers,omitempty" jsonschema_description:"Request headers, e.g. {\"Content-Type\": \"application/json\"}"`
+	Body    string            `json:"body,omitempty" jsonschema_description:"The request body"`
+	Timeout int               `json:"timeout,omitempty" jsonschema_description:"Seconds to wait for the response. Defaults to 30, at most 300"`
+}
+
+// HTTPRequestInputSchema is the JSON schema for the 'http_request' tool's input.
+var HTTPRequestInputSchema = agent.GenerateSchema[HTTPRequestInput]()
+
+// method returns the request's method, GET if none is given.
+func (i HTTPRequestInput) method() string {
+	return cmp.Or(strings.ToUpper(strings.TrimSpace(i.Method)), http.MethodGet)
+}
+
+// HTTPRequestNeedsApproval reports whether a request needs the user's
+// approval: any that is not a GET or HEAD, since the allowlisted local
+// services may include ones like Docker's API, which act on a POST.
+func HTTPRequestNeedsApproval(input json.RawMessage) bool {
+	requestInput := HTTPRequestInput{}
+	if err := json.Unmarshal(input, &requestInput); err != nil {
+		return true
+	}
+	method := requestInput.method()
+	return method != http.MethodGet && method != http.MethodHead
+}
+
+// HTTPRequestPreview returns the request's method and URL, followed by its
+// headers and body.
+func HTTPRequestPreview(input json.RawMessage) string {
+	requestInput := HTTPRequestInput{}
+	if err := json.Unmarshal(input, &requestInput); err != nil {
+		return string(input)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", requestInput.method(), requestInput.URL)
+	names := make([]string, 0, len(requestInput.Headers))
+	for name := range requestInput.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n%s: %s", name, requestInput.Headers[name])
+	}
+	if requestInput.Body != "" {
+		fmt.Fprintf(&b, "\n\n%s", requestInput.Body)
+	}
+	return b.String()
+}
+
+// httpConfig is the format of the user's HTTP config file.
+type httpConfig struct {
+	AllowedHosts []string `yaml:"allowed_hosts"`
+}
+
+// HTTPConfigPath returns the location of the user's HTTP config file, which
+// lists the hosts 'http_request' may contact. It lives outside the project
+// so the agent cannot widen its own allowlist.
+func HTTPConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tiny-trae", "http.yaml"), nil
+}
+
+// httpAllowedHosts returns the allowlist from the HTTP config file, or
+// DefaultHTTPAllowedHosts if there is none.
+func httpAllowedHosts() ([]string, error) {
+	path, err := HTTPConfigPath()
+	if err != nil {
+		return DefaultHTTPAllowedHosts, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultHTTPAllowedHosts, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var config httpConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return config.AllowedHosts, nil
+}
+
+// hostAllowed reports whether u's host matches an allowlist entry. Entries
+// are a host name or IP address, optionally with a port ("localhost:8080"),
+// or "*.example.com" for any subdomain of example.com.
+func hostAllowed(u *url.URL, allowed []string) bool {
+	host := strings.ToLower(u.Hostname())
+	for _, entry := range allowed {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		pattern, port := entry, ""
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			pattern, port = h, p
+		}
+		if port != "" && port != urlPort(u) {
+			continue
+		}
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == strings.Trim(pattern, "[]") {
+			return true
+		}
+	}
+	return false
+}
+
+// urlPort returns u's port, or the default port for its scheme.
+func urlPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	if u.Scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
+
+// HTTPRequest implements the 'http_request' tool.
+func HTTPRequest(ctx context.Context, input json.RawMessage) (string, error) {
+	requestInput := HTTPRequestInput{}
+	if err := json.Unmarshal(input, &requestInput); err != nil {
+		return "", err
+	}
+	method := requestInput.method()
+	target, err := url.Parse(requestInput.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return "", fmt.Errorf("url must start with http:// or https://")
+	}
+
+	allowed, err := httpAllowedHosts()
+	if err != nil {
+		return "", err
+	}
+	if !hostAllowed(target, allowed) {
+		return "", fmt.Errorf("host %s is not in the allowed hosts; ask the user to add it to allowed_hosts in ~/.config/tiny-trae/http.yaml", target.Host)
+	}
+
+	timeout := DefaultHTTPTimeout
+	if requestInput.Timeout > 0 {
+		timeout = min(time.Duration(requestInput.Timeout)*time.Second, maxHTTPTimeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), strings.NewReader(requestInput.Body))
+	if err != nil {
+		return "", err
+	}
+	for name, value := range requestInput.Headers {
+		req.Header.Set(name, value)
+	}
+
+	client := &http.Client{
+		// Redirects must stay on the allowlist too
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			if !hostAllowed(req.URL, allowed) {
+				return fmt.Errorf("redirect to %s is not in the allowed hosts", req.URL.Host)
+			}
+			return nil
+		},
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPBodyBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %w", err)
+	}
+	if len(body) > maxHTTPBodyBytes {
+		agent.ReportTruncated(ctx)
+	}
+	return formatHTTPResponse(resp, body, time.Since(start)), nil
+}
+
+// formatHTTPResponse renders the status line, sorted headers, and at most
+// maxHTTPBodyBytes of the body.
+func formatHTTPResponse(resp *http.Response, body []byte, elapsed time.Duration) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s (%d ms)\n", resp.Proto, resp.Status, elapsed.Milliseconds())
+
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range resp.Header[name] {
+			fmt.Fprintf(&b, "%s: %s\n", name, value)
+		}
+	}
+	b.WriteString("\n")
+
+	truncated := len(body) > maxHTTPBodyBytes
+	if truncated {
+		body = body[:maxHTTPBodyBytes]
+		// Cutting the body may have split its last character
+		for i := 0; i < utf8.UTFMax && !utf8.Valid(body); i++ {
+			body = body[:len(body)-1]
+		}
+	}
+	switch {
+	case len(body) == 0:
+		b.WriteString("(empty body)")
+	case !utf8.Valid(body) || bytes.IndexByte(body, 0) >= 0:
+		fmt.Fprintf(&b, "(binary data, Content-Type %q)", resp.Header.Get("Content-Type"))
+	default:
+		b.Write(body)
+		if truncated {
+			fmt.Fprintf(&b, "\n... (body truncated at %d KiB)", maxHTTPBodyBytes>>10)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHostAllowed(t *testing.T) {
+	allowed := []string{"localhost", "api.example.com:8443", "*.internal.dev", "[::1]:9000"}
+	tests := map[string]bool{
+		"http://localhost:3000/health":      true,
+		"https://api.example.com:8443/v1":   true,
+		"https://api.example.com/v1":        false,
+		"https://svc.internal.dev/":         true,
+		"https://a.b.internal.dev/":         true,
+		"https://internal.dev/":             false,
+		"http://[::1]:9000/":                true,
+		"http://[::1]:9001/":                false,
+		"https://evil.com/?localhost":       false,
+		"https://localhost.evil.com/":       false,
+		"http://LOCALHOST:8080/uppercase":   true,
+		"https://api.example.com.evil.com/": false,
+	}
+	for raw, want := range tests {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hostAllowed(u, allowed); got != want {
+			t.Errorf("hostAllowed(%s) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestHTTPRequest(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Method", r.Method)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"auth":"` + r.Header.Get("Authorization") + `","body":` + string(body) + `}`))
+	}))
+	defer server.Close()
+
+	input, _ := json.Marshal(HTTPRequestInput{
+		Method:  "post",
+		URL:     server.URL + "/items",
+		Headers: map[string]string{"Authorization": "Bearer token"},
+		Body:    `{"name":"widget"}`,
+	})
+	result, err := HTTPRequest(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{"201 Created", "Content-Type: application/json", "X-Request-Method: POST", `{"auth":"Bearer token","body":{"name":"widget"}}`} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected %q in result:\n%s", want, result)
+		}
+	}
+}
+
+func TestHTTPRequestAllowlist(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+
+	input := json.RawMessage(`{"url":"https://example.com/"}`)
+	if _, err := HTTPRequest(context.Background(), input); err == nil || !strings.Contains(err.Error(), "not in the allowed hosts") {
+		t.Errorf("Expected example.com to be rejected by default, got %v", err)
+	}
+	if _, err := HTTPRequest(context.Background(), json.RawMessage(`{"url":"file:///etc/passwd"}`)); err == nil {
+		t.Error("Expected non-HTTP URLs to be rejected")
+	}
+
+	// A config file replaces the default allowlist
+	writeTestFile(t, configDir, "tiny-trae/http.yaml", "allowed_hosts:\n  - api.example.com\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	if _, err := HTTPRequest(context.Background(), json.RawMessage(`{"url":"`+server.URL+`"}`)); err == nil {
+		t.Error("Expected localhost to be rejected once the config file lists other hosts")
+	}
+}
+
+func TestFormatHTTPResponse(t *testing.T) {
+	resp := &http.Response{Proto: "HTTP/1.1", Status: "200 OK", Header: http.Header{"Content-Type": {"image/png"}}}
+	if got := formatHTTPResponse(resp, []byte{0x89, 'P', 'N', 'G', 0}, 0); !strings.HasSuffix(got, `(binary data, Content-Type "image/png")`) {
+		t.Errorf("Expected binary body to be summarized, got:\n%s", got)
+	}
+
+	long := strings.Repeat("é", maxHTTPBodyBytes)
+	got := formatHTTPResponse(resp, []byte(long), 0)
+	if !strings.Contains(got, "(body truncated at 32 KiB)") {
+		t.Errorf("Expected truncation note, got %d bytes", len(got))
+	}
+	if strings.Contains(got, "binary") {
+		t.Error("Expected a truncated text body not to be reported as binary")
+	}
+	if len(got) > maxHTTPBodyBytes+200 {
+		t.Errorf("Expected the body to be capped, got %d bytes", len(got))
+	}
+}
+
+func TestHTTPRequestApproval(t *testing.T) {
+	for input, want := range map[string]bool{
+		`{"url":"http://localhost:2375/containers/create"}`:                 false,
+		`{"method":"head","url":"http://localhost/"}`:                       false,
+		`{"method":"POST","url":"http://localhost:2375/containers/create"}`: true,
+		`{"method":"delete","url":"http://localhost:8080/users/1"}`:         true,
+		`not json`: true,
+	} {
+		if got := HTTPRequestNeedsApproval(json.RawMessage(input)); got != want {
+			t.Errorf("HTTPRequestNeedsApproval(%s) = %v, want %v", input, got, want)
+		}
+	}
+
+	preview := HTTPRequestPreview(json.RawMessage(`{"method":"post","url":"http://localhost:2375/containers/create","headers":{"Content-Type":"application/json"},"body":"{}"}`))
+	if preview != "POST http://localhost:2375/containers/create\nContent-Type: application/json\n\n{}" {
+		t.Errorf("Unexpected preview %q", preview)
+	}
+}
@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"tiny-trae/internal/agent"
+
+	"github.com/itchyny/gojq"
+)
+
+// maxJSONQueryOutputBytes caps the output of 'json_query'.
+const maxJSONQueryOutputBytes = 32 << 10
+
+// JSONQueryDefinition defines the 'json_query' tool.
+var JSONQueryDefinition = agent.ToolDefinition{
+	Name: "json_query",
+	Description: `Evaluate a jq expression against a JSON file or the output of an earlier tool call, and return the results.
+
+Use it to slice large JSON, such as package-lock.json or API responses, instead of reading the whole thing, e.g. '.packages | keys | length' or '.items[] | select(.status == "failed") | .name'.
+Set exactly one of 'path' or 'tool_use_id'. Files with several JSON values (JSON Lines) are queried value by value. Each result is printed as compact JSON on its own line; set 'raw' to print strings without quotes. Output is truncated at 32 KiB.`,
+	InputSchema: JSONQueryInputSchema,
+	Function:    JSONQuery,
+}
+
+// JSONQueryInput defines the input schema for the 'json_query' tool.
+type JSONQueryInput struct {
+	Expression string `json:"expression" jsonschema_description:"The jq expression, e.g. '.dependencies | keys'"`
+	Path       string `json:"path,omitempty" jsonschema_description:"The JSON file to query"`
+	ToolUseID  string `json:"tool_use_id,omitempty" jsonschema_description:"The ID of an earlier tool call whose JSON output to query"`
+	Raw        bool   `json:"raw,omitempty" jsonschema_description:"Print string results without quotes, like jq -r"`
+}
+
+// JSONQueryInputSchema is the JSON schema for the 'json_query' tool's input.
+var JSONQueryInputSchema = agent.GenerateSchema[JSONQueryInput]()
+
+// JSONQuery implements the 'json_query' tool.
+func JSONQuery(ctx context.Context, input json.RawMessage) (string, error) {
+	queryInput := JSONQueryInput{}
+	if err := json.Unmarshal(input, &queryInput); err != nil {
+		return "", err
+	}
+
+	query, err := gojq.Parse(queryInput.Expression)
+	if err != nil {
+		return "", fmt.Errorf("invalid jq expression: %w", err)
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return "", fmt.Errorf("invalid jq expression: %w", err)
+	}
+
+	var data []byte
+	switch {
+	case queryInput.Path != "" && queryInput.ToolUseID != "":
+		return "", fmt.Errorf("set only one of path or tool_use_id")
+	case queryInput.Path != "":
+		data, err = os.ReadFile(queryInput.Path)
+		if err != nil {
+			return "", err
+		}
+	case queryInput.ToolUseID != "":
+		result, ok := agent.ToolResult(ctx, queryInput.ToolUseID)
+		if !ok {
+			return "", fmt.Errorf("no output found for tool call %s; only recent successful calls are kept", queryInput.ToolUseID)
+		}
+		data = []byte(result)
+	default:
+		return "", fmt.Errorf("path or tool_use_id is required")
+	}
+
+	var out jsonQueryOutput
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	for {
+		var value any
+		if err := decoder.Decode(&value); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("input is not valid JSON: %w", err)
+		}
+		if err := out.run(ctx, code, value, queryInput.Raw); err != nil {
+			return "", err
+		}
+		if out.truncated {
+			break
+		}
+	}
+
+	result := strings.TrimRight(out.buf.String(), "\n")
+	if out.count == 0 {
+		return "(no results)", nil
+	}
+	if out.truncated {
+		agent.ReportTruncated(ctx)
+		result += fmt.Sprintf("\n[output truncated at %d bytes; narrow the expression]", maxJSONQueryOutputBytes)
+	}
+	return result, nil
+}
+
+// jsonQueryOutput collects query results up to maxJSONQueryOutputBytes.
+type jsonQueryOutput struct {
+	buf       strings.Builder
+	count     int
+	truncated bool
+}
+
+// run evaluates code against one input value and appends its results.
+func (o *jsonQueryOutput) run(ctx context.Context, code *gojq.Code, value any, raw bool) error {
+	iter := code.RunWithContext(ctx, value)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			return nil
+		}
+		if err, ok := v.(error); ok {
+			var halt *gojq.HaltError
+			if errors.As(err, &halt) && halt.Value() == nil {
+				return nil
+			}
+			return fmt.Errorf("jq error: %w", err)
+		}
+
+		var line string
+		if s, ok := v.(string); ok && raw {
+			line = s
+		} else {
+			encoded, err := gojq.Marshal(v)
+			if err != nil {
+				return err
+			}
+			line = string(encoded)
+		}
+		if o.buf.Len()+len(line) > maxJSONQueryOutputBytes {
+			o.truncated = true
+			return nil
+		}
+		o.count++
+		o.buf.WriteString(line + "\n")
+	}
+}
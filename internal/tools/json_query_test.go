@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJSONQuery(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "package-lock.json", `{
+  "name": "app",
+  "packages": {
+    "node_modules/left-pad": {"version": "1.3.0"},
+    "node_modules/react": {"version": "18.2.0", "dev": true}
+  },
+  "size": 12345678901234567890
+}`)
+	writeTestFile(t, dir, "events.jsonl", "{\"level\":\"info\",\"msg\":\"start\"}\n{\"level\":\"error\",\"msg\":\"boom\"}\n")
+	writeTestFile(t, dir, "broken.json", `{"a":`)
+
+	tests := []struct {
+		name     string
+		input    JSONQueryInput
+		expected string
+	}{
+		{"keys", JSONQueryInput{Expression: ".packages | keys", Path: "package-lock.json"}, `["node_modules/left-pad","node_modules/react"]`},
+		{"select", JSONQueryInput{Expression: `.packages | to_entries[] | select(.value.dev) | .key`, Path: "package-lock.json", Raw: true}, "node_modules/react"},
+		{"big numbers", JSONQueryInput{Expression: ".size", Path: "package-lock.json"}, "12345678901234567890"},
+		{"json lines", JSONQueryInput{Expression: `select(.level == "error") | .msg`, Path: "events.jsonl"}, `"boom"`},
+		{"several results", JSONQueryInput{Expression: ".level", Path: "events.jsonl", Raw: true}, "info\nerror"},
+		{"no results", JSONQueryInput{Expression: "empty", Path: "events.jsonl"}, "(no results)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.input.Path = filepath.Join(dir, test.input.Path)
+			data, _ := json.Marshal(test.input)
+			result, err := JSONQuery(context.Background(), data)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, result)
+			}
+		})
+	}
+
+	for _, input := range []JSONQueryInput{
+		{Expression: ".[", Path: filepath.Join(dir, "events.jsonl")},
+		{Expression: ".a", Path: filepath.Join(dir, "broken.json")},
+		{Expression: ".msg | tonumber", Path: filepath.Join(dir, "events.jsonl")},
+		{Expression: "."},
+		{Expression: ".", ToolUseID: "toolu_unknown"},
+	} {
+		data, _ := json.Marshal(input)
+		if _, err := JSONQuery(context.Background(), data); err == nil {
+			t.Errorf("Expected error for %+v", input)
+		}
+	}
+}
+
+func TestJSONQueryTruncatesOutput(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "big.json", "["+strings.Repeat(`"abcdefghijklmnopqrstuvwxyz",`, 5000)+`"end"]`)
+
+	data, _ := json.Marshal(JSONQueryInput{Expression: ".[]", Path: filepath.Join(dir, "big.json")})
+	result, err := JSONQuery(context.Background(), data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result) > maxJSONQueryOutputBytes+100 || !strings.HasSuffix(result, "narrow the expression]") {
+		t.Errorf("Expected truncated output, got %d bytes ending in %q", len(result), result[len(result)-40:])
+	}
+}
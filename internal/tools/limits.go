@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+
+	"tiny-trae/internal/agent"
+)
+
+// ResourceLimits caps the resources a command spawned by a shell tool may use.
+// A zero value for any field means no limit.
+type ResourceLimits struct {
+	// CPUSeconds is the maximum CPU time of the command.
+	CPUSeconds uint64
+	// MemoryBytes is the maximum virtual memory of the command.
+	MemoryBytes uint64
+	// MaxProcesses is the maximum number of processes, so that a fork bomb
+	// cannot take the machine down. On Unix it counts all of the user's
+	// processes, not only the command's, and does not apply to root.
+	MaxProcesses uint64
+	// MaxOutputBytes is the maximum combined stdout and stderr that is kept.
+	// The command is stopped once it writes more than this.
+	MaxOutputBytes int
+}
+
+// DefaultLimits are the limits applied when none are configured.
+var DefaultLimits = ResourceLimits{
+	CPUSeconds:     600,
+	MemoryBytes:    4 << 30,
+	MaxProcesses:   4096,
+	MaxOutputBytes: 1 << 20,
+}
+
+// CommandLimits are the limits applied to the bash and powershell tools.
+var CommandLimits = DefaultLimits
+
+// errOutputLimit is returned by limitedBuffer once the output limit is reached.
+var errOutputLimit = errors.New("output limit exceeded")
+
+// limitedBuffer collects command output up to a maximum size.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+	// onWrite, if set, is called with the output that is kept as it is
+	// written
+	onWrite func(p []byte)
+}
+
+// Write implements io.Writer. Once the limit is reached it returns an error,
+// which makes os/exec close the pipe so the command stops on its next write.
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.max - b.buf.Len(); b.max > 0 && len(p) > remaining {
+		b.keep(p[:remaining])
+		b.truncated = true
+		return remaining, errOutputLimit
+	}
+	b.keep(p)
+	return len(p), nil
+}
+
+// keep adds p to the output.
+func (b *limitedBuffer) keep(p []byte) {
+	b.buf.Write(p)
+	if b.onWrite != nil && len(p) > 0 {
+		b.onWrite(p)
+	}
+}
+
+// runWithLimits runs cmd under limits and returns its combined output. If the
+// output limit was hit, a note is appended and no error is reported for the
+// resulting broken pipe. The output as it is written, the exit code, and any
+// truncation are reported to the agent through ctx.
+func runWithLimits(ctx context.Context, cmd *exec.Cmd, limits ResourceLimits) ([]byte, error) {
+	output := &limitedBuffer{max: limits.MaxOutputBytes}
+	output.onWrite = func(p []byte) { agent.ReportOutput(ctx, string(p)) }
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	release, err := startWithLimits(cmd, limits)
+	if err != nil {
+		return nil, err
+	}
+	err = cmd.Wait()
+	release()
+	if cmd.ProcessState != nil && cmd.ProcessState.ExitCode() >= 0 {
+		agent.ReportExitCode(ctx, cmd.ProcessState.ExitCode())
+	}
+
+	if output.truncated {
+		agent.ReportTruncated(ctx)
+		fmt.Fprintf(&output.buf, "\n[output truncated: command exceeded the %d byte output limit and was stopped]", limits.MaxOutputBytes)
+		return output.buf.Bytes(), nil
+	}
+	return output.buf.Bytes(), err
+}
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLimitedBuffer(t *testing.T) {
+	buf := &limitedBuffer{max: 5}
+
+	n, err := buf.Write([]byte("abc"))
+	if n != 3 || err != nil {
+		t.Fatalf("Expected (3, nil), got (%d, %v)", n, err)
+	}
+	n, err = buf.Write([]byte("defg"))
+	if n != 2 || err != errOutputLimit {
+		t.Fatalf("Expected (2, errOutputLimit), got (%d, %v)", n, err)
+	}
+	if buf.buf.String() != "abcde" {
+		t.Errorf("Expected buffer %q, got %q", "abcde", buf.buf.String())
+	}
+	if !buf.truncated {
+		t.Error("Expected buffer to be marked truncated")
+	}
+}
+
+func TestRunWithLimitsOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses bash")
+	}
+
+	cmd := exec.CommandContext(context.Background(), "bash", "-c", "yes")
+	output, err := runWithLimits(context.Background(), cmd, ResourceLimits{MaxOutputBytes: 100})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(string(output), "output truncated") {
+		t.Errorf("Expected truncation note in output, got %q", output)
+	}
+}
+
+func TestRunWithLimitsCPU(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses bash")
+	}
+
+	cmd := exec.CommandContext(context.Background(), "bash", "-c", "while :; do :; done")
+	_, err := runWithLimits(context.Background(), cmd, ResourceLimits{CPUSeconds: 1})
+	if err == nil {
+		t.Error("Expected busy loop to be killed by the CPU limit")
+	}
+}
+
+func TestRunWithLimitsProcesses(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("test reads /proc")
+	}
+
+	cmd := exec.CommandContext(context.Background(), "cat", "/proc/self/limits")
+	output, err := runWithLimits(context.Background(), cmd, ResourceLimits{MaxProcesses: 300})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !regexp.MustCompile(`Max processes\s+300\s+300`).Match(output) {
+		t.Errorf("Expected the process limit to be set, got:\n%s", output)
+	}
+}
@@ -0,0 +1,44 @@
+//go:build !windows
+
+package tools
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// startWithLimits starts cmd with CPU, memory, and process limits applied
+// via setrlimit. The command is re-executed through /bin/sh so that the
+// limits are set with ulimit in the child before the real program is
+// exec'd. A limit the platform does not support (e.g. virtual memory on
+// macOS) is reported in the command's output, and the command runs without
+// it.
+func startWithLimits(cmd *exec.Cmd, limits ResourceLimits) (func(), error) {
+	if script := limitScript(limits); script != "" {
+		cmd.Args = append([]string{"/bin/sh", "-c", script + `exec "$0" "$@"`, cmd.Path}, cmd.Args[1:]...)
+		cmd.Path = "/bin/sh"
+	}
+
+	return func() {}, cmd.Start()
+}
+
+// limitScript returns the shell commands setting limits, or "" if there
+// are none. Shells spell the process limit differently: bash, zsh, and
+// FreeBSD's sh use -u, while dash and busybox use -p.
+func limitScript(limits ResourceLimits) string {
+	var script strings.Builder
+	limit := func(name, set string) {
+		fmt.Fprintf(&script, "{ %s; } || echo \"[the %s limit could not be set; the command runs without it]\" >&2; ", set, name)
+	}
+	if limits.CPUSeconds > 0 {
+		limit("CPU time", fmt.Sprintf("ulimit -t %d 2>/dev/null", limits.CPUSeconds))
+	}
+	if limits.MemoryBytes > 0 {
+		limit("memory", fmt.Sprintf("ulimit -v %d 2>/dev/null", limits.MemoryBytes/1024))
+	}
+	if limits.MaxProcesses > 0 {
+		limit("process", fmt.Sprintf("ulimit -u %[1]d 2>/dev/null || ulimit -p %[1]d 2>/dev/null", limits.MaxProcesses))
+	}
+	return script.String()
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package tools
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestLimitScriptReportsFailures(t *testing.T) {
+	// A ulimit that always fails, as for limits the platform lacks
+	script := "ulimit() { return 1; }; " + limitScript(ResourceLimits{CPUSeconds: 1, MaxProcesses: 300}) + "echo ran"
+	output, err := exec.Command("/bin/sh", "-c", script).CombinedOutput()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{"[the CPU time limit could not be set", "[the process limit could not be set", "ran"} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("Expected %q in the output, got %q", want, output)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+//go:build windows
+
+package tools
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// startWithLimits starts cmd inside a job object that enforces the CPU time,
+// memory, and process limits. The command is started suspended and only
+// resumed once it is in the job, so that neither it nor any process it
+// starts can run outside the limits. The returned function closes the job,
+// which also kills any processes the command left behind.
+func startWithLimits(cmd *exec.Cmd, limits ResourceLimits) (func(), error) {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	release := func() { windows.CloseHandle(job) }
+
+	var info windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION
+	info.BasicLimitInformation.LimitFlags = windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE
+	if limits.CPUSeconds > 0 {
+		info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_PROCESS_TIME
+		// Job object times are measured in 100-nanosecond ticks
+		info.BasicLimitInformation.PerProcessUserTimeLimit = int64(limits.CPUSeconds) * 10_000_000
+	}
+	if limits.MemoryBytes > 0 {
+		info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_PROCESS_MEMORY
+		info.ProcessMemoryLimit = uintptr(limits.MemoryBytes)
+	}
+	if limits.MaxProcesses > 0 {
+		info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_ACTIVE_PROCESS
+		info.BasicLimitInformation.ActiveProcessLimit = uint32(min(limits.MaxProcesses, 1<<32-1))
+	}
+	_, err = windows.SetInformationJobObject(job, windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info)))
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= windows.CREATE_SUSPENDED
+	if err := cmd.Start(); err != nil {
+		release()
+		return nil, err
+	}
+	fail := func(err error) (func(), error) {
+		cmd.Process.Kill()
+		cmd.Wait()
+		release()
+		return nil, err
+	}
+
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(cmd.Process.Pid))
+	if err != nil {
+		return fail(err)
+	}
+	defer windows.CloseHandle(process)
+
+	if err := windows.AssignProcessToJobObject(job, process); err != nil {
+		return fail(err)
+	}
+	if err := resumeProcess(uint32(cmd.Process.Pid)); err != nil {
+		return fail(err)
+	}
+
+	return release, nil
+}
+
+// resumeProcess resumes the threads of the suspended process pid. A process
+// created suspended has only its main thread.
+func resumeProcess(pid uint32) error {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPTHREAD, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(snapshot)
+
+	resumed := false
+	entry := windows.ThreadEntry32{Size: uint32(unsafe.Sizeof(windows.ThreadEntry32{}))}
+	for err = windows.Thread32First(snapshot, &entry); err == nil; err = windows.Thread32Next(snapshot, &entry) {
+		if entry.OwnerProcessID != pid {
+			continue
+		}
+		thread, err := windows.OpenThread(windows.THREAD_SUSPEND_RESUME, false, entry.ThreadID)
+		if err != nil {
+			return err
+		}
+		_, err = windows.ResumeThread(thread)
+		windows.CloseHandle(thread)
+		if err != nil {
+			return err
+		}
+		resumed = true
+	}
+	if err != windows.ERROR_NO_MORE_FILES {
+		return err
+	}
+	if !resumed {
+		return fmt.Errorf("no thread of process %d to resume", pid)
+	}
+	return nil
+}
@@ -1,17 +1,19 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
-	"os"
+	"io/fs"
 	"path/filepath"
 
 	"tiny-trae/internal/agent"
+	"tiny-trae/internal/ignore"
 )
 
 // ListFilesDefinition defines the 'list_files' tool.
 var ListFilesDefinition = agent.ToolDefinition{
 	Name:        "list_files",
-	Description: "List files and directories at a given path. If no path is provided, lists files in the current directory.",
+	Description: "List files and directories at a given path. If no path is provided, lists files in the current directory. Paths ignored by .gitignore or .traeignore files are left out.",
 	InputSchema: ListFilesInputSchema,
 	Function:    ListFiles,
 }
@@ -25,7 +27,7 @@ type ListFilesInput struct {
 var ListFilesInputSchema = agent.GenerateSchema[ListFilesInput]()
 
 // ListFiles implements the 'list_files' tool.
-func ListFiles(input json.RawMessage) (string, error) {
+func ListFiles(ctx context.Context, input json.RawMessage) (string, error) {
 	listFilesInput := ListFilesInput{}
 	err := json.Unmarshal(input, &listFilesInput)
 	if err != nil {
@@ -38,7 +40,7 @@ func ListFiles(input json.RawMessage) (string, error) {
 	}
 
 	var files []string
-	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+	err = ignore.Walk(dir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -48,8 +50,11 @@ func ListFiles(input json.RawMessage) (string, error) {
 			return err
 		}
 
+		// Always report forward slashes so results look the same on every platform
+		relPath = filepath.ToSlash(relPath)
+
 		if relPath != "." {
-			if info.IsDir() {
+			if d.IsDir() {
 				files = append(files, relPath+"/")
 			} else {
 				files = append(files, relPath)
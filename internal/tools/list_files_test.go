@@ -1,10 +1,12 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
 )
 
@@ -92,7 +94,7 @@ func TestListFiles(t *testing.T) {
 				t.Fatalf("Failed to marshal input: %v", err)
 			}
 
-			result, err := ListFiles(inputJSON)
+			result, err := ListFiles(context.Background(), inputJSON)
 
 			if tt.expectError {
 				if err == nil {
@@ -149,7 +151,7 @@ func TestListFilesInvalidJSON(t *testing.T) {
 		}
 	}()
 	
-	ListFiles(invalidJSON)
+	ListFiles(context.Background(), invalidJSON)
 }
 
 func TestListFilesDefinition(t *testing.T) {
@@ -178,7 +180,7 @@ func TestListFilesEmptyDirectory(t *testing.T) {
 		t.Fatalf("Failed to marshal input: %v", err)
 	}
 
-	result, err := ListFiles(inputJSON)
+	result, err := ListFiles(context.Background(), inputJSON)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -191,4 +193,31 @@ func TestListFilesEmptyDirectory(t *testing.T) {
 	if len(files) != 0 {
 		t.Errorf("Expected empty directory to return no files, got %v", files)
 	}
-}
\ No newline at end of file
+}
+
+func TestListFilesSkipsIgnored(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		".gitignore":      "*.log\n",
+		".traeignore":     "secrets/\n",
+		"main.go":         "",
+		"debug.log":       "",
+		"secrets/key.pem": "",
+	} {
+		writeTestFile(t, dir, name, content)
+	}
+
+	result, err := ListFiles(context.Background(), json.RawMessage(`{"path":"`+filepath.ToSlash(dir)+`"}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var files []string
+	if err := json.Unmarshal([]byte(result), &files); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+	sort.Strings(files)
+	expected := []string{".gitignore", ".traeignore", "main.go"}
+	if strings.Join(files, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, files)
+	}
+}
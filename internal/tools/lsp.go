@@ -0,0 +1,204 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/lsp"
+)
+
+// lspToolNote explains the shared inputs of the LSP tools.
+const lspToolNote = `
+
+Identify the symbol by 'path' and 1-based 'line', plus either 'symbol' (the identifier text on that line) or a 1-based 'column'.
+Requires a language server for the file type: gopls (Go), pyright (Python), typescript-language-server (JS/TS), rust-analyzer (Rust), or clangd (C/C++).`
+
+// GotoDefinitionDefinition defines the 'goto_definition' tool.
+var GotoDefinitionDefinition = agent.ToolDefinition{
+	Name:        "goto_definition",
+	Description: "Find where a symbol is defined, using the language server for precise results." + lspToolNote,
+	InputSchema: LSPPositionInputSchema,
+	Function:    GotoDefinition,
+}
+
+// FindReferencesDefinition defines the 'find_references' tool.
+var FindReferencesDefinition = agent.ToolDefinition{
+	Name:        "find_references",
+	Description: "Find every reference to a symbol across the workspace, using the language server rather than text search." + lspToolNote,
+	InputSchema: LSPPositionInputSchema,
+	Function:    FindReferences,
+}
+
+// HoverDefinition defines the 'hover' tool.
+var HoverDefinition = agent.ToolDefinition{
+	Name:        "hover",
+	Description: "Show the type signature and documentation of a symbol, as an editor would on hover." + lspToolNote,
+	InputSchema: LSPPositionInputSchema,
+	Function:    Hover,
+}
+
+// LSPPositionInput defines the input schema shared by the LSP tools.
+type LSPPositionInput struct {
+	Path   string `json:"path" jsonschema_description:"The file containing the symbol"`
+	Line   int    `json:"line" jsonschema_description:"The 1-based line number of the symbol"`
+	Column int    `json:"column,omitempty" jsonschema_description:"The 1-based column of the symbol. Optional if 'symbol' is given"`
+	Symbol string `json:"symbol,omitempty" jsonschema_description:"The identifier to look up on the given line"`
+}
+
+// LSPPositionInputSchema is the JSON schema for the LSP tools' input.
+var LSPPositionInputSchema = agent.GenerateSchema[LSPPositionInput]()
+
+// GotoDefinition implements the 'goto_definition' tool.
+func GotoDefinition(ctx context.Context, input json.RawMessage) (string, error) {
+	client, path, pos, err := prepareLSPRequest(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	locations, err := client.Definition(ctx, path, pos)
+	if err != nil {
+		return "", err
+	}
+	if len(locations) == 0 {
+		return "No definition found.", nil
+	}
+	return formatLocations(locations), nil
+}
+
+// FindReferences implements the 'find_references' tool.
+func FindReferences(ctx context.Context, input json.RawMessage) (string, error) {
+	client, path, pos, err := prepareLSPRequest(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	locations, err := client.References(ctx, path, pos)
+	if err != nil {
+		return "", err
+	}
+	if len(locations) == 0 {
+		return "No references found.", nil
+	}
+	return fmt.Sprintf("%s\n\n%d references", formatLocations(locations), len(locations)), nil
+}
+
+// Hover implements the 'hover' tool.
+func Hover(ctx context.Context, input json.RawMessage) (string, error) {
+	client, path, pos, err := prepareLSPRequest(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	text, err := client.Hover(ctx, path, pos)
+	if err != nil {
+		return "", err
+	}
+	if text == "" {
+		return "No hover information available.", nil
+	}
+	return text, nil
+}
+
+// prepareLSPRequest parses the input, resolves the symbol's LSP position,
+// and returns a client for the file's language server.
+func prepareLSPRequest(ctx context.Context, input json.RawMessage) (*lsp.Client, string, lsp.Position, error) {
+	lspInput := LSPPositionInput{}
+	if err := json.Unmarshal(input, &lspInput); err != nil {
+		return nil, "", lsp.Position{}, err
+	}
+
+	content, err := os.ReadFile(lspInput.Path)
+	if err != nil {
+		return nil, "", lsp.Position{}, err
+	}
+	pos, err := resolvePosition(string(content), lspInput.Line, lspInput.Column, lspInput.Symbol)
+	if err != nil {
+		return nil, "", lsp.Position{}, err
+	}
+
+	client, err := lsp.DefaultManager.ClientFor(ctx, lspInput.Path)
+	if err != nil {
+		return nil, "", lsp.Position{}, err
+	}
+	return client, lspInput.Path, pos, nil
+}
+
+// resolvePosition converts a 1-based line and rune column (or a symbol on
+// the line) into a zero-based LSP position measured in UTF-16 units.
+func resolvePosition(content string, line, column int, symbol string) (lsp.Position, error) {
+	lines := strings.Split(content, "\n")
+	if line < 1 || line > len(lines) {
+		return lsp.Position{}, fmt.Errorf("line %d is out of range (file has %d lines)", line, len(lines))
+	}
+	text := []rune(lines[line-1])
+
+	if symbol != "" {
+		start := 0
+		if column > 0 {
+			start = min(column-1, len(text))
+		}
+		index := strings.Index(string(text[start:]), symbol)
+		if index < 0 {
+			index = strings.Index(string(text), symbol)
+			start = 0
+		}
+		if index < 0 {
+			return lsp.Position{}, fmt.Errorf("symbol %q not found on line %d", symbol, line)
+		}
+		column = start + len([]rune(string(text[start:])[:index])) + 1
+	}
+	if column < 1 || column > len(text)+1 {
+		return lsp.Position{}, fmt.Errorf("column %d is out of range on line %d", column, line)
+	}
+
+	return lsp.Position{Line: line - 1, Character: len(utf16.Encode(text[:column-1]))}, nil
+}
+
+// formatLocations renders locations as 'path:line:column: text' lines,
+// with paths relative to the working directory where possible.
+func formatLocations(locations []lsp.Location) string {
+	cwd, _ := os.Getwd()
+	fileLines := map[string][]string{}
+
+	var b strings.Builder
+	for _, loc := range locations {
+		path := loc.Path()
+		lines, ok := fileLines[path]
+		if !ok {
+			if content, err := os.ReadFile(path); err == nil {
+				lines = strings.Split(string(content), "\n")
+			}
+			fileLines[path] = lines
+		}
+
+		display := path
+		if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			display = rel
+		}
+
+		line := loc.Range.Start.Line
+		column := loc.Range.Start.Character + 1
+		text := ""
+		if line < len(lines) {
+			text = strings.TrimSpace(lines[line])
+			column = utf16ToRuneColumn(lines[line], loc.Range.Start.Character)
+		}
+		fmt.Fprintf(&b, "%s:%d:%d: %s\n", display, line+1, column, text)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// utf16ToRuneColumn converts a UTF-16 offset within a line to a 1-based rune column.
+func utf16ToRuneColumn(line string, offset int) int {
+	units := 0
+	for i, r := range []rune(line) {
+		if units >= offset {
+			return i + 1
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return len([]rune(line)) + 1
+}
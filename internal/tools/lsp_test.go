@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tiny-trae/internal/lsp"
+)
+
+func TestResolvePosition(t *testing.T) {
+	content := "package main\n\nfunc main() { fmt.Println(\"héllo\", x) }\n"
+
+	tests := []struct {
+		name    string
+		line    int
+		column  int
+		symbol  string
+		want    lsp.Position
+		wantErr bool
+	}{
+		{name: "column", line: 3, column: 6, want: lsp.Position{Line: 2, Character: 5}},
+		{name: "symbol", line: 3, symbol: "Println", want: lsp.Position{Line: 2, Character: 18}},
+		{name: "symbol after column", line: 3, column: 20, symbol: "x", want: lsp.Position{Line: 2, Character: 35}},
+		{name: "symbol not found", line: 3, symbol: "missing", wantErr: true},
+		{name: "line out of range", line: 10, column: 1, wantErr: true},
+		{name: "column out of range", line: 1, column: 50, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolvePosition(content, tt.line, tt.column, tt.symbol)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestResolvePositionUTF16(t *testing.T) {
+	// '😀' is two UTF-16 code units, so 'x' sits at rune 11 but UTF-16 offset 12
+	content := "s := \"😀\" + x"
+	got, err := resolvePosition(content, 1, 0, "x")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got.Character != 12 {
+		t.Errorf("Expected character 12, got %d", got.Character)
+	}
+	if column := utf16ToRuneColumn(content, got.Character); column != 12 {
+		t.Errorf("Expected column 12, got %d", column)
+	}
+}
+
+func TestFormatLocations(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n\n\tfunc run() {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	result := formatLocations([]lsp.Location{{
+		URI:   lsp.PathToURI(path),
+		Range: lsp.Range{Start: lsp.Position{Line: 2, Character: 6}},
+	}})
+	if !strings.HasSuffix(result, "main.go:3:7: func run() {}") {
+		t.Errorf("Unexpected result: %q", result)
+	}
+}
+
+func TestLSPToolsErrors(t *testing.T) {
+	tempDir := t.TempDir()
+	notes := filepath.Join(tempDir, "notes.txt")
+	if err := os.WriteFile(notes, []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	inputs := []json.RawMessage{
+		json.RawMessage(`{"path":`),
+		mustMarshal(t, LSPPositionInput{Path: filepath.Join(tempDir, "missing.go"), Line: 1, Column: 1}),
+		mustMarshal(t, LSPPositionInput{Path: notes, Line: 1, Symbol: "hello"}),
+	}
+
+	for _, definition := range []func(context.Context, json.RawMessage) (string, error){GotoDefinition, FindReferences, Hover} {
+		for _, input := range inputs {
+			if _, err := definition(context.Background(), input); err == nil {
+				t.Errorf("Expected error for input %s", input)
+			}
+		}
+	}
+
+	// Files without a configured server report it clearly
+	_, err := Hover(context.Background(), inputs[2])
+	if err == nil || !strings.Contains(err.Error(), "no language server") {
+		t.Errorf("Expected missing server error, got %v", err)
+	}
+}
+
+func TestLSPToolDefinitions(t *testing.T) {
+	for name, definition := range map[string]string{
+		"goto_definition": GotoDefinitionDefinition.Name,
+		"find_references": FindReferencesDefinition.Name,
+		"hover":           HoverDefinition.Name,
+	} {
+		if definition != name {
+			t.Errorf("Expected name %q, got %q", name, definition)
+		}
+	}
+}
+
+func mustMarshal(t *testing.T, v any) json.RawMessage {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Failed to marshal input: %v", err)
+	}
+	return data
+}
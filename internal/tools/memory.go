@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/memory"
+)
+
+// MemoryDefinition defines the 'memory' tool.
+var MemoryDefinition = agent.ToolDefinition{
+	Name: "memory",
+	Description: `Remember facts about this project for future sessions. They are kept in .tiny-trae/memory.md, which is added to the system prompt of every session started in the project.
+
+Record what you or the user would otherwise have to find out again: build and test commands, architecture notes, conventions, and the user's stated preferences. Record an entry when the user asks you to remember something, or when you learn something that cost effort to find out. Do not record secrets, or what only matters for the current task.
+
+Actions:
+- 'add': remember 'entry' under 'topic', such as "Build", "Architecture", or "Preferences" (default "Notes")
+- 'update': replace the entry containing the text 'old' with 'entry'
+- 'remove': forget the entry containing the text 'old'`,
+	InputSchema: MemoryInputSchema,
+	Function:    Memory,
+
+	RequiresApproval: true,
+	Preview:          MemoryPreview,
+}
+
+// MemoryInput defines the input schema for the 'memory' tool.
+type MemoryInput struct {
+	Action string `json:"action" jsonschema:"enum=add,enum=update,enum=remove" jsonschema_description:"What to do: add, update, or remove"`
+	Topic  string `json:"topic,omitempty" jsonschema_description:"The topic to file a new entry under, for add"`
+	Entry  string `json:"entry,omitempty" jsonschema_description:"The fact to remember, for add and update"`
+	Old    string `json:"old,omitempty" jsonschema_description:"Text of the entry to change, for update and remove; it must match only one entry"`
+}
+
+// MemoryInputSchema is the JSON schema for the 'memory' tool's input.
+var MemoryInputSchema = agent.GenerateSchema[MemoryInput]()
+
+// MemoryPreview describes the change to the memory for approval.
+func MemoryPreview(input json.RawMessage) string {
+	memoryInput := MemoryInput{}
+	if err := json.Unmarshal(input, &memoryInput); err != nil {
+		return string(input)
+	}
+	switch memoryInput.Action {
+	case "add":
+		return fmt.Sprintf("Remember under %s:\n%s", cmp.Or(memoryInput.Topic, memory.DefaultTopic), memoryInput.Entry)
+	case "update":
+		return fmt.Sprintf("Replace the remembered entry containing %q with:\n%s", memoryInput.Old, memoryInput.Entry)
+	case "remove":
+		return fmt.Sprintf("Forget the entry containing %q", memoryInput.Old)
+	default:
+		return string(input)
+	}
+}
+
+// Memory implements the 'memory' tool.
+func Memory(ctx context.Context, input json.RawMessage) (string, error) {
+	memoryInput := MemoryInput{}
+	if err := json.Unmarshal(input, &memoryInput); err != nil {
+		return "", err
+	}
+
+	m, err := memory.Load(memory.File)
+	if err != nil {
+		return "", err
+	}
+	var result string
+	switch memoryInput.Action {
+	case "add":
+		err = m.Add(memoryInput.Topic, memoryInput.Entry)
+		result = "Remembered."
+	case "update":
+		err = m.Update(memoryInput.Old, memoryInput.Entry)
+		result = "Updated the entry."
+	case "remove":
+		err = m.Remove(memoryInput.Old)
+		result = "Forgot the entry."
+	default:
+		return "", fmt.Errorf("unknown action %q; use add, update, or remove", memoryInput.Action)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := m.Save(memory.File); err != nil {
+		return "", fmt.Errorf("failed to save the memory: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"tiny-trae/internal/memory"
+)
+
+func TestMemory(t *testing.T) {
+	t.Chdir(t.TempDir())
+	run := func(input MemoryInput) (string, error) {
+		data, _ := json.Marshal(input)
+		return Memory(context.Background(), data)
+	}
+
+	if result, err := run(MemoryInput{Action: "add", Topic: "Build", Entry: "Run make generate first."}); err != nil || result != "Remembered." {
+		t.Fatalf("Unexpected add result %q, %v", result, err)
+	}
+	if _, err := run(MemoryInput{Action: "update", Old: "generate", Entry: "Run make gen first."}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(MemoryInput{Action: "remove", Old: "missing"}); err == nil {
+		t.Error("Expected an error for a missing entry")
+	}
+	if _, err := run(MemoryInput{Action: "list"}); err == nil {
+		t.Error("Expected an error for an unknown action")
+	}
+
+	data, err := os.ReadFile(memory.File)
+	if err != nil || !strings.Contains(string(data), "## Build\n\n- Run make gen first.\n") {
+		t.Errorf("Unexpected memory file %q, %v", data, err)
+	}
+}
+
+func TestMemoryPreview(t *testing.T) {
+	if got := MemoryPreview(json.RawMessage(`{"action":"add","entry":"Use tabs."}`)); got != "Remember under Notes:\nUse tabs." {
+		t.Errorf("Unexpected add preview %q", got)
+	}
+}
@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"runtime"
+
+	"tiny-trae/internal/agent"
+)
+
+// PowerShellDefinition defines the 'powershell' tool.
+var PowerShellDefinition = agent.ToolDefinition{
+	Name:        "powershell",
+	Description: "Execute a PowerShell command.",
+	InputSchema: PowerShellInputSchema,
+	Function:    PowerShell,
+
+	RequiresApproval: true,
+	Preview:          PowerShellPreview,
+}
+
+// PowerShellInput defines the input schema for the 'powershell' tool.
+type PowerShellInput struct {
+	Command string `json:"command" jsonschema:"description=The command to execute"`
+}
+
+// PowerShellInputSchema is the JSON schema for the 'powershell' tool's input.
+var PowerShellInputSchema = agent.GenerateSchema[PowerShellInput]()
+
+// PowerShellPreview returns the command text so the user can review it before approving.
+func PowerShellPreview(input json.RawMessage) string {
+	powerShellInput := PowerShellInput{}
+	if err := json.Unmarshal(input, &powerShellInput); err != nil {
+		return string(input)
+	}
+	return powerShellInput.Command
+}
+
+// PowerShell implements the 'powershell' tool.
+func PowerShell(ctx context.Context, input json.RawMessage) (string, error) {
+	powerShellInput := PowerShellInput{}
+	err := json.Unmarshal(input, &powerShellInput)
+	if err != nil {
+		return "", err
+	}
+
+	shell, err := powerShellExecutable()
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.CommandContext(ctx, shell, "-NoProfile", "-NonInteractive", "-Command", powerShellInput.Command)
+	output, err := runWithLimits(ctx, cmd, CommandLimits)
+	if err != nil {
+		return "", fmt.Errorf("command execution error: %v - %s", err, string(output))
+	}
+
+	return string(output), nil
+}
+
+// powerShellExecutable returns the PowerShell binary to use, preferring
+// PowerShell 7 (pwsh) over Windows PowerShell.
+func powerShellExecutable() (string, error) {
+	for _, name := range []string{"pwsh", "powershell"} {
+		if p, err := exec.LookPath(name); err == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("powershell is not available on this system")
+}
+
+// ShellDefinition returns the shell execution tool for the current platform:
+// 'powershell' on Windows and 'bash' everywhere else.
+func ShellDefinition() agent.ToolDefinition {
+	if runtime.GOOS == "windows" {
+		return PowerShellDefinition
+	}
+	return BashDefinition
+}
@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestPowerShell(t *testing.T) {
+	if _, err := powerShellExecutable(); err != nil {
+		t.Skip("powershell is not available, skipping tests")
+	}
+
+	inputJSON, err := json.Marshal(PowerShellInput{Command: "Write-Output 'hello world'"})
+	if err != nil {
+		t.Fatalf("Failed to marshal input: %v", err)
+	}
+
+	result, err := PowerShell(context.Background(), inputJSON)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.TrimSpace(result) != "hello world" {
+		t.Errorf("Expected output %q, got %q", "hello world", result)
+	}
+}
+
+func TestPowerShellInvalidJSON(t *testing.T) {
+	invalidJSON := []byte(`{"invalid": json}`)
+	_, err := PowerShell(context.Background(), invalidJSON)
+	if err == nil {
+		t.Error("Expected error for invalid JSON input")
+	}
+}
+
+func TestPowerShellDefinition(t *testing.T) {
+	if PowerShellDefinition.Name != "powershell" {
+		t.Errorf("Expected name 'powershell', got %q", PowerShellDefinition.Name)
+	}
+	if PowerShellDefinition.Description == "" {
+		t.Error("Expected non-empty description")
+	}
+	if PowerShellDefinition.Function == nil {
+		t.Error("Expected non-nil function")
+	}
+}
+
+func TestShellDefinition(t *testing.T) {
+	expected := "bash"
+	if runtime.GOOS == "windows" {
+		expected = "powershell"
+	}
+	if name := ShellDefinition().Name; name != expected {
+		t.Errorf("Expected shell tool %q on %s, got %q", expected, runtime.GOOS, name)
+	}
+}
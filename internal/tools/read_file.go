@@ -1,12 +1,18 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 
 	"tiny-trae/internal/agent"
 )
 
+// DefaultReadFileMaxBytes is how much of a file read_file returns unless the
+// profile sets the max_bytes option.
+const DefaultReadFileMaxBytes = 256 << 10
+
 // ReadFileDefinition defines the 'read_file' tool.
 var ReadFileDefinition = agent.ToolDefinition{
 	Name:        "read_file",
@@ -24,7 +30,7 @@ type ReadFileInput struct {
 var ReadFileInputSchema = agent.GenerateSchema[ReadFileInput]()
 
 // ReadFile implements the 'read_file' tool.
-func ReadFile(input json.RawMessage) (string, error) {
+func ReadFile(ctx context.Context, input json.RawMessage) (string, error) {
 	readFileInput := ReadFileInput{}
 	err := json.Unmarshal(input, &readFileInput)
 	if err != nil {
@@ -36,5 +42,10 @@ func ReadFile(input json.RawMessage) (string, error) {
 		return "", err
 	}
 
+	maxBytes := agent.Options(ctx).Int("max_bytes", DefaultReadFileMaxBytes)
+	if maxBytes > 0 && len(content) > maxBytes {
+		agent.ReportTruncated(ctx)
+		return fmt.Sprintf("%s\n[file truncated: showing %d of %d bytes; use ripgrep to find the part you need]", content[:maxBytes], maxBytes, len(content)), nil
+	}
 	return string(content), nil
 }
@@ -1,10 +1,14 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"tiny-trae/internal/agent"
 )
 
 func TestReadFile(t *testing.T) {
@@ -93,7 +97,7 @@ func TestReadFile(t *testing.T) {
 				t.Fatalf("Failed to marshal input: %v", err)
 			}
 
-			result, err := ReadFile(inputJSON)
+			result, err := ReadFile(context.Background(), inputJSON)
 
 			if tt.expectError {
 				if err == nil {
@@ -132,7 +136,7 @@ func TestReadFileWithRelativePath(t *testing.T) {
 		t.Fatalf("Failed to marshal input: %v", err)
 	}
 
-	result, err := ReadFile(inputJSON)
+	result, err := ReadFile(context.Background(), inputJSON)
 	if err != nil {
 		t.Errorf("Unexpected error reading relative path: %v", err)
 	}
@@ -145,7 +149,7 @@ func TestReadFileWithRelativePath(t *testing.T) {
 
 func TestReadFileInvalidJSON(t *testing.T) {
 	invalidJSON := []byte(`{"invalid": json}`)
-	_, err := ReadFile(invalidJSON)
+	_, err := ReadFile(context.Background(), invalidJSON)
 	if err == nil {
 		t.Error("Expected error for invalid JSON input")
 	}
@@ -188,11 +192,26 @@ func TestReadFileLargeFile(t *testing.T) {
 		t.Fatalf("Failed to marshal input: %v", err)
 	}
 
-	result, err := ReadFile(inputJSON)
+	result, err := ReadFile(context.Background(), inputJSON)
 	if err != nil {
 		t.Errorf("Unexpected error reading large file: %v", err)
 	}
 	if result != largeContent {
 		t.Errorf("Large file content mismatch. Expected length %d, got %d", len(largeContent), len(result))
 	}
-}
\ No newline at end of file
+}
+
+func TestReadFileMaxBytesOption(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "big.txt", "0123456789")
+	input := json.RawMessage(`{"path":"` + filepath.Join(dir, "big.txt") + `"}`)
+
+	ctx := agent.WithOptions(context.Background(), agent.ToolOptions{"max_bytes": 4})
+	result, err := ReadFile(ctx, input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(result, "0123\n[file truncated: showing 4 of 10 bytes") {
+		t.Errorf("Unexpected result %q", result)
+	}
+}
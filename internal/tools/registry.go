@@ -1,23 +1,193 @@
 package tools
 
-import "tiny-trae/internal/agent"
-
-// GetAllTools returns all available tool definitions.
-func GetAllTools() []agent.ToolDefinition {
-	return []agent.ToolDefinition{
-		ReadFileDefinition,
-		ListFilesDefinition,
-		EditFileDefinition,
-		RipgrepDefinition,
-		BashDefinition,
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"tiny-trae/internal/agent"
+)
+
+// Tags group tools so profiles can select them together.
+const (
+	// TagDefault marks the tools of the default profile.
+	TagDefault = "default"
+	// TagMinimal marks the small set of tools for basic tasks.
+	TagMinimal = "minimal"
+	// TagReadOnly marks tools that inspect the workspace without changing it
+	// or running arbitrary commands.
+	TagReadOnly = "readonly"
+)
+
+// TagPrefix marks a tool reference in Select as a tag rather than a name.
+const TagPrefix = "tag:"
+
+// Registry holds tool definitions by name, with tags for grouping.
+type Registry struct {
+	mu    sync.RWMutex
+	tools map[string]registeredTool
+	order []string
+}
+
+// registeredTool is a tool and its tags.
+type registeredTool struct {
+	definition agent.ToolDefinition
+	tags       []string
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{tools: make(map[string]registeredTool)}
+}
+
+// Register adds a tool with the given tags. Names must be unique.
+func (r *Registry) Register(definition agent.ToolDefinition, tags ...string) error {
+	if definition.Name == "" {
+		return fmt.Errorf("tool has no name")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tools[definition.Name]; ok {
+		return fmt.Errorf("tool %q is already registered", definition.Name)
+	}
+	r.tools[definition.Name] = registeredTool{definition: definition, tags: tags}
+	r.order = append(r.order, definition.Name)
+	return nil
+}
+
+// Get returns the tool called name.
+func (r *Registry) Get(name string) (agent.ToolDefinition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tool, ok := r.tools[name]
+	return tool.definition, ok
+}
+
+// List returns every tool in registration order.
+func (r *Registry) List() []agent.ToolDefinition {
+	return r.filter(func(registeredTool) bool { return true })
+}
+
+// Tagged returns the tools with tag, in registration order.
+func (r *Registry) Tagged(tag string) []agent.ToolDefinition {
+	return r.filter(func(tool registeredTool) bool { return slices.Contains(tool.tags, tag) })
+}
+
+// Tags returns the tags of the tool called name.
+func (r *Registry) Tags(name string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.tools[name].tags)
+}
+
+// Select returns the tools matching refs, in registration order. Each
+// reference is a tool name or "tag:" followed by a tag, e.g.
+// []string{"tag:readonly", "bash"}.
+func (r *Registry) Select(refs []string) ([]agent.ToolDefinition, error) {
+	names := make(map[string]bool)
+	tags := make(map[string]bool)
+	for _, ref := range refs {
+		if tag, ok := strings.CutPrefix(ref, TagPrefix); ok {
+			if len(r.Tagged(tag)) == 0 {
+				return nil, fmt.Errorf("no tools are tagged %q", tag)
+			}
+			tags[tag] = true
+			continue
+		}
+		if _, ok := r.Get(ref); !ok {
+			return nil, fmt.Errorf("unknown tool %q", ref)
+		}
+		names[ref] = true
 	}
+	return r.filter(func(tool registeredTool) bool {
+		if names[tool.definition.Name] {
+			return true
+		}
+		for _, tag := range tool.tags {
+			if tags[tag] {
+				return true
+			}
+		}
+		return false
+	}), nil
 }
 
-// GetMinimalTools returns a minimal set of tools for basic tasks.
-func GetMinimalTools() []agent.ToolDefinition {
-	return []agent.ToolDefinition{
-		ReadFileDefinition,
-		ListFilesDefinition,
-		EditFileDefinition,
+// filter returns the tools for which keep is true, in registration order.
+func (r *Registry) filter(keep func(registeredTool) bool) []agent.ToolDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var tools []agent.ToolDefinition
+	for _, name := range r.order {
+		if tool := r.tools[name]; keep(tool) {
+			tools = append(tools, tool.definition)
+		}
 	}
+	return tools
+}
+
+// DefaultRegistry holds the built-in tools and any registered by other
+// packages at init time.
+var DefaultRegistry = NewRegistry()
+
+// Register adds a tool to DefaultRegistry. It is meant to be called from
+// init functions and panics if the name is taken.
+func Register(definition agent.ToolDefinition, tags ...string) {
+	if err := DefaultRegistry.Register(definition, tags...); err != nil {
+		panic(err)
+	}
+}
+
+// Get returns the tool called name from DefaultRegistry.
+func Get(name string) (agent.ToolDefinition, bool) {
+	return DefaultRegistry.Get(name)
+}
+
+// List returns every tool in DefaultRegistry.
+func List() []agent.ToolDefinition {
+	return DefaultRegistry.List()
+}
+
+// Tagged returns the tools in DefaultRegistry with tag.
+func Tagged(tag string) []agent.ToolDefinition {
+	return DefaultRegistry.Tagged(tag)
+}
+
+// Select returns the tools in DefaultRegistry matching refs.
+func Select(refs []string) ([]agent.ToolDefinition, error) {
+	return DefaultRegistry.Select(refs)
+}
+
+func init() {
+	Register(ReadFileDefinition, TagDefault, TagMinimal, TagReadOnly, "files")
+	Register(ListFilesDefinition, TagDefault, TagMinimal, TagReadOnly, "files")
+	Register(EditFileDefinition, TagDefault, TagMinimal, "files")
+	Register(RipgrepDefinition, TagDefault, TagReadOnly, "search")
+	Register(CodeOutlineDefinition, TagDefault, TagReadOnly, "code")
+	Register(GotoDefinitionDefinition, TagDefault, TagReadOnly, "code", "lsp")
+	Register(FindReferencesDefinition, TagDefault, TagReadOnly, "code", "lsp")
+	Register(HoverDefinition, TagDefault, TagReadOnly, "code", "lsp")
+	Register(SearchSymbolsDefinition, TagDefault, TagReadOnly, "code", "lsp")
+	Register(RunTestsDefinition, TagDefault, "build")
+	Register(BuildAndLintDefinition, TagDefault, "build")
+	Register(GoDepsDefinition, TagDefault, TagReadOnly, "code")
+	Register(SemanticSearchDefinition, TagDefault, "search")
+	Register(GitCommitDefinition, TagDefault, "git")
+	Register(GitBranchDefinition, TagDefault, "git")
+	Register(GitWorktreeDefinition, TagDefault, "git")
+	Register(CreatePRDefinition, TagDefault, "git")
+	Register(GitLogDefinition, TagDefault, TagReadOnly, "git")
+	Register(GitBlameDefinition, TagDefault, TagReadOnly, "git")
+	Register(WebSearchDefinition, TagDefault, "web")
+	Register(HTTPRequestDefinition, TagDefault, "web")
+	Register(ViewImageDefinition, TagDefault, TagReadOnly, "files")
+	Register(GitHubIssueDefinition, TagDefault, "web")
+	Register(DBQueryDefinition, TagDefault, "data")
+	Register(ClipboardDefinition, TagDefault)
+	Register(JSONQueryDefinition, TagDefault, TagReadOnly, "data")
+	Register(MemoryDefinition, TagDefault)
+	Register(SessionSummaryDefinition, TagDefault)
+	Register(ShellDefinition(), TagDefault, "shell")
+	// Opt-in with --screen-capture
+	Register(CaptureScreenDefinition, "optional")
 }
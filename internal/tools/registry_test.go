@@ -2,25 +2,51 @@ package tools
 
 import (
 	"testing"
+
+	"tiny-trae/internal/agent"
 )
 
-func TestGetAllTools(t *testing.T) {
-	tools := GetAllTools()
+func TestDefaultTools(t *testing.T) {
+	tools := Tagged(TagDefault)
 
 	// Check that we get the expected number of tools
-	expectedCount := 5
+	expectedCount := 29
 	if len(tools) != expectedCount {
 		t.Errorf("Expected %d tools, got %d", expectedCount, len(tools))
 	}
 
 	// Check that all expected tools are present
 	expectedTools := map[string]bool{
-		"read_file":  false,
-		"list_files": false,
-		"edit_file":  false,
-		"ripgrep":    false,
-		"bash":       false,
+		"read_file":       false,
+		"list_files":      false,
+		"edit_file":       false,
+		"ripgrep":         false,
+		"code_outline":    false,
+		"goto_definition": false,
+		"find_references": false,
+		"hover":           false,
+		"search_symbols":  false,
+		"run_tests":       false,
+		"build_and_lint":  false,
+		"go_deps":         false,
+		"semantic_search": false,
+		"git_commit":      false,
+		"git_branch":      false,
+		"git_worktree":    false,
+		"create_pr":       false,
+		"git_log":         false,
+		"git_blame":       false,
+		"web_search":      false,
+		"http_request":    false,
+		"view_image":      false,
+		"github_issue":    false,
+		"db_query":        false,
+		"clipboard":       false,
+		"json_query":      false,
+		"memory":          false,
+		"session_summary": false,
 	}
+	expectedTools[ShellDefinition().Name] = false
 
 	for _, tool := range tools {
 		if _, exists := expectedTools[tool.Name]; !exists {
@@ -28,8 +54,18 @@ func TestGetAllTools(t *testing.T) {
 		} else {
 			expectedTools[tool.Name] = true
 		}
+	}
 
-		// Validate each tool has required fields
+	// Check that all expected tools were found
+	for toolName, found := range expectedTools {
+		if !found {
+			t.Errorf("Expected tool %s not found in registry", toolName)
+		}
+	}
+}
+
+func TestRegisteredToolsAreValid(t *testing.T) {
+	for _, tool := range List() {
 		if tool.Name == "" {
 			t.Error("Tool has empty name")
 		}
@@ -43,34 +79,15 @@ func TestGetAllTools(t *testing.T) {
 			t.Errorf("Tool %s has empty input schema type", tool.Name)
 		}
 	}
-
-	// Check that all expected tools were found
-	for toolName, found := range expectedTools {
-		if !found {
-			t.Errorf("Expected tool %s not found in registry", toolName)
-		}
-	}
 }
 
-func TestGetAllToolsUniqueness(t *testing.T) {
-	tools := GetAllTools()
-	toolNames := make(map[string]bool)
-
-	for _, tool := range tools {
-		if toolNames[tool.Name] {
-			t.Errorf("Duplicate tool name found: %s", tool.Name)
-		}
-		toolNames[tool.Name] = true
-	}
-}
-
-func TestGetAllToolsConsistency(t *testing.T) {
-	// Test that GetAllTools returns the same tools each time it's called
-	tools1 := GetAllTools()
-	tools2 := GetAllTools()
+func TestListConsistency(t *testing.T) {
+	// Test that List returns the same tools each time it's called
+	tools1 := List()
+	tools2 := List()
 
 	if len(tools1) != len(tools2) {
-		t.Errorf("GetAllTools returned different number of tools: %d vs %d", len(tools1), len(tools2))
+		t.Errorf("List returned different number of tools: %d vs %d", len(tools1), len(tools2))
 	}
 
 	for i, tool1 := range tools1 {
@@ -101,4 +118,79 @@ func TestIndividualToolDefinitions(t *testing.T) {
 	if BashDefinition.Name != "bash" {
 		t.Errorf("Expected BashDefinition name 'bash', got %q", BashDefinition.Name)
 	}
-}
\ No newline at end of file
+}
+
+func TestReadOnlyTools(t *testing.T) {
+	for _, tool := range Tagged(TagReadOnly) {
+		if tool.RequiresApproval || tool.MutatesFiles {
+			t.Errorf("Tool %s is not read-only", tool.Name)
+		}
+	}
+}
+
+func TestMinimalTools(t *testing.T) {
+	tools := Tagged(TagMinimal)
+	if len(tools) != 3 || tools[0].Name != "read_file" || tools[1].Name != "list_files" || tools[2].Name != "edit_file" {
+		t.Errorf("Unexpected minimal tools %v", tools)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	for _, tool := range []struct {
+		name string
+		tags []string
+	}{
+		{"read", []string{"files", TagReadOnly}},
+		{"write", []string{"files"}},
+		{"shell", nil},
+	} {
+		if err := r.Register(agent.ToolDefinition{Name: tool.name}, tool.tags...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Register(agent.ToolDefinition{Name: "read"}); err == nil {
+		t.Error("Expected error for a duplicate name")
+	}
+	if err := r.Register(agent.ToolDefinition{}); err == nil {
+		t.Error("Expected error for an empty name")
+	}
+
+	if tool, ok := r.Get("write"); !ok || tool.Name != "write" {
+		t.Errorf("Get(write) = %v, %v", tool, ok)
+	}
+	if _, ok := r.Get("missing"); ok {
+		t.Error("Expected Get to fail for a missing tool")
+	}
+	if tags := r.Tags("read"); len(tags) != 2 || tags[0] != "files" {
+		t.Errorf("Unexpected tags %v", tags)
+	}
+
+	names := func(tools []agent.ToolDefinition) string {
+		var s string
+		for _, tool := range tools {
+			s += tool.Name + " "
+		}
+		return s
+	}
+	if got := names(r.List()); got != "read write shell " {
+		t.Errorf("Unexpected List order %q", got)
+	}
+	if got := names(r.Tagged("files")); got != "read write " {
+		t.Errorf("Unexpected Tagged result %q", got)
+	}
+
+	// Results follow registration order and contain each tool once
+	selected, err := r.Select([]string{"shell", "tag:readonly", "read"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := names(selected); got != "read shell " {
+		t.Errorf("Unexpected Select result %q", got)
+	}
+	for _, refs := range [][]string{{"missing"}, {"tag:missing"}} {
+		if _, err := r.Select(refs); err == nil {
+			t.Errorf("Expected error for %v", refs)
+		}
+	}
+}
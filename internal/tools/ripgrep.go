@@ -1,11 +1,20 @@
 package tools
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"strconv"
+	"strings"
 
 	"tiny-trae/internal/agent"
+	"tiny-trae/internal/ignore"
 )
 
 // RipgrepDefinition defines the 'ripgrep' tool.
@@ -25,53 +34,349 @@ WHEN NOT TO USE THIS TOOL:
 - When you already have read the entire file
 
 RESULT INTERPRETATION:
-- Results show the file path, line number, and matching line content
-- Results are grouped by file, with up to 15 matches per file`,
+- Results show the file path, then each match as 'line:column: text' (context lines as 'line-  text')
+- A final line reports how many matches were found in how many files
+- If ripgrep is not installed, a built-in engine with the same options runs instead and says so in the result
+- Results are grouped by file, with up to 15 matches per file and 200 in total by default
+  (set 'maxPerFile' and 'maxResults' to change this); the summary says how many matches were omitted,
+  or at least how many, since the search stops once the limits are passed
+- Use 'context' to include surrounding lines, 'glob' and 'type' to narrow the files searched
+  (e.g. glob ["*.go", "!vendor/**"]), and 'filesWithMatches' to list only matching file paths`,
 	InputSchema: RipgrepInputSchema,
 	Function:    Ripgrep,
 }
 
 // RipgrepInput defines the input schema for the 'ripgrep' tool.
 type RipgrepInput struct {
-	Pattern       string `json:"pattern" jsonschema_description:"The pattern to search for"`
-	Path          string `json:"path,omitempty" jsonschema_description:"The file or directory path to search in"`
-	CaseSensitive bool   `json:"caseSensitive,omitempty" jsonschema_description:"Whether to search case-sensitively"`
+	Pattern          string   `json:"pattern" jsonschema_description:"The pattern to search for"`
+	Path             string   `json:"path,omitempty" jsonschema_description:"The file or directory path to search in"`
+	CaseSensitive    bool     `json:"caseSensitive,omitempty" jsonschema_description:"Whether to search case-sensitively"`
+	Context          int      `json:"context,omitempty" jsonschema_description:"Number of lines of context to show before and after each match"`
+	Glob             []string `json:"glob,omitempty" jsonschema_description:"Glob patterns to include or exclude files, e.g. '*.go' or '!vendor/**'"`
+	Type             []string `json:"type,omitempty" jsonschema_description:"File types to search, e.g. 'go' or 'py' (see 'rg --type-list')"`
+	FilesWithMatches bool     `json:"filesWithMatches,omitempty" jsonschema_description:"Only list the paths of files that contain a match"`
+	MaxPerFile       int      `json:"maxPerFile,omitempty" jsonschema_description:"Maximum number of matches to show per file (default 15)"`
+	MaxResults       int      `json:"maxResults,omitempty" jsonschema_description:"Maximum number of matches to show in total (default 200)"`
 }
 
+// Default result limits for the 'ripgrep' tool, used when neither the input
+// nor the profile sets them.
+const (
+	DefaultRipgrepMaxPerFile = 15
+	DefaultRipgrepMaxResults = 200
+)
+
 // RipgrepInputSchema is the JSON schema for the 'ripgrep' tool's input.
 var RipgrepInputSchema = agent.GenerateSchema[RipgrepInput]()
 
 // Ripgrep implements the 'ripgrep' tool.
-func Ripgrep(input json.RawMessage) (string, error) {
+func Ripgrep(ctx context.Context, input json.RawMessage) (string, error) {
 	ripgrepInput := RipgrepInput{}
 	err := json.Unmarshal(input, &ripgrepInput)
 	if err != nil {
 		return "", err
 	}
 
-	args := []string{"--line-number"}
+	// Fall back to the built-in engine so searching works without rg installed
+	if _, err := exec.LookPath("rg"); err != nil {
+		return goGrep(ctx, ripgrepInput)
+	}
+
+	args := []string{"--json"}
 
 	if !ripgrepInput.CaseSensitive {
 		args = append(args, "-i")
 	}
 
-	args = append(args, "--max-count", "15")
+	if ripgrepInput.Context > 0 && !ripgrepInput.FilesWithMatches {
+		args = append(args, "--context", strconv.Itoa(ripgrepInput.Context))
+	}
+
+	// rg does not know about .traeignore; its --ignore-file patterns are
+	// relative to the working directory, like the project's own .traeignore
+	if _, err := os.Stat(ignore.TraeIgnoreFile); err == nil {
+		args = append(args, "--ignore-file", ignore.TraeIgnoreFile)
+	}
+	// Nor about the project's ignore patterns. As globs they are matched
+	// relative to the search path rather than the project, which is the
+	// same for the usual unanchored patterns
+	for _, pattern := range ignore.ProjectPatterns() {
+		args = append(args, "--glob", "!"+pattern)
+	}
+
+	for _, glob := range ripgrepInput.Glob {
+		args = append(args, "--glob", glob)
+	}
+
+	for _, fileType := range ripgrepInput.Type {
+		args = append(args, "--type", fileType)
+	}
+
+	// rg stops one match past the per-file limit, so that files with more
+	// are known without reading all their matches. --json cannot be
+	// combined with --files-with-matches, so in that mode it stops at the
+	// first match per file and only the file paths are reported.
+	options := agent.Options(ctx)
+	perFile := ripgrepInput.maxPerFile(options)
+	maxCount := perFile + 1
+	if ripgrepInput.FilesWithMatches {
+		maxCount = 1
+	}
+	args = append(args, "--max-count", strconv.Itoa(maxCount))
 	args = append(args, ripgrepInput.Pattern)
 
 	if ripgrepInput.Path != "" {
 		args = append(args, ripgrepInput.Path)
 	}
 
-	cmd := exec.Command("rg", args...)
-	output, err := cmd.CombinedOutput()
-
+	// Once the total limit is passed, rg is stopped rather than left to
+	// find every match in the tree
+	rgCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	cmd := exec.CommandContext(rgCtx, "rg", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
 	if err != nil {
+		return "", fmt.Errorf("ripgrep error: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("ripgrep error: %w", err)
+	}
+
+	limiter := newMatchLimiter(perFile, ripgrepInput.maxResults(options))
+	readErr := readRipgrepJSON(stdout, limiter.add)
+	stopped := limiter.full
+	if stopped || readErr != nil {
+		stop()
+	}
+	// The rest of the output is not needed, but rg must not block on it
+	io.Copy(io.Discard, stdout)
+	err = cmd.Wait()
+
+	if err != nil && !stopped {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
 		// Exit code 1 in ripgrep means "no matches found", which isn't an error for us
 		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
 			return "No matches found.", nil
 		}
-		return "", fmt.Errorf("ripgrep error: %v - %s", err, string(output))
+		return "", fmt.Errorf("ripgrep error: %v - %s", err, stderr.String())
+	}
+	if readErr != nil {
+		return "", fmt.Errorf("ripgrep error: %w", readErr)
+	}
+	if len(limiter.kept) == 0 {
+		return "No matches found.", nil
+	}
+
+	// The matches past the limits were not all read, so the count of
+	// omitted ones is a lower bound
+	return formatRipgrepMatches(limiter.kept, ripgrepInput.FilesWithMatches, limiter.omitted, limiter.omitted > 0), nil
+}
+
+// maxPerFile returns the per-file match limit, applying the profile's
+// max_per_file option or the default.
+func (r RipgrepInput) maxPerFile(options agent.ToolOptions) int {
+	if r.FilesWithMatches {
+		return 1
+	}
+	if r.MaxPerFile > 0 {
+		return r.MaxPerFile
 	}
+	return options.Int("max_per_file", DefaultRipgrepMaxPerFile)
+}
+
+// maxResults returns the total match limit, applying the profile's
+// max_results option or the default.
+func (r RipgrepInput) maxResults(options agent.ToolOptions) int {
+	if r.MaxResults > 0 {
+		return r.MaxResults
+	}
+	return options.Int("max_results", DefaultRipgrepMaxResults)
+}
+
+// limitMatches keeps at most perFile matches per file and total matches
+// overall, along with the context lines around the kept matches. It returns
+// the kept entries and the number of matches that were dropped.
+func limitMatches(matches []RipgrepMatch, perFile, total int) ([]RipgrepMatch, int) {
+	limiter := newMatchLimiter(perFile, total)
+	for _, match := range matches {
+		limiter.add(match)
+	}
+	return limiter.kept, limiter.omitted
+}
+
+// matchLimiter applies limitMatches' limits to matches as they arrive.
+type matchLimiter struct {
+	perFile, total int
+	perFileCount   map[string]int
+	kept           []RipgrepMatch
+	keptTotal      int
+	omitted        int
+	// full reports whether a match was dropped for the total limit, after
+	// which no more matches can be kept.
+	full bool
+}
+
+// newMatchLimiter returns a limiter keeping perFile matches per file and
+// total overall.
+func newMatchLimiter(perFile, total int) *matchLimiter {
+	return &matchLimiter{perFile: perFile, total: total, perFileCount: map[string]int{}}
+}
 
-	return string(output), nil
+// add keeps or drops match, and reports whether more are wanted.
+func (l *matchLimiter) add(match RipgrepMatch) bool {
+	full := l.perFileCount[match.File] >= l.perFile || l.keptTotal >= l.total
+	if match.Context {
+		if !full {
+			l.kept = append(l.kept, match)
+		}
+		return true
+	}
+	if full {
+		l.omitted++
+		if l.keptTotal >= l.total {
+			l.full = true
+			return false
+		}
+		return true
+	}
+	l.perFileCount[match.File]++
+	l.keptTotal++
+	l.kept = append(l.kept, match)
+	return true
+}
+
+// RipgrepMatch is a single matching (or context) line reported by ripgrep.
+type RipgrepMatch struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Text    string `json:"text"`
+	Match   string `json:"match"`
+	Context bool   `json:"context,omitempty"`
+}
+
+// ripgrepText is ripgrep's representation of a path or line, which is either
+// UTF-8 text or base64-encoded bytes.
+type ripgrepText struct {
+	Text  *string `json:"text"`
+	Bytes *string `json:"bytes"`
+}
+
+// String returns the text, decoding it from base64 if needed.
+func (t ripgrepText) String() string {
+	if t.Text != nil {
+		return *t.Text
+	}
+	if t.Bytes != nil {
+		if decoded, err := base64.StdEncoding.DecodeString(*t.Bytes); err == nil {
+			return string(decoded)
+		}
+	}
+	return ""
+}
+
+// ripgrepEvent is one line of 'rg --json' output.
+type ripgrepEvent struct {
+	Type string `json:"type"`
+	Data struct {
+		Path       ripgrepText `json:"path"`
+		Lines      ripgrepText `json:"lines"`
+		LineNumber int         `json:"line_number"`
+		Submatches []struct {
+			Match ripgrepText `json:"match"`
+			Start int         `json:"start"`
+		} `json:"submatches"`
+	} `json:"data"`
+}
+
+// parseRipgrepJSON converts 'rg --json' output into a list of matches and
+// context lines in the order ripgrep reported them.
+func parseRipgrepJSON(output []byte) ([]RipgrepMatch, error) {
+	var matches []RipgrepMatch
+	err := readRipgrepJSON(bytes.NewReader(output), func(match RipgrepMatch) bool {
+		matches = append(matches, match)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// readRipgrepJSON reads 'rg --json' output and passes each match and
+// context line to yield, in the order ripgrep reported them, until yield
+// returns false.
+func readRipgrepJSON(r io.Reader, yield func(RipgrepMatch) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var event ripgrepEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("failed to parse ripgrep output: %w", err)
+		}
+		if event.Type != "match" && event.Type != "context" {
+			continue
+		}
+
+		match := RipgrepMatch{
+			File:    event.Data.Path.String(),
+			Line:    event.Data.LineNumber,
+			Text:    strings.TrimRight(event.Data.Lines.String(), "\r\n"),
+			Context: event.Type == "context",
+		}
+		if len(event.Data.Submatches) > 0 {
+			match.Column = event.Data.Submatches[0].Start + 1
+			match.Match = event.Data.Submatches[0].Match.String()
+		}
+		if !yield(match) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// formatRipgrepMatches renders matches compactly for the model, grouped by
+// file, followed by a count of matches and files and of any omitted matches,
+// which is a lower bound if atLeast is set.
+func formatRipgrepMatches(matches []RipgrepMatch, filesOnly bool, omitted int, atLeast bool) string {
+	var b strings.Builder
+	matchCount, fileCount := 0, 0
+	currentFile := ""
+	for _, match := range matches {
+		if match.File != currentFile || fileCount == 0 {
+			if fileCount > 0 && !filesOnly {
+				b.WriteString("\n")
+			}
+			currentFile = match.File
+			fileCount++
+			b.WriteString(match.File + "\n")
+		}
+		if filesOnly {
+			continue
+		}
+		if match.Context {
+			fmt.Fprintf(&b, "  %d-  %s\n", match.Line, match.Text)
+			continue
+		}
+		matchCount++
+		fmt.Fprintf(&b, "  %d:%d: %s\n", match.Line, match.Column, match.Text)
+	}
+
+	if filesOnly {
+		fmt.Fprintf(&b, "\n%d files with matches", fileCount)
+	} else {
+		fmt.Fprintf(&b, "\n%d matches in %d files", matchCount, fileCount)
+	}
+	if omitted > 0 {
+		more := fmt.Sprintf("%d more", omitted)
+		if atLeast {
+			more = "at least " + more
+		}
+		fmt.Fprintf(&b, " (%s omitted due to result limits; narrow the search or raise maxPerFile/maxResults)", more)
+	}
+	return b.String()
 }
@@ -1,12 +1,16 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestRipgrep(t *testing.T) {
@@ -129,7 +133,7 @@ func TestRipgrep(t *testing.T) {
 				t.Fatalf("Failed to marshal input: %v", err)
 			}
 
-			result, err := Ripgrep(inputJSON)
+			result, err := Ripgrep(context.Background(), inputJSON)
 
 			if tt.expectError {
 				if err == nil {
@@ -174,7 +178,7 @@ func TestRipgrepInvalidJSON(t *testing.T) {
 	}
 
 	invalidJSON := []byte(`{"invalid": json}`)
-	_, err := Ripgrep(invalidJSON)
+	_, err := Ripgrep(context.Background(), invalidJSON)
 	if err == nil {
 		t.Error("Expected error for invalid JSON input")
 	}
@@ -223,15 +227,172 @@ func TestRipgrepMaxCount(t *testing.T) {
 		t.Fatalf("Failed to marshal input: %v", err)
 	}
 
-	result, err := Ripgrep(inputJSON)
+	result, err := Ripgrep(context.Background(), inputJSON)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
 
-	// Count the number of matches in the result
-	lines := strings.Split(strings.TrimSpace(result), "\n")
-	if len(lines) > 15 {
-		t.Errorf("Expected at most 15 matches due to max-count, got %d", len(lines))
+	// Count the number of match lines in the result
+	matchLine := regexp.MustCompile(`^\s+\d+:\d+: `)
+	count := 0
+	for _, line := range strings.Split(result, "\n") {
+		if matchLine.MatchString(line) {
+			count++
+		}
+	}
+	if count > 15 {
+		t.Errorf("Expected at most 15 matches due to max-count, got %d", count)
+	}
+}
+
+func TestRipgrepFilters(t *testing.T) {
+	if !isRipgrepAvailable() {
+		t.Skip("ripgrep (rg) is not available, skipping test")
+	}
+
+	tempDir := t.TempDir()
+	testFiles := map[string]string{
+		"main.go":         "package main\n// TODO: first\nfunc main() {}\n",
+		"notes.md":        "# Notes\nTODO: write docs\n",
+		"vendor/lib/a.go": "package lib\n// TODO: vendored\n",
+	}
+	for filename, content := range testFiles {
+		fullPath := filepath.Join(tempDir, filename)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", fullPath, err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		input       RipgrepInput
+		expected    []string
+		notExpected []string
+	}{
+		{
+			name:        "glob include and exclude",
+			input:       RipgrepInput{Pattern: "TODO", Path: tempDir, Glob: []string{"*.go", "!vendor/**"}},
+			expected:    []string{"main.go"},
+			notExpected: []string{"notes.md", "a.go"},
+		},
+		{
+			name:        "file type",
+			input:       RipgrepInput{Pattern: "TODO", Path: tempDir, Type: []string{"md"}},
+			expected:    []string{"notes.md"},
+			notExpected: []string{"main.go"},
+		},
+		{
+			name:     "context lines",
+			input:    RipgrepInput{Pattern: "TODO: first", Path: filepath.Join(tempDir, "main.go"), Context: 1},
+			expected: []string{"package main", "func main"},
+		},
+		{
+			name:        "files with matches",
+			input:       RipgrepInput{Pattern: "TODO", Path: tempDir, FilesWithMatches: true},
+			expected:    []string{"main.go", "notes.md"},
+			notExpected: []string{"TODO"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputJSON, err := json.Marshal(tt.input)
+			if err != nil {
+				t.Fatalf("Failed to marshal input: %v", err)
+			}
+
+			result, err := Ripgrep(context.Background(), inputJSON)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(result, expected) {
+					t.Errorf("Expected %q to be in output, but got: %s", expected, result)
+				}
+			}
+			for _, notExpected := range tt.notExpected {
+				if strings.Contains(result, notExpected) {
+					t.Errorf("Expected %q not to be in output, but got: %s", notExpected, result)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRipgrepJSON(t *testing.T) {
+	output := `{"type":"begin","data":{"path":{"text":"a.go"}}}
+{"type":"context","data":{"path":{"text":"a.go"},"lines":{"text":"package main\n"},"line_number":1,"absolute_offset":0,"submatches":[]}}
+{"type":"match","data":{"path":{"text":"a.go"},"lines":{"text":"func Hello() {}\n"},"line_number":2,"absolute_offset":13,"submatches":[{"match":{"text":"Hello"},"start":5,"end":10}]}}
+{"type":"end","data":{"path":{"text":"a.go"}}}
+{"type":"begin","data":{"path":{"bytes":"Yi5nbw=="}}}
+{"type":"match","data":{"path":{"bytes":"Yi5nbw=="},"lines":{"text":"Hello()\n"},"line_number":7,"absolute_offset":40,"submatches":[{"match":{"text":"Hello"},"start":0,"end":5}]}}
+{"type":"end","data":{"path":{"bytes":"Yi5nbw=="}}}
+{"type":"summary","data":{}}
+`
+
+	matches, err := parseRipgrepJSON([]byte(output))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []RipgrepMatch{
+		{File: "a.go", Line: 1, Text: "package main", Context: true},
+		{File: "a.go", Line: 2, Column: 6, Text: "func Hello() {}", Match: "Hello"},
+		{File: "b.go", Line: 7, Column: 1, Text: "Hello()", Match: "Hello"},
+	}
+	if len(matches) != len(expected) {
+		t.Fatalf("Expected %d matches, got %d: %+v", len(expected), len(matches), matches)
+	}
+	for i := range expected {
+		if matches[i] != expected[i] {
+			t.Errorf("Match %d: expected %+v, got %+v", i, expected[i], matches[i])
+		}
+	}
+
+	formatted := formatRipgrepMatches(matches, false, 0, false)
+	for _, want := range []string{"a.go\n  1-  package main\n  2:6: func Hello() {}", "b.go\n  7:1: Hello()", "2 matches in 2 files"} {
+		if !strings.Contains(formatted, want) {
+			t.Errorf("Expected %q in formatted output, got:\n%s", want, formatted)
+		}
+	}
+
+	filesOnly := formatRipgrepMatches(matches, true, 0, false)
+	if filesOnly != "a.go\nb.go\n\n2 files with matches" {
+		t.Errorf("Unexpected files-only output:\n%s", filesOnly)
+	}
+}
+
+func TestLimitMatches(t *testing.T) {
+	matches := []RipgrepMatch{
+		{File: "a.go", Line: 1, Text: "ctx", Context: true},
+		{File: "a.go", Line: 2, Text: "m1"},
+		{File: "a.go", Line: 3, Text: "m2"},
+		{File: "a.go", Line: 4, Text: "ctx", Context: true},
+		{File: "a.go", Line: 5, Text: "m3"},
+		{File: "b.go", Line: 1, Text: "m4"},
+		{File: "c.go", Line: 1, Text: "m5"},
+	}
+
+	kept, omitted := limitMatches(matches, 2, 3)
+	if omitted != 2 {
+		t.Errorf("Expected 2 omitted matches, got %d", omitted)
+	}
+
+	var texts []string
+	for _, m := range kept {
+		texts = append(texts, m.File+":"+m.Text)
+	}
+	expected := "a.go:ctx a.go:m1 a.go:m2 b.go:m4"
+	if strings.Join(texts, " ") != expected {
+		t.Errorf("Expected %q, got %q", expected, strings.Join(texts, " "))
+	}
+
+	formatted := formatRipgrepMatches(kept, false, omitted, false)
+	if !strings.Contains(formatted, "3 matches in 2 files (2 more omitted") {
+		t.Errorf("Expected omitted count in summary, got:\n%s", formatted)
 	}
 }
 
@@ -239,4 +400,39 @@ func TestRipgrepMaxCount(t *testing.T) {
 func isRipgrepAvailable() bool {
 	_, err := exec.LookPath("rg")
 	return err == nil
-}
\ No newline at end of file
+}
+
+func TestRipgrepStopsAtTheLimit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake rg is a shell script")
+	}
+	// A fake rg that records its arguments and reports matches without end,
+	// as a common pattern in a huge tree would
+	dir := t.TempDir()
+	script := `#!/bin/sh
+echo "$@" > "$(dirname "$0")/args"
+i=0
+while true; do
+	i=$((i+1))
+	echo '{"type":"match","data":{"path":{"text":"f'$((i / 3))'.go"},"lines":{"text":"hit"},"line_number":'$i',"submatches":[{"match":{"text":"hit"},"start":0}]}}'
+done
+`
+	if err := os.WriteFile(filepath.Join(dir, "rg"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := Ripgrep(ctx, json.RawMessage(`{"pattern":"hit","maxPerFile":2,"maxResults":5}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "5 matches in 3 files (at least 2 more omitted") {
+		t.Errorf("Expected the output to stop at the limit, got:\n%s", result)
+	}
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	if !strings.Contains(string(args), "--max-count 3") {
+		t.Errorf("Expected rg to stop one match past the per-file limit, got args %q", args)
+	}
+}
@@ -0,0 +1,268 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"tiny-trae/internal/agent"
+)
+
+const (
+	// maxTestFailureLines caps the output lines kept for each failing test.
+	maxTestFailureLines = 30
+	// maxRawTestOutputLines is how much of unparsed test output is returned.
+	maxRawTestOutputLines = 80
+)
+
+// RunTestsDefinition defines the 'run_tests' tool.
+var RunTestsDefinition = agent.ToolDefinition{
+	Name: "run_tests",
+	Description: `Run the project's tests and return a compact summary of the failures.
+
+By default runs 'go test -json' on the given packages (./... if none) and reports each failing test with its package, name, and failure output; passing tests are only counted.
+Set 'command' to run a different test command; if it does not produce 'go test -json' output, the exit status and the end of its output are returned.`,
+	InputSchema: RunTestsInputSchema,
+	Function:    RunTests,
+
+	RequiresApproval: true,
+	Preview:          RunTestsPreview,
+}
+
+// RunTestsInput defines the input schema for the 'run_tests' tool.
+type RunTestsInput struct {
+	Packages []string `json:"packages,omitempty" jsonschema_description:"Go package patterns to test. Defaults to ./..."`
+	Run      string   `json:"run,omitempty" jsonschema_description:"Only run tests matching this regular expression (go test -run)"`
+	Path     string   `json:"path,omitempty" jsonschema_description:"The directory to run the tests in. Defaults to the current directory"`
+	Command  string   `json:"command,omitempty" jsonschema_description:"A custom shell command to run instead of go test"`
+}
+
+// RunTestsInputSchema is the JSON schema for the 'run_tests' tool's input.
+var RunTestsInputSchema = agent.GenerateSchema[RunTestsInput]()
+
+// RunTestsPreview returns the command that will run.
+func RunTestsPreview(input json.RawMessage) string {
+	runTestsInput := RunTestsInput{}
+	if err := json.Unmarshal(input, &runTestsInput); err != nil {
+		return string(input)
+	}
+	return strings.Join(runTestsInput.command(), " ")
+}
+
+// command returns the argv that runs the tests.
+func (i RunTestsInput) command() []string {
+	if i.Command != "" {
+		return []string{"bash", "-c", i.Command}
+	}
+	args := []string{"go", "test", "-json"}
+	if i.Run != "" {
+		args = append(args, "-run", i.Run)
+	}
+	if len(i.Packages) == 0 {
+		return append(args, "./...")
+	}
+	return append(args, i.Packages...)
+}
+
+// RunTests implements the 'run_tests' tool.
+func RunTests(ctx context.Context, input json.RawMessage) (string, error) {
+	runTestsInput := RunTestsInput{}
+	if err := json.Unmarshal(input, &runTestsInput); err != nil {
+		return "", err
+	}
+
+	args := runTestsInput.command()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = runTestsInput.Path
+	output, err := runWithLimits(ctx, cmd, CommandLimits)
+
+	// A failing test run exits non-zero; that is reported in the summary
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return "", fmt.Errorf("failed to run tests: %w", err)
+	}
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+
+	if report, ok := parseGoTestJSON(output); ok {
+		return report.String(), nil
+	}
+	return summarizeRawTestOutput(output, err), nil
+}
+
+// goTestEvent is one line of 'go test -json' output (see 'go doc test2json').
+type goTestEvent struct {
+	Action     string  `json:"Action"`
+	Package    string  `json:"Package"`
+	ImportPath string  `json:"ImportPath"`
+	Test       string  `json:"Test"`
+	Output     string  `json:"Output"`
+	Elapsed    float64 `json:"Elapsed"`
+}
+
+// TestFailure is a failed test, or a package that failed outside any test.
+type TestFailure struct {
+	Package string  `json:"package"`
+	Test    string  `json:"test,omitempty"`
+	Elapsed float64 `json:"elapsed"`
+	Output  string  `json:"output"`
+}
+
+// TestReport summarizes a test run.
+type TestReport struct {
+	Passed   int           `json:"passed"`
+	Failed   int           `json:"failed"`
+	Skipped  int           `json:"skipped"`
+	Packages int           `json:"packages"`
+	Failures []TestFailure `json:"failures,omitempty"`
+}
+
+// parseGoTestJSON builds a report from 'go test -json' output. Lines that are
+// not JSON, such as compiler errors, are attributed to the packages that
+// failed without a failing test. It returns false if no test events are found.
+func parseGoTestJSON(output []byte) (TestReport, bool) {
+	var report TestReport
+	outputs := map[string][]string{}
+	packages := map[string]bool{}
+	failedPackages := map[string]float64{}
+	packagesWithFailedTests := map[string]bool{}
+	var stray []string
+	found := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		var event goTestEvent
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &event) != nil || event.Action == "" {
+			if strings.TrimSpace(line) != "" {
+				stray = append(stray, line)
+			}
+			continue
+		}
+		found = true
+
+		pkg := event.Package
+		if pkg == "" {
+			pkg = event.ImportPath
+		}
+		key := pkg + "\x00" + event.Test
+
+		switch event.Action {
+		case "output", "build-output":
+			outputs[key] = append(outputs[key], strings.TrimRight(event.Output, "\n"))
+		case "pass", "skip", "fail":
+			if event.Test == "" {
+				packages[pkg] = true
+				if event.Action == "fail" {
+					failedPackages[pkg] = event.Elapsed
+				}
+				continue
+			}
+			switch event.Action {
+			case "pass":
+				report.Passed++
+			case "skip":
+				report.Skipped++
+			case "fail":
+				report.Failed++
+				packagesWithFailedTests[pkg] = true
+				report.Failures = append(report.Failures, TestFailure{
+					Package: pkg,
+					Test:    event.Test,
+					Elapsed: event.Elapsed,
+					Output:  testFailureOutput(outputs[key]),
+				})
+			}
+		}
+	}
+	if !found {
+		return TestReport{}, false
+	}
+	report.Packages = len(packages)
+
+	// Packages that failed without a failing test did not build or crashed
+	var broken []string
+	for pkg := range failedPackages {
+		if !packagesWithFailedTests[pkg] {
+			broken = append(broken, pkg)
+		}
+	}
+	sort.Strings(broken)
+	for _, pkg := range broken {
+		lines := append(outputs[pkg+"\x00"], stray...)
+		stray = nil
+		report.Failures = append(report.Failures, TestFailure{
+			Package: pkg,
+			Elapsed: failedPackages[pkg],
+			Output:  testFailureOutput(lines),
+		})
+	}
+	return report, true
+}
+
+// testFailureOutput drops test framework noise from a failure's output and
+// keeps at most maxTestFailureLines lines.
+func testFailureOutput(lines []string) string {
+	var kept []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "FAIL" || trimmed == "PASS" ||
+			strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- ") ||
+			strings.HasPrefix(trimmed, "FAIL\t") || strings.HasPrefix(trimmed, "ok  \t") {
+			continue
+		}
+		kept = append(kept, trimmed)
+	}
+	if len(kept) > maxTestFailureLines {
+		omitted := len(kept) - maxTestFailureLines
+		kept = append(kept[:maxTestFailureLines], fmt.Sprintf("... (%d more lines)", omitted))
+	}
+	return strings.Join(kept, "\n")
+}
+
+// String renders the report for the model.
+func (r TestReport) String() string {
+	status := "PASS"
+	if len(r.Failures) > 0 {
+		status = "FAIL"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d passed, %d failed, %d skipped in %d packages", status, r.Passed, r.Failed, r.Skipped, r.Packages)
+	for _, failure := range r.Failures {
+		if failure.Test == "" {
+			fmt.Fprintf(&b, "\n\n--- FAIL: package %s", failure.Package)
+		} else {
+			fmt.Fprintf(&b, "\n\n--- FAIL: %s %s (%.2fs)", failure.Package, failure.Test, failure.Elapsed)
+		}
+		if failure.Output != "" {
+			b.WriteString("\n    " + strings.ReplaceAll(failure.Output, "\n", "\n    "))
+		}
+	}
+	return b.String()
+}
+
+// summarizeRawTestOutput reports the exit status and the last lines of
+// output from a test command whose output could not be parsed.
+func summarizeRawTestOutput(output []byte, runErr error) string {
+	status := "Tests passed (exit status 0)"
+	var exitErr *exec.ExitError
+	if errors.As(runErr, &exitErr) {
+		status = fmt.Sprintf("Tests failed (exit status %d)", exitErr.ExitCode())
+	}
+
+	lines := strings.Split(strings.TrimRight(string(output), "\n"), "\n")
+	if len(lines) > maxRawTestOutputLines {
+		omitted := len(lines) - maxRawTestOutputLines
+		lines = append([]string{fmt.Sprintf("... (%d earlier lines omitted)", omitted)}, lines[omitted:]...)
+	}
+	return status + "\n\n" + strings.Join(lines, "\n")
+}
@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const sampleGoTestJSON = `{"Action":"start","Package":"example.com/a"}
+{"Action":"run","Package":"example.com/a","Test":"TestOK"}
+{"Action":"output","Package":"example.com/a","Test":"TestOK","Output":"=== RUN   TestOK\n"}
+{"Action":"pass","Package":"example.com/a","Test":"TestOK","Elapsed":0.01}
+{"Action":"run","Package":"example.com/a","Test":"TestBad"}
+{"Action":"output","Package":"example.com/a","Test":"TestBad","Output":"=== RUN   TestBad\n"}
+{"Action":"output","Package":"example.com/a","Test":"TestBad","Output":"    a_test.go:12: got 1, want 2\n"}
+{"Action":"output","Package":"example.com/a","Test":"TestBad","Output":"--- FAIL: TestBad (0.00s)\n"}
+{"Action":"fail","Package":"example.com/a","Test":"TestBad","Elapsed":0.02}
+{"Action":"run","Package":"example.com/a","Test":"TestSkip"}
+{"Action":"skip","Package":"example.com/a","Test":"TestSkip","Elapsed":0}
+{"Action":"output","Package":"example.com/a","Output":"FAIL\n"}
+{"Action":"fail","Package":"example.com/a","Elapsed":0.1}
+# example.com/b
+b/b.go:3:1: syntax error: unexpected }
+{"Action":"output","Package":"example.com/b","Output":"FAIL\texample.com/b [build failed]\n"}
+{"Action":"fail","Package":"example.com/b","Elapsed":0}
+`
+
+func TestParseGoTestJSON(t *testing.T) {
+	report, ok := parseGoTestJSON([]byte(sampleGoTestJSON))
+	if !ok {
+		t.Fatal("Expected go test -json output to be parsed")
+	}
+	if report.Passed != 1 || report.Failed != 1 || report.Skipped != 1 || report.Packages != 2 {
+		t.Errorf("Unexpected counts: %+v", report)
+	}
+	if len(report.Failures) != 2 {
+		t.Fatalf("Expected 2 failures, got %d: %+v", len(report.Failures), report.Failures)
+	}
+
+	failure := report.Failures[0]
+	if failure.Package != "example.com/a" || failure.Test != "TestBad" {
+		t.Errorf("Unexpected first failure: %+v", failure)
+	}
+	if failure.Output != "a_test.go:12: got 1, want 2" {
+		t.Errorf("Expected only the failure message, got %q", failure.Output)
+	}
+
+	broken := report.Failures[1]
+	if broken.Package != "example.com/b" || broken.Test != "" {
+		t.Errorf("Unexpected package failure: %+v", broken)
+	}
+	if !strings.Contains(broken.Output, "syntax error") {
+		t.Errorf("Expected build error in package failure, got %q", broken.Output)
+	}
+
+	out := report.String()
+	if !strings.HasPrefix(out, "FAIL: 1 passed, 1 failed, 1 skipped in 2 packages") {
+		t.Errorf("Unexpected summary: %q", out)
+	}
+	if strings.Contains(out, "TestOK") {
+		t.Errorf("Passing tests should not be listed: %q", out)
+	}
+}
+
+func TestParseGoTestJSONNotJSON(t *testing.T) {
+	if _, ok := parseGoTestJSON([]byte("ok  \tpkg\t0.01s\n")); ok {
+		t.Error("Expected plain output not to be parsed as go test -json")
+	}
+}
+
+func TestTestFailureOutputTruncates(t *testing.T) {
+	var lines []string
+	for i := 0; i < maxTestFailureLines+5; i++ {
+		lines = append(lines, "line")
+	}
+	out := testFailureOutput(lines)
+	if !strings.HasSuffix(out, "... (5 more lines)") {
+		t.Errorf("Expected truncation note, got %q", out[len(out)-30:])
+	}
+}
+
+func TestRunTestsCustomCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		expected string
+	}{
+		{
+			name:     "passing command",
+			command:  "echo all good",
+			expected: "Tests passed (exit status 0)\n\nall good",
+		},
+		{
+			name:     "failing command",
+			command:  "echo broken; exit 3",
+			expected: "Tests failed (exit status 3)\n\nbroken",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, _ := json.Marshal(RunTestsInput{Command: tt.command})
+			result, err := RunTests(context.Background(), input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRunTestsPreview(t *testing.T) {
+	preview := RunTestsPreview(json.RawMessage(`{"packages":["./internal/..."],"run":"TestFoo"}`))
+	if preview != "go test -json -run TestFoo ./internal/..." {
+		t.Errorf("Unexpected preview: %q", preview)
+	}
+}
+
+func TestRunTestsInvalidJSON(t *testing.T) {
+	if _, err := RunTests(context.Background(), []byte(`{"invalid": json}`)); err == nil {
+		t.Error("Expected error for invalid JSON input")
+	}
+}
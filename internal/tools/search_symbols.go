@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/lsp"
+)
+
+// DefaultSearchSymbolsMaxResults caps the symbols returned by 'search_symbols'.
+const DefaultSearchSymbolsMaxResults = 50
+
+// SearchSymbolsDefinition defines the 'search_symbols' tool.
+var SearchSymbolsDefinition = agent.ToolDefinition{
+	Name: "search_symbols",
+	Description: `Find Go types, functions, methods, and other declarations by name across the workspace using gopls.
+
+The query is fuzzy-matched against symbol names, so 'NewAgent' or 'agent.Run' both work.
+Prefer this over ripgrep when looking for where something is declared in a Go repository. Requires gopls.`,
+	InputSchema: SearchSymbolsInputSchema,
+	Function:    SearchSymbols,
+}
+
+// SearchSymbolsInput defines the input schema for the 'search_symbols' tool.
+type SearchSymbolsInput struct {
+	Query      string `json:"query" jsonschema_description:"The symbol name or part of it"`
+	Path       string `json:"path,omitempty" jsonschema_description:"A directory inside the Go module or workspace. Defaults to the current directory"`
+	Kind       string `json:"kind,omitempty" jsonschema_description:"Only return symbols of this kind, e.g. 'function', 'method', 'struct', 'interface', 'constant'"`
+	MaxResults int    `json:"max_results,omitempty" jsonschema_description:"The maximum number of symbols to return. Defaults to 50"`
+}
+
+// SearchSymbolsInputSchema is the JSON schema for the 'search_symbols' tool's input.
+var SearchSymbolsInputSchema = agent.GenerateSchema[SearchSymbolsInput]()
+
+// SearchSymbols implements the 'search_symbols' tool.
+func SearchSymbols(ctx context.Context, input json.RawMessage) (string, error) {
+	searchInput := SearchSymbolsInput{}
+	if err := json.Unmarshal(input, &searchInput); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(searchInput.Query) == "" {
+		return "", fmt.Errorf("query must not be empty")
+	}
+
+	dir := searchInput.Path
+	if dir == "" {
+		dir = "."
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return "", err
+	} else if !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+
+	server, _ := lsp.ServerNamed("gopls")
+	client, err := lsp.DefaultManager.ClientForServer(ctx, server, lsp.FindRoot(dir, server.RootMarkers))
+	if err != nil {
+		return "", err
+	}
+	symbols, err := client.WorkspaceSymbols(ctx, searchInput.Query)
+	if err != nil {
+		return "", err
+	}
+
+	maxResults := searchInput.MaxResults
+	if maxResults <= 0 {
+		maxResults = DefaultSearchSymbolsMaxResults
+	}
+	return formatSymbols(symbols, searchInput.Kind, maxResults), nil
+}
+
+// formatSymbols renders symbols as 'kind name (container) path:line' lines,
+// keeping only those of the given kind when kind is set.
+func formatSymbols(symbols []lsp.SymbolInformation, kind string, maxResults int) string {
+	cwd, _ := os.Getwd()
+
+	var b strings.Builder
+	shown, total := 0, 0
+	for _, symbol := range symbols {
+		if kind != "" && !strings.EqualFold(symbol.Kind.String(), kind) {
+			continue
+		}
+		total++
+		if shown >= maxResults {
+			continue
+		}
+		shown++
+
+		path := symbol.Location.Path()
+		if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+		name := symbol.Name
+		if symbol.ContainerName != "" {
+			name += " (" + symbol.ContainerName + ")"
+		}
+		fmt.Fprintf(&b, "%s %s %s:%d\n", symbol.Kind, name, path, symbol.Location.Range.Start.Line+1)
+	}
+
+	if total == 0 {
+		return "No symbols found."
+	}
+	if total > shown {
+		fmt.Fprintf(&b, "\n(%d more symbols omitted; refine the query)", total-shown)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
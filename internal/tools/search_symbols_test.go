@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tiny-trae/internal/lsp"
+)
+
+func TestFormatSymbols(t *testing.T) {
+	cwd, _ := os.Getwd()
+	uri := lsp.PathToURI(filepath.Join(cwd, "agent.go"))
+	symbols := []lsp.SymbolInformation{
+		{Name: "Agent", Kind: 23, ContainerName: "agent", Location: lsp.Location{URI: uri, Range: lsp.Range{Start: lsp.Position{Line: 9}}}},
+		{Name: "NewAgent", Kind: 12, ContainerName: "agent", Location: lsp.Location{URI: uri, Range: lsp.Range{Start: lsp.Position{Line: 19}}}},
+		{Name: "Run", Kind: 6, Location: lsp.Location{URI: uri, Range: lsp.Range{Start: lsp.Position{Line: 29}}}},
+	}
+
+	tests := []struct {
+		name       string
+		kind       string
+		maxResults int
+		expected   string
+	}{
+		{
+			name:       "all",
+			maxResults: 10,
+			expected:   "struct Agent (agent) agent.go:10\nfunction NewAgent (agent) agent.go:20\nmethod Run agent.go:30",
+		},
+		{
+			name:       "kind filter",
+			kind:       "Function",
+			maxResults: 10,
+			expected:   "function NewAgent (agent) agent.go:20",
+		},
+		{
+			name:       "truncated",
+			maxResults: 1,
+			expected:   "struct Agent (agent) agent.go:10\n\n(2 more symbols omitted; refine the query)",
+		},
+		{
+			name:       "no match",
+			kind:       "interface",
+			maxResults: 10,
+			expected:   "No symbols found.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := formatSymbols(symbols, tt.kind, tt.maxResults)
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestSearchSymbolsErrors(t *testing.T) {
+	inputs := []json.RawMessage{
+		json.RawMessage(`{"query":`),
+		json.RawMessage(`{"query":"  "}`),
+		json.RawMessage(`{"query":"Agent","path":"/does/not/exist"}`),
+	}
+	for _, input := range inputs {
+		if _, err := SearchSymbols(context.Background(), input); err == nil {
+			t.Errorf("Expected error for input %s", input)
+		}
+	}
+}
+
+func TestSearchSymbolsDefinition(t *testing.T) {
+	if SearchSymbolsDefinition.Name != "search_symbols" {
+		t.Errorf("Expected name 'search_symbols', got %q", SearchSymbolsDefinition.Name)
+	}
+	if !strings.Contains(SearchSymbolsDefinition.Description, "gopls") {
+		t.Error("Expected description to mention gopls")
+	}
+}
@@ -0,0 +1,128 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/semantic"
+)
+
+const (
+	// DefaultSemanticSearchMaxResults is the number of snippets returned by
+	// 'semantic_search' when max_results is not set.
+	DefaultSemanticSearchMaxResults = 8
+	// maxSnippetLines caps the lines shown for each snippet.
+	maxSnippetLines = 20
+)
+
+// SemanticSearchDefinition defines the 'semantic_search' tool.
+var SemanticSearchDefinition = agent.ToolDefinition{
+	Name: "semantic_search",
+	Description: `Find the code most related to a natural-language description using the project's embeddings index.
+
+WHEN TO USE THIS TOOL:
+- For conceptual questions such as "where are retries handled" or "how does authentication work"
+- When you do not know the exact names or strings used in the code
+
+WHEN NOT TO USE THIS TOOL:
+- When you know the identifier or text to look for; use ripgrep or search_symbols instead
+
+Returns the best-matching snippets with their file path, line range, and similarity score. Requires an index built with 'tiny-trae index'; files changed since then may be out of date.`,
+	InputSchema: SemanticSearchInputSchema,
+	Function:    SemanticSearch,
+}
+
+// SemanticSearchInput defines the input schema for the 'semantic_search' tool.
+type SemanticSearchInput struct {
+	Query      string `json:"query" jsonschema_description:"A description of the code you are looking for"`
+	Path       string `json:"path,omitempty" jsonschema_description:"A directory inside the indexed project. Defaults to the current directory"`
+	MaxResults int    `json:"max_results,omitempty" jsonschema_description:"The maximum number of snippets to return. Defaults to 8"`
+}
+
+// SemanticSearchInputSchema is the JSON schema for the 'semantic_search' tool's input.
+var SemanticSearchInputSchema = agent.GenerateSchema[SemanticSearchInput]()
+
+// SemanticSearch implements the 'semantic_search' tool.
+func SemanticSearch(ctx context.Context, input json.RawMessage) (string, error) {
+	searchInput := SemanticSearchInput{}
+	if err := json.Unmarshal(input, &searchInput); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(searchInput.Query) == "" {
+		return "", fmt.Errorf("query must not be empty")
+	}
+	maxResults := searchInput.MaxResults
+	if maxResults <= 0 {
+		maxResults = DefaultSemanticSearchMaxResults
+	}
+	dir := searchInput.Path
+	if dir == "" {
+		dir = "."
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	root, err := semantic.FindRoot(absDir)
+	if err != nil {
+		return "", err
+	}
+	index, err := semantic.Load(root)
+	if err != nil {
+		return "", err
+	}
+	embedder, err := index.EmbedderFor()
+	if err != nil {
+		return "", err
+	}
+	vectors, err := embedder.Embed(ctx, []string{searchInput.Query})
+	if err != nil {
+		return "", err
+	}
+
+	results := index.Search(vectors[0], maxResults)
+	if len(results) == 0 {
+		return "The semantic index is empty.", nil
+	}
+
+	var b strings.Builder
+	for i, result := range results {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		path := filepath.Join(root, filepath.FromSlash(result.Path))
+		display := path
+		if rel, err := filepath.Rel(absDir, path); err == nil {
+			display = rel
+		}
+		fmt.Fprintf(&b, "%s:%d-%d (score %.2f)\n", display, result.StartLine, result.EndLine, result.Score)
+		b.WriteString(readSnippet(path, result.StartLine, result.EndLine))
+	}
+	return b.String(), nil
+}
+
+// readSnippet returns lines start through end of a file with line numbers,
+// capped at maxSnippetLines.
+func readSnippet(path string, start, end int) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "    (file no longer exists; re-run 'tiny-trae index')"
+	}
+	lines := strings.Split(string(content), "\n")
+	if start > len(lines) {
+		return "    (file has changed since it was indexed; re-run 'tiny-trae index')"
+	}
+	end = min(end, len(lines), start+maxSnippetLines-1)
+
+	var b strings.Builder
+	for n := start; n <= end; n++ {
+		fmt.Fprintf(&b, "%6d\t%s\n", n, strings.TrimRight(lines[n-1], "\r"))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tiny-trae/internal/semantic"
+)
+
+func TestSemanticSearch(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "retry.go"), []byte("package main\n\n// retryWithBackoff retries a request with exponential backoff.\nfunc retryWithBackoff() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "color.go"), []byte("package main\n\n// pickColor chooses a terminal color theme.\nfunc pickColor() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	index, err := semantic.Build(context.Background(), root, semantic.LocalEmbedder{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Save(root); err != nil {
+		t.Fatal(err)
+	}
+
+	input, _ := json.Marshal(SemanticSearchInput{Query: "where are retries and backoff handled", Path: root, MaxResults: 1})
+	result, err := SemanticSearch(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(result, "retry.go:1-4 (score ") {
+		t.Errorf("Expected retry.go to match, got:\n%s", result)
+	}
+	if !strings.Contains(result, "     3\t// retryWithBackoff retries") {
+		t.Errorf("Expected numbered snippet, got:\n%s", result)
+	}
+}
+
+func TestSemanticSearchNoIndex(t *testing.T) {
+	input, _ := json.Marshal(SemanticSearchInput{Query: "anything", Path: t.TempDir()})
+	if _, err := SemanticSearch(context.Background(), input); err == nil {
+		t.Error("Expected error when no index exists")
+	}
+}
+
+func TestSemanticSearchEmptyQuery(t *testing.T) {
+	if _, err := SemanticSearch(context.Background(), []byte(`{"query": " "}`)); err == nil {
+		t.Error("Expected error for empty query")
+	}
+}
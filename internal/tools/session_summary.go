@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"tiny-trae/internal/agent"
+)
+
+// SessionSummaryDefinition defines the 'session_summary' tool.
+var SessionSummaryDefinition = agent.ToolDefinition{
+	Name: "session_summary",
+	Description: `Keep the running summary of this session up to date. The summary is shown to you with every request, apart from the conversation, so it keeps what matters once earlier messages are out of reach.
+
+Update it when a decision is made, such as an approach the user chose or a constraint they gave, and when a question comes up that is not answered yet or gets answered. Keep items short and specific. The files changed by edits are added to it for you.
+
+Each list given replaces the current one, so pass the whole list, including items to keep; leave a list out to keep it as it is, or pass an empty list to clear it.`,
+	InputSchema: SessionSummaryInputSchema,
+	Function:    SessionSummary,
+}
+
+// SessionSummaryInput defines the input schema for the 'session_summary' tool.
+type SessionSummaryInput struct {
+	Decisions     []string `json:"decisions,omitempty" jsonschema_description:"All decisions made so far, replacing the current list"`
+	OpenQuestions []string `json:"open_questions,omitempty" jsonschema_description:"All questions still open, replacing the current list"`
+}
+
+// SessionSummaryInputSchema is the JSON schema for the 'session_summary' tool's input.
+var SessionSummaryInputSchema = agent.GenerateSchema[SessionSummaryInput]()
+
+// SessionSummary implements the 'session_summary' tool.
+func SessionSummary(ctx context.Context, input json.RawMessage) (string, error) {
+	summaryInput := SessionSummaryInput{}
+	if err := json.Unmarshal(input, &summaryInput); err != nil {
+		return "", err
+	}
+	if summaryInput.Decisions == nil && summaryInput.OpenQuestions == nil {
+		return "", fmt.Errorf("give decisions, open_questions, or both")
+	}
+
+	ok := agent.UpdateSummary(ctx, func(s *agent.Summary) {
+		if summaryInput.Decisions != nil {
+			s.Decisions = trimItems(summaryInput.Decisions)
+		}
+		if summaryInput.OpenQuestions != nil {
+			s.OpenQuestions = trimItems(summaryInput.OpenQuestions)
+		}
+	})
+	if !ok {
+		return "", fmt.Errorf("there is no session summary outside an agent session")
+	}
+	return "Updated the summary.", nil
+}
+
+// trimItems returns items without surrounding space, leaving out blank ones.
+func trimItems(items []string) []string {
+	trimmed := []string{}
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			trimmed = append(trimmed, item)
+		}
+	}
+	return trimmed
+}
@@ -0,0 +1,27 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSessionSummary(t *testing.T) {
+	if _, err := SessionSummary(context.Background(), json.RawMessage(`{}`)); err == nil || !strings.Contains(err.Error(), "give decisions") {
+		t.Errorf("Expected an error for an empty update, got %v", err)
+	}
+	if _, err := SessionSummary(context.Background(), json.RawMessage(`{"decisions":["Use SQLite"]}`)); err == nil || !strings.Contains(err.Error(), "outside an agent session") {
+		t.Errorf("Expected an error outside a session, got %v", err)
+	}
+}
+
+func TestTrimItems(t *testing.T) {
+	if got := trimItems([]string{" Use SQLite ", "", "  "}); !slices.Equal(got, []string{"Use SQLite"}) {
+		t.Errorf("Unexpected items %q", got)
+	}
+	if got := trimItems([]string{}); got == nil {
+		t.Error("Expected an empty list, which clears the summary's list, not nil")
+	}
+}
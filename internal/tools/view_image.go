@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"tiny-trae/internal/agent"
+)
+
+// ViewImageDefinition defines the 'view_image' tool.
+var ViewImageDefinition = agent.ToolDefinition{
+	Name: "view_image",
+	Description: `Look at an image file, such as a screenshot, diagram, or UI asset in the repository.
+
+Supports PNG, JPEG, GIF, and WebP files up to 5 MiB. The image is attached to the tool result so you can see it; use read_file for text files.`,
+	InputSchema: ViewImageInputSchema,
+	Function:    ViewImage,
+	Images:      ViewImageImages,
+}
+
+// ViewImageInput defines the input schema for the 'view_image' tool.
+type ViewImageInput struct {
+	Path string `json:"path" jsonschema_description:"The path of the image file"`
+}
+
+// ViewImageInputSchema is the JSON schema for the 'view_image' tool's input.
+var ViewImageInputSchema = agent.GenerateSchema[ViewImageInput]()
+
+// ViewImage implements the 'view_image' tool. It describes the image; the
+// image itself is attached by ViewImageImages.
+func ViewImage(ctx context.Context, input json.RawMessage) (string, error) {
+	viewInput := ViewImageInput{}
+	if err := json.Unmarshal(input, &viewInput); err != nil {
+		return "", err
+	}
+	img, err := agent.LoadImage(viewInput.Path)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s (%s)", viewInput.Path, describeImage(img)), nil
+}
+
+// describeImage returns an image's media type, size, and dimensions.
+func describeImage(img agent.Image) string {
+	description := fmt.Sprintf("%s, %d KiB", img.MediaType, (len(img.Data)+1023)>>10)
+	if config, _, err := image.DecodeConfig(bytes.NewReader(img.Data)); err == nil {
+		description += fmt.Sprintf(", %dx%d", config.Width, config.Height)
+	}
+	return description
+}
+
+// ViewImageImages loads the image named in a 'view_image' call.
+func ViewImageImages(input json.RawMessage) ([]agent.Image, error) {
+	viewInput := ViewImageInput{}
+	if err := json.Unmarshal(input, &viewInput); err != nil {
+		return nil, err
+	}
+	img, err := agent.LoadImage(viewInput.Path)
+	if err != nil {
+		return nil, err
+	}
+	return []agent.Image{img}, nil
+}
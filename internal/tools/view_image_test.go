@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestViewImage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "icon.png")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(file, image.NewRGBA(image.Rect(0, 0, 16, 8))); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	input, _ := json.Marshal(ViewImageInput{Path: path})
+	result, err := ViewImage(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := path + " (image/png, 1 KiB, 16x8)"; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	images, err := ViewImageImages(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(images) != 1 || images[0].MediaType != "image/png" {
+		t.Errorf("Expected the PNG to be attached, got %+v", images)
+	}
+
+	if _, err := ViewImage(context.Background(), json.RawMessage(`{"path":"main.go"}`)); err == nil {
+		t.Error("Expected error for a file that is not an image")
+	}
+}
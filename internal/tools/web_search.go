@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/websearch"
+)
+
+const (
+	// DefaultWebSearchCount is the number of results 'web_search' returns by default.
+	DefaultWebSearchCount = 5
+	// maxWebSearchCount caps the results 'web_search' returns.
+	maxWebSearchCount = 20
+)
+
+// WebSearchDefinition defines the 'web_search' tool.
+var WebSearchDefinition = agent.ToolDefinition{
+	Name: "web_search",
+	Description: `Search the web and return the titles, URLs, and snippets of the top results.
+
+Use it to look up current library documentation, API signatures, error messages, or release notes instead of relying on memory, especially for versions newer than your training data.
+Write queries the way you would type them into a search engine, including the library name and version where it matters.`,
+	InputSchema: WebSearchInputSchema,
+	Function:    WebSearch,
+
+	// Search APIs allow about one request per second on free plans
+	MinInterval: time.Second,
+}
+
+// WebSearchInput defines the input schema for the 'web_search' tool.
+type WebSearchInput struct {
+	Query    string `json:"query" jsonschema_description:"The search query"`
+	Count    int    `json:"count,omitempty" jsonschema_description:"The number of results to return. Defaults to 5, at most 20"`
+	Provider string `json:"provider,omitempty" jsonschema_description:"The search backend (brave, searxng, or bing). Defaults to the one configured in the environment"`
+}
+
+// WebSearchInputSchema is the JSON schema for the 'web_search' tool's input.
+var WebSearchInputSchema = agent.GenerateSchema[WebSearchInput]()
+
+// WebSearch implements the 'web_search' tool.
+func WebSearch(ctx context.Context, input json.RawMessage) (string, error) {
+	searchInput := WebSearchInput{}
+	if err := json.Unmarshal(input, &searchInput); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(searchInput.Query) == "" {
+		return "", fmt.Errorf("query must not be empty")
+	}
+	count := searchInput.Count
+	if count <= 0 {
+		count = DefaultWebSearchCount
+	}
+	count = min(count, maxWebSearchCount)
+
+	searcher, err := websearch.NewSearcher(searchInput.Provider)
+	if err != nil {
+		return "", err
+	}
+	results, err := searcher.Search(ctx, searchInput.Query, count)
+	if err != nil {
+		return "", err
+	}
+	return formatWebSearchResults(results), nil
+}
+
+// formatWebSearchResults renders results as a numbered list.
+func formatWebSearchResults(results []websearch.Result) string {
+	if len(results) == 0 {
+		return "No results found."
+	}
+	var b strings.Builder
+	for i, result := range results {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "%d. %s\n   %s", i+1, result.Title, result.URL)
+		if result.Snippet != "" {
+			fmt.Fprintf(&b, "\n   %s", result.Snippet)
+		}
+	}
+	return b.String()
+}
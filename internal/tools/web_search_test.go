@@ -0,0 +1,50 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tiny-trae/internal/websearch"
+)
+
+func TestFormatWebSearchResults(t *testing.T) {
+	results := []websearch.Result{
+		{Title: "Go 1.24 Release Notes", URL: "https://go.dev/doc/go1.24", Snippet: "Go 1.24 adds generic type aliases."},
+		{Title: "Example", URL: "https://example.com"},
+	}
+	expected := "1. Go 1.24 Release Notes\n   https://go.dev/doc/go1.24\n   Go 1.24 adds generic type aliases.\n\n2. Example\n   https://example.com"
+	if got := formatWebSearchResults(results); got != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, got)
+	}
+	if got := formatWebSearchResults(nil); got != "No results found." {
+		t.Errorf("Unexpected empty result %q", got)
+	}
+}
+
+func TestWebSearch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" || r.URL.Query().Get("format") != "json" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"results":[{"title":"slog package","url":"https://pkg.go.dev/log/slog","content":"Structured logging."}]}`))
+	}))
+	defer server.Close()
+	t.Setenv("SEARXNG_URL", server.URL)
+
+	input, _ := json.Marshal(WebSearchInput{Query: "go slog", Provider: "searxng"})
+	result, err := WebSearch(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "https://pkg.go.dev/log/slog") {
+		t.Errorf("Expected the result URL, got:\n%s", result)
+	}
+
+	if _, err := WebSearch(context.Background(), json.RawMessage(`{"query":" "}`)); err == nil {
+		t.Error("Expected error for an empty query")
+	}
+}
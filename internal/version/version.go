@@ -0,0 +1,83 @@
+// Package version reports which build of tiny-trae is running. Release
+// builds set the version, commit, and date with the linker:
+//
+//	go build -ldflags "-X tiny-trae/internal/version.Version=v1.2.0 -X tiny-trae/internal/version.Commit=$(git rev-parse HEAD) -X tiny-trae/internal/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Otherwise they come from the build information the go command embeds.
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Set with -ldflags "-X ..."; empty unless set.
+var (
+	Version string
+	Commit  string
+	Date    string
+)
+
+// Info is the version of a build.
+type Info struct {
+	Version string
+	Commit  string
+	Date    string
+	// Modified is set for builds of a checkout with uncommitted changes.
+	Modified bool
+}
+
+// Get returns the running build's version. Values set with the linker take
+// precedence over the embedded build information.
+var Get = sync.OnceValue(func() Info {
+	info := Info{Version: "dev"}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		if v := build.Main.Version; v != "" && v != "(devel)" {
+			info.Version = v
+		}
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Commit = setting.Value
+			case "vcs.time":
+				info.Date = setting.Value
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	if Version != "" {
+		info.Version = Version
+	}
+	if Commit != "" {
+		info.Commit, info.Modified = Commit, false
+	}
+	if Date != "" {
+		info.Date = Date
+	}
+	return info
+})
+
+// String describes the build on one line, as --version prints it.
+func (i Info) String() string {
+	s := "tiny-trae " + i.Version
+	if i.Commit != "" {
+		commit := i.Commit[:min(12, len(i.Commit))]
+		if i.Modified {
+			commit += "-dirty"
+		}
+		s += " (" + commit
+		if i.Date != "" {
+			s += ", " + i.Date
+		}
+		s += ")"
+	}
+	return fmt.Sprintf("%s %s %s/%s", s, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+// UserAgent returns the User-Agent header for requests tiny-trae makes.
+func (i Info) UserAgent() string {
+	return fmt.Sprintf("tiny-trae/%s (%s/%s)", i.Version, runtime.GOOS, runtime.GOARCH)
+}
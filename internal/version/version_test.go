@@ -0,0 +1,19 @@
+package version
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	info := Info{Version: "v1.2.0", Commit: "0123456789abcdef", Date: "2025-01-02T03:04:05Z", Modified: true}
+	if got := info.String(); !strings.HasPrefix(got, "tiny-trae v1.2.0 (0123456789ab-dirty, 2025-01-02T03:04:05Z) go") {
+		t.Errorf("Unexpected version line %q", got)
+	}
+	if got := (Info{Version: "dev"}).String(); !strings.HasPrefix(got, "tiny-trae dev go") {
+		t.Errorf("Unexpected version line %q", got)
+	}
+	if got := info.UserAgent(); !strings.HasPrefix(got, "tiny-trae/v1.2.0 (") {
+		t.Errorf("Unexpected user agent %q", got)
+	}
+}
@@ -0,0 +1,221 @@
+// Package websearch queries web search APIs for the 'web_search' tool.
+package websearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Result is one search hit.
+type Result struct {
+	Title   string
+	URL     string
+	Snippet string
+}
+
+// Searcher runs web searches against one backend.
+type Searcher interface {
+	// Name identifies the backend, e.g. "brave".
+	Name() string
+	// Search returns up to count results for query.
+	Search(ctx context.Context, query string, count int) ([]Result, error)
+}
+
+// Providers lists the backends NewSearcher accepts.
+var Providers = []string{"brave", "searxng", "bing"}
+
+// NewSearcher returns the searcher for a provider. An empty provider uses
+// WEB_SEARCH_PROVIDER, or else the first provider whose credentials are set.
+//
+//   - brave: the Brave Search API, using BRAVE_API_KEY.
+//   - searxng: a SearXNG instance at SEARXNG_URL with the JSON format enabled.
+//   - bing: the Bing Web Search API, using BING_API_KEY. Set BING_ENDPOINT to
+//     use another endpoint.
+func NewSearcher(provider string) (Searcher, error) {
+	if provider == "" {
+		provider = detectProvider()
+	}
+	switch provider {
+	case "brave":
+		return newSearcher("brave", "https://api.search.brave.com/res/v1/web/search", "BRAVE_API_KEY")
+	case "searxng":
+		base := os.Getenv("SEARXNG_URL")
+		if base == "" {
+			return nil, fmt.Errorf("SEARXNG_URL is not set")
+		}
+		return &searcher{name: "searxng", endpoint: strings.TrimRight(base, "/") + "/search"}, nil
+	case "bing":
+		return newSearcher("bing", envOr("BING_ENDPOINT", "https://api.bing.microsoft.com/v7.0/search"), "BING_API_KEY")
+	case "":
+		return nil, fmt.Errorf("no web search provider is configured; set BRAVE_API_KEY, SEARXNG_URL, or BING_API_KEY")
+	default:
+		return nil, fmt.Errorf("unknown web search provider %q (want one of %s)", provider, strings.Join(Providers, ", "))
+	}
+}
+
+// detectProvider picks a provider from the environment.
+func detectProvider() string {
+	if provider := os.Getenv("WEB_SEARCH_PROVIDER"); provider != "" {
+		return provider
+	}
+	envs := []string{"BRAVE_API_KEY", "SEARXNG_URL", "BING_API_KEY"}
+	for i, env := range envs {
+		if os.Getenv(env) != "" {
+			return Providers[i]
+		}
+	}
+	return ""
+}
+
+// envOr returns the environment variable key, or fallback if it is unset.
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// newSearcher returns a searcher for an API that needs the key in keyEnv.
+func newSearcher(name, endpoint, keyEnv string) (Searcher, error) {
+	apiKey := os.Getenv(keyEnv)
+	if apiKey == "" {
+		return nil, fmt.Errorf("%s is not set", keyEnv)
+	}
+	return &searcher{name: name, endpoint: endpoint, apiKey: apiKey}, nil
+}
+
+// searcher implements Searcher for the supported APIs, which differ only in
+// their query parameters, authentication header, and response shape.
+type searcher struct {
+	name     string
+	endpoint string
+	apiKey   string
+}
+
+// Name implements Searcher.
+func (s *searcher) Name() string {
+	return s.name
+}
+
+// Search implements Searcher.
+func (s *searcher) Search(ctx context.Context, query string, count int) ([]Result, error) {
+	params := url.Values{"q": {query}}
+	switch s.name {
+	case "searxng":
+		params.Set("format", "json")
+	default:
+		params.Set("count", strconv.Itoa(count))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	switch s.name {
+	case "brave":
+		req.Header.Set("X-Subscription-Token", s.apiKey)
+	case "bing":
+		req.Header.Set("Ocp-Apim-Subscription-Key", s.apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s search request failed: %w", s.name, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s search failed: %s: %s", s.name, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	results, err := s.parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s response: %w", s.name, err)
+	}
+	if len(results) > count {
+		results = results[:count]
+	}
+	return results, nil
+}
+
+// parse extracts the results from a response body.
+func (s *searcher) parse(body []byte) ([]Result, error) {
+	var results []Result
+	switch s.name {
+	case "brave":
+		var resp struct {
+			Web struct {
+				Results []struct {
+					Title       string `json:"title"`
+					URL         string `json:"url"`
+					Description string `json:"description"`
+				} `json:"results"`
+			} `json:"web"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, err
+		}
+		for _, r := range resp.Web.Results {
+			results = append(results, Result{Title: r.Title, URL: r.URL, Snippet: r.Description})
+		}
+	case "searxng":
+		var resp struct {
+			Results []struct {
+				Title   string `json:"title"`
+				URL     string `json:"url"`
+				Content string `json:"content"`
+			} `json:"results"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, err
+		}
+		for _, r := range resp.Results {
+			results = append(results, Result{Title: r.Title, URL: r.URL, Snippet: r.Content})
+		}
+	case "bing":
+		var resp struct {
+			WebPages struct {
+				Value []struct {
+					Name    string `json:"name"`
+					URL     string `json:"url"`
+					Snippet string `json:"snippet"`
+				} `json:"value"`
+			} `json:"webPages"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, err
+		}
+		for _, r := range resp.WebPages.Value {
+			results = append(results, Result{Title: r.Name, URL: r.URL, Snippet: r.Snippet})
+		}
+	}
+
+	for i := range results {
+		results[i].Title = cleanText(results[i].Title)
+		results[i].Snippet = cleanText(results[i].Snippet)
+	}
+	return results, nil
+}
+
+// htmlTag matches the highlighting markup some APIs put in snippets.
+var htmlTag = regexp.MustCompile(`<[^>]+>`)
+
+// cleanText strips HTML tags and entities and collapses whitespace.
+func cleanText(text string) string {
+	text = html.UnescapeString(htmlTag.ReplaceAllString(text, ""))
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,100 @@
+package websearch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSearchProviders(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		header   string
+	}{
+		{
+			name:     "brave",
+			header:   "X-Subscription-Token",
+			response: `{"web":{"results":[{"title":"Go <strong>1.24</strong>","url":"https://go.dev/doc/go1.24","description":"Release notes &amp; changes"},{"title":"Second","url":"https://example.com","description":""}]}}`,
+		},
+		{
+			name:     "searxng",
+			response: `{"results":[{"title":"Go 1.24","url":"https://go.dev/doc/go1.24","content":"Release notes & changes"},{"title":"Second","url":"https://example.com","content":""}]}`,
+		},
+		{
+			name:     "bing",
+			header:   "Ocp-Apim-Subscription-Key",
+			response: `{"webPages":{"value":[{"name":"Go 1.24","url":"https://go.dev/doc/go1.24","snippet":"Release   notes & changes"},{"name":"Second","url":"https://example.com","snippet":""}]}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("q") != "go release notes" {
+					t.Errorf("Unexpected query %q", r.URL.RawQuery)
+				}
+				if tt.header != "" && r.Header.Get(tt.header) != "key" {
+					t.Errorf("Expected %s header", tt.header)
+				}
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			s := &searcher{name: tt.name, endpoint: server.URL, apiKey: "key"}
+			results, err := s.Search(context.Background(), "go release notes", 1)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("Expected 1 result, got %v", results)
+			}
+			expected := Result{Title: "Go 1.24", URL: "https://go.dev/doc/go1.24", Snippet: "Release notes & changes"}
+			if results[0] != expected {
+				t.Errorf("Expected %+v, got %+v", expected, results[0])
+			}
+		})
+	}
+}
+
+func TestSearchError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	s := &searcher{name: "brave", endpoint: server.URL, apiKey: "key"}
+	if _, err := s.Search(context.Background(), "q", 5); err == nil {
+		t.Error("Expected error for a failed request")
+	}
+}
+
+func TestNewSearcher(t *testing.T) {
+	t.Setenv("WEB_SEARCH_PROVIDER", "")
+	t.Setenv("BRAVE_API_KEY", "")
+	t.Setenv("SEARXNG_URL", "")
+	t.Setenv("BING_API_KEY", "")
+
+	if _, err := NewSearcher(""); err == nil {
+		t.Error("Expected error without a configured provider")
+	}
+	if _, err := NewSearcher("brave"); err == nil {
+		t.Error("Expected error without BRAVE_API_KEY")
+	}
+	if _, err := NewSearcher("altavista"); err == nil {
+		t.Error("Expected error for an unknown provider")
+	}
+
+	t.Setenv("SEARXNG_URL", "http://localhost:8888/")
+	s, err := NewSearcher("")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if s.Name() != "searxng" {
+		t.Errorf("Expected searxng to be detected, got %s", s.Name())
+	}
+	if endpoint := s.(*searcher).endpoint; endpoint != "http://localhost:8888/search" {
+		t.Errorf("Unexpected endpoint %q", endpoint)
+	}
+}
@@ -1,17 +1,56 @@
 package main
 
 import (
+	"bufio"
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
 	"os"
+	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"tiny-trae/internal/agent"
+	"tiny-trae/internal/audit"
+	"tiny-trae/internal/completion"
+	"tiny-trae/internal/config"
+	"tiny-trae/internal/credential"
+	"tiny-trae/internal/doctor"
 	"tiny-trae/internal/frontend"
+	"tiny-trae/internal/git"
+	"tiny-trae/internal/ignore"
+	"tiny-trae/internal/lsp"
+	"tiny-trae/internal/mcp"
+	"tiny-trae/internal/memory"
+	"tiny-trae/internal/permission"
+	"tiny-trae/internal/plugin"
 	"tiny-trae/internal/profile"
+	"tiny-trae/internal/prompt"
+	"tiny-trae/internal/repomap"
+	"tiny-trae/internal/review"
+	"tiny-trae/internal/rpc"
+	"tiny-trae/internal/scaffold"
+	"tiny-trae/internal/semantic"
+	"tiny-trae/internal/server"
+	"tiny-trae/internal/telegram"
+	"tiny-trae/internal/tools"
+	"tiny-trae/internal/version"
 
+	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
+	"google.golang.org/grpc"
 )
 
 // main is the entry point of the application.
@@ -19,46 +58,264 @@ import (
 // creates a new agent with a TUI frontend, and starts its execution.
 // It supports both interactive and non-interactive modes.
 // Any errors that occur during the agent's run are displayed in the TUI.
+// 'tiny-trae index' builds the semantic search index, 'tiny-trae review'
+// reviews the staged changes, 'tiny-trae serve-mcp' serves the tools over
+// MCP, 'tiny-trae serve' serves chat sessions over HTTP, and
+// 'tiny-trae telegram' relays a session to a Telegram chat instead.
+// 'tiny-trae completion' prints a shell completion script, 'tiny-trae
+// doctor' checks the environment, 'tiny-trae init' sets the repository up
+// for the agent, and 'tiny-trae debug tui' shows the TUI with a made-up
+// session.
 func main() {
-	// Define command line flags
+	// The doctor checks the settings rather than stopping at broken ones,
+	// and init writes settings rather than reading them
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "doctor":
+			os.Exit(runDoctor(os.Args[2:]))
+		case "init":
+			os.Exit(runInit(os.Args[2:]))
+		}
+	}
+
+	if len(os.Args) > 1 && isSubcommand(os.Args[1]) {
+		loadSettings()
+		switch os.Args[1] {
+		case "index":
+			runIndex(os.Args[2:])
+			return
+		case "review":
+			os.Exit(runReview(os.Args[2:]))
+		case "serve-mcp":
+			os.Exit(runServeMCP(os.Args[2:]))
+		case "serve":
+			os.Exit(runServe(os.Args[2:]))
+		case "telegram":
+			os.Exit(runTelegram(os.Args[2:]))
+		case "completion":
+			os.Exit(runCompletion(os.Args[2:]))
+		case "debug":
+			os.Exit(runDebug(os.Args[2:]))
+		case completion.Command:
+			runComplete(os.Args[2:])
+			return
+		}
+	}
+
+	// Define command line flags. Defaults that come from the settings are
+	// filled in once the workspace's settings are loaded, after parsing.
 	promptFlag := flag.String("p", "", "Accept a string as user input")
+	var workspace string
+	flag.StringVar(&workspace, "workspace", "", "Work in this directory instead of the current one; relative paths in other flags are relative to it")
+	flag.StringVar(&workspace, "cwd", "", "Same as --workspace")
 	listProfilesFlag := flag.Bool("list-profiles", false, "List all available profiles")
-	profileFlag := flag.String("profile", "default", "Specify which profile to use (default, coding, minimal)")
+	versionFlag := flag.Bool("version", false, "Print the version and exit")
+	profileFlag := flag.String("profile", "", "Specify which profile to use (default, coding, minimal, review, or one of your own; see --list-profiles; default: the profile in your settings, or default)")
+	modelFlag := flag.String("model", "", "Model to use instead of the profile's")
+	maxTokensFlag := flag.Int64("max-tokens", 0, "Response token limit to use instead of the profile's")
+	systemPromptFileFlag := flag.String("system-prompt-file", "", "Use the system prompt in this file instead of the profile's")
+	auditLogFlag := flag.String("audit-log", "", "Path to the tool execution audit log (default: audit.jsonl in a new session directory)")
+	permissionsFlag := flag.String("permissions", "", "Path to a permission policy file (default: ~/.config/tiny-trae/permissions.yaml)")
+	var yesFlag autoApproveFlag
+	flag.Var(&yesFlag, "yes", "Run tools that need approval without asking: all of them, or with --yes=edits only file edits, or with --yes=<tools> the comma-separated tool names and tag:<tag>s (permission policy deny rules still apply)")
+	flag.Var(&yesFlag, "auto-approve", "Same as --yes")
+	maxCPUFlag := flag.Uint64("max-cpu-seconds", tools.DefaultLimits.CPUSeconds, "CPU time limit for shell commands run by the agent (0 for no limit)")
+	maxMemoryFlag := flag.Uint64("max-memory-mb", tools.DefaultLimits.MemoryBytes>>20, "Memory limit in MiB for shell commands run by the agent (0 for no limit)")
+	maxProcessesFlag := flag.Uint64("max-processes", tools.DefaultLimits.MaxProcesses, "Process count limit for shell commands run by the agent (0 for no limit)")
+	worktreeFlag := flag.Bool("worktree", false, "Run the session in a new git worktree and branch, then offer to merge or discard its changes")
+	maxOutputFlag := flag.Int("max-output-kb", tools.DefaultLimits.MaxOutputBytes>>10, "Output size limit in KiB for shell commands run by the agent (0 for no limit)")
+	themeFlag := flag.String("theme", "", "TUI color theme: dark, light, or auto (default: the theme in ~/.config/tiny-trae/theme.yaml, or auto)")
+	notifyFlag := flag.String("notify", "", "Notify when a turn finishes or a tool needs approval while the terminal is in the background: off, bell (terminal bell and notification), or desktop (also a desktop notification); default: notify in your settings, or off")
+	transcriptFlag := flag.String("transcript", "", "Also write the session's transcript to this file, as JSON lines if it ends in .jsonl (default: transcript.txt in the session directory for interactive sessions)")
+	noTranscriptFlag := flag.Bool("no-transcript", false, "Do not write a transcript of the session")
+	noHistoryFlag := flag.Bool("no-history", false, "Do not save prompts to, or recall them from, ~/.local/share/tiny-trae/history")
+	thinkingFlag := flag.Int64("thinking", 0, "Turn on extended thinking with this many tokens of thinking per response (at least 1024; 0 for off; default: thinking in your settings, or off)")
+	quietFlag := flag.Bool("quiet", false, "Print only the final answer of a -p run, and errors on stderr")
+	verboseFlag := flag.Bool("verbose", false, "Print every tool call's full input and result, and the model's thinking, in a -p run")
+	maxTurnsFlag := flag.Int("max-turns", 0, "Stop a -p run after this many model responses, with a summary of its progress (0 for no limit)")
+	maxTimeFlag := flag.Duration("max-time", 0, "Stop a -p run after this long, such as 10m, with a summary of its progress (0 for no limit)")
+	outputFileFlag := flag.String("output-file", "", "Write the final answer of a -p run to this file")
+	outputFormatFlag := flag.String("output-format", "text", "Output format for -p runs: text, or jsonl for one JSON event per line on stdout")
+	noRepoMapFlag := flag.Bool("no-repo-map", false, "Do not give the model a map of the repository's layout and most referenced files in the first turn")
+	screenCaptureFlag := flag.Bool("screen-capture", false, "Enable the capture_screen tool, which screenshots pages, tmux panes, or the screen")
+	var imageFlags []string
+	flag.Func("image", "Attach an image file to the -p prompt (can be repeated)", func(path string) error {
+		if !agent.IsImagePath(path) {
+			return fmt.Errorf("%s is not a PNG, JPEG, GIF, or WebP image", path)
+		}
+		imageFlags = append(imageFlags, path)
+		return nil
+	})
 	flag.Parse()
 
+	// Everything, the project's settings included, is relative to the
+	// workspace
+	if workspace != "" {
+		if err := os.Chdir(workspace); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to change to the workspace: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	loadSettings()
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	if !given["profile"] {
+		*profileFlag = cmp.Or(settings.Profile, "default")
+	}
+	if !given["theme"] {
+		*themeFlag = settings.Theme
+	}
+	if !given["notify"] {
+		*notifyFlag = cmp.Or(settings.Notify, "off")
+	}
+	if !given["thinking"] {
+		*thinkingFlag = settings.Thinking
+	}
+
+	tools.CommandLimits = tools.ResourceLimits{
+		CPUSeconds:     *maxCPUFlag,
+		MemoryBytes:    *maxMemoryFlag << 20,
+		MaxProcesses:   *maxProcessesFlag,
+		MaxOutputBytes: *maxOutputFlag << 10,
+	}
+
+	if *versionFlag {
+		fmt.Println(version.Get())
+		return
+	}
+
 	// Handle list profiles flag
 	if *listProfilesFlag {
 		profile.ListProfiles()
 		return
 	}
 
-	var options []option.RequestOption
-	if apiKey := os.Getenv("ANTHROPIC_API_KEY"); apiKey != "" {
-		options = append(options, option.WithAPIKey(apiKey))
-	}
-	if baseURL := os.Getenv("ANTHROPIC_BASE_URL"); baseURL != "" {
-		options = append(options, option.WithBaseURL(baseURL))
-	}
-	client := agent.NewClientWithOptions(options...)
+	client := newClient()
 
 	// Determine if running in interactive mode
 	interactive := *promptFlag == ""
 	var initialMessage string
 	if *promptFlag != "" {
 		initialMessage = *promptFlag
+		// Images are attached by mentioning them, as in interactive prompts
+		for _, path := range imageFlags {
+			initialMessage += " @" + path
+		}
+	}
+
+	if *outputFormatFlag != "text" && *outputFormatFlag != "jsonl" {
+		fmt.Fprintf(os.Stderr, "Error: Unknown output format %q (want text or jsonl)\n", *outputFormatFlag)
+		os.Exit(1)
+	}
+	jsonl := *outputFormatFlag == "jsonl"
+	if jsonl && interactive {
+		fmt.Fprintln(os.Stderr, "Error: --output-format jsonl needs a prompt given with -p.")
+		os.Exit(1)
+	}
+	if *outputFileFlag != "" && interactive {
+		fmt.Fprintln(os.Stderr, "Error: --output-file needs a prompt given with -p.")
+		os.Exit(1)
+	}
+	if (*maxTurnsFlag != 0 || *maxTimeFlag != 0) && interactive {
+		fmt.Fprintln(os.Stderr, "Error: --max-turns and --max-time need a prompt given with -p.")
+		os.Exit(1)
+	}
+	if *maxTurnsFlag < 0 || *maxTimeFlag < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --max-turns and --max-time must be positive.")
+		os.Exit(1)
+	}
+	verbosity := frontend.VerbosityNormal
+	switch {
+	case *quietFlag && *verboseFlag:
+		fmt.Fprintln(os.Stderr, "Error: --quiet and --verbose cannot be used together.")
+		os.Exit(1)
+	case (*quietFlag || *verboseFlag) && (interactive || jsonl):
+		fmt.Fprintln(os.Stderr, "Error: --quiet and --verbose need a prompt given with -p and the text output format.")
+		os.Exit(1)
+	case *quietFlag:
+		verbosity = frontend.VerbosityQuiet
+	case *verboseFlag:
+		verbosity = frontend.VerbosityVerbose
+	}
+	// Keep stdout for the events; everything else printed goes to stderr
+	events := os.Stdout
+	if jsonl {
+		os.Stdout = os.Stderr
 	}
 
-	// Set up signal handler to ensure Ctrl+C always works
-	c := make(chan os.Signal, 1)
+	// The first SIGINT cancels the run, which then stops and cleans up
+	// like any other; a second one exits at once
+	ctx, interrupt := context.WithCancel(context.Background())
+	defer interrupt()
+	c := make(chan os.Signal, 2)
 	signal.Notify(c, os.Interrupt)
 	go func() {
 		<-c
-		fmt.Println()
-		os.Exit(0)
+		interrupt()
+		<-c
+		os.Exit(exitInterrupted)
+	}()
+
+	theme, err := frontend.LoadTheme(*themeFlag)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var historyPath string
+	if !*noHistoryFlag {
+		historyPath, _ = frontend.HistoryPath()
+	}
+
+	notify, err := frontend.ParseNotify(*notifyFlag)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	keyBindings, err := frontend.LoadKeyBindings()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Create the frontend
+	var agentFrontend agent.Frontend
+	var jsonlFrontend *frontend.JSONLFrontend
+	if jsonl {
+		jsonlFrontend = frontend.NewJSONLFrontend(events)
+		agentFrontend = jsonlFrontend
+	} else {
+		agentFrontend = frontend.NewTUIFrontend(interactive, frontend.TUIOptions{
+			Theme:       theme,
+			HistoryPath: historyPath,
+			Notify:      notify,
+			KeyBindings: keyBindings,
+			Verbosity:   verbosity,
+		})
+	}
+	// Exits after the deferred cleanup below has run
+	exitCode := exitSuccess
+	defer func() {
+		if exitCode != exitSuccess {
+			os.Exit(exitCode)
+		}
 	}()
 
-	// Create TUI frontend
-	agentFrontend := frontend.NewTUIFrontend(interactive)
+	// Log the session to a file as well, whatever the frontend shows
+	if !*noTranscriptFlag && (interactive || *transcriptFlag != "") {
+		transcript, err := openTranscript(*transcriptFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer transcript.Close()
+		var sink frontend.Sink = frontend.NewTextLog(transcript)
+		if strings.HasSuffix(transcript.Name(), ".jsonl") {
+			sink = frontend.NewJSONLFrontend(transcript)
+		}
+		agentFrontend = frontend.NewTee(agentFrontend, sink)
+	}
 	defer agentFrontend.Close()
 
 	// Select profile based on command line flag
@@ -67,18 +324,1125 @@ func main() {
 		fmt.Printf("Error: Unknown profile '%s'. Use --list-profiles to see available profiles.\n", *profileFlag)
 		os.Exit(1)
 	}
+	settings.ApplyTo(agentProfile)
+	if *modelFlag != "" {
+		agentProfile.Model = anthropic.Model(*modelFlag)
+	}
+	if *maxTokensFlag < 0 {
+		fmt.Println("Error: --max-tokens must be positive.")
+		os.Exit(1)
+	}
+	if *maxTokensFlag != 0 {
+		agentProfile.MaxTokens = *maxTokensFlag
+	}
+	if *systemPromptFileFlag != "" {
+		systemPrompt, err := os.ReadFile(*systemPromptFileFlag)
+		if err != nil {
+			fmt.Printf("Error: Failed to read the system prompt: %v\n", err)
+			os.Exit(1)
+		}
+		agentProfile.SystemPrompt = string(systemPrompt)
+	}
+	projectContext := addProjectContext(agentProfile)
+
+	if *screenCaptureFlag {
+		agentProfile.Tools = append(agentProfile.Tools, tools.CaptureScreenDefinition)
+	}
+	agentProfile.Tools = append(agentProfile.Tools, loadCustomTools()...)
+	agentProfile.Tools = append(agentProfile.Tools, loadPlugins(agentProfile.Tools)...)
+	if *thinkingFlag != 0 {
+		// The API's smallest thinking budget
+		if *thinkingFlag < 1024 {
+			fmt.Println("Error: --thinking must be at least 1024 tokens, or 0 to turn thinking off.")
+			os.Exit(1)
+		}
+		agentProfile.ThinkingBudget = *thinkingFlag
+	}
+
+	if verbosity != frontend.VerbosityQuiet {
+		fmt.Printf("Using profile: %s\n", agentProfile.Name)
+		for _, path := range projectContext {
+			fmt.Printf("Using context from %s\n", path)
+		}
+	}
+
+	// Load the tool permission policy
+	policy, err := loadPolicy(*permissionsFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to load permission policy: %v\n", err)
+		os.Exit(1)
+	}
 
-	fmt.Printf("Using profile: %s\n", agentProfile.Name)
+	// Open the audit log for this session
+	auditLog, err := openAuditLog(*auditLogFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer auditLog.Close()
+
+	// Stop any language servers started by the LSP tools
+	defer lsp.DefaultManager.Close()
+
+	// Run the session in a throwaway worktree so the user's checkout is untouched
+	var session *git.SessionWorktree
+	if *worktreeFlag {
+		started, err := startWorktree()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to create worktree: %v\n", err)
+			os.Exit(1)
+		}
+		session = &started
+		// The project's ignore patterns are relative to its directory in
+		// the worktree now
+		if project, err := config.FindProject("."); err == nil && project != nil {
+			ignore.SetProjectPatterns(project.Dir, project.Ignore)
+		}
+		fmt.Printf("Working in %s on branch %s\n", session.Path, session.Branch)
+	}
+	addEnvironment(agentProfile)
 
 	// Create agent with the selected frontend
 	agentInstance := agent.NewAgent(client, agentProfile, agentFrontend)
+	agentInstance.SetPermissionPolicy(policy)
+	agentInstance.SetAuditLog(auditLog)
+	agentInstance.SetLimits(agent.Limits{MaxTurns: *maxTurnsFlag, MaxTime: *maxTimeFlag})
+	autoApprove, err := autoApprover(string(yesFlag))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --yes: %v\n", err)
+		os.Exit(1)
+	}
+	agentInstance.SetAutoApprove(autoApprove)
+	if !*noRepoMapFlag {
+		addRepoMap(agentInstance)
+	}
 
 	// Run the agent
-	err := agentInstance.Run(context.TODO(), initialMessage)
-	if err != nil {
+	err = runSession(ctx, agentInstance, agentFrontend, initialMessage, session, interactive, os.Stdin, os.Stdout)
+	if jsonlFrontend != nil {
+		jsonlFrontend.Finish(err)
+	}
+	if *outputFileFlag != "" {
+		// Written even when the run failed, so scripts never read an
+		// answer left over from an earlier run
+		if writeErr := writeAnswer(*outputFileFlag, agentInstance.FinalAnswer()); writeErr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", writeErr)
+			exitCode = exitError
+			return
+		}
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		fmt.Fprintln(os.Stderr, "Interrupted")
+		exitCode = exitInterrupted
+	case err != nil:
 		// This should only happen in non-interactive mode now
 		// since interactive mode handles errors internally
 		fmt.Fprintf(os.Stderr, "Agent error: %v\n", err)
+		exitCode = exitError
+	}
+	if !interactive {
+		exitCode = runExitCode(err)
+	}
+}
+
+// Exit codes of -p runs, for scripts to branch on. 2 is left to the flag
+// package, which exits with it on bad flags.
+const (
+	exitSuccess        = 0
+	exitError          = 1
+	exitModelError     = 3
+	exitBudgetExceeded = 5
+	exitInterrupted    = 130 // as a shell reports a process killed by SIGINT
+)
+
+// autoApproveFlag is the value of --yes, which is given alone, like a
+// boolean flag, or with the tools it applies to, as in --yes=edits.
+type autoApproveFlag string
+
+func (f *autoApproveFlag) String() string { return string(*f) }
+
+func (f *autoApproveFlag) Set(value string) error {
+	if value == "false" {
+		value = ""
+	}
+	*f = autoApproveFlag(value)
+	return nil
+}
+
+func (f *autoApproveFlag) IsBoolFlag() bool { return true }
+
+// autoApprover returns which tools' calls run without asking for the scope
+// given with --yes: every tool for "true" or "all", the tools that change
+// files for "edits", and otherwise the comma-separated tool names and tags.
+// It returns nil when --yes was not given.
+func autoApprover(scope string) (func(agent.ToolDefinition) bool, error) {
+	switch scope {
+	case "":
+		return nil, nil
+	case "true", "all":
+		return func(agent.ToolDefinition) bool { return true }, nil
+	case "edits":
+		return func(tool agent.ToolDefinition) bool { return tool.MutatesFiles }, nil
+	}
+	selected, err := tools.Select(strings.Split(scope, ","))
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, tool := range selected {
+		names[tool.Name] = true
+	}
+	return func(tool agent.ToolDefinition) bool { return names[tool.Name] }, nil
+}
+
+// runExitCode returns the exit code of a -p run that returned err, which
+// says only how the run ended. Tool calls that failed or were denied along
+// the way are reported to the model, which may well have worked around them.
+func runExitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitSuccess
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.Is(err, agent.ErrBudgetExceeded):
+		return exitBudgetExceeded
+	}
+	return exitModelError
+}
+
+// writeAnswer writes a run's final answer to path.
+func writeAnswer(path, answer string) error {
+	if answer != "" && !strings.HasSuffix(answer, "\n") {
+		answer += "\n"
+	}
+	if err := os.WriteFile(path, []byte(answer), 0o644); err != nil {
+		return fmt.Errorf("failed to write the answer: %w", err)
+	}
+	return nil
+}
+
+// addProjectContext adds the project's instruction files, AGENTS.md or
+// CLAUDE.md in the working directory and those above it, and the project's
+// memory to the profile's system prompt, and returns the paths of the files
+// used. Files that cannot be read are reported and skipped.
+func addProjectContext(agentProfile *agent.Profile) []string {
+	var paths []string
+	instructions, err := prompt.FindInstructions(".")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to load project instructions: %v\n", err)
+	}
+	agentProfile.SystemPrompt = prompt.WithInstructions(agentProfile.SystemPrompt, instructions)
+	for _, i := range instructions {
+		paths = append(paths, i.Path)
+	}
+
+	remembered, err := memory.Load(memory.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to load the project's memory: %v\n", err)
+	} else if !remembered.Empty() {
+		agentProfile.SystemPrompt = strings.TrimRight(agentProfile.SystemPrompt, "\n") + "\n\n" + remembered.Prompt()
+		paths = append(paths, memory.File)
+	}
+	return paths
+}
+
+// addEnvironment describes the working directory, its git repository, the
+// system, and the date in the profile's system prompt.
+func addEnvironment(agentProfile *agent.Profile) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	agentProfile.SystemPrompt = prompt.WithEnvironment(agentProfile.SystemPrompt, prompt.DetectEnvironment(ctx, "."))
+}
+
+// addRepoMap gives the agent a map of the working directory for its first
+// turn.
+func addRepoMap(agentInstance *agent.Agent) {
+	repoMap, err := repomap.Build(".", repomap.DefaultOptions)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to map the repository: %v\n", err)
+		return
+	}
+	if repoMap != "" {
+		agentInstance.AddInitialContext("This is a map of the repository, as of the start of the session:\n" + repoMap)
+	}
+}
+
+// loadCustomTools registers the tools defined in the user's and the
+// project's custom tool files and returns them. Files that cannot be loaded
+// and tools whose names are taken are reported and skipped.
+func loadCustomTools() []agent.ToolDefinition {
+	var loaded []agent.ToolDefinition
+	register := func(path string, definitions []agent.ToolDefinition, err error) {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			return
+		}
+		for _, definition := range definitions {
+			if err := tools.DefaultRegistry.Register(definition, tools.TagCustom); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", path, err)
+				continue
+			}
+			loaded = append(loaded, definition)
+		}
+	}
+	if path, err := tools.CustomToolsPath(); err == nil {
+		definitions, err := tools.LoadCustomTools(path)
+		register(path, definitions, err)
+	}
+	if len(settings.ProjectTools) > 0 {
+		definitions, err := tools.ProjectCustomTools(settings.ProjectPath, settings.ProjectTools)
+		register(settings.ProjectPath, definitions, err)
+	}
+	if len(loaded) > 0 {
+		fmt.Fprintf(os.Stderr, "Loaded %d custom tools\n", len(loaded))
+	}
+	return loaded
+}
+
+// loadPlugins returns the tools implemented by executables in the plugin
+// directory. Plugins that cannot be loaded are reported and skipped.
+func loadPlugins(builtin []agent.ToolDefinition) []agent.ToolDefinition {
+	dir, err := plugin.Dir()
+	if err != nil {
+		return nil
+	}
+	taken := make([]string, len(builtin))
+	for i, tool := range builtin {
+		taken[i] = tool.Name
+	}
+	plugins, errs := plugin.Discover(context.Background(), dir, taken)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if len(plugins) > 0 {
+		// Not stdout, which carries the protocol in serve-mcp
+		fmt.Fprintf(os.Stderr, "Loaded %d plugin tools from %s\n", len(plugins), dir)
+	}
+	return plugins
+}
+
+// loadPolicy loads the permission policy at path, or the default one with
+// the rules from the settings if path is empty. The project's rules apply
+// either way.
+func loadPolicy(path string) (*permission.Policy, error) {
+	if path != "" {
+		policy, err := permission.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		settings.ApplyProjectPermissions(policy)
+		return policy, nil
+	}
+	policy, err := permission.LoadDefault()
+	if err != nil {
+		return nil, err
+	}
+	settings.ApplyPermissions(policy)
+	return policy, nil
+}
+
+// openAuditLog opens the audit log at path, or audit.jsonl in a new session
+// directory if path is empty.
+func openAuditLog(path string) (*audit.Log, error) {
+	if path == "" {
+		sessionDir, err := sessionDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine session directory: %w", err)
+		}
+		path = filepath.Join(sessionDir, "audit.jsonl")
+	}
+	return audit.Open(path)
+}
+
+// openTranscript opens the file the session's transcript is appended to:
+// path, or transcript.txt in the session directory if path is "".
+func openTranscript(path string) (*os.File, error) {
+	if path == "" {
+		sessionDir, err := sessionDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine session directory: %w", err)
+		}
+		path = filepath.Join(sessionDir, "transcript.txt")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create transcript directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transcript: %w", err)
+	}
+	return file, nil
+}
+
+// settings are the user's settings from the settings file, with the
+// project's settings merged over them.
+var settings *config.Config
+
+// isSubcommand reports whether name is a subcommand, which works in the
+// current directory and takes its own flags, run after loading settings.
+func isSubcommand(name string) bool {
+	switch name {
+	case "index", "review", "serve-mcp", "serve", "telegram", "completion", "debug", completion.Command:
+		return true
+	}
+	return false
+}
+
+// loadSettings loads settings for the current directory, exiting if they
+// are broken.
+func loadSettings() {
+	var err error
+	settings, err = config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to load settings: %v\n", err)
+		os.Exit(1)
+	}
+	if err := profile.SetUserProfiles(settings.Profiles); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Invalid profile in settings: %v\n", err)
 		os.Exit(1)
 	}
+	project, err := config.FindProject(".")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to load project settings: %v\n", err)
+		os.Exit(1)
+	}
+	if project != nil {
+		settings.Merge(project)
+		ignore.SetProjectPatterns(settings.IgnoreDir, settings.Ignore)
+	}
+}
+
+// sessionDir returns the directory of this run's session files. It is the
+// same on every call, so the audit log and the transcript end up together.
+var sessionDir = sync.OnceValues(audit.SessionDir)
+
+// startWorktree creates a session worktree for the repository in the current
+// directory and changes into the matching directory inside it.
+func startWorktree() (git.SessionWorktree, error) {
+	ctx := context.Background()
+	session, err := git.StartSession(ctx, ".", time.Now().Format("20060102-150405"))
+	if err != nil {
+		return git.SessionWorktree{}, err
+	}
+	dir := session.Path
+	if cwd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(session.Repo, cwd); err == nil && !strings.HasPrefix(rel, "..") {
+			dir = filepath.Join(session.Path, rel)
+		}
+	}
+	if err := os.Chdir(dir); err != nil {
+		session.Discard(ctx)
+		return git.SessionWorktree{}, err
+	}
+	return session, nil
+}
+
+// runSession runs the agent until the session ends, closes its frontend,
+// which gives the terminal back from the TUI, and then finishes the
+// session's worktree, if it has one, asking on in and out.
+func runSession(ctx context.Context, a *agent.Agent, f agent.Frontend, initialMessage string, session *git.SessionWorktree, interactive bool, in io.Reader, out io.Writer) error {
+	err := a.Run(ctx, initialMessage)
+	f.Close()
+	if session != nil {
+		finishWorktree(*session, interactive, in, out)
+	}
+	return err
+}
+
+// finishWorktree offers to merge the session's changes into the user's
+// branch or discard them, asking on in and out. Without a terminal to ask,
+// the worktree is kept and the commands to finish by hand are printed.
+func finishWorktree(session git.SessionWorktree, interactive bool, in io.Reader, out io.Writer) {
+	ctx := context.Background()
+	if err := os.Chdir(session.Repo); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	changed, err := session.Changed(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	if !changed {
+		if err := session.Discard(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to remove worktree: %v\n", err)
+		}
+		return
+	}
+
+	keep := func() {
+		fmt.Fprintf(out, "Kept the changes on branch %s in %s.\n", session.Branch, session.Path)
+		fmt.Fprintf(out, "Merge them with 'git merge %s', or discard them with 'git worktree remove --force %s && git branch -D %s'.\n", session.Branch, session.Path, session.Branch)
+	}
+	if !interactive {
+		keep()
+		return
+	}
+
+	reader := bufio.NewReader(in)
+	for {
+		fmt.Fprintf(out, "The session changed files on branch %s. [m]erge into your branch, [d]iscard, or [k]eep? ", session.Branch)
+		answer, err := reader.ReadString('\n')
+		if err != nil {
+			fmt.Fprintln(out)
+			keep()
+			return
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "m", "merge":
+			if err := session.Merge(ctx, "Apply changes from tiny-trae session"); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Failed to merge: %v\n", err)
+				keep()
+				return
+			}
+			fmt.Fprintf(out, "Merged %s into your branch.\n", session.Branch)
+			return
+		case "d", "discard":
+			if err := session.Discard(ctx); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Failed to discard worktree: %v\n", err)
+				return
+			}
+			fmt.Fprintln(out, "Discarded the session's changes.")
+			return
+		case "k", "keep":
+			keep()
+			return
+		}
+	}
+}
+
+// runServe implements the 'serve' subcommand, which serves chat sessions
+// over HTTP until interrupted.
+func runServe(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addrFlag := flags.String("addr", "127.0.0.1:8080", "Address to listen on; anyone who can reach it can run the agent's tools")
+	grpcAddrFlag := flags.String("grpc-addr", "", "Address to also serve the sessions over gRPC on, such as 127.0.0.1:50051 (default: no gRPC)")
+	profileFlag := flags.String("profile", cmp.Or(settings.Profile, "default"), "Profile of the sessions' agents")
+	permissionsFlag := flags.String("permissions", "", "Path to a permission policy file (default: ~/.config/tiny-trae/permissions.yaml)")
+	auditLogFlag := flags.String("audit-log", "", "Path to the tool execution audit log (default: audit.jsonl in a new session directory)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: tiny-trae serve [flags]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	agentProfile := profile.GetProfileByName(*profileFlag)
+	if agentProfile == nil {
+		fmt.Fprintf(os.Stderr, "Error: Unknown profile '%s'. Use --list-profiles to see available profiles.\n", *profileFlag)
+		return 1
+	}
+	settings.ApplyTo(agentProfile)
+	addProjectContext(agentProfile)
+	addEnvironment(agentProfile)
+	agentProfile.Tools = append(agentProfile.Tools, loadCustomTools()...)
+	agentProfile.Tools = append(agentProfile.Tools, loadPlugins(agentProfile.Tools)...)
+
+	policy, err := loadPolicy(*permissionsFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to load permission policy: %v\n", err)
+		return 1
+	}
+	auditLog, err := openAuditLog(*auditLogFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer auditLog.Close()
+	defer lsp.DefaultManager.Close()
+
+	sessions := &server.Server{
+		Client:   newClient(),
+		Profile:  agentProfile,
+		Policy:   policy,
+		AuditLog: auditLog,
+	}
+	defer sessions.Close()
+	httpServer := &http.Server{Addr: *addrFlag, Handler: sessions.Handler()}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		httpServer.Close()
+	}()
+
+	if *grpcAddrFlag != "" {
+		listener, err := net.Listen("tcp", *grpcAddrFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		grpcServer := grpc.NewServer()
+		rpc.Register(grpcServer, sessions)
+		go grpcServer.Serve(listener)
+		defer grpcServer.Stop()
+		fmt.Fprintf(os.Stderr, "Serving sessions over gRPC on %s\n", *grpcAddrFlag)
+	}
+
+	fmt.Fprintf(os.Stderr, "Serving sessions on http://%s\n", *addrFlag)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runTelegram implements the 'telegram' subcommand, which relays a session
+// to a Telegram chat, so it can be followed and approved from a phone.
+func runTelegram(args []string) int {
+	flags := flag.NewFlagSet("telegram", flag.ExitOnError)
+	chatIDFlag := flags.Int64("chat-id", 0, "ID of the only chat the bot talks to (required)")
+	promptFlag := flags.String("p", "", "First message of the session (default: wait for one from the chat)")
+	profileFlag := flags.String("profile", cmp.Or(settings.Profile, "default"), "Profile of the session's agent")
+	permissionsFlag := flags.String("permissions", "", "Path to a permission policy file (default: ~/.config/tiny-trae/permissions.yaml)")
+	auditLogFlag := flags.String("audit-log", "", "Path to the tool execution audit log (default: audit.jsonl in a new session directory)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: TELEGRAM_BOT_TOKEN=<token> tiny-trae telegram --chat-id <id> [flags]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	token := os.Getenv("TELEGRAM_BOT_TOKEN")
+	if token == "" {
+		fmt.Fprintln(os.Stderr, "Error: Set TELEGRAM_BOT_TOKEN to the bot's token")
+		return 1
+	}
+	if *chatIDFlag == 0 {
+		fmt.Fprintln(os.Stderr, "Error: --chat-id is required")
+		return 1
+	}
+
+	agentProfile := profile.GetProfileByName(*profileFlag)
+	if agentProfile == nil {
+		fmt.Fprintf(os.Stderr, "Error: Unknown profile '%s'. Use --list-profiles to see available profiles.\n", *profileFlag)
+		return 1
+	}
+	settings.ApplyTo(agentProfile)
+	addProjectContext(agentProfile)
+	addEnvironment(agentProfile)
+	agentProfile.Tools = append(agentProfile.Tools, loadCustomTools()...)
+	agentProfile.Tools = append(agentProfile.Tools, loadPlugins(agentProfile.Tools)...)
+
+	policy, err := loadPolicy(*permissionsFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to load permission policy: %v\n", err)
+		return 1
+	}
+	auditLog, err := openAuditLog(*auditLogFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer auditLog.Close()
+	defer lsp.DefaultManager.Close()
+
+	sessions := &server.Server{
+		Client:   newClient(),
+		Profile:  agentProfile,
+		Policy:   policy,
+		AuditLog: auditLog,
+	}
+	defer sessions.Close()
+	bot := &telegram.Bot{Sessions: sessions, Token: token, ChatID: *chatIDFlag}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Fprintf(os.Stderr, "Relaying a session to Telegram chat %d\n", *chatIDFlag)
+	if err := bot.Run(ctx, *promptFlag); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runServeMCP implements the 'serve-mcp' subcommand, which serves a profile's
+// tools to MCP clients over stdin and stdout.
+func runServeMCP(args []string) int {
+	flags := flag.NewFlagSet("serve-mcp", flag.ExitOnError)
+	profileFlag := flags.String("profile", cmp.Or(settings.Profile, "default"), "Profile whose tools are served")
+	permissionsFlag := flags.String("permissions", "", "Path to a permission policy file (default: ~/.config/tiny-trae/permissions.yaml)")
+	auditLogFlag := flags.String("audit-log", "", "Path to the tool execution audit log (default: audit.jsonl in a new session directory)")
+	autoApproveFlag := flags.Bool("auto-approve", false, "Run tools that need approval without asking (permission policy deny rules still apply)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: tiny-trae serve-mcp [flags]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	agentProfile := profile.GetProfileByName(*profileFlag)
+	if agentProfile == nil {
+		fmt.Fprintf(os.Stderr, "Error: Unknown profile '%s'. Use --list-profiles to see available profiles.\n", *profileFlag)
+		return 1
+	}
+	settings.ApplyTo(agentProfile)
+	agentProfile.Tools = append(agentProfile.Tools, loadCustomTools()...)
+	agentProfile.Tools = append(agentProfile.Tools, loadPlugins(agentProfile.Tools)...)
+
+	policy, err := loadPolicy(*permissionsFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to load permission policy: %v\n", err)
+		return 1
+	}
+	auditLog, err := openAuditLog(*auditLogFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer auditLog.Close()
+	defer lsp.DefaultManager.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	server := &mcp.Server{
+		Profile:     agentProfile,
+		Policy:      policy,
+		AuditLog:    auditLog,
+		AutoApprove: *autoApproveFlag,
+	}
+	if err := server.Serve(ctx, os.Stdin, os.Stdout); err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// subcommands are the names of the subcommands, for completion.
+var subcommands = []string{"index", "review", "serve-mcp", "serve", "telegram", "completion", "doctor", "init", "debug"}
+
+// runDoctor implements 'tiny-trae doctor': it checks the API key, the
+// programs the tools run, the terminal, and the settings files, and prints
+// how to fix what is wrong. It returns 1 if a check failed.
+func runDoctor(args []string) int {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: tiny-trae doctor")
+	}
+	flags.Parse(args)
+
+	settingsPath, _ := config.Path()
+	var settingsErr error
+	if settings, settingsErr = config.Load(); settingsErr != nil {
+		settings = &config.Config{}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	shell := doctor.Program("bash", "by the bash tool and custom tools", true, "Install bash")
+	if runtime.GOOS == "windows" {
+		shell = doctor.Program("pwsh", "by the powershell tool", false, "Install PowerShell 7, or the tool falls back to Windows PowerShell")
+	}
+	checks := []doctor.Check{
+		doctor.Load("settings", func() (string, error) {
+			if settingsErr != nil {
+				return "", settingsErr
+			}
+			return settingsPath, nil
+		}, "Fix or remove the settings file; see Settings in the README"),
+		doctor.Load("project settings", func() (string, error) {
+			project, err := config.FindProject(".")
+			if err != nil || project == nil {
+				return "no " + config.ProjectFile, err
+			}
+			return project.Path, nil
+		}, "Fix the project's "+config.ProjectFile),
+		doctor.Load("profiles", func() (string, error) {
+			if err := profile.SetUserProfiles(settings.Profiles); err != nil {
+				return "", err
+			}
+			name := cmp.Or(settings.Profile, "default")
+			if profile.GetProfileByName(name) == nil {
+				return "", fmt.Errorf("the default profile %s does not exist", name)
+			}
+			return fmt.Sprintf("%s, and %d of your own", name, len(settings.Profiles)), nil
+		}, "Fix the profiles in the settings file; --list-profiles lists the valid ones"),
+		doctor.Load("permissions", func() (string, error) {
+			policy, err := permission.LoadDefault()
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%d rules", len(policy.Rules)), nil
+		}, "Fix ~/.config/tiny-trae/permissions.yaml; see Tool Permissions in the README"),
+		doctor.Load("custom tools", func() (string, error) {
+			path, _ := tools.CustomToolsPath()
+			definitions, err := tools.LoadCustomTools(path)
+			if err != nil {
+				return "", err
+			}
+			count := len(definitions)
+			if project, err := config.FindProject("."); err == nil && project != nil {
+				definitions, err := tools.ProjectCustomTools(project.Path, project.Tools)
+				if err != nil {
+					return "", err
+				}
+				count += len(definitions)
+			}
+			return fmt.Sprintf("%d tools", count), nil
+		}, "Fix the tools file; see Custom Tools in the README"),
+		doctor.Load("TUI settings", func() (string, error) {
+			if _, err := frontend.LoadTheme(settings.Theme); err != nil {
+				return "", err
+			}
+			bindings, err := frontend.LoadKeyBindings()
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s key bindings", bindings), nil
+		}, "Fix theme.yaml or tui.yaml in ~/.config/tiny-trae"),
+		shell,
+		doctor.Program("git", "for checkpoints, --worktree, review, and the git tools", false, "Install git"),
+		doctor.Program("rg", "by the ripgrep tool, which otherwise uses a slower built-in search", false, "Install ripgrep: https://github.com/BurntSushi/ripgrep#installation"),
+		doctor.Terminal(os.Getenv),
+		{Name: "API", Run: checkAPI},
+	}
+
+	failed := doctor.Run(ctx, os.Stdout, checks)
+	if failed > 0 {
+		fmt.Printf("\n%d of %d checks failed.\n", failed, len(checks))
+		return 1
+	}
+	fmt.Println("\nNo problems found.")
+	return 0
+}
+
+// checkAPI checks that the API accepts the API key, with a request that
+// costs no tokens, and knows the default profile's model.
+func checkAPI(ctx context.Context) doctor.Result {
+	key, err := apiKey()
+	if err != nil {
+		return doctor.Fail(fmt.Sprintf("failed to get the API key: %v", err), "Fix credential_command in the settings, or set ANTHROPIC_API_KEY")
+	}
+	if key == "" {
+		return doctor.Fail("no API key", "Set ANTHROPIC_API_KEY, store the key in the system keychain, or set credential_command in the settings")
+	}
+
+	model := profile.DefaultProfile().Model
+	if defaultProfile := profile.GetProfileByName(cmp.Or(settings.Profile, "default")); defaultProfile != nil {
+		settings.ApplyTo(defaultProfile)
+		model = defaultProfile.Model
+	}
+	where := cmp.Or(apiBaseURL(), "https://api.anthropic.com")
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	client := newClientWithKey(key)
+	_, err = client.Models.Get(ctx, string(model), anthropic.ModelGetParams{}, option.WithMaxRetries(0))
+
+	var apiErr *anthropic.Error
+	switch {
+	case errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden):
+		return doctor.Fail(fmt.Sprintf("%s rejected the API key", where), "Check the key at https://console.anthropic.com/settings/keys")
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		return doctor.Warn(fmt.Sprintf("the API key works, but %s does not know the model %s", where, model), "Choose an available model with model in the settings or --model; some proxies do not list models")
+	case errors.As(err, &apiErr):
+		return doctor.Fail(fmt.Sprintf("%s answered %d %s", where, apiErr.StatusCode, http.StatusText(apiErr.StatusCode)), "Check that ANTHROPIC_BASE_URL or base_url in the settings is an Anthropic API endpoint")
+	case err != nil:
+		return doctor.Fail(fmt.Sprintf("cannot reach %s: %v", where, err), "Check your network connection, and ANTHROPIC_BASE_URL or base_url in the settings")
+	}
+	return doctor.OK(fmt.Sprintf("%s accepted the API key; %s is available", where, model))
+}
+
+// runInit implements 'tiny-trae init': it writes starter project settings,
+// agent instructions, and a .traeignore file to the root of the repository,
+// leaving any that exist alone.
+func runInit(args []string) int {
+	flags := flag.NewFlagSet("init", flag.ExitOnError)
+	testCommandFlag := flags.String("test-command", "", "Command run_tests runs (default: detected from go.mod or package.json)")
+	forceFlag := flags.Bool("force", false, "Overwrite files that already exist")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: tiny-trae init [flags]")
+		fmt.Fprintf(flags.Output(), "Writes %s, AGENTS.md, and %s to the root of the repository.\n", config.ProjectFile, ignore.TraeIgnoreFile)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 1
+	}
+
+	dir, err := git.Root(context.Background(), ".")
+	if err != nil {
+		// Not a git repository, so the current directory is the project
+		dir = "."
+	}
+	testCommand := cmp.Or(*testCommandFlag, scaffold.TestCommand(dir))
+	written, skipped, err := scaffold.Init(dir, scaffold.Options{TestCommand: testCommand, Force: *forceFlag})
+	for _, name := range written {
+		fmt.Printf("Created %s\n", filepath.Join(dir, name))
+	}
+	for _, name := range skipped {
+		fmt.Printf("Skipped %s, which already exists (--force overwrites it)\n", filepath.Join(dir, name))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if slices.Contains(written, config.ProjectFile) {
+		if testCommand != "" {
+			fmt.Printf("run_tests will run '%s'.\n", testCommand)
+		} else {
+			fmt.Printf("No test command found; set test_command in %s.\n", config.ProjectFile)
+		}
+	}
+	if len(written) > 0 {
+		fmt.Println("Fill in AGENTS.md, then review and commit the files.")
+	}
+	return 0
+}
+
+// runDebug implements 'tiny-trae debug tui', which plays a demo session
+// with every kind of message in the TUI, for working on the TUI without a
+// model or an API key.
+func runDebug(args []string) int {
+	flags := flag.NewFlagSet("debug", flag.ExitOnError)
+	themeFlag := flags.String("theme", settings.Theme, "TUI color theme: dark, light, or auto")
+	delayFlag := flags.Duration("delay", 150*time.Millisecond, "Pause between the demo's messages")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: tiny-trae debug tui [flags]")
+		flags.PrintDefaults()
+	}
+	if len(args) == 0 || args[0] != "tui" {
+		flags.Parse(args)
+		flags.Usage()
+		return 1
+	}
+	flags.Parse(args[1:])
+
+	theme, err := frontend.LoadTheme(*themeFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	keyBindings, err := frontend.LoadKeyBindings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	tui := frontend.NewTUIFrontend(true, frontend.TUIOptions{Theme: theme, KeyBindings: keyBindings})
+	frontend.Demo(tui, *delayFlag)
+	tui.Close()
+	return 0
+}
+
+// runCompletion implements 'tiny-trae completion': it prints the completion
+// script for a shell.
+func runCompletion(args []string) int {
+	flags := flag.NewFlagSet("completion", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: tiny-trae completion %s\n", strings.Join(completion.Shells, "|"))
+		fmt.Fprintln(flags.Output(), "For example, add 'source <(tiny-trae completion bash)' to ~/.bashrc.")
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+	script, err := completion.Script(flags.Arg(0), "tiny-trae")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Print(script)
+	return 0
+}
+
+// runComplete prints the candidates for the last of words, one per line,
+// for the completion scripts.
+func runComplete(words []string) {
+	completer := &completion.Completer{
+		Subcommands: subcommands,
+		Flags:       usageFlags,
+		Values:      flagValues,
+	}
+	for _, candidate := range completer.Complete(words) {
+		fmt.Println(candidate)
+	}
+}
+
+// usageFlags returns the flags of a subcommand, or of the main command for
+// "", from its usage message, so that completion never misses a flag.
+func usageFlags(subcommand string) []completion.Flag {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil
+	}
+	args := []string{"-h"}
+	if subcommand != "" {
+		args = []string{subcommand, "-h"}
+	}
+	usage, _ := exec.Command(executable, args...).CombinedOutput()
+	return completion.ParseUsage(string(usage))
+}
+
+// flagValues returns the values a flag can take, or nil for flags that
+// take a file name or free text.
+func flagValues(subcommand, name string) []string {
+	switch name {
+	case "profile":
+		return slices.Sorted(maps.Keys(profile.GetAvailableProfiles()))
+	case "theme":
+		return []string{"dark", "light", "auto"}
+	case "notify":
+		return []string{"off", "bell", "desktop"}
+	case "output-format":
+		return []string{"text", "jsonl"}
+	case "format":
+		return []string{"text", "json"}
+	case "fail-on":
+		return []string{"error", "warning", "suggestion"}
+	case "provider":
+		return semantic.Providers
+	}
+	return nil
+}
+
+// runIndex implements the 'index' subcommand, which embeds a directory tree
+// for the semantic_search tool.
+func runIndex(args []string) {
+	flags := flag.NewFlagSet("index", flag.ExitOnError)
+	providerFlag := flags.String("provider", "local", "Embedding provider ("+strings.Join(semantic.Providers, ", ")+")")
+	modelFlag := flags.String("model", "", "Embedding model (default: the provider's default)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: tiny-trae index [flags] [dir]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	root := "."
+	if flags.NArg() > 0 {
+		root = flags.Arg(0)
+	}
+
+	embedder, err := semantic.NewEmbedder(*providerFlag, *modelFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	index, err := semantic.Build(ctx, root, embedder, func(done, total int) {
+		fmt.Printf("\rEmbedded %d/%d chunks", done, total)
+	})
+	fmt.Println()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to build index: %v\n", err)
+		os.Exit(1)
+	}
+	if err := index.Save(root); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to save index: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Indexed %d chunks from %d files with %s into %s\n", len(index.Chunks), index.Files, index.Embedder, filepath.Join(root, semantic.IndexFile))
+}
+
+// newClient creates the Anthropic client, configured from ANTHROPIC_API_KEY
+// and ANTHROPIC_BASE_URL.
+func newClient() anthropic.Client {
+	key, err := apiKey()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to get the API key: %v\n", err)
+	}
+	return newClientWithKey(key)
+}
+
+// apiKey returns ANTHROPIC_API_KEY or, if it is not set, the key from the
+// credential command or the system keychain.
+func apiKey() (string, error) {
+	if key := os.Getenv("ANTHROPIC_API_KEY"); key != "" {
+		return key, nil
+	}
+	return credential.APIKey(context.Background(), settings.CredentialCommand)
+}
+
+// newClientWithKey creates the Anthropic client with the given API key.
+func newClientWithKey(key string) anthropic.Client {
+	options := []option.RequestOption{option.WithHeader("User-Agent", version.Get().UserAgent())}
+	if key != "" {
+		options = append(options, option.WithAPIKey(key))
+	}
+	if baseURL := apiBaseURL(); baseURL != "" {
+		options = append(options, option.WithBaseURL(baseURL))
+	}
+	return agent.NewClientWithOptions(options...)
+}
+
+// apiBaseURL returns the API's URL if it is not the default one.
+func apiBaseURL() string {
+	return cmp.Or(os.Getenv("ANTHROPIC_BASE_URL"), settings.BaseURL)
+}
+
+// runReview implements the 'review' subcommand, which has the agent review
+// the staged changes and prints its comments. It returns the exit status: 2
+// if a comment is at least as severe as --fail-on, so it can gate CI.
+func runReview(args []string) int {
+	flags := flag.NewFlagSet("review", flag.ExitOnError)
+	formatFlag := flags.String("format", "text", "Output format (text or json)")
+	failOnFlag := flags.String("fail-on", "", "Exit with status 2 if there is a comment of this severity or worse (error, warning, or suggestion)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: tiny-trae review [flags] [dir]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	dir := "."
+	if flags.NArg() > 0 {
+		dir = flags.Arg(0)
+	}
+	if *formatFlag != "text" && *formatFlag != "json" {
+		fmt.Fprintf(os.Stderr, "Error: Unknown format %q (want text or json)\n", *formatFlag)
+		return 1
+	}
+	var failOn review.Severity
+	if *failOnFlag != "" {
+		severity, err := review.ParseSeverity(*failOnFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		failOn = severity
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	request, err := review.BuildRequest(ctx, dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// The agent's tools work relative to the current directory
+	if err := os.Chdir(dir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer lsp.DefaultManager.Close()
+
+	collector := &review.Collector{Log: os.Stderr}
+	reviewer := agent.NewAgent(newClient(), profile.ReviewProfile(), collector)
+	if err := reviewer.Run(ctx, request); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Review failed: %v\n", err)
+		return 1
+	}
+	comments, err := review.ParseComments(collector.Answer())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n%s\n", err, collector.Answer())
+		return 1
+	}
+
+	if *formatFlag == "json" {
+		if comments == nil {
+			comments = []review.Comment{}
+		}
+		output, _ := json.MarshalIndent(comments, "", "  ")
+		fmt.Println(string(output))
+	} else {
+		fmt.Println(review.FormatText(comments))
+	}
+	if failOn != "" && review.AtLeast(comments, failOn) {
+		return 2
+	}
+	return 0
 }
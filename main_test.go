@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tiny-trae/internal/agent"
+	"tiny-trae/internal/frontend"
+	"tiny-trae/internal/git"
+	"tiny-trae/internal/tools"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+func TestYesEditsInNonInteractiveRun(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("notes.txt", []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	autoApprove, err := autoApprover("edits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shell := tools.ShellDefinition()
+	profile := &agent.Profile{Tools: []agent.ToolDefinition{tools.EditFileDefinition, shell}}
+	a := agent.NewAgent(anthropic.Client{}, profile, frontend.NewTUIFrontend(false, frontend.TUIOptions{}))
+	a.SetAutoApprove(autoApprove)
+
+	edit := a.CallTool(context.Background(), "1", "edit_file", json.RawMessage(`{"path":"notes.txt","old_str":"old","new_str":"new"}`))
+	if edit.IsError {
+		t.Errorf("Expected --yes=edits to approve edit_file, got %q", edit.Text)
+	}
+	if data, _ := os.ReadFile("notes.txt"); string(data) != "new\n" {
+		t.Errorf("Expected the edit to be made, got %q", data)
+	}
+
+	input, _ := json.Marshal(map[string]string{"command": "echo ran > ran.txt"})
+	run := a.CallTool(context.Background(), "2", shell.Name, input)
+	if !run.IsError || !strings.Contains(run.Text, "non-interactive run") {
+		t.Errorf("Expected --yes=edits to leave %s to approval, which a -p run denies, got %q", shell.Name, run.Text)
+	}
+	if _, err := os.Stat("ran.txt"); err == nil {
+		t.Errorf("Expected %s not to run", shell.Name)
+	}
+}
+
+// newTestRepo creates a git repository with one commit.
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	// Worktrees are created next to the repository
+	dir := filepath.Join(t.TempDir(), "repo")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+		{"add", "a.txt"},
+		{"commit", "-q", "-m", "initial"},
+	} {
+		if _, err := git.Run(ctx, dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// quitTUI calls run, which runs an interactive session in a TUI reading
+// the keys typed into keys, and presses Ctrl+C until run returns.
+func quitTUI(t *testing.T, keys *io.PipeWriter, run func() error) error {
+	t.Helper()
+	result := make(chan error, 1)
+	go func() { result <- run() }()
+	// The TUI quits on Ctrl+C once it waits for input
+	go func() {
+		for {
+			if _, err := keys.Write([]byte{3}); err != nil {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+	defer keys.Close()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected the session to end when the TUI quits")
+		return nil
+	}
+}
+
+func TestQuittingTheTUIFinishesTheWorktree(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+	session, err := git.StartSession(ctx, repo, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(session.Path)
+	if err := os.WriteFile("b.txt", []byte("b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, typed := io.Pipe()
+	f := frontend.NewTUIFrontend(true, frontend.TUIOptions{Input: keys, Output: io.Discard})
+	a := agent.NewAgent(anthropic.Client{}, &agent.Profile{}, f)
+	var out strings.Builder
+	err = quitTUI(t, typed, func() error {
+		return runSession(ctx, a, f, "", &session, true, strings.NewReader("d\n"), &out)
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "[m]erge into your branch, [d]iscard, or [k]eep?") {
+		t.Errorf("Expected to be asked what to do with the changes, got %q", out.String())
+	}
+	if _, err := os.Stat(session.Path); !os.IsNotExist(err) {
+		t.Errorf("Expected the worktree to be discarded, got %v", err)
+	}
+	// Closing again does not wait for a TUI that is gone
+	f.Close()
+}
+
+func TestQuittingTheTUIDeletesCheckpoints(t *testing.T) {
+	repo := newTestRepo(t)
+	t.Chdir(repo)
+	ctx := context.Background()
+	checkpoints := func() string {
+		t.Helper()
+		refs, err := git.Run(ctx, repo, "for-each-ref", "refs/tiny-trae/checkpoints/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return refs
+	}
+
+	keys, typed := io.Pipe()
+	f := frontend.NewTUIFrontend(true, frontend.TUIOptions{Input: keys, Output: io.Discard})
+	a := agent.NewAgent(anthropic.Client{}, &agent.Profile{Tools: []agent.ToolDefinition{tools.EditFileDefinition}}, f)
+	a.SetAutoApprove(func(agent.ToolDefinition) bool { return true })
+	edit := a.CallTool(ctx, "1", "edit_file", json.RawMessage(`{"path":"a.txt","old_str":"a","new_str":"b"}`))
+	if edit.IsError {
+		t.Fatalf("Unexpected error: %s", edit.Text)
+	}
+	if checkpoints() == "" {
+		t.Fatal("Expected the edit to be checkpointed")
+	}
+
+	if err := quitTUI(t, typed, func() error {
+		return runSession(ctx, a, f, "", nil, true, nil, io.Discard)
+	}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if refs := checkpoints(); refs != "" {
+		t.Errorf("Expected the session's checkpoints to be deleted when it ends, got %q", refs)
+	}
+}
+
+func TestInterruptedRunCleansUp(t *testing.T) {
+	repo := newTestRepo(t)
+	t.Chdir(repo)
+	ctx, interrupt := context.WithCancel(context.Background())
+
+	f := frontend.NewTUIFrontend(false, frontend.TUIOptions{})
+	a := agent.NewAgent(anthropic.Client{}, &agent.Profile{Tools: []agent.ToolDefinition{tools.EditFileDefinition}}, f)
+	a.SetAutoApprove(func(agent.ToolDefinition) bool { return true })
+	edit := a.CallTool(ctx, "1", "edit_file", json.RawMessage(`{"path":"a.txt","old_str":"a","new_str":"b"}`))
+	if edit.IsError {
+		t.Fatalf("Unexpected error: %s", edit.Text)
+	}
+
+	interrupt()
+	err := runSession(ctx, a, f, "fix the tests", nil, false, nil, io.Discard)
+	if code := runExitCode(err); code != exitInterrupted {
+		t.Errorf("Expected an interrupted run to exit %d, got %d (err %v)", exitInterrupted, code, err)
+	}
+	refs, err := git.Run(context.Background(), repo, "for-each-ref", "refs/tiny-trae/checkpoints/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refs != "" {
+		t.Errorf("Expected an interrupted run to delete its checkpoints, got %q", refs)
+	}
+}
+
+func TestRunExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, exitSuccess},
+		{context.Canceled, exitInterrupted},
+		{fmt.Errorf("%w: the run reached its turn limit", agent.ErrBudgetExceeded), exitBudgetExceeded},
+		{errors.New("529 overloaded"), exitModelError},
+	}
+	for _, tt := range tests {
+		if got := runExitCode(tt.err); got != tt.want {
+			t.Errorf("runExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}